@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/document"
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+// angleModes maps the modes selecting an angle unit to it
+var angleModes = map[string]number.AngleMode{
+	"rad": number.Radians,
+	"deg": number.Degrees,
+}
+
+// displayModes maps display bases to the modes selecting them
+var displayModes = map[int]string{
+	10:   "dec",
+	16:   "hex",
+	8:    "oct",
+	2:    "bin",
+	1000: "eng",
+}
+
+// setMode switches the angle mode or the display base of calc
+func setMode(calc *number.RPNCalculator, mode string) error {
+	if angle, ok := angleModes[mode]; ok {
+		calc.SetAngleMode(angle)
+		return nil
+	}
+	if !slices.Contains(slices.Collect(maps.Values(displayModes)), mode) {
+		return fmt.Errorf("unknown mode %s", mode)
+	}
+	return calc.Evaluate(mode)
+}
+
+// modes returns the angle mode and the display base of calc
+func modes(calc *number.RPNCalculator) []string {
+	state := calc.Snapshot()
+	angle := "rad"
+	if state.AngleMode == number.Degrees {
+		angle = "deg"
+	}
+	return []string{angle, displayModes[state.DisplayBase]}
+}
+
+// calculatorFlags are the flags of the commands running a calculator
+type calculatorFlags struct {
+	precision int
+	modes     string
+}
+
+// register adds the flags to a command's flag set
+func (f *calculatorFlags) register(flags *flag.FlagSet) {
+	flags.IntVar(&f.precision, "precision", 2, "decimals of the results")
+	flags.StringVar(&f.modes, "mode", "", "comma separated modes: deg, rad, dec, hex, oct, bin or eng")
+}
+
+// calculator creates a calculator with the precision and modes of the flags
+func (f *calculatorFlags) calculator() (*number.RPNCalculator, error) {
+	if f.precision < 0 {
+		return nil, fmt.Errorf("precision must not be negative, got %d", f.precision)
+	}
+	calc := number.NewRPNCalculator(number.WithFormatter(number.FixedFormatter{Decimals: f.precision}))
+	for _, mode := range strings.Split(f.modes, ",") {
+		if mode = strings.TrimSpace(mode); mode == "" {
+			continue
+		}
+		if err := setMode(calc, mode); err != nil {
+			return nil, err
+		}
+	}
+	return calc, nil
+}
+
+// runREPL starts the interactive calculator
+func runREPL(args []string) error {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	var calcFlags calculatorFlags
+	calcFlags.register(flags)
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+	calc, err := calcFlags.calculator()
+	if err != nil {
+		return err
+	}
+	session := &repl{calc: calc, out: os.Stdout}
+	return session.run(os.Stdin)
+}
+
+// runEval evaluates the RPN expression of the arguments and prints every
+// value it leaves, bottom first
+func runEval(args []string) error {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	var calcFlags calculatorFlags
+	calcFlags.register(flags)
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return errUsage
+	}
+	calc, err := calcFlags.calculator()
+	if err != nil {
+		return err
+	}
+	values, err := calc.EvaluateAll(strings.Join(flags.Args(), " "))
+	if err != nil {
+		return err
+	}
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = calc.Format(value)
+	}
+	fmt.Println(strings.Join(formatted, " "))
+	return nil
+}
+
+// runConvert converts the expression of the arguments from infix to RPN or
+// from RPN to infix
+func runConvert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	to := flags.String("to", "", "notation to convert to: rpn or infix")
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return errUsage
+	}
+	expression := strings.Join(flags.Args(), " ")
+	switch *to {
+	case "rpn":
+		expr, err := number.ParseInfix(expression)
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.Join(expr.ToRPN(), " "))
+	case "infix":
+		infix, err := number.ToInfix(strings.Fields(expression))
+		if err != nil {
+			return err
+		}
+		fmt.Println(infix)
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+// runMatch prints the lines of the files, or of standard input without
+// files, that match a boolean query, each prefixed with its file name when
+// there are several files
+func runMatch(args []string) error {
+	flags := flag.NewFlagSet("match", flag.ContinueOnError)
+	query := flags.String("query", "", "boolean query, like \"(python OR java) AND guide\"")
+	if err := flags.Parse(args); err != nil || *query == "" {
+		return errUsage
+	}
+	compiled, err := document.CompileQuery(*query)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return matchLines(compiled, os.Stdin, "")
+	}
+	for _, path := range flags.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		prefix := ""
+		if flags.NArg() > 1 {
+			prefix = path + ":"
+		}
+		err = matchLines(compiled, file, prefix)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// matchLines prints the lines of r matching compiled after prefix
+func matchLines(compiled *document.Query, r io.Reader, prefix string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if compiled.MatchDocument(document.PrepareDocument(scanner.Text())) {
+			fmt.Println(prefix + scanner.Text())
+		}
+	}
+	return scanner.Err()
+}
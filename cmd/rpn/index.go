@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/document"
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+// openIndex loads the index and saved queries saved to a directory
+func openIndex(dir string) (*document.Index, *document.QueryStore, error) {
+	storage, err := document.NewFileStorage(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document.LoadFromStorage(storage)
+}
+
+// saveIndex saves the index and saved queries to a directory
+func saveIndex(dir string, idx *document.Index, store *document.QueryStore) error {
+	storage, err := document.NewFileStorage(dir)
+	if err != nil {
+		return err
+	}
+	return document.SaveToStorage(storage, idx, store)
+}
+
+// indexFlags creates the flags of a command run on the index saved to the
+// directory given by -index
+func indexFlags(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	dir := flags.String("index", "", "directory the index is saved to")
+	return flags, dir
+}
+
+// runBackup writes the index and saved queries saved to a directory, and
+// the formula file at formulasPath unless it is empty, to an archive
+func runBackup(dir, path, formulasPath string) error {
+	idx, store, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var formulas *number.Formulas
+	if formulasPath != "" {
+		formulas = number.NewFormulas(formulasPath)
+	}
+	if err := document.Backup(file, idx, store, formulas); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Backed up %d documents and %d queries to %s\n", idx.Len(), len(store.Names()), path)
+	if formulas != nil {
+		fmt.Printf("Backed up the formulas of %s\n", formulasPath)
+	}
+	return nil
+}
+
+// runRestore verifies an archive, saves the index and queries it holds to
+// a directory, and the formulas it holds to formulasPath unless it is
+// empty, and reports what it restored
+func runRestore(path, dir, formulasPath string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	idx, store, formulas, err := document.Restore(file, formulasPath)
+	if err != nil {
+		return err
+	}
+	if err := saveIndex(dir, idx, store); err != nil {
+		return err
+	}
+	if err := reportRestored(idx, store, path); err != nil {
+		return err
+	}
+	if formulas != nil {
+		// Formulas that did not compile when they were backed up are
+		// restored as they were and reported
+		loadErr := formulas.Load()
+		fmt.Printf("Restored %d formulas to %s\n", len(formulas.Names()), formulasPath)
+		return loadErr
+	}
+	return nil
+}
+
+// runSave adds every line of the files as a document and the queries of
+// a query file to the index saved to a directory, creating the index when
+// the directory holds none
+func runSave(dir, queries string, paths []string) error {
+	idx, store, err := openIndex(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		idx, store, err = document.NewIndex(), document.NewQueryStore(), nil
+	}
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			idx.Add(scanner.Text())
+			added++
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if queries != "" {
+		if err := document.NewQueryFileLoader(queries, store).Load(); err != nil {
+			return err
+		}
+	}
+	if err := saveIndex(dir, idx, store); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %d documents, saved %d documents and %d queries to %s\n", added, idx.Len(), len(store.Names()), dir)
+	return nil
+}
+
+// runLoad loads an index persisted to a directory and reports what it holds
+func runLoad(dir string) error {
+	idx, store, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+	return reportRestored(idx, store, dir)
+}
+
+// runServe serves the diagnostics endpoints of the index saved to a
+// directory on address
+func runServe(dir, address string, profiling bool) error {
+	idx, store, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+	diag := document.NewDiagnostics(idx, store)
+	diag.SetReady(true)
+
+	fmt.Printf("Serving %d documents on %s\n", idx.Len(), address)
+	return http.ListenAndServe(address, serverHandler(diag, profiling))
+}
+
+// serverHandler serves the diagnostics endpoints and, with profiling, the
+// pprof endpoints under /debug/pprof/. Those expose the command line and
+// let any client start profiles, so they are only served on request
+func serverHandler(diag *document.Diagnostics, profiling bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", diag.Handler())
+	if profiling {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
+// reportRestored prints the size of a restored index and the hit count of
+// every saved query
+func reportRestored(idx *document.Index, store *document.QueryStore, source string) error {
+	fmt.Printf("Restored %d documents and %d queries from %s\n", idx.Len(), len(store.Names()), source)
+	for _, name := range store.Names() {
+		compiled, _ := store.Get(name)
+		result, err := idx.Search(compiled.String(), document.SearchOptions{CountOnly: true})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %s: %s (%d hits)\n", name, compiled, result.Total)
+	}
+	return nil
+}
+
+// evaluationScorers are the scorers the relevance command can compare
+var evaluationScorers = map[string]document.Scorer{
+	"tf":    nil,
+	"tfidf": document.TFIDFScorer{},
+	"bm25":  document.NewBM25Scorer(),
+}
+
+// evaluationCutoff is the rank up to which the relevance command measures hits
+const evaluationCutoff = 10
+
+// runEvaluation measures the index saved to a directory against a
+// judgments file
+func runEvaluation(dir, path, scorerName string) error {
+	scorer, ok := evaluationScorers[scorerName]
+	if !ok {
+		return fmt.Errorf("unknown scorer: %s", scorerName)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	judged, err := document.ReadJudgments(file)
+	if err != nil {
+		return err
+	}
+	idx, _, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	report, err := document.EvaluateSearch(idx, judged, document.SearchOptions{Scorer: scorer}, evaluationCutoff)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Evaluated %d queries with %s scoring at %d\n", len(report.Queries), scorerName, evaluationCutoff)
+	for _, metrics := range report.Queries {
+		fmt.Printf("  %-30s hits %-3d P %.3f  R %.3f  nDCG %.3f\n",
+			metrics.Query, metrics.Hits, metrics.Precision, metrics.Recall, metrics.NDCG)
+	}
+	fmt.Printf("  %-30s          P %.3f  R %.3f  nDCG %.3f\n", "mean", report.MeanPrecision, report.MeanRecall, report.MeanNDCG)
+	return nil
+}
+
+// runImpact reports the documents of the index saved to a directory an
+// edit of one of its saved queries would gain and lose
+func runImpact(dir, name, query string) error {
+	idx, store, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+	impact, err := store.DryRun(idx, name, query)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %s -> %s\n", name, impact.Old, impact.New)
+	fmt.Printf("  %d gained, %d lost, %d unchanged\n", len(impact.Gained), len(impact.Lost), impact.Unchanged)
+	for _, hit := range impact.Gained {
+		fmt.Printf("  + %d: %s\n", hit.ID, hit.Text)
+	}
+	for _, hit := range impact.Lost {
+		fmt.Printf("  - %d: %s\n", hit.ID, hit.Text)
+	}
+	return nil
+}
+
+// runUsage searches the index saved to a directory with the given saved
+// queries, each search counted, saves the usage and prints the usage of
+// every saved query
+func runUsage(dir string, names []string) error {
+	idx, store, err := openIndex(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := store.Search(idx, name, document.SearchOptions{CountOnly: true}); err != nil {
+			return err
+		}
+	}
+	if len(names) > 0 {
+		if err := saveIndex(dir, idx, store); err != nil {
+			return err
+		}
+	}
+	writeUsageReport(os.Stdout, store.Usage())
+	return nil
+}
+
+// writeUsageReport prints one line of usage per saved query
+func writeUsageReport(w io.Writer, usages []document.QueryUsage) {
+	fmt.Fprintf(w, "%-20s %8s %7s %12s  %s\n", "QUERY", "EVALS", "ERRORS", "AVG LATENCY", "LAST USED")
+	for _, usage := range usages {
+		lastUsed := "never"
+		if !usage.LastUsed.IsZero() {
+			lastUsed = usage.LastUsed.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%-20s %8d %6.1f%% %12s  %s\n", usage.Name, usage.Evaluations, 100*usage.ErrorRate(), usage.AverageLatency(), lastUsed)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/document"
+)
+
+// saveTestIndex saves the documents and queries in testdata to a new
+// index directory
+func saveTestIndex(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "index")
+	output := runRPN(t, "save", "-index", dir, "-queries", "testdata/index-queries.json", "testdata/index-docs.txt")
+	if !strings.HasPrefix(output, "Added 4 documents") {
+		t.Fatalf("rpn save printed %q", output)
+	}
+	return dir
+}
+
+func TestSaveCommand(t *testing.T) {
+	dir := saveTestIndex(t)
+	output := runRPN(t, "save", "-index", dir, "testdata/match-a.txt")
+	output += runRPN(t, "save", "-index", dir, "missing.txt")
+	output += runRPN(t, "save", "testdata/match-a.txt")
+	output += runRPN(t, "load", dir)
+	output = strings.ReplaceAll(output, dir, "INDEX")
+	checkGolden(t, "save", output)
+}
+
+func TestBackupRestoreCommands(t *testing.T) {
+	dir := t.TempDir()
+	saved, restored := saveTestIndex(t), filepath.Join(dir, "restored")
+	archive := filepath.Join(dir, "index.tgz")
+
+	formulas, restoredFormulas := filepath.Join(dir, "formulas.json"), filepath.Join(dir, "restored.json")
+	if err := os.WriteFile(formulas, []byte(`{"area": "w h *", "total": "price qty *"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := runRPN(t, "backup", "-formulas", formulas, saved, archive)
+	output += runRPN(t, "restore", "-formulas", restoredFormulas, archive, restored)
+	output += runRPN(t, "load", restored)
+	output = strings.NewReplacer(archive, "ARCHIVE", restoredFormulas, "FORMULAS", restored, "RESTORED", formulas, "FORMULAS").Replace(output)
+	checkGolden(t, "backup", output)
+	if data, err := os.ReadFile(restoredFormulas); err != nil || !strings.Contains(string(data), `"total": "price qty *"`) {
+		t.Errorf("restored formula file = %s, %v", data, err)
+	}
+
+	damaged := filepath.Join(dir, "damaged.tgz")
+	if err := os.WriteFile(damaged, []byte("not an archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output = runRPN(t, "restore", damaged, filepath.Join(dir, "unused"))
+	if !strings.HasPrefix(output, "Error: invalid backup archive") || !strings.HasSuffix(output, "[exit status 1]\n") {
+		t.Errorf("restore of a damaged archive printed %q", output)
+	}
+}
+
+func TestServerHandlerProfiling(t *testing.T) {
+	tests := []struct {
+		name      string
+		profiling bool
+		path      string
+		status    int
+	}{
+		{"diagnostics", false, "/healthz", http.StatusOK},
+		{"no profiling", false, "/debug/pprof/", http.StatusNotFound},
+		{"profiling", true, "/debug/pprof/", http.StatusOK},
+		{"diagnostics with profiling", true, "/healthz", http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := serverHandler(document.NewDiagnostics(document.NewIndex(), nil), test.profiling)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+			if recorder.Code != test.status {
+				t.Errorf("GET %s status = %d, want %d", test.path, recorder.Code, test.status)
+			}
+		})
+	}
+}
+
+func TestRelevanceCommand(t *testing.T) {
+	judgments := filepath.Join(t.TempDir(), "judgments.json")
+	data := `[{"query": "tutorial", "judgments": {"2": 2, "1": 1}}, {"query": "guide", "judgments": {"0": 1}}]`
+	if err := os.WriteFile(judgments, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := saveTestIndex(t)
+	output := ""
+	for _, scorer := range []string{"tf", "bm25", "boolean"} {
+		output += runRPN(t, "relevance", "-index", dir, judgments, scorer)
+	}
+	checkGolden(t, "relevance", output)
+}
+
+func TestImpactCommand(t *testing.T) {
+	dir := saveTestIndex(t)
+	output := runRPN(t, "impact", "-index", dir, "guides", "guide OR tutorial")
+	output += runRPN(t, "impact", "-index", dir, "python-guides", "java AND tutorial")
+	output += runRPN(t, "impact", "-index", dir, "python", "python")
+	output += runRPN(t, "impact", "-index", dir, "missing", "python")
+	checkGolden(t, "impact", output)
+}
+
+func TestWriteUsageReport(t *testing.T) {
+	lastUsed := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		usage document.QueryUsage
+		want  string
+	}{
+		{"never searched", document.QueryUsage{Name: "idle"},
+			"idle                        0    0.0%           0s  never\n"},
+		{"searched", document.QueryUsage{Name: "guides", Evaluations: 4, Errors: 1, Latency: 8 * time.Millisecond, LastUsed: lastUsed},
+			"guides                      4   25.0%          2ms  2024-03-01T12:30:00Z\n"},
+	}
+	header := "QUERY                   EVALS  ERRORS  AVG LATENCY  LAST USED\n"
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			writeUsageReport(&out, []document.QueryUsage{test.usage})
+			if got := out.String(); got != header+test.want {
+				t.Errorf("writeUsageReport() = %q, want %q", got, header+test.want)
+			}
+		})
+	}
+}
+
+func TestUsageCommand(t *testing.T) {
+	dir := saveTestIndex(t)
+	// The steps run in order on one index, so the usage of every step adds
+	// to the usage saved by the steps before
+	tests := []struct {
+		name  string
+		args  []string
+		lines []string
+	}{
+		{"no searches", nil, []string{"guides                      0", "python                      0", "python-guides               0"}},
+		{"counted searches", []string{"guides", "guides", "python"}, []string{"guides                      2", "python                      1", "python-guides               0"}},
+		{"saved searches", []string{"guides"}, []string{"guides                      3", "python                      1", "python-guides               0"}},
+		{"unknown query", []string{"missing"}, []string{"Error: unknown saved query: missing", "[exit status 1]"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := runRPN(t, append([]string{"usage", "-index", dir}, test.args...)...)
+			for _, line := range test.lines {
+				if !strings.Contains(output, line) {
+					t.Errorf("rpn %s printed %q, missing %q", strings.Join(test.args, " "), output, line)
+				}
+			}
+		})
+	}
+}
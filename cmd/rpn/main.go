@@ -0,0 +1,139 @@
+// Command rpn evaluates and converts RPN expressions, matches boolean
+// queries, validates stored formulas and queries and builds, searches and
+// serves saved indexes of the query processor. Without a command it starts
+// the interactive calculator, rpn help lists the commands
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// errUsage is the error of a command given arguments it does not take,
+// reported with the usage of the command
+var errUsage = errors.New("invalid arguments")
+
+// command is a subcommand of rpn
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order help shows them
+var commands = []command{
+	{"repl", "[-precision n] [-mode modes]", "start the interactive calculator", runREPL},
+	{"eval", "[-precision n] [-mode modes] <expression>", "evaluate an RPN expression", runEval},
+	{"convert", "-to=rpn|infix <expression>", "convert an expression between infix and RPN", runConvert},
+	{"match", "-query=<query> [file...]", "print the lines matching a boolean query", runMatch},
+	{"validate", "[-dir directory]", "compile every stored formula and query in a directory tree", runValidate},
+	{"backup", "[-formulas file] <directory> <archive>", "back up a saved index, its queries and a formula file", func(args []string) error {
+		flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+		formulas := flags.String("formulas", "", "JSON file of named formulas to back up with the index")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		return runBackup(flags.Arg(0), flags.Arg(1), *formulas)
+	}},
+	{"restore", "[-formulas file] <archive> <directory>", "verify a backup and save the index and formulas it holds", func(args []string) error {
+		flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+		formulas := flags.String("formulas", "", "file to write the formulas of the backup to")
+		if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+			return errUsage
+		}
+		return runRestore(flags.Arg(0), flags.Arg(1), *formulas)
+	}},
+	{"save", "-index <directory> [-queries file] [file...]", "add the lines of files as documents and saved queries to an index", func(args []string) error {
+		flags, dir := indexFlags("save")
+		queries := flags.String("queries", "", "JSON file mapping query names to queries")
+		if err := flags.Parse(args); err != nil || *dir == "" {
+			return errUsage
+		}
+		return runSave(*dir, *queries, flags.Args())
+	}},
+	{"load", "<directory>", "load a saved index and report what it holds", oneArg(runLoad)},
+	{"serve", "-index <directory> [-pprof] <address>", "serve the diagnostics of a saved index", func(args []string) error {
+		flags, dir := indexFlags("serve")
+		profiling := flags.Bool("pprof", false, "serve the pprof endpoints under /debug/pprof/")
+		if err := flags.Parse(args); err != nil || *dir == "" || flags.NArg() != 1 {
+			return errUsage
+		}
+		return runServe(*dir, flags.Arg(0), *profiling)
+	}},
+	{"relevance", "-index <directory> <judgments.json> [tf|tfidf|bm25]", "measure the search quality of a saved index", func(args []string) error {
+		flags, dir := indexFlags("relevance")
+		if err := flags.Parse(args); err != nil || *dir == "" || flags.NArg() < 1 || flags.NArg() > 2 {
+			return errUsage
+		}
+		scorer := "tf"
+		if flags.NArg() == 2 {
+			scorer = flags.Arg(1)
+		}
+		return runEvaluation(*dir, flags.Arg(0), scorer)
+	}},
+	{"impact", "-index <directory> <saved query> <edited query>", "compare an edit of a saved query on a saved index", func(args []string) error {
+		flags, dir := indexFlags("impact")
+		if err := flags.Parse(args); err != nil || *dir == "" || flags.NArg() != 2 {
+			return errUsage
+		}
+		return runImpact(*dir, flags.Arg(0), flags.Arg(1))
+	}},
+	{"usage", "-index <directory> [saved query...]", "search the saved queries of an index and print their usage", func(args []string) error {
+		flags, dir := indexFlags("usage")
+		if err := flags.Parse(args); err != nil || *dir == "" {
+			return errUsage
+		}
+		return runUsage(*dir, flags.Args())
+	}},
+}
+
+// oneArg adapts a command taking a single argument
+func oneArg(run func(string) error) func([]string) error {
+	return func(args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		return run(args[0])
+	}
+}
+
+// printHelp lists the commands
+func printHelp() {
+	fmt.Println("Usage: rpn <command> [arguments], without a command rpn starts the calculator")
+	fmt.Println()
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"repl"}
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printHelp()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args[1:])
+		if errors.Is(err, errUsage) {
+			err = fmt.Errorf("usage: rpn %s %s", cmd.name, cmd.usage)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown command: %s, rpn help lists the commands\n", name)
+	os.Exit(1)
+}
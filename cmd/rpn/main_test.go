@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites the golden files with the output of the commands
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// runMainEnv makes the test binary run rpn instead of the tests, so the
+// golden tests cover argument parsing, output and exit status
+const runMainEnv = "RPN_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runRPN runs rpn with args, returning its standard output and error
+// followed by its exit status when it fails
+func runRPN(t *testing.T, args ...string) string {
+	t.Helper()
+	return runRPNWithInput(t, "", args...)
+}
+
+// runRPNWithInput runs rpn like runRPN, reading input from standard input
+func runRPNWithInput(t *testing.T, input string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	cmd.Stdin = strings.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		fmt.Fprintf(&output, "[exit status %d]\n", exit.ExitCode())
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
+
+// checkGolden compares output with testdata/name.golden
+func checkGolden(t *testing.T, name, output string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != string(want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", path, output, want)
+	}
+}
+
+func TestConvertGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"convert-rpn-arithmetic", []string{"convert", "-to=rpn", "(3+4)*2"}},
+		{"convert-rpn-function", []string{"convert", "-to=rpn", "1 + sqrt(4)"}},
+		{"convert-rpn-nested-functions", []string{"convert", "-to=rpn", "sqrt(sqrt(16) + 5) * -sin(0)"}},
+		{"convert-rpn-arguments", []string{"convert", "-to=rpn", "atan2(1, -1) + max(1, 2) + clamp(5, 0, 1)"}},
+		{"convert-rpn-checked", []string{"convert", "-to=rpn", "5! + nCr(5, 2) - nPr(5, 2)"}},
+		{"convert-rpn-integer", []string{"convert", "-to=rpn", "gcd(12, 18) * roundN(3.14159, 2)"}},
+		{"convert-rpn-error", []string{"convert", "-to=rpn", "sqrt(1, 2)"}},
+		{"convert-infix-arithmetic", []string{"convert", "-to=infix", "3 4 + 2 *"}},
+		{"convert-infix-function", []string{"convert", "-to=infix", "1 4 sqrt +"}},
+		{"convert-infix-factorial", []string{"convert", "-to=infix", "5 !"}},
+		{"convert-infix-checked", []string{"convert", "-to=infix", "2 3 + ! 5 2 nCr * 5 2 nPr -"}},
+		{"convert-infix-arguments", []string{"convert", "-to=infix", "1 1 atan2 1 2 max + 5 0 1 clamp +"}},
+		{"convert-infix-error", []string{"convert", "-to=infix", "1 +"}},
+		{"convert-usage", []string{"convert", "-to=latex", "1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkGolden(t, test.name, runRPN(t, test.args...))
+		})
+	}
+}
+
+func TestEvalGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"eval-arithmetic", []string{"eval", "3 4 + 2 *"}},
+		{"eval-several-values", []string{"eval", "1 2 3 +"}},
+		{"eval-precision-degrees", []string{"eval", "-precision=4", "-mode=deg", "1 1 atan2"}},
+		{"eval-checked", []string{"eval", "5 ! 5 2 nCr 5 2 nPr"}},
+		{"eval-hex", []string{"eval", "-mode=hex", "255"}},
+		{"eval-error", []string{"eval", "2.5 !"}},
+		{"eval-underflow", []string{"eval", "1 +"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkGolden(t, test.name, runRPN(t, test.args...))
+		})
+	}
+}
+
+func TestMatchGolden(t *testing.T) {
+	a, b := filepath.Join("testdata", "match-a.txt"), filepath.Join("testdata", "match-b.txt")
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+	}{
+		{"match-file", "", []string{"match", "-query=guide", a}},
+		{"match-files", "", []string{"match", "-query=python OR go", a, b}},
+		{"match-stdin", "go guide\njava guide\nrust\n", []string{"match", "-query=guide AND NOT java"}},
+		{"match-pattern", "", []string{"match", "-query=/ru.t/ OR py*", a}},
+		{"match-no-lines", "", []string{"match", "-query=cobol", a}},
+		{"match-bad-query", "", []string{"match", "-query=(guide", a}},
+		{"match-missing-file", "", []string{"match", "-query=guide", filepath.Join("testdata", "missing.txt")}},
+		{"match-usage", "", []string{"match", a}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkGolden(t, test.name, runRPNWithInput(t, test.input, test.args...))
+		})
+	}
+}
+
+// TestConvertRoundTrip converts infix to RPN and back, and evaluates both
+// forms through the commands
+func TestConvertRoundTrip(t *testing.T) {
+	for _, infix := range []string{"1 + sqrt(4)", "5!", "nCr(5, 2) * 2", "atan2(1, 1)", "max(1, 2) ^ 2"} {
+		t.Run(infix, func(t *testing.T) {
+			rpn := runRPN(t, "convert", "-to=rpn", infix)
+			back := runRPN(t, "convert", "-to=infix", rpn)
+			if again := runRPN(t, "convert", "-to=rpn", back); again != rpn {
+				t.Errorf("%q converts to %q, %q and back to %q", infix, rpn, back, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+// commandHelp describes the REPL commands
+const commandHelp = `:clear          empty the stack
+:undo           undo the last line, :clear or :mode
+:mode [mode]    show the modes, or set deg, rad, dec, hex, oct, bin or eng
+:vars           list the values named with "as"
+:history        list the lines entered, !n runs line n again and !! the last
+:help [op]      show this help, or describe an operator
+:quit           leave, as does end of input`
+
+// repl is an interactive session on a calculator
+type repl struct {
+	calc *number.RPNCalculator
+	out  io.Writer
+
+	// history holds the lines entered, undo the state before each line
+	// that changed the calculator
+	history []string
+	undo    []number.State
+}
+
+// run reads lines from in until it ends or :quit
+func (r *repl) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if isRecall(line) {
+			recalled, err := r.recall(line)
+			if err != nil {
+				fmt.Fprintf(r.out, "Error: %v\n", err)
+				continue
+			}
+			line = recalled
+			fmt.Fprintln(r.out, line)
+		}
+		r.history = append(r.history, line)
+
+		if strings.HasPrefix(line, ":") {
+			quit, err := r.command(strings.Fields(line[1:]))
+			if err != nil {
+				fmt.Fprintf(r.out, "Error: %v\n", err)
+			}
+			if quit {
+				return nil
+			}
+			continue
+		}
+		if err := r.evaluate(line); err != nil {
+			fmt.Fprintf(r.out, "Error: %v\n", err)
+		}
+		r.printStack()
+	}
+}
+
+// isRecall reports whether line recalls a history line, being !! or !n.
+// Other lines starting with !, like the factorial or !=, go to the
+// calculator
+func isRecall(line string) bool {
+	digits, ok := strings.CutPrefix(line, "!")
+	return line == "!!" || (ok && digits != "" && strings.Trim(digits, "0123456789") == "")
+}
+
+// recall returns the history line !n or !! refers to
+func (r *repl) recall(line string) (string, error) {
+	if len(r.history) == 0 {
+		return "", fmt.Errorf("history is empty")
+	}
+	if line == "!!" {
+		return r.history[len(r.history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(r.history) {
+		return "", fmt.Errorf("no line %s in history", line[1:])
+	}
+	return r.history[n-1], nil
+}
+
+// evaluate runs the tokens of a line on the stack. A token naming a value
+// labeled with as pushes it. A failing line leaves the calculator as it
+// was before it
+func (r *repl) evaluate(line string) error {
+	before := r.calc.Snapshot()
+	tokens := number.Tokenize(line)
+	for _, token := range tokens {
+		err := r.calc.Evaluate(token.Value)
+		var unknown *engine.ErrUnknownToken
+		if errors.As(err, &unknown) {
+			if value, ok := r.calc.Label(token.Value); ok {
+				r.calc.Push(value)
+				err = nil
+			}
+		}
+		if err != nil {
+			r.calc.Restore(before)
+			column := utf8.RuneCountInString(line[:token.Pos]) + 1
+			return &number.PositionError{Token: token, Column: column, Err: err}
+		}
+	}
+	if last := tokens[len(tokens)-1]; last.Value == "as" {
+		r.calc.Restore(before)
+		column := utf8.RuneCountInString(line[:last.Pos]) + 1
+		return &number.PositionError{Token: last, Column: column, Err: fmt.Errorf("as requires a name")}
+	}
+	r.undo = append(r.undo, before)
+	return nil
+}
+
+// command runs a colon command, reporting whether the session ends
+func (r *repl) command(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, fmt.Errorf("missing command, :help lists them")
+	}
+	switch name, args := args[0], args[1:]; name {
+	case "clear":
+		r.undo = append(r.undo, r.calc.Snapshot())
+		r.calc.Clear()
+		r.printStack()
+	case "undo":
+		if len(r.undo) == 0 {
+			return false, fmt.Errorf("nothing to undo")
+		}
+		r.calc.Restore(r.undo[len(r.undo)-1])
+		r.undo = r.undo[:len(r.undo)-1]
+		r.printStack()
+	case "mode":
+		if len(args) == 0 {
+			r.printModes()
+			return false, nil
+		}
+		return false, r.setMode(args[0])
+	case "vars":
+		labels := r.calc.Labels()
+		for _, name := range slices.Sorted(maps.Keys(labels)) {
+			fmt.Fprintf(r.out, "%s = %s\n", name, r.calc.Format(labels[name]))
+		}
+	case "history":
+		for i, line := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, line)
+		}
+	case "help":
+		if len(args) == 0 {
+			fmt.Fprintln(r.out, "Enter RPN tokens, like 3 4 + 2 *, or a command:")
+			fmt.Fprintln(r.out, commandHelp)
+			return false, nil
+		}
+		help, err := number.Help(args[0])
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(r.out, help)
+	case "quit", "q":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command :%s, :help lists them", name)
+	}
+	return false, nil
+}
+
+// setMode switches the angle mode or the display base
+func (r *repl) setMode(mode string) error {
+	before := r.calc.Snapshot()
+	if err := setMode(r.calc, mode); err != nil {
+		return err
+	}
+	r.undo = append(r.undo, before)
+	r.printModes()
+	return nil
+}
+
+// printModes shows the angle mode and display base
+func (r *repl) printModes() {
+	fmt.Fprintf(r.out, "Modes: %s\n", strings.Join(modes(r.calc), " "))
+}
+
+// printStack shows the stack, bottom first
+func (r *repl) printStack() {
+	values := r.calc.Values()
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = r.calc.Format(value)
+	}
+	fmt.Fprintf(r.out, "Stack: [%s]\n", strings.Join(formatted, ", "))
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestREPLGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+	}{
+		{"repl-stack", []string{"repl"}, "3 4\n+\n2 *\n\n"},
+		{"repl-undo", []string{"repl"}, "1 2\n3 +\n:undo\n:clear\n:undo\n:undo\n:undo\n:undo\n"},
+		{"repl-errors", []string{"repl"}, "1 2\n3 + foo\n1 as\n+ + +\n:\n:bogus\n"},
+		{"repl-history", []string{"repl"}, "!!\n1 2 +\n:clear\n!1\n!!\n!9\n!x\n:history\n"},
+		{"repl-factorial", []string{"repl"}, "5\n!\n3\n!=\n!\n!1\n"},
+		{"repl-modes", []string{"repl"}, ":mode\n:mode deg\n1 1 atan2\n2 90 polar->rect\n:mode hex\n:mode\n:undo\n:mode\n:mode grad\n"},
+		{"repl-flags", []string{"repl", "-precision=4", "-mode=deg,hex"}, "1 1 atan2\n255\n1 3 /\n"},
+		{"repl-vars", []string{"repl"}, "2 as width 3 as height *\nwidth height +\n:vars\n"},
+		{"repl-help", []string{"repl"}, ":help\n:help atan2\n:help bogus\n"},
+		{"repl-quit", []string{"repl"}, "1 2 +\n:quit\n4 5 +\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkGolden(t, test.name, runRPNWithInput(t, test.input, test.args...))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/document"
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+// storedKinds maps the suffixes of the files validate checks to the kind
+// of expression they store by name, in the JSON format of Formulas and of
+// QueryFileLoader
+var storedKinds = []struct {
+	suffix  string
+	kind    string
+	compile func(source string) error
+}{
+	{"formulas.json", "formula", func(source string) error {
+		_, err := number.Compile(source)
+		return err
+	}},
+	{"queries.json", "query", func(source string) error {
+		_, err := document.CompileQuery(source)
+		return err
+	}},
+}
+
+// storedKind returns the entry of storedKinds for a file named
+// formulas.json, queries.json or ending in .formulas.json or .queries.json
+func storedKind(path string) (int, bool) {
+	base := filepath.Base(path)
+	for i, stored := range storedKinds {
+		if base == stored.suffix || strings.HasSuffix(base, "."+stored.suffix) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// runValidate compiles every formula and query stored in a directory tree
+// and reports every error with its file and line, failing when there is
+// any
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "directory holding formulas.json and queries.json files")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	files, failed, invalid := 0, 0, 0
+	err := filepath.WalkDir(*dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		kind, ok := storedKind(path)
+		if entry.IsDir() || !ok {
+			return nil
+		}
+		files++
+		problems, err := validateFile(path, kind)
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		invalid += len(problems)
+		if len(problems) > 0 {
+			failed++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case files == 0:
+		return fmt.Errorf("no formulas.json or queries.json files in %s", *dir)
+	case invalid > 0:
+		return fmt.Errorf("%d errors in %d of %d files", invalid, failed, files)
+	}
+	fmt.Printf("%d files valid\n", files)
+	return nil
+}
+
+// validateFile compiles every expression of a stored file, returning its
+// problems as path:line: message
+func validateFile(path string, kind int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stored := storedKinds[kind]
+	problems := []string{}
+	report := func(offset int64, format string, args ...any) {
+		line := 1 + bytes.Count(data[:offset], []byte("\n"))
+		problems = append(problems, fmt.Sprintf("%s:%d: %s", path, line, fmt.Sprintf(format, args...)))
+	}
+
+	// Decoding token by token keeps the offset of every name, and finds
+	// the duplicate names decoding into a map would drop silently
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	invalidJSON := func(err error) []string {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			report(syntax.Offset, "invalid JSON: %v", err)
+		} else {
+			report(decoder.InputOffset(), "invalid %s file: %v", stored.kind, err)
+		}
+		return problems
+	}
+	if token, err := decoder.Token(); err != nil {
+		return invalidJSON(err), nil
+	} else if token != json.Delim('{') {
+		report(decoder.InputOffset(), "invalid %s file: expected an object of names", stored.kind)
+		return problems, nil
+	}
+
+	lines := make(map[string]int64)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return invalidJSON(err), nil
+		}
+		name := token.(string)
+		offset := decoder.InputOffset()
+		var source string
+		if err := decoder.Decode(&source); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return invalidJSON(err), nil
+			}
+			report(offset, "%s %s: expected a string", stored.kind, name)
+			continue
+		}
+
+		if first, ok := lines[name]; ok {
+			report(offset, "%s %s: duplicate name, first on line %d", stored.kind, name, 1+bytes.Count(data[:first], []byte("\n")))
+		} else {
+			lines[name] = offset
+		}
+		if name == "" {
+			report(offset, "%s name must not be empty", stored.kind)
+			continue
+		}
+		if err := stored.compile(source); err != nil {
+			report(offset, "%s %s: %v", stored.kind, name, err)
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return invalidJSON(err), nil
+	}
+	return problems, nil
+}
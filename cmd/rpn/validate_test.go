@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateGolden(t *testing.T) {
+	dir := filepath.Join("testdata", "validate")
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"validate-valid", []string{"validate", "-dir", filepath.Join(dir, "valid")}},
+		{"validate-invalid", []string{"validate", "-dir", filepath.Join(dir, "invalid")}},
+		{"validate-tree", []string{"validate", "-dir", dir}},
+		{"validate-no-files", []string{"validate", "-dir", filepath.Join(dir, "none")}},
+		{"validate-missing-dir", []string{"validate", "-dir", filepath.Join(dir, "missing")}},
+		{"validate-usage", []string{"validate", "extra"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkGolden(t, test.name, runRPN(t, test.args...))
+		})
+	}
+}
+
+func TestStoredKind(t *testing.T) {
+	tests := []struct {
+		path string
+		kind string
+	}{
+		{"formulas.json", "formula"},
+		{"dir/pricing.formulas.json", "formula"},
+		{"queries.json", "query"},
+		{"dir/search.queries.json", "query"},
+		{"dir/myformulas.json", ""},
+		{"dir/formulas.json.bak", ""},
+		{"dir/notes.json", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			kind, ok := storedKind(test.path)
+			got := ""
+			if ok {
+				got = storedKinds[kind].kind
+			}
+			if got != test.kind {
+				t.Errorf("storedKind(%s) = %q, want %q", test.path, got, test.kind)
+			}
+		})
+	}
+}
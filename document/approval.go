@@ -0,0 +1,132 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrApprovalPending is returned for a change to a saved query that the
+// approval hook did not approve yet. The change is kept as a draft until
+// Approve activates it or Reject discards it
+var ErrApprovalPending = errors.New("query change is pending approval")
+
+// QueryChange is a create or update of a saved query submitted to the
+// approval hook, and a draft while it waits for approval
+type QueryChange struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	// Previous is the active query the change replaces, empty when it
+	// creates the name
+	Previous string    `json:"previous,omitempty"`
+	Time     time.Time `json:"time"`
+	Revision
+}
+
+// ApprovalHook reviews every create and update of a saved query before it
+// becomes active, so embedding applications can require a review of
+// business rules. Returning true activates the change at once, false keeps
+// it as a draft and an error rejects it
+type ApprovalHook interface {
+	Review(change QueryChange) (bool, error)
+}
+
+// ApprovalHookFunc adapts a function to the ApprovalHook interface
+type ApprovalHookFunc func(change QueryChange) (bool, error)
+
+// Review calls the function
+func (fn ApprovalHookFunc) Review(change QueryChange) (bool, error) {
+	return fn(change)
+}
+
+// SetApprovalHook sets the hook reviewing creates and updates, nil, the
+// default, activates every change at once. Deletions are not reviewed
+func (store *QueryStore) SetApprovalHook(hook ApprovalHook) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.hook = hook
+}
+
+// review submits a change to the approval hook, returning nil when it may
+// take effect. It runs the hook without the store lock held, so hooks may
+// take their time or read the store
+func (store *QueryStore) review(name string, compiled *Query, rev Revision, now time.Time) error {
+	store.mu.RLock()
+	hook := store.hook
+	change := QueryChange{Name: name, Query: compiled.String(), Time: now, Revision: rev}
+	if current, ok := store.queries[name]; ok {
+		change.Previous = current.String()
+	}
+	draft, drafted := store.drafts[name]
+	store.mu.RUnlock()
+
+	if hook == nil || change.Query == change.Previous {
+		return nil
+	}
+	if drafted && draft.Query == change.Query {
+		// Submitting the pending draft again, as reloading a file does,
+		// does not ask for another review
+		return ErrApprovalPending
+	}
+	approved, err := hook.Review(change)
+	if err != nil {
+		return fmt.Errorf("query %s rejected: %w", name, err)
+	}
+	if approved {
+		return nil
+	}
+
+	store.mu.Lock()
+	store.drafts[name] = change
+	store.mu.Unlock()
+	return ErrApprovalPending
+}
+
+// Drafts returns the changes waiting for approval, by name
+func (store *QueryStore) Drafts() []QueryChange {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	drafts := make([]QueryChange, 0, len(store.drafts))
+	for _, draft := range store.drafts {
+		drafts = append(drafts, draft)
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].Name < drafts[j].Name })
+	return drafts
+}
+
+// Draft returns the change to name waiting for approval
+func (store *QueryStore) Draft(name string) (QueryChange, bool) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	draft, ok := store.drafts[name]
+	return draft, ok
+}
+
+// Approve activates the draft of name, recording it in the history with
+// the revision it was submitted with
+func (store *QueryStore) Approve(name string) (QueryVersion, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	draft, ok := store.drafts[name]
+	if !ok {
+		return QueryVersion{}, fmt.Errorf("query %s has no draft", name)
+	}
+	compiled, err := CompileQuery(draft.Query)
+	if err != nil {
+		return QueryVersion{}, fmt.Errorf("query %s: %w", name, err)
+	}
+	delete(store.drafts, name)
+	return store.saveLocked(name, compiled, draft.Revision, time.Now()), nil
+}
+
+// Reject discards the draft of name, leaving the active query unchanged
+func (store *QueryStore) Reject(name string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.drafts[name]; !ok {
+		return fmt.Errorf("query %s has no draft", name)
+	}
+	delete(store.drafts, name)
+	return nil
+}
@@ -0,0 +1,199 @@
+package document
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reviewHook approves changes by admin, rejects queries mentioning cobol
+// and drafts everything else, recording every change it reviews
+type reviewHook struct {
+	reviewed []QueryChange
+}
+
+func (hook *reviewHook) Review(change QueryChange) (bool, error) {
+	hook.reviewed = append(hook.reviewed, change)
+	if strings.Contains(change.Query, "cobol") {
+		return false, errors.New("cobol is not allowed")
+	}
+	return change.Author == "admin", nil
+}
+
+func TestApprovalHook(t *testing.T) {
+	admin := Revision{Author: "admin"}
+	bob := Revision{Author: "bob", Comment: "widen"}
+	store := NewQueryStore()
+	hook := &reviewHook{}
+	store.SetApprovalHook(hook)
+
+	// Every step changes "q" and checks the active query, the draft and
+	// the number of reviews so far
+	steps := []struct {
+		name     string
+		change   func() error
+		wantErr  string
+		pending  bool
+		active   string
+		draft    string
+		reviewed int
+	}{
+		{"approved create", func() error { _, err := store.SaveRevision("q", "go", admin); return err },
+			"", false, "go", "", 1},
+		{"unchanged save", func() error { _, err := store.SaveRevision("q", "go", bob); return err },
+			"", false, "go", "", 1},
+		{"drafted edit", func() error { _, err := store.SaveRevision("q", "go OR rust", bob); return err },
+			"", true, "go", "go OR rust", 2},
+		{"resubmitted draft", func() error { _, err := store.SaveRevision("q", "go OR rust", bob); return err },
+			"", true, "go", "go OR rust", 2},
+		{"approve", func() error { _, err := store.Approve("q"); return err },
+			"", false, "go OR rust", "", 2},
+		{"approve without a draft", func() error { _, err := store.Approve("q"); return err },
+			"query q has no draft", false, "go OR rust", "", 2},
+		{"rejected edit", func() error { _, err := store.SaveRevision("q", "cobol", bob); return err },
+			"query q rejected: cobol is not allowed", false, "go OR rust", "", 3},
+		{"drafted again", func() error { _, err := store.SaveRevision("q", "java", bob); return err },
+			"", true, "go OR rust", "java", 4},
+		{"reject", func() error { return store.Reject("q") },
+			"", false, "go OR rust", "", 4},
+		{"reject without a draft", func() error { return store.Reject("q") },
+			"query q has no draft", false, "go OR rust", "", 4},
+		{"rollback is not reviewed", func() error { _, err := store.Rollback("q", 1, bob); return err },
+			"", false, "go", "", 4},
+		{"delete is not reviewed", func() error { store.DeleteRevision("q", bob); return nil },
+			"", false, "", "", 4},
+	}
+	for _, step := range steps {
+		err := step.change()
+		switch {
+		case step.pending:
+			if !errors.Is(err, ErrApprovalPending) {
+				t.Errorf("%s: error = %v, want ErrApprovalPending", step.name, err)
+			}
+		case step.wantErr != "":
+			if err == nil || err.Error() != step.wantErr {
+				t.Errorf("%s: error = %v, want %q", step.name, err, step.wantErr)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", step.name, err)
+		}
+		active := ""
+		if current, ok := store.Get("q"); ok {
+			active = current.String()
+		}
+		if active != step.active {
+			t.Errorf("%s: active query = %q, want %q", step.name, active, step.active)
+		}
+		draft, ok := store.Draft("q")
+		if ok != (step.draft != "") || draft.Query != step.draft {
+			t.Errorf("%s: draft = %+v, %t, want %q", step.name, draft, ok, step.draft)
+		}
+		if len(hook.reviewed) != step.reviewed {
+			t.Errorf("%s: %d reviews, want %d", step.name, len(hook.reviewed), step.reviewed)
+		}
+	}
+
+	first, edit := hook.reviewed[0], hook.reviewed[1]
+	if first.Previous != "" || first.Revision != admin || first.Time.IsZero() {
+		t.Errorf("create reviewed as %+v", first)
+	}
+	if edit.Name != "q" || edit.Previous != "go" || edit.Query != "go OR rust" || edit.Revision != bob {
+		t.Errorf("edit reviewed as %+v", edit)
+	}
+	// The approved draft went into the history with its submitted revision
+	if history := store.History("q"); len(history) < 2 || history[1].Query != "go OR rust" || history[1].Revision != bob {
+		t.Errorf("History() = %+v", history)
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	store := NewQueryStore()
+	store.SetApprovalHook(ApprovalHookFunc(func(QueryChange) (bool, error) { return false, nil }))
+	for _, name := range []string{"c", "a", "b"} {
+		if _, err := store.SaveRevision(name, name, Revision{}); !errors.Is(err, ErrApprovalPending) {
+			t.Fatalf("SaveRevision(%s) error = %v", name, err)
+		}
+	}
+	names := []string{}
+	for _, draft := range store.Drafts() {
+		names = append(names, draft.Name)
+	}
+	if strings.Join(names, " ") != "a b c" {
+		t.Errorf("Drafts() names = %v, want sorted", names)
+	}
+	if got := store.Names(); len(got) != 0 {
+		t.Errorf("drafted queries are active: %v", got)
+	}
+
+	// Without a hook every change is active at once
+	store.SetApprovalHook(nil)
+	if _, err := store.SaveRevision("d", "go", Revision{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Draft("d"); ok {
+		t.Error("a change without a hook was drafted")
+	}
+}
+
+func TestQueryFileLoaderApproval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.json")
+	store := NewQueryStore()
+	hook := &reviewHook{}
+	store.SetApprovalHook(hook)
+	loader := NewQueryFileLoader(path, store)
+
+	// The file loads without an author, so the hook drafts every change
+	// it does not reject
+	steps := []struct {
+		name     string
+		data     string
+		wantErr  string
+		sources  map[string]string
+		drafts   []string
+		reviewed int
+	}{
+		{"drafted", `{"a": "go", "b": "rust"}`, "", map[string]string{}, []string{"a", "b"}, 2},
+		{"reload", `{"a": "go", "b": "rust"}`, "", map[string]string{}, []string{"a", "b"}, 2},
+		{"rejected", `{"a": "go", "b": "rust", "c": "cobol"}`, "query c rejected: cobol is not allowed",
+			map[string]string{}, []string{"a", "b"}, 3},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			writeWatchedFile(t, path, step.data)
+			err := loader.Load()
+			if step.wantErr == "" && err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if step.wantErr != "" && (err == nil || strings.TrimPrefix(err.Error(), path+": ") != step.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, step.wantErr)
+			}
+			if got := loader.Sources(); !maps.Equal(got, step.sources) {
+				t.Errorf("Sources() = %v, want %v", got, step.sources)
+			}
+			names := []string{}
+			for _, draft := range store.Drafts() {
+				names = append(names, draft.Name)
+			}
+			if strings.Join(names, " ") != strings.Join(step.drafts, " ") {
+				t.Errorf("drafts = %v, want %v", names, step.drafts)
+			}
+			if len(hook.reviewed) != step.reviewed {
+				t.Errorf("%d reviews, want %d", len(hook.reviewed), step.reviewed)
+			}
+		})
+	}
+
+	// An approved draft goes live and the next load keeps it
+	if _, err := store.Approve("a"); err != nil {
+		t.Fatal(err)
+	}
+	writeWatchedFile(t, path, `{"a": "go", "b": "rust"}`)
+	if err := loader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loader.Sources(); !maps.Equal(got, map[string]string{"a": "go"}) {
+		t.Errorf("Sources() after Approve = %v", got)
+	}
+}
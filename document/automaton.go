@@ -0,0 +1,323 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxFuzzyEdits caps the edits a fuzzy term may ask for, beyond two almost
+// every short term in the dictionary matches
+const maxFuzzyEdits = 2
+
+// nfa is a nondeterministic automaton over runes. State sets are sorted,
+// free of duplicates and already closed over empty transitions
+type nfa interface {
+	start() []int
+	step(states []int, r rune) []int
+	accepts(states []int) bool
+}
+
+// deadState is the DFA state no input leads out of
+const deadState = 0
+
+// dfa determinizes an nfa by subset construction, lazily, so only the
+// states and transitions the dictionary walk reaches are ever built. A dfa
+// caches as it goes and must not be shared between goroutines
+type dfa struct {
+	nfa         nfa
+	sets        [][]int
+	ids         map[string]int
+	transitions []map[rune]int
+	accepting   []bool
+	initial     int
+}
+
+// newDFA determinizes automaton
+func newDFA(automaton nfa) *dfa {
+	d := &dfa{nfa: automaton, ids: make(map[string]int)}
+	d.state(nil)
+	d.initial = d.state(automaton.start())
+	return d
+}
+
+// state returns the DFA state of a set of NFA states, adding it when new
+func (d *dfa) state(set []int) int {
+	key := fmt.Sprint(set)
+	if id, ok := d.ids[key]; ok {
+		return id
+	}
+	id := len(d.sets)
+	d.ids[key] = id
+	d.sets = append(d.sets, set)
+	d.transitions = append(d.transitions, make(map[rune]int))
+	d.accepting = append(d.accepting, len(set) > 0 && d.nfa.accepts(set))
+	return id
+}
+
+// step returns the state reached from state on r
+func (d *dfa) step(state int, r rune) int {
+	if state == deadState {
+		return deadState
+	}
+	if next, ok := d.transitions[state][r]; ok {
+		return next
+	}
+	next := d.state(d.nfa.step(d.sets[state], r))
+	d.transitions[state][r] = next
+	return next
+}
+
+// accepts reports whether state is accepting
+func (d *dfa) accepts(state int) bool {
+	return d.accepting[state]
+}
+
+// matches reports whether the automaton accepts term
+func (d *dfa) matches(term string) bool {
+	state := d.initial
+	for _, r := range term {
+		if state = d.step(state, r); state == deadState {
+			return false
+		}
+	}
+	return d.accepts(state)
+}
+
+// stateSet collects NFA states into a sorted set
+type stateSet map[int]bool
+
+// sorted returns the states in ascending order
+func (set stateSet) sorted() []int {
+	states := make([]int, 0, len(set))
+	for state := range set {
+		states = append(states, state)
+	}
+	sort.Ints(states)
+	return states
+}
+
+// regexNFA runs a compiled regular expression program, its states are
+// instruction indexes
+type regexNFA struct {
+	prog *syntax.Prog
+}
+
+// newRegexNFA compiles a regular expression that must match whole terms
+func newRegexNFA(pattern string) (*regexNFA, error) {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range prog.Inst {
+		if inst.Op == syntax.InstEmptyWidth {
+			return nil, fmt.Errorf("anchors and word boundaries are not supported, patterns always match whole terms")
+		}
+	}
+	return &regexNFA{prog: prog}, nil
+}
+
+// closure adds pc and every instruction reachable from it without input
+func (automaton *regexNFA) closure(set stateSet, pc uint32) {
+	if set[int(pc)] {
+		return
+	}
+	set[int(pc)] = true
+	inst := automaton.prog.Inst[pc]
+	switch inst.Op {
+	case syntax.InstAlt, syntax.InstAltMatch:
+		automaton.closure(set, inst.Out)
+		automaton.closure(set, inst.Arg)
+	case syntax.InstNop, syntax.InstCapture:
+		automaton.closure(set, inst.Out)
+	}
+}
+
+func (automaton *regexNFA) start() []int {
+	set := stateSet{}
+	automaton.closure(set, uint32(automaton.prog.Start))
+	return set.sorted()
+}
+
+func (automaton *regexNFA) step(states []int, r rune) []int {
+	set := stateSet{}
+	for _, pc := range states {
+		inst := automaton.prog.Inst[pc]
+		matched := false
+		switch inst.Op {
+		case syntax.InstRune:
+			matched = inst.MatchRune(r)
+		case syntax.InstRune1:
+			matched = r == inst.Rune[0]
+		case syntax.InstRuneAny:
+			matched = true
+		case syntax.InstRuneAnyNotNL:
+			matched = r != '\n'
+		}
+		if matched {
+			automaton.closure(set, inst.Out)
+		}
+	}
+	return set.sorted()
+}
+
+func (automaton *regexNFA) accepts(states []int) bool {
+	for _, pc := range states {
+		if automaton.prog.Inst[pc].Op == syntax.InstMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// levenshteinNFA accepts the terms within a number of edits of a term,
+// counting insertions, deletions, substitutions and adjacent transpositions
+// like editDistance. State (i, e) has matched i runes of the term using e
+// edits, the states after it are halfway through a transposition at i
+type levenshteinNFA struct {
+	term     []rune
+	maxEdits int
+}
+
+func (automaton *levenshteinNFA) index(i, edits int, transposing bool) int {
+	state := i*(automaton.maxEdits+1) + edits
+	if transposing {
+		state += (len(automaton.term) + 1) * (automaton.maxEdits + 1)
+	}
+	return state
+}
+
+func (automaton *levenshteinNFA) position(state int) (int, int, bool) {
+	width := automaton.maxEdits + 1
+	normal := (len(automaton.term) + 1) * width
+	transposing := state >= normal
+	if transposing {
+		state -= normal
+	}
+	return state / width, state % width, transposing
+}
+
+// closure adds (i, edits) and the states reached by deleting term runes
+func (automaton *levenshteinNFA) closure(set stateSet, i, edits int) {
+	for ; i <= len(automaton.term) && edits <= automaton.maxEdits; i, edits = i+1, edits+1 {
+		set[automaton.index(i, edits, false)] = true
+	}
+}
+
+func (automaton *levenshteinNFA) start() []int {
+	set := stateSet{}
+	automaton.closure(set, 0, 0)
+	return set.sorted()
+}
+
+func (automaton *levenshteinNFA) step(states []int, r rune) []int {
+	term := automaton.term
+	set := stateSet{}
+	for _, state := range states {
+		i, edits, transposing := automaton.position(state)
+		if transposing {
+			if term[i] == r {
+				automaton.closure(set, i+2, edits)
+			}
+			continue
+		}
+		if i < len(term) && term[i] == r {
+			automaton.closure(set, i+1, edits)
+		}
+		if edits == automaton.maxEdits {
+			continue
+		}
+		// Insertion, then substitution and the start of a transposition
+		automaton.closure(set, i, edits+1)
+		if i < len(term) {
+			automaton.closure(set, i+1, edits+1)
+		}
+		if i+1 < len(term) && term[i+1] == r {
+			set[automaton.index(i, edits+1, true)] = true
+		}
+	}
+	return set.sorted()
+}
+
+func (automaton *levenshteinNFA) accepts(states []int) bool {
+	for _, state := range states {
+		if i, _, transposing := automaton.position(state); i == len(automaton.term) && !transposing {
+			return true
+		}
+	}
+	return false
+}
+
+// isRegexTerm reports whether a query token is a /regex/ term
+func isRegexTerm(token string) bool {
+	return len(token) > 2 && strings.HasPrefix(token, "/") && strings.HasSuffix(token, "/")
+}
+
+// compileTermPattern compiles a query term written as a pattern into an
+// automaton, reporting false for plain terms:
+//
+//   - /regex/ matches the whole lowercase term against a regular
+//     expression, which cannot contain spaces or parentheses as they split
+//     query tokens
+//   - term~ and term~N match the terms within N edits, by default as many
+//     as typo correction allows for the term's length
+//   - a term with * or ? matches any run of runes or a single rune there,
+//     a backslash makes the next rune literal
+func compileTermPattern(token string) (nfa, bool, error) {
+	if isRegexTerm(token) {
+		automaton, err := newRegexNFA(token[1 : len(token)-1])
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid pattern %s: %w", token, err)
+		}
+		return automaton, true, nil
+	}
+
+	if at := strings.LastIndex(token, "~"); at > 0 {
+		term, suffix := token[:at], token[at+1:]
+		edits := fuzziness(term)
+		if suffix != "" {
+			parsed, err := strconv.Atoi(suffix)
+			if err != nil {
+				return nil, false, nil
+			}
+			if parsed < 0 || parsed > maxFuzzyEdits {
+				return nil, true, fmt.Errorf("invalid pattern %s: edits must be between 0 and %d", token, maxFuzzyEdits)
+			}
+			edits = parsed
+		}
+		return &levenshteinNFA{term: []rune(term), maxEdits: edits}, true, nil
+	}
+
+	if !strings.ContainsAny(token, "*?") {
+		return nil, false, nil
+	}
+	var pattern strings.Builder
+	escaped := false
+	for _, r := range token {
+		switch {
+		case escaped:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			pattern.WriteString("(?s:.*)")
+		case r == '?':
+			pattern.WriteString("(?s:.)")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	automaton, err := newRegexNFA(pattern.String())
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid pattern %s: %w", token, err)
+	}
+	return automaton, true, nil
+}
@@ -0,0 +1,142 @@
+package document
+
+import (
+	"slices"
+	"testing"
+)
+
+// automatonTerms are dictionary terms for the pattern tests, sorted
+var automatonTerms = []string{"apple", "apply", "banana", "band", "bandana", "can", "cane", "cat", "caño", "guide", "guides", "pyhton", "python", "typhon", "x*y"}
+
+// dfaAccepts runs a term through an automaton
+func dfaAccepts(automaton *dfa, term string) bool {
+	state := automaton.initial
+	for _, r := range term {
+		state = automaton.step(state, r)
+	}
+	return automaton.accepts(state)
+}
+
+func TestLevenshteinAutomatonMatchesEditDistance(t *testing.T) {
+	candidates := append([]string{"", "a", "ab", "ba", "abc", "acb", "bca", "abcd", "xabc", "axc"}, automatonTerms...)
+	for _, term := range []string{"", "a", "abc", "python", "caño"} {
+		for edits := 0; edits <= maxFuzzyEdits; edits++ {
+			automaton := newDFA(&levenshteinNFA{term: []rune(term), maxEdits: edits})
+			for _, candidate := range candidates {
+				want := editDistance(term, candidate) <= edits
+				if got := dfaAccepts(automaton, candidate); got != want {
+					t.Errorf("%s~%d accepts %q = %v, want %v", term, edits, candidate, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCompileTermPattern(t *testing.T) {
+	dict := newTermDictionary(automatonTerms)
+	tests := []struct {
+		token   string
+		pattern bool
+		want    []string
+	}{
+		{"python", false, nil},
+		{"app*", true, []string{"apple", "apply"}},
+		{"*ana", true, []string{"banana", "bandana"}},
+		{"ca?", true, []string{"can", "cat"}},
+		{"ca??", true, []string{"cane", "caño"}},
+		{"x\\*y", true, []string{"x*y"}},
+		{"/gu.*s/", true, []string{"guides"}},
+		{"/ban(an)+a/", true, []string{"banana"}},
+		{"/ca[nt]/", true, []string{"can", "cat"}},
+		{"python~", true, []string{"pyhton", "python", "typhon"}},
+		{"python~0", true, []string{"python"}},
+		{"python~1", true, []string{"pyhton", "python"}},
+		{"cat~", true, []string{"can", "cat"}},
+		{"guide~x", false, nil},
+		{"/", false, nil},
+		{"//", false, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.token, func(t *testing.T) {
+			automaton, pattern, err := compileTermPattern(test.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pattern != test.pattern {
+				t.Fatalf("compileTermPattern(%q) pattern = %v, want %v", test.token, pattern, test.pattern)
+			}
+			if !pattern {
+				return
+			}
+			got := []string{}
+			dict.intersect(newDFA(automaton), func(term string) bool {
+				got = append(got, term)
+				return true
+			})
+			if !slices.Equal(got, test.want) {
+				t.Errorf("%s matches %v, want %v", test.token, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileTermPatternErrors(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"/a(b/", "invalid pattern /a(b/: error parsing regexp: missing closing ): `a(b`"},
+		{"/^ab/", "invalid pattern /^ab/: anchors and word boundaries are not supported, patterns always match whole terms"},
+		{"/a\\b/", "invalid pattern /a\\b/: anchors and word boundaries are not supported, patterns always match whole terms"},
+		{"python~3", "invalid pattern python~3: edits must be between 0 and 2"},
+		{"python~-1", "invalid pattern python~-1: edits must be between 0 and 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.token, func(t *testing.T) {
+			_, _, err := compileTermPattern(test.token)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("compileTermPattern(%q) error = %v, want %q", test.token, err, test.want)
+			}
+		})
+	}
+}
+
+func TestIntersectStopsEarly(t *testing.T) {
+	dict := newTermDictionary(automatonTerms)
+	automaton, _, err := compileTermPattern("*a*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	dict.intersect(newDFA(automaton), func(term string) bool {
+		got = append(got, term)
+		return len(got) < 3
+	})
+	if want := []string{"apple", "apply", "banana"}; !slices.Equal(got, want) {
+		t.Errorf("intersect() visited %v, want %v", got, want)
+	}
+}
+
+func TestPatternSearch(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range builderTestDocuments {
+		idx.Add(text)
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"gui*", []int{0, 1, 5}},
+		{"/jav./", []int{1, 2}},
+		{"pyhton~1", []int{0, 2, 4}},
+		{"gui* AND NOT /java/", []int{0, 5}},
+		{"zz*", []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := hitIDs(t, idx, test.query); !slices.Equal(got, test.want) {
+				t.Errorf("Search(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package document
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+// backupFormatVersion is bumped whenever the archive layout changes
+const backupFormatVersion = 3
+
+// manifestName is the archive entry listing every other entry's checksum
+const manifestName = "manifest.json"
+
+// backupFiles are the entries an archive holds besides the manifest, each
+// of which the manifest must list with its checksum
+var backupFiles = []string{"config.json", "index.json", "queries.json"}
+
+// maxBackupEntrySize caps the size of a single archive entry, so a damaged
+// or hostile archive cannot make Restore buffer unbounded data
+const maxBackupEntrySize = 1 << 30
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	Version   int               `json:"version"`
+	CreatedAt time.Time         `json:"created_at"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+// indexSnapshot is the archived form of an index, with document texts
+// stored by ID so deleted IDs stay unused after a restore
+type indexSnapshot struct {
+	Docs    []string `json:"docs"`
+	Deleted []int    `json:"deleted"`
+}
+
+// snapshot returns the document texts by ID, the IDs of deleted documents
+// and the sequence number of the last event included
+func (idx *Index) snapshot() ([]string, []int, uint64) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	docs := make([]string, len(idx.docs))
+	deleted := []int{}
+	for id, doc := range idx.docs {
+		if doc == nil {
+			deleted = append(deleted, id)
+			continue
+		}
+		docs[id] = doc.text
+	}
+	return docs, deleted, idx.seq
+}
+
+// backupConfig returns the ingest pipeline and retention of the index as
+// a configuration, without saved queries
+func (idx *Index) backupConfig() Config {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	config := Config{}
+	if idx.pipeline != nil {
+		pipeline := idx.pipeline.Config()
+		config.Pipeline = &pipeline
+	}
+	if idx.retention != 0 {
+		retention := ConfigDuration(idx.retention)
+		config.Retention = &retention
+	}
+	return config
+}
+
+// indexFromSnapshot rebuilds an index from document texts by ID
+func indexFromSnapshot(docs []string, deleted []int) (*Index, error) {
+	builder := NewIndexBuilder()
+	builder.AddAll(docs)
+	built := builder.Build()
+	for _, id := range deleted {
+		if err := built.Delete(id); err != nil {
+			return nil, err
+		}
+	}
+	return built, nil
+}
+
+// Backup writes a gzip compressed tar archive holding the index, its
+// ingest pipeline and retention, the saved queries with their history,
+// drafts and usage, and, unless formulas is nil, the formula file with the
+// history, drafts and usage of its formulas, together with a manifest of
+// SHA-256 checksums
+func Backup(w io.Writer, idx *Index, store *QueryStore, formulas *number.Formulas) error {
+	docs, deleted, _ := idx.snapshot()
+	files := map[string]any{
+		"config.json":  idx.backupConfig(),
+		"index.json":   indexSnapshot{Docs: docs, Deleted: deleted},
+		"queries.json": store.state(),
+	}
+	if formulas != nil {
+		data, err := os.ReadFile(formulas.Path())
+		if err != nil {
+			return err
+		}
+		sources := map[string]string{}
+		if err := json.Unmarshal(data, &sources); err != nil {
+			return fmt.Errorf("%s: invalid formula file: %w", formulas.Path(), err)
+		}
+		files["formulas.json"] = sources
+		files["formula-state.json"] = formulas.State()
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	archive := tar.NewWriter(gzipWriter)
+	manifest := BackupManifest{
+		Version:   backupFormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Checksums: make(map[string]string),
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := json.Marshal(files[name])
+		if err != nil {
+			return err
+		}
+		if err := writeArchiveFile(archive, name, data); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Checksums[name] = hex.EncodeToString(sum[:])
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveFile(archive, manifestName, data); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// writeArchiveFile adds a single file to a tar archive
+func writeArchiveFile(archive *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := archive.Write(data)
+	return err
+}
+
+// Restore reads an archive written by Backup, verifying every checksum
+// before rebuilding the index and query store with the history, drafts and
+// usage of the queries as archived. Formulas archived with them are written
+// to formulasPath and returned with their history, drafts and usage
+// restored but not loaded yet, so a reviewer can be set before the first
+// Load; formulas is nil when the archive holds none or formulasPath is
+// empty. Archives with entries the manifest does not list, duplicate
+// entries or entries larger than maxBackupEntrySize are rejected
+func Restore(r io.Reader, formulasPath string) (*Index, *QueryStore, *number.Formulas, error) {
+	return restore(r, maxBackupEntrySize, formulasPath)
+}
+
+// restore reads an archive like Restore, rejecting entries larger than
+// maxEntrySize
+func restore(r io.Reader, maxEntrySize int64, formulasPath string) (*Index, *QueryStore, *number.Formulas, error) {
+	files, err := readArchive(r, maxEntrySize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	config, err := ParseConfig(files["config.json"])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid config in backup: %w", err)
+	}
+
+	snapshot := indexSnapshot{}
+	if err := json.Unmarshal(files["index.json"], &snapshot); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid index in backup: %w", err)
+	}
+	idx, err := indexFromSnapshot(snapshot.Docs, snapshot.Deleted)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if config.pipeline != nil {
+		idx.SetPipeline(config.pipeline)
+	}
+	if config.Retention != nil {
+		idx.SetRetention(time.Duration(*config.Retention))
+	}
+
+	state := queryStoreState{}
+	if err := json.Unmarshal(files["queries.json"], &state); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid queries in backup: %w", err)
+	}
+	store, err := queryStoreFromState(state)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if _, ok := files["formulas.json"]; !ok || formulasPath == "" {
+		return idx, store, nil, nil
+	}
+	formulas, err := restoreFormulas(files, formulasPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return idx, store, formulas, nil
+}
+
+// restoreFormulas writes the archived formula file to path and restores
+// the state of its formulas
+func restoreFormulas(files map[string][]byte, path string) (*number.Formulas, error) {
+	sources := map[string]string{}
+	if err := json.Unmarshal(files["formulas.json"], &sources); err != nil {
+		return nil, fmt.Errorf("invalid formulas in backup: %w", err)
+	}
+	state := number.FormulaState{}
+	if err := json.Unmarshal(files["formula-state.json"], &state); err != nil {
+		return nil, fmt.Errorf("invalid formula state in backup: %w", err)
+	}
+	data, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	formulas := number.NewFormulas(path)
+	if err := formulas.Restore(state); err != nil {
+		return nil, fmt.Errorf("invalid formula state in backup: %w", err)
+	}
+	return formulas, nil
+}
+
+// readArchive reads the entries of an archive written by Backup, verifying
+// them against the checksums of its manifest
+func readArchive(r io.Reader, maxEntrySize int64) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gzipReader.Close()
+
+	files := make(map[string][]byte)
+	archive := tar.NewReader(gzipReader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		if _, ok := files[header.Name]; ok {
+			return nil, fmt.Errorf("duplicate backup entry %s", header.Name)
+		}
+		if header.Size > maxEntrySize {
+			return nil, fmt.Errorf("backup entry %s exceeds %d bytes", header.Name, maxEntrySize)
+		}
+		var buffer bytes.Buffer
+		// Read one byte past the limit to catch entries larger than their header
+		if _, err := io.Copy(&buffer, io.LimitReader(archive, maxEntrySize+1)); err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		if int64(buffer.Len()) > maxEntrySize {
+			return nil, fmt.Errorf("backup entry %s exceeds %d bytes", header.Name, maxEntrySize)
+		}
+		files[header.Name] = buffer.Bytes()
+	}
+
+	manifest := BackupManifest{}
+	if err := json.Unmarshal(files[manifestName], &manifest); err != nil {
+		return nil, fmt.Errorf("backup manifest missing or invalid: %w", err)
+	}
+	if manifest.Version != backupFormatVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+	for _, name := range backupFiles {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("backup is missing %s", name)
+		}
+		if _, ok := manifest.Checksums[name]; !ok {
+			return nil, fmt.Errorf("backup manifest has no checksum for %s", name)
+		}
+	}
+	// Formulas are optional, but never archived without their state
+	_, hasFormulas := files["formulas.json"]
+	_, hasState := files["formula-state.json"]
+	if hasFormulas != hasState {
+		missing := "formulas.json"
+		if hasFormulas {
+			missing = "formula-state.json"
+		}
+		return nil, fmt.Errorf("backup is missing %s", missing)
+	}
+	for name, data := range files {
+		if name == manifestName {
+			continue
+		}
+		checksum, ok := manifest.Checksums[name]
+		if !ok {
+			return nil, fmt.Errorf("backup entry %s is not in the manifest", name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != checksum {
+			return nil, fmt.Errorf("checksum mismatch for %s", name)
+		}
+	}
+	for name := range manifest.Checksums {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("backup is missing %s", name)
+		}
+	}
+	return files, nil
+}
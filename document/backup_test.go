@@ -0,0 +1,292 @@
+package document
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("python guide")
+	deleted := idx.Add("java guide")
+	idx.Add("rust guide")
+	if err := idx.Delete(deleted); err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := ParsePipeline([]byte(`{"processors": [{"type": "drop", "query": "spam"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.SetPipeline(pipeline)
+	idx.SetRetention(time.Hour)
+	store := NewQueryStore()
+	for _, query := range []string{"python", "python OR rust"} {
+		if _, err := store.SaveRevision("langs", query, Revision{Author: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Search(idx, "langs", SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	store.SetApprovalHook(ApprovalHookFunc(func(QueryChange) (bool, error) { return false, nil }))
+	if err := store.Save("langs", "go OR rust"); !errors.Is(err, ErrApprovalPending) {
+		t.Fatalf("Save() error = %v, want a draft", err)
+	}
+
+	dir := t.TempDir()
+	formulas := number.NewFormulas(filepath.Join(dir, "formulas.json"))
+	for _, data := range []string{`{"area": "w h *"}`, `{"area": "w h * 2 /", "broken": "x +"}`} {
+		if err := os.WriteFile(formulas.Path(), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		formulas.Load()
+	}
+
+	var archive bytes.Buffer
+	if err := Backup(&archive, idx, store, formulas); err != nil {
+		t.Fatal(err)
+	}
+	restored, restoredStore, restoredFormulas, err := Restore(&archive, filepath.Join(dir, "restored.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Len() != 2 {
+		t.Errorf("restored Len() = %d, want 2", restored.Len())
+	}
+	if _, ok := restored.Get(deleted); ok {
+		t.Errorf("deleted document %d restored", deleted)
+	}
+	if ids := hitIDs(t, restored, "guide"); !slices.Equal(ids, []int{0, 2}) {
+		t.Errorf("restored Search(guide) = %v, want [0 2]", ids)
+	}
+	// A restored index keeps assigning IDs after the archived ones
+	if id := restored.Add("go guide"); id != 3 {
+		t.Errorf("Add() after Restore = %d, want 3", id)
+	}
+	if got := restoredStore.Sources(); !maps.Equal(got, store.Sources()) {
+		t.Errorf("restored queries = %v, want %v", got, store.Sources())
+	}
+	// The store comes back as it was, not replayed through Save
+	if got, want := restoredStore.state(), store.state(); !reflect.DeepEqual(stripTimes(got), stripTimes(want)) {
+		t.Errorf("restored store = %+v, want %+v", got, want)
+	}
+	if history := restoredStore.History("langs"); len(history) != 2 || history[1].Author != "alice" {
+		t.Errorf("restored History() = %+v", history)
+	}
+	if draft, ok := restoredStore.Draft("langs"); !ok || draft.Query != "go OR rust" {
+		t.Errorf("restored Draft() = %+v, %t", draft, ok)
+	}
+	if usage := restoredStore.Usage(); len(usage) != 1 || usage[0].Evaluations != 1 {
+		t.Errorf("restored Usage() = %+v", usage)
+	}
+
+	// The formula file comes back as it was, broken entry included, with
+	// the history of its formulas
+	data, err := os.ReadFile(restoredFormulas.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]string{}
+	if err := json.Unmarshal(data, &sources); err != nil || !maps.Equal(sources, map[string]string{"area": "w h * 2 /", "broken": "x +"}) {
+		t.Errorf("restored formula file = %s, %v", data, err)
+	}
+	if err := restoredFormulas.Load(); err == nil || !strings.Contains(err.Error(), "formula broken") {
+		t.Errorf("Load() of the restored formulas error = %v", err)
+	}
+	if history := restoredFormulas.History("area"); len(history) != 2 || history[1].Formula != "w h * 2 /" {
+		t.Errorf("restored formula History() = %+v", history)
+	}
+	got, _ := json.Marshal(restored.backupConfig())
+	want, _ := json.Marshal(idx.backupConfig())
+	if string(got) != string(want) {
+		t.Errorf("restored config = %s, want %s", got, want)
+	}
+	if ids, err := restored.Ingest("spam guide"); err != nil || len(ids) != 0 {
+		t.Errorf("Ingest() through the restored pipeline = %v, %v, want the document dropped", ids, err)
+	}
+}
+
+// stripTimes clears the times of a store state, which do not survive JSON
+// exactly
+func stripTimes(state queryStoreState) queryStoreState {
+	for name, versions := range state.History {
+		for i := range versions {
+			versions[i].Time = time.Time{}
+		}
+		state.History[name] = versions
+	}
+	for name, draft := range state.Drafts {
+		draft.Time = time.Time{}
+		state.Drafts[name] = draft
+	}
+	for name, usage := range state.Usage {
+		usage.LastUsed = time.Time{}
+		state.Usage[name] = usage
+	}
+	return state
+}
+
+// writeTestArchive writes an archive of the given files, bypassing Backup
+func writeTestArchive(t *testing.T, files map[string][]byte) *bytes.Buffer {
+	t.Helper()
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	archive := tar.NewWriter(gzipWriter)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if err := writeArchiveFile(archive, name, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buffer
+}
+
+// readTestArchive reads back the files of an archive written by Backup
+func readTestArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	archive := tar.NewReader(gzipReader)
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			break
+		}
+		var buffer bytes.Buffer
+		buffer.ReadFrom(archive)
+		files[header.Name] = buffer.Bytes()
+	}
+	return files
+}
+
+func TestRestoreRejectsDamagedArchives(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("python guide")
+	var backup bytes.Buffer
+	if err := Backup(&backup, idx, NewQueryStore(), nil); err != nil {
+		t.Fatal(err)
+	}
+	original := readTestArchive(t, backup.Bytes())
+
+	tests := []struct {
+		name   string
+		damage func(files map[string][]byte)
+		want   string
+	}{
+		{
+			name:   "tampered index",
+			damage: func(files map[string][]byte) { files["index.json"] = []byte(`{"docs":["evil"],"deleted":[]}`) },
+			want:   "checksum mismatch for index.json",
+		},
+		{
+			name:   "missing queries",
+			damage: func(files map[string][]byte) { delete(files, "queries.json") },
+			want:   "backup is missing queries.json",
+		},
+		{
+			name:   "formulas without their state",
+			damage: func(files map[string][]byte) { files["formulas.json"] = []byte(`{}`) },
+			want:   "backup is missing formula-state.json",
+		},
+		{
+			name:   "missing config",
+			damage: func(files map[string][]byte) { delete(files, "config.json") },
+			want:   "backup is missing config.json",
+		},
+		{
+			name: "payload without checksum",
+			damage: func(files map[string][]byte) {
+				manifest := BackupManifest{}
+				json.Unmarshal(files[manifestName], &manifest)
+				delete(manifest.Checksums, "config.json")
+				files[manifestName], _ = json.Marshal(manifest)
+			},
+			want: "backup manifest has no checksum for config.json",
+		},
+		{
+			name:   "unlisted entry",
+			damage: func(files map[string][]byte) { files["extra.json"] = []byte("{}") },
+			want:   "backup entry extra.json is not in the manifest",
+		},
+		{
+			name:   "missing manifest",
+			damage: func(files map[string][]byte) { delete(files, manifestName) },
+			want:   "backup manifest missing or invalid",
+		},
+		{
+			name: "future version",
+			damage: func(files map[string][]byte) {
+				manifest := BackupManifest{}
+				json.Unmarshal(files[manifestName], &manifest)
+				manifest.Version = backupFormatVersion + 1
+				files[manifestName], _ = json.Marshal(manifest)
+			},
+			want: "unsupported backup version 4",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := maps.Clone(original)
+			test.damage(files)
+			_, _, _, err := Restore(writeTestArchive(t, files), "")
+			if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+				t.Errorf("Restore() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+
+	if _, _, _, err := Restore(strings.NewReader("not an archive"), ""); err == nil || !strings.HasPrefix(err.Error(), "invalid backup archive") {
+		t.Errorf("Restore(garbage) error = %v, want an invalid archive", err)
+	}
+}
+
+func TestRestoreRejectsDuplicateAndOversizedEntries(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("python guide")
+	var backup bytes.Buffer
+	if err := Backup(&backup, idx, NewQueryStore(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := restore(bytes.NewReader(backup.Bytes()), 16, ""); err == nil || err.Error() != "backup entry index.json exceeds 16 bytes" {
+		t.Errorf("restore() of an oversized entry error = %v", err)
+	}
+
+	// A second copy of an entry could replace the one the manifest checked
+	var duplicated bytes.Buffer
+	gzipWriter := gzip.NewWriter(&duplicated)
+	archive := tar.NewWriter(gzipWriter)
+	files := readTestArchive(t, backup.Bytes())
+	for _, name := range []string{"config.json", "index.json", "index.json", "queries.json", manifestName} {
+		if err := writeArchiveFile(archive, name, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive.Close()
+	gzipWriter.Close()
+	if _, _, _, err := Restore(&duplicated, ""); err == nil || err.Error() != "duplicate backup entry index.json" {
+		t.Errorf("Restore() of a duplicate entry error = %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package document
+
+import (
+	"math/bits"
+)
+
+// DocumentBatch holds a set of prepared documents as per-term bitsets so a
+// query can be evaluated against all of them with word-wide AND/OR/NOT
+type DocumentBatch struct {
+	docs     []*PreparedDoc
+	words    int
+	tailMask uint64
+	termBits map[string][]uint64
+	empty    []uint64
+}
+
+// NewDocumentBatch builds the term bitsets of a batch of prepared documents
+func NewDocumentBatch(docs []*PreparedDoc) *DocumentBatch {
+	words := (len(docs) + 63) / 64
+	batch := &DocumentBatch{
+		docs:     docs,
+		words:    words,
+		tailMask: ^uint64(0),
+		termBits: make(map[string][]uint64),
+		empty:    make([]uint64, words),
+	}
+	if remainder := len(docs) % 64; remainder != 0 {
+		batch.tailMask = uint64(1)<<remainder - 1
+	}
+
+	for i, doc := range docs {
+		for term := range doc.terms {
+			termBits, ok := batch.termBits[term]
+			if !ok {
+				termBits = make([]uint64, words)
+				batch.termBits[term] = termBits
+			}
+			termBits[i/64] |= 1 << (i % 64)
+		}
+	}
+	return batch
+}
+
+// Len returns the number of documents in the batch
+func (batch *DocumentBatch) Len() int {
+	return len(batch.docs)
+}
+
+// Match evaluates the query against every document of the batch and returns
+// a bitset with bit i set when document i matches
+func (batch *DocumentBatch) Match(q *Query) []uint64 {
+	stack := make([][]uint64, 0, len(q.rpn))
+	// Intermediate results are written to fresh buffers so the shared term
+	// bitsets are never modified
+	buffer := func() []uint64 {
+		return make([]uint64, batch.words)
+	}
+
+	for _, token := range q.rpn {
+		switch token {
+		case "AND":
+			top := len(stack) - 1
+			out := buffer()
+			left, right := stack[top-1], stack[top]
+			for w := range out {
+				out[w] = left[w] & right[w]
+			}
+			stack[top-1] = out
+			stack = stack[:top]
+		case "OR":
+			top := len(stack) - 1
+			out := buffer()
+			left, right := stack[top-1], stack[top]
+			for w := range out {
+				out[w] = left[w] | right[w]
+			}
+			stack[top-1] = out
+			stack = stack[:top]
+		case "NOT":
+			top := len(stack) - 1
+			out := buffer()
+			operand := stack[top]
+			for w := range out {
+				out[w] = ^operand[w]
+			}
+			if batch.words > 0 {
+				out[batch.words-1] &= batch.tailMask
+			}
+			stack[top] = out
+		case "FILTER":
+		default:
+			if r, ok := q.ranges[token]; ok {
+				rangeBits := buffer()
+				for i, doc := range batch.docs {
+					if r.matchDoc(doc) {
+						rangeBits[i/64] |= 1 << (i % 64)
+					}
+				}
+				stack = append(stack, rangeBits)
+				continue
+			}
+			termBits, ok := batch.termBits[token]
+			if !ok {
+				termBits = batch.empty
+			}
+			stack = append(stack, termBits)
+		}
+	}
+
+	last := len(q.rpn) - 1
+	for q.rpn[last] == "FILTER" {
+		last--
+	}
+	if !isOperator(q.rpn[last]) {
+		// A single term query, possibly filtered, would otherwise return the
+		// shared term bitset
+		return append([]uint64(nil), stack[0]...)
+	}
+	return stack[0]
+}
+
+// Count returns the number of documents in the batch matching the query
+func (batch *DocumentBatch) Count(q *Query) int {
+	count := 0
+	for _, word := range batch.Match(q) {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// MatchDocuments returns the documents of the batch matching the query
+func (batch *DocumentBatch) MatchDocuments(q *Query) []*PreparedDoc {
+	matches := []*PreparedDoc{}
+	for w, word := range batch.Match(q) {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			matches = append(matches, batch.docs[w*64+bit])
+			word &= word - 1
+		}
+	}
+	return matches
+}
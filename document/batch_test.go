@@ -0,0 +1,97 @@
+package document
+
+import (
+	"fmt"
+	"testing"
+)
+
+// batchTestDocuments spans three bitset words so the tail mask and word
+// boundaries are exercised
+func batchTestDocuments() []*PreparedDoc {
+	docs := make([]*PreparedDoc, 130)
+	for i := range docs {
+		text := fmt.Sprintf("doc price:%d", i)
+		if i%2 == 0 {
+			text += " even"
+		}
+		if i%3 == 0 {
+			text += " three"
+		}
+		if i%64 == 63 {
+			text += " edge"
+		}
+		docs[i] = PrepareDocument(text)
+	}
+	return docs
+}
+
+// TestDocumentBatchMatchesPerDocument evaluates queries against a batch and
+// against each of its documents alone, which must agree
+func TestDocumentBatchMatchesPerDocument(t *testing.T) {
+	docs := batchTestDocuments()
+	batch := NewDocumentBatch(docs)
+	if batch.Len() != len(docs) {
+		t.Fatalf("Len() = %d, want %d", batch.Len(), len(docs))
+	}
+
+	tests := []struct {
+		query string
+		count int
+	}{
+		{"even", 65},
+		{"even AND three", 22},
+		{"even OR three", 87},
+		{"NOT even", 65},
+		{"NOT doc", 0},
+		{"edge", 2},
+		{"three AND NOT even", 22},
+		{"missing", 0},
+		{"NOT missing", 130},
+		{"price:100..", 30},
+		{"even AND price:<10", 5},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			compiled, err := CompileQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := batch.Count(compiled); got != test.count {
+				t.Errorf("Count() = %d, want %d", got, test.count)
+			}
+
+			want := []*PreparedDoc{}
+			for _, doc := range docs {
+				if compiled.MatchDocument(doc) {
+					want = append(want, doc)
+				}
+			}
+			got := batch.MatchDocuments(compiled)
+			if len(got) != len(want) {
+				t.Fatalf("MatchDocuments() returned %d documents, want %d", len(got), len(want))
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Errorf("MatchDocuments()[%d] = %q, want %q", i, got[i].Text(), want[i].Text())
+				}
+			}
+		})
+	}
+}
+
+// TestDocumentBatchMatchLeavesTermBitsUntouched modifies the result of a
+// single term query, which must not change later matches
+func TestDocumentBatchMatchLeavesTermBitsUntouched(t *testing.T) {
+	batch := NewDocumentBatch(batchTestDocuments())
+	compiled, err := CompileQuery("even")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := batch.Match(compiled)
+	for w := range result {
+		result[w] = 0
+	}
+	if got := batch.Count(compiled); got != 65 {
+		t.Errorf("Count() after modifying a result = %d, want 65", got)
+	}
+}
@@ -0,0 +1,188 @@
+package document
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// benchmarkVocabulary is the word list synthetic benchmark documents are drawn from
+var benchmarkVocabulary = []string{
+	"c", "c++", "java", "python", "go", "rust", "guide", "tutorial", "reference",
+	"intro", "advanced", "book", "notes", "course", "manual", "cookbook",
+}
+
+// generateCorpus builds a deterministic synthetic corpus of docCount documents
+func generateCorpus(docCount int) []string {
+	random := rand.New(rand.NewSource(1))
+	corpus := make([]string, docCount)
+	for i := range corpus {
+		words := make([]string, 3+random.Intn(6))
+		for j := range words {
+			words[j] = benchmarkVocabulary[random.Intn(len(benchmarkVocabulary))]
+		}
+		corpus[i] = strings.Join(words, " ")
+	}
+	return corpus
+}
+
+// benchmarkDocuments is the size of the benchmark corpus
+const benchmarkDocuments = 100000
+
+// benchmarkQuery is the query the matching benchmarks run
+const benchmarkQuery = "(python OR java) AND guide AND NOT advanced"
+
+// preparedCorpus analyzes the benchmark corpus once
+func preparedCorpus() []*PreparedDoc {
+	corpus := generateCorpus(benchmarkDocuments)
+	prepared := make([]*PreparedDoc, len(corpus))
+	for i, doc := range corpus {
+		prepared[i] = PrepareDocument(doc)
+	}
+	return prepared
+}
+
+// BenchmarkMatchCompiled matches a compiled query against documents
+// analyzed once
+func BenchmarkMatchCompiled(b *testing.B) {
+	prepared := preparedCorpus()
+	compiled, err := CompileQuery(benchmarkQuery)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, doc := range prepared {
+			compiled.MatchDocument(doc)
+		}
+	}
+}
+
+// BenchmarkMatchBatch matches a compiled query against the term bitsets of
+// a document batch
+func BenchmarkMatchBatch(b *testing.B) {
+	batch := NewDocumentBatch(preparedCorpus())
+	compiled, err := CompileQuery(benchmarkQuery)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch.Match(compiled)
+	}
+}
+
+// matchesBenchmarkQuery evaluates benchmarkQuery by hand against the words
+// of doc, the reference compiled and batch matching must agree with
+func matchesBenchmarkQuery(doc string) bool {
+	words := map[string]bool{}
+	for _, word := range strings.Fields(doc) {
+		words[word] = true
+	}
+	return (words["python"] || words["java"]) && words["guide"] && !words["advanced"]
+}
+
+func TestCompiledMatchingAgreesWithReference(t *testing.T) {
+	corpus := generateCorpus(2000)
+	compiled, err := CompileQuery(benchmarkQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepared := make([]*PreparedDoc, len(corpus))
+	for i, doc := range corpus {
+		prepared[i] = PrepareDocument(doc)
+	}
+	matches := NewDocumentBatch(prepared).Match(compiled)
+
+	for i, doc := range corpus {
+		want := matchesBenchmarkQuery(doc)
+		if got := compiled.MatchDocument(prepared[i]); got != want {
+			t.Errorf("MatchDocument(%q) = %v, want %v", doc, got, want)
+		}
+		if got := matches[i/64]&(1<<(i%64)) != 0; got != want {
+			t.Errorf("batch match of %q = %v, want %v", doc, got, want)
+		}
+	}
+}
+
+// processorBenchmarkQuery is the query the processor benchmarks run
+const processorBenchmarkQuery = "(python OR java) AND (guide OR tutorial) AND NOT (advanced OR intro)"
+
+// BenchmarkProcessorPerDocument creates a boolean processor per document,
+// growing its stack on every match
+func BenchmarkProcessorPerDocument(b *testing.B) {
+	prepared := preparedCorpus()
+	compiled, err := CompileQuery(processorBenchmarkQuery)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, doc := range prepared {
+			NewBooleanRPNProcessor().MatchDocument(compiled, doc)
+		}
+	}
+}
+
+// BenchmarkProcessorReused reuses one processor whose stack is preallocated
+// from the query's maximum depth
+func BenchmarkProcessorReused(b *testing.B) {
+	prepared := preparedCorpus()
+	compiled, err := CompileQuery(processorBenchmarkQuery)
+	if err != nil {
+		b.Fatal(err)
+	}
+	processor := compiled.NewProcessor()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, doc := range prepared {
+			processor.MatchDocument(compiled, doc)
+		}
+	}
+}
+
+func TestReusedProcessorDoesNotAllocate(t *testing.T) {
+	compiled, err := CompileQuery(processorBenchmarkQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := PrepareDocument("a python guide")
+	processor := compiled.NewProcessor()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if !processor.MatchDocument(compiled, doc) {
+			t.Fatal("query does not match")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("reused processor allocates %g times per match, want 0", allocs)
+	}
+	if fresh := testing.AllocsPerRun(100, func() { NewBooleanRPNProcessor().MatchDocument(compiled, doc) }); fresh == 0 {
+		t.Error("a new processor per match does not allocate, the benchmark compares nothing")
+	}
+}
+
+func TestQueryMaxDepth(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"python", 1},
+		{"python AND java", 2},
+		{"python AND java AND guide", 2},
+		{"python AND (java OR (guide AND NOT rust))", 4},
+	}
+	for _, test := range tests {
+		compiled, err := CompileQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := compiled.MaxDepth(); got != test.want {
+			t.Errorf("MaxDepth(%q) = %d, want %d", test.query, got, test.want)
+		}
+	}
+}
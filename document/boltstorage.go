@@ -0,0 +1,106 @@
+//go:build bbolt
+
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket is the bucket holding the blobs of a BoltStorage
+var boltBucket = []byte("blobs")
+
+// BoltStorage keeps blobs in a bbolt database file. Every write is a
+// transaction synced to disk before it returns, and a save through
+// SaveToStorage is a single transaction, so a crash leaves either the
+// previous or the new index. It is only built with the bbolt tag
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// OpenBoltStorage opens or creates a bbolt database at path
+func OpenBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStorage{db: db}, nil
+}
+
+// Close closes the database
+func (storage *BoltStorage) Close() error {
+	return storage.db.Close()
+}
+
+// Put writes data under name
+func (storage *BoltStorage) Put(name string, data []byte) error {
+	return storage.Batch(map[string][]byte{name: data}, nil)
+}
+
+// Batch writes puts and removes deletes in one transaction
+func (storage *BoltStorage) Batch(puts map[string][]byte, deletes []string) error {
+	for name := range puts {
+		if err := checkStorageName(name); err != nil {
+			return err
+		}
+	}
+	return storage.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for name, data := range puts {
+			if err := bucket.Put([]byte(name), data); err != nil {
+				return err
+			}
+		}
+		for _, name := range deletes {
+			if err := bucket.Delete([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get reads the blob stored under name
+func (storage *BoltStorage) Get(name string) ([]byte, error) {
+	var data []byte
+	err := storage.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltBucket).Get([]byte(name))
+		if value == nil {
+			return fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+		}
+		// Values are only valid during the transaction
+		data = append([]byte(nil), value...)
+		return nil
+	})
+	return data, err
+}
+
+// Delete removes the blob stored under name
+func (storage *BoltStorage) Delete(name string) error {
+	return storage.Batch(nil, []string{name})
+}
+
+// List returns the names starting with prefix in sorted order
+func (storage *BoltStorage) List(prefix string) ([]string, error) {
+	names := []string{}
+	err := storage.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltBucket).Cursor()
+		start := []byte(prefix)
+		for key, _ := cursor.Seek(start); key != nil && bytes.HasPrefix(key, start); key, _ = cursor.Next() {
+			names = append(names, string(key))
+		}
+		return nil
+	})
+	return names, err
+}
@@ -0,0 +1,48 @@
+//go:build bbolt
+
+package document
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// openTestBoltStorage opens the database at path, which is closed
+// when the test ends
+func openTestBoltStorage(t *testing.T, path string) *BoltStorage {
+	t.Helper()
+	storage, err := OpenBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestBoltStorage(t *testing.T) {
+	checkStorage(t, openTestBoltStorage(t, filepath.Join(t.TempDir(), "index.db")))
+}
+
+func TestBoltStorageSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	storage := openTestBoltStorage(t, path)
+	idx, store := storageTestIndex(t)
+	if err := SaveToStorage(storage, idx, store); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The saved index survives reopening the database
+	loaded, _, err := LoadFromStorage(openTestBoltStorage(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"parity1", "document AND NOT parity0"} {
+		if got, want := hitIDs(t, loaded, query), hitIDs(t, idx, query); !slices.Equal(got, want) {
+			t.Errorf("Search(%q) = %d hits, want %d", query, len(got), len(want))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package document
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IndexBuilder bulk-loads documents into a new index without taking the
+// index lock per document, analyzing the documents in parallel
+type IndexBuilder struct {
+	texts   []string
+	workers int
+}
+
+// NewIndexBuilder creates a new index builder using one worker per CPU
+func NewIndexBuilder() *IndexBuilder {
+	return &IndexBuilder{
+		texts:   make([]string, 0),
+		workers: runtime.NumCPU(),
+	}
+}
+
+// SetWorkers sets the number of goroutines used to analyze documents
+func (builder *IndexBuilder) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	builder.workers = workers
+}
+
+// Add queues a document for the build
+func (builder *IndexBuilder) Add(text string) {
+	builder.texts = append(builder.texts, text)
+}
+
+// AddAll queues a batch of documents for the build
+func (builder *IndexBuilder) AddAll(texts []string) {
+	builder.texts = append(builder.texts, texts...)
+}
+
+// Build analyzes the queued documents and returns the finished index
+func (builder *IndexBuilder) Build() *Index {
+	docs := make([]*PreparedDoc, len(builder.texts))
+
+	// Each worker analyzes its own contiguous chunk, so no locking is needed
+	chunk := (len(docs) + builder.workers - 1) / builder.workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(docs); start += chunk {
+		end := min(start+chunk, len(docs))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				docs[i] = PrepareDocument(builder.texts[i])
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	// Collect the sorted term list first so postings are built term by term
+	termSet := make(map[string]int)
+	for _, doc := range docs {
+		for term := range doc.terms {
+			termSet[term]++
+		}
+	}
+	terms := make([]string, 0, len(termSet))
+	for term := range termSet {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	postings := make(map[string][]int, len(terms))
+	for _, term := range terms {
+		postings[term] = make([]int, 0, termSet[term])
+	}
+	for id, doc := range docs {
+		for term := range doc.terms {
+			postings[term] = append(postings[term], id)
+		}
+	}
+
+	return &Index{docs: docs, postings: postings, dict: newTermDictionary(terms), fields: buildNumericFields(docs), live: len(docs)}
+}
+
+// Swap atomically replaces the contents of the index with a built index
+func (idx *Index) Swap(built *Index) {
+	built.mu.RLock()
+	docs, postings, dict, fields, live := built.docs, built.postings, built.dict, built.fields, built.live
+	built.mu.RUnlock()
+
+	idx.mu.Lock()
+	now := time.Now()
+	for id, doc := range idx.docs {
+		if doc != nil {
+			idx.retireLocked(id, now)
+		}
+	}
+	idx.added = nil
+	idx.expires = nil
+	for id, doc := range docs {
+		if doc != nil {
+			idx.setAddedLocked(id, now)
+		}
+	}
+	idx.docs = docs
+	idx.rebuild = nil
+	idx.postings = postings
+	idx.dict = dict
+	idx.fields = fields
+	idx.live = live
+	event := idx.recordEvent(EventSegmentMerged, -1, "")
+	event.Docs = make([]string, len(docs))
+	for id, doc := range docs {
+		if doc == nil {
+			event.Deleted = append(event.Deleted, id)
+			continue
+		}
+		event.Docs[id] = doc.text
+	}
+	idx.unlockAndPublish([]IndexEvent{event})
+}
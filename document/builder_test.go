@@ -0,0 +1,106 @@
+package document
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// builderTestDocuments are documents with shared, unique and field terms
+var builderTestDocuments = []string{
+	"python guide for beginners",
+	"java guide",
+	"advanced python and java interop",
+	"rust in action price:30",
+	"cooking with python price:12.5",
+	"a guide to guides",
+}
+
+// hitIDs returns the IDs of the hits of a search, sorted
+func hitIDs(t *testing.T, idx *Index, query string) []int {
+	t.Helper()
+	result, err := idx.Search(query, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search(%q): %v", query, err)
+	}
+	ids := []int{}
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestIndexBuilderMatchesIncrementalIndex(t *testing.T) {
+	incremental := NewIndex()
+	for _, text := range builderTestDocuments {
+		incremental.Add(text)
+	}
+
+	queries := []string{"python", "guide AND NOT java", "python OR rust", "gui*", "price:10..20", "missing"}
+	for _, workers := range []int{1, 2, 4, 16} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			builder := NewIndexBuilder()
+			builder.SetWorkers(workers)
+			builder.Add(builderTestDocuments[0])
+			builder.AddAll(builderTestDocuments[1:])
+			built := builder.Build()
+
+			if built.Len() != incremental.Len() {
+				t.Errorf("Len() = %d, want %d", built.Len(), incremental.Len())
+			}
+			for id, text := range builderTestDocuments {
+				if got, ok := built.Get(id); !ok || got != text {
+					t.Errorf("Get(%d) = %q, %v, want %q", id, got, ok, text)
+				}
+			}
+			for _, query := range queries {
+				if got, want := hitIDs(t, built, query), hitIDs(t, incremental, query); !slices.Equal(got, want) {
+					t.Errorf("Search(%q) = %v, want %v", query, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexBuilderEmpty(t *testing.T) {
+	builder := NewIndexBuilder()
+	builder.SetWorkers(0)
+	built := builder.Build()
+	if built.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", built.Len())
+	}
+	if ids := hitIDs(t, built, "python"); len(ids) != 0 {
+		t.Errorf("Search() = %v, want no hits", ids)
+	}
+}
+
+func TestIndexSwap(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("old document about cobol")
+
+	events := []IndexEvent{}
+	cancel := idx.Subscribe(func(event IndexEvent) {
+		events = append(events, event)
+	})
+	defer cancel()
+
+	builder := NewIndexBuilder()
+	builder.AddAll(builderTestDocuments)
+	idx.Swap(builder.Build())
+
+	if ids := hitIDs(t, idx, "cobol"); len(ids) != 0 {
+		t.Errorf("Search(cobol) after Swap = %v, want no hits", ids)
+	}
+	if ids := hitIDs(t, idx, "python"); !slices.Equal(ids, []int{0, 2, 4}) {
+		t.Errorf("Search(python) after Swap = %v, want [0 2 4]", ids)
+	}
+	if len(events) != 1 || events[0].Type != EventSegmentMerged || len(events[0].Docs) != len(builderTestDocuments) {
+		t.Errorf("events = %+v, want one segment merge of %d documents", events, len(builderTestDocuments))
+	}
+
+	// Documents added after the swap get the next IDs
+	if id := idx.Add("new python document"); id != len(builderTestDocuments) {
+		t.Errorf("Add() after Swap = %d, want %d", id, len(builderTestDocuments))
+	}
+}
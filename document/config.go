@@ -0,0 +1,184 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config declares the setup of an index in JSON, so a deployment can
+// describe it in a file instead of in Go:
+//
+//	{
+//	  "pipeline": {"processors": [{"type": "rename", "field": "text", "target": "body"}]},
+//	  "queries": {"guides": "(python OR java) AND guide"},
+//	  "retention": "24h"
+//	}
+//
+// Every part is optional and leaves that setting alone when missing
+type Config struct {
+	Pipeline *PipelineConfig `json:"pipeline,omitempty"`
+	// Queries are saved under their names, replacing the queries the
+	// previous configuration saved
+	Queries map[string]string `json:"queries,omitempty"`
+	// Retention is how long removed documents stay searchable through
+	// AsOf, as a Go duration such as "90m"
+	Retention *ConfigDuration `json:"retention,omitempty"`
+
+	pipeline *Pipeline
+}
+
+// ConfigDuration is a duration written in a configuration as a Go duration
+// string
+type ConfigDuration time.Duration
+
+// UnmarshalJSON parses a duration string such as "1h30m"
+func (d *ConfigDuration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"1h30m\"")
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = ConfigDuration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a Go duration string
+func (d ConfigDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ParseConfig decodes and validates a configuration, compiling its
+// pipeline and queries. Unknown keys are errors, so typos do not silently
+// leave a setting at its default
+func ParseConfig(data []byte) (*Config, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if config.Pipeline != nil {
+		pipeline, err := NewPipeline(*config.Pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: pipeline: %w", err)
+		}
+		config.pipeline = pipeline
+	}
+	names := make([]string, 0, len(config.Queries))
+	for name := range config.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("invalid configuration: query name must not be empty")
+		}
+		if _, err := CompileQuery(config.Queries[name]); err != nil {
+			return nil, fmt.Errorf("invalid configuration: query %s: %w", name, err)
+		}
+	}
+	if config.Retention != nil && *config.Retention < 0 {
+		return nil, fmt.Errorf("invalid configuration: retention must not be negative")
+	}
+	return config, nil
+}
+
+// ConfigLoader applies a configuration file to an index and its saved
+// queries, and applies it again whenever the file changes
+type ConfigLoader struct {
+	path  string
+	idx   *Index
+	store *QueryStore
+
+	mu      sync.Mutex
+	applied *Config
+	version fileVersion
+}
+
+// NewConfigLoader creates a loader of the configuration file at path
+func NewConfigLoader(path string, idx *Index, store *QueryStore) *ConfigLoader {
+	return &ConfigLoader{path: path, idx: idx, store: store}
+}
+
+// Load reads, validates and applies the configuration file. An invalid
+// file is reported and the configuration applied before stays in effect
+func (loader *ConfigLoader) Load() error {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+	return loader.loadLocked(true)
+}
+
+// loadLocked loads the file unless it is the version last read, or always
+// when force is set. The caller must hold loader.mu
+func (loader *ConfigLoader) loadLocked(force bool) error {
+	last := loader.version
+	if force {
+		last = fileVersion{}
+	}
+	data, version, changed, err := readChanged(loader.path, last)
+	if err != nil || !changed {
+		return err
+	}
+	// A bad edit is not retried until the file changes again
+	loader.version = version
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", loader.path, err)
+	}
+	loader.apply(config)
+	return nil
+}
+
+// apply puts a validated configuration in effect
+func (loader *ConfigLoader) apply(config *Config) {
+	if config.Pipeline != nil {
+		loader.idx.SetPipeline(config.pipeline)
+	}
+	if config.Retention != nil {
+		loader.idx.SetRetention(time.Duration(*config.Retention))
+	}
+
+	saves := make(map[string]*Query, len(config.Queries))
+	for name, query := range config.Queries {
+		// ParseConfig compiled every query already
+		saves[name], _ = CompileQuery(query)
+	}
+	deletes := []string{}
+	if loader.applied != nil {
+		for name := range loader.applied.Queries {
+			if _, ok := saves[name]; !ok {
+				deletes = append(deletes, name)
+			}
+		}
+	}
+	loader.store.update(saves, deletes)
+	loader.applied = config
+}
+
+// Config returns the configuration in effect, nil before the first
+// successful Load
+func (loader *ConfigLoader) Config() *Config {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+	return loader.applied
+}
+
+// Watch checks the file every interval and loads it when its modification
+// time or size changed, passing load errors to onError. It runs until the
+// returned function is called
+func (loader *ConfigLoader) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	return pollEvery(interval, func() error {
+		loader.mu.Lock()
+		defer loader.mu.Unlock()
+		return loader.loadLocked(false)
+	}, onError)
+}
@@ -0,0 +1,186 @@
+package document
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", `{}`, ""},
+		{"full", `{"pipeline": {"processors": [{"type": "drop", "query": "spam"}]}, "queries": {"guides": "(python OR java) AND guide"}, "retention": "1h30m"}`, ""},
+		{"not json", `not json`, "invalid configuration: invalid character 'o' in literal null (expecting 'u')"},
+		{"unknown key", `{"colour": 1}`, `invalid configuration: json: unknown field "colour"`},
+		{"duration not a string", `{"retention": 5}`, `invalid configuration: duration must be a string such as "1h30m"`},
+		{"bad duration", `{"retention": "5x"}`, `invalid configuration: time: unknown unit "x" in duration "5x"`},
+		{"negative retention", `{"retention": "-1h"}`, "invalid configuration: retention must not be negative"},
+		{"empty query name", `{"queries": {"": "a"}}`, "invalid configuration: query name must not be empty"},
+		{"bad query", `{"queries": {"b": "a AND"}}`, "invalid configuration: query b: insufficient operands for AND operation"},
+		{"bad processor", `{"pipeline": {"processors": [{"type": "nope"}]}}`, `invalid configuration: pipeline: processor 0 (nope): unknown processor type "nope"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(test.data))
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("ParseConfig() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (config.Pipeline != nil) != (config.pipeline != nil) {
+				t.Error("pipeline was not compiled")
+			}
+		})
+	}
+}
+
+func TestConfigDuration(t *testing.T) {
+	var config Config
+	if err := json.Unmarshal([]byte(`{"retention": "1h30m"}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Duration(*config.Retention); got != 90*time.Minute {
+		t.Errorf("retention = %v, want 1h30m", got)
+	}
+	data, err := json.Marshal(config)
+	if err != nil || string(data) != `{"retention":"1h30m0s"}` {
+		t.Errorf("Marshal() = %s, %v", data, err)
+	}
+}
+
+// writeWatchedFile writes a watched file, moving its modification time
+// forward so every write is a new version of the file
+func writeWatchedFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigLoader(t *testing.T) {
+	// Every step writes the file and loads it
+	steps := []struct {
+		name      string
+		data      string
+		wantErr   bool
+		queries   []string
+		retention time.Duration
+		drops     bool
+	}{
+		{"initial", `{"pipeline": {"processors": [{"type": "drop", "query": "spam"}]}, "queries": {"a": "go", "b": "rust"}, "retention": "1h"}`, false, []string{"a", "b"}, time.Hour, true},
+		{"remove a query", `{"queries": {"b": "rust OR go", "c": "java"}}`, false, []string{"b", "c"}, time.Hour, true},
+		{"invalid edit", `{"queries": {"b": "rust AND"}}`, true, []string{"b", "c"}, time.Hour, true},
+		{"retention only", `{"retention": "2h"}`, false, []string{}, 2 * time.Hour, true},
+		{"clear pipeline", `{"pipeline": {"processors": []}}`, false, []string{}, 2 * time.Hour, false},
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	idx := NewIndex()
+	store := NewQueryStore()
+	// Queries saved by hand are left alone
+	if err := store.Save("manual", "python"); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewConfigLoader(path, idx, store)
+	if loader.Config() != nil {
+		t.Error("Config() before Load is not nil")
+	}
+	if err := loader.Load(); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			writeWatchedFile(t, path, step.data)
+			err := loader.Load()
+			if (err != nil) != step.wantErr {
+				t.Fatalf("Load() error = %v, want error %t", err, step.wantErr)
+			}
+			if got, want := store.Names(), append(step.queries, "manual"); !sameSet(got, want) {
+				t.Errorf("saved queries = %v, want %v", got, want)
+			}
+			if idx.retention != step.retention {
+				t.Errorf("retention = %v, want %v", idx.retention, step.retention)
+			}
+			ids, err := idx.Ingest("spam offer")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dropped := len(ids) == 0; dropped != step.drops {
+				t.Errorf("spam dropped = %t, want %t", dropped, step.drops)
+			}
+			if !step.wantErr && loader.Config() == nil {
+				t.Error("Config() is nil after a successful Load")
+			}
+		})
+	}
+	if compiled, ok := store.Get("b"); ok {
+		t.Errorf("query b = %v after the configuration dropped it", compiled)
+	}
+}
+
+// sameSet reports whether two lists hold the same strings in any order
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func TestConfigLoaderWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeWatchedFile(t, path, `{"queries": {"a": "go"}}`)
+	store := NewQueryStore()
+	loader := NewConfigLoader(path, NewIndex(), store)
+	errs := make(chan error, 10)
+	stop := loader.Watch(5*time.Millisecond, func(err error) { errs <- err })
+	defer stop()
+
+	waitFor(t, "the first load", func() bool { return sameSet(store.Names(), []string{"a"}) })
+
+	writeWatchedFile(t, path, `{"queries": {"a": "go AND"}}`)
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Watch reported a nil error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not report the invalid edit")
+	}
+
+	writeWatchedFile(t, path, `{"queries": {"b": "rust"}}`)
+	waitFor(t, "the reload", func() bool { return sameSet(store.Names(), []string{"b"}) })
+	stop()
+	stop()
+	if len(errs) != 0 {
+		t.Errorf("Watch reported %v", <-errs)
+	}
+}
+
+// waitFor polls done until it holds, failing the test after five seconds
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !done(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
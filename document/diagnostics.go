@@ -0,0 +1,177 @@
+package document
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+// ActiveQuery is a search that is still running
+type ActiveQuery struct {
+	TraceID string        `json:"trace_id,omitempty"`
+	Query   string        `json:"query"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// trackQuery records a search as running until the returned function is
+// called
+func (idx *Index) trackQuery(compiled *Query, opts SearchOptions, start time.Time) func() {
+	idx.activeMu.Lock()
+	defer idx.activeMu.Unlock()
+	if idx.active == nil {
+		idx.active = make(map[uint64]ActiveQuery)
+	}
+	idx.nextActive++
+	id := idx.nextActive
+	idx.active[id] = ActiveQuery{TraceID: opts.TraceID, Query: compiled.String(), Started: start}
+	return func() {
+		idx.activeMu.Lock()
+		defer idx.activeMu.Unlock()
+		delete(idx.active, id)
+	}
+}
+
+// ActiveQueries returns the searches running on the index, oldest first
+func (idx *Index) ActiveQueries() []ActiveQuery {
+	idx.activeMu.Lock()
+	defer idx.activeMu.Unlock()
+	now := time.Now()
+	queries := make([]ActiveQuery, 0, len(idx.active))
+	for _, query := range idx.active {
+		query.Elapsed = now.Sub(query.Started)
+		queries = append(queries, query)
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Started.Before(queries[j].Started) })
+	return queries
+}
+
+// Diagnostics serves the endpoints an orchestrator such as Kubernetes uses
+// to operate a server: /healthz answers while the process runs, /readyz once
+// the server is ready to take traffic, and /debug/rpn reports the state of
+// the index
+type Diagnostics struct {
+	idx     *Index
+	store   *QueryStore
+	started time.Time
+	ready   atomic.Bool
+	// programs is the cache of compiled formulas of the server, nil when
+	// it has none
+	programs atomic.Pointer[number.ProgramCache]
+}
+
+// NewDiagnostics creates the diagnostics of a server searching idx with the
+// saved queries in store. It is not ready until SetReady is called
+func NewDiagnostics(idx *Index, store *QueryStore) *Diagnostics {
+	return &Diagnostics{idx: idx, store: store, started: time.Now()}
+}
+
+// SetReady sets whether /readyz reports the server as ready, false while it
+// is loading the index or draining before shutdown
+func (diag *Diagnostics) SetReady(ready bool) {
+	diag.ready.Store(ready)
+}
+
+// SetProgramCache sets the cache of compiled formulas whose stats
+// /debug/rpn reports, nil to report none
+func (diag *Diagnostics) SetProgramCache(cache *number.ProgramCache) {
+	diag.programs.Store(cache)
+}
+
+// DebugReport is the body of /debug/rpn
+type DebugReport struct {
+	Uptime        time.Duration `json:"uptime_ns"`
+	Ready         bool          `json:"ready"`
+	Docs          int           `json:"docs"`
+	Terms         int           `json:"terms"`
+	ActiveQueries []ActiveQuery `json:"active_queries"`
+	// SavedQueries counts the compiled queries kept by the query store
+	SavedQueries int `json:"saved_queries"`
+	// QueryUsage is the usage of every saved query
+	QueryUsage []QueryUsage `json:"query_usage,omitempty"`
+	// ProgramCache is the lookup counts of the cache of compiled formulas
+	// set with SetProgramCache
+	ProgramCache *number.CacheStats `json:"program_cache,omitempty"`
+	Build        BuildInfo          `json:"build"`
+}
+
+// BuildInfo identifies the running binary
+type BuildInfo struct {
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// Report collects the state served on /debug/rpn
+func (diag *Diagnostics) Report() DebugReport {
+	stats := diag.idx.Stats()
+	report := DebugReport{
+		Uptime:        time.Since(diag.started),
+		Ready:         diag.ready.Load(),
+		Docs:          stats.Docs,
+		Terms:         stats.Terms,
+		ActiveQueries: diag.idx.ActiveQueries(),
+		Build:         readBuildInfo(),
+	}
+	if diag.store != nil {
+		report.SavedQueries = len(diag.store.Names())
+		report.QueryUsage = diag.store.Usage()
+	}
+	if cache := diag.programs.Load(); cache != nil {
+		stats := cache.Stats()
+		report.ProgramCache = &stats
+	}
+	return report
+}
+
+// readBuildInfo reads the module and version control details embedded by
+// the go tool
+func readBuildInfo() BuildInfo {
+	build := BuildInfo{GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	build.Path = info.Main.Path
+	build.Version = info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
+
+// Handler returns the diagnostics endpoints. It serves no profiling
+// endpoints, servers wanting pprof mount them next to it
+func (diag *Diagnostics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !diag.ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /debug/rpn", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(diag.Report())
+	})
+	return mux
+}
@@ -0,0 +1,127 @@
+package document
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/number"
+)
+
+func TestDiagnosticsHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		ready  bool
+		path   string
+		status int
+		body   string
+	}{
+		{"health", false, "/healthz", http.StatusOK, "ok\n"},
+		{"not ready", false, "/readyz", http.StatusServiceUnavailable, "not ready\n"},
+		{"ready", true, "/readyz", http.StatusOK, "ok\n"},
+		{"no profiling", true, "/debug/pprof/", http.StatusNotFound, ""},
+		{"unknown", true, "/metrics", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diag := NewDiagnostics(NewIndex(), nil)
+			diag.SetReady(test.ready)
+			recorder := httptest.NewRecorder()
+			diag.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+			if recorder.Code != test.status {
+				t.Errorf("GET %s status = %d, want %d", test.path, recorder.Code, test.status)
+			}
+			if test.body != "" && recorder.Body.String() != test.body {
+				t.Errorf("GET %s body = %q, want %q", test.path, recorder.Body.String(), test.body)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsReport(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("go is fun")
+	idx.Add("rust is fast")
+	store := NewQueryStore()
+	for name, query := range map[string]string{"fast": "fast", "go": "go"} {
+		if err := store.Save(name, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Search(idx, "go", SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	cache := number.NewProgramCache(8)
+	for _, expression := range []string{"1 2 +", "1 2 +", "x 2 *"} {
+		if _, err := cache.Compile(expression); err != nil {
+			t.Fatal(err)
+		}
+	}
+	diag := NewDiagnostics(idx, store)
+	diag.SetReady(true)
+	diag.SetProgramCache(cache)
+
+	recorder := httptest.NewRecorder()
+	diag.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/rpn", nil))
+	if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var report DebugReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Ready || report.Docs != 2 || report.Terms != 5 || report.SavedQueries != 2 || len(report.ActiveQueries) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if want := (number.CacheStats{Size: 2, Capacity: 8, Hits: 1, Misses: 2}); report.ProgramCache == nil || *report.ProgramCache != want {
+		t.Errorf("program cache = %+v, want %+v", report.ProgramCache, want)
+	}
+	if report.Build.GoVersion == "" {
+		t.Error("report has no Go version")
+	}
+	usage := map[string]int{}
+	for _, query := range report.QueryUsage {
+		usage[query.Name] = query.Evaluations
+	}
+	if usage["go"] != 1 || usage["fast"] != 0 || len(usage) != 2 {
+		t.Errorf("query usage = %v", usage)
+	}
+}
+
+func TestActiveQueries(t *testing.T) {
+	idx := NewIndex()
+	now := time.Now()
+	queries := []struct {
+		query   string
+		traceID string
+		started time.Time
+	}{
+		{"new", "b", now},
+		{"old", "a", now.Add(-time.Minute)},
+	}
+	var dones []func()
+	for _, query := range queries {
+		dones = append(dones, idx.trackQuery(mustCompileQuery(t, query.query), SearchOptions{TraceID: query.traceID}, query.started))
+	}
+
+	active := idx.ActiveQueries()
+	if len(active) != 2 || active[0].Query != "old" || active[0].TraceID != "a" || active[1].Query != "new" {
+		t.Fatalf("ActiveQueries() = %+v", active)
+	}
+	if active[0].Elapsed < time.Minute {
+		t.Errorf("oldest query elapsed %v", active[0].Elapsed)
+	}
+	dones[1]()
+	if active := idx.ActiveQueries(); len(active) != 1 || active[0].Query != "new" {
+		t.Errorf("after finishing old, ActiveQueries() = %+v", active)
+	}
+	dones[0]()
+	if _, err := idx.Search("go", SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if active := idx.ActiveQueries(); len(active) != 0 {
+		t.Errorf("after searches finished, ActiveQueries() = %+v", active)
+	}
+}
@@ -0,0 +1,223 @@
+package document
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxPatternExpansions caps how many dictionary terms a single pattern term
+// expands to, the terms past it in dictionary order are ignored
+const maxPatternExpansions = 1024
+
+// noMatchTerm stands in for a pattern matching no indexed term, analysis
+// never produces an empty term
+const noMatchTerm = ""
+
+// minDictionaryOverlay is the number of pending changes a term dictionary
+// accepts before rebuilding its transducer, however small it is
+const minDictionaryOverlay = 256
+
+// termDictionary holds the indexed terms in a transducer, which supports
+// ordered prefix, range and automaton intersection iteration. The
+// transducer is immutable, so terms added or removed since it was built are
+// kept aside and merged in while iterating until there are enough of them
+// to rebuild it
+type termDictionary struct {
+	fst     *fst
+	added   []string
+	removed map[string]bool
+}
+
+// newTermDictionary builds a dictionary from sorted terms
+func newTermDictionary(terms []string) *termDictionary {
+	return &termDictionary{fst: buildFST(terms), removed: make(map[string]bool)}
+}
+
+// insert adds a term missing from the dictionary
+func (dict *termDictionary) insert(term string) {
+	if dict.removed[term] {
+		delete(dict.removed, term)
+		return
+	}
+	at := sort.SearchStrings(dict.added, term)
+	dict.added = append(dict.added, "")
+	copy(dict.added[at+1:], dict.added[at:])
+	dict.added[at] = term
+	dict.compact()
+}
+
+// remove deletes a term from the dictionary
+func (dict *termDictionary) remove(term string) {
+	at := sort.SearchStrings(dict.added, term)
+	if at < len(dict.added) && dict.added[at] == term {
+		dict.added = append(dict.added[:at], dict.added[at+1:]...)
+		return
+	}
+	if _, ok := dict.fst.ordinal(term); ok {
+		dict.removed[term] = true
+		dict.compact()
+	}
+}
+
+// compact rebuilds the transducer once the pending changes outgrow a
+// fraction of it, keeping iteration close to the cost of the transducer alone
+func (dict *termDictionary) compact() {
+	if len(dict.added)+len(dict.removed) <= max(minDictionaryOverlay, dict.fst.size()/8) {
+		return
+	}
+	terms := make([]string, 0, dict.size())
+	dict.walk("", func(term string) bool {
+		terms = append(terms, term)
+		return true
+	})
+	*dict = *newTermDictionary(terms)
+}
+
+// size returns the number of terms in the dictionary
+func (dict *termDictionary) size() int {
+	return dict.fst.size() - len(dict.removed) + len(dict.added)
+}
+
+// walk calls visit with every term not sorting before from, in order,
+// until visit returns false
+func (dict *termDictionary) walk(from string, visit func(term string) bool) {
+	extra := dict.added[sort.SearchStrings(dict.added, from):]
+	dict.merge(func(visit func(string) bool) { dict.fst.walk(from, visit) }, extra, visit)
+}
+
+// intersect calls visit with every term the automaton accepts, in order,
+// until visit returns false
+func (dict *termDictionary) intersect(automaton *dfa, visit func(term string) bool) {
+	extra := []string{}
+	for _, term := range dict.added {
+		if automaton.matches(term) {
+			extra = append(extra, term)
+		}
+	}
+	dict.merge(func(visit func(string) bool) { dict.fst.intersect(automaton, visit) }, extra, visit)
+}
+
+// merge visits the transducer terms produced by walk that were not removed
+// interleaved in order with the sorted pending terms of extra
+func (dict *termDictionary) merge(walk func(visit func(string) bool), extra []string, visit func(term string) bool) {
+	stopped := false
+	walk(func(term string) bool {
+		for len(extra) > 0 && extra[0] < term {
+			if !visit(extra[0]) {
+				stopped = true
+				return false
+			}
+			extra = extra[1:]
+		}
+		if dict.removed[term] {
+			return true
+		}
+		if !visit(term) {
+			stopped = true
+			return false
+		}
+		return true
+	})
+	for _, term := range extra {
+		if stopped || !visit(term) {
+			return
+		}
+	}
+}
+
+// TermsWithPrefix returns up to limit indexed terms starting with prefix in
+// sorted order, all of them when limit is zero, for autocompletion
+func (idx *Index) TermsWithPrefix(prefix string, limit int) []string {
+	prefix = strings.ToLower(prefix)
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := []string{}
+	idx.dict.walk(prefix, func(term string) bool {
+		if !strings.HasPrefix(term, prefix) {
+			return false
+		}
+		terms = append(terms, term)
+		return limit == 0 || len(terms) < limit
+	})
+	return terms
+}
+
+// TermRange returns up to limit indexed terms from from up to but excluding
+// to in sorted order, all of them when limit is zero. An empty to leaves the
+// range open
+func (idx *Index) TermRange(from, to string, limit int) []string {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := []string{}
+	idx.dict.walk(from, func(term string) bool {
+		if to != "" && term >= to {
+			return false
+		}
+		terms = append(terms, term)
+		return limit == 0 || len(terms) < limit
+	})
+	return terms
+}
+
+// matchPattern reports whether the document with the given term
+// frequencies contains a term the automaton accepts, which is how a
+// pattern term expanded against the document's own terms evaluates
+func matchPattern(automaton nfa, terms map[string]int) bool {
+	d := newDFA(automaton)
+	for term, count := range terms {
+		if count > 0 && d.matches(term) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPatterns rewrites a query's pattern terms into the OR of the
+// dictionary terms they match, returning queries without patterns as is
+func expandPatterns(compiled *Query, dict *termDictionary) *Query {
+	if len(compiled.patterns) == 0 {
+		return compiled
+	}
+	tokens, err := parseQuery(compiled.source)
+	if err != nil {
+		return compiled
+	}
+
+	expanded := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		automaton, ok := compiled.patterns[token]
+		if !ok {
+			expanded = append(expanded, token)
+			continue
+		}
+		matches := []string{}
+		dict.intersect(newDFA(automaton), func(term string) bool {
+			matches = append(matches, term)
+			return len(matches) < maxPatternExpansions
+		})
+		switch len(matches) {
+		case 0:
+			expanded = append(expanded, noMatchTerm)
+		case 1:
+			expanded = append(expanded, matches[0])
+		default:
+			expanded = append(expanded, "(")
+			for i, term := range matches {
+				if i > 0 {
+					expanded = append(expanded, "OR")
+				}
+				expanded = append(expanded, term)
+			}
+			expanded = append(expanded, ")")
+		}
+	}
+
+	rewritten, err := compileTokens(compiled.source, expanded)
+	if err != nil {
+		return compiled
+	}
+	return rewritten
+}
@@ -0,0 +1,126 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// JudgedQuery is a query with the graded relevance of documents for it,
+// keyed by document ID. Grades above zero are relevant, higher is better,
+// and unjudged documents count as irrelevant
+type JudgedQuery struct {
+	Query     string      `json:"query"`
+	Judgments map[int]int `json:"judgments"`
+}
+
+// QueryMetrics measures the ranking of a single judged query
+type QueryMetrics struct {
+	Query     string
+	Hits      int
+	Precision float64
+	Recall    float64
+	NDCG      float64
+}
+
+// EvaluationReport holds the metrics of every judged query and their means
+type EvaluationReport struct {
+	Queries       []QueryMetrics
+	MeanPrecision float64
+	MeanRecall    float64
+	MeanNDCG      float64
+}
+
+// ReadJudgments decodes a JSON array of judged queries
+func ReadJudgments(r io.Reader) ([]JudgedQuery, error) {
+	var judged []JudgedQuery
+	if err := json.NewDecoder(r).Decode(&judged); err != nil {
+		return nil, fmt.Errorf("invalid judgments: %w", err)
+	}
+	return judged, nil
+}
+
+// EvaluateSearch runs every judged query against the index and measures
+// precision, recall and nDCG over the top k hits, or all hits when k is
+// zero, so analyzer and scorer changes can be compared
+func EvaluateSearch(idx *Index, judged []JudgedQuery, opts SearchOptions, k int) (EvaluationReport, error) {
+	report := EvaluationReport{}
+	opts.Size = k
+	opts.CountOnly = false
+
+	for _, query := range judged {
+		result, err := idx.Search(query.Query, opts)
+		if err != nil {
+			return EvaluationReport{}, fmt.Errorf("query %q: %w", query.Query, err)
+		}
+		metrics := measureRanking(query, result.Hits, k)
+		report.Queries = append(report.Queries, metrics)
+		report.MeanPrecision += metrics.Precision
+		report.MeanRecall += metrics.Recall
+		report.MeanNDCG += metrics.NDCG
+	}
+
+	if count := float64(len(report.Queries)); count > 0 {
+		report.MeanPrecision /= count
+		report.MeanRecall /= count
+		report.MeanNDCG /= count
+	}
+	return report, nil
+}
+
+// measureRanking computes the metrics of ranked hits against judgments
+func measureRanking(query JudgedQuery, hits []Hit, k int) QueryMetrics {
+	metrics := QueryMetrics{Query: query.Query, Hits: len(hits)}
+
+	relevant := 0
+	ideal := []int{}
+	for _, grade := range query.Judgments {
+		if grade > 0 {
+			relevant++
+			ideal = append(ideal, grade)
+		}
+	}
+
+	retrieved := 0
+	dcg := 0.0
+	for rank, hit := range hits {
+		grade := query.Judgments[hit.ID]
+		if grade > 0 {
+			retrieved++
+		}
+		dcg += gain(grade, rank)
+	}
+
+	// Precision at k counts missing hits below k as irrelevant
+	cutoff := k
+	if cutoff == 0 {
+		cutoff = len(hits)
+	}
+	if cutoff > 0 {
+		metrics.Precision = float64(retrieved) / float64(cutoff)
+	}
+	if relevant > 0 {
+		metrics.Recall = float64(retrieved) / float64(relevant)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(ideal)))
+	if k > 0 && len(ideal) > k {
+		ideal = ideal[:k]
+	}
+	idealDCG := 0.0
+	for rank, grade := range ideal {
+		idealDCG += gain(grade, rank)
+	}
+	if idealDCG > 0 {
+		metrics.NDCG = dcg / idealDCG
+	}
+	return metrics
+}
+
+// gain is the discounted gain of a document with a relevance grade at a
+// zero-based rank
+func gain(grade, rank int) float64 {
+	return (math.Pow(2, float64(grade)) - 1) / math.Log2(float64(rank+2))
+}
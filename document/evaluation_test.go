@@ -0,0 +1,101 @@
+package document
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMeasureRanking(t *testing.T) {
+	judgments := map[int]int{1: 2, 3: 1, 5: 1, 7: 0}
+	tests := []struct {
+		name      string
+		hits      []int
+		k         int
+		precision float64
+		recall    float64
+		ndcg      float64
+	}{
+		{"all hits", []int{1, 2, 3}, 0, 2.0 / 3, 2.0 / 3, 0.8472668887613066},
+		{"ideal ranking", []int{1, 3, 5}, 0, 1, 1, 1},
+		{"cut off at k", []int{3}, 1, 1, 1.0 / 3, 1.0 / 3},
+		{"fewer hits than k", []int{1}, 4, 0.25, 1.0 / 3, 3 / (3 + 1/math.Log2(3) + 0.5)},
+		{"irrelevant hits", []int{2, 7}, 0, 0, 0, 0},
+		{"no hits", nil, 0, 0, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hits := []Hit{}
+			for _, id := range test.hits {
+				hits = append(hits, Hit{ID: id})
+			}
+			got := measureRanking(JudgedQuery{Query: "q", Judgments: judgments}, hits, test.k)
+			if got.Query != "q" || got.Hits != len(test.hits) {
+				t.Errorf("measureRanking() = %+v, want query q with %d hits", got, len(test.hits))
+			}
+			for _, metric := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"Precision", got.Precision, test.precision},
+				{"Recall", got.Recall, test.recall},
+				{"NDCG", got.NDCG, test.ndcg},
+			} {
+				if math.Abs(metric.got-metric.want) > 1e-9 {
+					t.Errorf("%s = %g, want %g", metric.name, metric.got, metric.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateSearch(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range builderTestDocuments {
+		idx.Add(text)
+	}
+	judged, err := ReadJudgments(strings.NewReader(`[
+		{"query": "python", "judgments": {"0": 2, "2": 1, "4": 1}},
+		{"query": "rust", "judgments": {"3": 1, "1": 1}},
+		{"query": "missing", "judgments": {"5": 1}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := EvaluateSearch(idx, judged, SearchOptions{CountOnly: true}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []QueryMetrics{
+		{Query: "python", Hits: 3, Precision: 0.3, Recall: 1},
+		{Query: "rust", Hits: 1, Precision: 0.1, Recall: 0.5},
+		{Query: "missing", Hits: 0, Precision: 0, Recall: 0},
+	}
+	if len(report.Queries) != len(want) {
+		t.Fatalf("measured %d queries, want %d", len(report.Queries), len(want))
+	}
+	for i, metrics := range report.Queries {
+		if metrics.Query != want[i].Query || metrics.Hits != want[i].Hits ||
+			math.Abs(metrics.Precision-want[i].Precision) > 1e-9 || math.Abs(metrics.Recall-want[i].Recall) > 1e-9 {
+			t.Errorf("Queries[%d] = %+v, want %+v", i, metrics, want[i])
+		}
+	}
+	if math.Abs(report.MeanPrecision-0.4/3) > 1e-9 || math.Abs(report.MeanRecall-0.5) > 1e-9 {
+		t.Errorf("means = P %g R %g, want P %g R 0.5", report.MeanPrecision, report.MeanRecall, 0.4/3)
+	}
+	if want := (report.Queries[0].NDCG + report.Queries[1].NDCG) / 3; math.Abs(report.MeanNDCG-want) > 1e-9 {
+		t.Errorf("MeanNDCG = %g, want %g", report.MeanNDCG, want)
+	}
+}
+
+func TestEvaluateSearchErrors(t *testing.T) {
+	if _, err := ReadJudgments(strings.NewReader(`{"query": "python"}`)); err == nil || !strings.HasPrefix(err.Error(), "invalid judgments: ") {
+		t.Errorf("ReadJudgments() error = %v, want invalid judgments", err)
+	}
+
+	_, err := EvaluateSearch(NewIndex(), []JudgedQuery{{Query: "python AND"}}, SearchOptions{}, 0)
+	if err == nil || !strings.HasPrefix(err.Error(), `query "python AND": `) {
+		t.Errorf("EvaluateSearch() error = %v, want the failing query", err)
+	}
+}
@@ -0,0 +1,92 @@
+package document
+
+import (
+	"time"
+)
+
+// EventType identifies the kind of index change an event describes
+type EventType int
+
+const (
+	// EventDocumentAdded is emitted when a document is added
+	EventDocumentAdded EventType = iota
+	// EventDocumentUpdated is emitted when a document's text is replaced
+	EventDocumentUpdated
+	// EventDocumentDeleted is emitted when a document is deleted
+	EventDocumentDeleted
+	// EventSegmentMerged is emitted when a bulk-built segment replaces the
+	// contents of the index through Swap
+	EventSegmentMerged
+)
+
+// String returns the name of the event type
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventDocumentAdded:
+		return "added"
+	case EventDocumentUpdated:
+		return "updated"
+	case EventDocumentDeleted:
+		return "deleted"
+	case EventSegmentMerged:
+		return "segment-merged"
+	}
+	return "unknown"
+}
+
+// IndexEvent describes a single change to an index. Seq increases by one
+// for every event of the index, so subscribers can detect gaps
+type IndexEvent struct {
+	Seq  uint64
+	Type EventType
+	ID   int
+	Text string
+	Time time.Time
+	// Docs holds the texts of the new index contents for EventSegmentMerged,
+	// indexed by document ID with the IDs listed in Deleted left empty
+	Docs    []string
+	Deleted []int
+}
+
+// Subscribe registers handler to be called with every subsequent index
+// event, in order. Handlers run synchronously after the change is visible
+// and must not mutate the index themselves. The returned function cancels
+// the subscription
+func (idx *Index) Subscribe(handler func(IndexEvent)) func() {
+	idx.publishMu.Lock()
+	defer idx.publishMu.Unlock()
+
+	if idx.subscribers == nil {
+		idx.subscribers = make(map[int]func(IndexEvent))
+	}
+	id := idx.nextSubscriber
+	idx.nextSubscriber++
+	idx.subscribers[id] = handler
+
+	return func() {
+		idx.publishMu.Lock()
+		defer idx.publishMu.Unlock()
+		delete(idx.subscribers, id)
+	}
+}
+
+// recordEvent creates the next event, the caller must hold the write lock
+func (idx *Index) recordEvent(eventType EventType, id int, text string) IndexEvent {
+	idx.seq++
+	return IndexEvent{Seq: idx.seq, Type: eventType, ID: id, Text: text, Time: time.Now()}
+}
+
+// unlockAndPublish releases the write lock and delivers events to every
+// subscriber. The publish lock is taken before the write lock is released
+// so events from concurrent mutations are delivered in sequence order
+func (idx *Index) unlockAndPublish(events []IndexEvent) {
+	idx.publishMu.Lock()
+	idx.mu.Unlock()
+	defer idx.publishMu.Unlock()
+
+	for _, event := range events {
+		for _, handler := range idx.subscribers {
+			handler(event)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package document
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSubscribeEmitsEvents(t *testing.T) {
+	idx := NewIndex()
+	events := []IndexEvent{}
+	cancel := idx.Subscribe(func(event IndexEvent) {
+		events = append(events, event)
+	})
+
+	first := idx.Add("first document")
+	second := idx.Add("second document")
+	if err := idx.Update(first, "first revised"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Delete(second); err != nil {
+		t.Fatal(err)
+	}
+	builder := NewIndexBuilder()
+	builder.Add("rebuilt")
+	idx.Swap(builder.Build())
+
+	want := []struct {
+		eventType EventType
+		id        int
+		text      string
+	}{
+		{EventDocumentAdded, first, "first document"},
+		{EventDocumentAdded, second, "second document"},
+		{EventDocumentUpdated, first, "first revised"},
+		{EventDocumentDeleted, second, ""},
+		{EventSegmentMerged, -1, ""},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, event := range events {
+		if event.Seq != uint64(i+1) {
+			t.Errorf("event %d Seq = %d, want %d", i, event.Seq, i+1)
+		}
+		if event.Type != want[i].eventType || event.Text != want[i].text {
+			t.Errorf("event %d = %s %q, want %s %q", i, event.Type, event.Text, want[i].eventType, want[i].text)
+		}
+		if want[i].id >= 0 && event.ID != want[i].id {
+			t.Errorf("event %d ID = %d, want %d", i, event.ID, want[i].id)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+	}
+	if merged := events[4]; len(merged.Docs) != 1 || merged.Docs[0] != "rebuilt" {
+		t.Errorf("segment merge Docs = %q, want [rebuilt]", merged.Docs)
+	}
+
+	cancel()
+	idx.Add("after cancel")
+	if len(events) != len(want) {
+		t.Errorf("cancelled subscriber received %d more events", len(events)-len(want))
+	}
+}
+
+func TestEventTypeString(t *testing.T) {
+	tests := []struct {
+		eventType EventType
+		want      string
+	}{
+		{EventDocumentAdded, "added"},
+		{EventDocumentUpdated, "updated"},
+		{EventDocumentDeleted, "deleted"},
+		{EventSegmentMerged, "segment-merged"},
+		{EventType(99), "unknown"},
+	}
+	for _, test := range tests {
+		if got := test.eventType.String(); got != test.want {
+			t.Errorf("EventType(%d).String() = %q, want %q", test.eventType, got, test.want)
+		}
+	}
+}
+
+// TestConcurrentMutationsPublishInOrder adds documents from many goroutines,
+// whose events must reach a subscriber in sequence order without gaps
+func TestConcurrentMutationsPublishInOrder(t *testing.T) {
+	idx := NewIndex()
+	var last uint64
+	outOfOrder := 0
+	idx.Subscribe(func(event IndexEvent) {
+		if event.Seq != last+1 {
+			outOfOrder++
+		}
+		last = event.Seq
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				idx.Add("concurrent document")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if outOfOrder != 0 || last != 400 {
+		t.Errorf("%d events out of order, last Seq %d, want 400", outOfOrder, last)
+	}
+}
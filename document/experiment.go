@@ -0,0 +1,98 @@
+package document
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// Variant is one arm of a query experiment
+type Variant struct {
+	Name    string
+	Query   *Query
+	Options SearchOptions
+}
+
+// VariantResult is the outcome of running one variant for a request
+type VariantResult struct {
+	Variant string
+	Result  SearchResult
+	Latency time.Duration
+	// Err is set when the variant failed, such as by exceeding its
+	// MemoryBudget, and Result is then empty
+	Err error
+}
+
+// ExperimentRecord logs both variants of a single experiment request
+type ExperimentRecord struct {
+	Experiment string
+	RequestKey string
+	Time       time.Time
+	Control    VariantResult
+	Treatment  VariantResult
+	// Served names the variant whose result was returned
+	Served string
+}
+
+// Experiment runs a control and a treatment query for every request, logs
+// both result sets with their latencies and serves one of them, so query
+// relevance changes can be compared on live traffic
+type Experiment struct {
+	Name      string
+	Control   Variant
+	Treatment Variant
+	// Bucket reports whether a request is served the treatment
+	Bucket func(requestKey string) bool
+	// Log, if not nil, receives the record of every request
+	Log func(ExperimentRecord)
+}
+
+// NewExperiment creates an experiment serving the treatment to half the requests
+func NewExperiment(name string, control, treatment Variant) *Experiment {
+	return &Experiment{
+		Name:      name,
+		Control:   control,
+		Treatment: treatment,
+		Bucket:    HashBucket(50),
+	}
+}
+
+// HashBucket returns a bucketing function assigning percent of request keys
+// to the treatment. The same key always lands in the same bucket, so a user
+// keyed by ID sees consistent results
+func HashBucket(percent int) func(requestKey string) bool {
+	return func(requestKey string) bool {
+		hash := fnv.New32a()
+		hash.Write([]byte(requestKey))
+		return int(hash.Sum32()%100) < percent
+	}
+}
+
+// Run executes both variants against idx, logs them and returns the result
+// of the variant the request key is bucketed into, along with its name
+func (exp *Experiment) Run(idx *Index, requestKey string) (SearchResult, string) {
+	record := ExperimentRecord{
+		Experiment: exp.Name,
+		RequestKey: requestKey,
+		Time:       time.Now(),
+		Control:    runVariant(idx, exp.Control),
+		Treatment:  runVariant(idx, exp.Treatment),
+	}
+
+	served := record.Control
+	if exp.Bucket != nil && exp.Bucket(requestKey) {
+		served = record.Treatment
+	}
+	record.Served = served.Variant
+
+	if exp.Log != nil {
+		exp.Log(record)
+	}
+	return served.Result, served.Variant
+}
+
+// runVariant searches with a variant and measures how long it took
+func runVariant(idx *Index, variant Variant) VariantResult {
+	start := time.Now()
+	result, err := idx.SearchCompiled(variant.Query, variant.Options)
+	return VariantResult{Variant: variant.Name, Result: result, Latency: time.Since(start), Err: err}
+}
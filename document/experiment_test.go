@@ -0,0 +1,110 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHashBucket(t *testing.T) {
+	tests := []struct {
+		percent  int
+		min, max int
+	}{
+		{0, 0, 0},
+		{10, 50, 150},
+		{50, 400, 600},
+		{100, 1000, 1000},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d percent", test.percent), func(t *testing.T) {
+			bucket := HashBucket(test.percent)
+			treated := 0
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("user-%d", i)
+				got := bucket(key)
+				if got != bucket(key) {
+					t.Fatalf("bucket(%q) is not stable", key)
+				}
+				if got {
+					treated++
+				}
+			}
+			if treated < test.min || treated > test.max {
+				t.Errorf("%d of 1000 keys treated, want %d to %d", treated, test.min, test.max)
+			}
+		})
+	}
+}
+
+func TestExperimentRun(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range builderTestDocuments {
+		idx.Add(text)
+	}
+	control := Variant{Name: "control", Query: mustCompileQuery(t, "python")}
+	treatment := Variant{Name: "treatment", Query: mustCompileQuery(t, "python OR java")}
+	failing := Variant{
+		Name:    "failing",
+		Query:   mustCompileQuery(t, "python OR java"),
+		Options: SearchOptions{MemoryBudget: 1},
+	}
+
+	tests := []struct {
+		name      string
+		treatment Variant
+		treat     bool
+		served    string
+		total     int
+	}{
+		{"control served", treatment, false, "control", 3},
+		{"treatment served", treatment, true, "treatment", 4},
+		{"failed treatment served", failing, true, "failing", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var records []ExperimentRecord
+			exp := NewExperiment("or-java", control, test.treatment)
+			exp.Bucket = func(requestKey string) bool {
+				if requestKey != "user-1" {
+					t.Errorf("Bucket(%q), want the request key user-1", requestKey)
+				}
+				return test.treat
+			}
+			exp.Log = func(record ExperimentRecord) { records = append(records, record) }
+
+			result, served := exp.Run(idx, "user-1")
+			if served != test.served || result.Total != test.total {
+				t.Errorf("Run() = %d hits from %q, want %d from %q", result.Total, served, test.total, test.served)
+			}
+			if len(records) != 1 {
+				t.Fatalf("logged %d records, want 1", len(records))
+			}
+			record := records[0]
+			if record.Experiment != "or-java" || record.RequestKey != "user-1" || record.Served != test.served || record.Time.IsZero() {
+				t.Errorf("record = %+v", record)
+			}
+			// Both variants run and are logged whichever is served
+			if record.Control.Variant != "control" || record.Control.Err != nil || record.Control.Result.Total != 3 {
+				t.Errorf("Control = %+v, want 3 hits", record.Control)
+			}
+			if record.Treatment.Variant != test.treatment.Name {
+				t.Errorf("Treatment.Variant = %q, want %q", record.Treatment.Variant, test.treatment.Name)
+			}
+			var limitErr *MemoryLimitError
+			if failed := errors.As(record.Treatment.Err, &limitErr); failed != (test.treatment.Name == "failing") {
+				t.Errorf("Treatment.Err = %v", record.Treatment.Err)
+			}
+		})
+	}
+}
+
+// mustCompileQuery compiles a query, failing the test on errors
+func mustCompileQuery(t *testing.T, query string) *Query {
+	t.Helper()
+	compiled, err := CompileQuery(query)
+	if err != nil {
+		t.Fatalf("CompileQuery(%q): %v", query, err)
+	}
+	return compiled
+}
@@ -0,0 +1,197 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fst is an immutable minimal acyclic transducer over the runes of a sorted
+// set of terms. Terms sharing a prefix share its arcs and terms sharing a
+// suffix share its nodes, so the dictionary costs far less than the terms
+// themselves. Each term's output is its ordinal in sorted order, the sum
+// along its path of the terms skipped over, so the terms can be numbered
+// without storing them
+type fst struct {
+	nodes []fstNode
+	arcs  []fstArc
+	root  int32
+}
+
+// fstNode is a state of the transducer, its arcs are arcs[first:first+count]
+// sorted by label and terms counts the terms accepted from it
+type fstNode struct {
+	first int32
+	count int32
+	terms int32
+	final bool
+}
+
+// fstArc is a labelled transition to a node
+type fstArc struct {
+	label  rune
+	target int32
+}
+
+// fstBuilderNode is a node on the path of the last added term, not yet
+// frozen into the transducer because later terms may still add arcs to it
+type fstBuilderNode struct {
+	arcs  []fstArc
+	final bool
+}
+
+// buildFST builds the minimal transducer accepting terms, which must be
+// sorted, by freezing each node once no later term can reach it and merging
+// it with an equivalent frozen node when there is one
+func buildFST(terms []string) *fst {
+	automaton := &fst{}
+	register := make(map[string]int32)
+	path := []*fstBuilderNode{{}}
+	previous := []rune(nil)
+
+	// freeze replaces the nodes of path beyond depth with frozen ones
+	freeze := func(depth int) {
+		for i := len(path) - 1; i > depth; i-- {
+			node := automaton.freeze(path[i], register)
+			parent := path[i-1]
+			parent.arcs[len(parent.arcs)-1].target = node
+		}
+		path = path[:depth+1]
+	}
+
+	for i, term := range terms {
+		if i > 0 && term == terms[i-1] {
+			continue
+		}
+		runes := []rune(term)
+		shared := 0
+		for shared < len(previous) && shared < len(runes) && previous[shared] == runes[shared] {
+			shared++
+		}
+		freeze(shared)
+		for _, label := range runes[shared:] {
+			parent := path[len(path)-1]
+			parent.arcs = append(parent.arcs, fstArc{label: label, target: -1})
+			path = append(path, &fstBuilderNode{})
+		}
+		path[len(path)-1].final = true
+		previous = runes
+	}
+
+	freeze(0)
+	automaton.root = automaton.freeze(path[0], register)
+	return automaton
+}
+
+// freeze adds a builder node to the transducer, or returns the equivalent
+// node already in it: one as final with the same arcs to the same nodes
+func (automaton *fst) freeze(node *fstBuilderNode, register map[string]int32) int32 {
+	var key strings.Builder
+	fmt.Fprint(&key, node.final)
+	for _, arc := range node.arcs {
+		fmt.Fprintf(&key, " %d:%d", arc.label, arc.target)
+	}
+	if id, ok := register[key.String()]; ok {
+		return id
+	}
+
+	frozen := fstNode{first: int32(len(automaton.arcs)), count: int32(len(node.arcs)), final: node.final}
+	if node.final {
+		frozen.terms++
+	}
+	for _, arc := range node.arcs {
+		frozen.terms += automaton.nodes[arc.target].terms
+	}
+	automaton.arcs = append(automaton.arcs, node.arcs...)
+
+	id := int32(len(automaton.nodes))
+	automaton.nodes = append(automaton.nodes, frozen)
+	register[key.String()] = id
+	return id
+}
+
+// size returns the number of terms the transducer accepts
+func (automaton *fst) size() int {
+	return int(automaton.nodes[automaton.root].terms)
+}
+
+// arcsOf returns the arcs leaving a node
+func (automaton *fst) arcsOf(node int32) []fstArc {
+	n := automaton.nodes[node]
+	return automaton.arcs[n.first : n.first+n.count]
+}
+
+// ordinal returns the position of term among the accepted terms in sorted
+// order, reporting false when the term is not accepted
+func (automaton *fst) ordinal(term string) (int, bool) {
+	node, ordinal := automaton.root, 0
+	for _, label := range term {
+		if automaton.nodes[node].final {
+			ordinal++
+		}
+		arcs := automaton.arcsOf(node)
+		at := sort.Search(len(arcs), func(i int) bool { return arcs[i].label >= label })
+		if at == len(arcs) || arcs[at].label != label {
+			return 0, false
+		}
+		for _, arc := range arcs[:at] {
+			ordinal += int(automaton.nodes[arc.target].terms)
+		}
+		node = arcs[at].target
+	}
+	return ordinal, automaton.nodes[node].final
+}
+
+// walk calls visit with every accepted term not sorting before from, in
+// order, until visit returns false
+func (automaton *fst) walk(from string, visit func(term string) bool) {
+	automaton.walkFrom(automaton.root, make([]rune, 0, 16), []rune(from), visit)
+}
+
+// walkFrom visits the terms below node, whose path spells prefix, that do
+// not sort before prefix followed by bound. It reports false once visit did
+func (automaton *fst) walkFrom(node int32, prefix, bound []rune, visit func(term string) bool) bool {
+	if automaton.nodes[node].final && len(bound) == 0 && !visit(string(prefix)) {
+		return false
+	}
+	for _, arc := range automaton.arcsOf(node) {
+		next := []rune(nil)
+		if len(bound) > 0 {
+			if arc.label < bound[0] {
+				continue
+			}
+			if arc.label == bound[0] {
+				next = bound[1:]
+			}
+		}
+		if !automaton.walkFrom(arc.target, append(prefix, arc.label), next, visit) {
+			return false
+		}
+	}
+	return true
+}
+
+// intersect calls visit with every term accepted by both the transducer
+// and a DFA, in order, until visit returns false. Branches are abandoned as
+// soon as the DFA dies, so the cost follows the matching terms rather than
+// the size of the dictionary
+func (automaton *fst) intersect(d *dfa, visit func(term string) bool) {
+	automaton.intersectFrom(automaton.root, d.initial, make([]rune, 0, 16), d, visit)
+}
+
+// intersectFrom visits the terms below node accepted from the DFA state
+func (automaton *fst) intersectFrom(node int32, state int, prefix []rune, d *dfa, visit func(term string) bool) bool {
+	if automaton.nodes[node].final && d.accepts(state) && !visit(string(prefix)) {
+		return false
+	}
+	for _, arc := range automaton.arcsOf(node) {
+		next := d.step(state, arc.label)
+		if next == deadState {
+			continue
+		}
+		if !automaton.intersectFrom(arc.target, next, append(prefix, arc.label), d, visit) {
+			return false
+		}
+	}
+	return true
+}
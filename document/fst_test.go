@@ -0,0 +1,181 @@
+package document
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// fstTestTerms are sorted terms sharing prefixes and suffixes
+var fstTestTerms = []string{"a", "ab", "abc", "band", "bandana", "bank", "banks", "can", "cañon", "tap", "taps", "top", "tops", "zebra"}
+
+// collect gathers the terms a walk visits
+func collect(walk func(visit func(term string) bool)) []string {
+	terms := []string{}
+	walk(func(term string) bool {
+		terms = append(terms, term)
+		return true
+	})
+	return terms
+}
+
+// termsFrom returns the sorted terms not sorting before from
+func termsFrom(terms []string, from string) []string {
+	return terms[sort.SearchStrings(terms, from):]
+}
+
+func TestFSTWalk(t *testing.T) {
+	automaton := buildFST(fstTestTerms)
+	if automaton.size() != len(fstTestTerms) {
+		t.Errorf("size() = %d, want %d", automaton.size(), len(fstTestTerms))
+	}
+	for _, from := range []string{"", "a", "aa", "abd", "ban", "bandz", "banks", "c", "cañ", "cao", "tops", "topz", "zz"} {
+		t.Run(from, func(t *testing.T) {
+			got := collect(func(visit func(string) bool) { automaton.walk(from, visit) })
+			if want := termsFrom(fstTestTerms, from); !slices.Equal(got, want) {
+				t.Errorf("walk(%q) = %v, want %v", from, got, want)
+			}
+		})
+	}
+}
+
+func TestFSTOrdinal(t *testing.T) {
+	automaton := buildFST(fstTestTerms)
+	for want, term := range fstTestTerms {
+		if got, ok := automaton.ordinal(term); !ok || got != want {
+			t.Errorf("ordinal(%q) = %d, %v, want %d", term, got, ok, want)
+		}
+	}
+	for _, term := range []string{"", "b", "ban", "bandanas", "ca", "tapss", "zebras"} {
+		if _, ok := automaton.ordinal(term); ok {
+			t.Errorf("ordinal(%q) accepted a missing term", term)
+		}
+	}
+}
+
+func TestFSTIsMinimal(t *testing.T) {
+	tests := []struct {
+		terms []string
+		nodes int
+	}{
+		{nil, 1},
+		{[]string{"a"}, 2},
+		// root -t-> -a,o-> -p-> final -s-> final
+		{[]string{"tap", "taps", "top", "tops"}, 5},
+		// the shared "ing" suffix is stored once
+		{[]string{"going", "seeing", "tying"}, 9},
+		{[]string{"x", "x", "y"}, 2},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.terms), func(t *testing.T) {
+			automaton := buildFST(test.terms)
+			if len(automaton.nodes) != test.nodes {
+				t.Errorf("built %d nodes, want %d", len(automaton.nodes), test.nodes)
+			}
+		})
+	}
+}
+
+func TestTermDictionaryOverlay(t *testing.T) {
+	dict := newTermDictionary(fstTestTerms)
+	want := slices.Clone(fstTestTerms)
+	apply := func(term string, add bool) {
+		at, found := slices.BinarySearch(want, term)
+		switch {
+		case add && !found:
+			dict.insert(term)
+			want = slices.Insert(want, at, term)
+		case !add && found:
+			dict.remove(term)
+			want = slices.Delete(want, at, at+1)
+		}
+	}
+
+	// Enough changes to rebuild the transducer several times over
+	for i := 0; i < 3*minDictionaryOverlay; i++ {
+		apply(fmt.Sprintf("term%04d", i*7%1000), true)
+		if i%3 == 0 {
+			apply(fmt.Sprintf("term%04d", i*5%1000), false)
+		}
+		if i < len(fstTestTerms) && i%2 == 0 {
+			apply(fstTestTerms[i], false)
+		}
+		if i == 50 {
+			apply("band", true)
+		}
+	}
+
+	if dict.size() != len(want) {
+		t.Errorf("size() = %d, want %d", dict.size(), len(want))
+	}
+	for _, from := range []string{"", "b", "term05", "term0999", "zz"} {
+		got := collect(func(visit func(string) bool) { dict.walk(from, visit) })
+		if expected := termsFrom(want, from); !slices.Equal(got, expected) {
+			t.Errorf("walk(%q) = %d terms, want %d", from, len(got), len(expected))
+		}
+	}
+
+	automaton, _, err := compileTermPattern("term00?7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collect(func(visit func(string) bool) { dict.intersect(newDFA(automaton), visit) })
+	expected := []string{}
+	for _, term := range want {
+		if len(term) == 8 && term[:6] == "term00" && term[7] == '7' {
+			expected = append(expected, term)
+		}
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("intersect(term00?7) = %v, want %v", got, expected)
+	}
+}
+
+func TestTermDictionaryMergeStopsEarly(t *testing.T) {
+	dict := newTermDictionary([]string{"b", "d"})
+	dict.insert("a")
+	dict.insert("c")
+	dict.insert("e")
+	for limit := 1; limit <= 5; limit++ {
+		got := []string{}
+		dict.walk("", func(term string) bool {
+			got = append(got, term)
+			return len(got) < limit
+		})
+		if want := []string{"a", "b", "c", "d", "e"}[:limit]; !slices.Equal(got, want) {
+			t.Errorf("walk stopping after %d = %v, want %v", limit, got, want)
+		}
+	}
+}
+
+func TestTermsWithPrefixAndRange(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range []string{"bandana bank", "banks band", "Banner cable"} {
+		idx.Add(text)
+	}
+	if err := idx.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	idx.Add("bandit")
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"prefix", idx.TermsWithPrefix("BAN", 0), []string{"bandana", "bandit", "bank", "banner"}},
+		{"prefix limit", idx.TermsWithPrefix("ban", 2), []string{"bandana", "bandit"}},
+		{"prefix missing", idx.TermsWithPrefix("x", 0), []string{}},
+		{"range", idx.TermRange("bandb", "bann", 0), []string{"bandit", "bank"}},
+		{"open range", idx.TermRange("bank", "", 0), []string{"bank", "banner", "cable"}},
+		{"range limit", idx.TermRange("", "", 1), []string{"bandana"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !slices.Equal(test.got, test.want) {
+				t.Errorf("got %v, want %v", test.got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package document
+
+import (
+	"math"
+	"time"
+)
+
+// FieldFunc extracts a numeric value of the document being scored, false if
+// the document has none. Values can come from the document itself, like
+// AddedAt, or from an external store keyed by ScoreInput.DocID
+type FieldFunc func(input ScoreInput) (float64, bool)
+
+// AddedAt is a FieldFunc returning when the document was added, in seconds
+// since the Unix epoch
+func AddedAt(input ScoreInput) (float64, bool) {
+	if input.Added.IsZero() {
+		return 0, false
+	}
+	return float64(input.Added.UnixNano()) / float64(time.Second), true
+}
+
+// Modifier computes a factor a FunctionScore multiplies the text score by
+type Modifier interface {
+	Factor(input ScoreInput) float64
+}
+
+// FunctionScore is a Scorer multiplying the score of Base by the factor of
+// every modifier, so text relevance can be combined with recency or
+// popularity. A nil Base scores every document 1, ranking by modifiers alone
+type FunctionScore struct {
+	Base      Scorer
+	Modifiers []Modifier
+}
+
+// Score implements Scorer
+func (fs FunctionScore) Score(input ScoreInput) float64 {
+	score := 1.0
+	if fs.Base != nil {
+		score = fs.Base.Score(input)
+	}
+	for _, modifier := range fs.Modifiers {
+		score *= modifier.Factor(input)
+	}
+	return score
+}
+
+// DecayFunction selects the shape of a Decay
+type DecayFunction int
+
+const (
+	// DecayGauss falls off along a normal curve
+	DecayGauss DecayFunction = iota
+	// DecayExp falls off exponentially
+	DecayExp
+	// DecayLinear falls off linearly down to zero
+	DecayLinear
+)
+
+// Decay scores documents by how far a field value is from Origin. Within
+// Offset of Origin the factor is 1, at Offset+Scale it is Decay, which
+// defaults to 0.5. Documents without the field get a factor of 1
+type Decay struct {
+	Function DecayFunction
+	Field    FieldFunc
+	Origin   float64
+	Scale    float64
+	Offset   float64
+	Decay    float64
+}
+
+// RecencyDecay returns a gaussian decay halving the score of documents added
+// scale before now, for newer first ranking
+func RecencyDecay(now time.Time, scale time.Duration) Decay {
+	return Decay{
+		Function: DecayGauss,
+		Field:    AddedAt,
+		Origin:   float64(now.UnixNano()) / float64(time.Second),
+		Scale:    scale.Seconds(),
+	}
+}
+
+// Factor implements Modifier
+func (decay Decay) Factor(input ScoreInput) float64 {
+	value, ok := decay.Field(input)
+	if !ok || decay.Scale <= 0 {
+		return 1
+	}
+	target := decay.Decay
+	if target <= 0 || target >= 1 {
+		target = 0.5
+	}
+
+	distance := math.Max(0, math.Abs(value-decay.Origin)-decay.Offset)
+	switch decay.Function {
+	case DecayExp:
+		return math.Exp(math.Log(target) / decay.Scale * distance)
+	case DecayLinear:
+		span := decay.Scale / (1 - target)
+		return math.Max(0, (span-distance)/span)
+	}
+	variance := -decay.Scale * decay.Scale / (2 * math.Log(target))
+	return math.Exp(-distance * distance / (2 * variance))
+}
+
+// FieldValueFactor scores documents by a field value, such as a popularity
+// count, as Multiplier times the value after applying Modifier: "" uses the
+// value as is, "log1p" log(1+v) and "sqrt" its square root. Missing is used
+// for documents without the field
+type FieldValueFactor struct {
+	Field      FieldFunc
+	Multiplier float64
+	Modifier   string
+	Missing    float64
+}
+
+// Factor implements Modifier
+func (fvf FieldValueFactor) Factor(input ScoreInput) float64 {
+	value, ok := fvf.Field(input)
+	if !ok {
+		value = fvf.Missing
+	}
+	multiplier := fvf.Multiplier
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	value *= multiplier
+
+	switch fvf.Modifier {
+	case "log1p":
+		return math.Log1p(math.Max(0, value))
+	case "sqrt":
+		return math.Sqrt(math.Max(0, value))
+	}
+	return value
+}
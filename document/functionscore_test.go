@@ -0,0 +1,106 @@
+package document
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// fieldValue returns a FieldFunc reading values by document ID
+func fieldValue(values map[int]float64) FieldFunc {
+	return func(input ScoreInput) (float64, bool) {
+		value, ok := values[input.DocID]
+		return value, ok
+	}
+}
+
+func TestDecayFactor(t *testing.T) {
+	// Document IDs are the distance of the field value from the origin
+	field := func(input ScoreInput) (float64, bool) {
+		return 100 + float64(input.DocID), input.DocID >= 0
+	}
+	tests := []struct {
+		name     string
+		function DecayFunction
+		decay    float64
+		distance int
+		want     float64
+	}{
+		{"gauss at origin", DecayGauss, 0, 0, 1},
+		{"gauss within offset", DecayGauss, 0, 5, 1},
+		{"gauss at scale", DecayGauss, 0, 15, 0.5},
+		{"gauss at twice scale", DecayGauss, 0, 25, 0.0625},
+		{"exp at scale", DecayExp, 0, 15, 0.5},
+		{"exp at twice scale", DecayExp, 0, 25, 0.25},
+		{"exp custom decay", DecayExp, 0.2, 15, 0.2},
+		{"linear at scale", DecayLinear, 0, 15, 0.5},
+		{"linear at zero", DecayLinear, 0, 25, 0},
+		{"linear beyond zero", DecayLinear, 0, 40, 0},
+		{"missing field", DecayGauss, 0, -1, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decay := Decay{Function: test.function, Field: field, Origin: 100, Scale: 10, Offset: 5, Decay: test.decay}
+			if got := decay.Factor(ScoreInput{DocID: test.distance}); math.Abs(got-test.want) > 1e-12 {
+				t.Errorf("Factor() at distance %d = %v, want %v", test.distance, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFieldValueFactor(t *testing.T) {
+	popularity := fieldValue(map[int]float64{0: 99, 1: 16})
+	tests := []struct {
+		name   string
+		factor FieldValueFactor
+		docID  int
+		want   float64
+	}{
+		{"value", FieldValueFactor{Field: popularity}, 1, 16},
+		{"multiplier", FieldValueFactor{Field: popularity, Multiplier: 2}, 1, 32},
+		{"sqrt", FieldValueFactor{Field: popularity, Modifier: "sqrt"}, 1, 4},
+		{"log1p", FieldValueFactor{Field: popularity, Modifier: "log1p"}, 0, math.Log(100)},
+		{"missing", FieldValueFactor{Field: popularity, Missing: 3}, 7, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.factor.Factor(ScoreInput{DocID: test.docID}); math.Abs(got-test.want) > 1e-12 {
+				t.Errorf("Factor() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFunctionScoreRanking(t *testing.T) {
+	idx := NewIndex()
+	old := idx.Add("python guide")
+	time.Sleep(20 * time.Millisecond)
+	recent := idx.Add("python guide")
+	popularity := fieldValue(map[int]float64{old: 10, recent: 1})
+
+	tests := []struct {
+		name   string
+		scorer Scorer
+		first  int
+	}{
+		{"recency", FunctionScore{Base: TFIDFScorer{}, Modifiers: []Modifier{RecencyDecay(time.Now(), 10*time.Millisecond)}}, recent},
+		{"popularity", FunctionScore{Base: TFIDFScorer{}, Modifiers: []Modifier{FieldValueFactor{Field: popularity}}}, old},
+		{"modifiers only", FunctionScore{Modifiers: []Modifier{FieldValueFactor{Field: popularity}}}, old},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := idx.Search("python", SearchOptions{Scorer: test.scorer})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Hits) != 2 || result.Hits[0].ID != test.first || result.Hits[0].Score <= result.Hits[1].Score {
+				t.Errorf("hits = %+v, want %d ranked first", result.Hits, test.first)
+			}
+		})
+	}
+
+	score := FunctionScore{Modifiers: []Modifier{FieldValueFactor{Field: popularity}}}.Score(ScoreInput{DocID: old})
+	if score != 10 {
+		t.Errorf("Score() without a base = %v, want the factor 10", score)
+	}
+}
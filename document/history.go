@@ -0,0 +1,164 @@
+package document
+
+import (
+	"fmt"
+	"time"
+)
+
+// tombstone is a deleted or replaced version of a document, kept for
+// time-travel searches until it falls out of the retention window
+type tombstone struct {
+	id      int
+	doc     *PreparedDoc
+	added   time.Time
+	removed time.Time
+}
+
+// IndexView is a read-only view of an index as it looked at a past time
+type IndexView struct {
+	idx *Index
+	at  time.Time
+}
+
+// SetRetention keeps deleted and replaced documents searchable through AsOf
+// for d after their removal. Zero, the default, discards them immediately
+func (idx *Index) SetRetention(d time.Duration) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	now := time.Now()
+	if idx.retention == 0 && d > 0 {
+		// Nothing removed before now was kept, so the window starts here
+		idx.historySince = now
+	}
+	idx.retention = d
+	idx.pruneLocked(now)
+}
+
+// AsOf returns a view of the index as it looked at the given time. Searches
+// through the view fail when at is older than the retention window
+func (idx *Index) AsOf(at time.Time) *IndexView {
+	return &IndexView{idx: idx, at: at}
+}
+
+// setAddedLocked records when the document stored under id became visible,
+// the caller must hold the write lock
+func (idx *Index) setAddedLocked(id int, at time.Time) {
+	for len(idx.added) <= id {
+		idx.added = append(idx.added, time.Time{})
+	}
+	idx.added[id] = at
+}
+
+// addedAt returns when the document stored under id became visible, the
+// zero time for documents loaded without one
+func (idx *Index) addedAt(id int) time.Time {
+	if id < len(idx.added) {
+		return idx.added[id]
+	}
+	return time.Time{}
+}
+
+// retireLocked keeps the live document stored under id as a tombstone when
+// history is retained, the caller must hold the write lock
+func (idx *Index) retireLocked(id int, now time.Time) {
+	idx.lastRemoval = now
+	if idx.retention == 0 {
+		return
+	}
+	idx.history = append(idx.history, tombstone{id: id, doc: idx.docs[id], added: idx.addedAt(id), removed: now})
+	idx.pruneLocked(now)
+}
+
+// pruneLocked drops tombstones removed before the retention window, the
+// caller must hold the write lock
+func (idx *Index) pruneLocked(now time.Time) {
+	if idx.retention == 0 {
+		idx.history = nil
+		return
+	}
+	// Tombstones are appended in removal order
+	cutoff := now.Add(-idx.retention)
+	expired := 0
+	for expired < len(idx.history) && idx.history[expired].removed.Before(cutoff) {
+		expired++
+	}
+	if expired > 0 {
+		idx.history = append([]tombstone(nil), idx.history[expired:]...)
+	}
+}
+
+// checkRetainedLocked reports an error unless the index still holds every
+// document version visible at the given time, the caller must hold a lock
+func (idx *Index) checkRetainedLocked(at time.Time) error {
+	if !at.Before(idx.lastRemoval) {
+		return nil
+	}
+	horizon := time.Now().Add(-idx.retention)
+	if idx.historySince.After(horizon) {
+		horizon = idx.historySince
+	}
+	if idx.retention == 0 || at.Before(horizon) {
+		return fmt.Errorf("time %s is outside the retention window", at.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// docsAsOfLocked returns the document versions visible at the given time
+// and when each was added, indexed by ID with nil for IDs not live then
+func (idx *Index) docsAsOfLocked(at time.Time) ([]*PreparedDoc, []time.Time) {
+	size := len(idx.docs)
+	for _, entry := range idx.history {
+		size = max(size, entry.id+1)
+	}
+
+	docs := make([]*PreparedDoc, size)
+	added := make([]time.Time, size)
+	for id, doc := range idx.docs {
+		if doc != nil && !idx.addedAt(id).After(at) {
+			docs[id], added[id] = doc, idx.addedAt(id)
+		}
+	}
+	for _, entry := range idx.history {
+		if !entry.added.After(at) && entry.removed.After(at) {
+			docs[entry.id], added[entry.id] = entry.doc, entry.added
+		}
+	}
+	return docs, added
+}
+
+// Search finds the documents that matched a boolean query at the time of the view
+func (view *IndexView) Search(query string, opts SearchOptions) (SearchResult, error) {
+	compiled, err := CompileQuery(query)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	view.idx.mu.RLock()
+	defer view.idx.mu.RUnlock()
+
+	if err := view.idx.checkRetainedLocked(view.at); err != nil {
+		return SearchResult{}, err
+	}
+	docs, added := view.idx.docsAsOfLocked(view.at)
+	if len(compiled.patterns) > 0 {
+		// Patterns expand against the terms of the documents at the time
+		compiled = expandPatterns(compiled, docsDictionary(docs))
+	}
+	return searchDocs(compiled, compiled, docs, added, nil, opts, newMemoryAccount(opts.MemoryBudget))
+}
+
+// Get returns the text a document had at the time of the view
+func (view *IndexView) Get(id int) (string, bool) {
+	view.idx.mu.RLock()
+	defer view.idx.mu.RUnlock()
+
+	if view.idx.checkRetainedLocked(view.at) != nil {
+		return "", false
+	}
+	docs, _ := view.idx.docsAsOfLocked(view.at)
+	if id < 0 || id >= len(docs) || docs[id] == nil {
+		return "", false
+	}
+	return docs[id].text, true
+}
@@ -0,0 +1,124 @@
+package document
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// checkpoint returns the current time, pausing so mutations before and after
+// it get distinct timestamps
+func checkpoint() time.Time {
+	time.Sleep(time.Millisecond)
+	at := time.Now()
+	time.Sleep(time.Millisecond)
+	return at
+}
+
+func TestAsOfSearchesPastVersions(t *testing.T) {
+	idx := NewIndex()
+	idx.SetRetention(time.Hour)
+	beforeAll := checkpoint()
+
+	python := idx.Add("python guide")
+	java := idx.Add("java guide")
+	afterAdds := checkpoint()
+
+	if err := idx.Update(python, "python cookbook"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Delete(java); err != nil {
+		t.Fatal(err)
+	}
+	afterChanges := checkpoint()
+	rust := idx.Add("rust guide")
+
+	tests := []struct {
+		name  string
+		at    time.Time
+		query string
+		want  []int
+	}{
+		{"before any document", beforeAll, "guide", []int{}},
+		{"after adds", afterAdds, "guide", []int{python, java}},
+		{"after adds, old text", afterAdds, "cookbook", []int{}},
+		{"after changes", afterChanges, "guide", []int{}},
+		{"after changes, new text", afterChanges, "cookbook", []int{python}},
+		{"now", time.Now(), "guide", []int{rust}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := idx.AsOf(test.at).Search(test.query, SearchOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []int{}
+			for _, hit := range result.Hits {
+				got = append(got, hit.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("AsOf().Search(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+
+	if text, ok := idx.AsOf(afterAdds).Get(python); !ok || text != "python guide" {
+		t.Errorf("AsOf(afterAdds).Get(%d) = %q, %v, want the original text", python, text, ok)
+	}
+	if _, ok := idx.AsOf(afterChanges).Get(java); ok {
+		t.Errorf("AsOf(afterChanges).Get(%d) found a deleted document", java)
+	}
+	// The live index is unaffected by the retained history
+	if ids := hitIDs(t, idx, "guide"); !slices.Equal(ids, []int{rust}) {
+		t.Errorf("Search(guide) = %v, want [%d]", ids, rust)
+	}
+}
+
+func TestAsOfOutsideRetention(t *testing.T) {
+	t.Run("no retention", func(t *testing.T) {
+		idx := NewIndex()
+		id := idx.Add("python guide")
+		beforeDelete := checkpoint()
+		if err := idx.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := idx.AsOf(beforeDelete).Search("python", SearchOptions{}); err == nil {
+			t.Error("AsOf() before a discarded deletion succeeded")
+		}
+		// Views after the last removal need no history
+		if _, err := idx.AsOf(time.Now()).Search("python", SearchOptions{}); err != nil {
+			t.Errorf("AsOf(now): %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		idx := NewIndex()
+		idx.SetRetention(10 * time.Millisecond)
+		id := idx.Add("python guide")
+		beforeDelete := checkpoint()
+		if err := idx.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := idx.AsOf(beforeDelete).Search("python", SearchOptions{}); err != nil {
+			t.Errorf("AsOf() within retention: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if _, err := idx.AsOf(beforeDelete).Search("python", SearchOptions{}); err == nil {
+			t.Error("AsOf() after the retention window succeeded")
+		}
+	})
+
+	t.Run("before retention was enabled", func(t *testing.T) {
+		idx := NewIndex()
+		id := idx.Add("python guide")
+		beforeRetention := checkpoint()
+		idx.SetRetention(time.Hour)
+		if err := idx.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := idx.AsOf(beforeRetention).Search("python", SearchOptions{}); err == nil {
+			t.Error("AsOf() before retention was enabled succeeded")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+)
+
+// QueryImpact is the difference between the documents two versions of a
+// query match, for reviewing an edit of a saved query before deploying it
+type QueryImpact struct {
+	Old string
+	New string
+	// Gained holds the documents only the new version matches and Lost
+	// those only the old version matches, by ID, scored by the version
+	// matching them
+	Gained []Hit
+	Lost   []Hit
+	// Unchanged counts the documents both versions match
+	Unchanged int
+}
+
+// Changed reports whether the versions match different documents
+func (impact QueryImpact) Changed() bool {
+	return len(impact.Gained) > 0 || len(impact.Lost) > 0
+}
+
+// CompareQueries runs two versions of a query against the same state of
+// the index and reports the documents the new version gains and loses
+func (idx *Index) CompareQueries(oldQuery, newQuery *Query) (QueryImpact, error) {
+	idx.mu.RLock()
+	oldResult, err := idx.searchCompiledLocked(oldQuery, SearchOptions{}, &searchTrace{})
+	if err != nil {
+		idx.mu.RUnlock()
+		return QueryImpact{}, err
+	}
+	newResult, err := idx.searchCompiledLocked(newQuery, SearchOptions{}, &searchTrace{})
+	idx.mu.RUnlock()
+	if err != nil {
+		return QueryImpact{}, err
+	}
+
+	impact := QueryImpact{Old: oldQuery.String(), New: newQuery.String()}
+	matched := make(map[int]bool, len(oldResult.Hits))
+	for _, hit := range oldResult.Hits {
+		matched[hit.ID] = true
+	}
+	for _, hit := range newResult.Hits {
+		if matched[hit.ID] {
+			delete(matched, hit.ID)
+			impact.Unchanged++
+			continue
+		}
+		impact.Gained = append(impact.Gained, hit)
+	}
+	for _, hit := range oldResult.Hits {
+		if matched[hit.ID] {
+			impact.Lost = append(impact.Lost, hit)
+		}
+	}
+	sort.Slice(impact.Gained, func(i, j int) bool { return impact.Gained[i].ID < impact.Gained[j].ID })
+	sort.Slice(impact.Lost, func(i, j int) bool { return impact.Lost[i].ID < impact.Lost[j].ID })
+	return impact, nil
+}
+
+// DryRun compares the query saved under name with an edited version of it
+// on idx, leaving the saved query unchanged
+func (store *QueryStore) DryRun(idx *Index, name, query string) (QueryImpact, error) {
+	saved, ok := store.Get(name)
+	if !ok {
+		return QueryImpact{}, fmt.Errorf("unknown saved query: %s", name)
+	}
+	edited, err := CompileQuery(query)
+	if err != nil {
+		return QueryImpact{}, fmt.Errorf("query %s: %w", name, err)
+	}
+	return idx.CompareQueries(saved, edited)
+}
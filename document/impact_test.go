@@ -0,0 +1,74 @@
+package document
+
+import (
+	"slices"
+	"testing"
+)
+
+// hitIDsOf returns the IDs of hits in their order
+func hitIDsOf(hits []Hit) []int {
+	ids := []int{}
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestDryRun(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range []string{"go guide", "rust guide", "go tutorial", "java tutorial", "go rust"} {
+		idx.Add(text)
+	}
+	store := NewQueryStore()
+	if err := store.Save("go", "go AND guide"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		gained    []int
+		lost      []int
+		unchanged int
+		wantErr   string
+	}{
+		{"same", "guide AND go", []int{}, []int{}, 1, ""},
+		{"broaden", "go", []int{2, 4}, []int{}, 1, ""},
+		{"narrow", "go AND guide AND rust", []int{}, []int{0}, 0, ""},
+		{"replace", "rust OR java", []int{1, 3, 4}, []int{0}, 0, ""},
+		{"shift", "guide", []int{1}, []int{}, 1, ""},
+		{"bad edit", "go AND", nil, nil, 0, "query go: insufficient operands for AND operation"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			impact, err := store.DryRun(idx, "go", test.query)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("DryRun() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(hitIDsOf(impact.Gained), test.gained) || !slices.Equal(hitIDsOf(impact.Lost), test.lost) || impact.Unchanged != test.unchanged {
+				t.Errorf("DryRun() gained %v, lost %v, unchanged %d, want %v, %v, %d",
+					hitIDsOf(impact.Gained), hitIDsOf(impact.Lost), impact.Unchanged, test.gained, test.lost, test.unchanged)
+			}
+			if changed := len(test.gained)+len(test.lost) > 0; impact.Changed() != changed {
+				t.Errorf("Changed() = %t, want %t", impact.Changed(), changed)
+			}
+			if impact.Old != "go AND guide" || impact.New != test.query {
+				t.Errorf("DryRun() compared %q with %q", impact.Old, impact.New)
+			}
+		})
+	}
+
+	// The saved query is left unchanged
+	if saved, _ := store.Get("go"); saved.String() != "go AND guide" {
+		t.Errorf("saved query = %q after dry runs", saved.String())
+	}
+	if _, err := store.DryRun(idx, "rust", "rust"); err == nil || err.Error() != "unknown saved query: rust" {
+		t.Errorf("DryRun() of an unknown query error = %v", err)
+	}
+}
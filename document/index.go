@@ -0,0 +1,510 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Index is an in-memory collection of analyzed documents that can be searched
+// with boolean queries
+type Index struct {
+	mu       sync.RWMutex
+	docs     []*PreparedDoc
+	postings map[string][]int
+	dict     *termDictionary
+	fields   map[string]*numericField
+	live     int
+
+	// added holds when each live document became visible, history the
+	// removed versions kept for AsOf searches
+	added        []time.Time
+	history      []tombstone
+	retention    time.Duration
+	historySince time.Time
+	lastRemoval  time.Time
+
+	// expires holds the expiry time of documents added with a TTL
+	expires map[int]time.Time
+
+	pipeline *Pipeline
+	slowLog  SlowQueryLog
+	rebuild  *rebuildState
+
+	// active holds the running searches, under its own lock so listing
+	// them never waits for a writer
+	activeMu   sync.Mutex
+	active     map[uint64]ActiveQuery
+	nextActive uint64
+
+	seq            uint64
+	publishMu      sync.Mutex
+	subscribers    map[int]func(IndexEvent)
+	nextSubscriber int
+}
+
+// Hit is a single document matching a search
+type Hit struct {
+	ID    int
+	Text  string
+	Score float64
+}
+
+// SearchOptions controls how much work a search does
+type SearchOptions struct {
+	// Size limits the number of hits returned, zero returns every hit
+	Size int
+	// CountOnly skips scoring and hit collection and only counts matches
+	CountOnly bool
+	// TrackTotalHitsUpTo caps Total at this many matches, zero counts every
+	// match exactly. A CountOnly search stops once the cap is reached, any
+	// other search still scores every match so the top hits are the best
+	// of the whole index
+	TrackTotalHitsUpTo int
+	// Scorer ranks the hits, nil scores by the frequency of the query terms
+	Scorer Scorer
+	// Boosts weights query terms for the Scorer, by lowercase term
+	Boosts map[string]float64
+	// FixTypos reruns a query without hits with its unknown terms replaced
+	// by the closest indexed terms, reporting it in RewrittenQuery
+	FixTypos bool
+	// MemoryBudget caps the bytes a search may allocate for filter bitsets,
+	// hits and scoring state, aborting it with a MemoryLimitError once it
+	// needs more. Zero is unlimited
+	MemoryBudget int64
+	// TraceID is the trace ID of the request running the search, as
+	// OpenTelemetry formats it, recorded in the slow query log
+	TraceID string
+	// AllowDegraded lets a search running during a Rebuild scan every
+	// stored document instead of failing with ErrIndexRebuilding. Such a
+	// search is slower, never fixes typos and reports Degraded
+	AllowDegraded bool
+}
+
+// SearchResult holds the outcome of a search
+type SearchResult struct {
+	Hits  []Hit
+	Total int
+	// TotalIsLowerBound reports that Total reached TrackTotalHitsUpTo and
+	// is only a lower bound of the real hit count
+	TotalIsLowerBound bool
+	// EstimatedTotal extrapolates the hit rate of the scanned documents to the
+	// whole index, it equals Total when the count was not capped and is the
+	// real hit count when the search scanned every document
+	EstimatedTotal int
+	// RewrittenQuery is the typo-corrected query that produced the hits,
+	// empty when the query ran as written
+	RewrittenQuery string
+	// Degraded reports that the search scanned every stored document
+	// because the index was rebuilding
+	Degraded bool
+}
+
+// NewIndex creates a new empty index
+func NewIndex() *Index {
+	return &Index{
+		docs:     make([]*PreparedDoc, 0),
+		postings: make(map[string][]int),
+		dict:     newTermDictionary(nil),
+		fields:   make(map[string]*numericField),
+	}
+}
+
+// Add analyzes and stores a document, returning its ID
+func (idx *Index) Add(text string) int {
+	doc := PrepareDocument(text)
+
+	idx.mu.Lock()
+	id := idx.addLocked(doc)
+	idx.unlockAndPublish([]IndexEvent{idx.recordEvent(EventDocumentAdded, id, text)})
+	return id
+}
+
+// addLocked stores a prepared document, the caller must hold the write lock
+func (idx *Index) addLocked(doc *PreparedDoc) int {
+	id := len(idx.docs)
+	idx.docs = append(idx.docs, doc)
+	idx.setAddedLocked(id, time.Now())
+	for term := range doc.terms {
+		if len(idx.postings[term]) == 0 {
+			idx.dict.insert(term)
+		}
+		idx.postings[term] = append(idx.postings[term], id)
+	}
+	idx.addFieldsLocked(id, doc)
+	idx.live++
+	return id
+}
+
+// Delete removes a document from the index, its ID is never reused
+func (idx *Index) Delete(id int) error {
+	idx.mu.Lock()
+	if err := idx.deleteLocked(id); err != nil {
+		idx.mu.Unlock()
+		return err
+	}
+	idx.unlockAndPublish([]IndexEvent{idx.recordEvent(EventDocumentDeleted, id, "")})
+	return nil
+}
+
+// Update replaces the text of a stored document, keeping its ID
+func (idx *Index) Update(id int, text string) error {
+	doc := PrepareDocument(text)
+
+	idx.mu.Lock()
+	if err := idx.checkLive(id); err != nil {
+		idx.mu.Unlock()
+		return err
+	}
+	idx.replaceLocked(id, doc)
+	idx.unlockAndPublish([]IndexEvent{idx.recordEvent(EventDocumentUpdated, id, text)})
+	return nil
+}
+
+// replaceLocked swaps the document stored under id, the caller must hold
+// the write lock and id must be live
+func (idx *Index) replaceLocked(id int, doc *PreparedDoc) {
+	// The document keeps its TTL across updates
+	expiry, expiring := idx.expires[id]
+	idx.deleteLocked(id)
+	if expiring {
+		idx.expires[id] = expiry
+	}
+	idx.docs[id] = doc
+	idx.setAddedLocked(id, time.Now())
+	idx.addFieldsLocked(id, doc)
+	idx.live++
+	if !idx.unindexedLocked(id) {
+		idx.insertPostingsLocked(id, doc)
+	}
+}
+
+// checkLive reports an error unless id refers to a stored document
+func (idx *Index) checkLive(id int) error {
+	if id < 0 || id >= len(idx.docs) || idx.docs[id] == nil {
+		return fmt.Errorf("document %d does not exist", id)
+	}
+	return nil
+}
+
+// deleteLocked removes a document, the caller must hold the write lock
+func (idx *Index) deleteLocked(id int) error {
+	if err := idx.checkLive(id); err != nil {
+		return err
+	}
+
+	// A running Rebuild may have yet to index the document
+	if !idx.unindexedLocked(id) {
+		for term := range idx.docs[id].terms {
+			postings := idx.postings[term]
+			at := sort.SearchInts(postings, id)
+			postings = append(postings[:at], postings[at+1:]...)
+			if len(postings) == 0 {
+				delete(idx.postings, term)
+				idx.dict.remove(term)
+			} else {
+				idx.postings[term] = postings
+			}
+		}
+	}
+	idx.retireLocked(id, time.Now())
+	delete(idx.expires, id)
+	doc := idx.docs[id]
+	idx.docs[id] = nil
+	idx.removeFieldsLocked(id, doc)
+	idx.live--
+	return nil
+}
+
+// Get returns the text of a stored document
+func (idx *Index) Get(id int) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.checkLive(id) != nil {
+		return "", false
+	}
+	return idx.docs[id].text, true
+}
+
+// Len returns the number of documents in the index
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.live
+}
+
+// IndexStats summarizes the size of an index
+type IndexStats struct {
+	Docs  int
+	Terms int
+}
+
+// Stats returns the document and term counts of the index
+func (idx *Index) Stats() IndexStats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return IndexStats{Docs: idx.live, Terms: len(idx.postings)}
+}
+
+// Postings returns the sorted IDs of the documents containing term
+func (idx *Index) Postings(term string) []int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return append([]int(nil), idx.postings[strings.ToLower(term)]...)
+}
+
+// Search finds the documents matching a boolean query
+func (idx *Index) Search(query string, opts SearchOptions) (SearchResult, error) {
+	compiled, err := CompileQuery(query)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	return idx.SearchCompiled(compiled, opts)
+}
+
+// SearchCompiled finds the documents matching an already compiled query
+func (idx *Index) SearchCompiled(compiled *Query, opts SearchOptions) (SearchResult, error) {
+	start := time.Now()
+	trace := &searchTrace{}
+	done := idx.trackQuery(compiled, opts, start)
+	defer done()
+
+	idx.mu.RLock()
+	result, err := idx.searchCompiledLocked(compiled, opts, trace)
+	slowLog := idx.slowLog
+	idx.mu.RUnlock()
+
+	logSlowQuery(slowLog, compiled, opts, start, trace, result, err)
+	return result, err
+}
+
+// searchCompiledLocked runs a compiled query and, when asked to, its typo
+// corrected rewrite. The caller must hold a lock
+func (idx *Index) searchCompiledLocked(compiled *Query, opts SearchOptions, trace *searchTrace) (SearchResult, error) {
+	if idx.rebuild != nil {
+		if !opts.AllowDegraded {
+			return SearchResult{}, ErrIndexRebuilding
+		}
+		return idx.searchDegradedLocked(compiled, opts, trace)
+	}
+	result, err := idx.searchLocked(compiled, opts, trace)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	if result.Total == 0 && opts.FixTypos {
+		if rewritten, ok := idx.rewriteTyposLocked(compiled); ok {
+			if corrected, err := CompileQuery(rewritten); err == nil {
+				if result, err = idx.searchLocked(corrected, opts, trace); err != nil {
+					return SearchResult{}, err
+				}
+				result.RewrittenQuery = rewritten
+			}
+		}
+	}
+	return result, nil
+}
+
+// searchLocked runs a compiled query within its memory budget, recording
+// its plan and the time of each phase in trace. The caller must hold a lock
+func (idx *Index) searchLocked(compiled *Query, opts SearchOptions, trace *searchTrace) (SearchResult, error) {
+	account := newMemoryAccount(opts.MemoryBudget)
+	phase := time.Now()
+	compiled = expandPatterns(compiled, idx.dict)
+	trace.plan = describePlan(compiled)
+	trace.phases.Expand += time.Since(phase)
+
+	var candidates []uint64
+	match := compiled
+	if len(compiled.filters) > 0 {
+		// Filters run first over the postings, so only the documents
+		// passing them are matched against the scoring clauses
+		phase = time.Now()
+		var err error
+		candidates, err = idx.filterBitsLocked(compiled.filters, compiled.ranges, account)
+		trace.phases.Filter += time.Since(phase)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		match = compiled.residual
+	}
+
+	phase = time.Now()
+	defer func() { trace.phases.Match += time.Since(phase) }()
+	return searchDocs(compiled, match, idx.docs, idx.added, candidates, opts, account)
+}
+
+// searchDocs matches a query against documents indexed by ID, skipping nil
+// entries, and scores the hits with compiled. When candidates is set only
+// the IDs in that bitset are considered and a nil match accepts them all.
+// added holds the time each document was stored. The hits and scoring state
+// are charged to account, aborting the search once it runs out
+func searchDocs(compiled, match *Query, docs []*PreparedDoc, added []time.Time, candidates []uint64, opts SearchOptions, account *memoryAccount) (SearchResult, error) {
+	result := SearchResult{}
+	var processor *BooleanRPNProcessor
+	if match != nil {
+		processor = match.NewProcessor()
+	}
+	scanned, matched := 0, 0
+	population := len(docs)
+	if candidates != nil {
+		population = countCandidates(candidates)
+	}
+
+	var custom *scoring
+	if opts.Scorer != nil && !opts.CountOnly {
+		if err := account.charge(int64(len(compiled.scoreTerms)) * termStatsBytes); err != nil {
+			return SearchResult{}, err
+		}
+		custom = newScoring(opts.Scorer, compiled, docs, opts.Boosts)
+	}
+
+	for id := nextCandidate(candidates, 0, len(docs)); id < len(docs); id = nextCandidate(candidates, id+1, len(docs)) {
+		doc := docs[id]
+		scanned++
+		if doc == nil || (match != nil && !processor.MatchDocument(match, doc)) {
+			continue
+		}
+
+		matched++
+		capped := opts.TrackTotalHitsUpTo > 0 && matched > opts.TrackTotalHitsUpTo
+		if !capped {
+			result.Total++
+		}
+		if !opts.CountOnly {
+			score := 0.0
+			if custom != nil {
+				var addedAt time.Time
+				if id < len(added) {
+					addedAt = added[id]
+				}
+				score = custom.score(id, doc, addedAt)
+			} else {
+				score = compiled.Score(doc.terms)
+			}
+			if err := account.charge(hitBytes); err != nil {
+				return SearchResult{}, err
+			}
+			result.Hits = append(result.Hits, Hit{ID: id, Text: doc.text, Score: score})
+		}
+
+		// Counting needs no ranking, so it stops at the cap
+		if opts.CountOnly && opts.TrackTotalHitsUpTo > 0 && matched >= opts.TrackTotalHitsUpTo {
+			break
+		}
+	}
+
+	result.EstimatedTotal = result.Total
+	if matched > result.Total || scanned < population {
+		result.TotalIsLowerBound = true
+		result.EstimatedTotal = matched * population / scanned
+	}
+
+	sort.SliceStable(result.Hits, func(i, j int) bool {
+		return result.Hits[i].Score > result.Hits[j].Score
+	})
+	if opts.Size > 0 && len(result.Hits) > opts.Size {
+		result.Hits = result.Hits[:opts.Size]
+	}
+
+	return result, nil
+}
+
+// analyze splits text into lowercase terms, the runs of letters and digits
+// of its words, so punctuation never sticks to a term: "Python." and
+// "Java, guide" hold python, java and guide. Field terms such as
+// price:12.5 stay whole
+func analyze(text string) []string {
+	terms := []string{}
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		terms = appendTerms(terms, word)
+	}
+	return terms
+}
+
+// appendTerms appends the terms of a lowercase word to terms
+func appendTerms(terms []string, word string) []string {
+	if _, _, ok := splitField(strings.TrimFunc(word, isSeparator)); ok {
+		return append(terms, strings.TrimFunc(word, isSeparator))
+	}
+	return append(terms, strings.FieldsFunc(word, isSeparator)...)
+}
+
+// isSeparator reports whether r separates terms, being neither a letter
+// nor a digit
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// isFieldTerm reports whether a query term names a field, like price:12.5
+// or a range like price:10..20
+func isFieldTerm(token string) bool {
+	return strings.IndexByte(token, ':') > 0
+}
+
+// isPatternTerm reports whether a query term is a wildcard, fuzzy or regex
+// pattern
+func isPatternTerm(token string) bool {
+	_, ok, err := compileTermPattern(token)
+	return ok || err != nil
+}
+
+// isOperator reports whether token is a boolean operator
+func isOperator(token string) bool {
+	return token == "AND" || token == "OR" || token == "NOT" || token == "FILTER"
+}
+
+// parseQuery splits a boolean query into operators, parentheses and lowercase terms
+func parseQuery(query string) ([]string, error) {
+	spaced := strings.ReplaceAll(query, "(", " ( ")
+	spaced = strings.ReplaceAll(spaced, ")", " ) ")
+
+	tokens := []string{}
+	depth := 0
+	for _, token := range strings.Fields(spaced) {
+		switch {
+		case token == "(":
+			depth++
+		case token == ")":
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("mismatched parentheses in query: %s", query)
+			}
+		case isRegexTerm(token):
+			// Regex terms keep their case so escapes like \W keep their meaning
+		case !isOperator(token):
+			token = strings.ToLower(token)
+			if !isFieldTerm(token) && !isPatternTerm(token) {
+				// Plain terms are analyzed like documents, a word holding
+				// several terms matching them all
+				terms := appendTerms(nil, token)
+				if len(terms) == 0 {
+					return nil, fmt.Errorf("term %s has no letters or digits in query: %s", token, query)
+				}
+				if len(terms) > 1 {
+					tokens = append(tokens, "(")
+					for i, term := range terms {
+						if i > 0 {
+							tokens = append(tokens, "AND")
+						}
+						tokens = append(tokens, term)
+					}
+					token = ")"
+				} else {
+					token = terms[0]
+				}
+			}
+		}
+		tokens = append(tokens, token)
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("mismatched parentheses in query: %s", query)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	return tokens, nil
+}
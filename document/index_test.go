@@ -0,0 +1,139 @@
+package document
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Python Guide", []string{"python", "guide"}},
+		{"Python.", []string{"python"}},
+		{"Java, guide!", []string{"java", "guide"}},
+		{"(rust) \"notes\"", []string{"rust", "notes"}},
+		{"well-known C++ tricks", []string{"well", "known", "c", "tricks"}},
+		{"Ünïcode café", []string{"ünïcode", "café"}},
+		{"price:12.5 date:2024-01-02.", []string{"price:12.5", "date:2024-01-02"}},
+		{"... --- !!!", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if got := analyze(test.text); !slices.Equal(got, test.want) {
+				t.Errorf("analyze(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchMatchesPunctuatedWords(t *testing.T) {
+	idx := NewIndex()
+	python := idx.Add("I like Python.")
+	java := idx.Add("Java, guide and more")
+	hyphen := idx.Add("a well-known trick")
+	idx.Add("nothing relevant here")
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"python", []int{python}},
+		{"PYTHON", []int{python}},
+		{"python.", []int{python}},
+		{"java", []int{java}},
+		{"java AND guide", []int{java}},
+		{"python OR java", []int{python, java}},
+		{"well-known", []int{hyphen}},
+		{"known AND NOT java", []int{hyphen}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			result, err := idx.Search(test.query, SearchOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []int{}
+			for _, hit := range result.Hits {
+				got = append(got, hit.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Search(%q) hits = %v, want %v", test.query, got, test.want)
+			}
+			if result.Total != len(test.want) {
+				t.Errorf("Search(%q) Total = %d, want %d", test.query, result.Total, len(test.want))
+			}
+		})
+	}
+}
+
+func TestCompiledQueryMatchesPreparedDocument(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		want  bool
+	}{
+		{"python", "Python.", true},
+		{"python", "pythonic", false},
+		{"(python OR java) AND guide", "A Java, guide", true},
+		{"(python OR java) AND guide", "a java tutorial", false},
+		{"python AND NOT advanced", "python (advanced)", false},
+		{"gui*", "guides!", true},
+	}
+	for _, test := range tests {
+		t.Run(test.query+"/"+test.text, func(t *testing.T) {
+			idx := NewIndex()
+			idx.Add(test.text)
+			result, err := idx.Search(test.query, SearchOptions{CountOnly: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result.Total == 1; got != test.want {
+				t.Errorf("%q matches %q = %v, want %v", test.query, test.text, got, test.want)
+			}
+
+			compiled, err := CompileQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(compiled.patterns) == 0 {
+				if got := compiled.MatchDocument(PrepareDocument(test.text)); got != test.want {
+					t.Errorf("MatchDocument = %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	for _, query := range []string{"", "(python", "python)", "python AND", "!!!"} {
+		if _, err := CompileQuery(query); err == nil {
+			t.Errorf("CompileQuery(%q) succeeded", query)
+		}
+	}
+}
+
+func TestCompileQueryTypedErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		target error
+		want   string
+	}{
+		{"python AND", engine.ErrStackUnderflow, "insufficient operands for AND operation"},
+		{"NOT", engine.ErrStackUnderflow, "insufficient operands for NOT operation"},
+		{"python java", engine.ErrIncompleteExpression, "invalid expression: expected 1 result, got 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			_, err := CompileQuery(test.query)
+			if !errors.Is(err, test.target) || err.Error() != test.want {
+				t.Errorf("CompileQuery(%q) error = %v, want %q wrapping %v", test.query, err, test.want, test.target)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// textField is the field an ingested document's raw text starts out in
+const textField = "text"
+
+// IngestDoc is a document being shaped by an ingest pipeline, as named fields
+type IngestDoc map[string]string
+
+// Text joins the field values into the text that is indexed, the text field
+// first and the others in field name order
+func (doc IngestDoc) Text() string {
+	names := make([]string, 0, len(doc))
+	for name := range doc {
+		if name != textField {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	values := []string{}
+	if text, ok := doc[textField]; ok {
+		values = append(values, text)
+	}
+	for _, name := range names {
+		values = append(values, doc[name])
+	}
+	return strings.Join(values, " ")
+}
+
+// ProcessorConfig declares a single ingest processor
+//
+//   - rename moves Field to Target
+//   - extract matches Pattern against Field and stores each named capture
+//     group in a field of the same name, or the first group or whole match
+//     in Target when the pattern has no named groups
+//   - split splits Field at every match of Pattern into one document per
+//     non-empty section, copying the other fields
+//   - drop discards documents whose Field matches Pattern, or whose text
+//     matches the boolean Query
+//   - keywords stores the Count top keyword phrases of Field, comma
+//     separated, in Target, which defaults to the keywords field
+type ProcessorConfig struct {
+	Type    string `json:"type"`
+	Field   string `json:"field,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Query   string `json:"query,omitempty"`
+	Count   int    `json:"count,omitempty"`
+}
+
+// PipelineConfig declares the processors an index applies, in order, to
+// documents passed to Ingest
+type PipelineConfig struct {
+	Processors []ProcessorConfig `json:"processors"`
+}
+
+// processor turns one document into zero or more documents
+type processor func(doc IngestDoc) []IngestDoc
+
+// Pipeline is a compiled sequence of ingest processors
+type Pipeline struct {
+	config     PipelineConfig
+	processors []processor
+}
+
+// ParsePipeline compiles a pipeline from its JSON configuration
+func ParsePipeline(data []byte) (*Pipeline, error) {
+	var config PipelineConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid pipeline configuration: %w", err)
+	}
+	return NewPipeline(config)
+}
+
+// NewPipeline validates a pipeline configuration and compiles its patterns and queries
+func NewPipeline(config PipelineConfig) (*Pipeline, error) {
+	pipeline := &Pipeline{config: config}
+	for i, processorConfig := range config.Processors {
+		proc, err := compileProcessor(processorConfig)
+		if err != nil {
+			return nil, fmt.Errorf("processor %d (%s): %w", i, processorConfig.Type, err)
+		}
+		pipeline.processors = append(pipeline.processors, proc)
+	}
+	return pipeline, nil
+}
+
+// Config returns the configuration the pipeline was compiled from
+func (pipeline *Pipeline) Config() PipelineConfig {
+	return pipeline.config
+}
+
+// Process runs raw text through every processor and returns the texts of
+// the documents to index
+func (pipeline *Pipeline) Process(text string) []string {
+	docs := []IngestDoc{{textField: text}}
+	for _, proc := range pipeline.processors {
+		next := []IngestDoc{}
+		for _, doc := range docs {
+			next = append(next, proc(doc)...)
+		}
+		docs = next
+	}
+
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Text()
+	}
+	return texts
+}
+
+// compileProcessor builds the processor declared by config
+func compileProcessor(config ProcessorConfig) (processor, error) {
+	field := config.Field
+	if field == "" {
+		field = textField
+	}
+
+	var pattern *regexp.Regexp
+	if config.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(config.Pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	switch config.Type {
+	case "rename":
+		if config.Target == "" {
+			return nil, fmt.Errorf("rename requires a target")
+		}
+		return func(doc IngestDoc) []IngestDoc {
+			if value, ok := doc[field]; ok {
+				doc = maps.Clone(doc)
+				delete(doc, field)
+				doc[config.Target] = value
+			}
+			return []IngestDoc{doc}
+		}, nil
+
+	case "extract":
+		if pattern == nil {
+			return nil, fmt.Errorf("extract requires a pattern")
+		}
+		named := false
+		for _, name := range pattern.SubexpNames() {
+			named = named || name != ""
+		}
+		if !named && config.Target == "" {
+			return nil, fmt.Errorf("extract requires a target or named groups")
+		}
+		return func(doc IngestDoc) []IngestDoc {
+			match := pattern.FindStringSubmatch(doc[field])
+			if match == nil {
+				return []IngestDoc{doc}
+			}
+			doc = maps.Clone(doc)
+			if !named {
+				doc[config.Target] = match[min(1, len(match)-1)]
+				return []IngestDoc{doc}
+			}
+			for i, name := range pattern.SubexpNames() {
+				if name != "" {
+					doc[name] = match[i]
+				}
+			}
+			return []IngestDoc{doc}
+		}, nil
+
+	case "split":
+		if pattern == nil {
+			return nil, fmt.Errorf("split requires a pattern")
+		}
+		return func(doc IngestDoc) []IngestDoc {
+			sections := []IngestDoc{}
+			for _, section := range pattern.Split(doc[field], -1) {
+				if strings.TrimSpace(section) == "" {
+					continue
+				}
+				copied := maps.Clone(doc)
+				copied[field] = section
+				sections = append(sections, copied)
+			}
+			return sections
+		}, nil
+
+	case "drop":
+		if config.Query != "" {
+			query, err := CompileQuery(config.Query)
+			if err != nil {
+				return nil, err
+			}
+			return func(doc IngestDoc) []IngestDoc {
+				if query.MatchDocument(PrepareDocument(doc.Text())) {
+					return nil
+				}
+				return []IngestDoc{doc}
+			}, nil
+		}
+		if pattern == nil {
+			return nil, fmt.Errorf("drop requires a pattern or a query")
+		}
+		return func(doc IngestDoc) []IngestDoc {
+			if value, ok := doc[field]; ok && pattern.MatchString(value) {
+				return nil
+			}
+			return []IngestDoc{doc}
+		}, nil
+
+	case "keywords":
+		target, count := config.Target, config.Count
+		if target == "" {
+			target = keywordsField
+		}
+		if count == 0 {
+			count = defaultKeywordCount
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("keyword count must be positive, got %d", count)
+		}
+		return func(doc IngestDoc) []IngestDoc {
+			keywords := extractKeywords(doc[field], count)
+			if len(keywords) == 0 {
+				return []IngestDoc{doc}
+			}
+			doc = maps.Clone(doc)
+			doc[target] = strings.Join(keywords, ", ")
+			return []IngestDoc{doc}
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown processor type %q", config.Type)
+}
+
+// SetPipeline sets the ingest pipeline applied by Ingest, nil indexes raw text
+func (idx *Index) SetPipeline(pipeline *Pipeline) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.pipeline = pipeline
+}
+
+// Ingest runs text through the index's pipeline and atomically adds the
+// resulting documents, returning their IDs. Unlike Add, which stores text
+// as is so replicas replay it exactly, Ingest shapes raw input first
+func (idx *Index) Ingest(text string) ([]int, error) {
+	idx.mu.RLock()
+	pipeline := idx.pipeline
+	idx.mu.RUnlock()
+
+	texts := []string{text}
+	if pipeline != nil {
+		texts = pipeline.Process(text)
+	}
+
+	batch := idx.Begin()
+	for _, text := range texts {
+		batch.Add(text)
+	}
+	return batch.Commit()
+}
@@ -0,0 +1,141 @@
+package document
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPipelineProcess(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		input  string
+		want   []string
+	}{
+		{
+			name:   "no processors",
+			config: `{"processors": []}`,
+			input:  "raw text",
+			want:   []string{"raw text"},
+		},
+		{
+			name:   "extract named groups",
+			config: `{"processors": [{"type": "extract", "pattern": "level=(?P<level>\\w+)"}]}`,
+			input:  "disk full level=error",
+			want:   []string{"disk full level=error error"},
+		},
+		{
+			name:   "extract into target",
+			config: `{"processors": [{"type": "extract", "pattern": "#(\\d+)", "target": "ticket"}]}`,
+			input:  "fixes #42",
+			want:   []string{"fixes #42 42"},
+		},
+		{
+			name:   "extract without match",
+			config: `{"processors": [{"type": "extract", "pattern": "#(\\d+)", "target": "ticket"}]}`,
+			input:  "no ticket",
+			want:   []string{"no ticket"},
+		},
+		{
+			name: "extract then rename",
+			config: `{"processors": [
+				{"type": "extract", "pattern": "#(\\d+)", "target": "ticket"},
+				{"type": "rename", "field": "ticket", "target": "issue"}]}`,
+			input: "fixes #42",
+			want:  []string{"fixes #42 42"},
+		},
+		{
+			name:   "split sections",
+			config: `{"processors": [{"type": "split", "pattern": "\\n---\\n"}]}`,
+			input:  "first section\n---\nsecond section\n---\n\n---\nthird",
+			want:   []string{"first section", "second section", "third"},
+		},
+		{
+			name: "split then drop by pattern",
+			config: `{"processors": [
+				{"type": "split", "pattern": ";"},
+				{"type": "drop", "pattern": "^\\s*debug"}]}`,
+			input: "error a; debug b; warn c",
+			want:  []string{"error a", " warn c"},
+		},
+		{
+			name:   "drop by query",
+			config: `{"processors": [{"type": "drop", "query": "spam AND NOT important"}]}`,
+			input:  "buy spam now",
+			want:   []string{},
+		},
+		{
+			name:   "kept by query",
+			config: `{"processors": [{"type": "drop", "query": "spam AND NOT important"}]}`,
+			input:  "important spam",
+			want:   []string{"important spam"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pipeline, err := ParsePipeline([]byte(test.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pipeline.Process(test.input); !slices.Equal(got, test.want) {
+				t.Errorf("Process(%q) = %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIngestDocText(t *testing.T) {
+	doc := IngestDoc{"zeta": "last", "text": "body", "alpha": "first"}
+	if got := doc.Text(); got != "body first last" {
+		t.Errorf("Text() = %q, want %q", got, "body first last")
+	}
+}
+
+func TestParsePipelineErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`{"processors": [{"type": "rename"}]}`, "processor 0 (rename): rename requires a target"},
+		{`{"processors": [{"type": "extract", "pattern": "(\\d+)"}]}`, "processor 0 (extract): extract requires a target or named groups"},
+		{`{"processors": [{"type": "split"}]}`, "processor 0 (split): split requires a pattern"},
+		{`{"processors": [{"type": "drop"}]}`, "processor 0 (drop): drop requires a pattern or a query"},
+		{`{"processors": [{"type": "split", "pattern": "("}]}`, "processor 0 (split): error parsing regexp: missing closing ): `(`"},
+		{`{"processors": [{"type": "split", "pattern": ";"}, {"type": "enrich"}]}`, `processor 1 (enrich): unknown processor type "enrich"`},
+		{`{"processors": [{"type": "drop", "query": "AND"}]}`, "processor 0 (drop): insufficient operands for AND operation"},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			_, err := ParsePipeline([]byte(test.config))
+			if err == nil || err.Error() != test.want {
+				t.Errorf("ParsePipeline() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+	if _, err := ParsePipeline([]byte("{")); err == nil {
+		t.Error("ParsePipeline() of invalid JSON succeeded")
+	}
+}
+
+func TestIngest(t *testing.T) {
+	pipeline, err := ParsePipeline([]byte(`{"processors": [{"type": "split", "pattern": ";"}, {"type": "drop", "pattern": "debug"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndex()
+	if ids, err := idx.Ingest("raw; text"); err != nil || !slices.Equal(ids, []int{0}) {
+		t.Errorf("Ingest() without a pipeline = %v, %v, want [0]", ids, err)
+	}
+
+	idx.SetPipeline(pipeline)
+	ids, err := idx.Ingest("error a;debug b;warn c")
+	if err != nil || !slices.Equal(ids, []int{1, 2}) {
+		t.Fatalf("Ingest() = %v, %v, want [1 2]", ids, err)
+	}
+	if text, _ := idx.Get(2); text != "warn c" {
+		t.Errorf("Get(2) = %q, want %q", text, "warn c")
+	}
+	if ids, err := idx.Ingest("debug only"); err != nil || len(ids) != 0 {
+		t.Errorf("Ingest() of a dropped document = %v, %v, want nothing added", ids, err)
+	}
+}
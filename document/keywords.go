@@ -0,0 +1,93 @@
+package document
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// keywordsField is the field the keywords processor writes to by default
+const keywordsField = "keywords"
+
+// defaultKeywordCount is the number of keywords extracted when none is configured
+const defaultKeywordCount = 5
+
+// stopwords delimit the candidate phrases of keyword extraction
+var stopwords = map[string]bool{
+	"a": true, "about": true, "after": true, "all": true, "also": true, "an": true,
+	"and": true, "any": true, "are": true, "as": true, "at": true, "be": true,
+	"been": true, "but": true, "by": true, "can": true, "could": true, "do": true,
+	"for": true, "from": true, "had": true, "has": true, "have": true, "he": true,
+	"her": true, "his": true, "how": true, "i": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "its": true, "may": true, "more": true,
+	"most": true, "no": true, "not": true, "of": true, "on": true, "or": true,
+	"our": true, "over": true, "she": true, "so": true, "some": true, "such": true, "than": true,
+	"that": true, "the": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "to": true,
+	"was": true, "we": true, "were": true, "what": true, "when": true,
+	"which": true, "while": true, "who": true, "will": true, "with": true,
+	"would": true, "you": true, "your": true,
+}
+
+// extractKeywords returns up to count keyword phrases of text ranked with
+// RAKE: candidate phrases are the runs of words between stopwords and
+// punctuation, each word scores its co-occurrence degree over its frequency
+// and a phrase scores the sum of its words
+func extractKeywords(text string, count int) []string {
+	phrases := [][]string{}
+	current := []string{}
+	endPhrase := func() {
+		if len(current) > 0 {
+			phrases = append(phrases, current)
+			current = []string{}
+		}
+	}
+
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		word := strings.TrimFunc(field, func(char rune) bool {
+			return !unicode.IsLetter(char) && !unicode.IsDigit(char)
+		})
+		if word == "" || stopwords[word] {
+			endPhrase()
+			continue
+		}
+		current = append(current, word)
+		if strings.ContainsAny(field[len(field)-1:], ".,;:!?") {
+			endPhrase()
+		}
+	}
+	endPhrase()
+
+	frequency := make(map[string]int)
+	degree := make(map[string]int)
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			frequency[word]++
+			degree[word] += len(phrase)
+		}
+	}
+
+	scores := make(map[string]float64)
+	for _, phrase := range phrases {
+		score := 0.0
+		for _, word := range phrase {
+			score += float64(degree[word]) / float64(frequency[word])
+		}
+		scores[strings.Join(phrase, " ")] = score
+	}
+
+	keywords := make([]string, 0, len(scores))
+	for keyword := range scores {
+		keywords = append(keywords, keyword)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if scores[keywords[i]] != scores[keywords[j]] {
+			return scores[keywords[i]] > scores[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+	if len(keywords) > count {
+		keywords = keywords[:count]
+	}
+	return keywords
+}
@@ -0,0 +1,101 @@
+package document
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestExtractKeywords(t *testing.T) {
+	tests := []struct {
+		text  string
+		count int
+		want  []string
+	}{
+		{
+			text:  "Compatibility of systems of linear constraints over the set of natural numbers. Criteria of compatibility of a system of linear Diophantine equations.",
+			count: 3,
+			want:  []string{"linear diophantine equations", "linear constraints", "natural numbers"},
+		},
+		{"Go is fast. Go is fun.", 5, []string{"fast", "fun", "go"}},
+		{"Go is fast. Go is fun.", 1, []string{"fast"}},
+		{"the and of", 5, []string{}},
+		{"", 5, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if got := extractKeywords(test.text, test.count); !slices.Equal(got, test.want) {
+				t.Errorf("extractKeywords(%q, %d) = %q, want %q", test.text, test.count, got, test.want)
+			}
+		})
+	}
+}
+
+func TestKeywordsProcessor(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ProcessorConfig
+		input  string
+		want   IngestDoc
+	}{
+		{
+			name:   "default field",
+			config: ProcessorConfig{Type: "keywords", Count: 2},
+			input:  "Rotating disk storage fails. Replace the rotating disk soon.",
+			want:   IngestDoc{"text": "Rotating disk storage fails. Replace the rotating disk soon.", "keywords": "rotating disk storage fails, rotating disk soon"},
+		},
+		{
+			name:   "target",
+			config: ProcessorConfig{Type: "keywords", Target: "tags", Count: 1},
+			input:  "Kernel panic on boot.",
+			want:   IngestDoc{"text": "Kernel panic on boot.", "tags": "kernel panic"},
+		},
+		{
+			name:   "nothing to extract",
+			config: ProcessorConfig{Type: "keywords"},
+			input:  "of the and",
+			want:   IngestDoc{"text": "of the and"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proc, err := compileProcessor(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs := proc(IngestDoc{"text": test.input})
+			if len(docs) != 1 {
+				t.Fatalf("processor returned %d documents", len(docs))
+			}
+			if got := docs[0]; !maps.Equal(got, test.want) {
+				t.Errorf("processor = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := compileProcessor(ProcessorConfig{Type: "keywords", Count: -1}); err == nil || err.Error() != "keyword count must be positive, got -1" {
+		t.Errorf("compileProcessor() with a negative count error = %v", err)
+	}
+}
+
+// TestIngestedKeywordsAreSearchable filters on the keywords an ingest
+// pipeline extracted
+func TestIngestedKeywordsAreSearchable(t *testing.T) {
+	pipeline, err := NewPipeline(PipelineConfig{Processors: []ProcessorConfig{{Type: "keywords", Count: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndex()
+	idx.SetPipeline(pipeline)
+	for _, text := range []string{"Kernel panic on boot.", "Disk full on boot."} {
+		if _, err := idx.Ingest(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if text, _ := idx.Get(0); text != "Kernel panic on boot. kernel panic" {
+		t.Errorf("Get(0) = %q, want the keywords appended", text)
+	}
+	if ids := hitIDs(t, idx, "boot"); !slices.Equal(ids, []int{0, 1}) {
+		t.Errorf("Search(boot) = %v, want [0 1]", ids)
+	}
+}
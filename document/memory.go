@@ -0,0 +1,55 @@
+package document
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// hitBytes is what collecting one hit costs, its text is shared with the
+// stored document
+const hitBytes = int64(unsafe.Sizeof(Hit{}))
+
+// termStatsBytes is what scoring costs per distinct query term
+const termStatsBytes = int64(unsafe.Sizeof(TermStats{}))
+
+// MemoryLimitError reports that a search allocated more than the
+// MemoryBudget of its options and was aborted
+type MemoryLimitError struct {
+	Budget int64
+	Used   int64
+}
+
+// Error describes the exceeded budget
+func (err *MemoryLimitError) Error() string {
+	return fmt.Sprintf("search memory budget of %d bytes exceeded: %d", err.Budget, err.Used)
+}
+
+// memoryAccount tracks the bytes a single search allocates for bitsets,
+// hits and scoring state against its budget, zero being unlimited
+type memoryAccount struct {
+	budget int64
+	used   int64
+}
+
+// newMemoryAccount starts accounting a search against a budget in bytes
+func newMemoryAccount(budget int64) *memoryAccount {
+	return &memoryAccount{budget: budget}
+}
+
+// charge records bytes allocated by the search, failing with a
+// MemoryLimitError once the total exceeds the budget
+func (account *memoryAccount) charge(bytes int64) error {
+	account.used += bytes
+	if account.budget > 0 && account.used > account.budget {
+		return &MemoryLimitError{Budget: account.budget, Used: account.used}
+	}
+	return nil
+}
+
+// bitset charges for and allocates a bitset of the given number of words
+func (account *memoryAccount) bitset(words int) ([]uint64, error) {
+	if err := account.charge(int64(words) * 8); err != nil {
+		return nil, err
+	}
+	return make([]uint64, words), nil
+}
@@ -0,0 +1,90 @@
+package document
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryAccountCharge(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  int64
+		charges []int64
+		wantErr bool
+	}{
+		{"unlimited", 0, []int64{1 << 40, 1 << 40}, false},
+		{"under budget", 100, []int64{40, 50}, false},
+		{"exactly at budget", 100, []int64{40, 60}, false},
+		{"over budget", 100, []int64{40, 61}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account := newMemoryAccount(test.budget)
+			var err error
+			total := int64(0)
+			for _, bytes := range test.charges {
+				total += bytes
+				if err = account.charge(bytes); err != nil {
+					break
+				}
+			}
+			if (err != nil) != test.wantErr {
+				t.Fatalf("charge() error = %v, want error %v", err, test.wantErr)
+			}
+			var limitErr *MemoryLimitError
+			if test.wantErr && (!errors.As(err, &limitErr) || *limitErr != MemoryLimitError{Budget: test.budget, Used: total}) {
+				t.Errorf("charge() error = %#v, want budget %d used %d", err, test.budget, total)
+			}
+		})
+	}
+}
+
+func TestSearchMemoryBudget(t *testing.T) {
+	idx := NewIndex()
+	for i := 0; i < 640; i++ {
+		if i%2 == 0 {
+			idx.Add("even number")
+		} else {
+			idx.Add("odd number")
+		}
+	}
+	// Each bitset over 640 documents takes 10 words
+	const bitset = 80
+	tests := []struct {
+		name   string
+		query  string
+		opts   SearchOptions
+		used   int64
+		passes bool
+	}{
+		{"hits within budget", "even", SearchOptions{MemoryBudget: 320 * hitBytes}, 0, true},
+		{"hits over budget", "even", SearchOptions{MemoryBudget: 320*hitBytes - 1}, 320 * hitBytes, false},
+		{"count only charges no hits", "even", SearchOptions{MemoryBudget: 1, CountOnly: true}, 0, true},
+		// The scoring state leaves room for one hit less
+		{"scoring state", "even", SearchOptions{MemoryBudget: 320 * hitBytes, Scorer: TFIDFScorer{}}, termStatsBytes + 319*hitBytes, false},
+		{"filter bitsets within budget", "FILTER even", SearchOptions{MemoryBudget: 3 * bitset, CountOnly: true}, 0, true},
+		{"filter bitsets over budget", "FILTER even", SearchOptions{MemoryBudget: 3*bitset - 1, CountOnly: true}, 3 * bitset, false},
+		{"unlimited", "number", SearchOptions{}, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := idx.Search(test.query, test.opts)
+			if test.passes {
+				if err != nil {
+					t.Fatalf("Search() error = %v", err)
+				}
+				if want, _ := idx.Search(test.query, SearchOptions{CountOnly: true}); result.Total != want.Total {
+					t.Errorf("Total = %d, want %d", result.Total, want.Total)
+				}
+				return
+			}
+			var limitErr *MemoryLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Search() error = %v, want a MemoryLimitError", err)
+			}
+			if limitErr.Budget != test.opts.MemoryBudget || limitErr.Used != test.used {
+				t.Errorf("MemoryLimitError = %+v, want budget %d used %d", limitErr, test.opts.MemoryBudget, test.used)
+			}
+		})
+	}
+}
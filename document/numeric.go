@@ -0,0 +1,341 @@
+package document
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bkdLeafSize is the number of points in a leaf block of a numeric tree
+const bkdLeafSize = 64
+
+// minFieldOverlay is the number of pending changes a numeric field accepts
+// before rebuilding its tree, however small it is
+const minFieldOverlay = 256
+
+// fieldDateLayouts are the date formats numeric fields accept, dates are
+// indexed as seconds since the Unix epoch
+var fieldDateLayouts = []string{"2006-01-02", time.RFC3339}
+
+// parseFieldValue parses the value of a name:value field term as a number
+// or a date, reporting false for anything else
+func parseFieldValue(text string) (float64, bool) {
+	if text == "" {
+		return 0, false
+	}
+	// ParseFloat also accepts words like inf and nan, which are not values
+	if first := text[0]; isFieldDigit(first) || first == '-' || first == '+' || first == '.' {
+		if value, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(value, 0) && !math.IsNaN(value) {
+			return value, true
+		}
+	}
+	for _, layout := range fieldDateLayouts {
+		if at, err := time.Parse(layout, strings.ToUpper(text)); err == nil {
+			return float64(at.UnixNano()) / float64(time.Second), true
+		}
+	}
+	return 0, false
+}
+
+// isFieldDigit reports whether b is an ASCII decimal digit
+func isFieldDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// splitField splits a name:value term, reporting false for terms without
+// a name or a numeric or date value
+func splitField(term string) (string, float64, bool) {
+	at := strings.IndexByte(term, ':')
+	if at <= 0 {
+		return "", 0, false
+	}
+	value, ok := parseFieldValue(term[at+1:])
+	return term[:at], value, ok
+}
+
+// numericRange is a range query over a numeric field, written in queries as
+// name:lo..hi with either bound optional and both inclusive, or as
+// name:>v, name:>=v, name:<v and name:<=v
+type numericRange struct {
+	field      string
+	min, max   float64
+	excludeMin bool
+	excludeMax bool
+}
+
+// parseRange parses a range query term, reporting false for other terms
+func parseRange(token string) (numericRange, bool) {
+	at := strings.IndexByte(token, ':')
+	if at <= 0 {
+		return numericRange{}, false
+	}
+	field, bounds := token[:at], token[at+1:]
+	r := numericRange{field: field, min: math.Inf(-1), max: math.Inf(1)}
+
+	for _, comparison := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(bounds, comparison) {
+			continue
+		}
+		value, ok := parseFieldValue(bounds[len(comparison):])
+		if !ok {
+			return numericRange{}, false
+		}
+		switch comparison {
+		case ">=":
+			r.min = value
+		case "<=":
+			r.max = value
+		case ">":
+			r.min, r.excludeMin = value, true
+		case "<":
+			r.max, r.excludeMax = value, true
+		}
+		return r, true
+	}
+
+	low, high, ok := strings.Cut(bounds, "..")
+	if !ok || (low == "" && high == "") {
+		return numericRange{}, false
+	}
+	if low != "" {
+		if r.min, ok = parseFieldValue(low); !ok {
+			return numericRange{}, false
+		}
+	}
+	if high != "" {
+		if r.max, ok = parseFieldValue(high); !ok {
+			return numericRange{}, false
+		}
+	}
+	return r, true
+}
+
+// contains reports whether value lies in the range
+func (r numericRange) contains(value float64) bool {
+	if value < r.min || (r.excludeMin && value == r.min) {
+		return false
+	}
+	return value < r.max || (!r.excludeMax && value == r.max)
+}
+
+// matchDoc reports whether a document has a field value in the range
+func (r numericRange) matchDoc(doc *PreparedDoc) bool {
+	value, ok := doc.fields[r.field]
+	return ok && r.contains(value)
+}
+
+// bkdPoint is a field value of a document
+type bkdPoint struct {
+	value float64
+	id    int
+}
+
+// bkdTree is a static one-dimensional block tree of points: the points are
+// sorted by value and cut into leaf blocks, and every node of a complete
+// binary tree over the blocks holds the smallest and largest value below
+// it. Range queries take whole subtrees inside the range without comparing
+// their points and only compare the points of the blocks at its edges
+type bkdTree struct {
+	points []bkdPoint
+	leaves int
+	// low and high hold the value bounds of node n, the root is node 1 and
+	// the children of n are 2n and 2n+1
+	low, high []float64
+}
+
+// newBKDTree builds a tree over points, which it sorts in place
+func newBKDTree(points []bkdPoint) *bkdTree {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].value != points[j].value {
+			return points[i].value < points[j].value
+		}
+		return points[i].id < points[j].id
+	})
+
+	leaves := 1
+	for leaves*bkdLeafSize < len(points) {
+		leaves *= 2
+	}
+	tree := &bkdTree{
+		points: points,
+		leaves: leaves,
+		low:    make([]float64, 2*leaves),
+		high:   make([]float64, 2*leaves),
+	}
+	for leaf := 0; leaf < leaves; leaf++ {
+		node := leaves + leaf
+		start, end := tree.leafSpan(leaf)
+		if start >= end {
+			// Empty trailing blocks match nothing
+			tree.low[node], tree.high[node] = math.Inf(1), math.Inf(-1)
+			continue
+		}
+		tree.low[node], tree.high[node] = points[start].value, points[end-1].value
+	}
+	for node := leaves - 1; node >= 1; node-- {
+		tree.low[node] = math.Min(tree.low[2*node], tree.low[2*node+1])
+		tree.high[node] = math.Max(tree.high[2*node], tree.high[2*node+1])
+	}
+	return tree
+}
+
+// leafSpan returns the range of points in a leaf block
+func (tree *bkdTree) leafSpan(leaf int) (int, int) {
+	start := min(leaf*bkdLeafSize, len(tree.points))
+	return start, min(start+bkdLeafSize, len(tree.points))
+}
+
+// search calls visit with the IDs of the points in the range
+func (tree *bkdTree) search(r numericRange, visit func(id int)) {
+	tree.searchNode(1, 0, tree.leaves, r, visit)
+}
+
+// searchNode searches node n covering leaf blocks [first, last)
+func (tree *bkdTree) searchNode(node, first, last int, r numericRange, visit func(id int)) {
+	low, high := tree.low[node], tree.high[node]
+	if low > high || !r.overlaps(low, high) {
+		return
+	}
+	if r.contains(low) && r.contains(high) {
+		start, _ := tree.leafSpan(first)
+		_, end := tree.leafSpan(last - 1)
+		for _, point := range tree.points[start:end] {
+			visit(point.id)
+		}
+		return
+	}
+	if last-first == 1 {
+		start, end := tree.leafSpan(first)
+		for _, point := range tree.points[start:end] {
+			if r.contains(point.value) {
+				visit(point.id)
+			}
+		}
+		return
+	}
+	middle := (first + last) / 2
+	tree.searchNode(2*node, first, middle, r, visit)
+	tree.searchNode(2*node+1, middle, last, r, visit)
+}
+
+// overlaps reports whether the range shares a value with [low, high]
+func (r numericRange) overlaps(low, high float64) bool {
+	if high < r.min || (r.excludeMin && high == r.min) {
+		return false
+	}
+	return low < r.max || (!r.excludeMax && low == r.max)
+}
+
+// numericField indexes the values of one field. Its tree is immutable, so
+// documents changed since it was built are kept aside: stale holds the IDs
+// whose points in the tree are outdated and pending their current values
+type numericField struct {
+	tree    *bkdTree
+	pending map[int]float64
+	stale   map[int]bool
+}
+
+// newNumericField builds a field index over points
+func newNumericField(points []bkdPoint) *numericField {
+	return &numericField{
+		tree:    newBKDTree(points),
+		pending: make(map[int]float64),
+		stale:   make(map[int]bool),
+	}
+}
+
+// search calls visit with the IDs of the documents whose value is in range
+func (field *numericField) search(r numericRange, visit func(id int)) {
+	field.tree.search(r, func(id int) {
+		if !field.stale[id] {
+			visit(id)
+		}
+	})
+	for id, value := range field.pending {
+		if r.contains(value) {
+			visit(id)
+		}
+	}
+}
+
+// buildNumericFields indexes the field values of documents by ID
+func buildNumericFields(docs []*PreparedDoc) map[string]*numericField {
+	points := make(map[string][]bkdPoint)
+	for id, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for name, value := range doc.fields {
+			points[name] = append(points[name], bkdPoint{value: value, id: id})
+		}
+	}
+
+	fields := make(map[string]*numericField, len(points))
+	for name, fieldPoints := range points {
+		fields[name] = newNumericField(fieldPoints)
+	}
+	return fields
+}
+
+// addFieldsLocked indexes the field values of a document, the caller must
+// hold the write lock
+func (idx *Index) addFieldsLocked(id int, doc *PreparedDoc) {
+	for name, value := range doc.fields {
+		field, ok := idx.fields[name]
+		if !ok {
+			field = newNumericField(nil)
+			idx.fields[name] = field
+		}
+		field.pending[id] = value
+		idx.compactFieldLocked(name)
+	}
+}
+
+// removeFieldsLocked drops the field values of a document already removed
+// from idx.docs, the caller must hold the write lock
+func (idx *Index) removeFieldsLocked(id int, doc *PreparedDoc) {
+	for name := range doc.fields {
+		field, ok := idx.fields[name]
+		if !ok {
+			continue
+		}
+		delete(field.pending, id)
+		field.stale[id] = true
+		idx.compactFieldLocked(name)
+	}
+}
+
+// compactFieldLocked rebuilds the tree of a field from the live documents
+// once its pending changes outgrow a fraction of it
+func (idx *Index) compactFieldLocked(name string) {
+	field := idx.fields[name]
+	if len(field.pending)+len(field.stale) <= max(minFieldOverlay, len(field.tree.points)/8) {
+		return
+	}
+	points := []bkdPoint{}
+	for id, doc := range idx.docs {
+		if doc == nil {
+			continue
+		}
+		if value, ok := doc.fields[name]; ok {
+			points = append(points, bkdPoint{value: value, id: id})
+		}
+	}
+	if len(points) == 0 {
+		delete(idx.fields, name)
+		return
+	}
+	idx.fields[name] = newNumericField(points)
+}
+
+// rangeBitsLocked sets the bits of the documents with a field value in the
+// range, the caller must hold a lock
+func (idx *Index) rangeBitsLocked(r numericRange, bits []uint64) {
+	if field, ok := idx.fields[r.field]; ok {
+		field.search(r, func(id int) {
+			bits[id/64] |= 1 << (id % 64)
+		})
+	}
+}
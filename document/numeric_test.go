@@ -0,0 +1,180 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseFieldValue(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  float64
+		valid bool
+	}{
+		{"12.5", 12.5, true},
+		{"-3", -3, true},
+		{"+4", 4, true},
+		{".5", 0.5, true},
+		{"1e3", 1000, true},
+		{"2024-01-02", 1704153600, true},
+		{"2024-01-02t03:04:05z", 1704164645, true},
+		{"", 0, false},
+		{"inf", 0, false},
+		{"nan", 0, false},
+		{"1e999", 0, false},
+		{"12abc", 0, false},
+		{"python", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			got, ok := parseFieldValue(test.text)
+			if ok != test.valid || got != test.want {
+				t.Errorf("parseFieldValue(%q) = %g, %v, want %g, %v", test.text, got, ok, test.want, test.valid)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		token string
+		want  numericRange
+		valid bool
+	}{
+		{"price:10..20", numericRange{field: "price", min: 10, max: 20}, true},
+		{"price:10..", numericRange{field: "price", min: 10, max: inf}, true},
+		{"price:..20", numericRange{field: "price", min: -inf, max: 20}, true},
+		{"price:>10", numericRange{field: "price", min: 10, max: inf, excludeMin: true}, true},
+		{"price:>=10", numericRange{field: "price", min: 10, max: inf}, true},
+		{"price:<20", numericRange{field: "price", min: -inf, max: 20, excludeMax: true}, true},
+		{"price:<=20", numericRange{field: "price", min: -inf, max: 20}, true},
+		{"date:2024-01-01..2024-01-02", numericRange{field: "date", min: 1704067200, max: 1704153600}, true},
+		{"price:..", numericRange{}, false},
+		{"price:10", numericRange{}, false},
+		{"price:>abc", numericRange{}, false},
+		{"price:a..b", numericRange{}, false},
+		{":1..2", numericRange{}, false},
+		{"python", numericRange{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.token, func(t *testing.T) {
+			got, ok := parseRange(test.token)
+			if ok != test.valid || got != test.want {
+				t.Errorf("parseRange(%q) = %+v, %v, want %+v, %v", test.token, got, ok, test.want, test.valid)
+			}
+		})
+	}
+}
+
+// numericTestRanges cover open, closed, exclusive and empty ranges
+var numericTestRanges = []string{
+	"n:0..1000", "n:10..20", "n:..-1", "n:990..", "n:>500", "n:>=500", "n:<3", "n:<=3",
+	"n:250..250", "n:20..10", "n:-5..-1", "n:2000..",
+}
+
+func TestBKDTreeMatchesScan(t *testing.T) {
+	for _, count := range []int{0, 1, bkdLeafSize, bkdLeafSize + 1, 1000} {
+		points := make([]bkdPoint, count)
+		for id := range points {
+			// Repeated values straddle the leaf blocks
+			points[id] = bkdPoint{value: float64(id * 37 % 1000 / 2), id: id}
+		}
+		values := make([]float64, count)
+		for _, point := range points {
+			values[point.id] = point.value
+		}
+		tree := newBKDTree(points)
+
+		for _, query := range numericTestRanges {
+			t.Run(fmt.Sprintf("%d points %s", count, query), func(t *testing.T) {
+				r, ok := parseRange(query)
+				if !ok {
+					t.Fatalf("parseRange(%q) failed", query)
+				}
+				got := []int{}
+				tree.search(r, func(id int) { got = append(got, id) })
+				slices.Sort(got)
+				want := []int{}
+				for id, value := range values {
+					if r.contains(value) {
+						want = append(want, id)
+					}
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("search() = %d ids, want %d", len(got), len(want))
+				}
+			})
+		}
+	}
+}
+
+func TestRangeSearchAfterUpdates(t *testing.T) {
+	idx := NewIndex()
+	values := map[int]int{}
+	for i := 0; i < 600; i++ {
+		values[idx.Add(fmt.Sprintf("doc n:%d", i%300))] = i % 300
+	}
+	// Enough changes to rebuild the field tree, and pending ones after it
+	for id := 0; id < 600; id += 2 {
+		if err := idx.Update(id, fmt.Sprintf("doc n:%d", 1000-id)); err != nil {
+			t.Fatal(err)
+		}
+		values[id] = 1000 - id
+	}
+	for id := 1; id < 600; id += 7 {
+		if err := idx.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+		delete(values, id)
+	}
+
+	for _, query := range numericTestRanges {
+		t.Run(query, func(t *testing.T) {
+			r, _ := parseRange(query)
+			want := []int{}
+			for id, value := range values {
+				if r.contains(float64(value)) {
+					want = append(want, id)
+				}
+			}
+			slices.Sort(want)
+			if got := hitIDs(t, idx, query); !slices.Equal(got, want) {
+				t.Errorf("Search(%q) = %d hits, want %d", query, len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestRangeQueries(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range []string{
+		"laptop price:999 released:2023-06-01",
+		"phone price:499.5 released:2024-01-15",
+		"cable price:9",
+		"laptop bag",
+	} {
+		idx.Add(text)
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"price:..500", []int{1, 2}},
+		{"price:>499.5", []int{0}},
+		{"laptop AND price:100..", []int{0}},
+		{"NOT price:0..", []int{3}},
+		{"released:>=2024-01-01", []int{1}},
+		{"released:2023-01-01..2023-12-31 OR cable", []int{0, 2}},
+		{"weight:>0", []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := hitIDs(t, idx, test.query); !slices.Equal(got, test.want) {
+				t.Errorf("Search(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+}
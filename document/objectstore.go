@@ -0,0 +1,105 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultObjectTimeout bounds a single object store request when
+// ObjectStorage has no Timeout
+const defaultObjectTimeout = 30 * time.Second
+
+// ObjectClient is the part of an object store API that ObjectStorage needs,
+// small enough to wrap an S3, GCS or Azure Blob client in a few lines.
+// GetObject must fail with an error matching fs.ErrNotExist for missing
+// keys. For example, with the AWS SDK for Go v2:
+//
+//	type s3Client struct{ client *s3.Client }
+//
+//	func (c s3Client) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+//		out, err := c.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+//		var missing *types.NoSuchKey
+//		if errors.As(err, &missing) {
+//			return nil, fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+//		}
+//		if err != nil {
+//			return nil, err
+//		}
+//		defer out.Body.Close()
+//		return io.ReadAll(out.Body)
+//	}
+//
+// with PutObject, DeleteObject and ListObjectsV2 wrapped the same way
+type ObjectClient interface {
+	PutObject(ctx context.Context, bucket, key string, data []byte) error
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+	DeleteObject(ctx context.Context, bucket, key string) error
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// ObjectStorage keeps blobs as objects in a bucket, below an optional key
+// prefix so several indexes can share a bucket
+type ObjectStorage struct {
+	Client ObjectClient
+	Bucket string
+	// Prefix is prepended to every name, such as "indexes/products/"
+	Prefix string
+	// Timeout bounds every request, zero uses defaultObjectTimeout
+	Timeout time.Duration
+}
+
+// context returns the context for a single request
+func (storage *ObjectStorage) context() (context.Context, context.CancelFunc) {
+	timeout := storage.Timeout
+	if timeout == 0 {
+		timeout = defaultObjectTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Put uploads data under name
+func (storage *ObjectStorage) Put(name string, data []byte) error {
+	if err := checkStorageName(name); err != nil {
+		return err
+	}
+	ctx, cancel := storage.context()
+	defer cancel()
+	return storage.Client.PutObject(ctx, storage.Bucket, storage.Prefix+name, data)
+}
+
+// Get downloads the object stored under name
+func (storage *ObjectStorage) Get(name string) ([]byte, error) {
+	ctx, cancel := storage.context()
+	defer cancel()
+	return storage.Client.GetObject(ctx, storage.Bucket, storage.Prefix+name)
+}
+
+// Delete removes the object stored under name
+func (storage *ObjectStorage) Delete(name string) error {
+	ctx, cancel := storage.context()
+	defer cancel()
+	return storage.Client.DeleteObject(ctx, storage.Bucket, storage.Prefix+name)
+}
+
+// List returns the names starting with prefix in sorted order
+func (storage *ObjectStorage) List(prefix string) ([]string, error) {
+	ctx, cancel := storage.context()
+	defer cancel()
+	keys, err := storage.Client.ListObjects(ctx, storage.Bucket, storage.Prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		name, ok := strings.CutPrefix(key, storage.Prefix)
+		if !ok {
+			return nil, fmt.Errorf("object store listed key %q outside prefix %q", key, storage.Prefix)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,168 @@
+package document
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// storageFormatVersion is bumped whenever the persisted layout changes
+const storageFormatVersion = 1
+
+// storageManifestName is the blob naming the segments of the current state,
+// written last so a save interrupted midway leaves the previous one intact
+const storageManifestName = "manifest.json"
+
+// segmentPrefix is where the content addressed segments are stored
+const segmentPrefix = "segments/"
+
+// segmentDocs is the number of document IDs stored in one segment
+const segmentDocs = 1024
+
+// storageManifest lists the segments holding the stored fields of the
+// documents in ID order and the saved queries with their history, drafts
+// and usage
+type storageManifest struct {
+	Version int `json:"version"`
+	// Seq is the sequence number of the last index event included
+	Seq      uint64   `json:"seq"`
+	Docs     int      `json:"docs"`
+	Deleted  []int    `json:"deleted"`
+	Segments []string `json:"segments"`
+	queryStoreState
+}
+
+// SaveToStorage persists the index and the saved queries, with their
+// history, drafts and usage, to storage. The document texts are cut into segments named after their
+// checksum, so segments unchanged since the previous save are not written
+// again, and segments no longer referenced are deleted once the new
+// manifest is in place. A BatchStorage receives the whole save as one
+// atomic batch
+func SaveToStorage(storage Storage, idx *Index, store *QueryStore) error {
+	_, err := saveToStorage(storage, idx, store)
+	return err
+}
+
+// saveToStorage persists the index and saved queries, returning the
+// sequence number of the last event the saved state includes
+func saveToStorage(storage Storage, idx *Index, store *QueryStore) (uint64, error) {
+	docs, deleted, seq := idx.snapshot()
+	manifest := storageManifest{
+		Version:         storageFormatVersion,
+		Seq:             seq,
+		Docs:            len(docs),
+		Deleted:         deleted,
+		queryStoreState: store.state(),
+	}
+
+	existing, err := storage.List(segmentPrefix)
+	if err != nil {
+		return 0, err
+	}
+	stored := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		stored[name] = true
+	}
+
+	puts := make(map[string][]byte)
+	for start := 0; start < len(docs); start += segmentDocs {
+		data, err := json.Marshal(docs[start:min(start+segmentDocs, len(docs))])
+		if err != nil {
+			return 0, err
+		}
+		sum := sha256.Sum256(data)
+		name := segmentPrefix + hex.EncodeToString(sum[:]) + ".json"
+		if !stored[name] {
+			puts[name] = data
+		}
+		manifest.Segments = append(manifest.Segments, name)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	referenced := make(map[string]bool, len(manifest.Segments))
+	for _, name := range manifest.Segments {
+		referenced[name] = true
+	}
+	deletes := []string{}
+	for _, name := range existing {
+		if !referenced[name] {
+			deletes = append(deletes, name)
+		}
+	}
+
+	if batch, ok := storage.(BatchStorage); ok {
+		puts[storageManifestName] = data
+		return seq, batch.Batch(puts, deletes)
+	}
+	for name, segment := range puts {
+		if err := storage.Put(name, segment); err != nil {
+			return 0, err
+		}
+	}
+	if err := storage.Put(storageManifestName, data); err != nil {
+		return 0, err
+	}
+	for _, name := range deletes {
+		if err := storage.Delete(name); err != nil {
+			return 0, err
+		}
+	}
+	return seq, nil
+}
+
+// LoadFromStorage rebuilds the index and query store persisted by
+// SaveToStorage, verifying every segment against its checksum. The history
+// and drafts of the queries are restored as saved, not replayed
+func LoadFromStorage(storage Storage) (*Index, *QueryStore, error) {
+	data, err := storage.Get(storageManifestName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("storage manifest missing: %w", err)
+	}
+	manifest := storageManifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid storage manifest: %w", err)
+	}
+	if manifest.Version != storageFormatVersion {
+		return nil, nil, fmt.Errorf("unsupported storage version %d", manifest.Version)
+	}
+
+	docs := make([]string, 0, manifest.Docs)
+	for _, name := range manifest.Segments {
+		data, err := storage.Get(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("storage is missing segment %s: %w", name, err)
+		}
+		sum := sha256.Sum256(data)
+		if segmentPrefix+hex.EncodeToString(sum[:])+".json" != name {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s", name)
+		}
+		segment := []string{}
+		if err := json.Unmarshal(data, &segment); err != nil {
+			return nil, nil, fmt.Errorf("invalid segment %s: %w", name, err)
+		}
+		docs = append(docs, segment...)
+	}
+	if len(docs) != manifest.Docs {
+		return nil, nil, fmt.Errorf("storage holds %d documents, manifest lists %d", len(docs), manifest.Docs)
+	}
+
+	idx, err := indexFromSnapshot(docs, manifest.Deleted)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Events continue the numbering of the saved index, so a write-ahead
+	// log can tell which of its entries the saved state already includes
+	idx.mu.Lock()
+	idx.seq = manifest.Seq
+	idx.mu.Unlock()
+	store, err := queryStoreFromState(manifest.queryStoreState)
+	if err != nil {
+		return nil, nil, err
+	}
+	return idx, store, nil
+}
@@ -0,0 +1,176 @@
+package document
+
+import (
+	"math/bits"
+)
+
+// span is the half-open range of RPN tokens forming one subexpression
+type span struct {
+	start, end int
+}
+
+// rpnSpans returns for every RPN token the span of the subexpression it is
+// the root of, the rpn must be valid
+func rpnSpans(rpn []string) []span {
+	spans := make([]span, len(rpn))
+	stack := []span{}
+	for i, token := range rpn {
+		current := span{start: i, end: i + 1}
+		switch token {
+		case "AND", "OR":
+			current.start = stack[len(stack)-2].start
+			stack = stack[:len(stack)-2]
+		case "NOT", "FILTER":
+			current.start = stack[len(stack)-1].start
+			stack = stack[:len(stack)-1]
+		}
+		spans[i] = current
+		stack = append(stack, current)
+	}
+	return spans
+}
+
+// planQuery splits the top-level AND clauses of a query into pure filters,
+// the clauses wrapped in FILTER and the range terms, which never score, and
+// a residual scoring query built from the other clauses, nil when every
+// clause is a filter. It also returns the terms that contribute to the
+// score, those outside any FILTER
+func planQuery(rpn []string, ranges map[string]numericRange) (filters [][]string, residual []string, scoreTerms []string) {
+	spans := rpnSpans(rpn)
+
+	var conjuncts func(root int) []span
+	conjuncts = func(root int) []span {
+		if rpn[root] != "AND" {
+			return []span{spans[root]}
+		}
+		// The right operand ends just before the AND, the left just before it
+		right := root - 1
+		left := spans[right].start - 1
+		return append(conjuncts(left), conjuncts(right)...)
+	}
+
+	for _, clause := range conjuncts(len(rpn) - 1) {
+		if rpn[clause.end-1] == "FILTER" {
+			filters = append(filters, rpn[clause.start:clause.end-1])
+			continue
+		}
+		if _, ok := ranges[rpn[clause.start]]; ok && clause.end-clause.start == 1 {
+			filters = append(filters, rpn[clause.start:clause.end])
+			continue
+		}
+		residual = append(residual, rpn[clause.start:clause.end]...)
+		if len(residual) > clause.end-clause.start {
+			residual = append(residual, "AND")
+		}
+	}
+
+	filtered := make([]bool, len(rpn))
+	for i, token := range rpn {
+		if token == "FILTER" {
+			for j := spans[i].start; j < i; j++ {
+				filtered[j] = true
+			}
+		}
+	}
+	for i, token := range rpn {
+		if _, ok := ranges[token]; !ok && !filtered[i] && !isOperator(token) {
+			scoreTerms = append(scoreTerms, token)
+		}
+	}
+	return filters, residual, scoreTerms
+}
+
+// filterBitsLocked evaluates filter queries against the postings and the
+// numeric fields of the index, returning a bitset of the live documents
+// matching all of them. Every bitset is charged to the search's account.
+// The caller must hold a lock
+func (idx *Index) filterBitsLocked(filters [][]string, ranges map[string]numericRange, account *memoryAccount) ([]uint64, error) {
+	words := (len(idx.docs) + 63) / 64
+	live, err := account.bitset(words)
+	if err != nil {
+		return nil, err
+	}
+	for id, doc := range idx.docs {
+		if doc != nil {
+			live[id/64] |= 1 << (id % 64)
+		}
+	}
+
+	result := live
+	for _, filter := range filters {
+		stack := [][]uint64{}
+		for _, token := range filter {
+			switch token {
+			case "AND":
+				top := len(stack) - 1
+				for w := range stack[top-1] {
+					stack[top-1][w] &= stack[top][w]
+				}
+				stack = stack[:top]
+			case "OR":
+				top := len(stack) - 1
+				for w := range stack[top-1] {
+					stack[top-1][w] |= stack[top][w]
+				}
+				stack = stack[:top]
+			case "NOT":
+				top := stack[len(stack)-1]
+				for w := range top {
+					top[w] = ^top[w] & live[w]
+				}
+			case "FILTER":
+			default:
+				termBits, err := account.bitset(words)
+				if err != nil {
+					return nil, err
+				}
+				if r, ok := ranges[token]; ok {
+					idx.rangeBitsLocked(r, termBits)
+					stack = append(stack, termBits)
+					continue
+				}
+				for _, id := range idx.postings[token] {
+					termBits[id/64] |= 1 << (id % 64)
+				}
+				stack = append(stack, termBits)
+			}
+		}
+
+		matched, err := account.bitset(words)
+		if err != nil {
+			return nil, err
+		}
+		for w := range matched {
+			matched[w] = result[w] & stack[0][w]
+		}
+		result = matched
+	}
+	return result, nil
+}
+
+// nextCandidate returns the first ID at or after from whose bit is set in
+// candidates, or limit if there is none. Nil candidates admit every ID
+func nextCandidate(candidates []uint64, from, limit int) int {
+	if candidates == nil || from >= limit {
+		return from
+	}
+	for w := from / 64; w < len(candidates); w++ {
+		word := candidates[w]
+		if w == from/64 {
+			word &= ^uint64(0) << (from % 64)
+		}
+		if word != 0 {
+			return min(w*64+bits.TrailingZeros64(word), limit)
+		}
+	}
+	return limit
+}
+
+// countCandidates returns the number of IDs admitted by candidates
+func countCandidates(candidates []uint64) int {
+	count := 0
+	for _, word := range candidates {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
@@ -0,0 +1,103 @@
+package document
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlanQuery(t *testing.T) {
+	tests := []struct {
+		query      string
+		filters    []string
+		residual   string
+		scoreTerms string
+	}{
+		{"python", nil, "python", "python"},
+		{"python AND FILTER java", []string{"java"}, "python", "python"},
+		{"FILTER java", []string{"java"}, "", ""},
+		{"python AND price:>10", []string{"price:>10"}, "python", "python"},
+		{"(python OR rust) AND FILTER (java OR go) AND guide", []string{"java go OR"}, "python rust OR guide AND", "python rust guide"},
+		{"FILTER java AND FILTER price:<5", []string{"java", "price:<5"}, "", ""},
+		{"FILTER NOT java AND python", []string{"java NOT"}, "python", "python"},
+		// A filter below OR cannot run first, it only stops scoring
+		{"python OR FILTER java", nil, "python java FILTER OR", "python"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			compiled, err := CompileQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filters, residual, scoreTerms := planQuery(compiled.rpn, compiled.ranges)
+			got := []string{}
+			for _, filter := range filters {
+				got = append(got, strings.Join(filter, " "))
+			}
+			if !slices.Equal(got, test.filters) && (len(got) != 0 || len(test.filters) != 0) {
+				t.Errorf("filters = %q, want %q", got, test.filters)
+			}
+			if got := strings.Join(residual, " "); got != test.residual {
+				t.Errorf("residual = %q, want %q", got, test.residual)
+			}
+			if got := strings.Join(scoreTerms, " "); got != test.scoreTerms {
+				t.Errorf("scoreTerms = %q, want %q", got, test.scoreTerms)
+			}
+		})
+	}
+}
+
+// TestFilteredSearch checks that filters select the same documents as the
+// equivalent scoring clauses without changing the scores
+func TestFilteredSearch(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("python java guide price:5")
+	idx.Add("python python tutorial price:20")
+	idx.Add("python java java price:15")
+	idx.Add("rust java price:8")
+
+	tests := []struct {
+		filtered string
+		matching string
+		scoring  string
+	}{
+		{"python AND FILTER java", "python AND java", "python"},
+		{"python AND price:>10", "python AND price:>10", "python"},
+		{"FILTER java AND FILTER price:<10", "java AND price:<10", ""},
+		{"python AND FILTER NOT java", "python AND NOT java", "python"},
+	}
+	for _, test := range tests {
+		t.Run(test.filtered, func(t *testing.T) {
+			filtered, err := idx.Search(test.filtered, SearchOptions{Scorer: TFIDFScorer{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := hitIDs(t, idx, test.filtered), hitIDs(t, idx, test.matching); !slices.Equal(got, want) {
+				t.Errorf("Search(%q) = %v, want the hits of %q %v", test.filtered, got, test.matching, want)
+			}
+
+			compiled, err := CompileQuery(test.filtered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(compiled.ScoringTerms(), " "); got != test.scoring {
+				t.Errorf("ScoringTerms() = %q, want %q", got, test.scoring)
+			}
+			scores := map[int]float64{}
+			if test.scoring != "" {
+				scored, err := idx.Search(test.scoring, SearchOptions{Scorer: TFIDFScorer{}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, hit := range scored.Hits {
+					scores[hit.ID] = hit.Score
+				}
+			}
+			for _, hit := range filtered.Hits {
+				if hit.Score != scores[hit.ID] {
+					t.Errorf("hit %d scored %v, want %v as for %q", hit.ID, hit.Score, scores[hit.ID], test.scoring)
+				}
+			}
+		})
+	}
+}
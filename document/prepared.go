@@ -0,0 +1,64 @@
+package document
+
+// PreparedDoc is a document analyzed once so it can be matched against many
+// queries without re-analyzing its text
+type PreparedDoc struct {
+	text      string
+	terms     map[string]int
+	positions map[string][]int
+	length    int
+	// fields holds the values of name:value terms with a numeric or date value
+	fields map[string]float64
+}
+
+// PrepareDocument analyzes a document into its term frequencies and positions
+func PrepareDocument(text string) *PreparedDoc {
+	doc := &PreparedDoc{
+		text:      text,
+		terms:     make(map[string]int),
+		positions: make(map[string][]int),
+	}
+
+	for position, term := range analyze(text) {
+		doc.terms[term]++
+		doc.positions[term] = append(doc.positions[term], position)
+		doc.length++
+		if name, value, ok := splitField(term); ok {
+			if doc.fields == nil {
+				doc.fields = make(map[string]float64)
+			}
+			doc.fields[name] = value
+		}
+	}
+	return doc
+}
+
+// Text returns the original document text
+func (doc *PreparedDoc) Text() string {
+	return doc.text
+}
+
+// Contains reports whether the document contains an analyzed term
+func (doc *PreparedDoc) Contains(term string) bool {
+	return doc.terms[term] > 0
+}
+
+// Positions returns the word positions at which term occurs in the document
+func (doc *PreparedDoc) Positions(term string) []int {
+	return doc.positions[term]
+}
+
+// Field returns the value of a numeric or date field of the document, dates
+// in seconds since the Unix epoch
+func (doc *PreparedDoc) Field(name string) (float64, bool) {
+	value, ok := doc.fields[name]
+	return value, ok
+}
+
+// MatchDocument evaluates the query against a prepared document
+func (q *Query) MatchDocument(doc *PreparedDoc) bool {
+	if len(q.ranges) > 0 {
+		return q.NewProcessor().MatchDocument(q, doc)
+	}
+	return q.Match(doc.terms)
+}
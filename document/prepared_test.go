@@ -0,0 +1,75 @@
+package document
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPrepareDocument(t *testing.T) {
+	doc := PrepareDocument("Python guide: the python way, price:12.5")
+
+	if doc.Text() != "Python guide: the python way, price:12.5" {
+		t.Errorf("Text() = %q", doc.Text())
+	}
+	tests := []struct {
+		term      string
+		contains  bool
+		positions []int
+	}{
+		{"python", true, []int{0, 3}},
+		{"guide", true, []int{1}},
+		{"way", true, []int{4}},
+		{"Python", false, nil},
+		{"java", false, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.term, func(t *testing.T) {
+			if got := doc.Contains(test.term); got != test.contains {
+				t.Errorf("Contains(%q) = %v, want %v", test.term, got, test.contains)
+			}
+			if got := doc.Positions(test.term); !slices.Equal(got, test.positions) {
+				t.Errorf("Positions(%q) = %v, want %v", test.term, got, test.positions)
+			}
+		})
+	}
+
+	if value, ok := doc.Field("price"); !ok || value != 12.5 {
+		t.Errorf("Field(price) = %g, %v, want 12.5", value, ok)
+	}
+	if _, ok := doc.Field("date"); ok {
+		t.Error("Field(date) found a field the document does not have")
+	}
+}
+
+// TestPreparedDocumentMatchesManyQueries matches one prepared document
+// against several queries, term and range queries alike
+func TestPreparedDocumentMatchesManyQueries(t *testing.T) {
+	doc := PrepareDocument("Cooking with Python, price:12.5")
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"python", true},
+		{"cooking AND python", true},
+		{"python AND NOT cooking", false},
+		{"java OR cooking", true},
+		{"price:10..20", true},
+		{"price:>20", false},
+		{"python AND price:<=12.5", true},
+		{"py*", true},
+		{"cook?ng AND NOT /jav.*/", true},
+		{"pyhton~1 AND price:10..20", true},
+		{"java*", false},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			compiled, err := CompileQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := compiled.MatchDocument(doc); got != test.want {
+				t.Errorf("MatchDocument() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
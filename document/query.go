@@ -0,0 +1,261 @@
+package document
+
+import "github.com/StefanTrusnov/go-rpn/engine"
+
+// Query is a boolean query parsed once into RPN so it can be matched against
+// many analyzed documents without re-tokenizing. Clauses wrapped in FILTER
+// must match but do not contribute to the score, and top-level filters are
+// evaluated first as bitsets over the index postings
+type Query struct {
+	source   string
+	rpn      []string
+	maxDepth int
+
+	// filters holds the top-level FILTER clauses, residual the query left
+	// to match against each document passing them
+	filters    [][]string
+	residual   *Query
+	scoreTerms []string
+
+	// patterns holds the automata of wildcard, fuzzy and regex terms, by
+	// token, which searches expand against the index's term dictionary
+	patterns map[string]nfa
+
+	// ranges holds the numeric range terms, by token
+	ranges map[string]numericRange
+}
+
+// CompileQuery parses and validates a boolean query
+func CompileQuery(query string) (*Query, error) {
+	tokens, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := map[string]nfa{}
+	for _, token := range tokens {
+		if token == "(" || token == ")" || isOperator(token) {
+			continue
+		}
+		automaton, ok, err := compileTermPattern(token)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			patterns[token] = automaton
+		}
+	}
+
+	compiled, err := compileTokens(query, tokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) > 0 {
+		compiled.patterns = patterns
+	}
+	return compiled, nil
+}
+
+// compileTokens builds a query from parsed tokens, taking every term literally
+func compileTokens(query string, tokens []string) (*Query, error) {
+	rpn := buildRPN(tokens)
+	ranges := map[string]numericRange{}
+	for _, token := range rpn {
+		if r, ok := parseRange(token); ok {
+			ranges[token] = r
+		}
+	}
+
+	maxDepth, err := rpnMaxDepth(rpn)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &Query{source: query, rpn: rpn, maxDepth: maxDepth}
+	if len(ranges) > 0 {
+		compiled.ranges = ranges
+	}
+	filters, residual, scoreTerms := planQuery(rpn, ranges)
+	compiled.scoreTerms = scoreTerms
+	if len(filters) > 0 {
+		compiled.filters = filters
+		if len(residual) > 0 {
+			residualDepth, _ := rpnMaxDepth(residual)
+			compiled.residual = &Query{source: query, rpn: residual, maxDepth: residualDepth, scoreTerms: scoreTerms, ranges: compiled.ranges}
+		}
+	}
+	return compiled, nil
+}
+
+// rpnMaxDepth validates the operand balance of a boolean RPN expression and
+// returns the deepest the evaluation stack gets
+func rpnMaxDepth(rpn []string) (int, error) {
+	depth, maxDepth := 0, 0
+	for _, token := range rpn {
+		switch token {
+		case "AND", "OR":
+			if depth < 2 {
+				return 0, engine.Underflow(token)
+			}
+			depth--
+		case "NOT", "FILTER":
+			if depth < 1 {
+				return 0, engine.Underflow(token)
+			}
+		default:
+			depth++
+			maxDepth = max(maxDepth, depth)
+		}
+	}
+	if depth != 1 {
+		return 0, engine.Incomplete(depth)
+	}
+	return maxDepth, nil
+}
+
+// MaxDepth returns the deepest the evaluation stack gets while matching the query
+func (q *Query) MaxDepth() int {
+	return q.maxDepth
+}
+
+// NewProcessor creates a boolean processor whose stack is preallocated for
+// the query, so matching it against many documents never grows the stack
+func (q *Query) NewProcessor() *BooleanRPNProcessor {
+	return NewBooleanRPNProcessorSize(q.maxDepth)
+}
+
+// String returns the source text of the query
+func (q *Query) String() string {
+	return q.source
+}
+
+// Canonical returns the query rebuilt from its RPN, with lowercase terms,
+// single spaces and only the parentheses precedence needs, so two sources
+// differing only in layout have the same canonical form
+func (q *Query) Canonical() string {
+	// Each entry is a rendered operand with the precedence of its top
+	// operator, terms binding tightest
+	type operand struct {
+		text       string
+		precedence int
+	}
+	stack := []operand{}
+	wrap := func(o operand, below int) string {
+		if o.precedence < below {
+			return "(" + o.text + ")"
+		}
+		return o.text
+	}
+	for _, token := range q.rpn {
+		switch token {
+		case "AND", "OR":
+			left, right := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			// Operators group to the left, so an equal precedence right
+			// operand keeps its parentheses
+			text := wrap(left, precedence[token]) + " " + token + " " + wrap(right, precedence[token]+1)
+			stack = append(stack, operand{text, precedence[token]})
+		case "NOT", "FILTER":
+			top := len(stack) - 1
+			stack[top] = operand{token + " " + wrap(stack[top], precedence[token]), precedence[token]}
+		default:
+			stack = append(stack, operand{token, len(precedence)})
+		}
+	}
+	return stack[0].text
+}
+
+// ScoringTerms returns the search terms that contribute to the score, the
+// terms outside FILTER clauses
+func (q *Query) ScoringTerms() []string {
+	return append([]string(nil), q.scoreTerms...)
+}
+
+// Terms returns the search terms used by the query, without range terms
+func (q *Query) Terms() []string {
+	terms := []string{}
+	for _, token := range q.rpn {
+		if _, ok := q.ranges[token]; !ok && !isOperator(token) {
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
+
+// Match evaluates the query against the term frequencies of an analyzed
+// document, expanding wildcard, fuzzy and regex terms against its terms.
+// Range terms need the document's fields and never match here,
+// MatchDocument evaluates them
+func (q *Query) Match(terms map[string]int) bool {
+	stack := make([]bool, 0, len(q.rpn))
+
+	for _, token := range q.rpn {
+		switch token {
+		case "AND":
+			top := len(stack) - 1
+			stack[top-1] = stack[top-1] && stack[top]
+			stack = stack[:top]
+		case "OR":
+			top := len(stack) - 1
+			stack[top-1] = stack[top-1] || stack[top]
+			stack = stack[:top]
+		case "NOT":
+			top := len(stack) - 1
+			stack[top] = !stack[top]
+		case "FILTER":
+		default:
+			if automaton, ok := q.patterns[token]; ok {
+				stack = append(stack, matchPattern(automaton, terms))
+				continue
+			}
+			stack = append(stack, terms[token] > 0)
+		}
+	}
+
+	return stack[0]
+}
+
+// Score scores a document by how often it contains the scoring terms of the query
+func (q *Query) Score(terms map[string]int) float64 {
+	score := 0.0
+	for _, term := range q.scoreTerms {
+		score += float64(terms[term])
+	}
+	return score
+}
+
+// MatchDocument evaluates a compiled query against a prepared document,
+// reusing the processor stack between calls
+func (proc *BooleanRPNProcessor) MatchDocument(q *Query, doc *PreparedDoc) bool {
+	proc.Clear()
+
+	for _, token := range q.rpn {
+		switch token {
+		case "AND":
+			second, _ := proc.Pop()
+			first, _ := proc.Pop()
+			proc.Push(first && second)
+		case "OR":
+			second, _ := proc.Pop()
+			first, _ := proc.Pop()
+			proc.Push(first || second)
+		case "NOT":
+			operand, _ := proc.Pop()
+			proc.Push(!operand)
+		case "FILTER":
+		default:
+			if r, ok := q.ranges[token]; ok {
+				proc.Push(r.matchDoc(doc))
+				continue
+			}
+			if automaton, ok := q.patterns[token]; ok {
+				proc.Push(matchPattern(automaton, doc.terms))
+				continue
+			}
+			proc.Push(doc.terms[token] > 0)
+		}
+	}
+
+	result, _ := proc.Pop()
+	return result
+}
@@ -0,0 +1,131 @@
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// QueryFileLoader keeps a store in sync with a JSON file mapping query
+// names to queries, the format of the queries in a backup's queries.json:
+//
+//	{"guides": "(python OR java) AND guide", "recent": "year > 2020"}
+//
+// Each query is compiled on its own, so an edit that breaks one query is
+// reported while the version of it loaded before stays live and the other
+// edits still take effect. Edits the store's approval hook does not approve
+// wait as drafts
+type QueryFileLoader struct {
+	path  string
+	store *QueryStore
+
+	mu      sync.Mutex
+	version fileVersion
+	// loaded holds the source in effect for every name the file declared,
+	// so names dropped from the file can be deleted from the store
+	loaded map[string]string
+}
+
+// NewQueryFileLoader creates a loader of the query file at path
+func NewQueryFileLoader(path string, store *QueryStore) *QueryFileLoader {
+	return &QueryFileLoader{path: path, store: store, loaded: make(map[string]string)}
+}
+
+// Load reads the query file and swaps the queries that compile into the
+// store in a single update. Queries that fail to compile are reported
+// together and keep their previous version
+func (loader *QueryFileLoader) Load() error {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+	return loader.loadLocked(true)
+}
+
+// loadLocked loads the file unless it is the version last read, or always
+// when force is set. The caller must hold loader.mu
+func (loader *QueryFileLoader) loadLocked(force bool) error {
+	last := loader.version
+	if force {
+		last = fileVersion{}
+	}
+	data, version, changed, err := readChanged(loader.path, last)
+	if err != nil || !changed {
+		return err
+	}
+	loader.version = version
+
+	var sources map[string]string
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return fmt.Errorf("%s: invalid query file: %w", loader.path, err)
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	saves := make(map[string]*Query)
+	var errs []error
+	for _, name := range names {
+		source := sources[name]
+		if name == "" {
+			errs = append(errs, fmt.Errorf("query name must not be empty"))
+			continue
+		}
+		if previous, ok := loader.loaded[name]; ok && previous == source {
+			continue
+		}
+		compiled, err := CompileQuery(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("query %s: %w", name, err))
+			continue
+		}
+		saves[name] = compiled
+	}
+	deletes := []string{}
+	for name := range loader.loaded {
+		if _, ok := sources[name]; !ok {
+			deletes = append(deletes, name)
+			delete(loader.loaded, name)
+		}
+	}
+	held, rejected := loader.store.update(saves, deletes)
+	errs = append(errs, rejected...)
+	for name, compiled := range saves {
+		if !slices.Contains(held, name) {
+			loader.loaded[name] = compiled.String()
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %w", loader.path, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Sources returns the source in effect for every query the file declares,
+// which for a query with a bad edit is the version loaded before it
+func (loader *QueryFileLoader) Sources() map[string]string {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+	sources := make(map[string]string, len(loader.loaded))
+	for name, source := range loader.loaded {
+		sources[name] = source
+	}
+	return sources
+}
+
+// Watch checks the file every interval and loads it when its modification
+// time or size changed, passing load errors to onError. It runs until the
+// returned function is called
+func (loader *QueryFileLoader) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	return pollEvery(interval, func() error {
+		loader.mu.Lock()
+		defer loader.mu.Unlock()
+		return loader.loadLocked(false)
+	}, onError)
+}
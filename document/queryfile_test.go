@@ -0,0 +1,100 @@
+package document
+
+import (
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryFileLoader(t *testing.T) {
+	// Every step writes the file and loads it
+	steps := []struct {
+		name    string
+		data    string
+		wantErr string
+		sources map[string]string
+	}{
+		{"initial", `{"a": "go", "b": "rust"}`, "", map[string]string{"a": "go", "b": "rust"}},
+		{"edit and add", `{"a": "go OR golang", "b": "rust", "c": "java"}`, "", map[string]string{"a": "go OR golang", "b": "rust", "c": "java"}},
+		{
+			"bad edit keeps the previous version",
+			`{"a": "go AND", "b": "rust AND java", "c": "java"}`,
+			"query a: insufficient operands for AND operation",
+			map[string]string{"a": "go OR golang", "b": "rust AND java", "c": "java"},
+		},
+		{
+			"several errors",
+			`{"": "x", "a": "go AND", "b": "OR", "c": "java"}`,
+			"query name must not be empty\nquery a: insufficient operands for AND operation\nquery b: insufficient operands for OR operation",
+			map[string]string{"a": "go OR golang", "b": "rust AND java", "c": "java"},
+		},
+		{"drop names", `{"c": "java"}`, "", map[string]string{"c": "java"}},
+		{"not json", `["c"]`, "invalid query file: json: cannot unmarshal array into Go value of type map[string]string", map[string]string{"c": "java"}},
+		{"empty", `{}`, "", map[string]string{}},
+	}
+	path := filepath.Join(t.TempDir(), "queries.json")
+	store := NewQueryStore()
+	// Queries saved by hand are left alone
+	if err := store.Save("manual", "python"); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewQueryFileLoader(path, store)
+	if err := loader.Load(); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			writeWatchedFile(t, path, step.data)
+			err := loader.Load()
+			if step.wantErr == "" && err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if step.wantErr != "" && (err == nil || strings.TrimPrefix(err.Error(), path+": ") != step.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, step.wantErr)
+			}
+			if got := loader.Sources(); !maps.Equal(got, step.sources) {
+				t.Errorf("Sources() = %v, want %v", got, step.sources)
+			}
+			stored := store.Sources()
+			delete(stored, "manual")
+			if !maps.Equal(stored, step.sources) {
+				t.Errorf("store holds %v, want %v", stored, step.sources)
+			}
+		})
+	}
+	if _, ok := store.Get("manual"); !ok {
+		t.Error("the query saved by hand was deleted")
+	}
+}
+
+func TestQueryFileLoaderWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.json")
+	writeWatchedFile(t, path, `{"a": "go"}`)
+	store := NewQueryStore()
+	loader := NewQueryFileLoader(path, store)
+	errs := make(chan error, 10)
+	stop := loader.Watch(5*time.Millisecond, func(err error) { errs <- err })
+	defer stop()
+	waitFor(t, "the first load", func() bool { return sameSet(store.Names(), []string{"a"}) })
+
+	writeWatchedFile(t, path, `{"a": "go AND", "b": "rust"}`)
+	select {
+	case err := <-errs:
+		if err == nil || !strings.HasSuffix(err.Error(), "query a: insufficient operands for AND operation") {
+			t.Errorf("Watch reported %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not report the bad edit")
+	}
+	// The good edit of the same file went live
+	if got := store.Sources(); !maps.Equal(got, map[string]string{"a": "go", "b": "rust"}) {
+		t.Errorf("store holds %v", got)
+	}
+	stop()
+	if len(errs) != 0 {
+		t.Errorf("Watch reported %v", <-errs)
+	}
+}
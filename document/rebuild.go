@@ -0,0 +1,144 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// rebuildChunk is the number of documents Rebuild indexes each time it
+// takes the write lock
+const rebuildChunk = 1024
+
+// ErrIndexRebuilding is returned by searches that need the postings while
+// Rebuild has only indexed part of the documents, unless they set
+// AllowDegraded
+var ErrIndexRebuilding = errors.New("index is rebuilding")
+
+// rebuildState tracks a running Rebuild: the documents from next up to end
+// are not in the postings yet
+type rebuildState struct {
+	next, end int
+}
+
+// Rebuild recomputes the postings and the term dictionary from the stored
+// documents, releasing the memory left behind by deletes and updates. It
+// clears them and indexes the documents a chunk at a time, so writers are
+// never blocked for the whole rebuild. Until it finishes searches fail with
+// ErrIndexRebuilding unless they set AllowDegraded, and Postings and the
+// term listings only cover the documents indexed so far
+func (idx *Index) Rebuild() {
+	state := idx.startRebuild()
+	if state == nil {
+		return
+	}
+	for !idx.rebuildStep(state) {
+	}
+}
+
+// startRebuild clears the postings and the term dictionary for a Rebuild,
+// returning nil if one is already running
+func (idx *Index) startRebuild() *rebuildState {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.rebuild != nil {
+		return nil
+	}
+	state := &rebuildState{end: len(idx.docs)}
+	idx.rebuild = state
+	idx.postings = make(map[string][]int)
+	idx.dict = newTermDictionary(nil)
+	return state
+}
+
+// rebuildStep indexes the next chunk of documents of a Rebuild under the
+// write lock, reporting whether the rebuild is over
+func (idx *Index) rebuildStep(state *rebuildState) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.rebuild != state {
+		// Swap replaced the postings with complete ones
+		return true
+	}
+	stop := min(state.next+rebuildChunk, state.end)
+	for id := state.next; id < stop; id++ {
+		if doc := idx.docs[id]; doc != nil {
+			idx.insertPostingsLocked(id, doc)
+		}
+	}
+	state.next = stop
+	if state.next == state.end {
+		idx.rebuild = nil
+		return true
+	}
+	return false
+}
+
+// Rebuilding reports whether a Rebuild is running
+func (idx *Index) Rebuilding() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.rebuild != nil
+}
+
+// unindexedLocked reports whether a running Rebuild has yet to add the
+// document to the postings, the caller must hold a lock
+func (idx *Index) unindexedLocked(id int) bool {
+	return idx.rebuild != nil && id >= idx.rebuild.next && id < idx.rebuild.end
+}
+
+// insertPostingsLocked adds a document to the postings of its terms in ID
+// order, the caller must hold the write lock
+func (idx *Index) insertPostingsLocked(id int, doc *PreparedDoc) {
+	for term := range doc.terms {
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			idx.dict.insert(term)
+		}
+		at := sort.SearchInts(postings, id)
+		postings = append(postings, 0)
+		copy(postings[at+1:], postings[at:])
+		postings[at] = id
+		idx.postings[term] = postings
+	}
+}
+
+// searchDegradedLocked runs a query by matching it against every stored
+// document, without the postings or the term dictionary. Patterns expand
+// against the terms of the documents. The caller must hold a lock
+func (idx *Index) searchDegradedLocked(compiled *Query, opts SearchOptions, trace *searchTrace) (SearchResult, error) {
+	phase := time.Now()
+	if len(compiled.patterns) > 0 {
+		compiled = expandPatterns(compiled, docsDictionary(idx.docs))
+	}
+	trace.plan = fmt.Sprintf("scan, match %v", compiled.rpn)
+	trace.phases.Expand += time.Since(phase)
+
+	phase = time.Now()
+	defer func() { trace.phases.Match += time.Since(phase) }()
+	result, err := searchDocs(compiled, compiled, idx.docs, idx.added, nil, opts, newMemoryAccount(opts.MemoryBudget))
+	if err != nil {
+		return SearchResult{}, err
+	}
+	result.Degraded = true
+	return result, nil
+}
+
+// docsDictionary builds a term dictionary of the terms of documents
+func docsDictionary(docs []*PreparedDoc) *termDictionary {
+	termSet := map[string]bool{}
+	for _, doc := range docs {
+		if doc != nil {
+			for term := range doc.terms {
+				termSet[term] = true
+			}
+		}
+	}
+	terms := make([]string, 0, len(termSet))
+	for term := range termSet {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return newTermDictionary(terms)
+}
@@ -0,0 +1,144 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// rebuildTestIndex creates an index with more documents than a Rebuild
+// indexes in one chunk, after some deletes and updates
+func rebuildTestIndex(t *testing.T) *Index {
+	t.Helper()
+	colors := []string{"red", "green", "blue"}
+	idx := NewIndex()
+	for i := 0; i < rebuildChunk+500; i++ {
+		idx.Add(fmt.Sprintf("item%d %s", i, colors[i%3]))
+	}
+	for _, id := range []int{3, 1100} {
+		if err := idx.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.Update(4, "item4 green yellow"); err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestRebuildDuringWrites(t *testing.T) {
+	// Each write is applied both to an index in the middle of a Rebuild
+	// and to an identical index that is not rebuilding
+	writes := []struct {
+		name  string
+		apply func(*Index) error
+	}{
+		{"delete indexed", func(idx *Index) error { return idx.Delete(10) }},
+		{"delete unindexed", func(idx *Index) error { return idx.Delete(1200) }},
+		{"update indexed", func(idx *Index) error { return idx.Update(20, "item20 purple") }},
+		{"update unindexed", func(idx *Index) error { return idx.Update(1300, "item1300 purple red") }},
+		{"add", func(idx *Index) error { idx.Add("item new red purple"); return nil }},
+	}
+	queries := []string{"red", "purple", "purple AND red", "green OR yellow", "item1* AND blue", "NOT red"}
+
+	rebuilt, expected := rebuildTestIndex(t), rebuildTestIndex(t)
+	state := rebuilt.startRebuild()
+	if state == nil || rebuilt.startRebuild() != nil {
+		t.Fatal("startRebuild() did not start exactly one rebuild")
+	}
+	if rebuilt.rebuildStep(state) {
+		t.Fatal("rebuild finished after one chunk")
+	}
+	if !rebuilt.Rebuilding() {
+		t.Error("Rebuilding() = false during a rebuild")
+	}
+	for _, write := range writes {
+		if err := write.apply(rebuilt); err != nil {
+			t.Fatalf("%s: %v", write.name, err)
+		}
+		if err := write.apply(expected); err != nil {
+			t.Fatalf("%s: %v", write.name, err)
+		}
+	}
+
+	for _, query := range queries {
+		t.Run("degraded "+query, func(t *testing.T) {
+			if _, err := rebuilt.Search(query, SearchOptions{}); !errors.Is(err, ErrIndexRebuilding) {
+				t.Errorf("Search(%q) error = %v, want %v", query, err, ErrIndexRebuilding)
+			}
+			result, err := rebuilt.Search(query, SearchOptions{AllowDegraded: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Degraded {
+				t.Error("result is not Degraded")
+			}
+			ids := []int{}
+			for _, hit := range result.Hits {
+				ids = append(ids, hit.ID)
+			}
+			slices.Sort(ids)
+			if want := hitIDs(t, expected, query); !slices.Equal(ids, want) {
+				t.Errorf("degraded Search(%q) = %v, want %v", query, ids, want)
+			}
+		})
+	}
+
+	for !rebuilt.rebuildStep(state) {
+	}
+	if rebuilt.Rebuilding() {
+		t.Error("Rebuilding() = true after the rebuild")
+	}
+	for _, query := range queries {
+		t.Run("rebuilt "+query, func(t *testing.T) {
+			result, err := rebuilt.Search(query, SearchOptions{})
+			if err != nil || result.Degraded {
+				t.Fatalf("Search(%q) = degraded %t, %v", query, result.Degraded, err)
+			}
+			if got, want := hitIDs(t, rebuilt, query), hitIDs(t, expected, query); !slices.Equal(got, want) {
+				t.Errorf("Search(%q) = %v, want %v", query, got, want)
+			}
+		})
+	}
+	for _, term := range []string{"red", "purple", "yellow", "item10", "item1200", "item1300"} {
+		if got, want := rebuilt.Postings(term), expected.Postings(term); !slices.Equal(got, want) {
+			t.Errorf("Postings(%q) = %v, want %v", term, got, want)
+		}
+	}
+	if got, want := rebuilt.Stats(), expected.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	idx, expected := rebuildTestIndex(t), rebuildTestIndex(t)
+	idx.Rebuild()
+	if idx.Rebuilding() {
+		t.Error("Rebuilding() = true after Rebuild")
+	}
+	for _, query := range []string{"red", "yellow", "item4", "item3"} {
+		if got, want := hitIDs(t, idx, query), hitIDs(t, expected, query); !slices.Equal(got, want) {
+			t.Errorf("Search(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestSwapEndsRebuild(t *testing.T) {
+	idx := rebuildTestIndex(t)
+	state := idx.startRebuild()
+	idx.rebuildStep(state)
+
+	builder := NewIndexBuilder()
+	builder.Add("fresh red")
+	idx.Swap(builder.Build())
+	if idx.Rebuilding() {
+		t.Error("Rebuilding() = true after Swap")
+	}
+	if !idx.rebuildStep(state) {
+		t.Error("rebuild went on after Swap")
+	}
+	if got := hitIDs(t, idx, "red"); !slices.Equal(got, []int{0}) {
+		t.Errorf("Search(red) after Swap = %v, want [0]", got)
+	}
+}
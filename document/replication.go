@@ -0,0 +1,391 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// LogEntry is a replicated index operation. Entries of type
+// EventSegmentMerged carry the full index contents in Docs, with the IDs in
+// Deleted left empty, and replace whatever the follower held before
+type LogEntry struct {
+	Seq     uint64
+	Type    EventType
+	ID      int
+	Text    string
+	Docs    []string
+	Deleted []int
+	// indexSeq is the sequence number of the index event the entry
+	// records, or the last one a snapshot includes
+	indexSeq uint64
+}
+
+// ReplicationLog records the operations applied to a primary index so
+// followers can replay them in order. Compact drops the recorded entries
+// in favour of a new snapshot, so the log does not grow forever
+type ReplicationLog struct {
+	mu      sync.Mutex
+	idx     *Index
+	entries []LogEntry
+	// baseSeq is the sequence number of the last entry compacted away,
+	// entries[0] has sequence number baseSeq+1
+	baseSeq uint64
+	// indexSeq is the sequence number of the last index event the log
+	// holds, events up to it are already in a snapshot
+	indexSeq uint64
+	pending  []IndexEvent
+	ready    bool
+	cancel   func()
+}
+
+// NewReplicationLog starts recording the operations of idx. The log opens
+// with a snapshot of the current contents so followers can catch up from
+// an empty index
+func NewReplicationLog(idx *Index) *ReplicationLog {
+	log := &ReplicationLog{idx: idx, entries: make([]LogEntry, 0)}
+	log.cancel = idx.Subscribe(log.handle)
+
+	docs, deleted, snapshotSeq := idx.snapshot()
+	snapshot := LogEntry{Seq: 1, Type: EventSegmentMerged, ID: -1, Docs: docs, Deleted: deleted, indexSeq: snapshotSeq}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.entries = append(log.entries, snapshot)
+	log.indexSeq = snapshotSeq
+	for _, event := range log.pending {
+		log.appendLocked(event)
+	}
+	log.pending = nil
+	log.ready = true
+	return log
+}
+
+// Close stops recording operations
+func (log *ReplicationLog) Close() {
+	log.cancel()
+}
+
+// handle records an index event once the initial snapshot is in place
+func (log *ReplicationLog) handle(event IndexEvent) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if !log.ready {
+		log.pending = append(log.pending, event)
+		return
+	}
+	log.appendLocked(event)
+}
+
+// appendLocked adds an event newer than the snapshot to the log
+func (log *ReplicationLog) appendLocked(event IndexEvent) {
+	if event.Seq <= log.indexSeq {
+		return
+	}
+	log.entries = append(log.entries, LogEntry{
+		Seq:      log.latestSeqLocked() + 1,
+		Type:     event.Type,
+		ID:       event.ID,
+		Text:     event.Text,
+		Docs:     event.Docs,
+		Deleted:  event.Deleted,
+		indexSeq: event.Seq,
+	})
+	log.indexSeq = event.Seq
+}
+
+// Compact replaces the entries recorded so far with a snapshot of the
+// index, appended as a new entry and followed again by the entries the
+// snapshot does not include yet. Followers up to date replay the snapshot
+// like any other entry, followers behind it are told to resnapshot
+func (log *ReplicationLog) Compact() {
+	docs, deleted, snapshotSeq := log.idx.snapshot()
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	latest := log.latestSeqLocked()
+	entries := []LogEntry{{Seq: latest + 1, Type: EventSegmentMerged, ID: -1, Docs: docs, Deleted: deleted, indexSeq: snapshotSeq}}
+	for _, entry := range log.entries {
+		if entry.indexSeq > snapshotSeq {
+			entry.Seq = latest + uint64(len(entries)) + 1
+			entries = append(entries, entry)
+		}
+	}
+	log.entries = entries
+	log.baseSeq = latest
+	log.indexSeq = max(log.indexSeq, snapshotSeq)
+}
+
+// FirstSeq returns the sequence number of the oldest entry the log holds,
+// always a snapshot
+func (log *ReplicationLog) FirstSeq() uint64 {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return log.baseSeq + 1
+}
+
+// LatestSeq returns the sequence number of the newest entry
+func (log *ReplicationLog) LatestSeq() uint64 {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return log.latestSeqLocked()
+}
+
+// latestSeqLocked returns the sequence number of the newest entry. The
+// caller must hold log.mu
+func (log *ReplicationLog) latestSeqLocked() uint64 {
+	return log.baseSeq + uint64(len(log.entries))
+}
+
+// Since returns up to max entries with a sequence number after seq. When
+// the entries right after seq were compacted away it returns the entries
+// from the oldest one it holds, which is a snapshot
+func (log *ReplicationLog) Since(seq uint64, max int) []LogEntry {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return log.sinceLocked(seq, max)
+}
+
+// sinceLocked is Since with log.mu held
+func (log *ReplicationLog) sinceLocked(seq uint64, max int) []LogEntry {
+	if seq < log.baseSeq {
+		seq = log.baseSeq
+	}
+	if seq >= log.latestSeqLocked() {
+		return nil
+	}
+	entries := log.entries[seq-log.baseSeq:]
+	if max > 0 && len(entries) > max {
+		entries = entries[:max]
+	}
+	return append([]LogEntry(nil), entries...)
+}
+
+// fetch answers a follower that applied the entries up to afterSeq
+func (log *ReplicationLog) fetch(afterSeq uint64, max int) *FetchReply {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return &FetchReply{
+		Entries:    log.sinceLocked(afterSeq, max),
+		LatestSeq:  log.latestSeqLocked(),
+		Resnapshot: afterSeq < log.baseSeq,
+	}
+}
+
+// FetchArgs asks the primary for log entries after AfterSeq
+type FetchArgs struct {
+	AfterSeq uint64
+	Max      int
+}
+
+// FetchReply holds the entries returned to a follower
+type FetchReply struct {
+	Entries   []LogEntry
+	LatestSeq uint64
+	// Resnapshot reports that the entries after AfterSeq were compacted
+	// away. Entries then start with a snapshot replacing the follower's
+	// index instead of the entry after AfterSeq
+	Resnapshot bool
+}
+
+// replicationFetchMethod is the full gRPC name of the Fetch method
+const replicationFetchMethod = "/document.Replication/Fetch"
+
+// gobCodecName is the gRPC content subtype of replication messages, which
+// are gob encoded rather than protocol buffers
+const gobCodecName = "gob"
+
+// gobCodec encodes gRPC messages with encoding/gob. The wire format is
+// Go-only: only a Follower from this package can call the Replication
+// service. The codec is not registered with encoding.RegisterCodec, which
+// would change the codec lookup of every gRPC user in the process; the
+// server forces it with grpc.ForceServerCodec and followers select it per
+// call with grpc.ForceCodec
+type gobCodec struct{}
+
+// Marshal implements encoding.Codec
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements encoding.Codec
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Name implements encoding.Codec
+func (gobCodec) Name() string {
+	return gobCodecName
+}
+
+// replicationServiceDesc describes the Replication gRPC service
+var replicationServiceDesc = grpc.ServiceDesc{
+	ServiceName: "document.Replication",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Fetch",
+		Handler: func(srv any, ctx context.Context, decode func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			args := &FetchArgs{}
+			if err := decode(args); err != nil {
+				return nil, err
+			}
+			fetch := func(ctx context.Context, req any) (any, error) {
+				return srv.(*ReplicationService).Fetch(ctx, req.(*FetchArgs))
+			}
+			if interceptor == nil {
+				return fetch(ctx, args)
+			}
+			return interceptor(ctx, args, &grpc.UnaryServerInfo{Server: srv, FullMethod: replicationFetchMethod}, fetch)
+		},
+	}},
+}
+
+// ReplicationService exposes a replication log over gRPC
+type ReplicationService struct {
+	log *ReplicationLog
+}
+
+// Fetch returns the log entries a follower has not applied yet
+func (service *ReplicationService) Fetch(ctx context.Context, args *FetchArgs) (*FetchReply, error) {
+	return service.log.fetch(args.AfterSeq, args.Max), nil
+}
+
+// NewReplicationServer creates a gRPC server serving the replication log,
+// for callers stopping it gracefully. The server decodes every message
+// with the gob codec, so services using protocol buffers need a server
+// of their own
+func NewReplicationServer(log *ReplicationLog, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(opts, grpc.ForceServerCodec(gobCodec{}))...)
+	server.RegisterService(&replicationServiceDesc, &ReplicationService{log: log})
+	return server
+}
+
+// ServeReplication serves the replication log to followers connecting on
+// listener until the listener fails or is closed
+func ServeReplication(listener net.Listener, log *ReplicationLog) error {
+	return NewReplicationServer(log).Serve(listener)
+}
+
+// Follower keeps a local index in sync with a primary's replication log
+type Follower struct {
+	idx     *Index
+	conn    *grpc.ClientConn
+	lastSeq uint64
+	batch   int
+}
+
+// NewFollower creates a follower applying the operations of the primary
+// conn is connected to to idx
+func NewFollower(idx *Index, conn *grpc.ClientConn) *Follower {
+	return &Follower{idx: idx, conn: conn, batch: 1000}
+}
+
+// DialFollower connects to a primary serving replication at address,
+// without transport security unless opts configure it
+func DialFollower(address string, idx *Index, opts ...grpc.DialOption) (*Follower, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewFollower(idx, conn), nil
+}
+
+// LastSeq returns the sequence number of the last applied entry
+func (follower *Follower) LastSeq() uint64 {
+	return follower.lastSeq
+}
+
+// Close closes the connection to the primary
+func (follower *Follower) Close() error {
+	return follower.conn.Close()
+}
+
+// Sync fetches and applies every entry the follower is missing, returning
+// how many were applied
+func (follower *Follower) Sync() (int, error) {
+	return follower.sync(context.Background())
+}
+
+// sync is Sync with the fetches bound to ctx
+func (follower *Follower) sync(ctx context.Context) (int, error) {
+	applied := 0
+	for {
+		reply := FetchReply{}
+		args := FetchArgs{AfterSeq: follower.lastSeq, Max: follower.batch}
+		if err := follower.conn.Invoke(ctx, replicationFetchMethod, &args, &reply, grpc.ForceCodec(gobCodec{})); err != nil {
+			return applied, err
+		}
+
+		for i, entry := range reply.Entries {
+			resnapshot := i == 0 && reply.Resnapshot && entry.Type == EventSegmentMerged
+			if entry.Seq != follower.lastSeq+1 && !resnapshot {
+				return applied, fmt.Errorf("replication gap: expected seq %d, got %d", follower.lastSeq+1, entry.Seq)
+			}
+			if err := applyLogEntry(follower.idx, entry); err != nil {
+				return applied, err
+			}
+			follower.lastSeq = entry.Seq
+			applied++
+		}
+
+		if len(reply.Entries) == 0 || follower.lastSeq >= reply.LatestSeq {
+			return applied, nil
+		}
+	}
+}
+
+// Run syncs with the primary every interval until ctx is done
+func (follower *Follower) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := follower.sync(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyLogEntry replays a single log entry on an index, for followers and
+// write-ahead log recovery
+func applyLogEntry(idx *Index, entry LogEntry) error {
+	switch entry.Type {
+	case EventDocumentAdded:
+		if id := idx.Add(entry.Text); id != entry.ID {
+			return fmt.Errorf("replay diverged: added document %d, log has %d", id, entry.ID)
+		}
+		return nil
+	case EventDocumentUpdated:
+		return idx.Update(entry.ID, entry.Text)
+	case EventDocumentDeleted:
+		return idx.Delete(entry.ID)
+	case EventSegmentMerged:
+		built, err := indexFromSnapshot(entry.Docs, entry.Deleted)
+		if err != nil {
+			return err
+		}
+		idx.Swap(built)
+		return nil
+	}
+	return fmt.Errorf("unknown log entry type: %d", entry.Type)
+}
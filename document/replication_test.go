@@ -0,0 +1,247 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// startPrimary serves the replication log of idx on a local port for the
+// duration of the test
+func startPrimary(t *testing.T, idx *Index) (*ReplicationLog, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := NewReplicationLog(idx)
+	server := NewReplicationServer(log)
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		server.Stop()
+		log.Close()
+	})
+	return log, listener.Addr().String()
+}
+
+// assertSameDocuments fails unless the follower holds the primary's documents
+func assertSameDocuments(t *testing.T, primary, follower *Index, ids []int) {
+	t.Helper()
+	for _, id := range ids {
+		want, wantOK := primary.Get(id)
+		got, gotOK := follower.Get(id)
+		if got != want || gotOK != wantOK {
+			t.Errorf("follower document %d = %q, %v, want %q, %v", id, got, gotOK, want, wantOK)
+		}
+	}
+}
+
+func TestFollowerReplaysPrimaryOverGRPC(t *testing.T) {
+	primary := NewIndex()
+	ids := []int{primary.Add("python guide"), primary.Add("java guide")}
+	log, address := startPrimary(t, primary)
+
+	follower, err := DialFollower(address, NewIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+
+	applied, err := follower.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 1 {
+		t.Errorf("first Sync() applied %d entries, want the snapshot", applied)
+	}
+	assertSameDocuments(t, primary, follower.idx, ids)
+
+	ids = append(ids, primary.Add("rust in action"))
+	if err := primary.Update(ids[0], "python tutorial"); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Delete(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if applied, err = follower.Sync(); err != nil || applied != 3 {
+		t.Fatalf("Sync() = %d, %v, want 3 entries", applied, err)
+	}
+	assertSameDocuments(t, primary, follower.idx, ids)
+	if follower.LastSeq() != log.LatestSeq() {
+		t.Errorf("LastSeq() = %d, want %d", follower.LastSeq(), log.LatestSeq())
+	}
+
+	result, err := follower.idx.Search("python AND tutorial", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 1 {
+		t.Errorf("follower search Total = %d, want 1", result.Total)
+	}
+	if applied, err = follower.Sync(); err != nil || applied != 0 {
+		t.Errorf("Sync() when caught up = %d, %v, want 0", applied, err)
+	}
+	if codec := encoding.GetCodec(gobCodecName); codec != nil {
+		t.Errorf("GetCodec(%q) = %T, want the codec selected per call only", gobCodecName, codec)
+	}
+}
+
+func TestFollowerCatchesUpInBatches(t *testing.T) {
+	primary := NewIndex()
+	_, address := startPrimary(t, primary)
+	ids := []int{}
+	for i := 0; i < 25; i++ {
+		ids = append(ids, primary.Add("document number guide"))
+	}
+
+	follower, err := DialFollower(address, NewIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+	follower.batch = 4
+
+	if applied, err := follower.Sync(); err != nil || applied != 26 {
+		t.Fatalf("Sync() = %d, %v, want the snapshot and 25 additions", applied, err)
+	}
+	assertSameDocuments(t, primary, follower.idx, ids)
+}
+
+func TestReplicationLogSince(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("before the log")
+	log := NewReplicationLog(idx)
+	defer log.Close()
+	idx.Add("first")
+	idx.Add("second")
+
+	tests := []struct {
+		seq   uint64
+		max   int
+		first uint64
+		count int
+	}{
+		{0, 0, 1, 3},
+		{1, 0, 2, 2},
+		{0, 2, 1, 2},
+		{3, 0, 0, 0},
+		{10, 0, 0, 0},
+	}
+	for _, test := range tests {
+		entries := log.Since(test.seq, test.max)
+		if len(entries) != test.count || (test.count > 0 && entries[0].Seq != test.first) {
+			t.Errorf("Since(%d, %d) = %v, want %d entries from %d", test.seq, test.max, entries, test.count, test.first)
+		}
+	}
+	if snapshot := log.Since(0, 1)[0]; snapshot.Type != EventSegmentMerged || len(snapshot.Docs) != 1 {
+		t.Errorf("first entry = %+v, want a snapshot of one document", snapshot)
+	}
+}
+
+func TestReplicationLogCompact(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("first")
+	log := NewReplicationLog(idx)
+	defer log.Close()
+	idx.Add("second")
+	if err := idx.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+
+	log.Compact()
+	if first, latest := log.FirstSeq(), log.LatestSeq(); first != 4 || latest != 4 {
+		t.Fatalf("FirstSeq(), LatestSeq() = %d, %d, want 4 and 4", first, latest)
+	}
+	idx.Add("third")
+
+	tests := []struct {
+		seq   uint64
+		first uint64
+		count int
+	}{
+		{0, 4, 2},
+		{2, 4, 2},
+		{3, 4, 2},
+		{4, 5, 1},
+		{5, 0, 0},
+	}
+	for _, test := range tests {
+		entries := log.Since(test.seq, 0)
+		if len(entries) != test.count || (test.count > 0 && entries[0].Seq != test.first) {
+			t.Errorf("Since(%d) = %v, want %d entries from %d", test.seq, entries, test.count, test.first)
+		}
+	}
+	snapshot := log.Since(0, 1)[0]
+	if snapshot.Type != EventSegmentMerged || len(snapshot.Docs) != 2 || len(snapshot.Deleted) != 1 {
+		t.Errorf("first entry after Compact = %+v, want a snapshot of two documents, one deleted", snapshot)
+	}
+	if reply := log.fetch(2, 0); !reply.Resnapshot {
+		t.Error("fetch behind the retained entries does not ask to resnapshot")
+	}
+	if reply := log.fetch(3, 0); reply.Resnapshot {
+		t.Error("fetch of a follower up to date asks to resnapshot")
+	}
+}
+
+func TestFollowerCatchesUpAfterCompact(t *testing.T) {
+	primary := NewIndex()
+	ids := []int{primary.Add("python guide")}
+	log, address := startPrimary(t, primary)
+
+	current, err := DialFollower(address, NewIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer current.Close()
+	behind, err := DialFollower(address, NewIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer behind.Close()
+	if _, err := behind.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	ids = append(ids, primary.Add("java guide"), primary.Add("rust guide"))
+	if err := primary.Update(ids[0], "python tutorial"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := current.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	log.Compact()
+	ids = append(ids, primary.Add("go guide"))
+
+	for name, follower := range map[string]*Follower{"current": current, "behind": behind} {
+		if _, err := follower.Sync(); err != nil {
+			t.Fatalf("%s follower: %v", name, err)
+		}
+		assertSameDocuments(t, primary, follower.idx, ids)
+		if follower.LastSeq() != log.LatestSeq() {
+			t.Errorf("%s follower LastSeq() = %d, want %d", name, follower.LastSeq(), log.LatestSeq())
+		}
+	}
+}
+
+func TestFollowerRunStopsWithContext(t *testing.T) {
+	primary := NewIndex()
+	id := primary.Add("python guide")
+	_, address := startPrimary(t, primary)
+
+	follower, err := DialFollower(address, NewIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	if err := follower.Run(ctx, 10*time.Millisecond); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v, want the context cancellation", err)
+	}
+	assertSameDocuments(t, primary, follower.idx, []int{id})
+}
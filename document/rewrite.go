@@ -0,0 +1,195 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRewritePasses bounds how often the rules are applied before giving up
+// on reaching a fixpoint
+const maxRewritePasses = 100
+
+// queryNode is a boolean query as a tree, a term or an operator with its
+// operands
+type queryNode struct {
+	token    string
+	operands []*queryNode
+}
+
+// queryTree builds the tree of a validated boolean RPN expression
+func queryTree(rpn []string) *queryNode {
+	stack := []*queryNode{}
+	for _, token := range rpn {
+		node := &queryNode{token: token}
+		switch token {
+		case "AND", "OR":
+			node.operands = []*queryNode{stack[len(stack)-2], stack[len(stack)-1]}
+			stack = stack[:len(stack)-2]
+		case "NOT", "FILTER":
+			node.operands = []*queryNode{stack[len(stack)-1]}
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, node)
+	}
+	return stack[0]
+}
+
+// appendRPN appends the tree in RPN order
+func (node *queryNode) appendRPN(rpn []string) []string {
+	for _, operand := range node.operands {
+		rpn = operand.appendRPN(rpn)
+	}
+	return append(rpn, node.token)
+}
+
+// equal reports whether two trees have the same structure
+func (node *queryNode) equal(other *queryNode) bool {
+	if node.token != other.token || len(node.operands) != len(other.operands) {
+		return false
+	}
+	for i := range node.operands {
+		if !node.operands[i].equal(other.operands[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// QueryRewriteRule replaces the parts of a query matching Pattern with
+// Replacement. The terms of the pattern match any subquery, and a term used
+// twice must match equal subqueries both times
+type QueryRewriteRule struct {
+	pattern     *queryNode
+	replacement *queryNode
+}
+
+// NewQueryRewriteRule parses a rule from pattern and replacement queries,
+// such as "NOT NOT x" and "x"
+func NewQueryRewriteRule(pattern, replacement string) (QueryRewriteRule, error) {
+	patternQuery, err := CompileQuery(pattern)
+	if err != nil {
+		return QueryRewriteRule{}, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	replacementQuery, err := CompileQuery(replacement)
+	if err != nil {
+		return QueryRewriteRule{}, fmt.Errorf("invalid replacement %q: %w", replacement, err)
+	}
+
+	bound := map[string]bool{}
+	for _, term := range patternQuery.Terms() {
+		bound[term] = true
+	}
+	for _, term := range replacementQuery.Terms() {
+		if !bound[term] {
+			return QueryRewriteRule{}, fmt.Errorf("replacement term %s does not appear in pattern %q", term, pattern)
+		}
+	}
+
+	return QueryRewriteRule{pattern: queryTree(patternQuery.rpn), replacement: queryTree(replacementQuery.rpn)}, nil
+}
+
+// String returns the rule as "pattern => replacement" in RPN
+func (rule QueryRewriteRule) String() string {
+	return strings.Join(rule.pattern.appendRPN(nil), " ") + " => " + strings.Join(rule.replacement.appendRPN(nil), " ")
+}
+
+// apply rewrites node if it matches the rule pattern at its root
+func (rule QueryRewriteRule) apply(node *queryNode) (*queryNode, bool) {
+	bindings := map[string]*queryNode{}
+	if !matchQueryPattern(rule.pattern, node, bindings) {
+		return node, false
+	}
+	return substituteQuery(rule.replacement, bindings), true
+}
+
+// QueryRewriter applies a set of user registered rules to queries, the
+// boolean counterpart of the number calculator's Rewriter
+type QueryRewriter struct {
+	rules []QueryRewriteRule
+}
+
+// NewQueryRewriter creates a rewriter without rules
+func NewQueryRewriter() *QueryRewriter {
+	return &QueryRewriter{
+		rules: make([]QueryRewriteRule, 0),
+	}
+}
+
+// Add registers a rule given as pattern and replacement queries
+func (rw *QueryRewriter) Add(pattern, replacement string) error {
+	rule, err := NewQueryRewriteRule(pattern, replacement)
+	if err != nil {
+		return err
+	}
+	rw.rules = append(rw.rules, rule)
+	return nil
+}
+
+// Rules returns the registered rules in order
+func (rw *QueryRewriter) Rules() []QueryRewriteRule {
+	return append([]QueryRewriteRule(nil), rw.rules...)
+}
+
+// Rewrite applies the rules bottom-up until no rule matches anywhere, and
+// compiles the rewritten query
+func (rw *QueryRewriter) Rewrite(q *Query) (*Query, error) {
+	tree := queryTree(q.rpn)
+	for pass := 0; pass < maxRewritePasses; pass++ {
+		changed := false
+		tree = rw.rewriteNode(tree, &changed)
+		if !changed {
+			rewritten := &Query{rpn: tree.appendRPN(nil)}
+			return CompileQuery(rewritten.Canonical())
+		}
+	}
+	return nil, fmt.Errorf("rewrite did not reach a fixpoint after %d passes", maxRewritePasses)
+}
+
+// rewriteNode rewrites the operands of node, then node itself with the
+// first matching rule
+func (rw *QueryRewriter) rewriteNode(node *queryNode, changed *bool) *queryNode {
+	rebuilt := &queryNode{token: node.token}
+	for _, operand := range node.operands {
+		rebuilt.operands = append(rebuilt.operands, rw.rewriteNode(operand, changed))
+	}
+	for _, rule := range rw.rules {
+		if rewritten, ok := rule.apply(rebuilt); ok {
+			*changed = true
+			return rewritten
+		}
+	}
+	return rebuilt
+}
+
+// matchQueryPattern matches node against pattern, recording term bindings
+func matchQueryPattern(pattern, node *queryNode, bindings map[string]*queryNode) bool {
+	if !isOperator(pattern.token) {
+		if bound, ok := bindings[pattern.token]; ok {
+			return bound.equal(node)
+		}
+		bindings[pattern.token] = node
+		return true
+	}
+	if pattern.token != node.token {
+		return false
+	}
+	for i := range pattern.operands {
+		if !matchQueryPattern(pattern.operands[i], node.operands[i], bindings) {
+			return false
+		}
+	}
+	return true
+}
+
+// substituteQuery builds a copy of template with terms replaced by their
+// bindings
+func substituteQuery(template *queryNode, bindings map[string]*queryNode) *queryNode {
+	if !isOperator(template.token) {
+		return bindings[template.token]
+	}
+	node := &queryNode{token: template.token}
+	for _, operand := range template.operands {
+		node.operands = append(node.operands, substituteQuery(operand, bindings))
+	}
+	return node
+}
@@ -0,0 +1,122 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStackedPrefixOperators(t *testing.T) {
+	tests := []struct {
+		query     string
+		rpn       string
+		canonical string
+	}{
+		{"NOT NOT python", "python NOT NOT", "NOT NOT python"},
+		{"NOT NOT NOT python", "python NOT NOT NOT", "NOT NOT NOT python"},
+		{"NOT python AND java", "python NOT java AND", "NOT python AND java"},
+		{"java AND NOT NOT python", "java python NOT NOT AND", "java AND NOT NOT python"},
+		{"FILTER NOT python", "python NOT FILTER", "FILTER NOT python"},
+		{"NOT (python OR java)", "python java OR NOT", "NOT (python OR java)"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			compiled, err := CompileQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(compiled.rpn, " "); got != test.rpn {
+				t.Errorf("RPN = %q, want %q", got, test.rpn)
+			}
+			if got := compiled.Canonical(); got != test.canonical {
+				t.Errorf("Canonical() = %q, want %q", got, test.canonical)
+			}
+		})
+	}
+
+	compiled, err := CompileQuery("NOT NOT python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compiled.Match(map[string]int{"python": 1}) || compiled.Match(map[string]int{"java": 1}) {
+		t.Error("NOT NOT python does not match like python")
+	}
+}
+
+func TestQueryRewriter(t *testing.T) {
+	rw := NewQueryRewriter()
+	for _, rule := range [][2]string{
+		{"NOT NOT x", "x"},
+		{"x AND x", "x"},
+		{"x OR x", "x"},
+		{"NOT x AND NOT y", "NOT (x OR y)"},
+	} {
+		if err := rw.Add(rule[0], rule[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"NOT NOT python", "python"},
+		{"NOT NOT NOT NOT python", "python"},
+		{"guide AND NOT NOT (python OR java)", "guide AND (python OR java)"},
+		{"python AND python", "python"},
+		{"(python OR java) AND (python OR java)", "python OR java"},
+		{"NOT python AND NOT java", "NOT (python OR java)"},
+		{"python AND java", "python AND java"},
+		{"NOT NOT gui*", "gui*"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			compiled, err := CompileQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rewritten, err := rw.Rewrite(compiled)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rewritten.Canonical(); got != test.want {
+				t.Errorf("Rewrite(%q) = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+
+	if got := rw.Rules()[0].String(); got != "x NOT NOT => x" {
+		t.Errorf("String() = %q, want %q", got, "x NOT NOT => x")
+	}
+}
+
+func TestQueryRewriterErrors(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		replacement string
+		want        string
+	}{
+		{"NOT NOT x", "y", "replacement term y does not appear in pattern"},
+		{"NOT", "x", "invalid pattern"},
+		{"x", "x AND", "invalid replacement"},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" => "+test.replacement, func(t *testing.T) {
+			err := NewQueryRewriter().Add(test.pattern, test.replacement)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Add() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+
+	rw := NewQueryRewriter()
+	if err := rw.Add("x AND y", "y AND x"); err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := CompileQuery("python AND java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rw.Rewrite(compiled); err == nil || !strings.Contains(err.Error(), "fixpoint") {
+		t.Errorf("Rewrite() error = %v, want a fixpoint error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package document
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// ringReplicas is the number of points every node has on a hash ring,
+// spreading each node's share of keys evenly around it
+const ringReplicas = 64
+
+// ringPoint is a position on the ring owned by a node
+type ringPoint struct {
+	hash uint64
+	node string
+}
+
+// HashRing assigns keys to nodes by consistent hashing: every node owns
+// the arcs of the ring ending at its points, so adding or removing a node
+// only moves the keys on the arcs it gains or loses
+type HashRing struct {
+	points []ringPoint
+}
+
+// NewHashRing creates a ring over the given nodes
+func NewHashRing(nodes ...string) *HashRing {
+	ring := &HashRing{}
+	for _, node := range nodes {
+		ring.Add(node)
+	}
+	return ring
+}
+
+// ringHash hashes a key or point name onto the ring
+func ringHash(key string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	// FNV spreads short similar keys poorly, so finish with a mixer
+	sum := hash.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	return sum
+}
+
+// Add places a node on the ring, doing nothing if it is already there
+func (ring *HashRing) Add(node string) {
+	for _, point := range ring.points {
+		if point.node == node {
+			return
+		}
+	}
+	for i := 0; i < ringReplicas; i++ {
+		ring.points = append(ring.points, ringPoint{hash: ringHash(node + "#" + strconv.Itoa(i)), node: node})
+	}
+	sort.Slice(ring.points, func(i, j int) bool {
+		if ring.points[i].hash != ring.points[j].hash {
+			return ring.points[i].hash < ring.points[j].hash
+		}
+		return ring.points[i].node < ring.points[j].node
+	})
+}
+
+// Remove takes a node off the ring
+func (ring *HashRing) Remove(node string) {
+	kept := ring.points[:0]
+	for _, point := range ring.points {
+		if point.node != node {
+			kept = append(kept, point)
+		}
+	}
+	ring.points = kept
+}
+
+// Locate returns the node owning key, or an empty string for an empty ring
+func (ring *HashRing) Locate(key string) string {
+	if len(ring.points) == 0 {
+		return ""
+	}
+	hash := ringHash(key)
+	at := sort.Search(len(ring.points), func(i int) bool { return ring.points[i].hash >= hash })
+	if at == len(ring.points) {
+		at = 0
+	}
+	return ring.points[at].node
+}
@@ -0,0 +1,94 @@
+package document
+
+import (
+	"strconv"
+	"testing"
+)
+
+// ringKeys is the number of keys placed on rings under test
+const ringKeys = 4000
+
+// ringPlacement locates every test key on the ring
+func ringPlacement(ring *HashRing) []string {
+	nodes := make([]string, ringKeys)
+	for i := range nodes {
+		nodes[i] = ring.Locate(strconv.Itoa(i))
+	}
+	return nodes
+}
+
+func TestHashRingLocate(t *testing.T) {
+	if node := NewHashRing().Locate("key"); node != "" {
+		t.Errorf("empty ring located %q", node)
+	}
+	if node := NewHashRing("only").Locate("key"); node != "only" {
+		t.Errorf("single node ring located %q", node)
+	}
+
+	ring := NewHashRing("a", "b", "c")
+	twice := NewHashRing("c", "a", "b", "a")
+	twice.Add("b")
+	if len(twice.points) != 3*ringReplicas {
+		t.Errorf("ring has %d points, want %d", len(twice.points), 3*ringReplicas)
+	}
+	first, second := ringPlacement(ring), ringPlacement(twice)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("key %d located on %q and %q", i, first[i], second[i])
+		}
+	}
+}
+
+func TestHashRingBalance(t *testing.T) {
+	nodes := []string{"shard-0", "shard-1", "shard-2", "shard-3"}
+	counts := map[string]int{}
+	for _, node := range ringPlacement(NewHashRing(nodes...)) {
+		counts[node]++
+	}
+	fair := ringKeys / len(nodes)
+	for _, node := range nodes {
+		if counts[node] < fair/2 || counts[node] > fair*3/2 {
+			t.Errorf("%s owns %d keys, want about %d", node, counts[node], fair)
+		}
+	}
+}
+
+func TestHashRingMovement(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*HashRing)
+		moved  func(before, after string) bool
+	}{
+		{
+			name:   "add",
+			change: func(ring *HashRing) { ring.Add("d") },
+			moved:  func(before, after string) bool { return after == "d" },
+		},
+		{
+			name:   "remove",
+			change: func(ring *HashRing) { ring.Remove("b") },
+			moved:  func(before, after string) bool { return before == "b" },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ring := NewHashRing("a", "b", "c")
+			before := ringPlacement(ring)
+			test.change(ring)
+			after := ringPlacement(ring)
+			moves := 0
+			for i := range before {
+				if before[i] == after[i] {
+					continue
+				}
+				moves++
+				if !test.moved(before[i], after[i]) {
+					t.Fatalf("key %d moved from %q to %q", i, before[i], after[i])
+				}
+			}
+			if moves == 0 || moves > ringKeys/2 {
+				t.Errorf("%d of %d keys moved", moves, ringKeys)
+			}
+		})
+	}
+}
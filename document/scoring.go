@@ -0,0 +1,207 @@
+package document
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// TermStats describes a query term for scoring a single document
+type TermStats struct {
+	Term string
+	// Freq is how often the term occurs in the document
+	Freq int
+	// DocFreq is how many searched documents contain the term
+	DocFreq int
+	// Boost weights the term, 1 unless set in SearchOptions.Boosts
+	Boost float64
+}
+
+// ScoreInput is everything a Scorer may use to score a matching document.
+// Terms is reused between documents and must not be retained
+type ScoreInput struct {
+	DocID int
+	// Added is when the document was stored, zero if unknown
+	Added time.Time
+	// Length is the number of terms in the document, the field norm
+	Length int
+	// AvgLength is the mean number of terms of the searched documents
+	AvgLength float64
+	// DocCount is the number of searched documents
+	DocCount int
+	Terms    []TermStats
+}
+
+// Scorer computes the relevance of a document matching a query
+type Scorer interface {
+	Score(input ScoreInput) float64
+}
+
+// ScorerFunc adapts a function to the Scorer interface, e.g. to combine a
+// built-in scorer with recency decay or popularity priors
+type ScorerFunc func(input ScoreInput) float64
+
+// Score calls the function
+func (scorer ScorerFunc) Score(input ScoreInput) float64 {
+	return scorer(input)
+}
+
+// TFIDFScorer scores the sum over query terms of the boosted term frequency
+// times the smoothed inverse document frequency
+type TFIDFScorer struct{}
+
+// Score implements Scorer
+func (TFIDFScorer) Score(input ScoreInput) float64 {
+	score := 0.0
+	for _, term := range input.Terms {
+		idf := 1 + math.Log(float64(input.DocCount+1)/float64(term.DocFreq+1))
+		score += term.Boost * float64(term.Freq) * idf
+	}
+	return score
+}
+
+// BM25Scorer scores with Okapi BM25, saturating term frequency with K1 and
+// normalizing by document length with B
+type BM25Scorer struct {
+	K1 float64
+	B  float64
+}
+
+// NewBM25Scorer creates a BM25 scorer with the usual K1 1.2 and B 0.75
+func NewBM25Scorer() BM25Scorer {
+	return BM25Scorer{K1: 1.2, B: 0.75}
+}
+
+// Score implements Scorer
+func (scorer BM25Scorer) Score(input ScoreInput) float64 {
+	norm := 1.0
+	if input.AvgLength > 0 {
+		norm = 1 - scorer.B + scorer.B*float64(input.Length)/input.AvgLength
+	}
+
+	score := 0.0
+	for _, term := range input.Terms {
+		if term.Freq == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(input.DocCount-term.DocFreq)+0.5)/(float64(term.DocFreq)+0.5))
+		freq := float64(term.Freq)
+		score += term.Boost * idf * freq * (scorer.K1 + 1) / (freq + scorer.K1*norm)
+	}
+	return score
+}
+
+// scoring holds the collection statistics of a search with a custom scorer
+type scoring struct {
+	scorer    Scorer
+	terms     []string
+	boosts    map[string]float64
+	docFreq   map[string]int
+	docCount  int
+	avgLength float64
+	input     ScoreInput
+}
+
+// newScoring gathers the document frequencies and lengths of the searched
+// documents for the terms of a query
+func newScoring(scorer Scorer, compiled *Query, docs []*PreparedDoc, boosts map[string]float64) *scoring {
+	s := &scoring{scorer: scorer, boosts: boosts, docFreq: make(map[string]int)}
+	for _, term := range compiled.scoreTerms {
+		if _, seen := s.docFreq[term]; !seen {
+			s.docFreq[term] = 0
+			s.terms = append(s.terms, term)
+		}
+	}
+
+	totalLength := 0
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		s.docCount++
+		totalLength += doc.length
+		for _, term := range s.terms {
+			if doc.terms[term] > 0 {
+				s.docFreq[term]++
+			}
+		}
+	}
+	if s.docCount > 0 {
+		s.avgLength = float64(totalLength) / float64(s.docCount)
+	}
+	s.input.Terms = make([]TermStats, len(s.terms))
+	return s
+}
+
+// score runs the scorer on a matching document
+func (s *scoring) score(id int, doc *PreparedDoc, added time.Time) float64 {
+	s.input.DocID = id
+	s.input.Added = added
+	s.input.Length = doc.length
+	s.input.AvgLength = s.avgLength
+	s.input.DocCount = s.docCount
+	for i, term := range s.terms {
+		boost, ok := s.boosts[term]
+		if !ok {
+			boost = 1
+		}
+		s.input.Terms[i] = TermStats{Term: term, Freq: doc.terms[term], DocFreq: s.docFreq[term], Boost: boost}
+	}
+	return s.scorer.Score(s.input)
+}
+
+// collectionStats are the statistics of the documents of several indexes
+// for the terms of a query, summed so every index scores with the same ones
+type collectionStats struct {
+	docCount    int
+	totalLength int
+	docFreq     map[string]int
+}
+
+// addCollectionStats adds the number and lengths of the documents of the
+// index and how many contain each term of the query, as expanded by the
+// index's dictionary
+func (idx *Index) addCollectionStats(stats *collectionStats, compiled *Query) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	terms := []string{}
+	for _, term := range expandPatterns(compiled, idx.dict).scoreTerms {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+		if _, ok := stats.docFreq[term]; !ok {
+			stats.docFreq[term] = 0
+		}
+	}
+	for _, doc := range idx.docs {
+		if doc == nil {
+			continue
+		}
+		stats.docCount++
+		stats.totalLength += doc.length
+		for _, term := range terms {
+			if doc.terms[term] > 0 {
+				stats.docFreq[term]++
+			}
+		}
+	}
+}
+
+// scorer wraps a Scorer so it sees the summed statistics instead of those
+// of the index it runs on
+func (stats *collectionStats) scorer(scorer Scorer) Scorer {
+	avgLength := 0.0
+	if stats.docCount > 0 {
+		avgLength = float64(stats.totalLength) / float64(stats.docCount)
+	}
+	return ScorerFunc(func(input ScoreInput) float64 {
+		input.DocCount = stats.docCount
+		input.AvgLength = avgLength
+		for i, term := range input.Terms {
+			if docFreq, ok := stats.docFreq[term.Term]; ok {
+				input.Terms[i].DocFreq = docFreq
+			}
+		}
+		return scorer.Score(input)
+	})
+}
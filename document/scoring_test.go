@@ -0,0 +1,108 @@
+package document
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestBuiltinScorers(t *testing.T) {
+	input := ScoreInput{
+		Length:    10,
+		AvgLength: 5,
+		DocCount:  9,
+		Terms: []TermStats{
+			{Term: "python", Freq: 2, DocFreq: 2, Boost: 1},
+			{Term: "guide", Freq: 1, DocFreq: 4, Boost: 2},
+			{Term: "absent", Freq: 0, DocFreq: 1, Boost: 1},
+		},
+	}
+	tests := []struct {
+		name   string
+		scorer Scorer
+		want   float64
+	}{
+		{"tfidf", TFIDFScorer{}, 2*(1+math.Log(10.0/3)) + 2*(1+math.Log(10.0/5))},
+		{
+			"bm25", NewBM25Scorer(),
+			// norm = 0.25 + 0.75*10/5 = 1.75
+			math.Log(1+7.5/2.5)*2*2.2/(2+1.2*1.75) + 2*math.Log(1+5.5/4.5)*2.2/(1+1.2*1.75),
+		},
+		{"func", ScorerFunc(func(input ScoreInput) float64 { return float64(input.Length) }), 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.scorer.Score(input); math.Abs(got-test.want) > 1e-12 {
+				t.Errorf("Score() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestBM25FavorsShorterDocuments scores the same term frequency in documents
+// of different lengths
+func TestBM25FavorsShorterDocuments(t *testing.T) {
+	scorer := NewBM25Scorer()
+	terms := []TermStats{{Term: "python", Freq: 1, DocFreq: 1, Boost: 1}}
+	short := scorer.Score(ScoreInput{Length: 2, AvgLength: 6, DocCount: 3, Terms: terms})
+	long := scorer.Score(ScoreInput{Length: 12, AvgLength: 6, DocCount: 3, Terms: terms})
+	if short <= long {
+		t.Errorf("BM25 short document %v <= long document %v", short, long)
+	}
+}
+
+func TestSearchWithScorer(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("python python guide")
+	idx.Add("java guide for python developers and more words")
+	idx.Add("rust guide")
+
+	var inputs []ScoreInput
+	recording := ScorerFunc(func(input ScoreInput) float64 {
+		input.Terms = slices.Clone(input.Terms)
+		inputs = append(inputs, input)
+		return float64(input.Terms[0].Freq)
+	})
+	if _, err := idx.Search("python AND NOT rust", SearchOptions{Scorer: recording, Boosts: map[string]float64{"python": 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 2 {
+		t.Fatalf("scorer called %d times, want once per hit", len(inputs))
+	}
+	for _, input := range inputs {
+		if input.DocCount != 3 || input.AvgLength != 13.0/3 {
+			t.Errorf("DocCount %d AvgLength %v, want 3 and 13/3", input.DocCount, input.AvgLength)
+		}
+		// Negated terms are listed but never occur in a hit
+		want := []TermStats{{Term: "python", Freq: input.Terms[0].Freq, DocFreq: 2, Boost: 3}, {Term: "rust", Freq: 0, DocFreq: 1, Boost: 1}}
+		if !slices.Equal(input.Terms, want) {
+			t.Errorf("Terms = %+v, want %+v", input.Terms, want)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		opts   SearchOptions
+		wantID []int
+	}{
+		{"tfidf", "python OR rust", SearchOptions{Scorer: TFIDFScorer{}}, []int{0, 2, 1}},
+		{"bm25", "python OR rust", SearchOptions{Scorer: NewBM25Scorer()}, []int{2, 0, 1}},
+		{"boosted", "python OR rust", SearchOptions{Scorer: TFIDFScorer{}, Boosts: map[string]float64{"rust": 10}}, []int{2, 0, 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := idx.Search(test.query, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []int{}
+			for _, hit := range result.Hits {
+				got = append(got, hit.ID)
+			}
+			if !slices.Equal(got, test.wantID) {
+				t.Errorf("Search(%q) ranked %v, want %v", test.query, got, test.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package document
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newCountingIndex indexes 100 documents, every other one about python
+func newCountingIndex() *Index {
+	idx := NewIndex()
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			idx.Add(fmt.Sprintf("python guide %d", i))
+		} else {
+			idx.Add(fmt.Sprintf("java guide %d", i))
+		}
+	}
+	return idx
+}
+
+func TestSearchHitCounting(t *testing.T) {
+	idx := newCountingIndex()
+	tests := []struct {
+		name       string
+		query      string
+		opts       SearchOptions
+		total      int
+		hits       int
+		lowerBound bool
+		estimated  int
+	}{
+		{"exact", "python", SearchOptions{}, 50, 50, false, 50},
+		{"size", "python", SearchOptions{Size: 10}, 50, 10, false, 50},
+		{"count only", "python", SearchOptions{CountOnly: true}, 50, 0, false, 50},
+		{"count only with scorer", "python", SearchOptions{CountOnly: true, Scorer: NewBM25Scorer()}, 50, 0, false, 50},
+		{"capped count", "python", SearchOptions{TrackTotalHitsUpTo: 10}, 10, 50, true, 50},
+		{"capped count with size", "python", SearchOptions{TrackTotalHitsUpTo: 10, Size: 5}, 10, 5, true, 50},
+		{"early termination counting", "python", SearchOptions{TrackTotalHitsUpTo: 10, CountOnly: true}, 10, 0, true, 52},
+		{"threshold at total", "python", SearchOptions{TrackTotalHitsUpTo: 50}, 50, 50, false, 50},
+		{"threshold above total", "python", SearchOptions{TrackTotalHitsUpTo: 80}, 50, 50, false, 50},
+		{"every document", "guide", SearchOptions{TrackTotalHitsUpTo: 25}, 25, 100, true, 100},
+		{"no match", "rust", SearchOptions{TrackTotalHitsUpTo: 5}, 0, 0, false, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := idx.Search(test.query, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Total != test.total || len(result.Hits) != test.hits {
+				t.Errorf("Total = %d with %d hits, want %d with %d", result.Total, len(result.Hits), test.total, test.hits)
+			}
+			if result.TotalIsLowerBound != test.lowerBound || result.EstimatedTotal != test.estimated {
+				t.Errorf("TotalIsLowerBound = %v, EstimatedTotal = %d, want %v and %d",
+					result.TotalIsLowerBound, result.EstimatedTotal, test.lowerBound, test.estimated)
+			}
+		})
+	}
+}
+
+func TestCappedCountKeepsBestHits(t *testing.T) {
+	idx := NewIndex()
+	for i := 0; i < 20; i++ {
+		idx.Add("python")
+	}
+	idx.Add("python python python")
+
+	result, err := idx.Search("python", SearchOptions{Size: 1, TrackTotalHitsUpTo: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 5 || !result.TotalIsLowerBound {
+		t.Errorf("Total = %d, TotalIsLowerBound = %v, want 5 and true", result.Total, result.TotalIsLowerBound)
+	}
+	if len(result.Hits) != 1 || result.Hits[0].ID != 20 {
+		t.Errorf("Hits = %+v, want document 20, the best match past the cap", result.Hits)
+	}
+}
+
+func TestSearchRanksHitsByScore(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("python")
+	idx.Add("python python python")
+	idx.Add("python python")
+
+	result, err := idx.Search("python", SearchOptions{Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 2 || result.Hits[0].ID != 1 || result.Hits[1].ID != 2 {
+		t.Errorf("Hits = %+v, want documents 1 and 2 first", result.Hits)
+	}
+}
@@ -0,0 +1,291 @@
+package document
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ShardedIndex partitions documents across several independent indexes so
+// indexing and searching scale across CPUs. Documents are assigned to
+// shards by consistent hashing of their IDs, so adding or removing a shard
+// only moves the documents it gains or loses
+type ShardedIndex struct {
+	mu     sync.Mutex
+	nextID int
+
+	// topology guards the shards and the ring: operations on documents
+	// hold it for reading and resharding holds it for writing
+	topology  sync.RWMutex
+	shards    []*shard
+	ring      *HashRing
+	nextShard int
+}
+
+// shard is one partition of a sharded index, globalIDs maps its local
+// document IDs to global ones and localIDs the other way. Searches hold mu
+// for reading, so they run concurrently on a shard
+type shard struct {
+	mu        sync.RWMutex
+	name      string
+	index     *Index
+	globalIDs []int
+	localIDs  map[int]int
+}
+
+// NewShardedIndex creates a sharded index, using one shard per CPU when
+// shardCount is not positive
+func NewShardedIndex(shardCount int) *ShardedIndex {
+	if shardCount < 1 {
+		shardCount = runtime.NumCPU()
+	}
+
+	sharded := &ShardedIndex{ring: NewHashRing()}
+	for i := 0; i < shardCount; i++ {
+		sharded.newShardLocked()
+	}
+	return sharded
+}
+
+// newShardLocked creates an empty shard and places it on the ring, the
+// caller must hold the topology lock for writing
+func (sharded *ShardedIndex) newShardLocked() *shard {
+	target := &shard{
+		name:      "shard-" + strconv.Itoa(sharded.nextShard),
+		index:     NewIndex(),
+		globalIDs: make([]int, 0),
+		localIDs:  make(map[int]int),
+	}
+	sharded.nextShard++
+	sharded.shards = append(sharded.shards, target)
+	sharded.ring.Add(target.name)
+	return target
+}
+
+// ShardCount returns the number of shards
+func (sharded *ShardedIndex) ShardCount() int {
+	sharded.topology.RLock()
+	defer sharded.topology.RUnlock()
+	return len(sharded.shards)
+}
+
+// ShardNames returns the names of the shards, in the order of Stats
+func (sharded *ShardedIndex) ShardNames() []string {
+	sharded.topology.RLock()
+	defer sharded.topology.RUnlock()
+	names := make([]string, len(sharded.shards))
+	for i, target := range sharded.shards {
+		names[i] = target.name
+	}
+	return names
+}
+
+// ownerLocked returns the shard a global document ID hashes to, the caller
+// must hold the topology lock
+func (sharded *ShardedIndex) ownerLocked(id int) *shard {
+	name := sharded.ring.Locate(strconv.Itoa(id))
+	for _, target := range sharded.shards {
+		if target.name == name {
+			return target
+		}
+	}
+	return nil
+}
+
+// add stores a document in a shard under a global ID, the caller must hold
+// the shard's lock
+func (target *shard) add(id int, text string) {
+	local := target.index.Add(text)
+	for len(target.globalIDs) <= local {
+		target.globalIDs = append(target.globalIDs, -1)
+	}
+	target.globalIDs[local] = id
+	target.localIDs[id] = local
+}
+
+// Add stores a document in the shard its ID hashes to, returning its global ID
+func (sharded *ShardedIndex) Add(text string) int {
+	sharded.mu.Lock()
+	id := sharded.nextID
+	sharded.nextID++
+	sharded.mu.Unlock()
+
+	sharded.topology.RLock()
+	defer sharded.topology.RUnlock()
+	target := sharded.ownerLocked(id)
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	target.add(id, text)
+	return id
+}
+
+// Get returns the text of a stored document from the shard owning it
+func (sharded *ShardedIndex) Get(id int) (string, bool) {
+	sharded.topology.RLock()
+	defer sharded.topology.RUnlock()
+	target := sharded.ownerLocked(id)
+	target.mu.RLock()
+	defer target.mu.RUnlock()
+	local, ok := target.localIDs[id]
+	if !ok {
+		return "", false
+	}
+	return target.index.Get(local)
+}
+
+// Delete removes a document from the shard owning it
+func (sharded *ShardedIndex) Delete(id int) error {
+	sharded.topology.RLock()
+	defer sharded.topology.RUnlock()
+	target := sharded.ownerLocked(id)
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	local, ok := target.localIDs[id]
+	if !ok {
+		return fmt.Errorf("document %d does not exist", id)
+	}
+	if err := target.index.Delete(local); err != nil {
+		return err
+	}
+	delete(target.localIDs, id)
+	return nil
+}
+
+// AddShard adds an empty shard and moves to it the documents whose IDs now
+// hash to it, returning its name and how many documents moved
+func (sharded *ShardedIndex) AddShard() (string, int, error) {
+	sharded.topology.Lock()
+	defer sharded.topology.Unlock()
+	added := sharded.newShardLocked()
+	moved, err := sharded.rebalanceLocked(sharded.shards)
+	return added.name, moved, err
+}
+
+// RemoveShard takes a shard off the ring and moves its documents to the
+// shards their IDs now hash to, returning how many moved
+func (sharded *ShardedIndex) RemoveShard(name string) (int, error) {
+	sharded.topology.Lock()
+	defer sharded.topology.Unlock()
+	if len(sharded.shards) == 1 {
+		return 0, fmt.Errorf("cannot remove the last shard")
+	}
+	at := -1
+	for i, target := range sharded.shards {
+		if target.name == name {
+			at = i
+		}
+	}
+	if at < 0 {
+		return 0, fmt.Errorf("shard %s does not exist", name)
+	}
+
+	removed := sharded.shards[at]
+	sharded.shards = append(sharded.shards[:at], sharded.shards[at+1:]...)
+	sharded.ring.Remove(name)
+	return sharded.rebalanceLocked([]*shard{removed})
+}
+
+// rebalanceLocked moves the documents of sources not owned by them under the
+// current ring to their owners, the caller must hold the topology lock for
+// writing
+func (sharded *ShardedIndex) rebalanceLocked(sources []*shard) (int, error) {
+	moved := 0
+	for _, source := range sources {
+		ids := make([]int, 0, len(source.localIDs))
+		for id := range source.localIDs {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		for _, id := range ids {
+			owner := sharded.ownerLocked(id)
+			if owner == source {
+				continue
+			}
+			local := source.localIDs[id]
+			text, _ := source.index.Get(local)
+			if err := source.index.Delete(local); err != nil {
+				return moved, err
+			}
+			delete(source.localIDs, id)
+			owner.add(id, text)
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// Stats returns the statistics of every shard
+func (sharded *ShardedIndex) Stats() []IndexStats {
+	sharded.topology.RLock()
+	defer sharded.topology.RUnlock()
+	stats := make([]IndexStats, len(sharded.shards))
+	for i, target := range sharded.shards {
+		stats[i] = target.index.Stats()
+	}
+	return stats
+}
+
+// Search runs the query on every shard in parallel and merges the results.
+// A Scorer sees the document counts, lengths and frequencies of every
+// shard, so a document scores the same whichever shard holds it
+func (sharded *ShardedIndex) Search(query string, opts SearchOptions) (SearchResult, error) {
+	sharded.topology.RLock()
+	defer sharded.topology.RUnlock()
+	results := make([]SearchResult, len(sharded.shards))
+	errs := make([]error, len(sharded.shards))
+
+	if opts.Scorer != nil && !opts.CountOnly {
+		compiled, err := CompileQuery(query)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		stats := &collectionStats{docFreq: make(map[string]int)}
+		for _, target := range sharded.shards {
+			target.index.addCollectionStats(stats, compiled)
+		}
+		opts.Scorer = stats.scorer(opts.Scorer)
+	}
+
+	var wg sync.WaitGroup
+	for i, target := range sharded.shards {
+		wg.Add(1)
+		go func(i int, target *shard) {
+			defer wg.Done()
+			target.mu.RLock()
+			defer target.mu.RUnlock()
+
+			result, err := target.index.Search(query, opts)
+			for j := range result.Hits {
+				result.Hits[j].ID = target.globalIDs[result.Hits[j].ID]
+			}
+			results[i], errs[i] = result, err
+		}(i, target)
+	}
+	wg.Wait()
+
+	merged := SearchResult{}
+	for i, result := range results {
+		if errs[i] != nil {
+			return SearchResult{}, errs[i]
+		}
+		merged.Hits = append(merged.Hits, result.Hits...)
+		merged.Total += result.Total
+		merged.EstimatedTotal += result.EstimatedTotal
+		merged.TotalIsLowerBound = merged.TotalIsLowerBound || result.TotalIsLowerBound
+	}
+
+	sort.Slice(merged.Hits, func(i, j int) bool {
+		if merged.Hits[i].Score != merged.Hits[j].Score {
+			return merged.Hits[i].Score > merged.Hits[j].Score
+		}
+		return merged.Hits[i].ID < merged.Hits[j].ID
+	})
+	if opts.Size > 0 && len(merged.Hits) > opts.Size {
+		merged.Hits = merged.Hits[:opts.Size]
+	}
+
+	return merged, nil
+}
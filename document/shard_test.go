@@ -0,0 +1,136 @@
+package document
+
+import (
+	"sync"
+	"testing"
+)
+
+var shardTestDocuments = []string{
+	"python guide for beginners",
+	"java guide",
+	"python python python tricks",
+	"rust in action",
+	"advanced python and java interop guide",
+	"cooking with python",
+	"a guide to guides",
+	"java concurrency in practice",
+}
+
+func TestShardedIndexScoresMatchSingleIndex(t *testing.T) {
+	single := NewIndex()
+	sharded := NewShardedIndex(3)
+	for _, text := range shardTestDocuments {
+		single.Add(text)
+		sharded.Add(text)
+	}
+
+	scorers := map[string]Scorer{"tfidf": TFIDFScorer{}, "bm25": NewBM25Scorer()}
+	for name, scorer := range scorers {
+		for _, query := range []string{"python", "python OR java", "guide AND (python OR java)", "gui*"} {
+			t.Run(name+"/"+query, func(t *testing.T) {
+				want, err := single.Search(query, SearchOptions{Scorer: scorer})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := sharded.Search(query, SearchOptions{Scorer: scorer})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Total != want.Total {
+					t.Fatalf("Total = %d, want %d", got.Total, want.Total)
+				}
+				scores := map[string]float64{}
+				for _, hit := range want.Hits {
+					scores[hit.Text] = hit.Score
+				}
+				for _, hit := range got.Hits {
+					if hit.Score != scores[hit.Text] {
+						t.Errorf("score of %q = %g, want %g", hit.Text, hit.Score, scores[hit.Text])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestShardedIndexAddGetDelete(t *testing.T) {
+	sharded := NewShardedIndex(4)
+	ids := make([]int, len(shardTestDocuments))
+	for i, text := range shardTestDocuments {
+		ids[i] = sharded.Add(text)
+	}
+	for i, id := range ids {
+		if text, ok := sharded.Get(id); !ok || text != shardTestDocuments[i] {
+			t.Errorf("Get(%d) = %q, %v, want %q", id, text, ok, shardTestDocuments[i])
+		}
+	}
+
+	if err := sharded.Delete(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sharded.Get(ids[0]); ok {
+		t.Errorf("Get(%d) found a deleted document", ids[0])
+	}
+	if err := sharded.Delete(ids[0]); err == nil {
+		t.Errorf("Delete(%d) twice succeeded", ids[0])
+	}
+
+	total := 0
+	for _, stats := range sharded.Stats() {
+		total += stats.Docs
+	}
+	if total != len(shardTestDocuments)-1 {
+		t.Errorf("shards hold %d documents, want %d", total, len(shardTestDocuments)-1)
+	}
+}
+
+func TestShardedIndexResharding(t *testing.T) {
+	sharded := NewShardedIndex(2)
+	for _, text := range shardTestDocuments {
+		sharded.Add(text)
+	}
+	before, err := sharded.Search("python OR java OR guide", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, _, err := sharded.AddShard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sharded.RemoveShard(name); err != nil {
+		t.Fatal(err)
+	}
+	after, err := sharded.Search("python OR java OR guide", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Total != before.Total {
+		t.Errorf("Total after resharding = %d, want %d", after.Total, before.Total)
+	}
+	if _, err := sharded.RemoveShard("missing"); err == nil {
+		t.Error("RemoveShard of an unknown shard succeeded")
+	}
+}
+
+func TestShardedIndexConcurrentSearches(t *testing.T) {
+	sharded := NewShardedIndex(2)
+	for _, text := range shardTestDocuments {
+		sharded.Add(text)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := sharded.Search("python", SearchOptions{Scorer: NewBM25Scorer()}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
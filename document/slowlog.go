@@ -0,0 +1,106 @@
+package document
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SlowQueryLog configures the logging of searches that take at least
+// Threshold, a nil Log disables it
+type SlowQueryLog struct {
+	Threshold time.Duration
+	Log       func(SlowQuery)
+}
+
+// SearchPhases breaks down where a search spent its time: expanding pattern
+// terms against the dictionary, evaluating filters over the postings, and
+// matching, scoring and sorting the candidate documents
+type SearchPhases struct {
+	Expand time.Duration
+	Filter time.Duration
+	Match  time.Duration
+}
+
+// SlowQuery is the record of a search slower than the log's threshold
+type SlowQuery struct {
+	// TraceID correlates the record with the trace of the request, it is
+	// the TraceID of the search options or a generated one
+	TraceID  string
+	Time     time.Time
+	Query    string
+	Plan     string
+	Duration time.Duration
+	Phases   SearchPhases
+	Total    int
+	Hits     int
+	// Err is the error that aborted the search, if any
+	Err error
+}
+
+// searchTrace collects the plan and phase timings of a running search
+type searchTrace struct {
+	plan   string
+	phases SearchPhases
+}
+
+// SetSlowQueryLog sets how searches slower than a threshold are logged
+func (idx *Index) SetSlowQueryLog(log SlowQueryLog) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.slowLog = log
+}
+
+// logSlowQuery hands a finished search to the slow query log when it took
+// at least the threshold. It runs without the index lock held
+func logSlowQuery(log SlowQueryLog, compiled *Query, opts SearchOptions, start time.Time, trace *searchTrace, result SearchResult, err error) {
+	elapsed := time.Since(start)
+	if log.Log == nil || elapsed < log.Threshold {
+		return
+	}
+	traceID := opts.TraceID
+	if traceID == "" {
+		traceID = newTraceID()
+	}
+	log.Log(SlowQuery{
+		TraceID:  traceID,
+		Time:     start,
+		Query:    compiled.String(),
+		Plan:     trace.plan,
+		Duration: elapsed,
+		Phases:   trace.phases,
+		Total:    result.Total,
+		Hits:     len(result.Hits),
+		Err:      err,
+	})
+}
+
+// newTraceID returns a random trace ID in the W3C trace context format
+// OpenTelemetry uses, 32 lowercase hex digits
+func newTraceID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// describePlan renders how a compiled query runs: the filter clauses
+// evaluated over the postings, then the query matched against each
+// candidate document
+func describePlan(compiled *Query) string {
+	steps := []string{}
+	for _, filter := range compiled.filters {
+		steps = append(steps, fmt.Sprintf("filter %v", filter))
+	}
+	match := compiled
+	if len(compiled.filters) > 0 {
+		match = compiled.residual
+	}
+	if match == nil {
+		steps = append(steps, "match all")
+	} else {
+		steps = append(steps, fmt.Sprintf("match %v", match.rpn))
+	}
+	return strings.Join(steps, ", ")
+}
@@ -0,0 +1,92 @@
+package document
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range builderTestDocuments {
+		idx.Add(text)
+	}
+	tests := []struct {
+		name      string
+		query     string
+		threshold time.Duration
+		opts      SearchOptions
+		logged    bool
+		plan      string
+		total     int
+		hits      int
+		err       bool
+	}{
+		{"every search", "python", 0, SearchOptions{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}, true, "match [python]", 3, 3, false},
+		{"filtered", "python AND FILTER java", 0, SearchOptions{Size: 1}, true, "filter [java], match [python]", 1, 1, false},
+		{"filter only", "FILTER guide", 0, SearchOptions{CountOnly: true}, true, "filter [guide], match all", 3, 0, false},
+		{"failed", "python", 0, SearchOptions{MemoryBudget: 1}, true, "match [python]", 0, 0, true},
+		{"faster than the threshold", "python", time.Hour, SearchOptions{}, false, "", 0, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var records []SlowQuery
+			idx.SetSlowQueryLog(SlowQueryLog{Threshold: test.threshold, Log: func(record SlowQuery) {
+				records = append(records, record)
+			}})
+			before := time.Now()
+			_, err := idx.Search(test.query, test.opts)
+
+			if !test.logged {
+				if len(records) != 0 {
+					t.Errorf("logged %+v, want nothing", records)
+				}
+				return
+			}
+			if len(records) != 1 {
+				t.Fatalf("logged %d records, want 1", len(records))
+			}
+			record := records[0]
+			if test.opts.TraceID != "" && record.TraceID != test.opts.TraceID {
+				t.Errorf("TraceID = %q, want %q", record.TraceID, test.opts.TraceID)
+			}
+			if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(record.TraceID) {
+				t.Errorf("TraceID = %q, want 32 hex digits", record.TraceID)
+			}
+			if record.Plan != test.plan || record.Total != test.total || record.Hits != test.hits {
+				t.Errorf("record = plan %q, %d total, %d hits, want %q, %d, %d", record.Plan, record.Total, record.Hits, test.plan, test.total, test.hits)
+			}
+			if record.Query == "" || record.Time.Before(before) || record.Duration < 0 {
+				t.Errorf("record = %+v", record)
+			}
+			if phases := record.Phases.Expand + record.Phases.Filter + record.Phases.Match; phases > record.Duration {
+				t.Errorf("phases take %v, longer than the search's %v", phases, record.Duration)
+			}
+			if (record.Err != nil) != test.err || !errors.Is(record.Err, err) {
+				t.Errorf("Err = %v, want the search's error %v", record.Err, err)
+			}
+		})
+	}
+}
+
+// TestSlowQueryLogDisabled checks that a log without a Log function is off
+func TestSlowQueryLogDisabled(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("python")
+	idx.SetSlowQueryLog(SlowQueryLog{})
+	if _, err := idx.Search("python", SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewTraceIDIsRandom(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := newTraceID()
+		if seen[id] {
+			t.Fatalf("newTraceID() repeated %s", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,211 @@
+package document
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Storage holds named blobs for persisted indexes, so they can live on a
+// local disk, in memory or in an object store. Names are slash separated
+// paths. Get fails with an error matching fs.ErrNotExist for missing names,
+// and Delete ignores them
+type Storage interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error)
+	Delete(name string) error
+	// List returns the names starting with prefix in sorted order
+	List(prefix string) ([]string, error)
+}
+
+// BatchStorage is a Storage that can apply several writes at once
+// atomically, so a save either lands completely or not at all
+type BatchStorage interface {
+	Storage
+	Batch(puts map[string][]byte, deletes []string) error
+}
+
+// checkStorageName rejects names that could escape a storage's root
+func checkStorageName(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("invalid storage name %q", name)
+	}
+	return nil
+}
+
+// MemoryStorage keeps blobs in memory, for tests and throwaway indexes
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+// NewMemoryStorage creates an empty in-memory storage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{blobs: make(map[string][]byte)}
+}
+
+// Put stores a copy of data under name
+func (storage *MemoryStorage) Put(name string, data []byte) error {
+	if err := checkStorageName(name); err != nil {
+		return err
+	}
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	storage.blobs[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// Batch stores copies of puts and removes deletes in one step
+func (storage *MemoryStorage) Batch(puts map[string][]byte, deletes []string) error {
+	for name := range puts {
+		if err := checkStorageName(name); err != nil {
+			return err
+		}
+	}
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	for name, data := range puts {
+		storage.blobs[name] = append([]byte(nil), data...)
+	}
+	for _, name := range deletes {
+		delete(storage.blobs, name)
+	}
+	return nil
+}
+
+// Get returns a copy of the blob stored under name
+func (storage *MemoryStorage) Get(name string) ([]byte, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	data, ok := storage.blobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Delete removes the blob stored under name
+func (storage *MemoryStorage) Delete(name string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	delete(storage.blobs, name)
+	return nil
+}
+
+// List returns the names starting with prefix in sorted order
+func (storage *MemoryStorage) List(prefix string) ([]string, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	names := []string{}
+	for name := range storage.blobs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// FileStorage keeps blobs as files below a directory, names mapping to
+// relative paths. Writes go to a temporary file renamed into place, so a
+// crash never leaves a partially written blob behind
+type FileStorage struct {
+	dir string
+}
+
+// NewFileStorage creates a storage rooted at dir, creating it if needed
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+// path returns the file holding the blob stored under name
+func (storage *FileStorage) path(name string) (string, error) {
+	if err := checkStorageName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(storage.dir, filepath.FromSlash(name)), nil
+}
+
+// Put durably writes data under name
+func (storage *FileStorage) Put(name string, data []byte) error {
+	path, err := storage.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Get reads the blob stored under name
+func (storage *FileStorage) Get(name string) ([]byte, error) {
+	path, err := storage.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Delete removes the blob stored under name
+func (storage *FileStorage) Delete(name string) error {
+	path, err := storage.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the names starting with prefix in sorted order, skipping
+// the temporary files of writes in progress
+func (storage *FileStorage) List(prefix string) ([]string, error) {
+	names := []string{}
+	err := filepath.WalkDir(storage.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+		relative, err := filepath.Rel(storage.dir, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(relative); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,316 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// memoryObjectClient is an object store holding its buckets in memory
+type memoryObjectClient struct {
+	objects map[string][]byte
+}
+
+func (client *memoryObjectClient) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	client.objects[bucket+"/"+key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (client *memoryObjectClient) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	data, ok := client.objects[bucket+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (client *memoryObjectClient) DeleteObject(ctx context.Context, bucket, key string) error {
+	delete(client.objects, bucket+"/"+key)
+	return nil
+}
+
+func (client *memoryObjectClient) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	keys := []string{}
+	for key := range client.objects {
+		if name, ok := strings.CutPrefix(key, bucket+"/"); ok && strings.HasPrefix(name, prefix) {
+			keys = append(keys, name)
+		}
+	}
+	return keys, nil
+}
+
+// countingStorage is a Storage, not a BatchStorage, counting its writes
+type countingStorage struct {
+	Storage
+	puts, deletes []string
+}
+
+func (storage *countingStorage) Put(name string, data []byte) error {
+	storage.puts = append(storage.puts, name)
+	return storage.Storage.Put(name, data)
+}
+
+func (storage *countingStorage) Delete(name string) error {
+	storage.deletes = append(storage.deletes, name)
+	return storage.Storage.Delete(name)
+}
+
+// testStorages returns a fresh storage of every kind
+func testStorages(t *testing.T) map[string]Storage {
+	t.Helper()
+	file, err := NewFileStorage(t.TempDir() + "/index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &memoryObjectClient{objects: map[string][]byte{"other/indexes/a/x": []byte("other bucket")}}
+	return map[string]Storage{
+		"memory":       NewMemoryStorage(),
+		"file":         file,
+		"object store": &ObjectStorage{Client: client, Bucket: "bucket", Prefix: "indexes/a/"},
+		"unbatched":    &countingStorage{Storage: NewMemoryStorage()},
+	}
+}
+
+func TestStorageBackends(t *testing.T) {
+	for kind, storage := range testStorages(t) {
+		t.Run(kind, func(t *testing.T) {
+			checkStorage(t, storage)
+		})
+	}
+}
+
+// checkStorage runs a storage through the behavior every backend shares
+func checkStorage(t *testing.T, storage Storage) {
+	t.Helper()
+	for name, data := range map[string]string{"a.json": "1", "segments/b": "2", "segments/c": "3", "segments/cd": "4"} {
+		if err := storage.Put(name, []byte(data)); err != nil {
+			t.Fatalf("Put(%q): %v", name, err)
+		}
+	}
+	if err := storage.Put("a.json", []byte("replaced")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := storage.Get("a.json"); err != nil || string(data) != "replaced" {
+		t.Errorf("Get(a.json) = %q, %v, want replaced", data, err)
+	}
+	if _, err := storage.Get("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get(missing) error = %v, want fs.ErrNotExist", err)
+	}
+
+	for _, test := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"a.json", "segments/b", "segments/c", "segments/cd"}},
+		{"segments/", []string{"segments/b", "segments/c", "segments/cd"}},
+		{"segments/c", []string{"segments/c", "segments/cd"}},
+		{"x", []string{}},
+	} {
+		if got, err := storage.List(test.prefix); err != nil || !slices.Equal(got, test.want) {
+			t.Errorf("List(%q) = %v, %v, want %v", test.prefix, got, err, test.want)
+		}
+	}
+
+	if err := storage.Delete("segments/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Delete("segments/b"); err != nil {
+		t.Errorf("Delete() of a missing name = %v, want nil", err)
+	}
+	if _, err := storage.Get("segments/b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get() after Delete error = %v, want fs.ErrNotExist", err)
+	}
+
+	for _, name := range []string{"", ".", "../escape", "/absolute", "a//b", "a/./b"} {
+		if err := storage.Put(name, nil); err == nil || err.Error() != fmt.Sprintf("invalid storage name %q", name) {
+			t.Errorf("Put(%q) error = %v, want an invalid name", name, err)
+		}
+	}
+}
+
+func TestMemoryStorageCopiesBlobs(t *testing.T) {
+	storage := NewMemoryStorage()
+	data := []byte("original")
+	if err := storage.Put("a", data); err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 'X'
+	got, _ := storage.Get("a")
+	got[1] = 'X'
+	if again, _ := storage.Get("a"); string(again) != "original" {
+		t.Errorf("Get() = %q, want the stored blob unchanged", again)
+	}
+}
+
+// storageTestIndex builds an index spanning several segments
+func storageTestIndex(t *testing.T) (*Index, *QueryStore) {
+	t.Helper()
+	idx := NewIndex()
+	for i := 0; i < 2*segmentDocs+10; i++ {
+		idx.Add(fmt.Sprintf("document %d parity%d", i, i%2))
+	}
+	for _, id := range []int{3, segmentDocs + 1} {
+		if err := idx.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := NewQueryStore()
+	if err := store.Save("odd", "parity1"); err != nil {
+		t.Fatal(err)
+	}
+	return idx, store
+}
+
+func TestSaveLoadStorage(t *testing.T) {
+	for kind, storage := range testStorages(t) {
+		t.Run(kind, func(t *testing.T) {
+			idx, store := storageTestIndex(t)
+			if err := SaveToStorage(storage, idx, store); err != nil {
+				t.Fatal(err)
+			}
+			segments, _ := storage.List(segmentPrefix)
+			if len(segments) != 3 {
+				t.Errorf("saved %d segments, want 3", len(segments))
+			}
+
+			loaded, loadedStore, err := LoadFromStorage(storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Len() != idx.Len() {
+				t.Errorf("Len() = %d, want %d", loaded.Len(), idx.Len())
+			}
+			for _, query := range []string{"parity1", "document AND NOT parity0", "2057"} {
+				if got, want := hitIDs(t, loaded, query), hitIDs(t, idx, query); !slices.Equal(got, want) {
+					t.Errorf("Search(%q) = %d hits, want %d", query, len(got), len(want))
+				}
+			}
+			if got := loadedStore.Sources(); !maps.Equal(got, store.Sources()) {
+				t.Errorf("queries = %v, want %v", got, store.Sources())
+			}
+		})
+	}
+}
+
+func TestSaveToStorageIsIncremental(t *testing.T) {
+	storage := &countingStorage{Storage: NewMemoryStorage()}
+	idx, store := storageTestIndex(t)
+	if err := SaveToStorage(storage, idx, store); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := storage.List(segmentPrefix)
+
+	// Only the last segment changes, so only it is rewritten and the one it
+	// replaces deleted, after the manifest
+	storage.puts, storage.deletes = nil, nil
+	idx.Add("one more document")
+	if err := SaveToStorage(storage, idx, store); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := storage.List(segmentPrefix)
+	if len(storage.puts) != 2 || storage.puts[1] != storageManifestName || len(storage.deletes) != 1 {
+		t.Fatalf("second save put %v and deleted %v, want one segment and the manifest, and one delete", storage.puts, storage.deletes)
+	}
+	if slices.Contains(after, storage.deletes[0]) || !slices.Contains(before, storage.deletes[0]) || !slices.Contains(after, storage.puts[0]) {
+		t.Errorf("segments went from %v to %v putting %v and deleting %v", before, after, storage.puts, storage.deletes)
+	}
+
+	// Saving unchanged state writes only the manifest
+	storage.puts, storage.deletes = nil, nil
+	if err := SaveToStorage(storage, idx, store); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(storage.puts, []string{storageManifestName}) || len(storage.deletes) != 0 {
+		t.Errorf("unchanged save put %v and deleted %v, want only the manifest", storage.puts, storage.deletes)
+	}
+}
+
+// batchOnlyStorage is a BatchStorage failing any write outside a batch
+type batchOnlyStorage struct {
+	*MemoryStorage
+	batches int
+}
+
+func (storage *batchOnlyStorage) Put(name string, data []byte) error {
+	return fmt.Errorf("unexpected Put(%s)", name)
+}
+
+func (storage *batchOnlyStorage) Delete(name string) error {
+	return fmt.Errorf("unexpected Delete(%s)", name)
+}
+
+func (storage *batchOnlyStorage) Batch(puts map[string][]byte, deletes []string) error {
+	storage.batches++
+	return storage.MemoryStorage.Batch(puts, deletes)
+}
+
+func TestSaveToBatchStorageIsOneBatch(t *testing.T) {
+	storage := &batchOnlyStorage{MemoryStorage: NewMemoryStorage()}
+	idx, store := storageTestIndex(t)
+	for save := 1; save <= 2; save++ {
+		if err := SaveToStorage(storage, idx, store); err != nil {
+			t.Fatal(err)
+		}
+		if storage.batches != save {
+			t.Errorf("save %d ran %d batches in all, want %d", save, storage.batches, save)
+		}
+		idx.Add("one more document")
+	}
+	// The batch of the second save also dropped the replaced segment
+	if segments, _ := storage.List(segmentPrefix); len(segments) != 3 {
+		t.Errorf("storage holds %d segments, want 3", len(segments))
+	}
+	loaded, _, err := LoadFromStorage(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != idx.Len()-1 {
+		t.Errorf("Len() = %d, want %d", loaded.Len(), idx.Len()-1)
+	}
+}
+
+func TestLoadFromStorageErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(storage *MemoryStorage, segment string)
+		want   string
+	}{
+		{"missing manifest", func(storage *MemoryStorage, segment string) {
+			storage.Delete(storageManifestName)
+		}, "storage manifest missing: manifest.json: file does not exist"},
+		{"invalid manifest", func(storage *MemoryStorage, segment string) {
+			storage.Put(storageManifestName, []byte("{"))
+		}, "invalid storage manifest: unexpected end of JSON input"},
+		{"unsupported version", func(storage *MemoryStorage, segment string) {
+			storage.Put(storageManifestName, []byte(`{"version": 9}`))
+		}, "unsupported storage version 9"},
+		{"missing segment", func(storage *MemoryStorage, segment string) {
+			storage.Delete(segment)
+		}, "storage is missing segment " + "SEGMENT: SEGMENT: file does not exist"},
+		{"corrupted segment", func(storage *MemoryStorage, segment string) {
+			storage.Put(segment, []byte(`["tampered"]`))
+		}, "checksum mismatch for SEGMENT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storage := NewMemoryStorage()
+			idx := NewIndex()
+			idx.Add("python")
+			if err := SaveToStorage(storage, idx, NewQueryStore()); err != nil {
+				t.Fatal(err)
+			}
+			segments, _ := storage.List(segmentPrefix)
+			test.damage(storage, segments[0])
+
+			_, _, err := LoadFromStorage(storage)
+			if want := strings.ReplaceAll(test.want, "SEGMENT", segments[0]); err == nil || err.Error() != want {
+				t.Errorf("LoadFromStorage() error = %v, want %q", err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// QueryStore holds named, compiled boolean queries along with the version
+// history of every name
+type QueryStore struct {
+	mu      sync.RWMutex
+	queries map[string]*Query
+	history map[string][]QueryVersion
+
+	// hook reviews creates and updates, drafts holds the ones it has not
+	// approved yet
+	hook   ApprovalHook
+	drafts map[string]QueryChange
+
+	usage queryUsage
+}
+
+// NewQueryStore creates an empty query store
+func NewQueryStore() *QueryStore {
+	return &QueryStore{
+		queries: make(map[string]*Query),
+		history: make(map[string][]QueryVersion),
+		drafts:  make(map[string]QueryChange),
+	}
+}
+
+// Save compiles a query and stores it under name, replacing any previous query
+func (store *QueryStore) Save(name, query string) error {
+	_, err := store.SaveRevision(name, query, Revision{})
+	return err
+}
+
+// update stores and removes several compiled queries at once, so readers
+// never see only part of the change. Saves the approval hook does not
+// approve become drafts instead and saves it rejects are dropped; the
+// names of both are returned along with the rejections
+func (store *QueryStore) update(saves map[string]*Query, deletes []string) ([]string, []error) {
+	now := time.Now()
+	approved := make(map[string]bool, len(saves))
+	rejected := []error{}
+	for name, compiled := range saves {
+		err := store.review(name, compiled, Revision{}, now)
+		approved[name] = err == nil
+		if err != nil && !errors.Is(err, ErrApprovalPending) {
+			rejected = append(rejected, err)
+		}
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, name := range deletes {
+		store.deleteLocked(name, Revision{}, now)
+	}
+	held := []string{}
+	for name, compiled := range saves {
+		if !approved[name] {
+			held = append(held, name)
+			continue
+		}
+		store.saveLocked(name, compiled, Revision{}, now)
+	}
+	return held, rejected
+}
+
+// Get returns the compiled query stored under name
+func (store *QueryStore) Get(name string) (*Query, bool) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	compiled, ok := store.queries[name]
+	return compiled, ok
+}
+
+// Delete removes the query stored under name, keeping its history
+func (store *QueryStore) Delete(name string) {
+	store.DeleteRevision(name, Revision{})
+}
+
+// Names returns the names of every stored query in sorted order
+func (store *QueryStore) Names() []string {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	names := make([]string, 0, len(store.queries))
+	for name := range store.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sources returns the source text of every stored query keyed by name
+func (store *QueryStore) Sources() map[string]string {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	sources := make(map[string]string, len(store.queries))
+	for name, compiled := range store.queries {
+		sources[name] = compiled.String()
+	}
+	return sources
+}
+
+// queryStoreState is what a QueryStore persists: the active queries, the
+// history of every name, the drafts waiting for approval and the usage
+type queryStoreState struct {
+	Queries map[string]string         `json:"queries"`
+	History map[string][]QueryVersion `json:"history,omitempty"`
+	Drafts  map[string]QueryChange    `json:"drafts,omitempty"`
+	// Usage is the usage of the saved queries searched through the store
+	Usage map[string]QueryUsage `json:"usage,omitempty"`
+}
+
+// state returns a copy of everything the store persists
+func (store *QueryStore) state() queryStoreState {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	state := queryStoreState{
+		Queries: make(map[string]string, len(store.queries)),
+		History: make(map[string][]QueryVersion, len(store.history)),
+		Drafts:  make(map[string]QueryChange, len(store.drafts)),
+		Usage:   store.usage.snapshot(),
+	}
+	for name, compiled := range store.queries {
+		state.Queries[name] = compiled.String()
+	}
+	for name, versions := range store.history {
+		state.History[name] = append([]QueryVersion(nil), versions...)
+	}
+	for name, draft := range store.drafts {
+		state.Drafts[name] = draft
+	}
+	return state
+}
+
+// queryStoreFromState rebuilds a store from its persisted state, keeping
+// the history and drafts as they were. A query without a history, as
+// states saved before histories were persisted hold, starts one
+func queryStoreFromState(state queryStoreState) (*QueryStore, error) {
+	store := NewQueryStore()
+	now := time.Now()
+	for name, versions := range state.History {
+		for i, version := range versions {
+			if version.Version != i+1 {
+				return nil, fmt.Errorf("query %s history has version %d at position %d", name, version.Version, i+1)
+			}
+		}
+		store.history[name] = versions
+	}
+	for name, query := range state.Queries {
+		compiled, err := CompileQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", name, err)
+		}
+		versions := store.history[name]
+		if len(versions) == 0 || versions[len(versions)-1].Deleted || versions[len(versions)-1].Query != compiled.String() {
+			store.saveLocked(name, compiled, Revision{}, now)
+			continue
+		}
+		store.queries[name] = compiled
+	}
+	for name, draft := range state.Drafts {
+		store.drafts[name] = draft
+	}
+	store.usage.restore(state.Usage)
+	return store, nil
+}
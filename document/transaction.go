@@ -0,0 +1,87 @@
+package document
+
+import (
+	"fmt"
+)
+
+// batchOp is a single staged index mutation
+type batchOp struct {
+	doc *PreparedDoc
+	id  int
+}
+
+// Batch stages document additions and deletions that become visible
+// together when committed, or not at all
+type Batch struct {
+	idx  *Index
+	ops  []batchOp
+	done bool
+}
+
+// Begin starts a new unit of work against the index
+func (idx *Index) Begin() *Batch {
+	return &Batch{
+		idx: idx,
+		ops: make([]batchOp, 0),
+	}
+}
+
+// Add stages a document addition, analyzing it right away so the commit
+// only has to link it into the index
+func (batch *Batch) Add(text string) {
+	batch.ops = append(batch.ops, batchOp{doc: PrepareDocument(text)})
+}
+
+// Delete stages the deletion of a stored document
+func (batch *Batch) Delete(id int) {
+	batch.ops = append(batch.ops, batchOp{id: id})
+}
+
+// Commit applies every staged mutation atomically and returns the IDs of the
+// added documents. Nothing is applied if any deletion is invalid
+func (batch *Batch) Commit() ([]int, error) {
+	if batch.done {
+		return nil, fmt.Errorf("batch already committed or rolled back")
+	}
+	batch.done = true
+
+	idx := batch.idx
+	idx.mu.Lock()
+
+	deleting := make(map[int]bool)
+	for _, op := range batch.ops {
+		if op.doc != nil {
+			continue
+		}
+		if err := idx.checkLive(op.id); err != nil {
+			idx.mu.Unlock()
+			return nil, err
+		}
+		if deleting[op.id] {
+			idx.mu.Unlock()
+			return nil, fmt.Errorf("document %d deleted twice in one batch", op.id)
+		}
+		deleting[op.id] = true
+	}
+
+	ids := []int{}
+	events := make([]IndexEvent, 0, len(batch.ops))
+	for _, op := range batch.ops {
+		if op.doc != nil {
+			id := idx.addLocked(op.doc)
+			ids = append(ids, id)
+			events = append(events, idx.recordEvent(EventDocumentAdded, id, op.doc.text))
+			continue
+		}
+		idx.deleteLocked(op.id)
+		events = append(events, idx.recordEvent(EventDocumentDeleted, op.id, ""))
+	}
+	idx.unlockAndPublish(events)
+	return ids, nil
+}
+
+// Rollback discards every staged mutation
+func (batch *Batch) Rollback() {
+	batch.ops = nil
+	batch.done = true
+}
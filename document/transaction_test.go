@@ -0,0 +1,121 @@
+package document
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestBatchCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		stage   func(batch *Batch)
+		wantIDs []int
+		wantErr string
+		// live lists the documents matching "doc" after the commit
+		live []int
+	}{
+		{
+			name:    "adds and deletes",
+			stage:   func(batch *Batch) { batch.Add("doc three"); batch.Delete(0); batch.Add("doc four") },
+			wantIDs: []int{3, 4},
+			live:    []int{1, 2, 3, 4},
+		},
+		{
+			name:    "missing document",
+			stage:   func(batch *Batch) { batch.Add("doc three"); batch.Delete(9) },
+			wantErr: "document 9 does not exist",
+			live:    []int{0, 1, 2},
+		},
+		{
+			name:    "deleted twice",
+			stage:   func(batch *Batch) { batch.Delete(1); batch.Add("doc three"); batch.Delete(1) },
+			wantErr: "document 1 deleted twice in one batch",
+			live:    []int{0, 1, 2},
+		},
+		{
+			name:    "empty",
+			stage:   func(batch *Batch) {},
+			wantIDs: []int{},
+			live:    []int{0, 1, 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idx := NewIndex()
+			for _, text := range []string{"doc zero", "doc one", "doc two"} {
+				idx.Add(text)
+			}
+
+			batch := idx.Begin()
+			test.stage(batch)
+			if ids := hitIDs(t, idx, "doc"); !slices.Equal(ids, []int{0, 1, 2}) {
+				t.Errorf("staged mutations visible before Commit: %v", ids)
+			}
+
+			ids, err := batch.Commit()
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("Commit() error = %v, want %q", err, test.wantErr)
+				}
+			} else if err != nil || !slices.Equal(ids, test.wantIDs) {
+				t.Errorf("Commit() = %v, %v, want %v", ids, err, test.wantIDs)
+			}
+			if got := hitIDs(t, idx, "doc"); !slices.Equal(got, test.live) {
+				t.Errorf("documents after Commit = %v, want %v", got, test.live)
+			}
+
+			if _, err := batch.Commit(); err == nil {
+				t.Error("second Commit() succeeded")
+			}
+		})
+	}
+}
+
+func TestBatchRollback(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("doc zero")
+
+	batch := idx.Begin()
+	batch.Add("doc one")
+	batch.Delete(0)
+	batch.Rollback()
+
+	if _, err := batch.Commit(); err == nil || err.Error() != "batch already committed or rolled back" {
+		t.Errorf("Commit() after Rollback error = %v", err)
+	}
+	if ids := hitIDs(t, idx, "doc"); !slices.Equal(ids, []int{0}) {
+		t.Errorf("documents after Rollback = %v, want [0]", ids)
+	}
+}
+
+// TestBatchCommitIsAtomic searches concurrently with commits adding
+// documents in pairs, so a search seeing an odd count saw half a batch
+func TestBatchCommitIsAtomic(t *testing.T) {
+	idx := NewIndex()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			batch := idx.Begin()
+			batch.Add("pair first")
+			batch.Add("pair second")
+			if _, err := batch.Commit(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for range 200 {
+		result, err := idx.Search("pair", SearchOptions{CountOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Total%2 != 0 {
+			t.Fatalf("search saw %d documents, half a batch", result.Total)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,97 @@
+package document
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// AddWithTTL stores a document that expires after ttl, returning its ID.
+// Expired documents are removed by the next Expire sweep
+func (idx *Index) AddWithTTL(text string, ttl time.Duration) int {
+	doc := PrepareDocument(text)
+
+	idx.mu.Lock()
+	id := idx.addLocked(doc)
+	idx.setExpiryLocked(id, ttl)
+	idx.unlockAndPublish([]IndexEvent{idx.recordEvent(EventDocumentAdded, id, text)})
+	return id
+}
+
+// SetTTL makes a stored document expire ttl from now, a ttl of zero or less
+// keeps it until it is deleted
+func (idx *Index) SetTTL(id int, ttl time.Duration) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.checkLive(id); err != nil {
+		return err
+	}
+	idx.setExpiryLocked(id, ttl)
+	return nil
+}
+
+// ExpiresAt returns when a document expires, false if it has no TTL
+func (idx *Index) ExpiresAt(id int) (time.Time, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	at, ok := idx.expires[id]
+	return at, ok
+}
+
+// setExpiryLocked records the expiry of a live document, the caller must
+// hold the write lock
+func (idx *Index) setExpiryLocked(id int, ttl time.Duration) {
+	if ttl <= 0 {
+		delete(idx.expires, id)
+		return
+	}
+	if idx.expires == nil {
+		idx.expires = make(map[int]time.Time)
+	}
+	idx.expires[id] = time.Now().Add(ttl)
+}
+
+// Expire deletes every document whose TTL has passed and returns how many
+// were removed. Each removal is published as a deletion event
+func (idx *Index) Expire() int {
+	idx.mu.Lock()
+
+	now := time.Now()
+	expired := []int{}
+	for id, at := range idx.expires {
+		if !at.After(now) {
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) == 0 {
+		idx.mu.Unlock()
+		return 0
+	}
+	sort.Ints(expired)
+
+	events := make([]IndexEvent, 0, len(expired))
+	for _, id := range expired {
+		// Only live documents have an expiry
+		idx.deleteLocked(id)
+		events = append(events, idx.recordEvent(EventDocumentDeleted, id, ""))
+	}
+	idx.unlockAndPublish(events)
+	return len(expired)
+}
+
+// RunExpiry sweeps expired documents every interval until ctx is done
+func (idx *Index) RunExpiry(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		idx.Expire()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExpire(t *testing.T) {
+	idx := NewIndex()
+	expiring := idx.AddWithTTL("alert expiring", time.Millisecond)
+	lasting := idx.AddWithTTL("alert lasting", time.Hour)
+	permanent := idx.Add("alert permanent")
+	cleared := idx.AddWithTTL("alert cleared", time.Millisecond)
+	if err := idx.SetTTL(cleared, 0); err != nil {
+		t.Fatal(err)
+	}
+	deleted := idx.AddWithTTL("alert deleted", time.Millisecond)
+	if err := idx.Delete(deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := idx.ExpiresAt(lasting); !ok {
+		t.Errorf("ExpiresAt(%d) reports no TTL", lasting)
+	}
+	for _, id := range []int{permanent, cleared, deleted} {
+		if _, ok := idx.ExpiresAt(id); ok {
+			t.Errorf("ExpiresAt(%d) reports a TTL", id)
+		}
+	}
+
+	events := []IndexEvent{}
+	idx.Subscribe(func(event IndexEvent) { events = append(events, event) })
+
+	time.Sleep(5 * time.Millisecond)
+	if removed := idx.Expire(); removed != 1 {
+		t.Errorf("Expire() = %d, want 1", removed)
+	}
+	if ids := hitIDs(t, idx, "alert"); !slices.Equal(ids, []int{lasting, permanent, cleared}) {
+		t.Errorf("Search(alert) after Expire = %v, want [%d %d %d]", ids, lasting, permanent, cleared)
+	}
+	if len(events) != 1 || events[0].Type != EventDocumentDeleted || events[0].ID != expiring {
+		t.Errorf("events = %+v, want the deletion of %d", events, expiring)
+	}
+	if removed := idx.Expire(); removed != 0 {
+		t.Errorf("second Expire() = %d, want 0", removed)
+	}
+
+	if err := idx.SetTTL(expiring, time.Hour); err == nil {
+		t.Error("SetTTL() of an expired document succeeded")
+	}
+}
+
+func TestUpdateKeepsTTL(t *testing.T) {
+	idx := NewIndex()
+	id := idx.AddWithTTL("session one", time.Hour)
+	before, _ := idx.ExpiresAt(id)
+	if err := idx.Update(id, "session two"); err != nil {
+		t.Fatal(err)
+	}
+	if after, ok := idx.ExpiresAt(id); !ok || !after.Equal(before) {
+		t.Errorf("ExpiresAt() after Update = %v, %v, want %v", after, ok, before)
+	}
+}
+
+func TestRunExpiry(t *testing.T) {
+	idx := NewIndex()
+	idx.AddWithTTL("ephemeral", time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- idx.RunExpiry(ctx, time.Millisecond) }()
+
+	deadline := time.Now().Add(time.Second)
+	for idx.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("RunExpiry() = %v, want context.Canceled", err)
+	}
+	if idx.Len() != 0 {
+		t.Errorf("Len() = %d after the sweep, want 0", idx.Len())
+	}
+}
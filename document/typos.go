@@ -0,0 +1,120 @@
+package document
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxFuzzyExpansions caps how many similar terms replace an unknown term
+const maxFuzzyExpansions = 3
+
+// rewriteTyposLocked rewrites a query replacing every term missing from the
+// index with the indexed terms closest to it, OR-ed together when several
+// are equally close. It reports false when no term could be corrected. The
+// caller must hold a lock
+func (idx *Index) rewriteTyposLocked(compiled *Query) (string, bool) {
+	tokens, err := parseQuery(compiled.source)
+	if err != nil {
+		return "", false
+	}
+
+	rewritten := false
+	for i, token := range tokens {
+		if _, ok := compiled.ranges[token]; ok {
+			continue
+		}
+		if token == "(" || token == ")" || isOperator(token) || len(idx.postings[token]) > 0 || compiled.patterns[token] != nil {
+			continue
+		}
+		corrections := idx.fuzzyTermsLocked(token)
+		switch {
+		case len(corrections) == 1:
+			tokens[i] = corrections[0]
+		case len(corrections) > 1:
+			tokens[i] = "(" + strings.Join(corrections, " OR ") + ")"
+		default:
+			continue
+		}
+		rewritten = true
+	}
+	return strings.Join(tokens, " "), rewritten
+}
+
+// fuzzyTermsLocked returns the indexed terms at the smallest edit distance
+// from term within its allowed number of edits, most frequent first
+func (idx *Index) fuzzyTermsLocked(term string) []string {
+	maxEdits := fuzziness(term)
+	if maxEdits == 0 {
+		return nil
+	}
+
+	// The automaton finds the terms within reach without scanning the
+	// dictionary, the edit distance then keeps the closest of them
+	best := maxEdits + 1
+	matches := []string{}
+	automaton := newDFA(&levenshteinNFA{term: []rune(term), maxEdits: maxEdits})
+	idx.dict.intersect(automaton, func(candidate string) bool {
+		distance := editDistance(term, candidate)
+		switch {
+		case distance < best:
+			best = distance
+			matches = []string{candidate}
+		case distance == best:
+			matches = append(matches, candidate)
+		}
+		return true
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		left, right := len(idx.postings[matches[i]]), len(idx.postings[matches[j]])
+		if left != right {
+			return left > right
+		}
+		return matches[i] < matches[j]
+	})
+	if len(matches) > maxFuzzyExpansions {
+		matches = matches[:maxFuzzyExpansions]
+	}
+	return matches
+}
+
+// fuzziness returns how many edits a term may need to be corrected, none
+// for very short terms where any edit changes the meaning
+func fuzziness(term string) int {
+	switch length := len([]rune(term)); {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	}
+	return 2
+}
+
+// editDistance returns the optimal string alignment distance of a and b:
+// the insertions, deletions, substitutions and adjacent transpositions
+// turning one into the other
+func editDistance(a, b string) int {
+	left, right := []rune(a), []rune(b)
+	previous2 := make([]int, len(right)+1)
+	previous := make([]int, len(right)+1)
+	current := make([]int, len(right)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(left); i++ {
+		current[0] = i
+		for j := 1; j <= len(right); j++ {
+			cost := 1
+			if left[i-1] == right[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && left[i-1] == right[j-2] && left[i-2] == right[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(right)]
+}
@@ -0,0 +1,81 @@
+package document
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"python", "python", 0},
+		{"", "abc", 3},
+		{"pyton", "python", 1},
+		{"pythn", "pyhton", 2},
+		{"pyhton", "python", 1},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"/"+test.b, func(t *testing.T) {
+			if got := editDistance(test.a, test.b); got != test.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+			}
+			if got := editDistance(test.b, test.a); got != test.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", test.b, test.a, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchFixTypos(t *testing.T) {
+	idx := NewIndex()
+	for _, text := range []string{
+		"python guide",
+		"java guide",
+		"rust book",
+		"rest api",
+		"go book",
+	} {
+		idx.Add(text)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		fixTypos  bool
+		rewritten string
+		hits      []int
+	}{
+		{"disabled", "pyton", false, "", []int{}},
+		{"single correction", "pyton", true, "python", []int{0}},
+		{"transposition", "giude", true, "guide", []int{0, 1}},
+		{"equally close corrections", "rast", true, "(rest OR rust)", []int{2, 3}},
+		{"known terms kept", "pyton AND guide", true, "python AND guide", []int{0}},
+		{"hits are not rewritten", "java OR pyton", true, "", []int{1}},
+		{"short terms are not corrected", "gx", true, "", []int{}},
+		{"nothing close", "haskell", true, "", []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := idx.Search(test.query, SearchOptions{FixTypos: test.fixTypos})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.RewrittenQuery != test.rewritten {
+				t.Errorf("RewrittenQuery = %q, want %q", result.RewrittenQuery, test.rewritten)
+			}
+			ids := []int{}
+			for _, hit := range result.Hits {
+				ids = append(ids, hit.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, test.hits) {
+				t.Errorf("hits = %v, want %v", ids, test.hits)
+			}
+		})
+	}
+}
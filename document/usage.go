@@ -0,0 +1,110 @@
+package document
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QueryUsage is how often a saved query was searched through the store,
+// including the searches persisted with a store loaded from storage, so
+// dead queries and problematic ones stand out. A query never searched has no evaluations and a zero LastUsed
+type QueryUsage struct {
+	Name        string    `json:"name"`
+	Evaluations int       `json:"evaluations"`
+	Errors      int       `json:"errors"`
+	LastUsed    time.Time `json:"last_used"`
+	// Latency is the total time of the searches, see AverageLatency
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// AverageLatency returns the mean time of a search, zero when there was
+// none
+func (usage QueryUsage) AverageLatency() time.Duration {
+	if usage.Evaluations == 0 {
+		return 0
+	}
+	return usage.Latency / time.Duration(usage.Evaluations)
+}
+
+// ErrorRate returns the fraction of the searches that failed, zero when
+// there was none
+func (usage QueryUsage) ErrorRate() float64 {
+	if usage.Evaluations == 0 {
+		return 0
+	}
+	return float64(usage.Errors) / float64(usage.Evaluations)
+}
+
+// queryUsage counts the searches of every saved query. It has its own lock
+// so searches do not contend with updates of the store
+type queryUsage struct {
+	mu     sync.Mutex
+	byName map[string]QueryUsage
+}
+
+// record counts a search of name that started at start
+func (usage *queryUsage) record(name string, start time.Time, err error) {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	if usage.byName == nil {
+		usage.byName = make(map[string]QueryUsage)
+	}
+	entry := usage.byName[name]
+	entry.Evaluations++
+	if err != nil {
+		entry.Errors++
+	}
+	entry.LastUsed = start
+	entry.Latency += time.Since(start)
+	usage.byName[name] = entry
+}
+
+// snapshot returns a copy of the usage of every searched name, as
+// SaveToStorage persists it
+func (usage *queryUsage) snapshot() map[string]QueryUsage {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	byName := make(map[string]QueryUsage, len(usage.byName))
+	for name, entry := range usage.byName {
+		entry.Name = name
+		byName[name] = entry
+	}
+	return byName
+}
+
+// restore replaces the usage with byName, as LoadFromStorage read it
+func (usage *queryUsage) restore(byName map[string]QueryUsage) {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	usage.byName = byName
+}
+
+// Search runs the query saved under name on idx, counting the search in
+// the usage of name. Searches of a compiled query taken with Get are not
+// counted
+func (store *QueryStore) Search(idx *Index, name string, opts SearchOptions) (SearchResult, error) {
+	compiled, ok := store.Get(name)
+	if !ok {
+		return SearchResult{}, fmt.Errorf("unknown saved query: %s", name)
+	}
+	start := time.Now()
+	result, err := idx.SearchCompiled(compiled, opts)
+	store.usage.record(name, start, err)
+	return result, err
+}
+
+// Usage returns the usage of every saved query, sorted by name. Usage is
+// kept by name, so it carries over updates of a query and deleting and
+// saving it again, and SaveToStorage persists it with the queries
+func (store *QueryStore) Usage() []QueryUsage {
+	names := store.Names()
+	store.usage.mu.Lock()
+	defer store.usage.mu.Unlock()
+	usages := make([]QueryUsage, len(names))
+	for i, name := range names {
+		usages[i] = store.usage.byName[name]
+		usages[i].Name = name
+	}
+	return usages
+}
@@ -0,0 +1,160 @@
+package document
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryStoreUsage(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("go is fun")
+	idx.Add("rust is fast")
+	store := NewQueryStore()
+	for name, query := range map[string]string{"go": "go", "fast": "fast", "idle": "java"} {
+		if err := store.Save(name, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every step searches one saved query and checks the evaluations and
+	// errors counted for it so far
+	steps := []struct {
+		name        string
+		query       string
+		opts        SearchOptions
+		wantErr     string
+		evaluations int
+		errors      int
+	}{
+		{"first search", "go", SearchOptions{}, "", 1, 0},
+		{"second search", "go", SearchOptions{CountOnly: true}, "", 2, 0},
+		{"failed search", "fast", SearchOptions{MemoryBudget: 1}, "search", 1, 1},
+		{"search after a failure", "fast", SearchOptions{}, "", 2, 1},
+		{"unknown query", "missing", SearchOptions{}, "unknown saved query: missing", 0, 0},
+	}
+	for _, step := range steps {
+		before := time.Now()
+		_, err := store.Search(idx, step.query, step.opts)
+		switch {
+		case step.wantErr == "search":
+			if err == nil {
+				t.Errorf("%s: Search() succeeded", step.name)
+			}
+		case step.wantErr != "":
+			if err == nil || err.Error() != step.wantErr {
+				t.Errorf("%s: Search() error = %v, want %q", step.name, err, step.wantErr)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", step.name, err)
+		}
+		var usage QueryUsage
+		for _, candidate := range store.Usage() {
+			if candidate.Name == step.query {
+				usage = candidate
+			}
+		}
+		if usage.Evaluations != step.evaluations || usage.Errors != step.errors {
+			t.Errorf("%s: usage = %+v, want %d evaluations and %d errors", step.name, usage, step.evaluations, step.errors)
+		}
+		if step.evaluations > 0 && usage.LastUsed.Before(before) {
+			t.Errorf("%s: LastUsed = %v, before the search", step.name, usage.LastUsed)
+		}
+	}
+
+	usages := store.Usage()
+	names := []string{}
+	for _, usage := range usages {
+		names = append(names, usage.Name)
+	}
+	if len(names) != 3 || names[0] != "fast" || names[1] != "go" || names[2] != "idle" {
+		t.Fatalf("Usage() names = %v, want fast go idle", names)
+	}
+	if idle := usages[2]; idle.Evaluations != 0 || !idle.LastUsed.IsZero() || idle.Latency != 0 {
+		t.Errorf("unsearched query usage = %+v", idle)
+	}
+
+	// Usage is kept by name across an update, a delete and a new save
+	if err := store.Save("go", "go OR rust"); err != nil {
+		t.Fatal(err)
+	}
+	store.Delete("go")
+	if got := len(store.Usage()); got != 2 {
+		t.Errorf("Usage() after Delete has %d queries, want 2", got)
+	}
+	if err := store.Save("go", "go"); err != nil {
+		t.Fatal(err)
+	}
+	for _, usage := range store.Usage() {
+		if usage.Name == "go" && usage.Evaluations != 2 {
+			t.Errorf("usage of go after saving it again = %+v, want 2 evaluations", usage)
+		}
+	}
+}
+
+func TestQueryUsageRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   QueryUsage
+		latency time.Duration
+		rate    float64
+	}{
+		{"never searched", QueryUsage{}, 0, 0},
+		{"one search", QueryUsage{Evaluations: 1, Latency: 3 * time.Millisecond}, 3 * time.Millisecond, 0},
+		{"some errors", QueryUsage{Evaluations: 4, Errors: 1, Latency: 8 * time.Millisecond}, 2 * time.Millisecond, 0.25},
+		{"all errors", QueryUsage{Evaluations: 2, Errors: 2, Latency: time.Millisecond}, 500 * time.Microsecond, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.usage.AverageLatency(); got != test.latency {
+				t.Errorf("AverageLatency() = %v, want %v", got, test.latency)
+			}
+			if got := test.usage.ErrorRate(); got != test.rate {
+				t.Errorf("ErrorRate() = %g, want %g", got, test.rate)
+			}
+		})
+	}
+}
+
+func TestQueryUsagePersists(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("go is fun")
+	store := NewQueryStore()
+	for name, query := range map[string]string{"go": "go", "idle": "java"} {
+		if err := store.Save(name, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := store.Search(idx, "go", SearchOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storage := NewMemoryStorage()
+	if err := SaveToStorage(storage, idx, store); err != nil {
+		t.Fatal(err)
+	}
+	_, loaded, err := LoadFromStorage(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := store.Usage()
+	got := loaded.Usage()
+	if len(got) != len(want) {
+		t.Fatalf("loaded Usage() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].LastUsed.Equal(want[i].LastUsed) || got[i].Name != want[i].Name ||
+			got[i].Evaluations != want[i].Evaluations || got[i].Latency != want[i].Latency {
+			t.Errorf("loaded usage = %+v, want %+v", got[i], want[i])
+		}
+	}
+
+	// Searches of the loaded store add to the persisted counts
+	if _, err := loaded.Search(idx, "go", SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if usage := loaded.Usage()[0]; usage.Evaluations != 4 {
+		t.Errorf("usage after another search = %+v, want 4 evaluations", usage)
+	}
+}
@@ -0,0 +1,163 @@
+package document
+
+import (
+	"fmt"
+	"time"
+)
+
+// Revision describes who made a change to a saved query and why
+type Revision struct {
+	Author  string `json:"author,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// QueryVersion is one version in the history of a saved query
+type QueryVersion struct {
+	// Version numbers the versions of a name from 1, deletions included
+	Version int    `json:"version"`
+	Query   string `json:"query,omitempty"`
+	// Deleted marks a version removing the query, whose Query is empty
+	Deleted bool      `json:"deleted,omitempty"`
+	Time    time.Time `json:"time"`
+	Revision
+}
+
+// QueryDiff compares two versions of a saved query by their canonical
+// forms, so edits of layout alone are not reported as changes
+type QueryDiff struct {
+	Name string
+	From QueryVersion
+	To   QueryVersion
+	// Old and New are the canonical forms of the versions, empty for a
+	// deletion
+	Old string
+	New string
+}
+
+// Changed reports whether the versions match differently, which edits
+// that only change spacing, case or redundant parentheses do not
+func (diff QueryDiff) Changed() bool {
+	return diff.Old != diff.New
+}
+
+// SaveRevision compiles a query and stores it under name like Save,
+// recording the change in the history of name. Saving the query already
+// stored adds no version and returns the current one. A change the
+// approval hook does not approve is kept as a draft and reported with
+// ErrApprovalPending
+func (store *QueryStore) SaveRevision(name, query string, rev Revision) (QueryVersion, error) {
+	if name == "" {
+		return QueryVersion{}, fmt.Errorf("query name must not be empty")
+	}
+	compiled, err := CompileQuery(query)
+	if err != nil {
+		return QueryVersion{}, fmt.Errorf("query %s: %w", name, err)
+	}
+	if err := store.review(name, compiled, rev, time.Now()); err != nil {
+		return QueryVersion{}, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.saveLocked(name, compiled, rev, time.Now()), nil
+}
+
+// DeleteRevision removes the query stored under name like Delete, recording
+// the change in the history of name
+func (store *QueryStore) DeleteRevision(name string, rev Revision) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.deleteLocked(name, rev, time.Now())
+}
+
+// saveLocked stores a compiled query and appends it to the history unless
+// it is the query already stored. The caller must hold store.mu
+func (store *QueryStore) saveLocked(name string, compiled *Query, rev Revision, now time.Time) QueryVersion {
+	versions := store.history[name]
+	if current, ok := store.queries[name]; ok && current.String() == compiled.String() && len(versions) > 0 {
+		return versions[len(versions)-1]
+	}
+	store.queries[name] = compiled
+	version := QueryVersion{Version: len(versions) + 1, Query: compiled.String(), Time: now, Revision: rev}
+	store.history[name] = append(versions, version)
+	return version
+}
+
+// deleteLocked removes a stored query and appends the deletion to the
+// history. The caller must hold store.mu
+func (store *QueryStore) deleteLocked(name string, rev Revision, now time.Time) {
+	if _, ok := store.queries[name]; !ok {
+		return
+	}
+	delete(store.queries, name)
+	versions := store.history[name]
+	store.history[name] = append(versions, QueryVersion{Version: len(versions) + 1, Deleted: true, Time: now, Revision: rev})
+}
+
+// History returns every version of the query saved under name, oldest
+// first, including the versions before a deletion
+func (store *QueryStore) History(name string) []QueryVersion {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return append([]QueryVersion(nil), store.history[name]...)
+}
+
+// versionLocked returns a version of name. The caller must hold store.mu
+func (store *QueryStore) versionLocked(name string, version int) (QueryVersion, error) {
+	versions := store.history[name]
+	if version < 1 || version > len(versions) {
+		return QueryVersion{}, fmt.Errorf("query %s has no version %d", name, version)
+	}
+	return versions[version-1], nil
+}
+
+// Diff compares two versions of the query saved under name
+func (store *QueryStore) Diff(name string, from, to int) (QueryDiff, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	diff := QueryDiff{Name: name}
+	var err error
+	if diff.From, err = store.versionLocked(name, from); err != nil {
+		return QueryDiff{}, err
+	}
+	if diff.To, err = store.versionLocked(name, to); err != nil {
+		return QueryDiff{}, err
+	}
+	diff.Old, diff.New = canonicalVersion(diff.From), canonicalVersion(diff.To)
+	return diff, nil
+}
+
+// canonicalVersion returns the canonical form of a version's query, empty
+// for a deletion
+func canonicalVersion(version QueryVersion) string {
+	if version.Deleted {
+		return ""
+	}
+	// Every version compiled when it was saved
+	compiled, _ := CompileQuery(version.Query)
+	return compiled.Canonical()
+}
+
+// Rollback stores the query of an earlier version of name again, as a new
+// version, so the history keeps the change being undone. An empty comment
+// records which version was restored. The version restored was active
+// before, so the approval hook does not review it again
+func (store *QueryStore) Rollback(name string, version int, rev Revision) (QueryVersion, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	target, err := store.versionLocked(name, version)
+	if err != nil {
+		return QueryVersion{}, err
+	}
+	if target.Deleted {
+		return QueryVersion{}, fmt.Errorf("query %s version %d is a deletion", name, version)
+	}
+	compiled, err := CompileQuery(target.Query)
+	if err != nil {
+		return QueryVersion{}, fmt.Errorf("query %s: %w", name, err)
+	}
+	if rev.Comment == "" {
+		rev.Comment = fmt.Sprintf("rollback to version %d", version)
+	}
+	return store.saveLocked(name, compiled, rev, time.Now()), nil
+}
@@ -0,0 +1,235 @@
+package document
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryCanonical(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"go", "go"},
+		{"Go  AND (Rust)", "go AND rust"},
+		{"((a OR b)) OR c", "a OR b OR c"},
+		{"a OR (b OR c)", "a OR (b OR c)"},
+		{"(a AND b) OR c", "a AND b OR c"},
+		{"a AND (b OR c)", "a AND (b OR c)"},
+		{"(NOT a) AND b", "NOT a AND b"},
+		{"NOT (a AND b)", "NOT (a AND b)"},
+		{"go*", "go*"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := mustCompileQuery(t, test.query).Canonical(); got != test.want {
+				t.Errorf("Canonical() = %q, want %q", got, test.want)
+			}
+			// The canonical form is its own canonical form
+			if again := mustCompileQuery(t, test.want).Canonical(); again != test.want {
+				t.Errorf("Canonical() of %q = %q", test.want, again)
+			}
+		})
+	}
+}
+
+func TestQueryHistory(t *testing.T) {
+	alice := Revision{Author: "alice", Comment: "first"}
+	bob := Revision{Author: "bob", Comment: "widen"}
+	store := NewQueryStore()
+
+	// Every step changes the history of "guides" and checks the version
+	// it returns
+	steps := []struct {
+		name    string
+		change  func() (QueryVersion, error)
+		want    QueryVersion
+		wantErr string
+	}{
+		{"save", func() (QueryVersion, error) { return store.SaveRevision("guides", "go AND guide", alice) },
+			QueryVersion{Version: 1, Query: "go AND guide", Revision: alice}, ""},
+		{"save unchanged", func() (QueryVersion, error) { return store.SaveRevision("guides", "go AND guide", bob) },
+			QueryVersion{Version: 1, Query: "go AND guide", Revision: alice}, ""},
+		{"edit", func() (QueryVersion, error) { return store.SaveRevision("guides", "(go OR rust) AND guide", bob) },
+			QueryVersion{Version: 2, Query: "(go OR rust) AND guide", Revision: bob}, ""},
+		{"bad edit", func() (QueryVersion, error) { return store.SaveRevision("guides", "go AND", bob) },
+			QueryVersion{}, "query guides: insufficient operands for AND operation"},
+		{"layout edit", func() (QueryVersion, error) {
+			return store.SaveRevision("guides", "(Go OR rust)  AND guide", Revision{})
+		},
+			QueryVersion{Version: 3, Query: "(Go OR rust)  AND guide"}, ""},
+		{"rollback", func() (QueryVersion, error) { return store.Rollback("guides", 1, Revision{Author: "carol"}) },
+			QueryVersion{Version: 4, Query: "go AND guide", Revision: Revision{Author: "carol", Comment: "rollback to version 1"}}, ""},
+		{"rollback with comment", func() (QueryVersion, error) { return store.Rollback("guides", 2, bob) },
+			QueryVersion{Version: 5, Query: "(go OR rust) AND guide", Revision: bob}, ""},
+		{"rollback to the current version", func() (QueryVersion, error) { return store.Rollback("guides", 2, alice) },
+			QueryVersion{Version: 5, Query: "(go OR rust) AND guide", Revision: bob}, ""},
+		{"delete", func() (QueryVersion, error) {
+			store.DeleteRevision("guides", alice)
+			// Deleting a deleted query adds no version
+			store.Delete("guides")
+			history := store.History("guides")
+			return history[len(history)-1], nil
+		}, QueryVersion{Version: 6, Deleted: true, Revision: alice}, ""},
+		{"rollback to a deletion", func() (QueryVersion, error) { return store.Rollback("guides", 6, alice) },
+			QueryVersion{}, "query guides version 6 is a deletion"},
+		{"rollback to a missing version", func() (QueryVersion, error) { return store.Rollback("guides", 7, alice) },
+			QueryVersion{}, "query guides has no version 7"},
+		{"save after delete", func() (QueryVersion, error) { return store.SaveRevision("guides", "guide", alice) },
+			QueryVersion{Version: 7, Query: "guide", Revision: alice}, ""},
+	}
+	for _, step := range steps {
+		got, err := step.change()
+		if step.wantErr != "" {
+			if err == nil || err.Error() != step.wantErr {
+				t.Errorf("%s: error = %v, want %q", step.name, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got.Time.IsZero() {
+			t.Errorf("%s: version has no time", step.name)
+		}
+		got.Time = step.want.Time
+		if got != step.want {
+			t.Errorf("%s: version = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	history := store.History("guides")
+	if len(history) != 7 {
+		t.Fatalf("History() has %d versions, want 7", len(history))
+	}
+	for i, version := range history {
+		if version.Version != i+1 || (i > 0 && version.Time.Before(history[i-1].Time)) {
+			t.Errorf("version %d = %+v", i+1, version)
+		}
+	}
+	if current, ok := store.Get("guides"); !ok || current.String() != "guide" {
+		t.Errorf("current query = %v, %t", current, ok)
+	}
+	if history := store.History("other"); len(history) != 0 {
+		t.Errorf("History() of an unknown name = %v", history)
+	}
+	if _, err := store.SaveRevision("", "go", alice); err == nil || err.Error() != "query name must not be empty" {
+		t.Errorf("SaveRevision() without a name error = %v", err)
+	}
+}
+
+func TestQueryDiff(t *testing.T) {
+	store := NewQueryStore()
+	for _, query := range []string{"go AND guide", "(Go) AND  guide", "go OR rust"} {
+		if _, err := store.SaveRevision("q", query, Revision{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Delete("q")
+
+	tests := []struct {
+		from, to int
+		old, new string
+		changed  bool
+		wantErr  string
+	}{
+		{1, 2, "go AND guide", "go AND guide", false, ""},
+		{2, 3, "go AND guide", "go OR rust", true, ""},
+		{3, 1, "go OR rust", "go AND guide", true, ""},
+		{3, 4, "go OR rust", "", true, ""},
+		{0, 1, "", "", false, "query q has no version 0"},
+		{1, 5, "", "", false, "query q has no version 5"},
+	}
+	for _, test := range tests {
+		diff, err := store.Diff("q", test.from, test.to)
+		if test.wantErr != "" {
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("Diff(%d, %d) error = %v, want %q", test.from, test.to, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff.Old != test.old || diff.New != test.new || diff.Changed() != test.changed {
+			t.Errorf("Diff(%d, %d) = %q -> %q changed %t, want %q -> %q changed %t",
+				test.from, test.to, diff.Old, diff.New, diff.Changed(), test.old, test.new, test.changed)
+		}
+		if diff.Name != "q" || diff.From.Version != test.from || diff.To.Version != test.to {
+			t.Errorf("Diff(%d, %d) compares %+v with %+v", test.from, test.to, diff.From, diff.To)
+		}
+	}
+}
+
+func TestQueryHistoryPersists(t *testing.T) {
+	store := NewQueryStore()
+	alice := Revision{Author: "alice", Comment: "first"}
+	for _, query := range []string{"go", "go AND guide"} {
+		if _, err := store.SaveRevision("guides", query, alice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Save("old", "java"); err != nil {
+		t.Fatal(err)
+	}
+	store.DeleteRevision("old", Revision{Author: "bob", Comment: "unused"})
+	store.SetApprovalHook(ApprovalHookFunc(func(QueryChange) (bool, error) { return false, nil }))
+	if _, err := store.SaveRevision("guides", "rust AND guide", Revision{Author: "carol"}); err == nil {
+		t.Fatal("SaveRevision() was not drafted")
+	}
+
+	storage := NewMemoryStorage()
+	if err := SaveToStorage(storage, NewIndex(), store); err != nil {
+		t.Fatal(err)
+	}
+	_, loaded, err := LoadFromStorage(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"guides", "old"} {
+		want, got := store.History(name), loaded.History(name)
+		if len(got) != len(want) {
+			t.Fatalf("loaded History(%s) = %+v, want %+v", name, got, want)
+		}
+		for i := range want {
+			if !got[i].Time.Equal(want[i].Time) {
+				t.Errorf("loaded version %d of %s at %v, want %v", i+1, name, got[i].Time, want[i].Time)
+			}
+			got[i].Time = want[i].Time
+			if got[i] != want[i] {
+				t.Errorf("loaded version %d of %s = %+v, want %+v", i+1, name, got[i], want[i])
+			}
+		}
+	}
+	if !reflect.DeepEqual(loaded.Names(), []string{"guides"}) {
+		t.Errorf("loaded Names() = %v", loaded.Names())
+	}
+	draft, ok := loaded.Draft("guides")
+	if !ok || draft.Query != "rust AND guide" || draft.Previous != "go AND guide" || draft.Author != "carol" {
+		t.Errorf("loaded Draft() = %+v, %t", draft, ok)
+	}
+	// The restored history goes on where it left off
+	version, err := loaded.Approve("guides")
+	if err != nil || version.Version != 3 || version.Author != "carol" {
+		t.Errorf("Approve() after loading = %+v, %v", version, err)
+	}
+	if version, err := loaded.Rollback("guides", 1, alice); err != nil || version.Version != 4 || version.Query != "go" {
+		t.Errorf("Rollback() after loading = %+v, %v", version, err)
+	}
+}
+
+func TestQueryStoreStateWithoutHistory(t *testing.T) {
+	// States saved before the history was persisted list the queries alone
+	store, err := queryStoreFromState(queryStoreState{Queries: map[string]string{"go": "go AND guide"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history := store.History("go"); len(history) != 1 || history[0].Version != 1 || history[0].Query != "go AND guide" {
+		t.Errorf("History() = %+v, want one version", history)
+	}
+
+	_, err = queryStoreFromState(queryStoreState{History: map[string][]QueryVersion{"go": {{Version: 2, Query: "go"}}}})
+	if want := "query go history has version 2 at position 1"; err == nil || err.Error() != want {
+		t.Errorf("queryStoreFromState() error = %v, want %q", err, want)
+	}
+}
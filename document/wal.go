@@ -0,0 +1,211 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// WAL is a write-ahead log of index mutations in a file, one JSON log
+// entry per line. Once attached to an index every event is written and
+// synced before the mutation returns, so the documents indexed since the
+// last save survive a crash: at startup the saved index is loaded and the
+// log replayed on top of it
+type WAL struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	err    error
+	cancel func()
+}
+
+// OpenWAL opens or creates the log at path. A record torn by a crash while
+// it was written is cut off, since its mutation never returned
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	valid, err := scanWAL(file, func(LogEntry) error { return nil })
+	if err == nil {
+		err = file.Truncate(valid)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &WAL{path: path, file: file}, nil
+}
+
+// scanWAL calls visit with every complete entry of the log from its start
+// and returns the length of the complete entries. The file is opened for
+// appending, so reading it never moves where entries are written
+func scanWAL(file *os.File, visit func(LogEntry) error) (int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	reader := bufio.NewReader(file)
+	valid := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A last line without its newline was torn mid-write
+			return valid, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		entry := LogEntry{}
+		if err := json.Unmarshal(bytes.TrimSpace(line), &entry); err != nil {
+			return 0, fmt.Errorf("corrupt write-ahead log entry at offset %d: %w", valid, err)
+		}
+		if err := visit(entry); err != nil {
+			return 0, err
+		}
+		valid += int64(len(line))
+	}
+}
+
+// Replay applies the logged mutations the index does not hold yet, those
+// with a sequence number past its last event, returning how many it
+// applied. It runs before Attach, on an index loaded with LoadFromStorage
+// or a new one
+func (wal *WAL) Replay(idx *Index) (int, error) {
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+	if wal.cancel != nil {
+		return 0, fmt.Errorf("write-ahead log replayed after Attach")
+	}
+
+	applied := 0
+	_, err := scanWAL(wal.file, func(entry LogEntry) error {
+		idx.mu.RLock()
+		seq := idx.seq
+		idx.mu.RUnlock()
+		if entry.Seq <= seq {
+			return nil
+		}
+		if entry.Seq != seq+1 {
+			return fmt.Errorf("write-ahead log gap: expected seq %d, got %d", seq+1, entry.Seq)
+		}
+		if err := applyLogEntry(idx, entry); err != nil {
+			return err
+		}
+		applied++
+		return nil
+	})
+	return applied, err
+}
+
+// Attach starts logging every mutation of idx
+func (wal *WAL) Attach(idx *Index) {
+	cancel := idx.Subscribe(wal.append)
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+	wal.cancel = cancel
+}
+
+// append writes and syncs an event. Events cannot fail, so the first write
+// error is kept for Err and stops further logging
+func (wal *WAL) append(event IndexEvent) {
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+	if wal.err != nil {
+		return
+	}
+
+	line, err := json.Marshal(LogEntry{
+		Seq:     event.Seq,
+		Type:    event.Type,
+		ID:      event.ID,
+		Text:    event.Text,
+		Docs:    event.Docs,
+		Deleted: event.Deleted,
+	})
+	if err == nil {
+		_, err = wal.file.Write(append(line, '\n'))
+	}
+	if err == nil {
+		err = wal.file.Sync()
+	}
+	wal.err = err
+}
+
+// Err returns the error that stopped logging, if any. Mutations after it
+// are not durable
+func (wal *WAL) Err() error {
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+	return wal.err
+}
+
+// Checkpoint saves the index and saved queries to storage and drops the
+// log entries the saved state includes
+func (wal *WAL) Checkpoint(storage Storage, idx *Index, store *QueryStore) error {
+	seq, err := saveToStorage(storage, idx, store)
+	if err != nil {
+		return err
+	}
+
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+	if wal.err != nil {
+		return wal.err
+	}
+
+	temp, err := os.Create(wal.path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+	_, err = scanWAL(wal.file, func(entry LogEntry) error {
+		if entry.Seq <= seq {
+			return nil
+		}
+		return encoder.Encode(entry)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), wal.path)
+	}
+	if err != nil {
+		// The old log is still complete and stays in use
+		return err
+	}
+
+	file, err := os.OpenFile(wal.path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		wal.err = err
+		return err
+	}
+	wal.file.Close()
+	wal.file = file
+	return nil
+}
+
+// Close stops logging and closes the file, returning any write error
+func (wal *WAL) Close() error {
+	if wal.cancel != nil {
+		wal.cancel()
+	}
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+	if err := wal.file.Close(); err != nil {
+		return err
+	}
+	return wal.err
+}
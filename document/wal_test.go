@@ -0,0 +1,222 @@
+package document
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// indexDocuments returns the stored documents of an index by ID, looking
+// at the IDs below limit
+func indexDocuments(idx *Index, limit int) map[int]string {
+	docs := map[int]string{}
+	for id := 0; id < limit; id++ {
+		if text, ok := idx.Get(id); ok {
+			docs[id] = text
+		}
+	}
+	return docs
+}
+
+// openAttachedWAL opens the log at path, replays it onto idx and attaches
+// it, returning how many entries were replayed
+func openAttachedWAL(t *testing.T, path string, idx *Index) (*WAL, int) {
+	t.Helper()
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := wal.Replay(idx)
+	if err != nil {
+		t.Fatalf("Replay(): %v", err)
+	}
+	wal.Attach(idx)
+	return wal, applied
+}
+
+func TestWALReplayRestoresIndex(t *testing.T) {
+	mutations := []struct {
+		name   string
+		mutate func(t *testing.T, idx *Index)
+	}{
+		{"add", func(t *testing.T, idx *Index) { idx.Add("python guide") }},
+		{"update", func(t *testing.T, idx *Index) {
+			if err := idx.Update(1, "rust guide, second edition"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"delete", func(t *testing.T, idx *Index) {
+			if err := idx.Delete(2); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"batch", func(t *testing.T, idx *Index) {
+			batch := idx.Begin()
+			batch.Add("go in action")
+			batch.Add("haskell primer")
+			batch.Delete(0)
+			if _, err := batch.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"swap", func(t *testing.T, idx *Index) {
+			builder := NewIndexBuilder()
+			builder.AddAll([]string{"rebuilt one", "rebuilt two", "rebuilt three", "rebuilt four", "rebuilt five", "rebuilt six"})
+			idx.Swap(builder.Build())
+		}},
+	}
+	for _, mutation := range mutations {
+		t.Run(mutation.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index.wal")
+			idx := NewIndex()
+			wal, _ := openAttachedWAL(t, path, idx)
+			for _, text := range []string{"java guide", "rust guide", "cobol manual"} {
+				idx.Add(text)
+			}
+			mutation.mutate(t, idx)
+			// The process crashes: the index was never saved
+			if err := wal.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			restored := NewIndex()
+			wal, applied := openAttachedWAL(t, path, restored)
+			defer wal.Close()
+			if applied == 0 {
+				t.Fatal("Replay() applied nothing")
+			}
+			if got, want := indexDocuments(restored, 10), indexDocuments(idx, 10); !maps.Equal(got, want) {
+				t.Errorf("replayed documents = %v, want %v", got, want)
+			}
+			for _, query := range []string{"guide", "rebuilt OR action", "NOT manual"} {
+				if got, want := hitIDs(t, restored, query), hitIDs(t, idx, query); !slices.Equal(got, want) {
+					t.Errorf("Search(%q) = %v, want %v", query, got, want)
+				}
+			}
+			if applied, err := wal.Replay(NewIndex()); err == nil {
+				t.Errorf("Replay() after Attach applied %d, want an error", applied)
+			}
+		})
+	}
+}
+
+func TestWALCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.wal")
+	storage := NewMemoryStorage()
+	idx, store := NewIndex(), NewQueryStore()
+	wal, _ := openAttachedWAL(t, path, idx)
+	idx.Add("saved one")
+	idx.Add("saved two")
+	if err := wal.Checkpoint(storage, idx, store); err != nil {
+		t.Fatal(err)
+	}
+	idx.Add("logged three")
+	if err := idx.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log only keeps what the checkpoint did not save
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("log holds %d entries after the checkpoint, want 2:\n%s", lines, data)
+	}
+
+	restored, _, err := LoadFromStorage(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal, applied := openAttachedWAL(t, path, restored)
+	defer wal.Close()
+	if applied != 2 {
+		t.Errorf("Replay() applied %d entries, want 2", applied)
+	}
+	if got, want := indexDocuments(restored, 5), indexDocuments(idx, 5); !maps.Equal(got, want) {
+		t.Errorf("restored documents = %v, want %v", got, want)
+	}
+
+	// Mutations after the restart are logged after the replayed ones
+	restored.Add("after restart")
+	if err := wal.Err(); err != nil {
+		t.Fatal(err)
+	}
+	saved, _, err := LoadFromStorage(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if applied, err := second.Replay(saved); err != nil || applied != 3 {
+		t.Errorf("Replay() = %d, %v, want 3 entries", applied, err)
+	}
+	if got, want := indexDocuments(saved, 5), indexDocuments(restored, 5); !maps.Equal(got, want) {
+		t.Errorf("documents after the second restart = %v, want %v", got, want)
+	}
+}
+
+func TestOpenWALCutsTornEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.wal")
+	idx := NewIndex()
+	wal, _ := openAttachedWAL(t, path, idx)
+	idx.Add("complete")
+	wal.Close()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"Seq":2,"Type":0,"ID":1,"Te`)
+	file.Close()
+
+	restored := NewIndex()
+	wal, applied := openAttachedWAL(t, path, restored)
+	restored.Add("after the crash")
+	wal.Close()
+	if applied != 1 {
+		t.Errorf("Replay() applied %d entries, want 1", applied)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), `"Te`+"\n") || strings.Count(string(data), "\n") != 2 {
+		t.Errorf("log after cutting the torn entry:\n%s", data)
+	}
+}
+
+func TestWALReplayErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"corrupt entry", "{\"Seq\":1,\"Type\":0,\"ID\":0,\"Text\":\"a\"}\nnot json\n{}\n", "corrupt write-ahead log entry at offset 37: invalid character 'o' in literal null (expecting 'u')"},
+		{"gap", "{\"Seq\":1,\"Type\":0,\"ID\":0,\"Text\":\"a\"}\n{\"Seq\":3,\"Type\":0,\"ID\":1,\"Text\":\"b\"}\n", "write-ahead log gap: expected seq 2, got 3"},
+		{"diverged", "{\"Seq\":1,\"Type\":0,\"ID\":4,\"Text\":\"a\"}\n", "replay diverged: added document 0, log has 4"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index.wal")
+			if err := os.WriteFile(path, []byte(test.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			wal, err := OpenWAL(path)
+			if err == nil {
+				defer wal.Close()
+				_, err = wal.Replay(NewIndex())
+			}
+			if err == nil || err.Error() != test.want {
+				t.Errorf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package document
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// fileVersion identifies a version of a watched file by its modification
+// time and size
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// readChanged reads the file at path unless it is still at version last,
+// returning the version read and whether it changed
+func readChanged(path string, last fileVersion) ([]byte, fileVersion, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, last, false, err
+	}
+	version := fileVersion{modTime: info.ModTime(), size: info.Size()}
+	if version == last {
+		return nil, last, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, last, false, err
+	}
+	return data, version, true, nil
+}
+
+// pollEvery calls poll every interval, passing its errors to onError, until
+// the returned function is called
+func pollEvery(interval time.Duration, poll func() error, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if err := poll(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,169 @@
+// Package engine is the stack machine shared by the RPN calculators and the
+// boolean query processor. An Engine evaluates RPN tokens over operands of
+// any type using a per-type table of operators and a parser for literals
+package engine
+
+import "fmt"
+
+// Operator pops Arity operands, deepest first, and pushes the result. An
+// operator failing with an error leaves the stack untouched. The operands
+// slice aliases the stack and must not be retained
+type Operator[T any] struct {
+	Arity int
+	Apply func(operands []T) (T, error)
+}
+
+// Unary adapts a function of one operand into an Operator
+func Unary[T any](fn func(T) T) Operator[T] {
+	return Operator[T]{Arity: 1, Apply: func(operands []T) (T, error) {
+		return fn(operands[0]), nil
+	}}
+}
+
+// Binary adapts a function of two operands into an Operator
+func Binary[T any](fn func(T, T) T) Operator[T] {
+	return Operator[T]{Arity: 2, Apply: func(operands []T) (T, error) {
+		return fn(operands[0], operands[1]), nil
+	}}
+}
+
+// Operators is the operator table of an operand type, keyed by token
+type Operators[T any] map[string]Operator[T]
+
+// Engine is an RPN stack of T values with an operator table. Operators are
+// looked up before literals are parsed, so a table can shadow literals
+type Engine[T any] struct {
+	stack     []T
+	operators Operators[T]
+	parse     func(token string) (T, error)
+}
+
+// New creates an engine with an operator table and a literal parser, both
+// may be nil for an engine only used as a stack
+func New[T any](operators Operators[T], parse func(token string) (T, error)) *Engine[T] {
+	return &Engine[T]{
+		stack:     make([]T, 0),
+		operators: operators,
+		parse:     parse,
+	}
+}
+
+// Grow makes room for capacity values, so evaluations up to that depth
+// never grow the stack
+func (e *Engine[T]) Grow(capacity int) {
+	if capacity > cap(e.stack) {
+		grown := make([]T, len(e.stack), capacity)
+		copy(grown, e.stack)
+		e.stack = grown
+	}
+}
+
+// Push adds a value to the stack
+func (e *Engine[T]) Push(value T) {
+	e.stack = append(e.stack, value)
+}
+
+// Pop removes and returns the top value from the stack
+func (e *Engine[T]) Pop() (T, error) {
+	if len(e.stack) == 0 {
+		var zero T
+		return zero, fmt.Errorf("%w: stack is empty", ErrStackUnderflow)
+	}
+
+	index := len(e.stack) - 1
+	value := e.stack[index]
+	e.stack = e.stack[:index]
+	return value, nil
+}
+
+// Peek returns the top value without removing it
+func (e *Engine[T]) Peek() (T, error) {
+	if len(e.stack) == 0 {
+		var zero T
+		return zero, fmt.Errorf("%w: stack is empty", ErrStackUnderflow)
+	}
+	return e.stack[len(e.stack)-1], nil
+}
+
+// IsEmpty checks if the stack is empty
+func (e *Engine[T]) IsEmpty() bool {
+	return len(e.stack) == 0
+}
+
+// Size returns the number of values in the stack
+func (e *Engine[T]) Size() int {
+	return len(e.stack)
+}
+
+// Clear empties the stack
+func (e *Engine[T]) Clear() {
+	e.stack = e.stack[:0]
+}
+
+// Values returns a copy of the stack, bottom first
+func (e *Engine[T]) Values() []T {
+	return append([]T(nil), e.stack...)
+}
+
+// SetValues replaces the stack with a copy of values, bottom first
+func (e *Engine[T]) SetValues(values []T) {
+	e.stack = append(e.stack[:0], values...)
+}
+
+// Truncate drops every value above the bottom size values
+func (e *Engine[T]) Truncate(size int) {
+	if size < len(e.stack) {
+		e.stack = e.stack[:size]
+	}
+}
+
+// Apply runs an operator on the top of the stack, name only labels errors
+func (e *Engine[T]) Apply(name string, operator Operator[T]) error {
+	if len(e.stack) < operator.Arity {
+		return Underflow(name)
+	}
+
+	start := len(e.stack) - operator.Arity
+	result, err := operator.Apply(e.stack[start:])
+	if err != nil {
+		return err
+	}
+	e.stack = append(e.stack[:start], result)
+	return nil
+}
+
+// Evaluate processes a single token, an operator from the table or a
+// literal. A token the parser rejects fails with the parser's error, every
+// token fails as unknown without a parser
+func (e *Engine[T]) Evaluate(token string) error {
+	if operator, ok := e.operators[token]; ok {
+		return e.Apply(token, operator)
+	}
+	if e.parse == nil {
+		return UnknownToken(token)
+	}
+	value, err := e.parse(token)
+	if err != nil {
+		return err
+	}
+	e.Push(value)
+	return nil
+}
+
+// EvaluateTokens clears the stack, processes every token and returns the
+// single value left on the stack
+func (e *Engine[T]) EvaluateTokens(tokens []string) (T, error) {
+	e.Clear()
+	var zero T
+
+	for _, token := range tokens {
+		if err := e.Evaluate(token); err != nil {
+			return zero, err
+		}
+	}
+
+	if len(e.stack) != 1 {
+		return zero, Incomplete(len(e.stack))
+	}
+	return e.stack[0], nil
+}
@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateTokensFloat(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"3 4 +", 7},
+		{"10 4 -", 6},
+		{"2 3 4 * +", 14},
+		{"1 4 /", 0.25},
+		{"5 neg", -5},
+		{"1e3 2.5 *", 2500},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			e := New(FloatOperators, ParseFloat)
+			got, err := e.EvaluateTokens(strings.Fields(test.expression))
+			if err != nil || got != test.want {
+				t.Errorf("EvaluateTokens(%q) = %g, %v, want %g", test.expression, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateTokensBool(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"T F AND", false},
+		{"T F OR", true},
+		{"F NOT", true},
+		{"true false NOT AND", true},
+		{"1 0 OR 0 AND", false},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			e := New(BoolOperators, ParseBool)
+			got, err := e.EvaluateTokens(strings.Fields(test.expression))
+			if err != nil || got != test.want {
+				t.Errorf("EvaluateTokens(%q) = %v, %v, want %v", test.expression, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateTokensBigFloat(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"0.1 0.2 +", "0.3"},
+		{"1 3 /", "0.33333333333333333333333333333333333333"},
+		{"123456789012345678901234567890 1 +", "123456789012345678901234567891"},
+		{"2 neg 3 *", "-6"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			e := New(BigFloatOperators, ParseBigFloat)
+			got, err := e.EvaluateTokens(strings.Fields(test.expression))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text := got.Text('f', -1); !strings.HasPrefix(text, test.want) {
+				t.Errorf("EvaluateTokens(%q) = %s, want %s", test.expression, text, test.want)
+			}
+			if got.Prec() != BigFloatPrecision {
+				t.Errorf("precision = %d, want %d", got.Prec(), BigFloatPrecision)
+			}
+		})
+	}
+}
+
+func TestEvaluateTokensErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		target     error
+		want       string
+	}{
+		{"1 +", ErrStackUnderflow, "insufficient operands for + operation"},
+		{"neg", ErrStackUnderflow, "insufficient operands for neg operation"},
+		{"1 2", ErrIncompleteExpression, "invalid expression: expected 1 result, got 2"},
+		{"", ErrIncompleteExpression, "invalid expression: expected 1 result, got 0"},
+		{"1 x +", nil, "unknown token: x"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := New(FloatOperators, ParseFloat).EvaluateTokens(strings.Fields(test.expression))
+			if err == nil || err.Error() != test.want {
+				t.Fatalf("EvaluateTokens(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+			if test.target != nil && !errors.Is(err, test.target) {
+				t.Errorf("error %v is not %v", err, test.target)
+			}
+			var unknown *ErrUnknownToken
+			if isUnknown := errors.As(err, &unknown); isUnknown != (test.target == nil) {
+				t.Errorf("error %v as ErrUnknownToken = %v", err, isUnknown)
+			} else if isUnknown && (unknown.Token != "x" || unknown.Pos != -1) {
+				t.Errorf("ErrUnknownToken = %+v, want token x at -1", unknown)
+			}
+		})
+	}
+}
+
+func TestBigFloatUndefinedResult(t *testing.T) {
+	for _, expression := range []string{"0 0 /", "inf inf -"} {
+		t.Run(expression, func(t *testing.T) {
+			e := New(BigFloatOperators, ParseBigFloat)
+			_, err := e.EvaluateTokens(strings.Fields(expression))
+			if err == nil || !strings.HasPrefix(err.Error(), "undefined result: ") {
+				t.Errorf("EvaluateTokens(%q) error = %v, want an undefined result", expression, err)
+			}
+		})
+	}
+}
+
+func TestFailedOperatorLeavesStack(t *testing.T) {
+	failing := Operator[int]{Arity: 2, Apply: func(operands []int) (int, error) {
+		return 0, fmt.Errorf("cannot combine %d and %d", operands[0], operands[1])
+	}}
+	e := New(map[string]Operator[int]{"fail": failing}, nil)
+	e.Push(1)
+	e.Push(2)
+	e.Push(3)
+	if err := e.Evaluate("fail"); err == nil || err.Error() != "cannot combine 2 and 3" {
+		t.Errorf("Evaluate(fail) error = %v", err)
+	}
+	if got := e.Values(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("stack after a failed operator = %v, want [1 2 3]", got)
+	}
+	// Without a parser every other token is unknown
+	if err := e.Evaluate("4"); err == nil || err.Error() != "unknown token: 4" {
+		t.Errorf("Evaluate(4) error = %v, want unknown token", err)
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	parse := func(token string) (int, error) {
+		if token == "[" {
+			return 0, errors.New("unterminated literal [")
+		}
+		return 0, UnknownToken(token)
+	}
+	e := New(nil, parse)
+	if err := e.Evaluate("["); err == nil || err.Error() != "unterminated literal [" {
+		t.Errorf("Evaluate([) error = %v, want the parser's error", err)
+	}
+	var unknown *ErrUnknownToken
+	if err := e.Evaluate("x"); !errors.As(err, &unknown) || unknown.Token != "x" {
+		t.Errorf("Evaluate(x) error = %v, want unknown token x", err)
+	}
+	if !e.IsEmpty() {
+		t.Errorf("rejected literals pushed %v", e.Values())
+	}
+}
+
+func TestOperatorsShadowLiterals(t *testing.T) {
+	operators := map[string]Operator[float64]{
+		"1": {Arity: 0, Apply: func([]float64) (float64, error) { return 100, nil }},
+	}
+	got, err := New(operators, ParseFloat).EvaluateTokens([]string{"1"})
+	if err != nil || got != 100 {
+		t.Errorf("EvaluateTokens(1) = %g, %v, want the operator's 100", got, err)
+	}
+}
+
+func TestStack(t *testing.T) {
+	e := New[string](nil, nil)
+	if !e.IsEmpty() || e.Size() != 0 {
+		t.Fatalf("new engine holds %v", e.Values())
+	}
+	for _, err := range []error{second(e.Pop()), second(e.Peek())} {
+		if !errors.Is(err, ErrStackUnderflow) || err.Error() != "insufficient operands: stack is empty" {
+			t.Errorf("error on an empty stack = %v", err)
+		}
+	}
+
+	e.Grow(8)
+	e.Push("a")
+	e.Push("b")
+	e.Push("c")
+	if top, err := e.Peek(); err != nil || top != "c" || e.Size() != 3 {
+		t.Errorf("Peek() = %q, %v with size %d, want c with size 3", top, err, e.Size())
+	}
+	values := e.Values()
+	values[0] = "changed"
+	if got := e.Values(); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("Values() = %v, want a copy of [a b c]", got)
+	}
+	if top, err := e.Pop(); err != nil || top != "c" {
+		t.Errorf("Pop() = %q, %v, want c", top, err)
+	}
+
+	e.Truncate(5)
+	if got := e.Values(); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Values() after Truncate(5) = %v, want [a b]", got)
+	}
+	e.Truncate(1)
+	if got := e.Values(); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("Values() after Truncate(1) = %v, want [a]", got)
+	}
+
+	replacement := []string{"x", "y"}
+	e.SetValues(replacement)
+	replacement[0] = "changed"
+	if got := e.Values(); !slices.Equal(got, []string{"x", "y"}) {
+		t.Errorf("Values() after SetValues = %v, want [x y]", got)
+	}
+	e.Clear()
+	if !e.IsEmpty() {
+		t.Errorf("Clear() left %v", e.Values())
+	}
+}
+
+func TestUnderflowUnnamed(t *testing.T) {
+	err := New[float64](nil, nil).Apply("", Binary(func(a, b float64) float64 { return a + b }))
+	if !errors.Is(err, ErrStackUnderflow) || err.Error() != "insufficient operands for operation" {
+		t.Errorf("Apply() error = %v", err)
+	}
+}
+
+// second returns the error of a value and error pair
+func second[T any](_ T, err error) error {
+	return err
+}
+
+func TestParseBigFloatPrecision(t *testing.T) {
+	value, err := ParseBigFloat("0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Prec() != BigFloatPrecision {
+		t.Errorf("Prec() = %d, want %d", value.Prec(), BigFloatPrecision)
+	}
+	if value.Cmp(big.NewFloat(0.1)) == 0 {
+		t.Error("0.1 parsed with float64 precision")
+	}
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStackUnderflow is the error of an operation finding fewer operands on
+// the stack than it takes
+var ErrStackUnderflow = errors.New("insufficient operands")
+
+// ErrIncompleteExpression is the error of an expression leaving other than
+// exactly one value on the stack
+var ErrIncompleteExpression = errors.New("invalid expression")
+
+// ErrUnknownToken is the error of a token that is neither an operator nor a
+// literal. Pos is the byte offset of the token in its expression, -1 when
+// the token did not come from source text
+type ErrUnknownToken struct {
+	Token string
+	Pos   int
+}
+
+// Error names the unknown token
+func (err *ErrUnknownToken) Error() string {
+	return "unknown token: " + err.Token
+}
+
+// Underflow returns an ErrStackUnderflow error for the operation called
+// name, or for an unnamed operation when name is empty
+func Underflow(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w for operation", ErrStackUnderflow)
+	}
+	return fmt.Errorf("%w for %s operation", ErrStackUnderflow, name)
+}
+
+// Incomplete returns an ErrIncompleteExpression error for an expression
+// leaving results values on the stack
+func Incomplete(results int) error {
+	return fmt.Errorf("%w: expected 1 result, got %d", ErrIncompleteExpression, results)
+}
+
+// UnknownToken returns an ErrUnknownToken error for a token without a
+// position
+func UnknownToken(token string) error {
+	return &ErrUnknownToken{Token: token, Pos: -1}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// FloatOperators is the arithmetic operator table for float64 operands
+var FloatOperators = Operators[float64]{
+	"+":   Binary(func(a, b float64) float64 { return a + b }),
+	"-":   Binary(func(a, b float64) float64 { return a - b }),
+	"*":   Binary(func(a, b float64) float64 { return a * b }),
+	"/":   Binary(func(a, b float64) float64 { return a / b }),
+	"neg": Unary(func(a float64) float64 { return -a }),
+}
+
+// ParseFloat parses a float64 literal
+func ParseFloat(token string) (float64, error) {
+	value, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return 0, UnknownToken(token)
+	}
+	return value, nil
+}
+
+// BoolOperators is the boolean operator table used by query evaluation
+var BoolOperators = Operators[bool]{
+	"AND": Binary(func(a, b bool) bool { return a && b }),
+	"OR":  Binary(func(a, b bool) bool { return a || b }),
+	"NOT": Unary(func(a bool) bool { return !a }),
+}
+
+// ParseBool parses a boolean literal, T and F included
+func ParseBool(token string) (bool, error) {
+	value, err := strconv.ParseBool(token)
+	if err != nil {
+		return false, UnknownToken(token)
+	}
+	return value, nil
+}
+
+// BigFloatPrecision is the mantissa precision in bits of parsed big.Float
+// literals and operator results
+const BigFloatPrecision = 256
+
+// BigFloatOperators is the arithmetic operator table for arbitrary
+// precision operands. Operations without a defined result, like 0/0 or
+// the difference of equal infinities, fail instead of panicking
+var BigFloatOperators = Operators[*big.Float]{
+	"+":   bigBinary((*big.Float).Add),
+	"-":   bigBinary((*big.Float).Sub),
+	"*":   bigBinary((*big.Float).Mul),
+	"/":   bigBinary((*big.Float).Quo),
+	"neg": Unary(func(a *big.Float) *big.Float { return new(big.Float).SetPrec(BigFloatPrecision).Neg(a) }),
+}
+
+// bigBinary adapts a big.Float method into an Operator writing a new value
+func bigBinary(method func(z, x, y *big.Float) *big.Float) Operator[*big.Float] {
+	return Operator[*big.Float]{Arity: 2, Apply: func(operands []*big.Float) (result *big.Float, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				nan, ok := recovered.(big.ErrNaN)
+				if !ok {
+					panic(recovered)
+				}
+				result, err = nil, fmt.Errorf("undefined result: %s", nan.Error())
+			}
+		}()
+		return method(new(big.Float).SetPrec(BigFloatPrecision), operands[0], operands[1]), nil
+	}}
+}
+
+// ParseBigFloat parses an arbitrary precision literal
+func ParseBigFloat(token string) (*big.Float, error) {
+	value, _, err := big.ParseFloat(token, 10, BigFloatPrecision, big.ToNearestEven)
+	if err != nil {
+		return nil, UnknownToken(token)
+	}
+	return value, nil
+}
@@ -0,0 +1,121 @@
+package number
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// StackAnalysis describes how an RPN token sequence uses the stack
+type StackAnalysis struct {
+	// MaxDepth is the deepest the stack gets during evaluation
+	MaxDepth int
+	// FinalDepth is the number of values left once every token has run
+	FinalDepth int
+}
+
+// Balanced reports whether the expression leaves exactly one result
+func (analysis StackAnalysis) Balanced() bool {
+	return analysis.FinalDepth == 1
+}
+
+// AnalyzeStack statically computes the exact stack usage of RPN tokens
+// without evaluating them, rejecting tokens that would underflow the stack
+func AnalyzeStack(tokens []string) (StackAnalysis, error) {
+	analysis, _, err := analyzeStack(tokens)
+	return analysis, err
+}
+
+// analyzeStack computes the stack usage of RPN tokens, also returning the
+// index of the token an error refers to
+func analyzeStack(tokens []string) (StackAnalysis, int, error) {
+	analysis := StackAnalysis{}
+	depth := 0
+
+	for i, token := range tokens {
+		pops, pushes, err := stackEffect(tokens, i, depth)
+		if err != nil {
+			return StackAnalysis{}, i, err
+		}
+		if depth < pops {
+			return StackAnalysis{}, i, engine.Underflow(token)
+		}
+		depth += pushes - pops
+		analysis.MaxDepth = max(analysis.MaxDepth, depth)
+	}
+
+	analysis.FinalDepth = depth
+	return analysis, 0, nil
+}
+
+// stackEffect returns how many values the token at index pops and pushes
+func stackEffect(tokens []string, index, depth int) (int, int, error) {
+	token := tokens[index]
+
+	if _, ok := ternaryOperators[token]; ok {
+		return 3, 1, nil
+	}
+	if _, ok := binaryOperators[token]; ok {
+		return 2, 1, nil
+	}
+	if _, ok := checkedBinaryOperators[token]; ok {
+		return 2, 1, nil
+	}
+	if _, ok := unaryOperators[token]; ok {
+		return 1, 1, nil
+	}
+	if _, ok := checkedUnaryOperators[token]; ok {
+		return 1, 1, nil
+	}
+	if _, ok := displayBases[token]; ok {
+		return 0, 0, nil
+	}
+	if token == "rand" {
+		return 0, 1, nil
+	}
+	if token == "randint" {
+		return 2, 1, nil
+	}
+	if operator, ok := pairOperators[token]; ok {
+		return operator.arity, 2, nil
+	}
+	if operator, ok := functions[token]; ok && operator.Arity != VariadicArity {
+		return operator.Arity, 1, nil
+	}
+	if token == labelToken {
+		if index+1 == len(tokens) {
+			return 1, 1, fmt.Errorf("%s requires a name", labelToken)
+		}
+		if err := checkLabel(tokens[index+1]); err != nil {
+			return 1, 1, err
+		}
+		return 1, 1, nil
+	}
+	if index > 0 && tokens[index-1] == labelToken {
+		// The name of a label
+		return 0, 0, nil
+	}
+
+	_, function := functions[token]
+	if name, counted, ok := lookupReducer(token); ok || function {
+		if ok && !counted {
+			return max(depth, minReducerOperands[name]), 1, nil
+		}
+		// The count of a counted reducer or variadic function is only known
+		// statically when it is written as a literal right before the token
+		if index == 0 {
+			return 1, 1, engine.Underflow(token)
+		}
+		count, err := parseNumber(tokens[index-1])
+		if err != nil || count != math.Trunc(count) || count < 0 {
+			return 0, 0, fmt.Errorf("stack effect of %s depends on a runtime count", token)
+		}
+		return int(count) + 1, 1, nil
+	}
+
+	if _, err := parseNumber(token); err == nil || isIdentifier(token) {
+		return 0, 1, nil
+	}
+	return 0, 0, engine.UnknownToken(token)
+}
@@ -0,0 +1,97 @@
+package number
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeStack(t *testing.T) {
+	tests := []struct {
+		expression string
+		maxDepth   int
+		finalDepth int
+	}{
+		{"3 4 +", 2, 1},
+		{"5 1 2 + 4 * + 3 -", 3, 1},
+		{"1 2 3 4 5 + + + +", 5, 1},
+		{"1 2 3 if", 3, 1},
+		{"9 sqrt neg", 1, 1},
+		{"1 2", 2, 2},
+		{"1 2 3 sum", 3, 1},
+		{"1 2 3 2 sumn", 4, 2},
+		{"2 as x x *", 2, 1},
+		{"5 hex", 1, 1},
+		{"x y +", 2, 1},
+		{"", 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			tokens := strings.Fields(test.expression)
+			analysis, err := AnalyzeStack(tokens)
+			if err != nil {
+				t.Fatalf("AnalyzeStack(%q): %v", test.expression, err)
+			}
+			if analysis.MaxDepth != test.maxDepth || analysis.FinalDepth != test.finalDepth {
+				t.Errorf("AnalyzeStack(%q) = %+v, want MaxDepth %d and FinalDepth %d", test.expression, analysis, test.maxDepth, test.finalDepth)
+			}
+			if got := analysis.Balanced(); got != (test.finalDepth == 1) {
+				t.Errorf("Balanced() = %v", got)
+			}
+		})
+	}
+}
+
+// TestAnalyzeStackMatchesEvaluation runs expressions on a calculator token
+// by token, whose deepest stack must be the statically computed MaxDepth
+func TestAnalyzeStackMatchesEvaluation(t *testing.T) {
+	expressions := []string{
+		"5 1 2 + 4 * + 3 -",
+		"1 2 3 4 5 + + + +",
+		"1 2 + 3 4 + *",
+		"0 10 20 if",
+		"1 2 3 2 sumn +",
+		"4 9 16 mean sqrt",
+		"5 2 nCr 3 ! +",
+	}
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			analysis, err := AnalyzeStack(strings.Fields(expression))
+			if err != nil {
+				t.Fatal(err)
+			}
+			calc := NewRPNCalculator()
+			deepest := 0
+			for _, token := range strings.Fields(expression) {
+				if err := calc.Evaluate(token); err != nil {
+					t.Fatal(err)
+				}
+				deepest = max(deepest, calc.Size())
+			}
+			if analysis.MaxDepth != deepest || analysis.FinalDepth != calc.Size() {
+				t.Errorf("AnalyzeStack() = %+v, evaluation reached depth %d and left %d values", analysis, deepest, calc.Size())
+			}
+		})
+	}
+}
+
+func TestAnalyzeStackErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"+", "insufficient operands for + operation"},
+		{"1 2 + *", "insufficient operands for * operation"},
+		{"1 2 if", "insufficient operands for if operation"},
+		{"1 $", "unknown token: $"},
+		{"1 2 n sumn", "stack effect of sumn depends on a runtime count"},
+		{"1 as", "as requires a name"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := AnalyzeStack(strings.Fields(test.expression))
+			if err == nil || err.Error() != test.want {
+				t.Errorf("AnalyzeStack(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package number
+
+import (
+	"math"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// AngleMode selects the unit of the angles the trigonometric functions of
+// the calculator take and return. Compiled programs and expression trees
+// always work in radians
+type AngleMode int
+
+const (
+	// Radians measures angles in radians, 1 1 atan2 leaves 0.785...
+	Radians AngleMode = iota
+	// Degrees measures angles in degrees, 1 1 atan2 leaves 45
+	Degrees
+)
+
+// angleArguments lists the functions taking an angle as their operand
+var angleArguments = map[string]bool{"sin": true, "cos": true, "tan": true}
+
+// angleResults lists the functions returning an angle
+var angleResults = map[string]bool{"asin": true, "acos": true, "atan": true, "atan2": true}
+
+// SetAngleMode sets the unit of the angles trigonometric functions use
+func (calc *RPNCalculator) SetAngleMode(mode AngleMode) {
+	calc.angleMode = mode
+}
+
+// WithAngleMode sets the unit of the angles trigonometric functions use
+func WithAngleMode(mode AngleMode) Option {
+	return func(calc *RPNCalculator) { calc.SetAngleMode(mode) }
+}
+
+// inAngleMode adapts a function working in radians to the calculator's
+// angle mode
+func (calc *RPNCalculator) inAngleMode(name string, operator engine.Operator[float64]) engine.Operator[float64] {
+	if calc.angleMode != Degrees || !angleArguments[name] && !angleResults[name] {
+		return operator
+	}
+	apply := operator.Apply
+	operator.Apply = func(operands []float64) (float64, error) {
+		if angleArguments[name] {
+			operands = []float64{operands[0] * math.Pi / 180}
+		}
+		result, err := apply(operands)
+		if angleResults[name] {
+			result *= 180 / math.Pi
+		}
+		return result, err
+	}
+	return operator
+}
@@ -0,0 +1,103 @@
+package number
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ProgramCache is a least recently used cache of compiled programs keyed
+// by their source, for services evaluating the same formulas over and over.
+// Programs are immutable, so a cached program can be shared by goroutines.
+// It is safe for concurrent use
+type ProgramCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	// order holds the cached programs, most recently used first
+	order *list.List
+	stats CacheStats
+}
+
+// CacheStats counts the lookups of a ProgramCache
+type CacheStats struct {
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// HitRate returns the fraction of lookups served from the cache, zero
+// before the first lookup
+func (stats CacheStats) HitRate() float64 {
+	lookups := stats.Hits + stats.Misses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(lookups)
+}
+
+// NewProgramCache creates a cache holding up to capacity programs, at
+// least one
+func NewProgramCache(capacity int) *ProgramCache {
+	return &ProgramCache{
+		capacity: max(capacity, 1),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Compile returns the cached program of an expression, compiling and
+// caching it on a miss. Expressions failing to compile are not cached
+func (cache *ProgramCache) Compile(expression string) (*Program, error) {
+	cache.mu.Lock()
+	if element, ok := cache.entries[expression]; ok {
+		cache.order.MoveToFront(element)
+		cache.stats.Hits++
+		cache.mu.Unlock()
+		return element.Value.(*Program), nil
+	}
+	cache.stats.Misses++
+	cache.mu.Unlock()
+
+	// Compile without the lock so a slow expression does not hold up
+	// lookups of cached ones
+	prog, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if element, ok := cache.entries[expression]; ok {
+		// Another goroutine compiled it meanwhile
+		cache.order.MoveToFront(element)
+		return element.Value.(*Program), nil
+	}
+	cache.entries[expression] = cache.order.PushFront(prog)
+	if cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*Program).source)
+		cache.stats.Evictions++
+	}
+	return prog, nil
+}
+
+// Stats returns the size of the cache and its lookup counts
+func (cache *ProgramCache) Stats() CacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	stats := cache.stats
+	stats.Size = cache.order.Len()
+	stats.Capacity = cache.capacity
+	return stats
+}
+
+// Purge empties the cache, keeping its counts
+func (cache *ProgramCache) Purge() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.entries = make(map[string]*list.Element)
+	cache.order.Init()
+}
@@ -0,0 +1,114 @@
+package number
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestProgramCache(t *testing.T) {
+	// Every step compiles an expression on a cache of two programs
+	tests := []struct {
+		expression string
+		hit        bool
+		want       CacheStats
+	}{
+		{"1 2 +", false, CacheStats{Size: 1, Capacity: 2, Misses: 1}},
+		{"1 2 +", true, CacheStats{Size: 1, Capacity: 2, Hits: 1, Misses: 1}},
+		{"3 4 *", false, CacheStats{Size: 2, Capacity: 2, Hits: 1, Misses: 2}},
+		// 1 2 + is used more recently than 3 4 *, which is evicted
+		{"1 2 +", true, CacheStats{Size: 2, Capacity: 2, Hits: 2, Misses: 2}},
+		{"5 6 -", false, CacheStats{Size: 2, Capacity: 2, Hits: 2, Misses: 3, Evictions: 1}},
+		{"1 2 +", true, CacheStats{Size: 2, Capacity: 2, Hits: 3, Misses: 3, Evictions: 1}},
+		{"3 4 *", false, CacheStats{Size: 2, Capacity: 2, Hits: 3, Misses: 4, Evictions: 2}},
+		// Failing expressions are counted but not cached
+		{"1 +", false, CacheStats{Size: 2, Capacity: 2, Hits: 3, Misses: 5, Evictions: 2}},
+		{"1 +", false, CacheStats{Size: 2, Capacity: 2, Hits: 3, Misses: 6, Evictions: 2}},
+		{"1 2 +", true, CacheStats{Size: 2, Capacity: 2, Hits: 4, Misses: 6, Evictions: 2}},
+	}
+	cache := NewProgramCache(2)
+	programs := map[string]*Program{}
+	for i, test := range tests {
+		prog, err := cache.Compile(test.expression)
+		if err == nil {
+			if prog.String() != test.expression {
+				t.Errorf("step %d: Compile(%q) = %q", i, test.expression, prog.String())
+			}
+			if cached := programs[test.expression]; (cached == prog) != test.hit {
+				t.Errorf("step %d: Compile(%q) hit = %t, want %t", i, test.expression, cached == prog, test.hit)
+			}
+			programs[test.expression] = prog
+		}
+		if got := cache.Stats(); got != test.want {
+			t.Errorf("step %d: Stats() = %+v, want %+v", i, got, test.want)
+		}
+	}
+
+	cache.Purge()
+	if got := cache.Stats(); got.Size != 0 || got.Hits != 4 || got.Misses != 6 {
+		t.Errorf("after Purge, Stats() = %+v", got)
+	}
+	if prog, err := cache.Compile("1 2 +"); err != nil || prog == programs["1 2 +"] {
+		t.Errorf("after Purge, Compile() = %p, %v, want a new program", prog, err)
+	}
+}
+
+func TestCacheStatsHitRate(t *testing.T) {
+	tests := []struct {
+		stats CacheStats
+		want  float64
+	}{
+		{CacheStats{}, 0},
+		{CacheStats{Misses: 4}, 0},
+		{CacheStats{Hits: 3, Misses: 1}, 0.75},
+		{CacheStats{Hits: 2}, 1},
+	}
+	for _, test := range tests {
+		if got := test.stats.HitRate(); got != test.want {
+			t.Errorf("%+v.HitRate() = %g, want %g", test.stats, got, test.want)
+		}
+	}
+}
+
+func TestProgramCacheCapacity(t *testing.T) {
+	for _, capacity := range []int{-1, 0, 1} {
+		cache := NewProgramCache(capacity)
+		for _, expression := range []string{"1", "2", "3"} {
+			if _, err := cache.Compile(expression); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := cache.Stats(); got.Capacity != 1 || got.Size != 1 || got.Evictions != 2 {
+			t.Errorf("NewProgramCache(%d) Stats() = %+v", capacity, got)
+		}
+	}
+}
+
+// TestConcurrentProgramCache compiles overlapping expressions from many
+// goroutines, which the race detector checks
+func TestConcurrentProgramCache(t *testing.T) {
+	cache := NewProgramCache(4)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				expression := fmt.Sprintf("%d 2 *", (i+j)%6)
+				prog, err := cache.Compile(expression)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got, err := prog.Eval(); err != nil || got != float64((i+j)%6*2) {
+					t.Errorf("%s = %g, %v", expression, got, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := cache.Stats(); got.Size != 4 || got.Hits+got.Misses != 800 {
+		t.Errorf("Stats() = %+v", got)
+	}
+}
@@ -0,0 +1,472 @@
+package number
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GoOptions controls the Go source GenerateGo writes
+type GoOptions struct {
+	// Package is the package clause of the file, "formulas" when empty
+	Package string
+	// Func is the name of the generated function, "Eval" when empty
+	Func string
+	// Params orders the float64 parameters of the function, one per
+	// variable of the expression. Empty orders them by first use
+	Params []string
+}
+
+// goFunctions maps the operators that are calls in Go to the function
+// generated code calls
+var goFunctions = map[string]string{
+	"mod":    "math.Mod",
+	"min":    "math.Min",
+	"max":    "math.Max",
+	"round":  "math.Round",
+	"floor":  "math.Floor",
+	"ceil":   "math.Ceil",
+	"trunc":  "math.Trunc",
+	"sqrt":   "math.Sqrt",
+	"sin":    "math.Sin",
+	"cos":    "math.Cos",
+	"tan":    "math.Tan",
+	"asin":   "math.Asin",
+	"acos":   "math.Acos",
+	"atan":   "math.Atan",
+	"atan2":  "math.Atan2",
+	"hypot":  "math.Hypot",
+	"clamp":  "rpnClamp",
+	"^":      "rpnPower",
+	"**":     "rpnPower",
+	"if":     "rpnSelect",
+	"select": "rpnSelect",
+	"!":      "rpnFactorial",
+	"nCr":    "rpnCombinations",
+	"nPr":    "rpnPermutations",
+	"roundN": "rpnRoundN",
+	"gcd":    "rpnGCD",
+	"lcm":    "rpnLCM",
+}
+
+// goHelpers holds the source of the helpers generated code calls for
+// operators Go has no equivalent of. They are declared inside the
+// generated function, so several generated files can share a package.
+// Generated functions cannot fail, so the helpers of operators rejecting
+// their operands return NaN where the calculator reports an error
+var goHelpers = map[string]string{
+	"rpnPower": `// rpnPower raises a to the integer part of b
+rpnPower := func(a, b float64) float64 {
+	result := 1.0
+	for i := 0; i < int(b); i++ {
+		result *= a
+	}
+	return result
+}`,
+	"rpnBool": `// rpnBool converts a comparison outcome to 1 or 0
+rpnBool := func(condition bool) float64 {
+	if condition {
+		return 1
+	}
+	return 0
+}`,
+	"rpnSelect": `// rpnSelect returns whenTrue if condition is nonzero and whenFalse otherwise
+rpnSelect := func(condition, whenTrue, whenFalse float64) float64 {
+	if condition != 0 {
+		return whenTrue
+	}
+	return whenFalse
+}`,
+	"rpnFactorial": `// rpnFactorial computes n!, NaN unless n is an integer in [0, 170]
+rpnFactorial := func(n float64) float64 {
+	if n != math.Trunc(n) || n < 0 || n > 170 {
+		return math.NaN()
+	}
+	result, _ := new(big.Float).SetInt(new(big.Int).MulRange(1, int64(n))).Float64()
+	return result
+}`,
+	"rpnCombinations": `// rpnCombinations computes n choose r, NaN unless 0 <= r <= n are
+// integers and the result fits a float64
+rpnCombinations := func(n, r float64) float64 {
+	if n != math.Trunc(n) || r != math.Trunc(r) || r < 0 || r > n || n > math.MaxInt32 || min(r, n-r) > 1024 {
+		return math.NaN()
+	}
+	result, _ := new(big.Float).SetInt(new(big.Int).Binomial(int64(n), int64(r))).Float64()
+	if math.IsInf(result, 0) {
+		return math.NaN()
+	}
+	return result
+}`,
+	"rpnPermutations": `// rpnPermutations computes the ordered selections of r items from n, NaN
+// unless 0 <= r <= n are integers and the result fits a float64
+rpnPermutations := func(n, r float64) float64 {
+	if n != math.Trunc(n) || r != math.Trunc(r) || r < 0 || r > n || n > math.MaxInt32 || r > 170 {
+		return math.NaN()
+	}
+	if r == 0 {
+		return 1
+	}
+	result, _ := new(big.Float).SetInt(new(big.Int).MulRange(int64(n-r)+1, int64(n))).Float64()
+	if math.IsInf(result, 0) {
+		return math.NaN()
+	}
+	return result
+}`,
+	"rpnRoundN": `// rpnRoundN rounds value to places decimal places with ties away from
+// zero, NaN unless places is an integer in [-15, 15]
+rpnRoundN := func(value, places float64) float64 {
+	if places != math.Trunc(places) || places < -15 || places > 15 {
+		return math.NaN()
+	}
+	scale := math.Pow(10, places)
+	return math.Round(value*scale) / scale
+}`,
+	"rpnGCD": `// rpnGCD computes the greatest common divisor, NaN unless a and b are
+// integers within 2^53
+rpnGCD := func(a, b float64) float64 {
+	if a != math.Trunc(a) || b != math.Trunc(b) || math.Abs(a) > 1<<53 || math.Abs(b) > 1<<53 {
+		return math.NaN()
+	}
+	x, y := int64(math.Abs(a)), int64(math.Abs(b))
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return float64(x)
+}`,
+	"rpnLCM": `// rpnLCM computes the least common multiple, NaN unless a and b are
+// integers within 2^53 and so is the result
+rpnLCM := func(a, b float64) float64 {
+	divisor := rpnGCD(a, b)
+	if math.IsNaN(divisor) {
+		return divisor
+	}
+	if divisor == 0 {
+		return 0
+	}
+	x, y := int64(math.Abs(a))/int64(divisor), int64(math.Abs(b))
+	if y != 0 && x > (1<<53)/y {
+		return math.NaN()
+	}
+	return float64(x * y)
+}`,
+	"rpnClamp": `// rpnClamp limits value to [lo, hi], NaN when lo is above hi
+rpnClamp := func(value, lo, hi float64) float64 {
+	if lo > hi {
+		return math.NaN()
+	}
+	return math.Min(math.Max(value, lo), hi)
+}`,
+}
+
+// goHelperOrder is the order generated functions declare the helpers in
+var goHelperOrder = []string{"rpnPower", "rpnBool", "rpnSelect", "rpnFactorial", "rpnCombinations", "rpnPermutations", "rpnRoundN", "rpnGCD", "rpnLCM", "rpnClamp"}
+
+// goHelperImports lists the packages the helpers use
+var goHelperImports = map[string][]string{
+	"rpnFactorial":    {"math", "math/big"},
+	"rpnCombinations": {"math", "math/big"},
+	"rpnPermutations": {"math", "math/big"},
+	"rpnRoundN":       {"math"},
+	"rpnGCD":          {"math"},
+	"rpnLCM":          {"math"},
+	"rpnClamp":        {"math"},
+}
+
+// goHelperDependencies lists the helpers a helper calls
+var goHelperDependencies = map[string][]string{
+	"rpnLCM": {"rpnGCD"},
+}
+
+// goComparisons lists the comparison operators, which yield 1 or 0
+var goComparisons = map[string]bool{"<": true, ">": true, "<=": true, ">=": true, "==": true, "!=": true}
+
+// GenerateGo writes a Go source file with a function computing the
+// expression from float64 parameters, so a formula can be compiled into a
+// binary. The function gives the same results as evaluating the expression:
+// constant subexpressions are evaluated here rather than left to the Go
+// compiler, whose exact constant arithmetic would round differently
+func GenerateGo(expr Expr, opts GoOptions) (string, error) {
+	pkg, name := opts.Package, opts.Func
+	if pkg == "" {
+		pkg = "formulas"
+	}
+	if name == "" {
+		name = "Eval"
+	}
+	for _, identifier := range []string{pkg, name} {
+		if err := checkGoName(identifier); err != nil {
+			return "", err
+		}
+	}
+
+	params := opts.Params
+	if len(params) == 0 {
+		params = exprVariables(expr)
+	}
+	declared := map[string]bool{}
+	for _, param := range params {
+		if err := checkGoName(param); err != nil {
+			return "", err
+		}
+		if declared[param] {
+			return "", fmt.Errorf("duplicate parameter %s", param)
+		}
+		declared[param] = true
+	}
+	for _, variable := range exprVariables(expr) {
+		if !declared[variable] {
+			return "", fmt.Errorf("variable %s is not a parameter", variable)
+		}
+	}
+
+	gen := &goGenerator{imports: map[string]bool{}, helpers: map[string]bool{}}
+	body, err := gen.expr(expr)
+	if err != nil {
+		return "", err
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by go-rpn from %q. DO NOT EDIT.\n\n", strings.Join(expr.ToRPN(), " "))
+	fmt.Fprintf(&src, "package %s\n\n", pkg)
+	if len(gen.imports) > 0 {
+		src.WriteString("import (\n")
+		for _, path := range slices.Sorted(maps.Keys(gen.imports)) {
+			fmt.Fprintf(&src, "%q\n", path)
+		}
+		src.WriteString(")\n\n")
+	}
+	fmt.Fprintf(&src, "// %s computes %s\n", name, strings.Join(expr.ToRPN(), " "))
+	signature := ""
+	if len(params) > 0 {
+		signature = strings.Join(params, ", ") + " float64"
+	}
+	fmt.Fprintf(&src, "func %s(%s) float64 {\n", name, signature)
+	for _, helper := range goHelperOrder {
+		if gen.helpers[helper] {
+			src.WriteString(goHelpers[helper] + "\n")
+		}
+	}
+	fmt.Fprintf(&src, "return %s\n}\n", body)
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("generated invalid Go: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// GenerateGo writes a Go source file computing the program, as it runs
+// after Optimize
+func (prog *Program) GenerateGo(opts GoOptions) (string, error) {
+	expr, err := parseRPNTokens("", tokenValues(prog.Tokens()))
+	if err != nil {
+		return "", err
+	}
+	if len(opts.Params) == 0 {
+		// Keep every variable of the source as a parameter, even one the
+		// optimizer removed, so the signature does not depend on it
+		opts.Params = prog.Variables()
+	}
+	return GenerateGo(expr, opts)
+}
+
+// checkGoName reports an error unless name can be declared in the
+// generated file without clashing with Go or the names it uses. The
+// predeclared identifiers are reserved too, as the helpers call float64,
+// int64, min and new
+func checkGoName(name string) error {
+	if !token.IsIdentifier(name) || token.IsKeyword(name) {
+		return fmt.Errorf("%s is not a valid Go identifier", name)
+	}
+	if _, ok := goHelpers[name]; ok || name == "math" || name == "big" || types.Universe.Lookup(name) != nil {
+		return fmt.Errorf("%s is reserved in generated Go", name)
+	}
+	return nil
+}
+
+// exprVariables returns the names of the variables an expression reads, in
+// order of first use
+func exprVariables(expr Expr) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	Inspect(expr, func(node Expr) bool {
+		if v, ok := node.(*Variable); ok && !seen[v.Name] {
+			seen[v.Name] = true
+			names = append(names, v.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// goGenerator renders expressions as Go, recording the packages and
+// helpers the file needs
+type goGenerator struct {
+	imports map[string]bool
+	helpers map[string]bool
+}
+
+// expr renders an expression as a Go float64 expression
+func (gen *goGenerator) expr(expr Expr) (string, error) {
+	if len(exprVariables(expr)) == 0 {
+		value, err := expr.Eval()
+		if err != nil {
+			return "", err
+		}
+		return gen.literal(value), nil
+	}
+
+	switch node := expr.(type) {
+	case *Variable:
+		return node.Name, nil
+	case *UnaryExpr:
+		if node.Op == "neg" {
+			operand, err := gen.operand(node.Operand, goUnaryPrecedence, true)
+			if err != nil {
+				return "", err
+			}
+			return "-" + operand, nil
+		}
+		operand, err := gen.expr(node.Operand)
+		if err != nil {
+			return "", err
+		}
+		return gen.call(node.Op, operand)
+	case *BinaryExpr:
+		precedence, infix := goPrecedence[node.Op]
+		if !infix && !goComparisons[node.Op] {
+			left, err := gen.expr(node.Left)
+			if err != nil {
+				return "", err
+			}
+			right, err := gen.expr(node.Right)
+			if err != nil {
+				return "", err
+			}
+			return gen.call(node.Op, left, right)
+		}
+		if !infix {
+			// Comparisons bind looser than arithmetic, so their operands
+			// never need parentheses
+			precedence = goComparePrecedence
+		}
+		left, err := gen.operand(node.Left, precedence, false)
+		if err != nil {
+			return "", err
+		}
+		right, err := gen.operand(node.Right, precedence, true)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case node.Op == "%":
+			return left + " * " + right + " / 100", nil
+		case infix:
+			return left + " " + node.Op + " " + right, nil
+		}
+		gen.helpers["rpnBool"] = true
+		return "rpnBool(" + left + " " + node.Op + " " + right + ")", nil
+	case *CallExpr:
+		args := make([]string, len(node.Args))
+		for i, arg := range node.Args {
+			rendered, err := gen.expr(arg)
+			if err != nil {
+				return "", err
+			}
+			args[i] = rendered
+		}
+		return gen.call(node.Func, args...)
+	}
+	return "", fmt.Errorf("cannot generate Go for %s", strings.Join(expr.ToRPN(), " "))
+}
+
+// goPrecedence holds the Go precedence of the operators rendered between
+// their operands, % rendering as a product
+var goPrecedence = map[string]int{"+": 4, "-": 4, "*": 5, "/": 5, "%": 5}
+
+// goComparePrecedence and goUnaryPrecedence are the Go precedences of
+// comparisons and of unary minus
+const (
+	goComparePrecedence = 3
+	goUnaryPrecedence   = 6
+)
+
+// operand renders an operand of an operator with the given precedence,
+// parenthesized when it binds looser, or as loosely on the right since
+// floating point operations do not associate
+func (gen *goGenerator) operand(expr Expr, precedence int, right bool) (string, error) {
+	rendered, err := gen.expr(expr)
+	if err != nil {
+		return "", err
+	}
+	operandPrecedence := goUnaryPrecedence + 1
+	switch node := expr.(type) {
+	case *BinaryExpr:
+		if p, ok := goPrecedence[node.Op]; ok && len(exprVariables(expr)) > 0 {
+			operandPrecedence = p
+		}
+	case *UnaryExpr:
+		if node.Op == "neg" {
+			operandPrecedence = goUnaryPrecedence
+		}
+	}
+	if strings.HasPrefix(rendered, "-") {
+		operandPrecedence = min(operandPrecedence, goUnaryPrecedence)
+	}
+	if operandPrecedence < precedence || (right && operandPrecedence == precedence) {
+		return "(" + rendered + ")", nil
+	}
+	return rendered, nil
+}
+
+// call renders an operator that is a function call in Go
+func (gen *goGenerator) call(op string, args ...string) (string, error) {
+	function, ok := goFunctions[op]
+	if !ok {
+		return "", fmt.Errorf("cannot generate Go for operator %s", op)
+	}
+	if strings.HasPrefix(function, "math.") {
+		gen.imports["math"] = true
+	} else {
+		gen.helper(function)
+	}
+	return function + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// helper declares a helper in the generated function, with the helpers
+// and packages it uses
+func (gen *goGenerator) helper(name string) {
+	gen.helpers[name] = true
+	for _, path := range goHelperImports[name] {
+		gen.imports[path] = true
+	}
+	for _, dependency := range goHelperDependencies[name] {
+		gen.helper(dependency)
+	}
+}
+
+// literal renders a constant, with the values Go has no literal for as
+// calls into math
+func (gen *goGenerator) literal(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		gen.imports["math"] = true
+		return "math.NaN()"
+	case math.IsInf(value, 1):
+		gen.imports["math"] = true
+		return "math.Inf(1)"
+	case math.IsInf(value, -1):
+		gen.imports["math"] = true
+		return "math.Inf(-1)"
+	case value == 0 && math.Signbit(value):
+		gen.imports["math"] = true
+		return "math.Copysign(0, -1)"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,165 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGo(t *testing.T) {
+	expr, err := ParseRPN("x 2 3 + * y <")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// Code generated by go-rpn from "x 2 3 + * y <". DO NOT EDIT.
+
+package formulas
+
+// Eval computes x 2 3 + * y <
+func Eval(x, y float64) float64 {
+	// rpnBool converts a comparison outcome to 1 or 0
+	rpnBool := func(condition bool) float64 {
+		if condition {
+			return 1
+		}
+		return 0
+	}
+	return rpnBool(x*5 < y)
+}
+`
+	if got, err := GenerateGo(expr, GoOptions{}); err != nil || got != want {
+		t.Errorf("GenerateGo() = %s, %v, want %s", got, err, want)
+	}
+}
+
+func TestGenerateGoErrors(t *testing.T) {
+	tests := []struct {
+		rpn     string
+		opts    GoOptions
+		wantErr string
+	}{
+		{"x", GoOptions{Func: "func"}, "func is not a valid Go identifier"},
+		{"x", GoOptions{Package: "1a"}, "1a is not a valid Go identifier"},
+		{"x", GoOptions{Params: []string{"y"}}, "variable x is not a parameter"},
+		{"x y +", GoOptions{Params: []string{"x", "x", "y"}}, "duplicate parameter x"},
+		{"math 1 +", GoOptions{}, "math is reserved in generated Go"},
+		{"x rpnGCD +", GoOptions{}, "rpnGCD is reserved in generated Go"},
+		{"int64 4 gcd", GoOptions{}, "int64 is reserved in generated Go"},
+		{"x", GoOptions{Func: "float64"}, "float64 is reserved in generated Go"},
+		{"-1 ! x +", GoOptions{}, "! requires a non-negative integer, got -1"},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := GenerateGo(expr, test.opts); err == nil || err.Error() != test.wantErr {
+				t.Errorf("GenerateGo() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// TestGeneratedGoMatchesProgram builds the generated functions and checks
+// that they compute what the programs evaluate to
+func TestGeneratedGoMatchesProgram(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a Go program")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	args := map[string]float64{"x": 2.5, "y": -4, "n": 6}
+	tests := []string{
+		"x y + x y - *",
+		"x y - y -",
+		"x y y - -",
+		"x neg y *",
+		"x y neg -",
+		"1 3 / x *",
+		"0.1 0.2 + x +",
+		"x 1 0 / *",
+		"x 0 0 / +",
+		"x -0 *",
+		"x 10 % y +",
+		"x 2 ^ y 3 ** +",
+		"x y < x y >= +",
+		"x y == x x != +",
+		"x y < 10 20 if",
+		"x y mod y x min + x y max +",
+		"x sqrt floor x ceil round + x trunc +",
+		"x sin x cos + x tan + y x atan2 + x y hypot +",
+		"n ! n 2 nCr + n 3 nPr +",
+		"x ! n 0.5 nCr +",
+		"x 1 roundN n 20 roundN +",
+		"n 4 gcd n 4 lcm + x 4 gcd +",
+		"n 3 5 clamp x 0 1 clamp +",
+		"x 1 0 clamp",
+	}
+
+	dir := t.TempDir()
+	var main strings.Builder
+	main.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"strconv\"\n)\n\nfunc main() {\n")
+	want := make([]string, len(tests))
+	for i, rpn := range tests {
+		prog, err := Compile(rpn)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", rpn, err)
+		}
+		value, err := prog.EvalWith(args)
+		if err != nil {
+			// Generated functions cannot fail and return NaN instead
+			value = math.NaN()
+		}
+		want[i] = strconv.FormatFloat(value, 'g', -1, 64)
+
+		name := fmt.Sprintf("F%d", i)
+		src, err := prog.GenerateGo(GoOptions{Package: "main", Func: name})
+		if err != nil {
+			t.Fatalf("GenerateGo(%q): %v", rpn, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".go"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		params := []string{}
+		for _, variable := range prog.Variables() {
+			params = append(params, strconv.FormatFloat(args[variable], 'g', -1, 64))
+		}
+		fmt.Fprintf(&main, "\tfmt.Println(strconv.FormatFloat(%s(%s), 'g', -1, 64))\n", name, strings.Join(params, ", "))
+	}
+	main.WriteString("}\n")
+	files := map[string]string{
+		"main.go": main.String(),
+		"go.mod":  "module formulas\n\ngo 1.25\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOTOOLCHAIN=local")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, output)
+	}
+	got := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(got) != len(tests) {
+		t.Fatalf("generated program printed %q", output)
+	}
+	for i, rpn := range tests {
+		if got[i] != want[i] {
+			t.Errorf("generated %q = %s, want %s", rpn, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// checkedUnaryOperators maps unary operator tokens to implementations that
+// validate their operand
+var checkedUnaryOperators = map[string]func(float64) (float64, error){
+	"!": factorial,
+}
+
+// checkedBinaryOperators maps binary operator tokens to implementations that
+// validate their operands
+var checkedBinaryOperators = map[string]func(float64, float64) (float64, error){
+	"nCr":    combinations,
+	"nPr":    permutations,
+	"roundN": roundN,
+	"gcd":    gcd,
+	"lcm":    lcm,
+}
+
+// checkedUnary adapts a validating function of one operand into an
+// Operator, which leaves the stack untouched on error
+func checkedUnary(operation func(float64) (float64, error)) engine.Operator[float64] {
+	return engine.Operator[float64]{Arity: 1, Apply: func(operands []float64) (float64, error) {
+		return operation(operands[0])
+	}}
+}
+
+// checkedBinary adapts a validating function of two operands into an
+// Operator, which leaves the stack untouched on error
+func checkedBinary(operation func(float64, float64) (float64, error)) engine.Operator[float64] {
+	return engine.Operator[float64]{Arity: 2, Apply: func(operands []float64) (float64, error) {
+		return operation(operands[0], operands[1])
+	}}
+}
+
+// performCheckedBinaryOperation applies the validating binary operation of
+// token to the top two stack elements, leaving the stack untouched on error
+func (calc *RPNCalculator) performCheckedBinaryOperation(token string, operation func(float64, float64) (float64, error)) error {
+	return calc.Apply(token, checkedBinary(operation))
+}
+
+// toNonNegativeInt validates that value is a non-negative integer
+func toNonNegativeInt(value float64, operation string) (int64, error) {
+	if value != math.Trunc(value) || value < 0 || value > math.MaxInt32 {
+		return 0, fmt.Errorf("%s requires a non-negative integer, got %g", operation, value)
+	}
+	return int64(value), nil
+}
+
+// bigToFloat converts an exact integer result to float64, failing on overflow
+func bigToFloat(value *big.Int, operation string) (float64, error) {
+	result, _ := new(big.Float).SetInt(value).Float64()
+	if math.IsInf(result, 0) {
+		return 0, fmt.Errorf("%s result overflows float64", operation)
+	}
+	return result, nil
+}
+
+// factorial computes n!
+func factorial(n float64) (float64, error) {
+	value, err := toNonNegativeInt(n, "!")
+	if err != nil {
+		return 0, err
+	}
+	// 171! is the first factorial beyond float64 range
+	if value > 170 {
+		return 0, fmt.Errorf("! result overflows float64")
+	}
+	return bigToFloat(new(big.Int).MulRange(1, value), "!")
+}
+
+// combinations computes n choose r
+func combinations(n, r float64) (float64, error) {
+	total, chosen, err := validateChoice(n, r, "nCr")
+	if err != nil {
+		return 0, err
+	}
+	// C(n, k) >= 2^k for k <= n/2, so larger k cannot fit in a float64
+	if min(chosen, total-chosen) > 1024 {
+		return 0, fmt.Errorf("nCr result overflows float64")
+	}
+	return bigToFloat(new(big.Int).Binomial(total, chosen), "nCr")
+}
+
+// permutations computes the number of ordered selections of r items from n
+func permutations(n, r float64) (float64, error) {
+	total, chosen, err := validateChoice(n, r, "nPr")
+	if err != nil {
+		return 0, err
+	}
+	if chosen == 0 {
+		return 1, nil
+	}
+	// nPr >= r!, which overflows past 170
+	if chosen > 170 {
+		return 0, fmt.Errorf("nPr result overflows float64")
+	}
+	return bigToFloat(new(big.Int).MulRange(total-chosen+1, total), "nPr")
+}
+
+// validateChoice checks the operands of nCr and nPr
+func validateChoice(n, r float64, operation string) (int64, int64, error) {
+	total, err := toNonNegativeInt(n, operation)
+	if err != nil {
+		return 0, 0, err
+	}
+	chosen, err := toNonNegativeInt(r, operation)
+	if err != nil {
+		return 0, 0, err
+	}
+	if chosen > total {
+		return 0, 0, fmt.Errorf("%s requires r <= n, got n=%d r=%d", operation, total, chosen)
+	}
+	return total, chosen, nil
+}
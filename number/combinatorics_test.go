@@ -0,0 +1,240 @@
+package number
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCombinatorics(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"0 !", 1},
+		{"5 !", 120},
+		{"170 !", 7.257415615307999e306},
+		{"5 2 nCr", 10},
+		{"5 0 nCr", 1},
+		{"52 5 nCr", 2598960},
+		{"5 2 nPr", 20},
+		{"5 0 nPr", 1},
+		{"3 ! 2 nCr", 15},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("EvaluateExpression(%q) = %g, want %g", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCombinatoricsErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"-1 !", "! requires a non-negative integer, got -1 (column 4)"},
+		{"2.5 !", "! requires a non-negative integer, got 2.5 (column 5)"},
+		{"171 !", "! result overflows float64 (column 5)"},
+		{"2 5 nCr", "nCr requires r <= n, got n=2 r=5 (column 5)"},
+		{"!", "insufficient operands for ! operation (column 1)"},
+		{"5 nCr", "insufficient operands for nCr operation (column 3)"},
+		{"5 nPr", "insufficient operands for nPr operation (column 3)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			_, err := calc.EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestUnderflowNamesOperator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"+", "insufficient operands for + operation (column 1)"},
+		{"1 *", "insufficient operands for * operation (column 3)"},
+		{"sqrt", "insufficient operands for sqrt operation (column 1)"},
+		{"1 2 if", "insufficient operands for if operation (column 5)"},
+		{"1 roundN", "insufficient operands for roundN operation (column 3)"},
+		{"4 gcd", "insufficient operands for gcd operation (column 3)"},
+		{"1 5 sumn", "insufficient operands for sumn operation (column 5)"},
+		{"sumn", "insufficient operands for sumn operation (column 1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestFailingOperationKeepsStack(t *testing.T) {
+	tests := []struct {
+		setup []string
+		token string
+	}{
+		{[]string{"1", "2", "5"}, "sumn"},
+		{[]string{"1", "2", "1.5"}, "sumn"},
+		{[]string{"1", "2", "-1"}, "meann"},
+		{[]string{"7"}, "+"},
+		{[]string{"-1"}, "!"},
+		{[]string{"2", "5"}, "nCr"},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.setup, " ")+" "+test.token, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			for _, token := range test.setup {
+				if err := calc.Evaluate(token); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before := calc.Values()
+			if err := calc.Evaluate(test.token); err == nil {
+				t.Fatalf("Evaluate(%q) succeeded", test.token)
+			}
+			if after := calc.Values(); !slices.Equal(after, before) {
+				t.Errorf("stack after failing %s = %v, want %v", test.token, after, before)
+			}
+		})
+	}
+}
+
+func TestCombinatoricsExpressionTrees(t *testing.T) {
+	tests := []struct {
+		rpn   string
+		want  float64
+		infix string
+		latex string
+	}{
+		{"5 !", 120, "5!", "5!"},
+		{"5 2 nCr", 10, "nCr(5, 2)", `\binom{5}{2}`},
+		{"5 2 nPr", 20, "nPr(5, 2)", `\operatorname{nPr}\left(5, 2\right)`},
+		{"2 3 + !", 120, "(2 + 3)!", `\left(2 + 3\right)!`},
+		{"3 ! neg", -6, "-3!", "-3!"},
+		{"3 neg !", math.NaN(), "(-3)!", `\left(-3\right)!`},
+		{"2 3 ! ^", 64, "2 ^ 3!", "2^{3!}"},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := expr.Eval()
+			if math.IsNaN(test.want) {
+				if err == nil {
+					t.Errorf("Eval() = %g, want an error", value)
+				}
+			} else if err != nil || value != test.want {
+				t.Errorf("Eval() = %g, %v, want %g", value, err, test.want)
+			}
+
+			infix, err := ToInfix(strings.Fields(test.rpn))
+			if err != nil || infix != test.infix {
+				t.Errorf("ToInfix() = %q, %v, want %q", infix, err, test.infix)
+			}
+			if latex := ToLaTeX(expr); latex != test.latex {
+				t.Errorf("ToLaTeX() = %q, want %q", latex, test.latex)
+			}
+			if dot := ToDOT(expr, DOTOptions{}); !strings.Contains(dot, "digraph") {
+				t.Errorf("ToDOT() = %q", dot)
+			}
+		})
+	}
+}
+
+func TestCombinatoricsDiffAndDerivative(t *testing.T) {
+	changes, err := Diff("n 2 nCr", "n 3 nCr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].String() != "replaced 2 with 3" {
+		t.Errorf("Diff() = %v, want one replacement of 2 with 3", changes)
+	}
+
+	expr, err := ParseRPN("x !")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Differentiate(expr, "x"); err == nil {
+		t.Error("Differentiate(x !) succeeded")
+	}
+	if derivative, err := Differentiate(expr, "y"); err != nil || formatInfix(derivative) != "0" {
+		t.Errorf("Differentiate(x !, y) = %v, %v, want 0", derivative, err)
+	}
+}
+
+func TestCalculatorOnlyTokensAreNotVariables(t *testing.T) {
+	for _, expression := range []string{"1 2 sum", "rand", "1 hex", "1 as x", "1 2 3 3 maxn"} {
+		if _, err := ParseRPN(expression); err == nil || !strings.Contains(err.Error(), "unsupported token in expression tree") {
+			t.Errorf("ParseRPN(%q) error = %v, want an unsupported token", expression, err)
+		}
+	}
+}
+
+// typeCheckGo parses and type checks generated Go source, returning the
+// types of its package
+func typeCheckGo(t *testing.T, source string) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "generated.go", source, 0)
+	if err != nil {
+		t.Fatalf("generated Go does not parse: %v\n%s", err, source)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check(file.Name.Name, fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("generated Go does not type check: %v\n%s", err, source)
+	}
+	return pkg
+}
+
+func TestGenerateGoCombinatorics(t *testing.T) {
+	tests := []struct {
+		rpn     string
+		helpers []string
+	}{
+		{"n !", []string{"rpnFactorial"}},
+		{"n r nCr", []string{"rpnCombinations"}},
+		{"n r nPr 2 ^", []string{"rpnPermutations", "rpnPower"}},
+		{"5 2 nCr x *", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, err := GenerateGo(expr, GoOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			typeCheckGo(t, source)
+			for _, helper := range test.helpers {
+				if !strings.Contains(source, helper+" := func") {
+					t.Errorf("generated Go lacks %s:\n%s", helper, source)
+				}
+			}
+		})
+	}
+}
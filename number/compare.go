@@ -0,0 +1,87 @@
+package number
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// ternaryOperators maps three operand tokens to their implementations
+var ternaryOperators = map[string]func(float64, float64, float64) float64{
+	"if":     selectValue,
+	"select": selectValue,
+}
+
+// boolValue converts a comparison outcome to 1 or 0
+func boolValue(condition bool) float64 {
+	if condition {
+		return 1
+	}
+	return 0
+}
+
+// selectValue returns whenTrue if condition is nonzero and whenFalse otherwise
+func selectValue(condition, whenTrue, whenFalse float64) float64 {
+	if condition != 0 {
+		return whenTrue
+	}
+	return whenFalse
+}
+
+// ternary adapts a function of three operands into an Operator
+func ternary(operation func(float64, float64, float64) float64) engine.Operator[float64] {
+	return engine.Operator[float64]{Arity: 3, Apply: func(operands []float64) (float64, error) {
+		return operation(operands[0], operands[1], operands[2]), nil
+	}}
+}
+
+// CallExpr applies a named function to a list of arguments
+type CallExpr struct {
+	Func string
+	Args []Expr
+}
+
+// ToRPN returns every argument's tokens followed by the function name
+func (expr *CallExpr) ToRPN() []string {
+	tokens := []string{}
+	for _, arg := range expr.Args {
+		tokens = append(tokens, arg.ToRPN()...)
+	}
+	return append(tokens, expr.Func)
+}
+
+// Eval evaluates the arguments and applies the function
+func (expr *CallExpr) Eval() (float64, error) {
+	operation, isTernary := ternaryOperators[expr.Func]
+	checked, isChecked := checkedBinaryOperators[expr.Func]
+	function, isFunction := functions[expr.Func]
+	if !(isTernary && len(expr.Args) == 3) && !(isChecked && len(expr.Args) == 2) && !(isFunction && len(expr.Args) == function.Arity) {
+		return 0, fmt.Errorf("unknown function: %s/%d", expr.Func, len(expr.Args))
+	}
+
+	values := make([]float64, len(expr.Args))
+	for i, arg := range expr.Args {
+		value, err := arg.Eval()
+		if err != nil {
+			return 0, err
+		}
+		values[i] = value
+	}
+	switch {
+	case isFunction:
+		return function.Apply(values)
+	case isChecked:
+		return checked(values[0], values[1])
+	}
+	return operation(values[0], values[1], values[2]), nil
+}
+
+// formatCall renders a function call as name(arg, ...)
+func formatCall(expr *CallExpr) string {
+	args := make([]string, len(expr.Args))
+	for i, arg := range expr.Args {
+		args[i] = formatInfix(arg)
+	}
+	return expr.Func + "(" + strings.Join(args, ", ") + ")"
+}
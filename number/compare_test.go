@@ -0,0 +1,94 @@
+package number
+
+import (
+	"testing"
+)
+
+// TestComparisonsAndSelection evaluates each expression on the calculator,
+// as a compiled program and as an expression tree, which must all agree
+func TestComparisonsAndSelection(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"1 2 <", 1},
+		{"2 1 <", 0},
+		{"2 2 <", 0},
+		{"2 1 >", 1},
+		{"2 2 <=", 1},
+		{"3 2 <=", 0},
+		{"2 2 >=", 1},
+		{"1 2 >=", 0},
+		{"2 2 ==", 1},
+		{"2 3 ==", 0},
+		{"2 3 !=", 1},
+		{"1 10 20 if", 10},
+		{"0 10 20 if", 20},
+		{"-0.5 10 20 select", 10},
+		{"3 5 < 100 200 if", 100},
+		{"7 5 < 100 200 if 1 +", 201},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			if got, err := NewRPNCalculator().EvaluateExpression(test.expression); err != nil || got != test.want {
+				t.Errorf("calculator = %g, %v, want %g", got, err, test.want)
+			}
+
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := prog.Eval(); err != nil || got != test.want {
+				t.Errorf("Program.Eval() = %g, %v, want %g", got, err, test.want)
+			}
+
+			expr, err := ParseRPN(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := expr.Eval(); err != nil || got != test.want {
+				t.Errorf("Expr.Eval() = %g, %v, want %g", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestComparisonRules(t *testing.T) {
+	prog, err := Compile("score 50 >= total 0 > * bonus 0 if")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		score, total float64
+		want         float64
+	}{
+		{80, 3, 10},
+		{50, 1, 10},
+		{49, 3, 0},
+		{80, 0, 0},
+	}
+	for _, test := range tests {
+		got, err := prog.EvalWith(map[string]float64{"score": test.score, "total": test.total, "bonus": 10})
+		if err != nil || got != test.want {
+			t.Errorf("EvalWith(score=%g, total=%g) = %g, %v, want %g", test.score, test.total, got, err, test.want)
+		}
+	}
+}
+
+func TestSelectionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"1 2 if", "insufficient operands for if operation (column 5)"},
+		{"1 <", "insufficient operands for < operation (column 3)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package number
+
+import "fmt"
+
+// expensiveOperators lists the operators whose results grow fastest with
+// their operands: power overflows for modest exponents, and the
+// combinatorics compute big integers
+var expensiveOperators = map[string]bool{
+	"^":   true,
+	"**":  true,
+	"!":   true,
+	"nCr": true,
+	"nPr": true,
+}
+
+// Complexity measures the cost of a compiled program, so services
+// evaluating user-supplied formulas can reject the ones too expensive to
+// run per request
+type Complexity struct {
+	// Depth is how deeply the operations nest, 0 for a single number or
+	// variable and 2 for x 1 + 2 *
+	Depth int `json:"depth"`
+	// Operators counts the operations the program runs
+	Operators int `json:"operators"`
+	// ExpensiveCalls counts the operations whose cost grows with their
+	// operands, power and the combinatorics, and the calls of functions,
+	// which may do anything once registered
+	ExpensiveCalls int `json:"expensive_calls"`
+}
+
+// String summarizes the complexity
+func (complexity Complexity) String() string {
+	return fmt.Sprintf("depth %d, %d operators, %d expensive", complexity.Depth, complexity.Operators, complexity.ExpensiveCalls)
+}
+
+// measureComplexity computes the complexity of compiled code
+func measureComplexity(code []instruction) Complexity {
+	complexity := Complexity{}
+	// depths holds the nesting depth of every value on the stack
+	depths := []int{}
+	for _, instr := range code {
+		if instr.kind == opPush || instr.kind == opVar {
+			depths = append(depths, 0)
+			continue
+		}
+		complexity.Operators++
+		if instr.kind == opCall || expensiveOperators[instr.op] {
+			complexity.ExpensiveCalls++
+		}
+		arity := instr.arity()
+		depth := 0
+		for _, operand := range depths[len(depths)-arity:] {
+			depth = max(depth, operand)
+		}
+		depths = append(depths[:len(depths)-arity], depth+1)
+		complexity.Depth = max(complexity.Depth, depth+1)
+	}
+	return complexity
+}
+
+// Complexity returns the complexity of the program, measured when it was
+// compiled
+func (prog *Program) Complexity() Complexity {
+	return prog.complexity
+}
+
+// checkComplexity reports a LimitError when complexity exceeds the limits
+func (limits Limits) checkComplexity(complexity Complexity) error {
+	checks := []struct {
+		resource    string
+		max, actual int
+	}{
+		{"nesting depth", limits.MaxNestingDepth, complexity.Depth},
+		{"operator count", limits.MaxOperators, complexity.Operators},
+		{"expensive call count", limits.MaxExpensiveCalls, complexity.ExpensiveCalls},
+	}
+	for _, check := range checks {
+		if check.max > 0 && check.actual > check.max {
+			return &LimitError{Resource: check.resource, Max: check.max, Actual: check.actual}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package number
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProgramComplexity(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       Complexity
+		optimized  Complexity
+	}{
+		{"1", Complexity{}, Complexity{}},
+		{"x", Complexity{}, Complexity{}},
+		{"x 1 + 2 *", Complexity{Depth: 2, Operators: 2}, Complexity{Depth: 2, Operators: 2}},
+		{"1 2 + 3 4 + *", Complexity{Depth: 2, Operators: 3}, Complexity{}},
+		{"4 sqrt", Complexity{Depth: 1, Operators: 1}, Complexity{}},
+		{"x 2 ^", Complexity{Depth: 1, Operators: 1, ExpensiveCalls: 1}, Complexity{Depth: 1, Operators: 1, ExpensiveCalls: 1}},
+		{"x 2 ** sqrt", Complexity{Depth: 2, Operators: 2, ExpensiveCalls: 1}, Complexity{Depth: 2, Operators: 2, ExpensiveCalls: 1}},
+		{"5 !", Complexity{Depth: 1, Operators: 1, ExpensiveCalls: 1}, Complexity{}},
+		{"5 2 nCr 5 2 nPr +", Complexity{Depth: 2, Operators: 3, ExpensiveCalls: 2}, Complexity{}},
+		{"1 1 atan2", Complexity{Depth: 1, Operators: 1, ExpensiveCalls: 1}, Complexity{}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := prog.Complexity(); got != test.want {
+				t.Errorf("Complexity() = %v, want %v", got, test.want)
+			}
+			if got := prog.Optimize().Complexity(); got != test.optimized {
+				t.Errorf("Optimize().Complexity() = %v, want %v", got, test.optimized)
+			}
+		})
+	}
+}
+
+func TestComplexityString(t *testing.T) {
+	got := Complexity{Depth: 2, Operators: 3, ExpensiveCalls: 1}.String()
+	if want := "depth 2, 3 operators, 1 expensive"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestCompileWithComplexityLimits(t *testing.T) {
+	// x 2 ^ 5 ! + sqrt has depth 3, 4 operators and 2 expensive calls
+	const expression = "x 2 ^ 5 ! + sqrt"
+	tests := []struct {
+		name     string
+		limits   Limits
+		resource string
+		max      int
+		actual   int
+	}{
+		{"unlimited", Limits{}, "", 0, 0},
+		{"within every limit", Limits{MaxNestingDepth: 3, MaxOperators: 4, MaxExpensiveCalls: 2}, "", 0, 0},
+		{"too deep", Limits{MaxNestingDepth: 2}, "nesting depth", 2, 3},
+		{"too many operators", Limits{MaxOperators: 3}, "operator count", 3, 4},
+		{"too many expensive calls", Limits{MaxExpensiveCalls: 1}, "expensive call count", 1, 2},
+		{"depth checked first", Limits{MaxNestingDepth: 1, MaxOperators: 1, MaxExpensiveCalls: 1}, "nesting depth", 1, 3},
+		{"stack depth checked before complexity", Limits{MaxStackDepth: 1, MaxNestingDepth: 1}, "stack depth", 1, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prog, err := CompileWithLimits(expression, test.limits)
+			if test.resource == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := prog.EvalWith(map[string]float64{"x": 2}); err != nil || !sameFloat(got, 11.135528725660043) {
+					t.Errorf("EvalWith() = %g, %v", got, err)
+				}
+				return
+			}
+			var limit *LimitError
+			if !errors.As(err, &limit) {
+				t.Fatalf("CompileWithLimits() error = %v, want a LimitError", err)
+			}
+			if limit.Resource != test.resource || limit.Max != test.max || limit.Actual != test.actual {
+				t.Errorf("LimitError = %+v, want %s limit of %d exceeded by %d", limit, test.resource, test.max, test.actual)
+			}
+		})
+	}
+}
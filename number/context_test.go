@@ -0,0 +1,93 @@
+package number
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// longExpression sums n ones, long enough to span many context checks
+func longExpression(n int) string {
+	return "1" + strings.Repeat(" 1 +", n-1)
+}
+
+func TestEvaluateExpressionContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"background", context.Background(), nil},
+		{"cancelled", cancelled, context.Canceled},
+		{"deadline exceeded", expired, context.DeadlineExceeded},
+	}
+	expression := longExpression(1000)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewRPNCalculator().EvaluateExpressionContext(test.ctx, expression)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("EvaluateExpressionContext() error = %v, want %v", err, test.want)
+			}
+			if test.want == nil && got != 1000 {
+				t.Errorf("EvaluateExpressionContext() = %g, want 1000", got)
+			}
+
+			prog, err := Compile(expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err = prog.EvalContext(test.ctx, nil)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("EvalContext() error = %v, want %v", err, test.want)
+			}
+			if test.want == nil && got != 1000 {
+				t.Errorf("EvalContext() = %g, want 1000", got)
+			}
+		})
+	}
+}
+
+// TestCancelledEvaluationCanBeReused checks that a calculator whose
+// evaluation was cancelled evaluates the next expression from scratch
+func TestCancelledEvaluationCanBeReused(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calc := NewRPNCalculator()
+	if _, err := calc.EvaluateExpressionContext(ctx, longExpression(100)); err == nil {
+		t.Fatal("cancelled evaluation succeeded")
+	}
+	if got, err := calc.EvaluateExpression("2 3 +"); err != nil || got != 5 {
+		t.Errorf("EvaluateExpression() after cancellation = %g, %v, want 5", got, err)
+	}
+}
+
+// TestHugeExponentReturnsWithinDeadline checks that a single ^ with a huge
+// exponent is constant time, evaluated, compiled and optimized
+func TestHugeExponentReturnsWithinDeadline(t *testing.T) {
+	const expression = "1 1e12 ^"
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	got, err := NewRPNCalculator().EvaluateExpressionContext(ctx, expression)
+	if err != nil || got != 1 {
+		t.Errorf("EvaluateExpressionContext(%q) = %g, %v, want 1", expression, got, err)
+	}
+	prog, err := Compile(expression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := prog.Optimize().EvalContext(ctx, nil); err != nil || got != 1 {
+		t.Errorf("optimized EvalContext(%q) = %g, %v, want 1", expression, got, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("%q took %v", expression, elapsed)
+	}
+}
@@ -0,0 +1,151 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// dateLayout is the ISO 8601 calendar date layout of date literals
+const dateLayout = "2006-01-02"
+
+// secondsPerDay converts between day counts and Unix seconds
+const secondsPerDay = 86400
+
+// dayUnits maps the suffixes of day count literals to their length in days
+var dayUnits = map[string]float64{
+	"d": 1,
+	"w": 7,
+}
+
+// DateValue is an operand of the date calculator: a calendar date, or a
+// number of days when IsDate is false. Dates are held as days since
+// 1970-01-01, so they carry no time of day or zone
+type DateValue struct {
+	Days   float64
+	IsDate bool
+}
+
+// Date returns the calendar date of a date value, at midnight UTC
+func (v DateValue) Date() time.Time {
+	return time.Unix(int64(v.Days)*secondsPerDay, 0).UTC()
+}
+
+// String formats a date as 2024-01-15 and a day count as 30d
+func (v DateValue) String() string {
+	if v.IsDate {
+		return v.Date().Format(dateLayout)
+	}
+	return strconv.FormatFloat(v.Days, 'g', -1, 64) + "d"
+}
+
+// DateCalculator is an RPN calculator for scheduling, whose operands are
+// dates like 2024-01-15 and day counts like 30d, 2w or a plain number.
+// Adding or subtracting days moves a date, subtracting two dates yields
+// the days between them and day counts scale by plain numbers
+type DateCalculator struct {
+	*engine.Engine[DateValue]
+}
+
+// NewDateCalculator creates a new date calculator instance
+func NewDateCalculator() *DateCalculator {
+	return &DateCalculator{
+		Engine: engine.New(dateOperators, parseDateValue),
+	}
+}
+
+// dateOperators applies the binary operators with applyDateBinary. today
+// pushes the current local date and weekday replaces a date by its ISO
+// weekday, 1 for Monday to 7 for Sunday
+var dateOperators = func() engine.Operators[DateValue] {
+	operators := scalarOperators(applyDateBinary, nil)
+	operators["today"] = engine.Operator[DateValue]{Arity: 0, Apply: func([]DateValue) (DateValue, error) {
+		year, month, day := time.Now().Date()
+		today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return DateValue{Days: float64(today.Unix() / secondsPerDay), IsDate: true}, nil
+	}}
+	operators["weekday"] = engine.Operator[DateValue]{Arity: 1, Apply: func(operands []DateValue) (DateValue, error) {
+		if !operands[0].IsDate {
+			return DateValue{}, fmt.Errorf("weekday requires a date")
+		}
+		weekday := int(operands[0].Date().Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		return DateValue{Days: float64(weekday)}, nil
+	}}
+	return operators
+}()
+
+// EvaluateExpression processes an entire RPN expression and returns the resulting date or day count
+func (calc *DateCalculator) EvaluateExpression(expression string) (DateValue, error) {
+	calc.Clear()
+
+	for _, token := range Tokenize(expression) {
+		if err := calc.Evaluate(token.Value); err != nil {
+			return DateValue{}, positionError(expression, token, err)
+		}
+	}
+
+	if calc.Size() != 1 {
+		return DateValue{}, engine.Incomplete(calc.Size())
+	}
+
+	return calc.Peek()
+}
+
+// applyDateBinary applies a binary operator, checking which operands may
+// be dates
+func applyDateBinary(token string, a, b DateValue) (DateValue, error) {
+	operation := binaryOperators[token]
+	switch {
+	case !a.IsDate && !b.IsDate:
+		return DateValue{Days: operation(a.Days, b.Days)}, nil
+	case token == "+" && a.IsDate != b.IsDate:
+		// Either operand may be the date
+		return DateValue{Days: math.Round(a.Days + b.Days), IsDate: true}, nil
+	case token == "-" && a.IsDate && b.IsDate:
+		return DateValue{Days: a.Days - b.Days}, nil
+	case token == "-" && a.IsDate:
+		return DateValue{Days: math.Round(a.Days - b.Days), IsDate: true}, nil
+	case a.IsDate && b.IsDate:
+		switch token {
+		case "min", "max":
+			return DateValue{Days: operation(a.Days, b.Days), IsDate: true}, nil
+		case "<", ">", "<=", ">=", "==", "!=":
+			return DateValue{Days: operation(a.Days, b.Days)}, nil
+		}
+	}
+	return DateValue{}, fmt.Errorf("cannot apply %s to %s and %s", token, dateKind(a), dateKind(b))
+}
+
+// dateKind names the kind of a date value in errors
+func dateKind(v DateValue) string {
+	if v.IsDate {
+		return "a date"
+	}
+	return "days"
+}
+
+// parseDateValue parses a date literal like 2024-01-15, a day count like
+// 30d or 2w, or a plain number of days
+func parseDateValue(token string) (DateValue, error) {
+	if date, err := time.Parse(dateLayout, token); err == nil {
+		return DateValue{Days: float64(date.Unix() / secondsPerDay), IsDate: true}, nil
+	}
+	for suffix, days := range dayUnits {
+		if count, ok := strings.CutSuffix(token, suffix); ok {
+			if value, err := parseNumber(count); err == nil {
+				return DateValue{Days: value * days}, nil
+			}
+		}
+	}
+	if value, err := parseNumber(token); err == nil {
+		return DateValue{Days: value}, nil
+	}
+	return DateValue{}, engine.UnknownToken(token)
+}
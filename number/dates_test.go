@@ -0,0 +1,80 @@
+package number
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateCalculator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		wantErr    string
+	}{
+		{"2024-01-15", "2024-01-15", ""},
+		{"2024-01-15 30d +", "2024-02-14", ""},
+		{"30d 2024-01-15 +", "2024-02-14", ""},
+		{"2024-01-15 2w -", "2024-01-01", ""},
+		{"2024-01-15 1.5d +", "2024-01-17", ""},
+		{"2024-03-01 2024-02-01 -", "29d", ""},
+		{"2024-01-15 30d + 2024-01-15 -", "30d", ""},
+		{"1969-12-31 1d +", "1970-01-01", ""},
+		{"2w 2 *", "28d", ""},
+		{"30 2 /", "15d", ""},
+		{"2024-01-15 weekday", "1d", ""},
+		{"2024-01-14 weekday", "7d", ""},
+		{"2024-01-15 2024-01-16 <", "1d", ""},
+		{"2024-01-15 2024-01-16 max", "2024-01-16", ""},
+		{"today today -", "0d", ""},
+		{"2024-01-15 2024-01-16 +", "", "cannot apply + to a date and a date (column 23)"},
+		{"30d 2024-01-15 -", "", "cannot apply - to days and a date (column 16)"},
+		{"2024-01-15 2 *", "", "cannot apply * to a date and days (column 14)"},
+		{"5 weekday", "", "weekday requires a date (column 3)"},
+		{"weekday", "", "insufficient operands for weekday operation (column 1)"},
+		{"+", "", "insufficient operands for + operation (column 1)"},
+		{"2024-02-30", "", "unknown token: 2024-02-30 (column 1)"},
+		{"2024-01-15 1", "", "invalid expression: expected 1 result, got 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewDateCalculator().EvaluateExpression(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateExpression() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got.String() != test.want {
+				t.Errorf("EvaluateExpression() = %q, %v, want %q", got.String(), err, test.want)
+			}
+		})
+	}
+}
+
+func TestDateCalculatorToday(t *testing.T) {
+	calc := NewDateCalculator()
+	before := time.Now().Format(dateLayout)
+	got, err := calc.EvaluateExpression("today")
+	after := time.Now().Format(dateLayout)
+	if err != nil || !got.IsDate || (got.String() != before && got.String() != after) {
+		t.Errorf("today = %q, %v, want %s", got.String(), err, before)
+	}
+	if date := got.Date(); date.Location() != time.UTC || date.Hour() != 0 {
+		t.Errorf("Date() = %v, want midnight UTC", date)
+	}
+}
+
+func TestDateCalculatorFailingOperationKeepsStack(t *testing.T) {
+	calc := NewDateCalculator()
+	for _, token := range []string{"2024-01-15", "2024-01-16"} {
+		if err := calc.Evaluate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := calc.Evaluate("*"); err == nil {
+		t.Fatal("multiplying two dates succeeded")
+	}
+	if values := calc.Values(); len(values) != 2 || values[0].String() != "2024-01-15" || values[1].String() != "2024-01-16" {
+		t.Errorf("stack after a failing operation = %v", values)
+	}
+}
@@ -0,0 +1,166 @@
+package number
+
+import (
+	"fmt"
+	"math"
+)
+
+// Differentiate returns the derivative of an expression with respect to a
+// variable, simplified. Other variables are held constant. It supports the
+// arithmetic operators and powers whose exponent is a non-negative integer
+// constant, the exponents the calculator's ^ raises to exactly, and fails on the piecewise operators such as round and min
+// when their operands do
+func Differentiate(expr Expr, variable string) (Expr, error) {
+	derivative, err := differentiate(expr, variable)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(derivative), nil
+}
+
+// differentiate applies the differentiation rules to a node
+func differentiate(expr Expr, variable string) (Expr, error) {
+	if !dependsOn(expr, variable) {
+		return &Literal{Value: 0}, nil
+	}
+
+	switch node := expr.(type) {
+	case *Variable:
+		return &Literal{Value: 1}, nil
+	case *UnaryExpr:
+		if node.Op != "neg" && node.Op != "sqrt" {
+			break
+		}
+		operand, err := differentiate(node.Operand, variable)
+		if err != nil {
+			return nil, err
+		}
+		if node.Op == "sqrt" {
+			return quotient(operand, product(&Literal{Value: 2}, node)), nil
+		}
+		return negation(operand), nil
+	case *BinaryExpr:
+		return differentiateBinary(node, variable)
+	}
+	return nil, fmt.Errorf("cannot differentiate %s with respect to %s", formatInfix(expr), variable)
+}
+
+// differentiateBinary applies the sum, product, quotient and power rules
+func differentiateBinary(node *BinaryExpr, variable string) (Expr, error) {
+	u, v := node.Left, node.Right
+	if node.Op == "^" || node.Op == "**" {
+		if dependsOn(v, variable) {
+			return nil, fmt.Errorf("cannot differentiate %s with respect to %s: exponent depends on it", formatInfix(node), variable)
+		}
+		// The operator multiplies by the base the integer part of the
+		// exponent times, 1 for exponents below 1, so the power rule only
+		// holds for exponents known to be non-negative integers
+		exponent, err := constantExponent(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot differentiate %s with respect to %s: %w", formatInfix(node), variable, err)
+		}
+		if exponent == 0 {
+			return &Literal{Value: 0}, nil
+		}
+		du, err := differentiate(u, variable)
+		if err != nil {
+			return nil, err
+		}
+		if exponent == 1 {
+			return du, nil
+		}
+		lowered := &BinaryExpr{Op: node.Op, Left: u, Right: &Literal{Value: exponent - 1}}
+		return product(product(&Literal{Value: exponent}, lowered), du), nil
+	}
+
+	du, err := differentiate(u, variable)
+	if err != nil {
+		return nil, err
+	}
+	dv, err := differentiate(v, variable)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.Op {
+	case "+":
+		return sum("+", du, dv), nil
+	case "-":
+		return sum("-", du, dv), nil
+	case "*":
+		return sum("+", product(du, v), product(u, dv)), nil
+	case "/":
+		numerator := sum("-", product(du, v), product(u, dv))
+		return quotient(numerator, product(v, v)), nil
+	case "%":
+		return quotient(sum("+", product(du, v), product(u, dv)), &Literal{Value: 100}), nil
+	}
+	return nil, fmt.Errorf("cannot differentiate %s with respect to %s", formatInfix(node), variable)
+}
+
+// constantExponent evaluates the exponent of a power, failing unless it is
+// a constant non-negative integer
+func constantExponent(exponent Expr) (float64, error) {
+	if len(exprVariables(exponent)) > 0 {
+		return 0, fmt.Errorf("exponent %s is not a constant", formatInfix(exponent))
+	}
+	value, err := exponent.Eval()
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value != math.Trunc(value) {
+		return 0, fmt.Errorf("exponent %g is not a non-negative integer", value)
+	}
+	return value, nil
+}
+
+// dependsOn reports whether an expression reads a variable
+func dependsOn(expr Expr, variable string) bool {
+	found := false
+	Inspect(expr, func(node Expr) bool {
+		if v, ok := node.(*Variable); ok && v.Name == variable {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// sum adds or subtracts two derivative terms, dropping zero ones
+func sum(op string, a, b Expr) Expr {
+	switch {
+	case isLiteral(b, 0):
+		return a
+	case isLiteral(a, 0) && op == "+":
+		return b
+	case isLiteral(a, 0):
+		return negation(b)
+	}
+	return &BinaryExpr{Op: op, Left: a, Right: b}
+}
+
+// product multiplies two derivative terms, dropping the product when either
+// is zero. The terms come from differentiation, which never produces the
+// infinities that make a zero product undefined
+func product(a, b Expr) Expr {
+	if isLiteral(a, 0) || isLiteral(b, 0) {
+		return &Literal{Value: 0}
+	}
+	return &BinaryExpr{Op: "*", Left: a, Right: b}
+}
+
+// quotient divides a derivative term, keeping a zero numerator zero
+func quotient(a, b Expr) Expr {
+	if isLiteral(a, 0) {
+		return a
+	}
+	return &BinaryExpr{Op: "/", Left: a, Right: b}
+}
+
+// negation negates a derivative term
+func negation(a Expr) Expr {
+	if isLiteral(a, 0) {
+		return a
+	}
+	return &UnaryExpr{Op: "neg", Operand: a}
+}
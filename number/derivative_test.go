@@ -0,0 +1,114 @@
+package number
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// evalAt evaluates expr with variable replaced by value
+func evalAt(t *testing.T, expr Expr, variable string, value float64) float64 {
+	t.Helper()
+	bound := Transform(expr, func(node Expr) Expr {
+		if v, ok := node.(*Variable); ok && v.Name == variable {
+			return &Literal{Value: value}
+		}
+		return node
+	})
+	result, err := bound.Eval()
+	if err != nil {
+		t.Fatalf("Eval(%s) at %s=%g: %v", formatInfix(expr), variable, value, err)
+	}
+	return result
+}
+
+func TestDifferentiate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"x", "1"},
+		{"5", "0"},
+		{"x 3 +", "1"},
+		{"x x *", "x + x"},
+		{"x 2 ^", "2 * x"},
+		{"x 3 ^", "3 * x ^ 2"},
+		{"x 0 ^", "0"},
+		{"x 1 ^", "1"},
+		{"x 2 1 + ^", "3 * x ^ 2"},
+		{"x neg", "-1"},
+		{"2 x * 3 ^", "3 * (2 * x) ^ 2 * 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseRPN(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			derivative, err := Differentiate(expr, "x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatInfix(derivative); got != test.want {
+				t.Errorf("Differentiate(%s) = %s, want %s", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+// TestDerivativeMatchesFiniteDifferences checks derivatives against the
+// slope of the expression as the calculator evaluates it
+func TestDerivativeMatchesFiniteDifferences(t *testing.T) {
+	expressions := []string{
+		"x 3 ^",
+		"x 2 ^ x * 1 +",
+		"1 x /",
+		"x sqrt x *",
+		"x 4 ^ x 2 ^ -",
+		"x 1 + 5 ^",
+	}
+	const h = 1e-6
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			expr, err := ParseRPN(expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			derivative, err := Differentiate(expr, "x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, x := range []float64{0.5, 1.5, 3} {
+				slope := (evalAt(t, expr, "x", x+h) - evalAt(t, expr, "x", x-h)) / (2 * h)
+				got := evalAt(t, derivative, "x", x)
+				if math.Abs(got-slope) > 1e-4*math.Max(1, math.Abs(slope)) {
+					t.Errorf("derivative at %g = %g, slope %g", x, got, slope)
+				}
+			}
+		})
+	}
+}
+
+func TestDifferentiateErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"x -1 ^", "exponent -1 is not a non-negative integer"},
+		{"x 0.5 ^", "exponent 0.5 is not a non-negative integer"},
+		{"x n ^", "exponent n is not a constant"},
+		{"2 x ^", "exponent depends on it"},
+		{"x round", "cannot differentiate round(x) with respect to x"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseRPN(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Differentiate(expr, "x"); err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Differentiate(%s) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
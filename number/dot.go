@@ -0,0 +1,102 @@
+package number
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DOTOptions controls how ToDOT draws an expression tree
+type DOTOptions struct {
+	// Name is the name of the graph, "expr" when empty
+	Name string
+	// Order numbers every node with the step at which RPN evaluation
+	// reaches it and links the steps with dashed edges, showing that the
+	// RPN tokens are the tree read bottom-up from left to right
+	Order bool
+}
+
+// ToDOT renders an expression tree as a Graphviz digraph, every operator
+// pointing at its operands from left to right. Nodes are named n1, n2, ...
+// in evaluation order, so node n is the value pushed by RPN token n
+func ToDOT(expr Expr, opts DOTOptions) string {
+	name := opts.Name
+	if name == "" {
+		name = "expr"
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "digraph %s {\n", dotQuote(name))
+	out.WriteString("  ordering=out;\n")
+	out.WriteString("  node [shape=circle];\n")
+	steps := 0
+	writeDOTNode(&out, expr, &steps, opts)
+	if opts.Order {
+		for step := 1; step < steps; step++ {
+			fmt.Fprintf(&out, "  n%d -> n%d [style=dashed, color=gray, constraint=false];\n", step, step+1)
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// ToDOT renders the program's expression tree as a Graphviz digraph, as it
+// runs after Optimize
+func (prog *Program) ToDOT(opts DOTOptions) (string, error) {
+	expr, err := parseRPNTokens("", tokenValues(prog.Tokens()))
+	if err != nil {
+		return "", err
+	}
+	return ToDOT(expr, opts), nil
+}
+
+// writeDOTNode writes a node after its operands, numbering it with the next
+// step, and returns its name
+func writeDOTNode(out *strings.Builder, expr Expr, steps *int, opts DOTOptions) string {
+	operands := []string{}
+	label := ""
+	shape := ""
+	switch node := expr.(type) {
+	case *Literal:
+		label = strconv.FormatFloat(node.Value, 'g', -1, 64)
+		shape = "box"
+	case *Variable:
+		label = node.Name
+		shape = "box"
+	case *UnaryExpr:
+		operands = append(operands, writeDOTNode(out, node.Operand, steps, opts))
+		label = node.Op
+	case *BinaryExpr:
+		operands = append(operands, writeDOTNode(out, node.Left, steps, opts))
+		operands = append(operands, writeDOTNode(out, node.Right, steps, opts))
+		label = node.Op
+	case *CallExpr:
+		for _, arg := range node.Args {
+			operands = append(operands, writeDOTNode(out, arg, steps, opts))
+		}
+		label = node.Func
+	default:
+		label = strings.Join(expr.ToRPN(), " ")
+		shape = "box"
+	}
+
+	*steps++
+	id := fmt.Sprintf("n%d", *steps)
+	attributes := []string{"label=" + dotQuote(label)}
+	if shape != "" {
+		attributes = append(attributes, "shape="+shape)
+	}
+	if opts.Order {
+		attributes = append(attributes, "xlabel="+dotQuote(strconv.Itoa(*steps)))
+	}
+	fmt.Fprintf(out, "  %s [%s];\n", id, strings.Join(attributes, ", "))
+	for _, operand := range operands {
+		fmt.Fprintf(out, "  %s -> %s;\n", id, operand)
+	}
+	return id
+}
+
+// dotQuote renders text as a quoted DOT string
+func dotQuote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(text) + `"`
+}
@@ -0,0 +1,122 @@
+package number
+
+import "testing"
+
+func TestToDOT(t *testing.T) {
+	tests := []struct {
+		name string
+		rpn  string
+		opts DOTOptions
+		want string
+	}{
+		{
+			name: "tree",
+			rpn:  "x 2 ^ y sin +",
+			want: `digraph "expr" {
+  ordering=out;
+  node [shape=circle];
+  n1 [label="x", shape=box];
+  n2 [label="2", shape=box];
+  n3 [label="^"];
+  n3 -> n1;
+  n3 -> n2;
+  n4 [label="y", shape=box];
+  n5 [label="sin"];
+  n5 -> n4;
+  n6 [label="+"];
+  n6 -> n3;
+  n6 -> n5;
+}
+`,
+		},
+		{
+			name: "order",
+			rpn:  "1 x max neg",
+			opts: DOTOptions{Name: `my "graph"`, Order: true},
+			want: `digraph "my \"graph\"" {
+  ordering=out;
+  node [shape=circle];
+  n1 [label="1", shape=box, xlabel="1"];
+  n2 [label="x", shape=box, xlabel="2"];
+  n3 [label="max", xlabel="3"];
+  n3 -> n1;
+  n3 -> n2;
+  n4 [label="neg", xlabel="4"];
+  n4 -> n3;
+  n1 -> n2 [style=dashed, color=gray, constraint=false];
+  n2 -> n3 [style=dashed, color=gray, constraint=false];
+  n3 -> n4 [style=dashed, color=gray, constraint=false];
+}
+`,
+		},
+		{
+			name: "literal",
+			rpn:  "1e-7",
+			opts: DOTOptions{Order: true},
+			want: `digraph "expr" {
+  ordering=out;
+  node [shape=circle];
+  n1 [label="1e-07", shape=box, xlabel="1"];
+}
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ToDOT(expr, test.opts); got != test.want {
+				t.Errorf("ToDOT(%q) = %s, want %s", test.rpn, got, test.want)
+			}
+		})
+	}
+}
+
+func TestProgramToDOT(t *testing.T) {
+	tests := []struct {
+		rpn      string
+		optimize bool
+		want     string
+	}{
+		{"2 3 + x *", false, `digraph "expr" {
+  ordering=out;
+  node [shape=circle];
+  n1 [label="2", shape=box];
+  n2 [label="3", shape=box];
+  n3 [label="+"];
+  n3 -> n1;
+  n3 -> n2;
+  n4 [label="x", shape=box];
+  n5 [label="*"];
+  n5 -> n3;
+  n5 -> n4;
+}
+`},
+		{"2 3 + x *", true, `digraph "expr" {
+  ordering=out;
+  node [shape=circle];
+  n1 [label="5", shape=box];
+  n2 [label="x", shape=box];
+  n3 [label="*"];
+  n3 -> n1;
+  n3 -> n2;
+}
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			prog, err := Compile(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.optimize {
+				prog = prog.Optimize()
+			}
+			if got, err := prog.ToDOT(DOTOptions{}); err != nil || got != test.want {
+				t.Errorf("ToDOT() = %s, %v, want %s", got, err, test.want)
+			}
+		})
+	}
+}
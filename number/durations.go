@@ -0,0 +1,51 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// durationUnit is the display unit of quantities written like
+// time.Duration, as 1h30m0s. Go duration literals get it, and the dur token
+// sets it on the duration on top of the stack
+const durationUnit = "dur"
+
+// parseDuration parses a Go duration literal like 1h30m or 1m30s as a
+// duration quantity. Unit literals are tried first, so 30m on its own stays
+// thirty meters, and a compound literal is needed for minutes
+func parseDuration(token string) (Quantity, bool) {
+	d, err := time.ParseDuration(token)
+	if err != nil {
+		return Quantity{}, false
+	}
+	return Quantity{Value: d.Seconds(), Dims: duration, Unit: durationUnit}, true
+}
+
+// formatDuration renders seconds like time.Duration, falling back to
+// seconds beyond the roughly 292 years it can hold
+func formatDuration(seconds float64) string {
+	nanoseconds := math.Round(seconds * 1e9)
+	if math.IsNaN(nanoseconds) || math.Abs(nanoseconds) >= math.MaxInt64 {
+		return strconv.FormatFloat(seconds, 'g', -1, 64) + " s"
+	}
+	return time.Duration(nanoseconds).String()
+}
+
+// formatAsDuration sets the display unit of the duration on top of the
+// stack to durationUnit
+func (calc *UnitCalculator) formatAsDuration() error {
+	top, err := calc.Peek()
+	if err != nil {
+		return engine.Underflow(durationUnit)
+	}
+	if top.Dims != duration {
+		return fmt.Errorf("%s requires a duration, got %s", durationUnit, formatDimension(top.Dims))
+	}
+	calc.Pop()
+	calc.Push(Quantity{Value: top.Value, Dims: top.Dims, Unit: durationUnit})
+	return nil
+}
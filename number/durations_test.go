@@ -0,0 +1,45 @@
+package number
+
+import "testing"
+
+func TestDurations(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		wantErr    string
+	}{
+		{"1h30m", "1h30m0s", ""},
+		{"-1h30m", "-1h30m0s", ""},
+		{"1h30m 45s +", "1h30m45s", ""},
+		{"1h30m 2 *", "3h0m0s", ""},
+		{"1h30m 3 /", "30m0s", ""},
+		{"1m30s 1h30m >", "0", ""},
+		{"1h30m s to", "5400 s", ""},
+		// The first operand sets the display unit of a sum
+		{"2h 1h30m -", "0.5 h", ""},
+		{"1h30m 1h30m *", "2.916e+07 s^2", ""},
+		{"90min dur", "1h30m0s", ""},
+		{"1.5h dur", "1h30m0s", ""},
+		{"45s dur", "45s", ""},
+		{"1e10h dur", "3.6e+13 s", ""},
+		// A single minutes literal stays meters
+		{"30m", "30 m", ""},
+		{"5m dur", "", "dur requires a duration, got m (column 4)"},
+		{"dur", "", "insufficient operands for dur operation (column 1)"},
+		{"1h30m 2m +", "", "incompatible units for +: s and m (column 10)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewUnitCalculator().EvaluateExpression(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateExpression() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got.String() != test.want {
+				t.Errorf("EvaluateExpression() = %q, %v, want %q", got.String(), err, test.want)
+			}
+		})
+	}
+}
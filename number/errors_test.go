@@ -0,0 +1,72 @@
+package number
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+func TestTypedErrors(t *testing.T) {
+	entryPoints := []struct {
+		name  string
+		parse func(string) error
+	}{
+		{"EvaluateExpression", func(expression string) error {
+			_, err := NewRPNCalculator().EvaluateExpression(expression)
+			return err
+		}},
+		{"Eval", func(expression string) error {
+			_, err := Eval(expression)
+			return err
+		}},
+		{"Compile", func(expression string) error {
+			_, err := Compile(expression)
+			return err
+		}},
+		{"ParseRPN", func(expression string) error {
+			_, err := ParseRPN(expression)
+			return err
+		}},
+	}
+	tests := []struct {
+		expression string
+		target     error
+		// pos is the offset of the unknown token, for ErrUnknownToken
+		pos  int
+		want string
+	}{
+		{"1 +", engine.ErrStackUnderflow, 0, "insufficient operands for + operation (column 3)"},
+		{"sqrt", engine.ErrStackUnderflow, 0, "insufficient operands for sqrt operation (column 1)"},
+		{"1 + $", engine.ErrStackUnderflow, 0, "insufficient operands for + operation (column 3)"},
+		{"1 2", engine.ErrIncompleteExpression, 0, "invalid expression: expected 1 result, got 2"},
+		{"", engine.ErrIncompleteExpression, 0, "invalid expression: expected 1 result, got 0"},
+		{"1 $", nil, 2, "unknown token: $ (column 3)"},
+		{"$x 1 +", nil, 0, "unknown token: $x (column 1)"},
+		{"1 2 + ¤", nil, 6, "unknown token: ¤ (column 7)"},
+	}
+	for _, entry := range entryPoints {
+		for _, test := range tests {
+			t.Run(entry.name+" "+test.expression, func(t *testing.T) {
+				err := entry.parse(test.expression)
+				if err == nil || err.Error() != test.want {
+					t.Fatalf("error = %v, want %q", err, test.want)
+				}
+				var unknown *engine.ErrUnknownToken
+				isUnknown := errors.As(err, &unknown)
+				if test.target != nil {
+					if !errors.Is(err, test.target) || isUnknown {
+						t.Errorf("error %v does not wrap only %v", err, test.target)
+					}
+					return
+				}
+				if !isUnknown || errors.Is(err, engine.ErrStackUnderflow) || errors.Is(err, engine.ErrIncompleteExpression) {
+					t.Fatalf("error %v is not only an ErrUnknownToken", err)
+				}
+				if unknown.Pos != test.pos || test.expression[unknown.Pos:unknown.Pos+len(unknown.Token)] != unknown.Token {
+					t.Errorf("ErrUnknownToken = %+v, want %q at %d", unknown, test.expression[test.pos:], test.pos)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package number
+
+import "sync"
+
+// calculatorPool recycles the calculators of Eval, reset to the defaults
+var calculatorPool = sync.Pool{
+	New: func() any { return NewRPNCalculator() },
+}
+
+// Eval evaluates an RPN expression on a calculator with the default
+// settings. It shares no state, so it is safe to call from any goroutine
+func Eval(expression string) (float64, error) {
+	calc := calculatorPool.Get().(*RPNCalculator)
+	defer recycleCalculator(calc)
+	return calc.EvaluateExpression(expression)
+}
+
+// recycleCalculator resets a calculator and returns it to the pool
+func recycleCalculator(calc *RPNCalculator) {
+	calc.Reset()
+	calculatorPool.Put(calc)
+}
+
+// Eval evaluates an RPN expression on a private stack using the
+// calculator's modes and limits, leaving the calculator untouched. Unlike
+// EvaluateExpression it is safe for concurrent use, so web handlers can
+// share one configured calculator as long as its settings are not changed
+// while evaluations run
+func (calc *RPNCalculator) Eval(expression string) (float64, error) {
+	worker := calculatorPool.Get().(*RPNCalculator)
+	defer recycleCalculator(worker)
+	stack, tokens := worker.Engine, worker.tokens
+	*worker = *calc
+	worker.Engine, worker.tokens = stack, tokens
+	// The labels belong to the calculator, the worker records its own
+	worker.labels, worker.labelNext = nil, false
+	return worker.EvaluateExpression(expression)
+}
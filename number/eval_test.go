@@ -0,0 +1,215 @@
+package number
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"3 4 +", 7},
+		{"2 3 ^ 1 -", 7},
+		{"10 as x 2 *", 20},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			if got, err := Eval(test.expression); err != nil || got != test.want {
+				t.Errorf("Eval(%q) = %g, %v, want %g", test.expression, got, err, test.want)
+			}
+		})
+	}
+	if _, err := Eval("1 +"); err == nil {
+		t.Error("Eval(1 +) succeeded")
+	}
+}
+
+func TestCalculatorEvalLeavesCalculatorUntouched(t *testing.T) {
+	calc := NewRPNCalculator(WithAngleMode(Degrees))
+	if _, err := calc.EvaluateExpression("5 as five"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := calc.Eval("90 sin 2 * as two")
+	if err != nil || got != 2 {
+		t.Fatalf("Eval() = %g, %v, want 2 in degrees", got, err)
+	}
+	if values := calc.Values(); len(values) != 1 || values[0] != 5 {
+		t.Errorf("stack after Eval = %v, want [5]", values)
+	}
+	if labels := calc.Labels(); len(labels) != 1 || labels["five"] != 5 {
+		t.Errorf("labels after Eval = %v, want only five", labels)
+	}
+}
+
+// TestConcurrentEvalWithLabels evaluates labeled expressions through one
+// configured calculator from many goroutines, which the race detector checks
+func TestConcurrentEvalWithLabels(t *testing.T) {
+	calc := NewRPNCalculator()
+	if _, err := calc.EvaluateExpression("1 as one"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				expression := fmt.Sprintf("%d as base 2 * as double", i)
+				if got, err := calc.Eval(expression); err != nil || got != float64(2*i) {
+					t.Errorf("Eval(%q) = %g, %v, want %d", expression, got, err, 2*i)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if labels := calc.Labels(); len(labels) != 1 || labels["one"] != 1 {
+		t.Errorf("labels after concurrent Eval = %v, want only one", labels)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		name       string
+		calc       *RPNCalculator
+		expression string
+	}{
+		{"angle mode", NewRPNCalculator(WithAngleMode(Degrees)), "1 1 atan2"},
+		{"percent mode", NewRPNCalculator(WithPercentMode(PercentKeepBase)), "200 10 %"},
+		{"rounding mode", NewRPNCalculator(WithRoundingMode(RoundHalfEven)), "2.5 round"},
+		{"integer policy", NewRPNCalculator(WithIntegerPolicy(IntegerRound)), "4.4 6 gcd"},
+		{"limits", NewRPNCalculator(WithMaxTokens(2)), "1 2 +"},
+		{"labels", NewRPNCalculator(), "5 as x x"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, wantErr := NewRPNCalculator().EvaluateExpression(test.expression)
+			test.calc.EvaluateExpression("5 as x")
+			for _, token := range []string{"1", "2"} {
+				if err := test.calc.Evaluate(token); err != nil {
+					t.Fatal(err)
+				}
+			}
+			test.calc.Reset()
+			if values := test.calc.Values(); len(values) != 0 {
+				t.Errorf("stack after Reset = %v", values)
+			}
+			if labels := test.calc.Labels(); len(labels) != 0 {
+				t.Errorf("labels after Reset = %v", labels)
+			}
+			got, err := test.calc.EvaluateExpression(test.expression)
+			if got != want || fmt.Sprint(err) != fmt.Sprint(wantErr) {
+				t.Errorf("after Reset %q = %g, %v, want %g, %v", test.expression, got, err, want, wantErr)
+			}
+		})
+	}
+}
+
+func TestEvalDoesNotLeakThroughPool(t *testing.T) {
+	calc := NewRPNCalculator(WithAngleMode(Degrees), WithMaxTokens(3))
+	if _, err := calc.Eval("1 1 atan2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := calc.Eval("1 2 + 3 *"); err == nil {
+		t.Error("Eval ignored the calculator's token limit")
+	}
+	for i := 0; i < 10; i++ {
+		if got, err := Eval("1 1 atan2 4 *"); err != nil || !sameFloat(got, math.Pi) {
+			t.Fatalf("Eval after a configured Eval = %g, %v, want pi", got, err)
+		}
+		if got, err := Eval("1 2 + 3 *"); err != nil || got != 9 {
+			t.Fatalf("Eval after a limited Eval = %g, %v, want 9", got, err)
+		}
+	}
+}
+
+func TestEvalDoesNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	const expression = "5 1 2 + 4 * + 3 - sqrt 5 max"
+	calc := NewRPNCalculator()
+	tests := []struct {
+		name string
+		eval func(string) (float64, error)
+	}{
+		{"Eval", Eval},
+		{"RPNCalculator.Eval", calc.Eval},
+		{"RPNCalculator.EvaluateExpression", calc.EvaluateExpression},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(100, func() {
+				if got, err := test.eval(expression); err != nil || got != 5 {
+					t.Fatalf("%s(%q) = %g, %v, want 5", test.name, expression, got, err)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("%s allocates %g times per call, want 0", test.name, allocs)
+			}
+		})
+	}
+}
+
+func TestEvaluateAll(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []float64
+		wantErr    string
+	}{
+		{"5", []float64{5}, ""},
+		{"1 2 3", []float64{1, 2, 3}, ""},
+		{"17 5 divmod", []float64{3, 2}, ""},
+		{"-17 5 divmod", []float64{-3, -2}, ""},
+		{"17 -5 divmod", []float64{-3, 2}, ""},
+		{"7.5 2 divmod", []float64{3, 1.5}, ""},
+		{"17 5 divmod 1", []float64{3, 2, 1}, ""},
+		{"17 5 divmod +", []float64{5}, ""},
+		{"3 4 + 3 4 *", []float64{7, 12}, ""},
+		{"2 as a 3 as b", []float64{2, 3}, ""},
+		{"", nil, "invalid expression: expected at least 1 result, got 0"},
+		{"1 +", nil, "insufficient operands for + operation (column 3)"},
+		{"1 2 as", nil, "as requires a name (column 5)"},
+		{"1 divmod", nil, "insufficient operands for divmod operation (column 3)"},
+	}
+	calc := NewRPNCalculator()
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			// A failing expression leaves nothing of the previous one
+			got, err := calc.EvaluateAll(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr || got != nil {
+					t.Errorf("EvaluateAll(%q) = %v, %v, want error %q", test.expression, got, err, test.wantErr)
+				}
+				if test.expression == "" && !errors.Is(err, engine.ErrIncompleteExpression) {
+					t.Errorf("error %v is not an ErrIncompleteExpression", err)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, test.want) {
+				t.Errorf("EvaluateAll(%q) = %v, %v, want %v", test.expression, got, err, test.want)
+			}
+			if values := calc.Values(); !slices.Equal(values, test.want) {
+				t.Errorf("stack after EvaluateAll = %v, want %v", values, test.want)
+			}
+		})
+	}
+
+	// The single result entry points still reject the extra values
+	for _, expression := range []string{"17 5 divmod", "1 2 3"} {
+		if _, err := calc.EvaluateExpression(expression); !errors.Is(err, engine.ErrIncompleteExpression) {
+			t.Errorf("EvaluateExpression(%q) error = %v, want an ErrIncompleteExpression", expression, err)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package number
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// Expr represents a node in an arithmetic expression tree
+type Expr interface {
+	// ToRPN returns the expression as a slice of RPN tokens
+	ToRPN() []string
+	// Eval computes the value of the expression
+	Eval() (float64, error)
+}
+
+// Literal is a numeric constant
+type Literal struct {
+	Value float64
+}
+
+// Variable is a named value bound at evaluation time
+type Variable struct {
+	Name string
+}
+
+// UnaryExpr applies a unary operator to a single operand
+type UnaryExpr struct {
+	Op      string
+	Operand Expr
+}
+
+// BinaryExpr applies a binary operator to two operands
+type BinaryExpr struct {
+	Op          string
+	Left, Right Expr
+}
+
+// ToRPN returns the literal as a single token
+func (lit *Literal) ToRPN() []string {
+	return []string{strconv.FormatFloat(lit.Value, 'g', -1, 64)}
+}
+
+// Eval returns the literal value
+func (lit *Literal) Eval() (float64, error) {
+	return lit.Value, nil
+}
+
+// ToRPN returns the variable name as a single token
+func (v *Variable) ToRPN() []string {
+	return []string{v.Name}
+}
+
+// Eval fails because a bare expression tree has no variable bindings
+func (v *Variable) Eval() (float64, error) {
+	return 0, fmt.Errorf("undefined variable: %s", v.Name)
+}
+
+// ToRPN returns the operand tokens followed by the operator
+func (expr *UnaryExpr) ToRPN() []string {
+	return append(expr.Operand.ToRPN(), expr.Op)
+}
+
+// Eval evaluates the operand and applies the operator
+func (expr *UnaryExpr) Eval() (float64, error) {
+	operation, ok := unaryOperators[expr.Op]
+	checked, isChecked := checkedUnaryOperators[expr.Op]
+	if !ok && !isChecked {
+		return 0, fmt.Errorf("unknown operator: %s", expr.Op)
+	}
+
+	operand, err := expr.Operand.Eval()
+	if err != nil {
+		return 0, err
+	}
+	if isChecked {
+		return checked(operand)
+	}
+	return operation(operand), nil
+}
+
+// ToRPN returns both operand token lists followed by the operator
+func (expr *BinaryExpr) ToRPN() []string {
+	tokens := append(expr.Left.ToRPN(), expr.Right.ToRPN()...)
+	return append(tokens, expr.Op)
+}
+
+// Eval evaluates both operands and applies the operator
+func (expr *BinaryExpr) Eval() (float64, error) {
+	operation, ok := binaryOperators[expr.Op]
+	if !ok {
+		return 0, fmt.Errorf("unknown operator: %s", expr.Op)
+	}
+
+	left, err := expr.Left.Eval()
+	if err != nil {
+		return 0, err
+	}
+	right, err := expr.Right.Eval()
+	if err != nil {
+		return 0, err
+	}
+	return operation(left, right), nil
+}
+
+// ParseRPN builds an expression tree from a whitespace separated RPN expression
+func ParseRPN(expression string) (Expr, error) {
+	return parseRPNTokens(expression, Tokenize(expression))
+}
+
+// parseRPNTokens builds an expression tree from RPN tokens, reporting errors
+// at the token's position in source
+func parseRPNTokens(source string, tokens []Token) (Expr, error) {
+	stack := []Expr{}
+
+	for _, tok := range tokens {
+		token := tok.Value
+		if _, ok := binaryOperators[token]; ok {
+			if len(stack) < 2 {
+				return nil, positionError(source, tok, engine.Underflow(token))
+			}
+			left, right := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			stack = append(stack, &BinaryExpr{Op: token, Left: left, Right: right})
+			continue
+		}
+
+		if _, ok := ternaryOperators[token]; ok {
+			if len(stack) < 3 {
+				return nil, positionError(source, tok, engine.Underflow(token))
+			}
+			args := append([]Expr(nil), stack[len(stack)-3:]...)
+			stack = stack[:len(stack)-3]
+			stack = append(stack, &CallExpr{Func: token, Args: args})
+			continue
+		}
+
+		if _, ok := checkedBinaryOperators[token]; ok {
+			if len(stack) < 2 {
+				return nil, positionError(source, tok, engine.Underflow(token))
+			}
+			args := append([]Expr(nil), stack[len(stack)-2:]...)
+			stack = stack[:len(stack)-2]
+			stack = append(stack, &CallExpr{Func: token, Args: args})
+			continue
+		}
+
+		if operator, ok := functions[token]; ok {
+			if operator.Arity == VariadicArity {
+				return nil, positionError(source, tok, fmt.Errorf("unsupported token in expression tree: %s", token))
+			}
+			if len(stack) < operator.Arity {
+				return nil, positionError(source, tok, engine.Underflow(token))
+			}
+			args := append([]Expr(nil), stack[len(stack)-operator.Arity:]...)
+			stack = stack[:len(stack)-operator.Arity]
+			stack = append(stack, &CallExpr{Func: token, Args: args})
+			continue
+		}
+
+		_, isUnary := unaryOperators[token]
+		_, isChecked := checkedUnaryOperators[token]
+		if isUnary || isChecked {
+			if len(stack) < 1 {
+				return nil, positionError(source, tok, engine.Underflow(token))
+			}
+			operand := stack[len(stack)-1]
+			stack[len(stack)-1] = &UnaryExpr{Op: token, Operand: operand}
+			continue
+		}
+
+		if value, err := parseNumber(token); err == nil {
+			stack = append(stack, &Literal{Value: value})
+		} else if isCalculatorOnlyToken(token) || token == labelToken {
+			return nil, positionError(source, tok, fmt.Errorf("unsupported token in expression tree: %s", token))
+		} else if isIdentifier(token) {
+			stack = append(stack, &Variable{Name: token})
+		} else {
+			return nil, positionError(source, tok, &engine.ErrUnknownToken{Token: token, Pos: tok.Pos})
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, engine.Incomplete(len(stack))
+	}
+
+	return stack[0], nil
+}
+
+// ParseInfix builds an expression tree from an infix expression
+func ParseInfix(expression string) (Expr, error) {
+	return ParseInfixWith(expression, InfixOptions{})
+}
+
+// InfixOptions controls how ParseInfixWith reads an infix expression
+type InfixOptions struct {
+	// ImplicitMultiplication reads juxtaposed operands as a product, so
+	// 2(3+4), 3pi, 2x and 2sqrt(x) mean 2*(3+4), 3*pi, 2*x and 2*sqrt(x)
+	// with the precedence of *, so 2x! is 2*(x!). Two numbers in a row stay an error, and a letter that is an SI
+	// prefix still scales the number, so 2k is 2000 rather than 2*k
+	ImplicitMultiplication bool
+	// Precedence replaces the precedence and associativity of the
+	// operators, nil uses DefaultPrecedence. It must cover every binary
+	// operator the expression uses and neg
+	Precedence PrecedenceTable
+}
+
+// ParseInfixWith builds an expression tree from an infix expression read
+// with the given options
+func ParseInfixWith(expression string, opts InfixOptions) (Expr, error) {
+	tokens := TokenizeInfix(expression)
+	table := opts.Precedence
+	if table == nil {
+		table = defaultPrecedence
+	}
+	if opts.ImplicitMultiplication {
+		tokens = insertImplicitMultiplication(tokens, table)
+	}
+	rpn, err := infixToRPN(expression, tokens, table)
+	if err != nil {
+		return nil, err
+	}
+	return parseRPNTokens(expression, rpn)
+}
+
+// insertImplicitMultiplication adds a * between every token ending an
+// operand and the next token starting one, a function call included,
+// unless both are numbers
+func insertImplicitMultiplication(tokens []Token, table PrecedenceTable) []Token {
+	result := make([]Token, 0, len(tokens))
+	for i, token := range tokens {
+		if i > 0 {
+			previous := tokens[i-1]
+			endsOperand := previous.Kind == TokenNumber || previous.Kind == TokenIdentifier || previous.Value == ")" || previous.Value == "!"
+			_, isCall := callArity(token.Value, table)
+			startsOperand := token.Kind == TokenNumber || token.Kind == TokenIdentifier || token.Value == "(" || isCall
+			if endsOperand && startsOperand && (previous.Kind != TokenNumber || token.Kind != TokenNumber) {
+				result = append(result, Token{Value: "*", Kind: TokenOperator, Pos: token.Pos})
+			}
+		}
+		result = append(result, token)
+	}
+	return result
+}
+
+// callArity returns the number of arguments of a name called like a
+// function in infix, sqrt(x), and whether token is such a name. Operators
+// with a precedence are written between their operands instead
+func callArity(token string, table PrecedenceTable) (int, bool) {
+	if _, infix := table[token]; infix || !isIdentifier(token) {
+		return 0, false
+	}
+	if _, ok := unaryOperators[token]; ok {
+		return 1, true
+	}
+	if _, ok := binaryOperators[token]; ok {
+		return 2, true
+	}
+	if _, ok := ternaryOperators[token]; ok {
+		return 3, true
+	}
+	if _, ok := checkedBinaryOperators[token]; ok {
+		return 2, true
+	}
+	if operator, ok := functions[token]; ok && operator.Arity != VariadicArity {
+		return operator.Arity, true
+	}
+	return 0, false
+}
+
+// infixToRPN converts infix tokens to RPN using the Shunting Yard algorithm
+// and a precedence table, keeping the position of every token. Functions
+// take their arguments in parentheses separated by commas, atan2(y, x),
+// and are output once the last is
+func infixToRPN(source string, tokens []Token, table PrecedenceTable) ([]Token, error) {
+	output := []Token{}
+	operations := []Token{}
+	// arguments counts, for every opening parenthesis on operations, the
+	// arguments of the function call it opens, 0 for a grouping
+	arguments := []int{}
+	// expectOperand is true when the next token starts an operand, so a
+	// leading minus is unary negation rather than subtraction
+	expectOperand := true
+
+	for i, tok := range tokens {
+		token := tok.Value
+
+		if expectOperand {
+			if _, isCall := callArity(token, table); isCall {
+				if i+1 == len(tokens) || tokens[i+1].Value != "(" {
+					return nil, positionError(source, tok, fmt.Errorf("%s requires its arguments in parentheses", token))
+				}
+				operations = append(operations, tok)
+				continue
+			}
+			switch {
+			case token == "(":
+				operations = append(operations, tok)
+				if len(operations) > 1 {
+					if _, isCall := callArity(operations[len(operations)-2].Value, table); isCall {
+						arguments = append(arguments, 1)
+						continue
+					}
+				}
+				arguments = append(arguments, 0)
+			case token == "neg":
+				if _, ok := table[token]; !ok {
+					return nil, positionError(source, tok, fmt.Errorf("no precedence for operator %s", token))
+				}
+				operations = append(operations, tok)
+			case tok.Kind == TokenNumber || tok.Kind == TokenIdentifier:
+				output = append(output, tok)
+				expectOperand = false
+			case tok.Kind == TokenUnknown:
+				return nil, positionError(source, tok, engine.UnknownToken(token))
+			default:
+				return nil, positionError(source, tok, fmt.Errorf("expected an operand, got %s", token))
+			}
+			continue
+		}
+
+		if token == ")" {
+			for len(operations) > 0 && operations[len(operations)-1].Value != "(" {
+				output = append(output, operations[len(operations)-1])
+				operations = operations[:len(operations)-1]
+			}
+			if len(operations) == 0 {
+				return nil, positionError(source, tok, fmt.Errorf("mismatched parentheses"))
+			}
+			// Remove the opening parenthesis, and output the function it
+			// holds the arguments of
+			operations = operations[:len(operations)-1]
+			count := arguments[len(arguments)-1]
+			arguments = arguments[:len(arguments)-1]
+			if count > 0 {
+				function := operations[len(operations)-1]
+				if arity, _ := callArity(function.Value, table); arity != count {
+					noun := "arguments"
+					if arity == 1 {
+						noun = "argument"
+					}
+					return nil, positionError(source, function, fmt.Errorf("%s takes %d %s, got %d", function.Value, arity, noun, count))
+				}
+				output = append(output, function)
+				operations = operations[:len(operations)-1]
+			}
+			continue
+		}
+
+		if tok.Kind == TokenSeparator {
+			// Finish the argument before the comma
+			for len(operations) > 0 && operations[len(operations)-1].Value != "(" {
+				output = append(output, operations[len(operations)-1])
+				operations = operations[:len(operations)-1]
+			}
+			if len(operations) == 0 || arguments[len(arguments)-1] == 0 {
+				return nil, positionError(source, tok, fmt.Errorf("%s outside the arguments of a function", token))
+			}
+			arguments[len(arguments)-1]++
+			expectOperand = true
+			continue
+		}
+
+		if token == "!" {
+			// A postfix operator applies to the operand just completed,
+			// binding tighter than any operator before it
+			output = append(output, tok)
+			continue
+		}
+
+		if _, ok := binaryOperators[token]; ok {
+			if _, ok := table[token]; !ok {
+				return nil, positionError(source, tok, fmt.Errorf("no precedence for operator %s", token))
+			}
+			for len(operations) > 0 && table.shouldPop(operations[len(operations)-1].Value, token) {
+				output = append(output, operations[len(operations)-1])
+				operations = operations[:len(operations)-1]
+			}
+			operations = append(operations, tok)
+			expectOperand = true
+			continue
+		}
+
+		if tok.Kind == TokenUnknown {
+			return nil, positionError(source, tok, engine.UnknownToken(token))
+		}
+		return nil, positionError(source, tok, fmt.Errorf("expected an operator, got %s", token))
+	}
+
+	// Pop remaining operations
+	for len(operations) > 0 {
+		if operations[len(operations)-1].Value == "(" {
+			return nil, positionError(source, operations[len(operations)-1], fmt.Errorf("mismatched parentheses"))
+		}
+		output = append(output, operations[len(operations)-1])
+		operations = operations[:len(operations)-1]
+	}
+
+	return output, nil
+}
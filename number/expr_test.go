@@ -0,0 +1,399 @@
+package number
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseRPN(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"3 4 +", 7},
+		{"5 1 2 + 4 * + 3 -", 14},
+		{"2 3 ^", 8},
+		{"9 sqrt neg", -3},
+		{"1 2 3 if", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseRPN(test.expression)
+			if err != nil {
+				t.Fatalf("ParseRPN(%q): %v", test.expression, err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != test.expression {
+				t.Errorf("ToRPN() = %q, want %q", got, test.expression)
+			}
+			value, err := expr.Eval()
+			if err != nil {
+				t.Fatalf("Eval(): %v", err)
+			}
+			if value != test.want {
+				t.Errorf("Eval() = %g, want %g", value, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRPNErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"3 +", "insufficient operands for + operation (column 3)"},
+		{"1 2", "expected 1 result, got 2"},
+		{"1 $", "unknown token: $ (column 3)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := ParseRPN(test.expression)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("ParseRPN(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInfix(t *testing.T) {
+	tests := []struct {
+		expression string
+		rpn        string
+		want       float64
+	}{
+		{"3 + 4 * 2", "3 4 2 * +", 11},
+		{"(3 + 4) * 2", "3 4 + 2 *", 14},
+		{"2 ^ 3 ^ 2", "2 3 2 ^ ^", 512},
+		{"-2 + 5", "2 neg 5 +", 3},
+		{"1 + sqrt(4)", "1 4 sqrt +", 3},
+		{"10 - floor(2.5)", "10 2.5 floor -", 8},
+		{"2 * sin(0)", "2 0 sin *", 0},
+		{"sqrt(9) * sqrt(16)", "9 sqrt 16 sqrt *", 12},
+		{"-sqrt(4)", "4 sqrt neg", -2},
+		{"sqrt(sqrt(16) + 5)", "16 sqrt 5 + sqrt", 3},
+		{"round(2.5 * 3)", "2.5 3 * round", 8},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseInfix(test.expression)
+			if err != nil {
+				t.Fatalf("ParseInfix(%q): %v", test.expression, err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != test.rpn {
+				t.Errorf("ToRPN() = %q, want %q", got, test.rpn)
+			}
+			value, err := expr.Eval()
+			if err != nil {
+				t.Fatalf("Eval(): %v", err)
+			}
+			if value != test.want {
+				t.Errorf("Eval() = %g, want %g", value, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInfixErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"sqrt 4", "sqrt requires its arguments in parentheses (column 1)"},
+		{"1 + sqrt", "sqrt requires its arguments in parentheses (column 5)"},
+		{"1 + * 2", "expected an operand, got * (column 5)"},
+		{"2 3", "expected an operator, got 3 (column 3)"},
+		{"1 + (2", "mismatched parentheses (column 5)"},
+		{"1 + 2)", "mismatched parentheses (column 6)"},
+		{"1 + $", "unknown token: $ (column 5)"},
+		{"atan2(1)", "atan2 takes 2 arguments, got 1 (column 1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := ParseInfix(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("ParseInfix(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInfixAssociativity(t *testing.T) {
+	// leftCaret groups ^ from the left, plus binds tighter than times and
+	// minus groups from the right
+	leftCaret := DefaultPrecedence()
+	leftCaret["^"] = OperatorPrecedence{Level: 5}
+	leftCaret["+"] = OperatorPrecedence{Level: 4}
+	leftCaret["-"] = OperatorPrecedence{Level: 2, Associativity: RightAssociative}
+
+	tests := []struct {
+		expression string
+		table      PrecedenceTable
+		rpn        string
+		want       float64
+	}{
+		{"8 - 3 - 2", nil, "8 3 - 2 -", 3},
+		{"64 / 4 / 2", nil, "64 4 / 2 /", 8},
+		{"2 ^ 3 ^ 2", nil, "2 3 2 ^ ^", 512},
+		{"2 ** 3 ** 2", nil, "2 3 2 ** **", 512},
+		{"2 ^ 3 ** 2", nil, "2 3 2 ** ^", 512},
+		{"-2 ^ 2", nil, "2 2 ^ neg", -4},
+		{"1 < 2 + 3", nil, "1 2 3 + <", 1},
+		{"2 ^ 3 ^ 2", leftCaret, "2 3 ^ 2 ^", 64},
+		{"3 + 4 * 2", leftCaret, "3 4 + 2 *", 14},
+		{"8 - 3 - 2", leftCaret, "8 3 2 - -", 7},
+		{"(8 - 3) - 2", leftCaret, "8 3 - 2 -", 3},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseInfixWith(test.expression, InfixOptions{Precedence: test.table})
+			if err != nil {
+				t.Fatalf("ParseInfixWith(%q): %v", test.expression, err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != test.rpn {
+				t.Errorf("ToRPN() = %q, want %q", got, test.rpn)
+			}
+			if value, err := expr.Eval(); err != nil || value != test.want {
+				t.Errorf("Eval() = %g, %v, want %g", value, err, test.want)
+			}
+
+			// The infix form always uses the default table, so it must
+			// parse back to the same tree whatever table read the input
+			infix, err := ToInfix(expr.ToRPN())
+			if err != nil {
+				t.Fatal(err)
+			}
+			reparsed, err := ParseInfix(infix)
+			if err != nil {
+				t.Fatalf("ParseInfix(%q): %v", infix, err)
+			}
+			if got := strings.Join(reparsed.ToRPN(), " "); got != test.rpn {
+				t.Errorf("ParseInfix(%q) = %q, want %q", infix, got, test.rpn)
+			}
+		})
+	}
+}
+
+func TestDefaultPrecedenceIsACopy(t *testing.T) {
+	table := DefaultPrecedence()
+	table["+"] = OperatorPrecedence{Level: 9}
+	expr, err := ParseInfix("3 + 4 * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(expr.ToRPN(), " "); got != "3 4 2 * +" {
+		t.Errorf("ToRPN() after changing a copy of the table = %q, want 3 4 2 * +", got)
+	}
+}
+
+func TestParseInfixPostfixFactorial(t *testing.T) {
+	tests := []struct {
+		expression string
+		rpn        string
+		want       float64
+	}{
+		{"5!", "5 !", 120},
+		{"3!+1", "3 ! 1 +", 7},
+		{"1+3!", "1 3 ! +", 7},
+		{"3!!", "3 ! !", 720},
+		{"(2+1)!", "2 1 + !", 6},
+		{"2^3!", "2 3 ! ^", 64},
+		{"-3!", "3 ! neg", -6},
+		{"3!-1", "3 ! 1 -", 5},
+		{"sqrt(9)!", "9 sqrt !", 6},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseInfix(test.expression)
+			if err != nil {
+				t.Fatalf("ParseInfix(%q): %v", test.expression, err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != test.rpn {
+				t.Errorf("ToRPN() = %q, want %q", got, test.rpn)
+			}
+			if value, err := expr.Eval(); err != nil || value != test.want {
+				t.Errorf("Eval() = %g, %v, want %g", value, err, test.want)
+			}
+		})
+	}
+
+	if _, err := ParseInfix("!3"); err == nil || err.Error() != "expected an operand, got ! (column 1)" {
+		t.Errorf("ParseInfix(!3) error = %v", err)
+	}
+}
+
+func TestParseInfixImplicitMultiplication(t *testing.T) {
+	tests := []struct {
+		expression string
+		rpn        string
+	}{
+		{"2(3+4)", "2 3 4 + *"},
+		{"3pi", "3 pi *"},
+		{"2x", "2 x *"},
+		{"2x!", "2 x ! *"},
+		{"2x^2", "2 x 2 ^ *"},
+		{"(x+1)(x-1)", "x 1 + x 1 - *"},
+		{"2sqrt(x)", "2 x sqrt *"},
+		{"x sqrt(x)", "x x sqrt *"},
+		{"3!x", "3 ! x *"},
+		{"2k", "2000"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseInfixWith(test.expression, InfixOptions{ImplicitMultiplication: true})
+			if err != nil {
+				t.Fatalf("ParseInfixWith(%q): %v", test.expression, err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != test.rpn {
+				t.Errorf("ToRPN() = %q, want %q", got, test.rpn)
+			}
+		})
+	}
+
+	for _, expression := range []string{"2 3", "2x"} {
+		if _, err := ParseInfix(expression); err == nil {
+			t.Errorf("ParseInfix(%q) without implicit multiplication succeeded", expression)
+		}
+	}
+	if _, err := ParseInfixWith("2 3", InfixOptions{ImplicitMultiplication: true}); err == nil {
+		t.Error("ParseInfixWith(2 3) multiplied two numbers")
+	}
+}
+
+func TestParseInfixArgumentLists(t *testing.T) {
+	tests := []struct {
+		expression string
+		rpn        string
+		want       float64
+	}{
+		{"atan2(1, 1)", "1 1 atan2", math.Pi / 4},
+		{"max(1,2)", "1 2 max", 2},
+		{"min(3, -1)", "3 1 neg min", -1},
+		{"clamp(5, 0, 1)", "5 0 1 clamp", 1},
+		{"hypot(3, 4) + 1", "3 4 hypot 1 +", 6},
+		{"max(1 + 2, 2 * 3)", "1 2 + 2 3 * max", 6},
+		{"max(min(1, 2), (3))", "1 2 min 3 max", 3},
+		{"if(1 > 2, 10, 20)", "1 2 > 10 20 if", 20},
+		{"nCr(5, 2)", "5 2 nCr", 10},
+		{"nCr(5, 2)!", "5 2 nCr !", 3628800},
+		{"roundN(3.14159, 2)", "3.14159 2 roundN", 3.14},
+		{"gcd(12, 18) * lcm(4, 6)", "12 18 gcd 4 6 lcm *", 72},
+		{"atan2(1,-1)", "1 1 neg atan2", 3 * math.Pi / 4},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseInfix(test.expression)
+			if err != nil {
+				t.Fatalf("ParseInfix(%q): %v", test.expression, err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != test.rpn {
+				t.Errorf("ToRPN() = %q, want %q", got, test.rpn)
+			}
+			if value, err := expr.Eval(); err != nil || value != test.want {
+				t.Errorf("Eval() = %g, %v, want %g", value, err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInfixArgumentListErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"atan2(1)", "atan2 takes 2 arguments, got 1 (column 1)"},
+		{"sqrt(1, 2)", "sqrt takes 1 argument, got 2 (column 1)"},
+		{"clamp(5, 0)", "clamp takes 3 arguments, got 2 (column 1)"},
+		{"1 + max(1, 2, 3)", "max takes 2 arguments, got 3 (column 5)"},
+		{"max(1, )", "expected an operand, got ) (column 8)"},
+		{"max(, 1)", "expected an operand, got , (column 5)"},
+		{"max()", "expected an operand, got ) (column 5)"},
+		{"(1, 2)", ", outside the arguments of a function (column 3)"},
+		{"max((1, 2))", ", outside the arguments of a function (column 7)"},
+		{"max(1, 2", "mismatched parentheses (column 4)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := ParseInfix(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("ParseInfix(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestToInfix(t *testing.T) {
+	tests := []struct {
+		rpn  string
+		want string
+	}{
+		{"3 4 2 * +", "3 + 4 * 2"},
+		{"3 4 + 2 *", "(3 + 4) * 2"},
+		{"1 2 + 3 +", "1 + 2 + 3"},
+		{"1 2 3 + +", "1 + 2 + 3"},
+		{"1 2 - 3 -", "1 - 2 - 3"},
+		{"1 2 3 - -", "1 - (2 - 3)"},
+		{"8 4 2 / /", "8 / (4 / 2)"},
+		{"2 3 2 ^ ^", "2 ^ 3 ^ 2"},
+		{"2 3 ^ 2 ^", "(2 ^ 3) ^ 2"},
+		{"x neg 2 ^", "(-x) ^ 2"},
+		{"x 2 ^ neg", "-x ^ 2"},
+		{"x 1 + sqrt", "sqrt(x + 1)"},
+		{"2 3 + !", "(2 + 3)!"},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			got, err := ToInfix(strings.Fields(test.rpn))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("ToInfix(%q) = %q, want %q", test.rpn, got, test.want)
+			}
+		})
+	}
+}
+
+// TestInfixRoundTrip parses the infix form of RPN expressions back into
+// the same expression tree
+func TestInfixRoundTrip(t *testing.T) {
+	expressions := []string{
+		"3 4 2 * +",
+		"3 4 + 2 *",
+		"2 3 2 ^ ^",
+		"1 2 - 3 -",
+		"1 2 3 - -",
+		"x neg 2 ^",
+		"x 2 ^ neg",
+		"1 1 atan2",
+		"1 2 max",
+		"5 0 1 clamp",
+		"x 0 > x x neg if",
+		"5 2 nCr",
+		"5 2 nPr 3 *",
+		"x 2 roundN",
+		"12 18 gcd 4 lcm",
+		"5 !",
+		"2 3 + !",
+		"3 ! neg",
+		"2 3 ! ^",
+		"x sqrt sin 1 +",
+		"a b hypot c d atan2 -",
+		"x 1 2 clamp 3 max",
+	}
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			infix, err := ToInfix(strings.Fields(expression))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expr, err := ParseInfix(infix)
+			if err != nil {
+				t.Fatalf("ParseInfix(%q): %v", infix, err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != expression {
+				t.Errorf("ParseInfix(ToInfix(%q)) = %q via %q", expression, got, infix)
+			}
+		})
+	}
+}
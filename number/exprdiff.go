@@ -0,0 +1,187 @@
+package number
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// chainOperators lists the operators whose operands may be reordered and
+// regrouped freely, so a + b + c, c + (b + a) and b + a + c are one sum
+var chainOperators = map[string]bool{"+": true, "*": true, "min": true, "max": true}
+
+// symmetricOperators lists the operators whose two operands may be swapped
+// but not regrouped
+var symmetricOperators = map[string]bool{"==": true, "!=": true}
+
+// Canonicalize simplifies an expression and orders the operands of the
+// operators that allow it, so formulas differing only in operand order,
+// grouping of sums and products, or constant arithmetic have the same
+// canonical tree. Regrouping can change floating point rounding, so the
+// canonical form is for comparing formulas rather than evaluating them
+func Canonicalize(expr Expr) Expr {
+	return Transform(Simplify(expr), func(node Expr) Expr {
+		binary, ok := node.(*BinaryExpr)
+		switch {
+		case !ok:
+			return node
+		case chainOperators[binary.Op]:
+			return chainExpr(binary.Op, chainOperands(binary.Op, binary))
+		case symmetricOperators[binary.Op] && exprKey(binary.Right) < exprKey(binary.Left):
+			return &BinaryExpr{Op: binary.Op, Left: binary.Right, Right: binary.Left}
+		}
+		return node
+	})
+}
+
+// chainOperands returns the operands of a chain of op in canonical order
+func chainOperands(op string, expr Expr) []Expr {
+	operands := []Expr{}
+	var collect func(Expr)
+	collect = func(node Expr) {
+		if binary, ok := node.(*BinaryExpr); ok && binary.Op == op {
+			collect(binary.Left)
+			collect(binary.Right)
+			return
+		}
+		operands = append(operands, node)
+	}
+	collect(expr)
+	slices.SortStableFunc(operands, func(a, b Expr) int {
+		return strings.Compare(exprKey(a), exprKey(b))
+	})
+	return operands
+}
+
+// chainExpr joins operands with op, grouping to the left
+func chainExpr(op string, operands []Expr) Expr {
+	expr := operands[0]
+	for _, operand := range operands[1:] {
+		expr = &BinaryExpr{Op: op, Left: expr, Right: operand}
+	}
+	return expr
+}
+
+// exprKey orders canonical operands by their RPN text
+func exprKey(expr Expr) string {
+	return strings.Join(expr.ToRPN(), " ")
+}
+
+// ChangeKind is the kind of an ExprChange
+type ChangeKind string
+
+const (
+	// ChangeAdded is an operand added to a sum, product, min or max
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is an operand removed from one
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeReplaced is a subtree replaced by another
+	ChangeReplaced ChangeKind = "replaced"
+)
+
+// ExprChange is a single semantic change between two formulas. Op is the
+// operator joining an added or removed operand, Old and New are the
+// subtrees before and after, Old nil for additions and New nil for
+// removals
+type ExprChange struct {
+	Kind     ChangeKind
+	Op       string
+	Old, New Expr
+}
+
+// String describes the change in infix, e.g. replaced b * 2 with b * 3
+func (change ExprChange) String() string {
+	switch change.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("added %s %s", change.Op, formatInfix(change.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("removed %s %s", change.Op, formatInfix(change.Old))
+	}
+	return fmt.Sprintf("replaced %s with %s", formatInfix(change.Old), formatInfix(change.New))
+}
+
+// Diff compares two RPN formulas structurally after canonicalizing them and
+// returns the subtrees added, removed and replaced, outermost first, so a
+// review of a formula edit shows what it changes rather than a text diff.
+// Formulas that only differ in layout, operand order or constant
+// arithmetic have no changes
+func Diff(exprA, exprB string) ([]ExprChange, error) {
+	a, err := ParseRPN(exprA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ParseRPN(exprB)
+	if err != nil {
+		return nil, err
+	}
+	return DiffExprs(a, b), nil
+}
+
+// DiffExprs compares two expression trees like Diff
+func DiffExprs(a, b Expr) []ExprChange {
+	return diffNodes(Canonicalize(a), Canonicalize(b), nil)
+}
+
+// diffNodes appends the changes between two canonical subtrees to changes
+func diffNodes(a, b Expr, changes []ExprChange) []ExprChange {
+	if Equal(a, b) {
+		return changes
+	}
+	switch left := a.(type) {
+	case *UnaryExpr:
+		if right, ok := b.(*UnaryExpr); ok && left.Op == right.Op {
+			return diffNodes(left.Operand, right.Operand, changes)
+		}
+	case *BinaryExpr:
+		right, ok := b.(*BinaryExpr)
+		if !ok || left.Op != right.Op {
+			break
+		}
+		if chainOperators[left.Op] {
+			return diffChains(left.Op, chainOperands(left.Op, left), chainOperands(left.Op, right), changes)
+		}
+		changes = diffNodes(left.Left, right.Left, changes)
+		return diffNodes(left.Right, right.Right, changes)
+	case *CallExpr:
+		right, ok := b.(*CallExpr)
+		if !ok || left.Func != right.Func || len(left.Args) != len(right.Args) {
+			break
+		}
+		for i := range left.Args {
+			changes = diffNodes(left.Args[i], right.Args[i], changes)
+		}
+		return changes
+	}
+	return append(changes, ExprChange{Kind: ChangeReplaced, Old: a, New: b})
+}
+
+// diffChains compares the operands of two chains of op. Operands in both
+// are unchanged. When as many are left on each side, they are paired in
+// canonical order and diffed as replacements, otherwise they are reported
+// as removed and added
+func diffChains(op string, before, after []Expr, changes []ExprChange) []ExprChange {
+	removed := []Expr{}
+	added := slices.Clone(after)
+	for _, operand := range before {
+		i := slices.IndexFunc(added, func(candidate Expr) bool { return Equal(operand, candidate) })
+		if i < 0 {
+			removed = append(removed, operand)
+			continue
+		}
+		added = slices.Delete(added, i, i+1)
+	}
+
+	if len(removed) == len(added) {
+		for i := range removed {
+			changes = diffNodes(removed[i], added[i], changes)
+		}
+		return changes
+	}
+	for _, operand := range removed {
+		changes = append(changes, ExprChange{Kind: ChangeRemoved, Op: op, Old: operand})
+	}
+	for _, operand := range added {
+		changes = append(changes, ExprChange{Kind: ChangeAdded, Op: op, New: operand})
+	}
+	return changes
+}
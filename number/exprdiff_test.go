@@ -0,0 +1,122 @@
+package number
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"b a +", "a b +"},
+		{"c b a + +", "a b + c +"},
+		{"c a b * *", "a b * c *"},
+		{"b a max", "a b max"},
+		{"b a ==", "a b =="},
+		{"b a !=", "a b !="},
+		// Differences and quotients keep their order
+		{"b a -", "b a -"},
+		{"b a /", "b a /"},
+		{"1 2 + x *", "3 x *"},
+		{"b a + d c + *", "a b + c d + *"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expr, err := ParseRPN(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(Canonicalize(expr).ToRPN(), " "); got != test.want {
+				t.Errorf("Canonicalize() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		want    []string
+		wantErr string
+	}{
+		{"same", "a b +", "a b +", []string{}, ""},
+		{"operand order", "a b +", "b a +", []string{}, ""},
+		{"grouping", "a b + c +", "c b a + +", []string{}, ""},
+		{"constant arithmetic", "1 2 + x *", "x 3 *", []string{}, ""},
+		{"symmetric comparison", "a b ==", "b a ==", []string{}, ""},
+		{"replaced constant", "a b 2 * +", "a b 3 * +", []string{"replaced 2 with 3"}, ""},
+		{"added operand", "a b +", "a b + c +", []string{"added + c"}, ""},
+		{"removed operand", "a b + c +", "a c +", []string{"removed + b"}, ""},
+		{"paired replacements", "a b c + +", "a e f + +", []string{"replaced b with e", "replaced c with f"}, ""},
+		{"regrouped", "a b * c +", "a b + c +", []string{"removed + a * b", "added + a", "added + b"}, ""},
+		{"swapped difference", "a b -", "b a -", []string{"replaced a with b", "replaced b with a"}, ""},
+		{"changed operator", "a b +", "a b *", []string{"replaced a + b with a * b"}, ""},
+		{"nested", "a b + c d + *", "a b + c e + *", []string{"replaced d with e"}, ""},
+		{"unary operand", "x sqrt", "y sqrt", []string{"replaced x with y"}, ""},
+		{"call argument", "n 2 nCr", "n 3 nCr", []string{"replaced 2 with 3"}, ""},
+		{"bad first formula", "a +", "a", nil, "insufficient operands for + operation (column 3)"},
+		{"bad second formula", "a", "a b", nil, "invalid expression: expected 1 result, got 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes, err := Diff(test.a, test.b)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("Diff() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Diff() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffChangeKinds(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []ChangeKind
+	}{
+		{"a b + c +", "a d + 2 x * +", []ChangeKind{ChangeReplaced, ChangeReplaced}},
+		{"a b + c +", "a +", nil},
+		{"a b +", "a b + c + d +", []ChangeKind{ChangeAdded, ChangeAdded}},
+		{"a b + c + d +", "a e +", []ChangeKind{ChangeRemoved, ChangeRemoved, ChangeRemoved, ChangeAdded}},
+	}
+	for _, test := range tests {
+		changes, err := Diff(test.a, test.b)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("Diff(%q, %q) succeeded", test.a, test.b)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		kinds := []ChangeKind{}
+		for _, change := range changes {
+			kinds = append(kinds, change.Kind)
+			if (change.Old == nil) != (change.Kind == ChangeAdded) || (change.New == nil) != (change.Kind == ChangeRemoved) {
+				t.Errorf("change %s has Old %v and New %v", change, change.Old, change.New)
+			}
+			if (change.Kind == ChangeReplaced) != (change.Op == "") {
+				t.Errorf("change %s has Op %q", change, change.Op)
+			}
+		}
+		if !slices.Equal(kinds, test.want) {
+			t.Errorf("Diff(%q, %q) kinds = %v, want %v", test.a, test.b, kinds, test.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package number
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Formatter renders a result in decimal. The calculator localizes the
+// output afterwards, so formatters write a point before the fraction
+type Formatter interface {
+	Format(value float64) string
+}
+
+// FormatterFunc adapts a function to the Formatter interface
+type FormatterFunc func(value float64) string
+
+// Format calls the function
+func (fn FormatterFunc) Format(value float64) string {
+	return fn(value)
+}
+
+// FixedFormatter renders a fixed number of decimals, 3.14 for two
+type FixedFormatter struct {
+	Decimals int
+}
+
+// Format renders value with the decimals
+func (f FixedFormatter) Format(value float64) string {
+	return strconv.FormatFloat(value, 'f', max(f.Decimals, 0), 64)
+}
+
+// SignificantFormatter renders a number of significant digits, switching
+// to an exponent for very large or small magnitudes: 3.142 or 1.235e+09
+// for four
+type SignificantFormatter struct {
+	Digits int
+}
+
+// Format renders value with the significant digits
+func (f SignificantFormatter) Format(value float64) string {
+	return strconv.FormatFloat(value, 'g', max(f.Digits, 1), 64)
+}
+
+// ScientificFormatter renders a mantissa with a number of decimals and an
+// exponent, 1.23e+04 for two
+type ScientificFormatter struct {
+	Decimals int
+}
+
+// Format renders value in scientific notation
+func (f ScientificFormatter) Format(value float64) string {
+	return strconv.FormatFloat(value, 'e', max(f.Decimals, 0), 64)
+}
+
+// EngineeringFormatter renders a mantissa in [1, 1000) with a number of
+// significant digits and the SI prefix of the magnitude, 4.7k or 22u, as
+// literals are written. Zero digits writes six, as the eng display base
+type EngineeringFormatter struct {
+	Digits int
+}
+
+// Format renders value with the nearest engineering prefix
+func (f EngineeringFormatter) Format(value float64) string {
+	digits := f.Digits
+	if digits <= 0 {
+		digits = engineeringDigits
+	}
+	return formatEngineering(value, digits)
+}
+
+// GroupedFormatter separates the thousands of the integer part written by
+// another formatter, 1,234,567.50 for a comma. To group with a decimal
+// comma, set a Locale on the calculator instead
+type GroupedFormatter struct {
+	Formatter Formatter
+	Separator string
+}
+
+// Format renders value with the base formatter and groups its digits
+func (f GroupedFormatter) Format(value float64) string {
+	formatted := FormatResult(value, f.Formatter)
+	if strings.ContainsAny(formatted, "eE") {
+		// Exponent notation has a single integer digit to group
+		return formatted
+	}
+	return Locale{Decimal: ".", Group: f.Separator}.localize(formatted)
+}
+
+// defaultFormatter renders two decimals, the calculator's historical output
+var defaultFormatter = FixedFormatter{Decimals: 2}
+
+// FormatResult renders a result with formatter, nil being two decimals.
+// Infinities and NaN are written as +Inf, -Inf and NaN by every formatter
+func FormatResult(value float64, formatter Formatter) string {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	if formatter == nil {
+		formatter = defaultFormatter
+	}
+	return formatter.Format(value)
+}
+
+// SetFormatter sets how decimal results are rendered, nil restoring two
+// decimals. Display bases other than decimal render integral values as
+// before and use the formatter for fractional ones
+func (calc *RPNCalculator) SetFormatter(formatter Formatter) {
+	calc.formatter = formatter
+}
+
+// WithFormatter sets how decimal results are rendered
+func WithFormatter(formatter Formatter) Option {
+	return func(calc *RPNCalculator) { calc.SetFormatter(formatter) }
+}
@@ -0,0 +1,98 @@
+package number
+
+import (
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter Formatter
+		value     float64
+		want      string
+	}{
+		{"default", nil, 3.14159, "3.14"},
+		{"default small", nil, 0.000047, "0.00"},
+		{"fixed", FixedFormatter{Decimals: 3}, 3.14159, "3.142"},
+		{"fixed without decimals", FixedFormatter{}, -1234567.5, "-1234568"},
+		{"fixed negative decimals", FixedFormatter{Decimals: -1}, 3.14159, "3"},
+		{"significant", SignificantFormatter{Digits: 4}, 3.14159, "3.142"},
+		{"significant large", SignificantFormatter{Digits: 4}, -1234567.5, "-1.235e+06"},
+		{"significant small", SignificantFormatter{Digits: 4}, 0.000047, "4.7e-05"},
+		{"significant without digits", SignificantFormatter{}, 3.14159, "3"},
+		{"scientific", ScientificFormatter{Decimals: 2}, 12345, "1.23e+04"},
+		{"scientific zero", ScientificFormatter{Decimals: 2}, 0, "0.00e+00"},
+		{"engineering", EngineeringFormatter{Digits: 3}, -1234567.5, "-1.23M"},
+		{"engineering small", EngineeringFormatter{}, 0.000047, "47u"},
+		{"engineering default digits", EngineeringFormatter{}, -1234567.5, "-1.23457M"},
+		{"grouped", GroupedFormatter{Separator: ","}, -1234567.5, "-1,234,567.50"},
+		{"grouped fixed", GroupedFormatter{Formatter: FixedFormatter{}, Separator: "."}, 1.5e9, "1.500.000.000"},
+		{"grouped short", GroupedFormatter{Separator: ","}, 123, "123.00"},
+		{"grouped exponent", GroupedFormatter{Formatter: ScientificFormatter{Decimals: 2}, Separator: ","}, 1.5e9, "1.50e+09"},
+		{"func", FormatterFunc(func(value float64) string { return "x" }), 1, "x"},
+		{"infinity", ScientificFormatter{Decimals: 2}, math.Inf(-1), "-Inf"},
+		{"nan", FixedFormatter{Decimals: 2}, math.NaN(), "NaN"},
+		{"grouped infinity", GroupedFormatter{Separator: ","}, math.Inf(1), "+Inf"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := FormatResult(test.value, test.formatter); got != test.want {
+				t.Errorf("FormatResult(%g) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCalculatorFormatter(t *testing.T) {
+	tests := []struct {
+		name       string
+		formatter  Formatter
+		expression string
+		value      float64
+		want       string
+	}{
+		{"decimal", FixedFormatter{Decimals: 1}, "", 2.75, "2.8"},
+		{"integral hex ignores the formatter", FixedFormatter{Decimals: 1}, "hex", 255, "0xFF"},
+		{"fractional hex uses the formatter", FixedFormatter{Decimals: 1}, "hex", 2.75, "2.8"},
+		{"nil restores two decimals", nil, "", 2.75, "2.75"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewRPNCalculator(WithFormatter(SignificantFormatter{Digits: 1}))
+			calc.SetFormatter(test.formatter)
+			if test.expression != "" {
+				calc.EvaluateAll(test.expression + " 0")
+			}
+			if got := calc.Format(test.value); got != test.want {
+				t.Errorf("Format(%g) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrintStack(t *testing.T) {
+	calc := NewRPNCalculator(WithFormatter(GroupedFormatter{Formatter: FixedFormatter{Decimals: 1}, Separator: ","}))
+	if _, err := calc.EvaluateAll("1234.56 2 0.25"); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	calc.PrintStack()
+	os.Stdout = stdout
+	writer.Close()
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Stack: [1,234.6, 2.0, 0.2]\n"; string(output) != want {
+		t.Errorf("PrintStack() wrote %q, want %q", output, want)
+	}
+}
@@ -0,0 +1,333 @@
+package number
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Formulas is a named set of compiled programs loaded from a JSON file
+// mapping names to RPN expressions:
+//
+//	{"area": "w h *", "total": "price qty * 1 tax + *"}
+//
+// Reloading recompiles the changed formulas and swaps them in at once, so
+// evaluations see either the old set or the new one. A formula that fails
+// to compile is reported and its previous version stays live. With a
+// reviewer set, changed formulas only go live once it approves them. Every
+// change that goes live is recorded in the history of its name. It is safe
+// for concurrent use
+type Formulas struct {
+	path string
+
+	mu       sync.RWMutex
+	programs map[string]*Program
+	reviewer FormulaReviewer
+	// drafts holds the changes the reviewer has not approved yet, it is
+	// only written with load held
+	drafts map[string]FormulaChange
+	// history holds the versions of every name, it is only written with
+	// load held
+	history map[string][]FormulaVersion
+
+	// load serializes loads, which read and compile outside mu
+	load    sync.Mutex
+	modTime time.Time
+	size    int64
+
+	usage formulaUsage
+}
+
+// NewFormulas creates an empty set of the formulas in the file at path
+func NewFormulas(path string) *Formulas {
+	return &Formulas{
+		path:     path,
+		programs: make(map[string]*Program),
+		drafts:   make(map[string]FormulaChange),
+		history:  make(map[string][]FormulaVersion),
+	}
+}
+
+// Path returns the path of the file the formulas are loaded from
+func (formulas *Formulas) Path() string {
+	return formulas.path
+}
+
+// Get returns the program of the formula called name
+func (formulas *Formulas) Get(name string) (*Program, bool) {
+	formulas.mu.RLock()
+	defer formulas.mu.RUnlock()
+	prog, ok := formulas.programs[name]
+	return prog, ok
+}
+
+// Names returns the names of the formulas, sorted
+func (formulas *Formulas) Names() []string {
+	formulas.mu.RLock()
+	defer formulas.mu.RUnlock()
+	names := make([]string, 0, len(formulas.programs))
+	for name := range formulas.programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval evaluates the formula called name with the given variables,
+// counting the evaluation in the usage of name. Evaluations of a program
+// taken with Get are not counted
+func (formulas *Formulas) Eval(name string, vars map[string]float64) (float64, error) {
+	prog, ok := formulas.Get(name)
+	if !ok {
+		return 0, fmt.Errorf("unknown formula: %s", name)
+	}
+	start := time.Now()
+	result, err := prog.EvalWith(vars)
+	formulas.usage.record(name, start, err)
+	return result, err
+}
+
+// Load reads the file and swaps in the formulas that compile. Formulas
+// that fail to compile are reported together and keep their previous
+// version, formulas dropped from the file are removed
+func (formulas *Formulas) Load() error {
+	return formulas.LoadRevision(FormulaRevision{})
+}
+
+// LoadRevision loads the file like Load, recording the changes it puts
+// live, or drafts, with rev
+func (formulas *Formulas) LoadRevision(rev FormulaRevision) error {
+	formulas.load.Lock()
+	defer formulas.load.Unlock()
+	return formulas.loadLocked(true, rev)
+}
+
+// loadLocked loads the file unless its modification time and size are the
+// ones last read, or always when force is set. The caller must hold
+// formulas.load
+func (formulas *Formulas) loadLocked(force bool, rev FormulaRevision) error {
+	info, err := os.Stat(formulas.path)
+	if err != nil {
+		return err
+	}
+	if !force && info.ModTime().Equal(formulas.modTime) && info.Size() == formulas.size {
+		return nil
+	}
+	data, err := os.ReadFile(formulas.path)
+	if err != nil {
+		return err
+	}
+	// A bad edit is not retried until the file changes again
+	formulas.modTime, formulas.size = info.ModTime(), info.Size()
+
+	var sources map[string]string
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return fmt.Errorf("%s: invalid formula file: %w", formulas.path, err)
+	}
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The program map is replaced rather than changed, so the current one
+	// can be read without the lock while the reviewer runs
+	formulas.mu.RLock()
+	current, reviewer := formulas.programs, formulas.reviewer
+	formulas.mu.RUnlock()
+
+	programs := make(map[string]*Program, len(sources))
+	drafts := make(map[string]FormulaChange)
+	var errs []error
+	for _, name := range names {
+		source := sources[name]
+		previous, ok := current[name]
+		if !ok {
+			previous, ok = formulas.restoredLocked(name)
+		}
+		if ok && previous.String() == source {
+			programs[name] = previous
+			continue
+		}
+		if ok {
+			// Until the change goes live the previous version stays
+			programs[name] = previous
+		}
+		prog, err := Compile(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("formula %s: %w", name, err))
+			continue
+		}
+		if reviewer != nil {
+			change := FormulaChange{Name: name, Formula: source, FormulaRevision: rev}
+			if ok {
+				change.Previous = previous.String()
+			}
+			if draft, drafted := formulas.drafts[name]; drafted && draft.Formula == source {
+				drafts[name] = draft
+				continue
+			}
+			approved, err := reviewer.Review(change)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("formula %s rejected: %w", name, err))
+				continue
+			}
+			if !approved {
+				drafts[name] = change
+				continue
+			}
+		}
+		programs[name] = prog
+	}
+
+	now := time.Now()
+	formulas.mu.Lock()
+	for name, prog := range programs {
+		if current[name] != prog {
+			formulas.recordLocked(name, prog.String(), false, rev, now)
+		}
+	}
+	for name := range current {
+		if _, ok := programs[name]; !ok {
+			formulas.recordLocked(name, "", true, rev, now)
+		}
+	}
+	formulas.programs = programs
+	formulas.drafts = drafts
+	formulas.mu.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %w", formulas.path, errors.Join(errs...))
+	}
+	return nil
+}
+
+// restoredLocked compiles the latest version of name when it is live, as
+// the first load after Restore finds it. The caller must hold
+// formulas.load
+func (formulas *Formulas) restoredLocked(name string) (*Program, bool) {
+	versions := formulas.history[name]
+	if len(versions) == 0 || versions[len(versions)-1].Deleted {
+		return nil, false
+	}
+	prog, err := Compile(versions[len(versions)-1].Formula)
+	return prog, err == nil
+}
+
+// Watch checks the file every interval and loads it when its modification
+// time or size changed, passing load errors to onError. It runs until the
+// returned function is called
+func (formulas *Formulas) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			formulas.load.Lock()
+			err := formulas.loadLocked(false, FormulaRevision{})
+			formulas.load.Unlock()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// FormulaChange is a create or update of a formula submitted to the
+// reviewer, and a draft while it waits for approval
+type FormulaChange struct {
+	Name    string `json:"name"`
+	Formula string `json:"formula"`
+	// Previous is the live formula the change replaces, empty when it
+	// creates the name
+	Previous string `json:"previous,omitempty"`
+	FormulaRevision
+}
+
+// FormulaReviewer reviews every changed formula a load finds before it
+// goes live, so embedding applications can require approval of business
+// formulas. Returning true puts the change live, false keeps it as a draft
+// and an error rejects it
+type FormulaReviewer interface {
+	Review(change FormulaChange) (bool, error)
+}
+
+// FormulaReviewerFunc adapts a function to the FormulaReviewer interface
+type FormulaReviewerFunc func(change FormulaChange) (bool, error)
+
+// Review calls the function
+func (fn FormulaReviewerFunc) Review(change FormulaChange) (bool, error) {
+	return fn(change)
+}
+
+// SetReviewer sets the reviewer of changed formulas, nil, the default,
+// puts every change live at once
+func (formulas *Formulas) SetReviewer(reviewer FormulaReviewer) {
+	formulas.mu.Lock()
+	defer formulas.mu.Unlock()
+	formulas.reviewer = reviewer
+}
+
+// Drafts returns the changes waiting for approval, by name
+func (formulas *Formulas) Drafts() []FormulaChange {
+	formulas.mu.RLock()
+	defer formulas.mu.RUnlock()
+	drafts := make([]FormulaChange, 0, len(formulas.drafts))
+	for _, draft := range formulas.drafts {
+		drafts = append(drafts, draft)
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].Name < drafts[j].Name })
+	return drafts
+}
+
+// Approve puts the draft of name live, recording it in the history with
+// the revision it was loaded with
+func (formulas *Formulas) Approve(name string) error {
+	formulas.load.Lock()
+	defer formulas.load.Unlock()
+	draft, ok := formulas.drafts[name]
+	if !ok {
+		return fmt.Errorf("formula %s has no draft", name)
+	}
+	prog, err := Compile(draft.Formula)
+	if err != nil {
+		return fmt.Errorf("formula %s: %w", name, err)
+	}
+
+	formulas.mu.Lock()
+	defer formulas.mu.Unlock()
+	programs := make(map[string]*Program, len(formulas.programs)+1)
+	for other, current := range formulas.programs {
+		programs[other] = current
+	}
+	programs[name] = prog
+	formulas.programs = programs
+	delete(formulas.drafts, name)
+	formulas.recordLocked(name, prog.String(), false, draft.FormulaRevision, time.Now())
+	return nil
+}
+
+// Reject discards the draft of name, leaving the live formula unchanged
+// until the file changes it again
+func (formulas *Formulas) Reject(name string) error {
+	formulas.load.Lock()
+	defer formulas.load.Unlock()
+	if _, ok := formulas.drafts[name]; !ok {
+		return fmt.Errorf("formula %s has no draft", name)
+	}
+	formulas.mu.Lock()
+	defer formulas.mu.Unlock()
+	delete(formulas.drafts, name)
+	return nil
+}
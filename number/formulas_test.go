@@ -0,0 +1,417 @@
+package number
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFormulas writes a formula file, moving its modification time
+// forward so every write is a new version of the file
+func writeFormulas(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// formulaSources returns the source of every live formula
+func formulaSources(formulas *Formulas) map[string]string {
+	sources := map[string]string{}
+	for _, name := range formulas.Names() {
+		prog, _ := formulas.Get(name)
+		sources[name] = prog.String()
+	}
+	return sources
+}
+
+func TestFormulasLoad(t *testing.T) {
+	// Every step writes the file and loads it
+	steps := []struct {
+		name    string
+		data    string
+		wantErr string
+		want    map[string]string
+	}{
+		{"initial", `{"area": "w h *", "double": "x 2 *"}`, "", map[string]string{"area": "w h *", "double": "x 2 *"}},
+		{"edit and add", `{"area": "w h * 2 /", "double": "x 2 *", "one": "1"}`, "", map[string]string{"area": "w h * 2 /", "double": "x 2 *", "one": "1"}},
+		{
+			"bad edits keep the previous version",
+			`{"area": "w +", "double": "x 3 *", "one": "1 sum", "two": "2 +"}`,
+			"formula area: insufficient operands for + operation (column 3)\nformula one: unsupported token in compiled program: sum (column 3)\nformula two: insufficient operands for + operation (column 3)",
+			map[string]string{"area": "w h * 2 /", "double": "x 3 *", "one": "1"},
+		},
+		{"drop names", `{"double": "x 3 *"}`, "", map[string]string{"double": "x 3 *"}},
+		{"not json", `"x"`, "invalid formula file: json: cannot unmarshal string into Go value of type map[string]string", map[string]string{"double": "x 3 *"}},
+		{"empty", `{}`, "", map[string]string{}},
+	}
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	formulas := NewFormulas(path)
+	if err := formulas.Load(); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			writeFormulas(t, path, step.data)
+			err := formulas.Load()
+			if step.wantErr == "" && err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if step.wantErr != "" && (err == nil || strings.TrimPrefix(err.Error(), path+": ") != step.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, step.wantErr)
+			}
+			got := formulaSources(formulas)
+			if len(got) != len(step.want) {
+				t.Fatalf("formulas = %v, want %v", got, step.want)
+			}
+			for name, source := range step.want {
+				if got[name] != source {
+					t.Errorf("formula %s = %q, want %q", name, got[name], source)
+				}
+			}
+		})
+	}
+}
+
+func TestFormulasKeepUnchangedPrograms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	writeFormulas(t, path, `{"a": "1 2 +", "b": "x 2 *"}`)
+	formulas := NewFormulas(path)
+	if err := formulas.Load(); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := formulas.Get("a")
+	b, _ := formulas.Get("b")
+	writeFormulas(t, path, `{"a": "1 2 +", "b": "x 3 *"}`)
+	if err := formulas.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := formulas.Get("a"); got != a {
+		t.Error("unchanged formula a was compiled again")
+	}
+	if got, _ := formulas.Get("b"); got == b {
+		t.Error("changed formula b kept its program")
+	}
+}
+
+func TestFormulasEval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	writeFormulas(t, path, `{"area": "w h *"}`)
+	formulas := NewFormulas(path)
+	if err := formulas.Load(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		formula string
+		vars    map[string]float64
+		want    float64
+		wantErr string
+	}{
+		{"bound", "area", map[string]float64{"w": 2, "h": 3}, 6, ""},
+		{"unbound", "area", map[string]float64{"w": 2}, 0, "undefined variable: h"},
+		{"unknown", "volume", nil, 0, "unknown formula: volume"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := formulas.Eval(test.formula, test.vars)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("Eval() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("Eval() = %g, %v, want %g", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestFormulasWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	writeFormulas(t, path, `{"a": "1"}`)
+	formulas := NewFormulas(path)
+	errs := make(chan error, 10)
+	stop := formulas.Watch(5*time.Millisecond, func(err error) { errs <- err })
+	defer stop()
+
+	waitFor := func(what string, done func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !done(); time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+		}
+	}
+	waitFor("the first load", func() bool { return slices.Equal(formulas.Names(), []string{"a"}) })
+
+	writeFormulas(t, path, `{"a": "1 +", "b": "2"}`)
+	select {
+	case err := <-errs:
+		if err == nil || !strings.HasSuffix(err.Error(), "formula a: insufficient operands for + operation (column 3)") {
+			t.Errorf("Watch reported %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not report the bad edit")
+	}
+	if got, err := formulas.Eval("a", nil); err != nil || got != 1 {
+		t.Errorf("a after a bad edit = %g, %v, want 1", got, err)
+	}
+	if got, err := formulas.Eval("b", nil); err != nil || got != 2 {
+		t.Errorf("b = %g, %v, want 2", got, err)
+	}
+	stop()
+	stop()
+	if len(errs) != 0 {
+		t.Errorf("Watch reported %v", <-errs)
+	}
+}
+
+func TestFormulasReviewer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	formulas := NewFormulas(path)
+	// The reviewer approves formulas using 2, rejects those using 9 and
+	// drafts the rest
+	reviewed := []FormulaChange{}
+	formulas.SetReviewer(FormulaReviewerFunc(func(change FormulaChange) (bool, error) {
+		reviewed = append(reviewed, change)
+		if strings.Contains(change.Formula, "9") {
+			return false, errors.New("nines are not allowed")
+		}
+		return strings.Contains(change.Formula, "2"), nil
+	}))
+
+	// Every step writes the file unless data is empty, then changes the
+	// formulas and checks the live ones, the drafts and the reviews
+	steps := []struct {
+		name     string
+		data     string
+		change   func() error
+		wantErr  string
+		live     map[string]string
+		drafts   []string
+		reviewed int
+	}{
+		{"approved and drafted", `{"a": "x 2 *", "b": "x 3 *"}`, formulas.Load, "",
+			map[string]string{"a": "x 2 *"}, []string{"b"}, 2},
+		{"reload keeps the draft", `{"a": "x 2 *", "b": "x 3 *"}`, formulas.Load, "",
+			map[string]string{"a": "x 2 *"}, []string{"b"}, 2},
+		{"approve", "", func() error { return formulas.Approve("b") }, "",
+			map[string]string{"a": "x 2 *", "b": "x 3 *"}, []string{}, 2},
+		{"approve without a draft", "", func() error { return formulas.Approve("b") }, "formula b has no draft",
+			map[string]string{"a": "x 2 *", "b": "x 3 *"}, []string{}, 2},
+		{"drafted edit keeps the live version", `{"a": "x 4 *", "b": "x 3 *"}`, formulas.Load, "",
+			map[string]string{"a": "x 2 *", "b": "x 3 *"}, []string{"a"}, 3},
+		{"reject", "", func() error { return formulas.Reject("a") }, "",
+			map[string]string{"a": "x 2 *", "b": "x 3 *"}, []string{}, 3},
+		{"reject without a draft", "", func() error { return formulas.Reject("a") }, "formula a has no draft",
+			map[string]string{"a": "x 2 *", "b": "x 3 *"}, []string{}, 3},
+		{"rejected edit", `{"a": "x 2 *", "b": "x 9 *"}`, formulas.Load, "formula b rejected: nines are not allowed",
+			map[string]string{"a": "x 2 *", "b": "x 3 *"}, []string{}, 4},
+		{"removal is not reviewed", `{"b": "x 3 *"}`, formulas.Load, "",
+			map[string]string{"b": "x 3 *"}, []string{}, 4},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.data != "" {
+				writeFormulas(t, path, step.data)
+			}
+			err := step.change()
+			if step.wantErr == "" && err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if step.wantErr != "" && (err == nil || strings.TrimPrefix(err.Error(), path+": ") != step.wantErr) {
+				t.Errorf("error = %v, want %q", err, step.wantErr)
+			}
+			if got := formulaSources(formulas); !maps.Equal(got, step.live) {
+				t.Errorf("live formulas = %v, want %v", got, step.live)
+			}
+			drafts := []string{}
+			for _, draft := range formulas.Drafts() {
+				drafts = append(drafts, draft.Name)
+			}
+			if !slices.Equal(drafts, step.drafts) {
+				t.Errorf("Drafts() = %v, want %v", drafts, step.drafts)
+			}
+			if len(reviewed) != step.reviewed {
+				t.Errorf("%d reviews, want %d", len(reviewed), step.reviewed)
+			}
+		})
+	}
+	if edit := reviewed[2]; edit.Name != "a" || edit.Previous != "x 2 *" || edit.Formula != "x 4 *" {
+		t.Errorf("edit reviewed as %+v", edit)
+	}
+}
+
+func TestFormulasHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	formulas := NewFormulas(path)
+	alice := FormulaRevision{Author: "alice", Comment: "first"}
+
+	// Every step writes the file unless data is empty, then changes the
+	// formulas and checks the latest version of "area"
+	steps := []struct {
+		name    string
+		data    string
+		change  func() (FormulaVersion, error)
+		want    FormulaVersion
+		wantErr string
+	}{
+		{"load", `{"area": "w h *", "id": "x"}`, func() (FormulaVersion, error) { return latest(formulas, formulas.LoadRevision(alice)) },
+			FormulaVersion{Version: 1, Formula: "w h *", FormulaRevision: alice}, ""},
+		{"reload unchanged", `{"area": "w h *"}`, func() (FormulaVersion, error) { return latest(formulas, formulas.Load()) },
+			FormulaVersion{Version: 1, Formula: "w h *", FormulaRevision: alice}, ""},
+		{"edit", `{"area": "h w * 2 /"}`, func() (FormulaVersion, error) { return latest(formulas, formulas.Load()) },
+			FormulaVersion{Version: 2, Formula: "h w * 2 /"}, ""},
+		{"bad edit", `{"area": "w *"}`, func() (FormulaVersion, error) { return latest(formulas, formulas.Load()) },
+			FormulaVersion{Version: 2, Formula: "h w * 2 /"}, ""},
+		{"rollback", "", func() (FormulaVersion, error) { return formulas.Rollback("area", 1, FormulaRevision{Author: "bob"}) },
+			FormulaVersion{Version: 3, Formula: "w h *", FormulaRevision: FormulaRevision{Author: "bob", Comment: "rollback to version 1"}}, ""},
+		{"rollback to the current version", "", func() (FormulaVersion, error) { return formulas.Rollback("area", 3, alice) },
+			FormulaVersion{Version: 3, Formula: "w h *", FormulaRevision: FormulaRevision{Author: "bob", Comment: "rollback to version 1"}}, ""},
+		{"remove", `{}`, func() (FormulaVersion, error) { return latest(formulas, formulas.LoadRevision(alice)) },
+			FormulaVersion{Version: 4, Deleted: true, FormulaRevision: alice}, ""},
+		{"rollback to a removal", "", func() (FormulaVersion, error) { return formulas.Rollback("area", 4, alice) },
+			FormulaVersion{}, "formula area version 4 is a removal"},
+		{"rollback to a missing version", "", func() (FormulaVersion, error) { return formulas.Rollback("area", 5, alice) },
+			FormulaVersion{}, "formula area has no version 5"},
+		{"rollback after the removal", "", func() (FormulaVersion, error) { return formulas.Rollback("area", 2, alice) },
+			FormulaVersion{Version: 5, Formula: "h w * 2 /", FormulaRevision: alice}, ""},
+	}
+	for _, step := range steps {
+		if step.data != "" {
+			writeFormulas(t, path, step.data)
+		}
+		got, err := step.change()
+		if step.wantErr != "" {
+			if err == nil || err.Error() != step.wantErr {
+				t.Errorf("%s: error = %v, want %q", step.name, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil && step.name != "bad edit" {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got.Time.IsZero() {
+			t.Errorf("%s: version has no time", step.name)
+		}
+		got.Time = step.want.Time
+		if got != step.want {
+			t.Errorf("%s: version = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	// The rollback is written to the file, so loading it keeps the formula
+	if err := formulas.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := formulaSources(formulas); !maps.Equal(got, map[string]string{"area": "h w * 2 /"}) {
+		t.Errorf("live formulas after reloading = %v", got)
+	}
+	if history := formulas.History("area"); len(history) != 5 {
+		t.Errorf("History() has %d versions, want 5", len(history))
+	}
+	if history := formulas.History("id"); len(history) != 2 || !history[1].Deleted {
+		t.Errorf("History() of a removed formula = %+v", history)
+	}
+
+	tests := []struct {
+		from, to int
+		changes  []string
+		changed  bool
+		wantErr  string
+	}{
+		{1, 3, nil, false, ""},
+		{1, 2, []string{"replaced h * w with h * w / 2"}, true, ""},
+		{3, 4, nil, true, ""},
+		{0, 1, nil, false, "formula area has no version 0"},
+	}
+	for _, test := range tests {
+		diff, err := formulas.Diff("area", test.from, test.to)
+		if test.wantErr != "" {
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("Diff(%d, %d) error = %v, want %q", test.from, test.to, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes := []string{}
+		for _, change := range diff.Changes {
+			changes = append(changes, change.String())
+		}
+		if !slices.Equal(changes, append([]string{}, test.changes...)) || diff.Changed() != test.changed {
+			t.Errorf("Diff(%d, %d) = %v changed %t, want %v changed %t", test.from, test.to, changes, diff.Changed(), test.changes, test.changed)
+		}
+	}
+}
+
+// latest returns the latest version of "area" after a load
+func latest(formulas *Formulas, err error) (FormulaVersion, error) {
+	history := formulas.History("area")
+	return history[len(history)-1], err
+}
+
+func TestFormulasStateRestores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	formulas := NewFormulas(path)
+	writeFormulas(t, path, `{"area": "w h *"}`)
+	if err := formulas.LoadRevision(FormulaRevision{Author: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	formulas.SetReviewer(FormulaReviewerFunc(func(FormulaChange) (bool, error) { return false, nil }))
+	writeFormulas(t, path, `{"area": "w h * 2 *"}`)
+	if err := formulas.LoadRevision(FormulaRevision{Author: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := formulas.Eval("area", map[string]float64{"w": 2, "h": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	reviews := 0
+	restored := NewFormulas(path)
+	restored.SetReviewer(FormulaReviewerFunc(func(FormulaChange) (bool, error) { reviews++; return false, nil }))
+	if err := restored.Restore(formulas.State()); err != nil {
+		t.Fatal(err)
+	}
+	// The restored draft of the edit is live in neither set, so the first
+	// load puts the previous version live again and keeps the draft
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if history := restored.History("area"); len(history) != 1 || history[0].Author != "alice" {
+		t.Errorf("restored History() = %+v", history)
+	}
+	if drafts := restored.Drafts(); len(drafts) != 1 || drafts[0].Author != "bob" || reviews != 0 {
+		t.Errorf("restored Drafts() = %+v after %d reviews", drafts, reviews)
+	}
+	if usage := restored.Usage(); len(usage) != 1 || usage[0].Evaluations != 1 {
+		t.Errorf("restored Usage() = %+v", usage)
+	}
+	if err := restored.Approve("area"); err != nil {
+		t.Fatal(err)
+	}
+	if history := restored.History("area"); len(history) != 2 || history[1].Formula != "w h * 2 *" || history[1].Author != "bob" {
+		t.Errorf("History() after approving = %+v", history)
+	}
+
+	err := restored.Restore(FormulaState{History: map[string][]FormulaVersion{"area": {{Version: 2}}}})
+	if want := "formula area history has version 2 at position 1"; err == nil || err.Error() != want {
+		t.Errorf("Restore() error = %v, want %q", err, want)
+	}
+}
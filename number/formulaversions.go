@@ -0,0 +1,218 @@
+package number
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// FormulaRevision describes who made a change to a formula and why
+type FormulaRevision struct {
+	Author  string `json:"author,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// FormulaVersion is one version in the history of a formula
+type FormulaVersion struct {
+	// Version numbers the versions of a name from 1, removals included
+	Version int    `json:"version"`
+	Formula string `json:"formula,omitempty"`
+	// Deleted marks a version removing the formula, whose Formula is empty
+	Deleted bool      `json:"deleted,omitempty"`
+	Time    time.Time `json:"time"`
+	FormulaRevision
+}
+
+// FormulaDiff compares two versions of a formula structurally, so edits
+// that only change layout, operand order or constant arithmetic are not
+// reported as changes
+type FormulaDiff struct {
+	Name string
+	From FormulaVersion
+	To   FormulaVersion
+	// Changes are the changes between the formulas as Diff reports them,
+	// none when either version is a removal
+	Changes []ExprChange
+}
+
+// Changed reports whether the versions compute differently or only one of
+// them removes the formula
+func (diff FormulaDiff) Changed() bool {
+	return len(diff.Changes) > 0 || diff.From.Deleted != diff.To.Deleted
+}
+
+// FormulaState is what Formulas keeps besides the file it loads: the
+// history of every name, the drafts waiting for approval and the usage
+type FormulaState struct {
+	History map[string][]FormulaVersion `json:"history,omitempty"`
+	Drafts  map[string]FormulaChange    `json:"drafts,omitempty"`
+	Usage   map[string]FormulaUsage     `json:"usage,omitempty"`
+}
+
+// recordLocked appends a version to the history of name unless it matches
+// the latest one. The caller must hold formulas.load and formulas.mu
+func (formulas *Formulas) recordLocked(name, formula string, deleted bool, rev FormulaRevision, now time.Time) FormulaVersion {
+	versions := formulas.history[name]
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if latest.Deleted == deleted && latest.Formula == formula {
+			return latest
+		}
+	} else if deleted {
+		return FormulaVersion{}
+	}
+	version := FormulaVersion{Version: len(versions) + 1, Formula: formula, Deleted: deleted, Time: now, FormulaRevision: rev}
+	formulas.history[name] = append(versions, version)
+	return version
+}
+
+// History returns every version of the formula called name, oldest first,
+// including the versions before a removal
+func (formulas *Formulas) History(name string) []FormulaVersion {
+	formulas.mu.RLock()
+	defer formulas.mu.RUnlock()
+	return append([]FormulaVersion(nil), formulas.history[name]...)
+}
+
+// versionLocked returns a version of name. The caller must hold
+// formulas.mu or formulas.load
+func (formulas *Formulas) versionLocked(name string, version int) (FormulaVersion, error) {
+	versions := formulas.history[name]
+	if version < 1 || version > len(versions) {
+		return FormulaVersion{}, fmt.Errorf("formula %s has no version %d", name, version)
+	}
+	return versions[version-1], nil
+}
+
+// Diff compares two versions of the formula called name
+func (formulas *Formulas) Diff(name string, from, to int) (FormulaDiff, error) {
+	formulas.mu.RLock()
+	defer formulas.mu.RUnlock()
+	diff := FormulaDiff{Name: name}
+	var err error
+	if diff.From, err = formulas.versionLocked(name, from); err != nil {
+		return FormulaDiff{}, err
+	}
+	if diff.To, err = formulas.versionLocked(name, to); err != nil {
+		return FormulaDiff{}, err
+	}
+	if diff.From.Deleted || diff.To.Deleted {
+		return diff, nil
+	}
+	// Every version compiled when it went live
+	diff.Changes, _ = Diff(diff.From.Formula, diff.To.Formula)
+	return diff, nil
+}
+
+// Rollback puts the formula of an earlier version of name live again, as a
+// new version, and writes it to the file so the next load keeps it. Other
+// edits of the file not loaded yet are kept and go live with the next
+// load. An empty comment records which version was restored. The version
+// restored was live before, so the reviewer does not review it again
+func (formulas *Formulas) Rollback(name string, version int, rev FormulaRevision) (FormulaVersion, error) {
+	formulas.load.Lock()
+	defer formulas.load.Unlock()
+	target, err := formulas.versionLocked(name, version)
+	if err != nil {
+		return FormulaVersion{}, err
+	}
+	if target.Deleted {
+		return FormulaVersion{}, fmt.Errorf("formula %s version %d is a removal", name, version)
+	}
+	prog, err := Compile(target.Formula)
+	if err != nil {
+		return FormulaVersion{}, fmt.Errorf("formula %s: %w", name, err)
+	}
+	if err := formulas.writeSource(name, target.Formula); err != nil {
+		return FormulaVersion{}, err
+	}
+	if rev.Comment == "" {
+		rev.Comment = fmt.Sprintf("rollback to version %d", version)
+	}
+
+	formulas.mu.Lock()
+	defer formulas.mu.Unlock()
+	programs := make(map[string]*Program, len(formulas.programs)+1)
+	for other, current := range formulas.programs {
+		programs[other] = current
+	}
+	programs[name] = prog
+	formulas.programs = programs
+	return formulas.recordLocked(name, prog.String(), false, rev, time.Now()), nil
+}
+
+// writeSource sets the formula called name in the file, keeping the other
+// entries. The caller must hold formulas.load
+func (formulas *Formulas) writeSource(name, formula string) error {
+	sources := map[string]string{}
+	mode := fs.FileMode(0o644)
+	data, err := os.ReadFile(formulas.path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &sources); err != nil {
+			return fmt.Errorf("%s: invalid formula file: %w", formulas.path, err)
+		}
+		if info, err := os.Stat(formulas.path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	sources[name] = formula
+	data, err = json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(formulas.path, append(data, '\n'), mode)
+}
+
+// State returns a copy of the history, drafts and usage of the formulas,
+// for persisting them along with the file
+func (formulas *Formulas) State() FormulaState {
+	formulas.mu.RLock()
+	defer formulas.mu.RUnlock()
+	state := FormulaState{
+		History: make(map[string][]FormulaVersion, len(formulas.history)),
+		Drafts:  make(map[string]FormulaChange, len(formulas.drafts)),
+		Usage:   formulas.usage.snapshot(),
+	}
+	for name, versions := range formulas.history {
+		state.History[name] = append([]FormulaVersion(nil), versions...)
+	}
+	for name, draft := range formulas.drafts {
+		state.Drafts[name] = draft
+	}
+	return state
+}
+
+// Restore replaces the history, drafts and usage with a state State
+// returned, keeping them as they were. Restore before the first Load: the
+// load then records only the formulas that differ from their latest
+// version, and a draft the file still holds stays a draft, without another
+// review, while the latest version of its formula goes live
+func (formulas *Formulas) Restore(state FormulaState) error {
+	for name, versions := range state.History {
+		for i, version := range versions {
+			if version.Version != i+1 {
+				return fmt.Errorf("formula %s history has version %d at position %d", name, version.Version, i+1)
+			}
+		}
+	}
+	formulas.load.Lock()
+	defer formulas.load.Unlock()
+	formulas.mu.Lock()
+	defer formulas.mu.Unlock()
+	formulas.history = make(map[string][]FormulaVersion, len(state.History))
+	for name, versions := range state.History {
+		formulas.history[name] = append([]FormulaVersion(nil), versions...)
+	}
+	formulas.drafts = make(map[string]FormulaChange, len(state.Drafts))
+	for name, draft := range state.Drafts {
+		formulas.drafts[name] = draft
+	}
+	formulas.usage.restore(state.Usage)
+	return nil
+}
@@ -0,0 +1,81 @@
+package number
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// functions maps named functions to their operators. A function of arity
+// VariadicArity takes a count from the top of the stack and then that many
+// operands, like the counted reducers
+var functions = map[string]engine.Operator[float64]{
+	"sin":   engine.Unary(math.Sin),
+	"cos":   engine.Unary(math.Cos),
+	"tan":   engine.Unary(math.Tan),
+	"asin":  engine.Unary(math.Asin),
+	"acos":  engine.Unary(math.Acos),
+	"atan":  engine.Unary(math.Atan),
+	"atan2": engine.Binary(math.Atan2),
+	"hypot": engine.Binary(math.Hypot),
+	"clamp": {Arity: 3, Apply: clamp},
+}
+
+// clamp limits a value to the bounds [lo, hi]
+func clamp(operands []float64) (float64, error) {
+	value, lo, hi := operands[0], operands[1], operands[2]
+	if lo > hi {
+		return 0, fmt.Errorf("clamp lower bound %g is above upper bound %g", lo, hi)
+	}
+	return math.Min(math.Max(value, lo), hi), nil
+}
+
+// RegisterFunction adds a function taking operator.Arity operands, or a
+// count and that many operands for VariadicArity, to every calculator,
+// compiled program and expression tree. info documents it and its Symbol
+// names it, which must be an identifier no operator uses. Functions must
+// not depend on anything but their operands, as Optimize folds them over
+// constants. Register functions before evaluating, the table is not
+// synchronized
+func RegisterFunction(info OperatorInfo, operator engine.Operator[float64]) error {
+	name := info.Symbol
+	if !isIdentifier(name) {
+		return fmt.Errorf("function name %q is not an identifier", name)
+	}
+	if _, ok := operatorRegistry[name]; ok || name == labelToken || isCalculatorOnlyToken(name) {
+		return fmt.Errorf("%s is already defined", name)
+	}
+	if operator.Arity < VariadicArity || operator.Apply == nil {
+		return fmt.Errorf("invalid function %s: arity %d", name, operator.Arity)
+	}
+	info.Arity = operator.Arity
+	functions[name] = operator
+	operatorRegistry[name] = info
+	return nil
+}
+
+// performFunction applies a function to the top of the stack
+func (calc *RPNCalculator) performFunction(name string, operator engine.Operator[float64]) error {
+	operator = calc.inAngleMode(name, operator)
+	if operator.Arity != VariadicArity {
+		return calc.Apply(name, operator)
+	}
+
+	n, err := calc.Peek()
+	if err != nil {
+		return engine.Underflow(name)
+	}
+	if n != math.Trunc(n) || n < 0 {
+		return fmt.Errorf("invalid count for %s: %g", name, n)
+	}
+	if int(n) >= calc.Size() {
+		return engine.Underflow(name)
+	}
+	calc.Pop()
+	if err := calc.Apply(name, engine.Operator[float64]{Arity: int(n), Apply: operator.Apply}); err != nil {
+		calc.Push(n)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package number
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxExactInteger is the largest integer float64 represents exactly
+const maxExactInteger = 1 << 53
+
+// IntegerPolicy selects how integer operators treat fractional operands
+type IntegerPolicy int
+
+const (
+	// IntegerStrict rejects fractional operands with an error
+	IntegerStrict IntegerPolicy = iota
+	// IntegerRound rounds fractional operands to the nearest integer
+	IntegerRound
+)
+
+// SetIntegerPolicy sets how gcd and lcm treat fractional operands
+func (calc *RPNCalculator) SetIntegerPolicy(policy IntegerPolicy) {
+	calc.integerPolicy = policy
+}
+
+// performIntegerOperation applies gcd or lcm using the calculator's integer policy
+func (calc *RPNCalculator) performIntegerOperation(token string) error {
+	policy := calc.integerPolicy
+	return calc.performCheckedBinaryOperation(token, func(a, b float64) (float64, error) {
+		return integerOperation(token, a, b, policy)
+	})
+}
+
+// toInteger converts an operand according to policy
+func toInteger(value float64, operation string, policy IntegerPolicy) (int64, error) {
+	if policy == IntegerRound {
+		value = math.Round(value)
+	}
+	if value != math.Trunc(value) {
+		return 0, fmt.Errorf("%s requires integer operands, got %g", operation, value)
+	}
+	if math.Abs(value) > maxExactInteger {
+		return 0, fmt.Errorf("%s operand %g exceeds the exact integer range", operation, value)
+	}
+	return int64(math.Abs(value)), nil
+}
+
+// integerOperation computes gcd or lcm of two operands
+func integerOperation(operation string, a, b float64, policy IntegerPolicy) (float64, error) {
+	x, err := toInteger(a, operation, policy)
+	if err != nil {
+		return 0, err
+	}
+	y, err := toInteger(b, operation, policy)
+	if err != nil {
+		return 0, err
+	}
+
+	divisor := gcdInt(x, y)
+	if operation == "gcd" {
+		return float64(divisor), nil
+	}
+
+	if divisor == 0 {
+		return 0, nil
+	}
+	multiple := x / divisor
+	if y != 0 && multiple > maxExactInteger/y {
+		return 0, fmt.Errorf("lcm result exceeds the exact integer range")
+	}
+	return float64(multiple * y), nil
+}
+
+// gcdInt computes the greatest common divisor with Euclid's algorithm
+func gcdInt(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// gcd computes the greatest common divisor of two integers
+func gcd(a, b float64) (float64, error) {
+	return integerOperation("gcd", a, b, IntegerStrict)
+}
+
+// lcm computes the least common multiple of two integers
+func lcm(a, b float64) (float64, error) {
+	return integerOperation("lcm", a, b, IntegerStrict)
+}
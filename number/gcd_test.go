@@ -0,0 +1,127 @@
+package number
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGCDAndLCM(t *testing.T) {
+	tests := []struct {
+		expression string
+		policy     IntegerPolicy
+		want       float64
+	}{
+		{"12 18 gcd", IntegerStrict, 6},
+		{"-12 18 gcd", IntegerStrict, 6},
+		{"0 5 gcd", IntegerStrict, 5},
+		{"0 0 gcd", IntegerStrict, 0},
+		{"4 6 lcm", IntegerStrict, 12},
+		{"-4 6 lcm", IntegerStrict, 12},
+		{"0 6 lcm", IntegerStrict, 0},
+		{"12.4 18 gcd", IntegerRound, 6},
+		{"3.6 6 lcm", IntegerRound, 12},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			calc.SetIntegerPolicy(test.policy)
+			got, err := calc.EvaluateExpression(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("EvaluateExpression(%q) = %g, want %g", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGCDAndLCMErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"12.5 18 gcd", "gcd requires integer operands, got 12.5 (column 9)"},
+		{"4 6.5 lcm", "lcm requires integer operands, got 6.5 (column 7)"},
+		{"1e16 3 gcd", "gcd operand 1e+16 exceeds the exact integer range (column 8)"},
+		{"9007199254740991 9007199254740990 lcm", "lcm result exceeds the exact integer range (column 35)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestGCDAndLCMExpressionTrees(t *testing.T) {
+	tests := []struct {
+		rpn   string
+		want  float64
+		infix string
+		latex string
+	}{
+		{"12 18 gcd", 6, "gcd(12, 18)", `\gcd\left(12, 18\right)`},
+		{"4 6 lcm", 12, "lcm(4, 6)", `\operatorname{lcm}\left(4, 6\right)`},
+		{"8 12 gcd 5 lcm", 20, "lcm(gcd(8, 12), 5)", `\operatorname{lcm}\left(\gcd\left(8, 12\right), 5\right)`},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value, err := expr.Eval(); err != nil || value != test.want {
+				t.Errorf("Eval() = %g, %v, want %g", value, err, test.want)
+			}
+			if infix := formatInfix(expr); infix != test.infix {
+				t.Errorf("infix = %q, want %q", infix, test.infix)
+			}
+			if latex := ToLaTeX(expr); latex != test.latex {
+				t.Errorf("ToLaTeX() = %q, want %q", latex, test.latex)
+			}
+		})
+	}
+
+	fractional, err := ParseRPN("12.5 18 gcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fractional.Eval(); err == nil {
+		t.Error("Eval() of a fractional gcd operand succeeded")
+	}
+}
+
+func TestGenerateGoGCDAndLCM(t *testing.T) {
+	tests := []struct {
+		rpn     string
+		helpers []string
+	}{
+		{"a b gcd", []string{"rpnGCD"}},
+		{"a b lcm", []string{"rpnGCD", "rpnLCM"}},
+		{"a b gcd c lcm", []string{"rpnGCD", "rpnLCM"}},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, err := GenerateGo(expr, GoOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			typeCheckGo(t, source)
+			for _, helper := range test.helpers {
+				if strings.Count(source, helper+" := func") != 1 {
+					t.Errorf("generated Go does not declare %s once:\n%s", helper, source)
+				}
+			}
+			if strings.Index(source, "rpnLCM := func") >= 0 && strings.Index(source, "rpnGCD := func") > strings.Index(source, "rpnLCM := func") {
+				t.Errorf("rpnGCD is declared after rpnLCM, which calls it:\n%s", source)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package number
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// goalSeekScans is how many equal steps GoalSeek splits the bounds into
+// looking for a change of sign when the bounds themselves do not bracket
+// the target
+const goalSeekScans = 64
+
+// goalSeekIterations bounds the bisection steps, more than enough to
+// narrow any float64 interval to adjacent values
+const goalSeekIterations = 2100
+
+// GoalSeek searches bounds for the value of the variable adjust making the
+// program hit value, the other variables keeping their values in vars, as
+// the goal seek of a spreadsheet. target names a label of the program to
+// hit instead of the result, empty for the result. The output must be
+// continuous in adjust over the bounds, the search bisects a change of
+// sign of its distance to value
+func GoalSeek(prog *Program, vars map[string]float64, target string, value float64, adjust string, bounds Interval) (float64, error) {
+	if !slices.Contains(prog.Variables(), adjust) {
+		return 0, fmt.Errorf("program does not read variable %s", adjust)
+	}
+	if target != "" && !slices.Contains(prog.Labels(), target) {
+		return 0, fmt.Errorf("program has no label %s", target)
+	}
+	if !(bounds.Lo <= bounds.Hi) || math.IsInf(bounds.Lo, 0) || math.IsInf(bounds.Hi, 0) {
+		return 0, fmt.Errorf("invalid goal seek bounds %s", bounds)
+	}
+
+	inputs := maps.Clone(vars)
+	if inputs == nil {
+		inputs = map[string]float64{}
+	}
+	// miss returns how far the output is from value with adjust set to x
+	miss := func(x float64) (float64, error) {
+		inputs[adjust] = x
+		result, labels, err := prog.EvalLabeled(inputs)
+		if err != nil {
+			return 0, fmt.Errorf("%s = %g: %w", adjust, x, err)
+		}
+		if target != "" {
+			result = labels[target]
+		}
+		if math.IsNaN(result) {
+			return 0, fmt.Errorf("%s = %g gives NaN", adjust, x)
+		}
+		return result - value, nil
+	}
+
+	lo, hi := bounds.Lo, bounds.Hi
+	missLo, err := miss(lo)
+	if err != nil {
+		return 0, err
+	}
+	if missLo == 0 {
+		return lo, nil
+	}
+	// Scan for the first step whose ends are on different sides of value
+	found := false
+	for i := 1; i <= goalSeekScans && !found; i++ {
+		x := bounds.Lo + (bounds.Hi-bounds.Lo)*float64(i)/goalSeekScans
+		missX, err := miss(x)
+		if err != nil {
+			return 0, err
+		}
+		if missX == 0 {
+			return x, nil
+		}
+		if math.Signbit(missX) != math.Signbit(missLo) {
+			hi, found = x, true
+		} else {
+			lo, missLo = x, missX
+		}
+	}
+	if !found {
+		output := "result"
+		if target != "" {
+			output = target
+		}
+		return 0, fmt.Errorf("no value of %s in %s brings the %s to %g", adjust, bounds, output, value)
+	}
+
+	for range goalSeekIterations {
+		mid := lo + (hi-lo)/2
+		if mid == lo || mid == hi {
+			break
+		}
+		missMid, err := miss(mid)
+		if err != nil {
+			return 0, err
+		}
+		if missMid == 0 {
+			return mid, nil
+		}
+		if math.Signbit(missMid) == math.Signbit(missLo) {
+			lo, missLo = mid, missMid
+		} else {
+			hi = mid
+		}
+	}
+	// Return the end closer to the target
+	missHi, err := miss(hi)
+	if err != nil {
+		return 0, err
+	}
+	if math.Abs(missHi) < math.Abs(missLo) {
+		return hi, nil
+	}
+	return lo, nil
+}
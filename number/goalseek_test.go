@@ -0,0 +1,60 @@
+package number
+
+import (
+	"maps"
+	"math"
+	"testing"
+)
+
+func TestGoalSeek(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		vars       map[string]float64
+		target     string
+		value      float64
+		adjust     string
+		bounds     Interval
+		want       float64
+		wantErr    string
+	}{
+		{"linear", "x 2 * 3 +", nil, "", 11, "x", Interval{0, 10}, 4, ""},
+		{"decreasing", "10 x -", nil, "", 2.5, "x", Interval{0, 10}, 7.5, ""},
+		{"square root", "x x *", nil, "", 2, "x", Interval{0, 2}, math.Sqrt2, ""},
+		{"at the lower bound", "x 2 * 3 +", nil, "", 3, "x", Interval{0, 5}, 0, ""},
+		{"at the upper bound", "x 2 * 3 +", nil, "", 13, "x", Interval{0, 5}, 5, ""},
+		{"first root inside the bounds", "x x * 4 -", nil, "", 0, "x", Interval{-3, 3}, -2, ""},
+		{"other variables", "p q * 50 -", map[string]float64{"p": 10}, "", 450, "q", Interval{0, 100}, 50, ""},
+		{"label", "p q * as revenue 50 -", map[string]float64{"p": 10}, "revenue", 500, "q", Interval{0, 100}, 50, ""},
+		{"unread variable", "x 2 *", nil, "", 1, "y", Interval{0, 1}, 0, "program does not read variable y"},
+		{"unknown label", "x 2 *", nil, "total", 1, "x", Interval{0, 1}, 0, "program has no label total"},
+		{"inverted bounds", "x 2 *", nil, "", 1, "x", Interval{1, 0}, 0, "invalid goal seek bounds [1,0]"},
+		{"infinite bounds", "x 2 *", nil, "", 1, "x", Interval{0, math.Inf(1)}, 0, "invalid goal seek bounds [0,+Inf]"},
+		{"no solution", "x x *", nil, "", -1, "x", Interval{-2, 2}, 0, "no value of x in [-2,2] brings the result to -1"},
+		{"no solution for a label", "x as y 1 +", nil, "y", 5, "x", Interval{0, 1}, 0, "no value of x in [0,1] brings the y to 5"},
+		{"unbound variable", "x y +", nil, "", 1, "x", Interval{0, 1}, 0, "x = 0: undefined variable: y"},
+		{"nan output", "x sqrt", nil, "", 1, "x", Interval{-1, 4}, 0, "x = -1 gives NaN"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vars := maps.Clone(test.vars)
+			got, err := GoalSeek(prog, test.vars, test.target, test.value, test.adjust, test.bounds)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("GoalSeek() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || math.Abs(got-test.want) > 1e-12 {
+				t.Errorf("GoalSeek() = %.17g, %v, want %g", got, err, test.want)
+			}
+			if !maps.Equal(test.vars, vars) {
+				t.Errorf("GoalSeek() changed the variables to %v", test.vars)
+			}
+		})
+	}
+}
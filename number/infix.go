@@ -0,0 +1,94 @@
+package number
+
+import (
+	"strings"
+)
+
+// associative lists the operators for which (a op b) op c == a op (b op c)
+var associative = map[string]bool{
+	"+": true,
+	"*": true,
+}
+
+// ToInfix converts RPN tokens into an infix expression using only the
+// parentheses needed to preserve the evaluation order
+func ToInfix(rpn []string) (string, error) {
+	expr, err := parseRPNTokens("", tokenValues(rpn))
+	if err != nil {
+		return "", err
+	}
+	return formatInfix(expr), nil
+}
+
+// formatInfix renders an expression tree as infix text
+func formatInfix(expr Expr) string {
+	switch node := expr.(type) {
+	case *UnaryExpr:
+		if _, postfix := checkedUnaryOperators[node.Op]; postfix {
+			operand := formatInfix(node.Operand)
+			if exprPrecedence(node.Operand) < exprPrecedence(node) {
+				operand = "(" + operand + ")"
+			}
+			return operand + node.Op
+		}
+		if node.Op != "neg" {
+			return node.Op + "(" + formatInfix(node.Operand) + ")"
+		}
+		operand := formatInfix(node.Operand)
+		if exprPrecedence(node.Operand) < exprPrecedence(node) {
+			operand = "(" + operand + ")"
+		}
+		return "-" + operand
+	case *BinaryExpr:
+		if _, infix := defaultPrecedence[node.Op]; !infix {
+			// Binary operators without a precedence, min and max, are
+			// written as calls
+			return node.Op + "(" + formatInfix(node.Left) + ", " + formatInfix(node.Right) + ")"
+		}
+		nodePrecedence := exprPrecedence(node)
+
+		left := formatInfix(node.Left)
+		leftPrecedence := exprPrecedence(node.Left)
+		if leftPrecedence < nodePrecedence || (leftPrecedence == nodePrecedence && defaultPrecedence.rightAssociative(node.Op)) {
+			left = "(" + left + ")"
+		}
+
+		right := formatInfix(node.Right)
+		rightPrecedence := exprPrecedence(node.Right)
+		if rightPrecedence < nodePrecedence || (rightPrecedence == nodePrecedence && !defaultPrecedence.rightAssociative(node.Op) && !sameAssociativeOp(node, node.Right)) {
+			right = "(" + right + ")"
+		}
+
+		return left + " " + node.Op + " " + right
+	case *CallExpr:
+		return formatCall(node)
+	default:
+		return strings.Join(expr.ToRPN(), " ")
+	}
+}
+
+// exprPrecedence returns the binding strength of the root of an expression
+func exprPrecedence(expr Expr) int {
+	switch node := expr.(type) {
+	case *UnaryExpr:
+		if node.Op == "neg" {
+			return defaultPrecedence.level(node.Op)
+		}
+	case *BinaryExpr:
+		if _, infix := defaultPrecedence[node.Op]; infix {
+			return defaultPrecedence.level(node.Op)
+		}
+	case *Literal:
+		// Negative literals bind like a unary minus
+		if node.Value < 0 {
+			return defaultPrecedence.level("neg")
+		}
+	}
+	return defaultPrecedence.level("^") + 1
+}
+
+// sameAssociativeOp reports whether child applies the same associative operator as parent
+func sameAssociativeOp(parent *BinaryExpr, child Expr) bool {
+	node, ok := child.(*BinaryExpr)
+	return ok && associative[parent.Op] && node.Op == parent.Op
+}
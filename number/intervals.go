@@ -0,0 +1,216 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// Interval is a value known to lie between Lo and Hi, both included. A
+// plain number is the interval of width zero
+type Interval struct {
+	Lo, Hi float64
+}
+
+// Mid returns the midpoint of the interval
+func (iv Interval) Mid() float64 {
+	return iv.Lo + (iv.Hi-iv.Lo)/2
+}
+
+// Radius returns half the width of the interval, the uncertainty around Mid
+func (iv Interval) Radius() float64 {
+	return (iv.Hi - iv.Lo) / 2
+}
+
+// Contains reports whether value lies in the interval
+func (iv Interval) Contains(value float64) bool {
+	return iv.Lo <= value && value <= iv.Hi
+}
+
+// exact reports whether the interval is a single value
+func (iv Interval) exact() bool {
+	return iv.Lo == iv.Hi
+}
+
+// String formats the interval as it is written in literals, [1,2], or a
+// single value as a plain number
+func (iv Interval) String() string {
+	if iv.exact() {
+		return strconv.FormatFloat(iv.Lo, 'g', -1, 64)
+	}
+	return "[" + strconv.FormatFloat(iv.Lo, 'g', -1, 64) + "," + strconv.FormatFloat(iv.Hi, 'g', -1, 64) + "]"
+}
+
+// IntervalCalculator is an RPN calculator whose operands are intervals like
+// [1.9,2.1], so measurement uncertainty is carried through an expression:
+// every result contains all the values the operator can give for operands
+// in their intervals. Bounds are computed in float64 without directed
+// rounding. Comparisons give 1 or 0 when every pair of values agrees and
+// [0,1] otherwise
+type IntervalCalculator struct {
+	*engine.Engine[Interval]
+}
+
+// NewIntervalCalculator creates a new interval calculator instance
+func NewIntervalCalculator() *IntervalCalculator {
+	return &IntervalCalculator{
+		Engine: engine.New(intervalOperators, parseInterval),
+	}
+}
+
+// intervalOperators applies the binary operators with applyIntervalBinary
+// and the unary ones with applyIntervalUnary
+var intervalOperators = scalarOperators(applyIntervalBinary, applyIntervalUnary)
+
+// applyIntervalUnary applies a unary operator to an interval
+func applyIntervalUnary(token string, a Interval) (Interval, error) {
+	switch {
+	case token == "neg":
+		return Interval{-a.Hi, -a.Lo}, nil
+	case token == "sqrt" && a.Lo < 0:
+		return Interval{}, fmt.Errorf("sqrt of an interval with negative values: %s", a)
+	}
+	// The other unary operators never decrease
+	operation := unaryOperators[token]
+	return Interval{operation(a.Lo), operation(a.Hi)}, nil
+}
+
+// EvaluateExpression processes an entire RPN expression and returns the resulting interval
+func (calc *IntervalCalculator) EvaluateExpression(expression string) (Interval, error) {
+	calc.Clear()
+
+	for _, token := range Tokenize(expression) {
+		if err := calc.Evaluate(token.Value); err != nil {
+			return Interval{}, positionError(expression, token, err)
+		}
+	}
+
+	if calc.Size() != 1 {
+		return Interval{}, engine.Incomplete(calc.Size())
+	}
+
+	return calc.Peek()
+}
+
+// applyIntervalBinary applies a binary operator to two intervals
+func applyIntervalBinary(token string, a, b Interval) (Interval, error) {
+	switch token {
+	case "+":
+		return Interval{a.Lo + b.Lo, a.Hi + b.Hi}, nil
+	case "-":
+		return Interval{a.Lo - b.Hi, a.Hi - b.Lo}, nil
+	case "*":
+		return multiplyIntervals(a, b), nil
+	case "%":
+		product := multiplyIntervals(a, b)
+		return Interval{product.Lo / 100, product.Hi / 100}, nil
+	case "/":
+		if b.Contains(0) {
+			return Interval{}, fmt.Errorf("division by an interval containing zero: %s", b)
+		}
+		return multiplyIntervals(a, Interval{1 / b.Hi, 1 / b.Lo}), nil
+	case "min", "max":
+		operation := binaryOperators[token]
+		return Interval{operation(a.Lo, b.Lo), operation(a.Hi, b.Hi)}, nil
+	case "^", "**":
+		if !b.exact() {
+			return Interval{}, fmt.Errorf("exponent must be a single value, got %s", b)
+		}
+		return powerInterval(a, int(b.Lo)), nil
+	case "<", ">", "<=", ">=", "==", "!=":
+		return compareIntervals(token, a, b), nil
+	}
+
+	if !a.exact() || !b.exact() {
+		return Interval{}, fmt.Errorf("%s requires single values", token)
+	}
+	value := binaryOperators[token](a.Lo, b.Lo)
+	return Interval{value, value}, nil
+}
+
+// multiplyIntervals returns the product of two intervals, spanned by the
+// products of their bounds
+func multiplyIntervals(a, b Interval) Interval {
+	products := []float64{a.Lo * b.Lo, a.Lo * b.Hi, a.Hi * b.Lo, a.Hi * b.Hi}
+	result := Interval{products[0], products[0]}
+	for _, product := range products[1:] {
+		result.Lo = math.Min(result.Lo, product)
+		result.Hi = math.Max(result.Hi, product)
+	}
+	return result
+}
+
+// powerInterval raises an interval to the power n, exponents below one
+// giving 1
+func powerInterval(a Interval, n int) Interval {
+	lo, hi := power(a.Lo, float64(n)), power(a.Hi, float64(n))
+	switch {
+	case n < 1:
+		return Interval{1, 1}
+	case n%2 == 1 || a.Lo >= 0:
+		// Odd powers and powers of non-negative values never decrease
+		return Interval{lo, hi}
+	case a.Hi <= 0:
+		return Interval{hi, lo}
+	}
+	// An even power of an interval around zero bottoms out at zero
+	return Interval{0, math.Max(lo, hi)}
+}
+
+// compareIntervals compares two intervals, giving 1 or 0 when the outcome
+// is the same for all their values and [0,1] otherwise
+func compareIntervals(token string, a, b Interval) Interval {
+	var always, never bool
+	switch token {
+	case "<":
+		always, never = a.Hi < b.Lo, a.Lo >= b.Hi
+	case ">":
+		always, never = a.Lo > b.Hi, a.Hi <= b.Lo
+	case "<=":
+		always, never = a.Hi <= b.Lo, a.Lo > b.Hi
+	case ">=":
+		always, never = a.Lo >= b.Hi, a.Hi < b.Lo
+	case "==":
+		always, never = a.exact() && b.exact() && a.Lo == b.Lo, a.Hi < b.Lo || b.Hi < a.Lo
+	case "!=":
+		always, never = a.Hi < b.Lo || b.Hi < a.Lo, a.exact() && b.exact() && a.Lo == b.Lo
+	}
+	switch {
+	case always:
+		return Interval{1, 1}
+	case never:
+		return Interval{0, 0}
+	}
+	return Interval{0, 1}
+}
+
+// parseInterval parses a number or a bracketed interval literal like [1,2]
+func parseInterval(token string) (Interval, error) {
+	if !strings.HasPrefix(token, "[") || !strings.HasSuffix(token, "]") {
+		value, err := parseNumber(token)
+		if err != nil {
+			return Interval{}, engine.UnknownToken(token)
+		}
+		return Interval{value, value}, nil
+	}
+
+	lower, upper, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(token, "["), "]"), ",")
+	if !ok {
+		return Interval{}, fmt.Errorf("invalid interval %s, expected [lo,hi]", token)
+	}
+	lo, err := parseNumber(lower)
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid interval bound %q in %s", lower, token)
+	}
+	hi, err := parseNumber(upper)
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid interval bound %q in %s", upper, token)
+	}
+	if !(lo <= hi) {
+		return Interval{}, fmt.Errorf("invalid interval %s, lower bound above upper bound", token)
+	}
+	return Interval{lo, hi}, nil
+}
@@ -0,0 +1,109 @@
+package number
+
+import "testing"
+
+func TestIntervalCalculator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		wantErr    string
+	}{
+		{"2", "2", ""},
+		{"[1,2] [3,4] +", "[4,6]", ""},
+		{"[1,2] [3,4] -", "[-3,-1]", ""},
+		{"[-1,2] [3,4] *", "[-4,8]", ""},
+		{"[1,2] [2,4] /", "[0.25,1]", ""},
+		{"[1,2] 50 %", "[0.5,1]", ""},
+		{"[-2,3] 2 ^", "[0,9]", ""},
+		{"[-3,-2] 2 ^", "[4,9]", ""},
+		{"[-2,3] 3 ^", "[-8,27]", ""},
+		{"[1,2] 0 ^", "1", ""},
+		{"[1,2] neg", "[-2,-1]", ""},
+		{"[1,4] sqrt", "[1,2]", ""},
+		{"[1.2,2.7] round", "[1,3]", ""},
+		{"[1,2] [0,5] max", "[1,5]", ""},
+		{"[1,2] [3,4] <", "1", ""},
+		{"[1,3] [2,4] <", "[0,1]", ""},
+		{"[3,4] [1,2] <", "0", ""},
+		{"[1,2] [2,3] <=", "1", ""},
+		{"[1,2] [1,2] ==", "[0,1]", ""},
+		{"2 2 ==", "1", ""},
+		{"[1,2] [3,4] !=", "1", ""},
+		{"7 3 mod", "1", ""},
+		{"[0.5,1.5] [1.5,2.5] * 3 +", "[3.75,6.75]", ""},
+		{"[1,2] [-1,1] /", "", "division by an interval containing zero: [-1,1] (column 14)"},
+		{"[1,2] [1,2] ^", "", "exponent must be a single value, got [1,2] (column 13)"},
+		{"[-1,4] sqrt", "", "sqrt of an interval with negative values: [-1,4] (column 8)"},
+		{"[1,2] 3 mod", "", "mod requires single values (column 9)"},
+		{"[2,1]", "", "invalid interval [2,1], lower bound above upper bound (column 1)"},
+		{"[1,x]", "", "invalid interval bound \"x\" in [1,x] (column 1)"},
+		{"[1]", "", "invalid interval [1], expected [lo,hi] (column 1)"},
+		{"[1,2", "", "unknown token: [1,2 (column 1)"},
+		{"+", "", "insufficient operands for + operation (column 1)"},
+		{"[1,2] 1", "", "invalid expression: expected 1 result, got 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewIntervalCalculator().EvaluateExpression(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateExpression() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got.String() != test.want {
+				t.Errorf("EvaluateExpression() = %q, %v, want %q", got.String(), err, test.want)
+			}
+		})
+	}
+}
+
+func TestIntervalsContainEveryResult(t *testing.T) {
+	// Every operator applied to values sampled from two intervals gives a
+	// result inside the interval result
+	operands := [][2]Interval{
+		{{1, 2}, {3, 4}},
+		{{-1, 2}, {-3, 4}},
+		{{-2, -1}, {0.5, 3}},
+		{{-3, 3}, {2, 2}},
+		{{-3, 3}, {3, 3}},
+	}
+	sample := func(iv Interval) []float64 {
+		return []float64{iv.Lo, iv.Lo + (iv.Hi-iv.Lo)/3, iv.Mid(), iv.Hi}
+	}
+	for _, token := range []string{"+", "-", "*", "/", "%", "^", "min", "max"} {
+		for _, pair := range operands {
+			a, b := pair[0], pair[1]
+			result, err := applyIntervalBinary(token, a, b)
+			if err != nil {
+				continue
+			}
+			for _, x := range sample(a) {
+				for _, y := range sample(b) {
+					if token == "^" {
+						y = b.Lo
+					}
+					value := binaryOperators[token](x, y)
+					if !result.Contains(value) && !sameFloat(value, result.Lo) && !sameFloat(value, result.Hi) {
+						t.Errorf("%g %s %g = %g, outside %s %s %s = %s", x, token, y, value, a, token, b, result)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	iv := Interval{1, 3}
+	if iv.Mid() != 2 || iv.Radius() != 1 {
+		t.Errorf("Mid() = %g, Radius() = %g, want 2 and 1", iv.Mid(), iv.Radius())
+	}
+	for _, test := range []struct {
+		value float64
+		want  bool
+	}{{0.5, false}, {1, true}, {2, true}, {3, true}, {3.5, false}} {
+		if got := iv.Contains(test.value); got != test.want {
+			t.Errorf("Contains(%g) = %t, want %t", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package number
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// labelToken names the value on top of the stack with the token after it,
+// so subtotal tax * as taxAmount + records taxAmount while it is computed
+const labelToken = "as"
+
+// programLabel names the value on top of the stack once the first at
+// instructions of a program have run
+type programLabel struct {
+	name string
+	at   int
+}
+
+// checkLabel reports an error unless name can label a value
+func checkLabel(name string) error {
+	if _, ok := operatorRegistry[name]; ok || !isIdentifier(name) {
+		return fmt.Errorf("invalid label: %s", name)
+	}
+	return nil
+}
+
+// Labels returns the names of the program's labels in program order
+func (prog *Program) Labels() []string {
+	names := make([]string, len(prog.labels))
+	for i, label := range prog.labels {
+		names[i] = label.name
+	}
+	return names
+}
+
+// EvalLabeled runs the program like EvalWith and also returns the value
+// of every label. A name labeling several values holds the last one
+func (prog *Program) EvalLabeled(vars map[string]float64) (float64, map[string]float64, error) {
+	var inline [maxInlineStack]float64
+	stack := inline[:]
+	if prog.maxDepth > maxInlineStack {
+		stack = make([]float64, prog.maxDepth)
+	}
+	values := make([]float64, len(prog.labels))
+	result, err := prog.vm.run(context.Background(), vars, stack, values)
+	if err != nil {
+		return 0, nil, err
+	}
+	labels := make(map[string]float64, len(prog.labels))
+	for i, label := range prog.labels {
+		labels[label.name] = values[i]
+	}
+	return result, labels, nil
+}
+
+// performLabel handles as and the name after it. as only checks there is
+// a value to label, the name then records the value on top
+func (calc *RPNCalculator) performLabel(token string) error {
+	if !calc.labelNext {
+		if calc.IsEmpty() {
+			return engine.Underflow(token)
+		}
+		calc.labelNext = true
+		return nil
+	}
+
+	calc.labelNext = false
+	if err := checkLabel(token); err != nil {
+		return err
+	}
+	value, err := calc.Peek()
+	if err != nil {
+		return err
+	}
+	if calc.labels == nil {
+		calc.labels = map[string]float64{}
+	}
+	calc.labels[token] = value
+	return nil
+}
+
+// Labels returns the values labeled with as by the last expression, or by
+// the tokens evaluated since it
+func (calc *RPNCalculator) Labels() map[string]float64 {
+	return maps.Clone(calc.labels)
+}
+
+// Label returns the value labeled name
+func (calc *RPNCalculator) Label(name string) (float64, bool) {
+	value, ok := calc.labels[name]
+	return value, ok
+}
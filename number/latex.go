@@ -0,0 +1,191 @@
+package number
+
+import (
+	"strconv"
+	"strings"
+)
+
+// latexGreek lists the variable names rendered as Greek letters
+var latexGreek = map[string]bool{
+	"alpha": true, "beta": true, "gamma": true, "delta": true, "epsilon": true,
+	"zeta": true, "eta": true, "theta": true, "kappa": true, "lambda": true,
+	"mu": true, "nu": true, "xi": true, "pi": true, "rho": true, "sigma": true,
+	"tau": true, "phi": true, "chi": true, "psi": true, "omega": true,
+	"Gamma": true, "Delta": true, "Theta": true, "Lambda": true, "Xi": true,
+	"Pi": true, "Sigma": true, "Phi": true, "Psi": true, "Omega": true,
+}
+
+// latexBinary maps binary operators rendered between their operands to
+// their LaTeX symbol
+var latexBinary = map[string]string{
+	"+":   "+",
+	"-":   "-",
+	"*":   `\cdot`,
+	"mod": `\bmod`,
+	"<":   "<",
+	">":   ">",
+	"<=":  `\leq`,
+	">=":  `\geq`,
+	"==":  "=",
+	"!=":  `\neq`,
+}
+
+// ToLaTeX renders an expression tree as LaTeX math, with divisions as
+// fractions, powers as superscripts and square roots as radicals, using
+// only the parentheses needed to preserve the evaluation order
+func ToLaTeX(expr Expr) string {
+	switch node := expr.(type) {
+	case *Literal:
+		return latexNumber(node.Value)
+	case *Variable:
+		return latexName(node.Name)
+	case *UnaryExpr:
+		operand := ToLaTeX(node.Operand)
+		switch node.Op {
+		case "neg":
+			if latexPrecedence(node.Operand) < latexPrecedence(node) {
+				operand = latexParens(operand)
+			}
+			return "-" + operand
+		case "sqrt":
+			return `\sqrt{` + operand + "}"
+		case "floor":
+			return `\left\lfloor ` + operand + ` \right\rfloor`
+		case "ceil":
+			return `\left\lceil ` + operand + ` \right\rceil`
+		case "!":
+			if !latexAtomic(node.Operand) {
+				operand = latexParens(operand)
+			}
+			return operand + "!"
+		}
+		return `\operatorname{` + node.Op + "}" + latexParens(operand)
+	case *BinaryExpr:
+		return latexBinaryExpr(node)
+	case *CallExpr:
+		args := make([]string, len(node.Args))
+		for i, arg := range node.Args {
+			args[i] = ToLaTeX(arg)
+		}
+		if node.Func == "nCr" && len(args) == 2 {
+			return `\binom{` + args[0] + "}{" + args[1] + "}"
+		}
+		if node.Func == "gcd" {
+			return `\gcd` + latexParens(strings.Join(args, ", "))
+		}
+		if (node.Func == "if" || node.Func == "select") && len(args) == 3 {
+			return `\begin{cases} ` + args[1] + ` & \text{if } ` + args[0] + ` \neq 0 \\ ` + args[2] + ` & \text{otherwise} \end{cases}`
+		}
+		return `\operatorname{` + node.Func + "}" + latexParens(strings.Join(args, ", "))
+	}
+	return strings.Join(expr.ToRPN(), " ")
+}
+
+// ToLaTeX renders the program as LaTeX math, as it runs after Optimize
+func (prog *Program) ToLaTeX() (string, error) {
+	expr, err := parseRPNTokens("", tokenValues(prog.Tokens()))
+	if err != nil {
+		return "", err
+	}
+	return ToLaTeX(expr), nil
+}
+
+// latexBinaryExpr renders a binary operation
+func latexBinaryExpr(node *BinaryExpr) string {
+	left, right := ToLaTeX(node.Left), ToLaTeX(node.Right)
+	switch node.Op {
+	case "/":
+		return `\frac{` + left + "}{" + right + "}"
+	case "^", "**":
+		if !latexAtomic(node.Left) {
+			left = latexParens(left)
+		}
+		return left + "^{" + right + "}"
+	case "min", "max":
+		return `\` + node.Op + latexParens(left+", "+right)
+	}
+
+	nodePrecedence := latexPrecedence(node)
+	leftPrecedence := latexPrecedence(node.Left)
+	if leftPrecedence < nodePrecedence {
+		left = latexParens(left)
+	}
+	rightPrecedence := latexPrecedence(node.Right)
+	if rightPrecedence < nodePrecedence || (rightPrecedence == nodePrecedence && !sameAssociativeOp(node, node.Right)) {
+		right = latexParens(right)
+	} else if strings.HasPrefix(right, "-") {
+		// A sign right after an operator reads as x \cdot -3 otherwise
+		right = latexParens(right)
+	}
+
+	if node.Op == "%" {
+		return left + ` \cdot ` + right + `\%`
+	}
+	return left + " " + latexBinary[node.Op] + " " + right
+}
+
+// latexPrecedence returns the binding strength of the root of an expression
+// as rendered, fractions and functions needing no parentheses
+func latexPrecedence(expr Expr) int {
+	if latexAtomic(expr) {
+		return defaultPrecedence.level("^") + 1
+	}
+	if lit, ok := expr.(*Literal); ok && lit.Value >= 0 {
+		// Scientific notation renders as a product
+		return defaultPrecedence.level("*")
+	}
+	return exprPrecedence(expr)
+}
+
+// latexAtomic reports whether an expression renders as a single unit that
+// never needs parentheses, even as the base of a power
+func latexAtomic(expr Expr) bool {
+	switch node := expr.(type) {
+	case *Literal:
+		return node.Value >= 0 && !strings.Contains(latexNumber(node.Value), `\times`)
+	case *Variable, *CallExpr:
+		return true
+	case *UnaryExpr:
+		return node.Op != "neg"
+	case *BinaryExpr:
+		return node.Op == "min" || node.Op == "max"
+	}
+	return false
+}
+
+// latexParens wraps text in parentheses that grow with their content
+func latexParens(text string) string {
+	return `\left(` + text + `\right)`
+}
+
+// latexNumber renders a number, with scientific notation as a power of ten
+func latexNumber(value float64) string {
+	text := strconv.FormatFloat(value, 'g', -1, 64)
+	mantissa, exponent, ok := strings.Cut(text, "e")
+	if !ok {
+		return text
+	}
+	exponent = strings.TrimPrefix(exponent, "+")
+	if strings.HasPrefix(exponent, "-") {
+		exponent = "-" + strings.TrimLeft(exponent[1:], "0")
+	} else {
+		exponent = strings.TrimLeft(exponent, "0")
+	}
+	return mantissa + ` \times 10^{` + exponent + "}"
+}
+
+// latexName renders a variable name, Greek letter names as letters, longer
+// names upright and the part after an underscore as a subscript
+func latexName(name string) string {
+	base, subscript, hasSubscript := strings.Cut(name, "_")
+	switch {
+	case latexGreek[base]:
+		base = `\` + base
+	case len([]rune(base)) > 1:
+		base = `\mathrm{` + base + "}"
+	}
+	if hasSubscript {
+		return base + "_{" + strings.ReplaceAll(subscript, "_", `\_`) + "}"
+	}
+	return base
+}
@@ -0,0 +1,98 @@
+package number
+
+import "testing"
+
+func TestToLaTeX(t *testing.T) {
+	tests := []struct {
+		rpn  string
+		want string
+	}{
+		{"1 2 +", `1 + 2`},
+		{"a b + c *", `\left(a + b\right) \cdot c`},
+		{"a b * c +", `a \cdot b + c`},
+		{"a b c + -", `a - \left(b + c\right)`},
+		{"a b - c -", `a - b - c`},
+		{"a b /", `\frac{a}{b}`},
+		{"a b + c d - /", `\frac{a + b}{c - d}`},
+		{"x 2 ^", `x^{2}`},
+		{"x 1 + 2 ^", `\left(x + 1\right)^{2}`},
+		{"x neg 2 ^", `\left(-x\right)^{2}`},
+		{"x 2 ^ neg", `-x^{2}`},
+		{"2 x 3 ^ ^", `2^{x^{3}}`},
+		{"2 3 ^ 2 ^", `\left(2^{3}\right)^{2}`},
+		{"x sqrt", `\sqrt{x}`},
+		{"x 1 + sqrt 2 *", `\sqrt{x + 1} \cdot 2`},
+		{"x floor", `\left\lfloor x \right\rfloor`},
+		{"x ceil", `\left\lceil x \right\rceil`},
+		{"n !", `n!`},
+		{"n 1 + !", `\left(n + 1\right)!`},
+		{"x sin", `\operatorname{sin}\left(x\right)`},
+		{"1 1 atan2", `\operatorname{atan2}\left(1, 1\right)`},
+		{"x y min", `\min\left(x, y\right)`},
+		{"x 2 ^ y max", `\max\left(x^{2}, y\right)`},
+		{"a b mod", `a \bmod b`},
+		{"a b <=", `a \leq b`},
+		{"a 2 %", `a \cdot 2\%`},
+		{"x -3 *", `x \cdot \left(-3\right)`},
+		{"x -3 +", `x + \left(-3\right)`},
+		{"5 x neg -", `5 - \left(-x\right)`},
+		{"-3 x *", `-3 \cdot x`},
+		{"6.02e23", `6.02 \times 10^{23}`},
+		{"1.5e-7 x *", `1.5 \times 10^{-7} \cdot x`},
+		{"1e-7 2 ^", `\left(1 \times 10^{-7}\right)^{2}`},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ToLaTeX(expr); got != test.want {
+				t.Errorf("ToLaTeX(%q) = %q, want %q", test.rpn, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLaTeXNames(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"x", `x`},
+		{"theta", `\theta`},
+		{"Omega", `\Omega`},
+		{"speed", `\mathrm{speed}`},
+		{"x_1", `x_{1}`},
+		{"x_max", `x_{max}`},
+		{"alpha_rate_2", `\alpha_{rate\_2}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := latexName(test.name); got != test.want {
+				t.Errorf("latexName(%q) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestProgramToLaTeX(t *testing.T) {
+	tests := []struct {
+		rpn  string
+		want string
+	}{
+		{"x 2 ^ 1 +", `x^{2} + 1`},
+		{"price qty * 1 rate + /", `\frac{\mathrm{price} \cdot \mathrm{qty}}{1 + \mathrm{rate}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			prog, err := Compile(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := prog.ToLaTeX(); err != nil || got != test.want {
+				t.Errorf("ToLaTeX() = %q, %v, want %q", got, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package number
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// symbolOperators lists the operators spelled with symbols, longest first so
+// the lexer prefers ** over *
+var symbolOperators = func() []string {
+	symbols := []string{}
+	for symbol := range operatorRegistry {
+		if !isIdentifier(symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if len(symbols[i]) != len(symbols[j]) {
+			return len(symbols[i]) > len(symbols[j])
+		}
+		return symbols[i] < symbols[j]
+	})
+	return symbols
+}()
+
+// TokenizeInfix splits an infix expression into numbers, names, operators,
+// parentheses and argument separators, so tokens need no separating whitespace: 3+4*(2-1) and
+// 12.5e3/-4 are both understood. A minus that starts an operand is emitted
+// as the unary neg operator, any other minus as subtraction
+func TokenizeInfix(expression string) []Token {
+	tokens := []Token{}
+
+	for pos := 0; pos < len(expression); {
+		char, size := utf8.DecodeRuneInString(expression[pos:])
+		switch {
+		case unicode.IsSpace(char):
+			pos += size
+			continue
+		case char == '(' || char == ')':
+			tokens = append(tokens, Token{Value: string(char), Kind: TokenParen, Pos: pos})
+			pos += size
+			continue
+		case char == ',':
+			tokens = append(tokens, Token{Value: ",", Kind: TokenSeparator, Pos: pos})
+			pos += size
+			continue
+		}
+
+		end := pos + lexNumber(expression[pos:])
+		if end == pos {
+			end = pos + lexName(expression[pos:])
+		}
+		if end == pos {
+			end = pos + lexSymbol(expression[pos:])
+		}
+		if end == pos {
+			// Unknown characters become single tokens so errors point at them
+			end = pos + size
+		}
+
+		token := newToken(expression[pos:end], pos)
+		if token.Value == "-" && startsOperand(tokens) {
+			token.Value = "neg"
+		}
+		tokens = append(tokens, token)
+		pos = end
+	}
+	return tokens
+}
+
+// startsOperand reports whether the next token begins an operand, that is
+// it follows nothing, an opening parenthesis, an argument separator or an
+// operator other than the postfix !
+func startsOperand(tokens []Token) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	return last.Value == "(" || last.Kind == TokenSeparator || (last.Kind == TokenOperator && last.Value != "!")
+}
+
+// lexNumber returns the length of the numeric literal at the start of text,
+// including a radix prefix, a fraction, an exponent or an SI prefix suffix
+func lexNumber(text string) int {
+	if len(text) > 2 && text[0] == '0' {
+		if _, ok := radixPrefixes[strings.ToLower(text[:2])]; ok {
+			end := 2
+			for end < len(text) && isHexDigit(text[end]) {
+				end++
+			}
+			if end > 2 {
+				return end
+			}
+		}
+	}
+
+	end := 0
+	digits := 0
+	for end < len(text) && (isDigit(text[end]) || text[end] == '.') {
+		if isDigit(text[end]) {
+			digits++
+		}
+		end++
+	}
+	if digits == 0 {
+		return 0
+	}
+
+	// An exponent needs at least one digit, so 2e is a number and a name
+	if end < len(text) && (text[end] == 'e' || text[end] == 'E') {
+		exponent := end + 1
+		if exponent < len(text) && (text[exponent] == '+' || text[exponent] == '-') {
+			exponent++
+		}
+		if exponent < len(text) && isDigit(text[exponent]) {
+			for exponent < len(text) && isDigit(text[exponent]) {
+				exponent++
+			}
+			end = exponent
+		}
+	}
+
+	// An SI prefix belongs to the number unless it starts a longer name
+	if suffix, size := utf8.DecodeRuneInString(text[end:]); size > 0 {
+		if _, ok := siPrefixes[string(suffix)]; ok && lexName(text[end+size:]) == 0 && !startsDigit(text[end+size:]) {
+			end += size
+		}
+	}
+	return end
+}
+
+// lexName returns the length of the identifier or named operator at the start of text
+func lexName(text string) int {
+	end := 0
+	for end < len(text) {
+		char, size := utf8.DecodeRuneInString(text[end:])
+		if char != '_' && !unicode.IsLetter(char) && (end == 0 || !unicode.IsDigit(char)) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+// lexSymbol returns the length of the longest symbolic operator at the start of text
+func lexSymbol(text string) int {
+	for _, symbol := range symbolOperators {
+		if strings.HasPrefix(text, symbol) {
+			return len(symbol)
+		}
+	}
+	return 0
+}
+
+// isDigit reports whether b is an ASCII decimal digit
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// startsDigit reports whether text starts with an ASCII decimal digit
+func startsDigit(text string) bool {
+	return text != "" && isDigit(text[0])
+}
+
+// isHexDigit reports whether b is an ASCII hexadecimal digit
+func isHexDigit(b byte) bool {
+	return isDigit(b) || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
@@ -0,0 +1,143 @@
+package number
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// tokenSummary renders tokens as value/kind pairs for comparison
+func tokenSummary(tokens []Token) string {
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		parts[i] = token.Value + "/" + token.Kind.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		positions  []int
+	}{
+		{"3 4 +", "3/number 4/number +/operator", []int{0, 2, 4}},
+		{"  x\t2 ^", "x/identifier 2/number ^/operator", []int{2, 4, 6}},
+		{"1.5k sqrt $", "1.5k/number sqrt/operator $/unknown", []int{0, 5, 10}},
+		{"", "", []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			tokens := Tokenize(test.expression)
+			if got := tokenSummary(tokens); got != test.want {
+				t.Errorf("Tokenize(%q) = %s, want %s", test.expression, got, test.want)
+			}
+			for i, token := range tokens {
+				if token.Pos != test.positions[i] {
+					t.Errorf("token %s at %d, want %d", token.Value, token.Pos, test.positions[i])
+				}
+			}
+		})
+	}
+}
+
+// TestPositionErrors checks that errors point at the offending token, with
+// columns counted in characters rather than bytes
+func TestPositionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		column     int
+		token      string
+	}{
+		{"1 2 3", "expected 1 result, got 3", 0, ""},
+		{"1 $ +", "unknown token: $ (column 3)", 3, "$"},
+		{"1.5µ 2 +  $", "unknown token: $ (column 11)", 11, "$"},
+		{"1 0 / sqrt +", "insufficient operands for + operation (column 12)", 12, "+"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err == nil || !strings.HasSuffix(err.Error(), test.want) {
+				t.Fatalf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+			var positioned *PositionError
+			if !errors.As(err, &positioned) {
+				if test.column != 0 {
+					t.Errorf("error %v has no position", err)
+				}
+				return
+			}
+			if positioned.Column != test.column || positioned.Token.Value != test.token {
+				t.Errorf("error at column %d on %q, want column %d on %q", positioned.Column, positioned.Token.Value, test.column, test.token)
+			}
+
+			var unknown *engine.ErrUnknownToken
+			if errors.As(err, &unknown) && test.expression[unknown.Pos:unknown.Pos+len(unknown.Token)] != unknown.Token {
+				t.Errorf("ErrUnknownToken.Pos %d does not point at %q", unknown.Pos, unknown.Token)
+			}
+		})
+	}
+}
+
+func TestTokenizeInfix(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"3+4*(2-1)", "3/number +/operator 4/number */operator (/paren 2/number -/operator 1/number )/paren"},
+		{"12.5e3/-4", "12.5e3/number //operator neg/operator 4/number"},
+		{"-x", "neg/operator x/identifier"},
+		{"2 - -3", "2/number -/operator neg/operator 3/number"},
+		{"(-1)", "(/paren neg/operator 1/number )/paren"},
+		{"2**3", "2/number **/operator 3/number"},
+		{"a!=b", "a/identifier !=/operator b/identifier"},
+		{"5!-1", "5/number !/operator -/operator 1/number"},
+		{"atan2(1,-1)", "atan2/operator (/paren 1/number ,/separator neg/operator 1/number )/paren"},
+		{"2e", "2/number e/identifier"},
+		{"2k", "2k/number"},
+		{"0x1F+1", "0x1F/number +/operator 1/number"},
+		{"1 $ 2", "1/number $/unknown 2/number"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			if got := tokenSummary(TokenizeInfix(test.expression)); got != test.want {
+				t.Errorf("TokenizeInfix(%q) = %s, want %s", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeInfixPositions(t *testing.T) {
+	tokens := TokenizeInfix("max(10, -2)")
+	want := []int{0, 3, 4, 6, 8, 9, 10}
+	if len(tokens) != len(want) {
+		t.Fatalf("TokenizeInfix() = %s", tokenSummary(tokens))
+	}
+	for i, token := range tokens {
+		if token.Pos != want[i] {
+			t.Errorf("token %s at %d, want %d", token.Value, token.Pos, want[i])
+		}
+	}
+}
+
+func TestParseInfixRejectsStraySeparators(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"1, 2", ", outside the arguments of a function (column 2)"},
+		{"(1, 2)", ", outside the arguments of a function (column 3)"},
+		{"1 + ,", "expected an operand, got , (column 5)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := ParseInfix(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("ParseInfix(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
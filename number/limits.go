@@ -0,0 +1,75 @@
+package number
+
+import (
+	"fmt"
+)
+
+// Limits bounds the resources evaluating an expression may use, protecting
+// services that evaluate user-supplied expressions. Zero fields are unlimited
+type Limits struct {
+	// MaxStackDepth is the most values the stack may hold at once
+	MaxStackDepth int `json:"max_stack_depth,omitempty"`
+	// MaxTokens is the most tokens an expression may have
+	MaxTokens int `json:"max_tokens,omitempty"`
+
+	// MaxNestingDepth, MaxOperators and MaxExpensiveCalls bound the
+	// Complexity of compiled programs. Only CompileWithLimits checks them
+	MaxNestingDepth   int `json:"max_nesting_depth,omitempty"`
+	MaxOperators      int `json:"max_operators,omitempty"`
+	MaxExpensiveCalls int `json:"max_expensive_calls,omitempty"`
+}
+
+// LimitError reports that an expression exceeded one of its Limits
+type LimitError struct {
+	// Resource names the exceeded limit, "stack depth", "token count",
+	// "nesting depth", "operator count" or "expensive call count"
+	Resource string
+	Max      int
+	Actual   int
+}
+
+// Error describes the exceeded limit
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded: %d", err.Resource, err.Max, err.Actual)
+}
+
+// checkTokens reports a LimitError when count exceeds MaxTokens
+func (limits Limits) checkTokens(count int) error {
+	if limits.MaxTokens > 0 && count > limits.MaxTokens {
+		return &LimitError{Resource: "token count", Max: limits.MaxTokens, Actual: count}
+	}
+	return nil
+}
+
+// checkStackDepth reports a LimitError when depth exceeds MaxStackDepth
+func (limits Limits) checkStackDepth(depth int) error {
+	if limits.MaxStackDepth > 0 && depth > limits.MaxStackDepth {
+		return &LimitError{Resource: "stack depth", Max: limits.MaxStackDepth, Actual: depth}
+	}
+	return nil
+}
+
+// SetLimits sets the resource limits enforced by Evaluate and EvaluateExpression
+func (calc *RPNCalculator) SetLimits(limits Limits) {
+	calc.limits = limits
+}
+
+// CompileWithLimits compiles an expression like Compile, rejecting it when
+// it has too many tokens, would grow the stack beyond the limits or is
+// more complex than they allow
+func CompileWithLimits(expression string, limits Limits) (*Program, error) {
+	if err := limits.checkTokens(len(Tokenize(expression))); err != nil {
+		return nil, err
+	}
+	prog, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.checkStackDepth(prog.MaxDepth()); err != nil {
+		return nil, err
+	}
+	if err := limits.checkComplexity(prog.complexity); err != nil {
+		return nil, err
+	}
+	return prog, nil
+}
@@ -0,0 +1,90 @@
+package number
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCalculatorLimits(t *testing.T) {
+	limits := Limits{MaxStackDepth: 3, MaxTokens: 6}
+	tests := []struct {
+		expression string
+		want       float64
+		resource   string
+		actual     int
+	}{
+		{"1 2 3 + +", 6, "", 0},
+		{"1 2 3 4 +", 0, "stack depth", 4},
+		{"1 2 + 3 + 4 +", 0, "token count", 7},
+		{"1 2 17 5 divmod + + +", 0, "token count", 8},
+		{"1 2 17 5 divmod", 0, "stack depth", 4},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewRPNCalculator(WithLimits(limits)).EvaluateExpression(test.expression)
+			if test.resource == "" {
+				if err != nil || got != test.want {
+					t.Errorf("EvaluateExpression() = %g, %v, want %g", got, err, test.want)
+				}
+				return
+			}
+			var limit *LimitError
+			if !errors.As(err, &limit) {
+				t.Fatalf("EvaluateExpression() error = %v, want a LimitError", err)
+			}
+			if limit.Resource != test.resource || limit.Actual != test.actual {
+				t.Errorf("LimitError = %+v, want %s of %d", limit, test.resource, test.actual)
+			}
+		})
+	}
+}
+
+// TestStackLimitLeavesStack checks that the token exceeding the stack
+// depth has no effect
+func TestStackLimitLeavesStack(t *testing.T) {
+	calc := NewRPNCalculator()
+	calc.SetLimits(Limits{MaxStackDepth: 2})
+	for _, token := range []string{"1", "2"} {
+		if err := calc.Evaluate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := calc.Evaluate("3")
+	if err == nil || err.Error() != "stack depth limit of 2 exceeded: 3" {
+		t.Errorf("Evaluate(3) error = %v", err)
+	}
+	if values := calc.Values(); len(values) != 2 || values[1] != 2 {
+		t.Errorf("stack after exceeding the limit = %v, want [1 2]", values)
+	}
+}
+
+func TestCompileWithLimits(t *testing.T) {
+	tests := []struct {
+		expression string
+		limits     Limits
+		want       string
+	}{
+		{"1 2 3 + +", Limits{MaxStackDepth: 3, MaxTokens: 5}, ""},
+		{"1 2 3 + +", Limits{MaxStackDepth: 2}, "stack depth limit of 2 exceeded: 3"},
+		{"1 2 3 + +", Limits{MaxTokens: 4}, "token count limit of 4 exceeded: 5"},
+		{"1 2 3 + +", Limits{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			prog, err := CompileWithLimits(test.expression, test.limits)
+			if test.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := prog.Eval(); err != nil || got != 6 {
+					t.Errorf("Eval() = %g, %v, want 6", got, err)
+				}
+				return
+			}
+			var limit *LimitError
+			if !errors.As(err, &limit) || err.Error() != test.want {
+				t.Errorf("CompileWithLimits() error = %v, want LimitError %q", err, test.want)
+			}
+		})
+	}
+}
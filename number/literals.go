@@ -0,0 +1,147 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// radixPrefixes maps integer literal prefixes to their base
+var radixPrefixes = map[string]int{
+	"0x": 16,
+	"0b": 2,
+	"0o": 8,
+}
+
+// displayBases maps output tokens to the base results are rendered in
+var displayBases = map[string]int{
+	"hex": 16,
+	"bin": 2,
+	"oct": 8,
+	"dec": 10,
+	"eng": engineeringBase,
+}
+
+// engineeringBase is the display base rendering results with SI prefixes
+// in steps of 1000
+const engineeringBase = 1000
+
+// engineeringDigits is the number of significant digits the eng display
+// base writes
+const engineeringDigits = 6
+
+// siPrefixes maps the SI prefix suffixes of decimal literals to their power of ten
+var siPrefixes = map[string]int{
+	"T": 12,
+	"G": 9,
+	"M": 6,
+	"k": 3,
+	"m": -3,
+	"u": -6,
+	"µ": -6,
+	"n": -9,
+	"p": -12,
+	"f": -15,
+}
+
+// engineeringPrefixes lists the prefix written for each power of ten that
+// is a multiple of three, from 10^-15 up
+var engineeringPrefixes = []string{"f", "p", "n", "u", "m", "", "k", "M", "G", "T"}
+
+// parseNumber parses a decimal literal, optionally with an SI prefix suffix
+// like 1.5k or 22u, or a 0x/0b/0o prefixed integer literal
+func parseNumber(token string) (float64, error) {
+	unsigned := token
+	if strings.HasPrefix(token, "+") || strings.HasPrefix(token, "-") {
+		unsigned = token[1:]
+	}
+	if len(unsigned) > 2 {
+		if base, ok := radixPrefixes[strings.ToLower(unsigned[:2])]; ok {
+			digits := unsigned[2:]
+			// ParseInt takes a sign of its own, the literal's sign goes
+			// before the prefix only
+			value, err := strconv.ParseInt(digits, base, 64)
+			if err != nil || strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+				return 0, fmt.Errorf("invalid base %d literal: %s", base, token)
+			}
+			if strings.HasPrefix(token, "-") {
+				value = -value
+			}
+			return float64(value), nil
+		}
+	}
+	value, err := strconv.ParseFloat(token, 64)
+	if err == nil {
+		return value, nil
+	}
+	for prefix, exponent := range siPrefixes {
+		if mantissa, ok := strings.CutSuffix(token, prefix); ok && !strings.ContainsAny(mantissa, "eE") {
+			// Parsing with the exponent avoids the rounding error of
+			// multiplying by the scale, 22u is exactly 22e-6
+			if value, err := strconv.ParseFloat(mantissa+"e"+strconv.Itoa(exponent), 64); err == nil {
+				return value, nil
+			}
+		}
+	}
+	return 0, err
+}
+
+// formatEngineering renders a value with a mantissa in [1, 1000) of up to
+// digits significant digits and the SI prefix of its magnitude, so the
+// output parses back as the same literal
+func formatEngineering(value float64, digits int) string {
+	if value == 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+
+	exponent := int(math.Floor(math.Log10(math.Abs(value))/3)) * 3
+	if math.Abs(value) >= math.Pow10(exponent+3) {
+		// Log10 can fall just short of exact powers of ten like 1e15
+		exponent += 3
+	}
+	if exponent < -15 || exponent > 12 {
+		// Beyond the prefixes an exponent is clearer than a large mantissa
+		return strconv.FormatFloat(value, 'g', digits, 64)
+	}
+	mantissa := strconv.FormatFloat(value/math.Pow10(exponent), 'g', digits, 64)
+	if scaled, _ := strconv.ParseFloat(mantissa, 64); math.Abs(scaled) >= 1000 {
+		// Rounding to the digits carried into the next prefix
+		if exponent == 12 {
+			return strconv.FormatFloat(value, 'g', digits, 64)
+		}
+		exponent += 3
+		mantissa = strconv.FormatFloat(value/math.Pow10(exponent), 'g', digits, 64)
+	}
+	if strings.ContainsRune(mantissa, 'e') {
+		// Fewer digits than the mantissa has before its point, which
+		// are kept so the output stays a literal
+		mantissa = strconv.FormatFloat(value/math.Pow10(exponent), 'f', 0, 64)
+	}
+	return mantissa + engineeringPrefixes[exponent/3+5]
+}
+
+// formatInBase renders integral values in base with the matching literal
+// prefix, falling back to decimal for fractional values
+func formatInBase(value float64, base int, decimal Formatter) string {
+	if base == engineeringBase {
+		return formatEngineering(value, engineeringDigits)
+	}
+	if base == 10 || value != math.Trunc(value) || math.Abs(value) >= math.MaxInt64 {
+		return FormatResult(value, decimal)
+	}
+
+	prefix := map[int]string{16: "0x", 2: "0b", 8: "0o"}[base]
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return sign + prefix + strings.ToUpper(strconv.FormatInt(int64(value), base))
+}
+
+// Format renders a value in the calculator's display base, with decimal
+// output in the calculator's locale
+func (calc *RPNCalculator) Format(value float64) string {
+	return calc.locale.localize(formatInBase(value, calc.displayBase, calc.formatter))
+}
@@ -0,0 +1,177 @@
+package number
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRadixLiterals(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"0xFF", 255},
+		{"0xff", 255},
+		{"0XfF", 255},
+		{"0b1010", 10},
+		{"0o755", 493},
+		{"-0x10", -16},
+		{"+0x10", 16},
+		{"0x10 0b11 +", 19},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err != nil || got != test.want {
+				t.Errorf("EvaluateExpression(%q) = %g, %v, want %g", test.expression, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestRadixLiteralErrors(t *testing.T) {
+	for _, literal := range []string{"0xG1", "0b102", "0o8", "0x", "0x-5", "0x+5", "--0x10", "+-0x10", "-0x-5", "++0b1"} {
+		t.Run(literal, func(t *testing.T) {
+			if value, err := parseNumber(literal); err == nil {
+				t.Errorf("parseNumber(%q) = %g, want an error", literal, value)
+			}
+		})
+	}
+}
+
+func TestDisplayBases(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"255 hex", "0xFF"},
+		{"10 bin", "0b1010"},
+		{"493 oct", "0o755"},
+		{"-16 hex", "-0x10"},
+		{"2.5 hex", "2.50"},
+		{"0xFF dec", "255.00"},
+		{"hex 0xF 1 + dec", "16.00"},
+		{"0x7FFFFFFFFFFFFC00 hex", "0x7FFFFFFFFFFFFC00"},
+		{"2 63 ^ hex", "9223372036854775808.00"},
+		{"2 63 ^ -1 * hex", "-9223372036854775808.00"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			value, err := calc.EvaluateExpression(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := calc.Format(value); got != test.want {
+				t.Errorf("Format() after %q = %q, want %q", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSILiterals(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    float64
+	}{
+		{"1.5k", 1500},
+		{"3M", 3e6},
+		{"2G", 2e9},
+		{"1T", 1e12},
+		{"5m", 5e-3},
+		{"22u", 22e-6},
+		{"2.2µ", 2.2e-6},
+		{"4n", 4e-9},
+		{"10p", 10e-12},
+		{"5f", 5e-15},
+		{"-4.7k", -4700},
+	}
+	for _, test := range tests {
+		t.Run(test.literal, func(t *testing.T) {
+			got, err := parseNumber(test.literal)
+			if err != nil || got != test.want {
+				t.Errorf("parseNumber(%q) = %g, %v, want %g", test.literal, got, err, test.want)
+			}
+		})
+	}
+	for _, literal := range []string{"1e3k", "k", "1.5K", "2x"} {
+		if value, err := parseNumber(literal); err == nil {
+			t.Errorf("parseNumber(%q) = %g, want an error", literal, value)
+		}
+	}
+}
+
+func TestFormatEngineering(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{1500, "1.5k"},
+		{3000, "3k"},
+		{22e-6, "22u"},
+		{-4700, "-4.7k"},
+		{0.001, "1m"},
+		{1, "1"},
+		{999.9999999, "1k"},
+		{1e12, "1T"},
+		{1e15, "1e+15"},
+		{1e-18, "1e-18"},
+		{math.Inf(1), "+Inf"},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			got := formatEngineering(test.value, engineeringDigits)
+			if got != test.want {
+				t.Errorf("formatEngineering(%g) = %q, want %q", test.value, got, test.want)
+			}
+			// The output is a literal parsing back to the value
+			if value, err := parseNumber(got); err != nil || (value != test.value && test.want != "1k") {
+				t.Errorf("parseNumber(%q) = %g, %v, want %g", got, value, err, test.value)
+			}
+		})
+	}
+
+	calc := NewRPNCalculator()
+	value, err := calc.EvaluateExpression("1.5k 2 * eng")
+	if err != nil || calc.Format(value) != "3k" {
+		t.Errorf("Format() after eng = %q, %v, want 3k", calc.Format(value), err)
+	}
+}
+
+func TestEngineeringFormatterDigits(t *testing.T) {
+	tests := []struct {
+		value  float64
+		digits int
+		want   string
+	}{
+		{4700, 1, "5k"},
+		{4700, 2, "4.7k"},
+		{123456, 6, "123.456k"},
+		{123456, 3, "123k"},
+		// Fewer digits than the mantissa's integer part keep the integer
+		{123456, 1, "123k"},
+		{99.96, 2, "100"},
+		{999.6, 3, "1k"},
+		{999.6, 6, "999.6"},
+		{999999.5, 3, "1M"},
+		{0.0009996, 3, "1m"},
+		{0.0009996, 6, "999.6u"},
+		{-0.000999, 3, "-999u"},
+		{-0.000999, 2, "-1m"},
+		// Carrying beyond the largest prefix switches to an exponent
+		{999.6e12, 3, "1e+15"},
+		{999.6e12, 6, "999.6T"},
+		{0, 3, "0"},
+	}
+	for _, test := range tests {
+		got := EngineeringFormatter{Digits: test.digits}.Format(test.value)
+		if got != test.want {
+			t.Errorf("Format(%g) with %d digits = %q, want %q", test.value, test.digits, got, test.want)
+			continue
+		}
+		if _, err := parseNumber(got); err != nil {
+			t.Errorf("Format(%g) with %d digits = %q, which is not a literal", test.value, test.digits, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package number
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Locale is how a convention writes decimal numbers: the separator before
+// the fraction and the one grouping the integer digits in threes. The zero
+// Locale is the default of a point and no grouping, as Go writes numbers
+type Locale struct {
+	Decimal string `json:"decimal,omitempty"`
+	Group   string `json:"group,omitempty"`
+}
+
+// Common locales. Conventions grouping with spaces, like French, cannot be
+// parsed since spaces separate tokens, so they are used without grouping
+var (
+	LocaleEnglish = Locale{Decimal: ".", Group: ","}
+	// LocaleGerman is also the convention of Italian, Spanish, Dutch and
+	// Portuguese
+	LocaleGerman = Locale{Decimal: ",", Group: "."}
+	LocaleFrench = Locale{Decimal: ","}
+	LocaleSwiss  = Locale{Decimal: ".", Group: "'"}
+)
+
+// decimal returns the decimal separator, a point for the zero Locale
+func (locale Locale) decimal() string {
+	if locale.Decimal == "" {
+		return "."
+	}
+	return locale.Decimal
+}
+
+// Parse parses a number written in the locale, such as 1.234,56 in
+// LocaleGerman. Group separators must split the integer digits in threes,
+// so a point in 1.5 is not mistaken for grouping: it is rejected instead.
+// Literals keep their SI prefixes and exponents, and radix literals are
+// read as usual
+func (locale Locale) Parse(token string) (float64, error) {
+	if locale == (Locale{}) {
+		return parseNumber(token)
+	}
+
+	integer, fraction, hasFraction := strings.Cut(token, locale.decimal())
+	if locale.Group != "" {
+		if strings.Contains(fraction, locale.Group) {
+			return 0, fmt.Errorf("invalid number: %s", token)
+		}
+		if strings.Contains(integer, locale.Group) {
+			grouped, ok := ungroup(integer, locale.Group)
+			if !ok {
+				return 0, fmt.Errorf("invalid number: %s", token)
+			}
+			integer = grouped
+		}
+	}
+	if strings.Contains(integer, ".") || strings.Contains(fraction, ".") {
+		// A point the locale does not use would be read as Go's decimal
+		// point by parseNumber
+		return 0, fmt.Errorf("invalid number: %s", token)
+	}
+	if hasFraction {
+		integer += "." + fraction
+	}
+	return parseNumber(integer)
+}
+
+// ungroup removes the group separators from the integer digits of a
+// number, reporting false unless every group after the first has three
+// digits and the first has one to three
+func ungroup(integer, group string) (string, bool) {
+	sign := integer[:len(integer)-len(strings.TrimLeft(integer, "+-"))]
+	groups := strings.Split(integer[len(sign):], group)
+	for i, digits := range groups {
+		if !isDigits(digits) || len(digits) > 3 || (i > 0 && len(digits) != 3) || digits == "" {
+			return "", false
+		}
+	}
+	return sign + strings.Join(groups, ""), true
+}
+
+// isDigits reports whether text consists of ASCII digits only
+func isDigits(text string) bool {
+	for _, char := range text {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// localize rewrites a number formatted with a decimal point, like
+// 1234.50, in the locale, leaving text that is not a plain number alone
+func (locale Locale) localize(text string) string {
+	if locale == (Locale{}) {
+		return text
+	}
+	sign := text[:len(text)-len(strings.TrimLeft(text, "+-"))]
+	integer, fraction, hasFraction := strings.Cut(text[len(sign):], ".")
+	if !isDigits(integer) || integer == "" {
+		return text
+	}
+
+	if locale.Group != "" {
+		var grouped strings.Builder
+		for i, digit := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				grouped.WriteString(locale.Group)
+			}
+			grouped.WriteRune(digit)
+		}
+		integer = grouped.String()
+	}
+	if hasFraction {
+		integer += locale.decimal() + fraction
+	}
+	return sign + integer
+}
+
+// SetLocale sets the convention numbers are parsed and formatted in
+func (calc *RPNCalculator) SetLocale(locale Locale) {
+	calc.locale = locale
+}
+
+// WithLocale sets the convention numbers are parsed and formatted in
+func WithLocale(locale Locale) Option {
+	return func(calc *RPNCalculator) { calc.SetLocale(locale) }
+}
@@ -0,0 +1,120 @@
+package number
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLocaleParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale Locale
+		token  string
+		want   float64
+		// invalid is set when the locale rejects the token
+		invalid bool
+	}{
+		{"default", Locale{}, "1234.5", 1234.5, false},
+		{"default rejects commas", Locale{}, "1,5", 0, true},
+		{"english grouping", LocaleEnglish, "1,234.56", 1234.56, false},
+		{"english without grouping", LocaleEnglish, "1234.5", 1234.5, false},
+		{"english short group", LocaleEnglish, "1234,5", 0, true},
+		{"english decimal comma", LocaleEnglish, "1.234,56", 0, true},
+		{"german grouping", LocaleGerman, "1.234,56", 1234.56, false},
+		{"german several groups", LocaleGerman, "-1.234.567", -1234567, false},
+		{"german group without fraction", LocaleGerman, "1.234", 1234, false},
+		{"german decimal comma", LocaleGerman, "1,5", 1.5, false},
+		{"german leading comma", LocaleGerman, ",5", 0.5, false},
+		{"german sign", LocaleGerman, "-0,5", -0.5, false},
+		{"german point is not a decimal", LocaleGerman, "1.5", 0, true},
+		{"german uneven groups", LocaleGerman, "12.34.567", 0, true},
+		{"german group in the fraction", LocaleGerman, "1.234,5.6", 0, true},
+		{"german english number", LocaleGerman, "1,234.56", 0, true},
+		{"german exponent", LocaleGerman, "1,5e3", 1500, false},
+		{"german si prefix", LocaleGerman, "1k", 1000, false},
+		{"german radix literal", LocaleGerman, "0x1F", 31, false},
+		{"french decimal comma", LocaleFrench, "1234,5", 1234.5, false},
+		{"french point", LocaleFrench, "1.234", 0, true},
+		{"swiss grouping", LocaleSwiss, "1'234.5", 1234.5, false},
+		{"swiss uneven groups", LocaleSwiss, "12'34.5", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.locale.Parse(test.token)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("Parse(%q) = %g, want an error", test.token, got)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("Parse(%q) = %g, %v, want %g", test.token, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestLocaleFormat(t *testing.T) {
+	// The default formatter writes two decimals, which the locale then
+	// rewrites
+	tests := []struct {
+		value   float64
+		english string
+		german  string
+		french  string
+		swiss   string
+	}{
+		{1234567.25, "1,234,567.25", "1.234.567,25", "1234567,25", "1'234'567.25"},
+		{-1234, "-1,234.00", "-1.234,00", "-1234,00", "-1'234.00"},
+		{123, "123.00", "123,00", "123,00", "123.00"},
+		{0.5, "0.50", "0,50", "0,50", "0.50"},
+		{math.NaN(), "NaN", "NaN", "NaN", "NaN"},
+		{math.Inf(1), "+Inf", "+Inf", "+Inf", "+Inf"},
+	}
+	for _, test := range tests {
+		for _, locale := range []struct {
+			locale Locale
+			want   string
+		}{
+			{LocaleEnglish, test.english},
+			{LocaleGerman, test.german},
+			{LocaleFrench, test.french},
+			{LocaleSwiss, test.swiss},
+		} {
+			if got := NewRPNCalculator(WithLocale(locale.locale)).Format(test.value); got != locale.want {
+				t.Errorf("Format(%g) in %+v = %q, want %q", test.value, locale.locale, got, locale.want)
+			}
+		}
+	}
+}
+
+func TestLocaleExpressions(t *testing.T) {
+	tests := []struct {
+		locale     Locale
+		expression string
+		want       float64
+		wantErr    string
+	}{
+		{LocaleGerman, "1.234,56 2 *", 2469.12, ""},
+		{LocaleGerman, "1,5 0,5 +", 2, ""},
+		{LocaleGerman, "1.5 2 *", 0, "unknown token: 1.5 (column 1)"},
+		{LocaleEnglish, "1,000 1,000.5 +", 2000.5, ""},
+		{Locale{}, "1,000 1 +", 0, "unknown token: 1,000 (column 1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			calc.SetLocale(test.locale)
+			got, err := calc.EvaluateExpression(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateExpression() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || !sameFloat(got, test.want) {
+				t.Errorf("EvaluateExpression() = %g, %v, want %g", got, err, test.want)
+			}
+		})
+	}
+}
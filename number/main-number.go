@@ -1,218 +1,297 @@
-package main
+// Package number is the RPN calculator over float64: an interactive stack
+// calculator, compiled programs and expression trees, and calculators for
+// units, dates, money, intervals, vectors and matrices
+package number
 
 import (
+	"context"
 	"fmt"
-	"strconv"
-	"strings"
+	"math"
+	"math/rand"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
 )
 
 // RPNCalculator represents a Reverse Polish Notation calculator
 type RPNCalculator struct {
-	stack []float64
+	*engine.Engine[float64]
+	displayBase  int
+	percentMode  PercentMode
+	roundingMode RoundingMode
+	angleMode    AngleMode
+	locale       Locale
+	formatter    Formatter
+
+	integerPolicy IntegerPolicy
+	random        *rand.Rand
+	limits        Limits
+
+	// tokens is reused to tokenize every expression
+	tokens []Token
+
+	// labels holds the values named with as, labelNext is set between as
+	// and the name
+	labels    map[string]float64
+	labelNext bool
 }
 
-// NewRPNCalculator creates a new RPN calculator instance
-func NewRPNCalculator() *RPNCalculator {
-	return &RPNCalculator{
-		stack: make([]float64, 0),
+// NewRPNCalculator creates a new RPN calculator instance with the default
+// modes, changed by the options in order
+func NewRPNCalculator(opts ...Option) *RPNCalculator {
+	calc := &RPNCalculator{
+		displayBase: 10,
+		random:      defaultRandom,
 	}
+	calc.Engine = engine.New(floatOperators, calc.parseLiteral)
+	calc.Grow(maxInlineStack)
+	for _, opt := range opts {
+		opt(calc)
+	}
+	return calc
 }
 
-// Push adds a number to the stack
-func (calc *RPNCalculator) Push(value float64) {
-	calc.stack = append(calc.stack, value)
-}
-
-// Pop removes and returns the top element from the stack
-func (calc *RPNCalculator) Pop() (float64, error) {
-	if len(calc.stack) == 0 {
-		return 0, fmt.Errorf("stack is empty")
+// Reset empties the stack and restores the default modes, limits and
+// random source, keeping the buffers already allocated. A reset calculator
+// behaves like a new one, so calculators can be recycled through a
+// sync.Pool
+func (calc *RPNCalculator) Reset() {
+	calc.Clear()
+	*calc = RPNCalculator{
+		Engine:      calc.Engine,
+		displayBase: 10,
+		random:      defaultRandom,
+		tokens:      calc.tokens[:0],
 	}
+}
 
-	index := len(calc.stack) - 1
-	value := calc.stack[index]
-	calc.stack = calc.stack[:index]
-	return value, nil
+// binaryOperators maps binary operator tokens to their implementations
+var binaryOperators = map[string]func(float64, float64) float64{
+	"+":   func(a, b float64) float64 { return a + b },
+	"-":   func(a, b float64) float64 { return a - b },
+	"*":   func(a, b float64) float64 { return a * b },
+	"/":   func(a, b float64) float64 { return a / b },
+	"^":   power,
+	"**":  power,
+	"%":   percentOf,
+	"mod": math.Mod,
+	"<":   func(a, b float64) float64 { return boolValue(a < b) },
+	">":   func(a, b float64) float64 { return boolValue(a > b) },
+	"<=":  func(a, b float64) float64 { return boolValue(a <= b) },
+	">=":  func(a, b float64) float64 { return boolValue(a >= b) },
+	"==":  func(a, b float64) float64 { return boolValue(a == b) },
+	"!=":  func(a, b float64) float64 { return boolValue(a != b) },
+	"min": math.Min,
+	"max": math.Max,
 }
 
-// Peek returns the top element without removing it
-func (calc *RPNCalculator) Peek() (float64, error) {
-	if len(calc.stack) == 0 {
-		return 0, fmt.Errorf("stack is empty")
-	}
-	return calc.stack[len(calc.stack)-1], nil
+// unaryOperators maps unary operator tokens to their implementations
+var unaryOperators = map[string]func(float64) float64{
+	"neg":   func(a float64) float64 { return -a },
+	"round": math.Round,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"trunc": math.Trunc,
+	"sqrt":  math.Sqrt,
 }
 
-// IsEmpty checks if the stack is empty
-func (calc *RPNCalculator) IsEmpty() bool {
-	return len(calc.stack) == 0
+// floatOperators is the operator table of the calculator's engine, holding
+// every binary, unary, ternary and validating operator. The tokens with
+// modes or operands beyond the stack, like round and rand, are handled
+// before the table is consulted
+var floatOperators = func() engine.Operators[float64] {
+	operators := make(engine.Operators[float64])
+	for token, operation := range binaryOperators {
+		operators[token] = engine.Binary(operation)
+	}
+	for token, operation := range unaryOperators {
+		operators[token] = engine.Unary(operation)
+	}
+	for token, operation := range ternaryOperators {
+		operators[token] = ternary(operation)
+	}
+	for token, operation := range checkedBinaryOperators {
+		operators[token] = checkedBinary(operation)
+	}
+	for token, operation := range checkedUnaryOperators {
+		operators[token] = checkedUnary(operation)
+	}
+	return operators
+}()
+
+// scalarOperators builds the operator table of a calculator whose operands
+// carry float64 values through the binary and unary operators: binary
+// applies a binary operator token, or one of the calculator's own binary
+// tokens, to two operands and unary, unless nil, a unary one to a single
+// operand
+func scalarOperators[T any](binary func(token string, a, b T) (T, error), unary func(token string, a T) (T, error), tokens ...string) engine.Operators[T] {
+	operators := make(engine.Operators[T])
+	for token := range binaryOperators {
+		tokens = append(tokens, token)
+	}
+	for _, token := range tokens {
+		operators[token] = engine.Operator[T]{Arity: 2, Apply: func(operands []T) (T, error) {
+			return binary(token, operands[0], operands[1])
+		}}
+	}
+	if unary == nil {
+		return operators
+	}
+	for token := range unaryOperators {
+		operators[token] = engine.Operator[T]{Arity: 1, Apply: func(operands []T) (T, error) {
+			return unary(token, operands[0])
+		}}
+	}
+	return operators
 }
 
-// Size returns the number of elements in the stack
-func (calc *RPNCalculator) Size() int {
-	return len(calc.stack)
+// parseLiteral parses a number in the calculator's locale, the literals of
+// its engine
+func (calc *RPNCalculator) parseLiteral(token string) (float64, error) {
+	value, err := calc.locale.Parse(token)
+	if err != nil {
+		return 0, engine.UnknownToken(token)
+	}
+	return value, nil
 }
 
-// Clear empties the stack
-func (calc *RPNCalculator) Clear() {
-	calc.stack = calc.stack[:0]
+// power raises a to the integer part of b, in constant time however large
+// b is, so a single token cannot outlast the context of its evaluation
+func power(a, b float64) float64 {
+	return math.Pow(a, math.Trunc(b))
 }
 
-// Evaluate processes a single token (number or operator)
+// Evaluate processes a single token (number or operator), failing with a
+// LimitError if the stack grows beyond the calculator's limits
 func (calc *RPNCalculator) Evaluate(token string) error {
-	switch token {
-	case "+":
-		return calc.performBinaryOperation(func(a, b float64) float64 { return a + b })
-	case "-":
-		return calc.performBinaryOperation(func(a, b float64) float64 { return a - b })
-	case "*":
-		return calc.performBinaryOperation(func(a, b float64) float64 { return a * b })
-	case "/":
-		return calc.performBinaryOperation(func(a, b float64) float64 { return a / b })
-	case "^", "**":
-		return calc.performBinaryOperation(func(a, b float64) float64 {
-			result := 1.0
-			for i := 0; i < int(b); i++ {
-				result *= a
-			}
-			return result
-		})
-	default:
-		if value, err := strconv.ParseFloat(token, 64); err == nil {
-			calc.Push(value)
-			return nil
-		}
-		return fmt.Errorf("unknown token: %s", token)
+	if err := calc.evaluateToken(token); err != nil {
+		return err
+	}
+	if err := calc.limits.checkStackDepth(calc.Size()); err != nil {
+		// A single token pushes at most one value, the operators leaving
+		// two results check the limit before changing the stack
+		calc.Truncate(calc.Size() - 1)
+		return err
 	}
+	return nil
 }
 
-// performBinaryOperation applies a binary operation to the top two stack elements
-func (calc *RPNCalculator) performBinaryOperation(operation func(float64, float64) float64) error {
-	if len(calc.stack) < 2 {
-		return fmt.Errorf("insufficient operands for operation")
+// evaluateToken applies a single token to the stack
+func (calc *RPNCalculator) evaluateToken(token string) error {
+	if token == labelToken || calc.labelNext {
+		return calc.performLabel(token)
 	}
+	if token == "%" && calc.percentMode == PercentKeepBase {
+		return calc.performPercent()
+	}
+	if token == "round" || token == "roundN" {
+		return calc.performRounding(token)
+	}
+	if token == "gcd" || token == "lcm" {
+		return calc.performIntegerOperation(token)
+	}
+	if randomTokens[token] {
+		return calc.performRandom(token)
+	}
+	if operator, ok := pairOperators[token]; ok {
+		return calc.performPair(token, operator)
+	}
+	if operator, ok := functions[token]; ok {
+		return calc.performFunction(token, operator)
+	}
+	if base, ok := displayBases[token]; ok {
+		calc.displayBase = base
+		return nil
+	}
+	// The binary min and max take precedence over the reducers of the same
+	// name, which stay available as minn and maxn
+	if _, ok := floatOperators[token]; !ok {
+		if name, counted, ok := lookupReducer(token); ok {
+			return calc.performReduction(name, counted)
+		}
+	}
+	return calc.Engine.Evaluate(token)
+}
 
-	// Pop second operand first (top of stack)
-	b, _ := calc.Pop()
-	// Pop first operand (second from top)
-	a, _ := calc.Pop()
-
-	result := operation(a, b)
-	calc.Push(result)
-	return nil
+// performUnaryOperation applies the unary operation of token to the top
+// stack element
+func (calc *RPNCalculator) performUnaryOperation(token string, operation func(float64) float64) error {
+	return calc.Apply(token, engine.Unary(operation))
 }
 
 // EvaluateExpression processes an entire RPN expression and returns the result
 func (calc *RPNCalculator) EvaluateExpression(expression string) (float64, error) {
-	calc.Clear()
-	tokens := strings.Fields(expression)
+	return calc.EvaluateExpressionContext(context.Background(), expression)
+}
 
-	for _, token := range tokens {
-		if err := calc.Evaluate(token); err != nil {
-			return 0, err
-		}
+// EvaluateExpressionContext processes an entire RPN expression like
+// EvaluateExpression, giving up with the context's error once ctx is done
+func (calc *RPNCalculator) EvaluateExpressionContext(ctx context.Context, expression string) (float64, error) {
+	if err := calc.runExpression(ctx, expression); err != nil {
+		return 0, err
 	}
 
 	if calc.Size() != 1 {
-		return 0, fmt.Errorf("invalid expression: expected 1 result, got %d", calc.Size())
+		return 0, engine.Incomplete(calc.Size())
 	}
 
 	return calc.Peek()
 }
 
-// PrintStack displays the current stack contents
-func (calc *RPNCalculator) PrintStack() {
-	fmt.Print("Stack: [")
-	for i, value := range calc.stack {
-		if i > 0 {
-			fmt.Print(", ")
-		}
-		fmt.Printf("%.2f", value)
+// EvaluateAll processes an entire RPN expression and returns every value
+// left on the stack, bottom first, so one expression can compute several
+// results: 17 5 divmod yields [3 2]. Only an expression leaving no value
+// is incomplete
+func (calc *RPNCalculator) EvaluateAll(expression string) ([]float64, error) {
+	if err := calc.runExpression(context.Background(), expression); err != nil {
+		return nil, err
 	}
-	fmt.Println("]")
-}
-
-func runNumbersDemo() {
-	fmt.Println("=== Reverse Polish Notation Calculator Demo ===\n")
 
-	calc := NewRPNCalculator()
-
-	// Example 1: Simple addition - 3 + 2 + 4
-	fmt.Println("Example 1:")
-	fmt.Println("Input equation: 3 + 2 + 4")
-	fmt.Println("Infix expression: 3 + 2 + 4")
-	fmt.Println("RPN expression: 3 2 + 4 +")
-	fmt.Println("Step-by-step evaluation:")
-
-	calc.Clear()
-	tokens1 := []string{"3", "2", "+", "4", "+"}
-
-	for i, token := range tokens1 {
-		fmt.Printf("  Step %d: Process '%s'", i+1, token)
-		if err := calc.Evaluate(token); err != nil {
-			fmt.Printf(" -> Error: %v\n", err)
-			break
-		}
-		fmt.Print(" -> ")
-		calc.PrintStack()
+	if calc.Size() == 0 {
+		return nil, fmt.Errorf("%w: expected at least 1 result, got 0", engine.ErrIncompleteExpression)
 	}
 
-	if !calc.IsEmpty() {
-		finalResult, _ := calc.Peek()
-		fmt.Printf("Final result: %.0f\n\n", finalResult)
-	}
-
-	// Example 2: (3 + 4) × (5 + 6)
-	fmt.Println("Example 2:")
-	fmt.Println("Input equation: (3 + 4) × (5 + 6)")
-	fmt.Println("Infix expression: (3 + 4) * (5 + 6)")
-	fmt.Println("RPN expression: 3 4 + 5 6 + *")
-	fmt.Println("Step-by-step evaluation:")
+	return calc.Values(), nil
+}
 
+// runExpression clears the stack and processes every token of an
+// expression, giving up with the context's error once ctx is done
+func (calc *RPNCalculator) runExpression(ctx context.Context, expression string) error {
 	calc.Clear()
-	tokens2 := []string{"3", "4", "+", "5", "6", "+", "*"}
-
-	for i, token := range tokens2 {
-		fmt.Printf("  Step %d: Process '%s'", i+1, token)
-		if err := calc.Evaluate(token); err != nil {
-			fmt.Printf(" -> Error: %v\n", err)
-			break
-		}
-		fmt.Print(" -> ")
-		calc.PrintStack()
-	}
-
-	if !calc.IsEmpty() {
-		finalResult, _ := calc.Peek()
-		fmt.Printf("Final result: %.0f\n\n", finalResult)
+	clear(calc.labels)
+	calc.labelNext = false
+	done := ctx.Done()
+
+	calc.tokens = appendTokens(calc.tokens[:0], expression)
+	tokens := calc.tokens
+	if err := calc.limits.checkTokens(len(tokens)); err != nil {
+		return err
 	}
-
-	// Example 3: More complex - ((15 / 3) + 2) * (8 - 3)
-	fmt.Println("Example 3:")
-	fmt.Println("Input equation: ((15 / 3) + 2) * (8 - 3)")
-	fmt.Println("Infix expression: ((15 / 3) + 2) * (8 - 3)")
-	fmt.Println("RPN expression: 15 3 / 2 + 8 3 - *")
-	fmt.Println("Step-by-step evaluation:")
-
-	calc.Clear()
-	tokens3 := []string{"15", "3", "/", "2", "+", "8", "3", "-", "*"}
-
-	for i, token := range tokens3 {
-		fmt.Printf("  Step %d: Process '%s'", i+1, token)
-		if err := calc.Evaluate(token); err != nil {
-			fmt.Printf(" -> Error: %v\n", err)
-			break
+	for i, token := range tokens {
+		if i%contextCheckInterval == 0 {
+			select {
+			case <-done:
+				return ctx.Err()
+			default:
+			}
+		}
+		if err := calc.Evaluate(token.Value); err != nil {
+			return positionError(expression, token, err)
 		}
-		fmt.Print(" -> ")
-		calc.PrintStack()
 	}
-
-	if !calc.IsEmpty() {
-		finalResult, _ := calc.Peek()
-		fmt.Printf("Final result: %.0f\n", finalResult)
+	if calc.labelNext {
+		return positionError(expression, tokens[len(tokens)-1], fmt.Errorf("%s requires a name", labelToken))
 	}
+	return nil
 }
 
-func main() {
-	runNumbersDemo()
+// PrintStack displays the current stack contents
+func (calc *RPNCalculator) PrintStack() {
+	fmt.Print("Stack: [")
+	for i, value := range calc.Values() {
+		if i > 0 {
+			fmt.Print(", ")
+		}
+		fmt.Print(calc.Format(value))
+	}
+	fmt.Println("]")
 }
@@ -0,0 +1,301 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// Matrix is a dense matrix stored row by row. A plain number is a 1x1
+// matrix and scales other matrices
+type Matrix struct {
+	Rows, Cols int
+	Data       []float64
+}
+
+// At returns the element in row i and column j, counting from zero
+func (m Matrix) At(i, j int) float64 {
+	return m.Data[i*m.Cols+j]
+}
+
+// scalar reports whether the matrix is a plain number
+func (m Matrix) scalar() bool {
+	return m.Rows == 1 && m.Cols == 1
+}
+
+// String formats the matrix as it is written in literals, rows separated by
+// semicolons: [1,2;3,4]. A 1x1 matrix is written as a plain number
+func (m Matrix) String() string {
+	if m.scalar() {
+		return strconv.FormatFloat(m.Data[0], 'g', -1, 64)
+	}
+	rows := make([]string, m.Rows)
+	for i := range rows {
+		row := make([]string, m.Cols)
+		for j := range row {
+			row[j] = strconv.FormatFloat(m.At(i, j), 'g', -1, 64)
+		}
+		rows[i] = strings.Join(row, ",")
+	}
+	return "[" + strings.Join(rows, ";") + "]"
+}
+
+// matrixFunctions maps the unary matrix tokens to their implementations
+var matrixFunctions = map[string]func(Matrix) (Matrix, error){
+	"transpose": transposeMatrix,
+	"det":       determinant,
+	"inv":       invertMatrix,
+}
+
+// MatrixCalculator is an RPN calculator whose operands are small matrices
+// like [1,2;3,4]. + and - work element-wise on matrices of one shape, *
+// multiplies matrices and plain numbers scale them, and transpose, det and
+// inv apply to the matrix on top
+type MatrixCalculator struct {
+	*engine.Engine[Matrix]
+}
+
+// NewMatrixCalculator creates a new matrix calculator instance
+func NewMatrixCalculator() *MatrixCalculator {
+	return &MatrixCalculator{
+		Engine: engine.New(matrixOperators, parseMatrix),
+	}
+}
+
+// matrixOperators applies the binary operators with applyMatrixBinary,
+// and the matrix functions and neg to the matrix on top
+var matrixOperators = func() engine.Operators[Matrix] {
+	operators := scalarOperators(applyMatrixBinary, nil)
+	for token, operation := range matrixFunctions {
+		operators[token] = engine.Operator[Matrix]{Arity: 1, Apply: func(operands []Matrix) (Matrix, error) {
+			return operation(operands[0])
+		}}
+	}
+	operators["neg"] = engine.Unary(func(a Matrix) Matrix { return scaleMatrix(a, -1) })
+	return operators
+}()
+
+// EvaluateExpression processes an entire RPN expression and returns the resulting matrix
+func (calc *MatrixCalculator) EvaluateExpression(expression string) (Matrix, error) {
+	calc.Clear()
+
+	for _, token := range Tokenize(expression) {
+		if err := calc.Evaluate(token.Value); err != nil {
+			return Matrix{}, positionError(expression, token, err)
+		}
+	}
+
+	if calc.Size() != 1 {
+		return Matrix{}, engine.Incomplete(calc.Size())
+	}
+
+	return calc.Peek()
+}
+
+// applyMatrixBinary applies a binary operator to two matrices
+func applyMatrixBinary(token string, a, b Matrix) (Matrix, error) {
+	switch {
+	case a.scalar() && b.scalar():
+		return scalarMatrix(binaryOperators[token](a.Data[0], b.Data[0])), nil
+	case token == "*" && a.scalar():
+		return scaleMatrix(b, a.Data[0]), nil
+	case (token == "*" || token == "/") && b.scalar():
+		factor := b.Data[0]
+		if token == "/" {
+			factor = 1 / factor
+		}
+		return scaleMatrix(a, factor), nil
+	case token == "*":
+		return multiplyMatrices(a, b)
+	case token == "+" || token == "-":
+		if a.Rows != b.Rows || a.Cols != b.Cols {
+			return Matrix{}, fmt.Errorf("matrix shape mismatch for %s: %dx%d and %dx%d", token, a.Rows, a.Cols, b.Rows, b.Cols)
+		}
+		result := Matrix{Rows: a.Rows, Cols: a.Cols, Data: make([]float64, len(a.Data))}
+		for i := range result.Data {
+			result.Data[i] = binaryOperators[token](a.Data[i], b.Data[i])
+		}
+		return result, nil
+	}
+	return Matrix{}, fmt.Errorf("cannot apply %s to %dx%d and %dx%d matrices", token, a.Rows, a.Cols, b.Rows, b.Cols)
+}
+
+// scalarMatrix returns the 1x1 matrix of value
+func scalarMatrix(value float64) Matrix {
+	return Matrix{Rows: 1, Cols: 1, Data: []float64{value}}
+}
+
+// scaleMatrix multiplies every element by factor
+func scaleMatrix(m Matrix, factor float64) Matrix {
+	result := Matrix{Rows: m.Rows, Cols: m.Cols, Data: make([]float64, len(m.Data))}
+	for i, value := range m.Data {
+		result.Data[i] = value * factor
+	}
+	return result
+}
+
+// multiplyMatrices returns the matrix product a b
+func multiplyMatrices(a, b Matrix) (Matrix, error) {
+	if a.Cols != b.Rows {
+		return Matrix{}, fmt.Errorf("cannot multiply %dx%d by %dx%d matrix", a.Rows, a.Cols, b.Rows, b.Cols)
+	}
+	result := Matrix{Rows: a.Rows, Cols: b.Cols, Data: make([]float64, a.Rows*b.Cols)}
+	for i := 0; i < a.Rows; i++ {
+		for j := 0; j < b.Cols; j++ {
+			sum := 0.0
+			for k := 0; k < a.Cols; k++ {
+				sum += a.At(i, k) * b.At(k, j)
+			}
+			result.Data[i*result.Cols+j] = sum
+		}
+	}
+	return result, nil
+}
+
+// transposeMatrix swaps rows and columns
+func transposeMatrix(m Matrix) (Matrix, error) {
+	result := Matrix{Rows: m.Cols, Cols: m.Rows, Data: make([]float64, len(m.Data))}
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			result.Data[j*result.Cols+i] = m.At(i, j)
+		}
+	}
+	return result, nil
+}
+
+// determinant computes the determinant of a square matrix by Gaussian
+// elimination with partial pivoting
+func determinant(m Matrix) (Matrix, error) {
+	if m.Rows != m.Cols {
+		return Matrix{}, fmt.Errorf("det requires a square matrix, got %dx%d", m.Rows, m.Cols)
+	}
+	n := m.Rows
+	work := append([]float64(nil), m.Data...)
+	det := 1.0
+	for col := 0; col < n; col++ {
+		pivot := pivotRow(work, n, col)
+		if work[pivot*n+col] == 0 {
+			return scalarMatrix(0), nil
+		}
+		if pivot != col {
+			swapRows(work, n, pivot, col)
+			det = -det
+		}
+		det *= work[col*n+col]
+		for row := col + 1; row < n; row++ {
+			factor := work[row*n+col] / work[col*n+col]
+			for k := col; k < n; k++ {
+				work[row*n+k] -= factor * work[col*n+k]
+			}
+		}
+	}
+	return scalarMatrix(det), nil
+}
+
+// invertMatrix inverts a square matrix by Gauss-Jordan elimination with
+// partial pivoting
+func invertMatrix(m Matrix) (Matrix, error) {
+	if m.Rows != m.Cols {
+		return Matrix{}, fmt.Errorf("inv requires a square matrix, got %dx%d", m.Rows, m.Cols)
+	}
+	n := m.Rows
+	// Pivots this much smaller than the largest element are rounding error
+	// left of a zero, which singular matrices would divide by
+	tolerance := 0.0
+	for _, value := range m.Data {
+		tolerance = math.Max(tolerance, math.Abs(value)*1e-12)
+	}
+	// Eliminate on the augmented matrix [m | I], leaving [I | m^-1]
+	width := 2 * n
+	work := make([]float64, n*width)
+	for i := 0; i < n; i++ {
+		copy(work[i*width:], m.Data[i*n:(i+1)*n])
+		work[i*width+n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := pivotRow(work, width, col)
+		if math.Abs(work[pivot*width+col]) <= tolerance {
+			return Matrix{}, fmt.Errorf("matrix is singular")
+		}
+		swapRows(work, width, pivot, col)
+		scale := 1 / work[col*width+col]
+		for k := 0; k < width; k++ {
+			work[col*width+k] *= scale
+		}
+		for row := 0; row < n; row++ {
+			if row == col {
+				continue
+			}
+			factor := work[row*width+col]
+			for k := 0; k < width; k++ {
+				work[row*width+k] -= factor * work[col*width+k]
+			}
+		}
+	}
+
+	result := Matrix{Rows: n, Cols: n, Data: make([]float64, n*n)}
+	for i := 0; i < n; i++ {
+		copy(result.Data[i*n:(i+1)*n], work[i*width+n:(i+1)*width])
+	}
+	return result, nil
+}
+
+// pivotRow returns the row at or below col with the largest magnitude in
+// column col of a matrix with width columns
+func pivotRow(work []float64, width, col int) int {
+	pivot := col
+	for row := col + 1; row*width < len(work); row++ {
+		if math.Abs(work[row*width+col]) > math.Abs(work[pivot*width+col]) {
+			pivot = row
+		}
+	}
+	return pivot
+}
+
+// swapRows swaps two rows of a matrix with width columns
+func swapRows(work []float64, width, a, b int) {
+	if a == b {
+		return
+	}
+	for k := 0; k < width; k++ {
+		work[a*width+k], work[b*width+k] = work[b*width+k], work[a*width+k]
+	}
+}
+
+// parseMatrix parses a number or a bracketed matrix literal whose rows are
+// separated by semicolons and elements by commas, like [1,2;3,4]
+func parseMatrix(token string) (Matrix, error) {
+	if !strings.HasPrefix(token, "[") || !strings.HasSuffix(token, "]") {
+		value, err := parseNumber(token)
+		if err != nil {
+			return Matrix{}, engine.UnknownToken(token)
+		}
+		return scalarMatrix(value), nil
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(token, "["), "]")
+	if body == "" {
+		return Matrix{}, fmt.Errorf("empty matrix %s", token)
+	}
+	rows := strings.Split(body, ";")
+	result := Matrix{Rows: len(rows)}
+	for i, row := range rows {
+		elements := strings.Split(row, ",")
+		if i == 0 {
+			result.Cols = len(elements)
+		} else if len(elements) != result.Cols {
+			return Matrix{}, fmt.Errorf("matrix row %d has %d elements, expected %d in %s", i+1, len(elements), result.Cols, token)
+		}
+		for _, element := range elements {
+			value, err := parseNumber(element)
+			if err != nil {
+				return Matrix{}, fmt.Errorf("invalid matrix element %q in %s", element, token)
+			}
+			result.Data = append(result.Data, value)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package number
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMatrixCalculator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		wantErr    string
+	}{
+		{"[1,2;3,4]", "[1,2;3,4]", ""},
+		{"2 3 +", "5", ""},
+		{"[1,2;3,4] [5,6;7,8] *", "[19,22;43,50]", ""},
+		{"[1,2;3,4] [5;6] *", "[17;39]", ""},
+		{"[1,2;3,4] [5,6;7,8] +", "[6,8;10,12]", ""},
+		{"[1,2;3,4] [5,6;7,8] -", "[-4,-4;-4,-4]", ""},
+		{"2 [1,2;3,4] *", "[2,4;6,8]", ""},
+		{"[1,2;3,4] 2 *", "[2,4;6,8]", ""},
+		{"[1,2;3,4] 2 /", "[0.5,1;1.5,2]", ""},
+		{"[1,2;3,4] neg", "[-1,-2;-3,-4]", ""},
+		{"[1,2,3;4,5,6] transpose", "[1,4;2,5;3,6]", ""},
+		{"[1,2;3,4] det", "-2", ""},
+		{"[2,0,0;0,3,0;0,0,4] det", "24", ""},
+		{"[0,1;1,0] det", "-1", ""},
+		{"[1,2;2,4] det", "0", ""},
+		{"[2,0;0,4] inv", "[0.5,0;0,0.25]", ""},
+		{"5 det", "5", ""},
+		{"5 inv", "0.2", ""},
+		{"[1,2;2,4] inv", "", "matrix is singular (column 11)"},
+		{"[1,2,3] inv", "", "inv requires a square matrix, got 1x3 (column 9)"},
+		{"[1,2,3] det", "", "det requires a square matrix, got 1x3 (column 9)"},
+		{"[1,2] [1,2,3] +", "", "matrix shape mismatch for +: 1x2 and 1x3 (column 15)"},
+		{"[1,2] [1,2] *", "", "cannot multiply 1x2 by 1x2 matrix (column 13)"},
+		{"[1,2] [1,2] /", "", "cannot apply / to 1x2 and 1x2 matrices (column 13)"},
+		{"[]", "", "empty matrix [] (column 1)"},
+		{"[1,2;3]", "", "matrix row 2 has 1 elements, expected 2 in [1,2;3] (column 1)"},
+		{"[1,x]", "", "invalid matrix element \"x\" in [1,x] (column 1)"},
+		{"[1,2", "", "unknown token: [1,2 (column 1)"},
+		{"transpose", "", "insufficient operands for transpose operation (column 1)"},
+		{"+", "", "insufficient operands for + operation (column 1)"},
+		{"[1,2] 1", "", "invalid expression: expected 1 result, got 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewMatrixCalculator().EvaluateExpression(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateExpression() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got.String() != test.want {
+				t.Errorf("EvaluateExpression() = %q, %v, want %q", got.String(), err, test.want)
+			}
+		})
+	}
+}
+
+func TestMatrixInverse(t *testing.T) {
+	tests := []struct {
+		matrix string
+		want   []float64
+	}{
+		{"[1,2;3,4]", []float64{-2, 1, 1.5, -0.5}},
+		{"[4,7;2,6]", []float64{0.6, -0.7, -0.2, 0.4}},
+		{"[0,1;1,0]", []float64{0, 1, 1, 0}},
+		{"[2,0,0;0,0,4;0,3,0]", []float64{0.5, 0, 0, 0, 0, 1.0 / 3, 0, 0.25, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.matrix, func(t *testing.T) {
+			calc := NewMatrixCalculator()
+			got, err := calc.EvaluateExpression(test.matrix + " inv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range test.want {
+				if math.Abs(got.Data[i]-want) > 1e-12 {
+					t.Fatalf("inv = %s, want %v", got, test.want)
+				}
+			}
+			// The product with the matrix is the identity
+			product, err := calc.EvaluateExpression(test.matrix + " " + test.matrix + " inv *")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < product.Rows; i++ {
+				for j := 0; j < product.Cols; j++ {
+					if want := boolValue(i == j); math.Abs(product.At(i, j)-want) > 1e-12 {
+						t.Fatalf("matrix times its inverse = %s", product)
+					}
+				}
+			}
+		})
+	}
+}
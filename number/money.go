@@ -0,0 +1,194 @@
+package number
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// RateProvider supplies exchange rates to the money calculator, for
+// example from a cache of a rates service the caller refreshes
+type RateProvider interface {
+	// Rate returns how many units of to one unit of from is worth
+	Rate(from, to string) (float64, error)
+}
+
+// RateProviderFunc adapts a function to the RateProvider interface
+type RateProviderFunc func(from, to string) (float64, error)
+
+// Rate calls the function
+func (fn RateProviderFunc) Rate(from, to string) (float64, error) {
+	return fn(from, to)
+}
+
+// Money is an amount in a currency, a plain number when Currency is empty
+type Money struct {
+	Amount   float64
+	Currency string
+}
+
+// String formats money with two decimals and its currency code, as it is
+// written in literals: 12.50USD
+func (m Money) String() string {
+	if m.Currency == "" {
+		return strconv.FormatFloat(m.Amount, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(m.Amount, 'f', 2, 64) + m.Currency
+}
+
+// MoneyCalculator is an RPN calculator whose operands are amounts of money
+// like 100USD or plain numbers. Adding, subtracting or comparing amounts
+// requires the same currency, amounts scale by plain numbers and the to
+// operator converts between currencies at the rates of a RateProvider
+type MoneyCalculator struct {
+	*engine.Engine[Money]
+	rates RateProvider
+}
+
+// NewMoneyCalculator creates a new money calculator converting currencies
+// with rates, which may be nil when no conversions are needed
+func NewMoneyCalculator(rates RateProvider) *MoneyCalculator {
+	calc := &MoneyCalculator{rates: rates}
+	// to converts at the calculator's rates, so every calculator has a
+	// table of its own
+	operators := scalarOperators(calc.applyMoneyBinary, applyMoneyUnary, "to")
+	calc.Engine = engine.New(operators, parseMoney)
+	return calc
+}
+
+// applyMoneyUnary applies a unary operator to an amount, keeping its
+// currency
+func applyMoneyUnary(token string, a Money) (Money, error) {
+	return Money{Amount: unaryOperators[token](a.Amount), Currency: a.Currency}, nil
+}
+
+// SetRateProvider sets the exchange rates used by the to operator
+func (calc *MoneyCalculator) SetRateProvider(rates RateProvider) {
+	calc.rates = rates
+}
+
+// Evaluate processes a single token. A money literal like 100USD is pushed
+// as is, a bare currency code like EUR tags a plain number on top of the
+// stack or otherwise pushes one of that currency, e.g. as the target of to
+func (calc *MoneyCalculator) Evaluate(token string) error {
+	if isCurrencyCode(token) {
+		if top, err := calc.Peek(); err == nil && top.Currency == "" {
+			calc.Pop()
+			calc.Push(Money{Amount: top.Amount, Currency: token})
+			return nil
+		}
+		calc.Push(Money{Amount: 1, Currency: token})
+		return nil
+	}
+	return calc.Engine.Evaluate(token)
+}
+
+// EvaluateExpression processes an entire RPN expression and returns the resulting amount
+func (calc *MoneyCalculator) EvaluateExpression(expression string) (Money, error) {
+	calc.Clear()
+
+	for _, token := range Tokenize(expression) {
+		if err := calc.Evaluate(token.Value); err != nil {
+			return Money{}, positionError(expression, token, err)
+		}
+	}
+
+	if calc.Size() != 1 {
+		return Money{}, engine.Incomplete(calc.Size())
+	}
+
+	return calc.Peek()
+}
+
+// applyMoneyBinary applies a binary operator or to, checking currencies
+func (calc *MoneyCalculator) applyMoneyBinary(token string, a, b Money) (Money, error) {
+	switch token {
+	case "to":
+		if a.Currency == "" || b.Currency == "" {
+			return Money{}, fmt.Errorf("to requires amounts of money")
+		}
+		return calc.convert(a, b.Currency)
+	case "*", "%":
+		// Either operand may be the amount
+		if a.Currency != "" && b.Currency != "" {
+			return Money{}, fmt.Errorf("cannot multiply %s by %s", a.Currency, b.Currency)
+		}
+		return Money{Amount: binaryOperators[token](a.Amount, b.Amount), Currency: a.Currency + b.Currency}, nil
+	case "/":
+		switch {
+		case b.Currency == "":
+			return Money{Amount: a.Amount / b.Amount, Currency: a.Currency}, nil
+		case a.Currency == b.Currency:
+			// The ratio of two amounts is a plain number
+			return Money{Amount: a.Amount / b.Amount}, nil
+		}
+		return Money{}, fmt.Errorf("cannot divide %s by %s", currencyName(a), b.Currency)
+	case "+", "-", "mod", "min", "max":
+		if a.Currency != b.Currency {
+			return Money{}, fmt.Errorf("currency mismatch for %s: %s and %s", token, currencyName(a), currencyName(b))
+		}
+		return Money{Amount: binaryOperators[token](a.Amount, b.Amount), Currency: a.Currency}, nil
+	case "<", ">", "<=", ">=", "==", "!=":
+		if a.Currency != b.Currency {
+			return Money{}, fmt.Errorf("currency mismatch for %s: %s and %s", token, currencyName(a), currencyName(b))
+		}
+		return Money{Amount: binaryOperators[token](a.Amount, b.Amount)}, nil
+	}
+
+	if a.Currency != "" || b.Currency != "" {
+		return Money{}, fmt.Errorf("%s requires plain numbers", token)
+	}
+	return Money{Amount: binaryOperators[token](a.Amount, b.Amount)}, nil
+}
+
+// convert converts an amount to currency at the provider's rate
+func (calc *MoneyCalculator) convert(m Money, currency string) (Money, error) {
+	if m.Currency == currency {
+		return m, nil
+	}
+	if calc.rates == nil {
+		return Money{}, fmt.Errorf("no exchange rate provider to convert %s to %s", m.Currency, currency)
+	}
+	rate, err := calc.rates.Rate(m.Currency, currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("exchange rate %s to %s: %w", m.Currency, currency, err)
+	}
+	return Money{Amount: m.Amount * rate, Currency: currency}, nil
+}
+
+// currencyName names the currency of an amount in errors
+func currencyName(m Money) string {
+	if m.Currency == "" {
+		return "plain number"
+	}
+	return m.Currency
+}
+
+// isCurrencyCode reports whether token is an ISO 4217 style code of three
+// uppercase letters
+func isCurrencyCode(token string) bool {
+	if len(token) != 3 {
+		return false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < 'A' || token[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMoney parses a number with an optional currency code suffix like
+// 100USD or 1.5kEUR
+func parseMoney(token string) (Money, error) {
+	if len(token) > 3 && isCurrencyCode(token[len(token)-3:]) {
+		if amount, err := parseNumber(token[:len(token)-3]); err == nil {
+			return Money{Amount: amount, Currency: token[len(token)-3:]}, nil
+		}
+	}
+	if amount, err := parseNumber(token); err == nil {
+		return Money{Amount: amount}, nil
+	}
+	return Money{}, engine.UnknownToken(token)
+}
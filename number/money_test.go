@@ -0,0 +1,88 @@
+package number
+
+import (
+	"errors"
+	"testing"
+)
+
+// testRates converts US dollars to euros and knows no other pair
+var testRates = RateProviderFunc(func(from, to string) (float64, error) {
+	if from == "USD" && to == "EUR" {
+		return 0.9, nil
+	}
+	return 0, errors.New("unknown pair")
+})
+
+func TestMoneyCalculator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		wantErr    string
+	}{
+		{"100USD", "100.00USD", ""},
+		{"1.5kEUR", "1500.00EUR", ""},
+		{"100 USD", "100.00USD", ""},
+		{"EUR", "1.00EUR", ""},
+		{"100USD 50USD +", "150.00USD", ""},
+		{"100USD 2 *", "200.00USD", ""},
+		{"2 100USD *", "200.00USD", ""},
+		{"100USD 4 /", "25.00USD", ""},
+		{"100USD 10 %", "10.00USD", ""},
+		{"100USD 50USD /", "2", ""},
+		{"100USD 50USD <", "0", ""},
+		{"100USD EUR to", "90.00EUR", ""},
+		{"100USD 50USD + EUR to", "135.00EUR", ""},
+		{"100USD USD to", "100.00USD", ""},
+		{"2 3 +", "5", ""},
+		{"100USD 50EUR +", "", "currency mismatch for +: USD and EUR (column 14)"},
+		{"100USD 50 +", "", "currency mismatch for +: USD and plain number (column 11)"},
+		{"100USD 50EUR <", "", "currency mismatch for <: USD and EUR (column 14)"},
+		{"100USD 50USD *", "", "cannot multiply USD by USD (column 14)"},
+		{"100USD 50EUR /", "", "cannot divide USD by EUR (column 14)"},
+		{"2 100USD /", "", "cannot divide plain number by USD (column 10)"},
+		{"100USD 3 ^", "", "^ requires plain numbers (column 10)"},
+		{"100EUR USD to", "", "exchange rate EUR to USD: unknown pair (column 12)"},
+		{"100 EUR to", "", "insufficient operands for to operation (column 9)"},
+		{"100USD 2 to", "", "to requires amounts of money (column 10)"},
+		{"100usd", "", "unknown token: 100usd (column 1)"},
+		{"100USD 1", "", "invalid expression: expected 1 result, got 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewMoneyCalculator(testRates).EvaluateExpression(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateExpression() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got.String() != test.want {
+				t.Errorf("EvaluateExpression() = %q, %v, want %q", got.String(), err, test.want)
+			}
+		})
+	}
+}
+
+func TestMoneyCalculatorRateProvider(t *testing.T) {
+	calc := NewMoneyCalculator(nil)
+	if _, err := calc.EvaluateExpression("100USD EUR to"); err == nil || err.Error() != "no exchange rate provider to convert USD to EUR (column 12)" {
+		t.Errorf("conversion without a provider error = %v", err)
+	}
+	// Amounts already in the currency need no provider
+	if got, err := calc.EvaluateExpression("100USD USD to"); err != nil || got.String() != "100.00USD" {
+		t.Errorf("conversion to the same currency = %q, %v", got.String(), err)
+	}
+
+	calc.SetRateProvider(testRates)
+	if got, err := calc.EvaluateExpression("100USD EUR to"); err != nil || got != (Money{Amount: 90, Currency: "EUR"}) {
+		t.Errorf("conversion after SetRateProvider = %+v, %v", got, err)
+	}
+	_, err := calc.EvaluateExpression("1EUR USD to")
+	if err == nil || err.Error() != "exchange rate EUR to USD: unknown pair (column 10)" {
+		t.Errorf("conversion with an unknown rate error = %v", err)
+	}
+	// The failing conversion leaves its operands
+	if values := calc.Values(); len(values) != 2 || values[0] != (Money{Amount: 1, Currency: "EUR"}) {
+		t.Errorf("stack after a failing conversion = %v", values)
+	}
+}
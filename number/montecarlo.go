@@ -0,0 +1,183 @@
+package number
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Distribution draws the values of a variable in a Monte Carlo run
+type Distribution interface {
+	Sample(random *rand.Rand) float64
+}
+
+// DistributionFunc adapts a function to the Distribution interface
+type DistributionFunc func(random *rand.Rand) float64
+
+// Sample calls the function
+func (fn DistributionFunc) Sample(random *rand.Rand) float64 {
+	return fn(random)
+}
+
+// Constant is a variable that keeps its value in every sample
+type Constant float64
+
+// Sample returns the value
+func (c Constant) Sample(random *rand.Rand) float64 {
+	return float64(c)
+}
+
+// Normal is the normal distribution with a mean and standard deviation
+type Normal struct {
+	Mean, StdDev float64
+}
+
+// Sample draws a normally distributed value
+func (n Normal) Sample(random *rand.Rand) float64 {
+	return n.Mean + n.StdDev*random.NormFloat64()
+}
+
+// validate rejects a negative standard deviation
+func (n Normal) validate() error {
+	if n.StdDev < 0 {
+		return fmt.Errorf("normal standard deviation must not be negative, got %g", n.StdDev)
+	}
+	return nil
+}
+
+// Uniform is the uniform distribution over [Min, Max)
+type Uniform struct {
+	Min, Max float64
+}
+
+// Sample draws a uniformly distributed value
+func (u Uniform) Sample(random *rand.Rand) float64 {
+	return u.Min + (u.Max-u.Min)*random.Float64()
+}
+
+// validate rejects a minimum above the maximum
+func (u Uniform) validate() error {
+	if u.Min > u.Max {
+		return fmt.Errorf("uniform minimum %g is above maximum %g", u.Min, u.Max)
+	}
+	return nil
+}
+
+// Triangular is the triangular distribution between Min and Max peaking at
+// Mode, the usual model of a three-point estimate
+type Triangular struct {
+	Min, Mode, Max float64
+}
+
+// Sample draws a triangularly distributed value by inverting its
+// cumulative distribution
+func (t Triangular) Sample(random *rand.Rand) float64 {
+	u := random.Float64()
+	width := t.Max - t.Min
+	if width == 0 {
+		return t.Min
+	}
+	if u < (t.Mode-t.Min)/width {
+		return t.Min + math.Sqrt(u*width*(t.Mode-t.Min))
+	}
+	return t.Max - math.Sqrt((1-u)*width*(t.Max-t.Mode))
+}
+
+// validate rejects a mode outside the bounds
+func (t Triangular) validate() error {
+	if t.Min > t.Mode || t.Mode > t.Max {
+		return fmt.Errorf("triangular mode %g must lie between minimum %g and maximum %g", t.Mode, t.Min, t.Max)
+	}
+	return nil
+}
+
+// MonteCarloResult summarizes the results of a Monte Carlo run
+type MonteCarloResult struct {
+	N                      int
+	Mean, StdDev, Min, Max float64
+
+	// sorted holds every result in increasing order
+	sorted []float64
+}
+
+// Percentile returns the result below which p percent of the samples fall,
+// interpolating between the nearest two, so Percentile(50) is the median
+func (result MonteCarloResult) Percentile(p float64) float64 {
+	if len(result.sorted) == 0 {
+		return math.NaN()
+	}
+	rank := math.Max(0, math.Min(p, 100)) / 100 * float64(len(result.sorted)-1)
+	below := int(math.Floor(rank))
+	if below == len(result.sorted)-1 {
+		return result.sorted[below]
+	}
+	fraction := rank - float64(below)
+	return result.sorted[below] + fraction*(result.sorted[below+1]-result.sorted[below])
+}
+
+// Samples returns every result of the run in increasing order
+func (result MonteCarloResult) Samples() []float64 {
+	return slices.Clone(result.sorted)
+}
+
+// MonteCarlo evaluates a program n times with each variable drawn from its
+// distribution, so a stored formula becomes a quick risk model. Every
+// variable the program reads needs a distribution, Constant fixing one.
+// Draws come from source, or from a clock seeded source when it is nil
+func MonteCarlo(prog *Program, n int, vars map[string]Distribution, source rand.Source) (MonteCarloResult, error) {
+	if n < 1 {
+		return MonteCarloResult{}, fmt.Errorf("monte carlo needs at least 1 sample, got %d", n)
+	}
+	variables := prog.Variables()
+	for _, name := range variables {
+		if _, ok := vars[name]; !ok {
+			return MonteCarloResult{}, fmt.Errorf("variable %s has no distribution", name)
+		}
+	}
+	names := slices.Sorted(maps.Keys(vars))
+	for _, name := range names {
+		if !slices.Contains(variables, name) {
+			return MonteCarloResult{}, fmt.Errorf("program does not read variable %s", name)
+		}
+		if checked, ok := vars[name].(interface{ validate() error }); ok {
+			if err := checked.validate(); err != nil {
+				return MonteCarloResult{}, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	random := rand.New(source)
+
+	results := make([]float64, n)
+	values := make(map[string]float64, len(names))
+	for i := range results {
+		// Drawing in name order keeps runs with the same seed reproducible
+		for _, name := range names {
+			values[name] = vars[name].Sample(random)
+		}
+		value, err := prog.EvalWith(values)
+		if err != nil {
+			return MonteCarloResult{}, fmt.Errorf("sample %d: %w", i+1, err)
+		}
+		results[i] = value
+	}
+
+	result := MonteCarloResult{
+		N:    n,
+		Mean: meanValues(results),
+		Min:  minValues(results),
+		Max:  maxValues(results),
+	}
+	if n > 1 {
+		result.StdDev = stddevValues(results)
+	}
+	sort.Float64s(results)
+	result.sorted = results
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package number
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestMonteCarloDistributions(t *testing.T) {
+	tests := []struct {
+		name         string
+		distribution Distribution
+		mean, stddev float64
+		min, max     float64
+	}{
+		{"constant", Constant(3), 3, 0, 3, 3},
+		{"uniform", Uniform{Min: 2, Max: 4}, 3, 2 / math.Sqrt(12), 2, 4},
+		{"normal", Normal{Mean: 10, StdDev: 2}, 10, 2, math.Inf(-1), math.Inf(1)},
+		{"triangular", Triangular{Min: 0, Mode: 1, Max: 4}, 5.0 / 3, math.Sqrt((0 + 1 + 16 - 0 - 0 - 4) / 18.0), 0, 4},
+		{"skewed triangular", Triangular{Min: 1, Mode: 1, Max: 2}, 4.0 / 3, math.Sqrt((1 + 1 + 4 - 1 - 2 - 2) / 18.0), 1, 2},
+		{"point triangular", Triangular{Min: 2, Mode: 2, Max: 2}, 2, 0, 2, 2},
+	}
+	prog, err := Compile("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := MonteCarlo(prog, 20000, map[string]Distribution{"x": test.distribution}, rand.NewSource(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.N != 20000 || math.Abs(result.Mean-test.mean) > 0.05 || math.Abs(result.StdDev-test.stddev) > 0.05 {
+				t.Errorf("mean %g and deviation %g of %d samples, want %g and %g", result.Mean, result.StdDev, result.N, test.mean, test.stddev)
+			}
+			if result.Min < test.min || result.Max > test.max || result.Min > result.Max {
+				t.Errorf("samples span [%g, %g], want within [%g, %g]", result.Min, result.Max, test.min, test.max)
+			}
+			if result.Percentile(0) != result.Min || result.Percentile(100) != result.Max {
+				t.Errorf("Percentile(0) = %g and Percentile(100) = %g, want the minimum and maximum", result.Percentile(0), result.Percentile(100))
+			}
+		})
+	}
+}
+
+func TestMonteCarloPercentile(t *testing.T) {
+	// The samples are 5, 4, 3, 2 and 1 whatever the source
+	next := 6.0
+	counter := DistributionFunc(func(*rand.Rand) float64 {
+		next--
+		return next
+	})
+	prog, err := Compile("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := MonteCarlo(prog, 5, map[string]Distribution{"x": counter}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1}, {12.5, 1.5}, {25, 2}, {50, 3}, {90, 4.6}, {100, 5}, {150, 5}, {-5, 1},
+	}
+	for _, test := range tests {
+		if got := result.Percentile(test.p); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("Percentile(%g) = %g, want %g", test.p, got, test.want)
+		}
+	}
+	samples := result.Samples()
+	if !slices.Equal(samples, []float64{1, 2, 3, 4, 5}) {
+		t.Errorf("Samples() = %v", samples)
+	}
+	samples[0] = 100
+	if result.Percentile(0) != 1 {
+		t.Error("changing Samples() changed the result")
+	}
+	if result.Mean != 3 || result.Min != 1 || result.Max != 5 || result.StdDev != math.Sqrt(2.5) {
+		t.Errorf("result = %+v", result)
+	}
+	if got := (MonteCarloResult{}).Percentile(50); !math.IsNaN(got) {
+		t.Errorf("Percentile() of no samples = %g, want NaN", got)
+	}
+}
+
+func TestMonteCarloReproducible(t *testing.T) {
+	prog, err := Compile("a b * c +")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]Distribution{
+		"a": Normal{Mean: 1, StdDev: 0.1},
+		"b": Uniform{Min: 2, Max: 3},
+		"c": Triangular{Min: 0, Mode: 1, Max: 2},
+	}
+	first, err := MonteCarlo(prog, 100, vars, rand.NewSource(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := MonteCarlo(prog, 100, vars, rand.NewSource(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(first.Samples(), second.Samples()) {
+		t.Error("runs with the same seed differ")
+	}
+	single, err := MonteCarlo(prog, 1, vars, rand.NewSource(7))
+	if err != nil || single.StdDev != 0 || single.Min != single.Max {
+		t.Errorf("a single sample = %+v, %v", single, err)
+	}
+}
+
+func TestMonteCarloErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		n          int
+		vars       map[string]Distribution
+		want       string
+	}{
+		{"no samples", "x", 0, map[string]Distribution{"x": Constant(1)}, "monte carlo needs at least 1 sample, got 0"},
+		{"missing distribution", "x y +", 10, map[string]Distribution{"x": Constant(1)}, "variable y has no distribution"},
+		{"unread variable", "x", 10, map[string]Distribution{"x": Constant(1), "z": Constant(1)}, "program does not read variable z"},
+		{"negative deviation", "x", 10, map[string]Distribution{"x": Normal{StdDev: -1}}, "x: normal standard deviation must not be negative, got -1"},
+		{"inverted uniform", "x", 10, map[string]Distribution{"x": Uniform{Min: 2, Max: 1}}, "x: uniform minimum 2 is above maximum 1"},
+		{"mode outside", "x", 10, map[string]Distribution{"x": Triangular{Min: 0, Mode: 3, Max: 2}}, "x: triangular mode 3 must lie between minimum 0 and maximum 2"},
+		{"failing sample", "n !", 10, map[string]Distribution{"n": Constant(-1)}, "sample 1: ! requires a non-negative integer, got -1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = MonteCarlo(prog, test.n, test.vars, rand.NewSource(1))
+			if err == nil || err.Error() != test.want {
+				t.Errorf("MonteCarlo() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !race
+
+package number
+
+// raceEnabled reports that the tests run with the race detector
+const raceEnabled = false
@@ -0,0 +1,238 @@
+package number
+
+import (
+	"math"
+	"strconv"
+)
+
+// rightIdentities maps binary operators to the right operand that leaves
+// the left one unchanged, x 0 + or x 1 ^
+var rightIdentities = map[string]float64{
+	"+":  0,
+	"-":  0,
+	"*":  1,
+	"/":  1,
+	"^":  1,
+	"**": 1,
+}
+
+// leftIdentities maps binary operators to the left operand that leaves the
+// right one unchanged, 0 x + or 1 x *
+var leftIdentities = map[string]float64{
+	"+": 0,
+	"*": 1,
+}
+
+// Simplify folds the constant subexpressions of an expression tree into
+// literals and drops identity operations such as x 0 + and neg neg x.
+// Subexpressions that fail, or fold to an infinity or NaN, are left for
+// evaluation to report
+func Simplify(expr Expr) Expr {
+	return Transform(expr, simplifyNode)
+}
+
+// simplifyNode simplifies a node whose children are already simplified
+func simplifyNode(node Expr) Expr {
+	if folded, ok := foldNode(node); ok {
+		return folded
+	}
+
+	switch node := node.(type) {
+	case *UnaryExpr:
+		if inner, ok := node.Operand.(*UnaryExpr); ok && node.Op == "neg" && inner.Op == "neg" {
+			return inner.Operand
+		}
+	case *BinaryExpr:
+		if identity, ok := rightIdentities[node.Op]; ok && isLiteral(node.Right, identity) {
+			return node.Left
+		}
+		if identity, ok := leftIdentities[node.Op]; ok && isLiteral(node.Left, identity) {
+			return node.Right
+		}
+	}
+	return node
+}
+
+// foldNode evaluates a node whose operands are all literals
+func foldNode(node Expr) (Expr, bool) {
+	switch node := node.(type) {
+	case *UnaryExpr:
+		if !isConstant(node.Operand) {
+			return nil, false
+		}
+	case *BinaryExpr:
+		if !isConstant(node.Left) || !isConstant(node.Right) {
+			return nil, false
+		}
+	case *CallExpr:
+		for _, arg := range node.Args {
+			if !isConstant(arg) {
+				return nil, false
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	value, err := node.Eval()
+	if err != nil || !isFoldable(value) {
+		return nil, false
+	}
+	return &Literal{Value: value}, true
+}
+
+// isConstant reports whether expr is a literal
+func isConstant(expr Expr) bool {
+	_, ok := expr.(*Literal)
+	return ok
+}
+
+// isLiteral reports whether expr is a literal of the given value
+func isLiteral(expr Expr, value float64) bool {
+	lit, ok := expr.(*Literal)
+	return ok && lit.Value == value
+}
+
+// isFoldable reports whether a folded value survives the round trip through
+// an RPN number token
+func isFoldable(value float64) bool {
+	return !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+// Optimize returns a copy of the program with its constant subexpressions
+// folded and its identity operations removed, so 2 3 + x * runs as 5 x *
+// and x 0 + as x. Operations that fail on constant operands are kept so the
+// program still reports the error when it runs. A program with labels is
+// returned as is, since folding would drop the values they name
+func (prog *Program) Optimize() *Program {
+	if len(prog.labels) > 0 {
+		return prog
+	}
+	code := make([]instruction, 0, len(prog.code))
+	// starts holds, for every value on the stack, the index in code of the
+	// first instruction computing it
+	starts := make([]int, 0, prog.maxDepth)
+
+	for _, instr := range prog.code {
+		arity := instr.arity()
+		if arity == 0 {
+			starts = append(starts, len(code))
+			code = append(code, instr)
+			continue
+		}
+		operands := starts[len(starts)-arity:]
+		start := operands[0]
+		starts = append(starts[:len(starts)-arity], start)
+
+		if value, ok := foldInstruction(instr, code[start:]); ok {
+			code = append(code[:start], instruction{kind: opPush, value: value})
+			continue
+		}
+
+		switch instr.kind {
+		case opUnary:
+			last := len(code) - 1
+			if instr.op == "neg" && code[last].kind == opUnary && code[last].op == "neg" {
+				code = code[:last]
+				continue
+			}
+		case opBinary:
+			right := operands[1]
+			if identity, ok := rightIdentities[instr.op]; ok && isPush(code[right:], identity) {
+				code = code[:right]
+				continue
+			}
+			if identity, ok := leftIdentities[instr.op]; ok && isPush(code[start:right], identity) {
+				code = append(code[:start], code[right:]...)
+				continue
+			}
+		}
+		code = append(code, instr)
+	}
+
+	return &Program{source: prog.source, code: code, vm: assemble(code, nil), maxDepth: stackDepth(code), complexity: measureComplexity(code)}
+}
+
+// Tokens returns the instructions of the program as RPN tokens, which show
+// what an optimized program runs
+func (prog *Program) Tokens() []string {
+	tokens := make([]string, len(prog.code))
+	for i, instr := range prog.code {
+		switch instr.kind {
+		case opPush:
+			tokens[i] = strconv.FormatFloat(instr.value, 'g', -1, 64)
+		case opVar:
+			tokens[i] = instr.name
+		default:
+			tokens[i] = instr.op
+		}
+	}
+	return tokens
+}
+
+// arity returns how many values an instruction pops
+func (instr instruction) arity() int {
+	switch instr.kind {
+	case opUnary, opCheckedUnary:
+		return 1
+	case opBinary, opCheckedBinary:
+		return 2
+	case opTernary:
+		return 3
+	case opCall:
+		return instr.call.Arity
+	}
+	return 0
+}
+
+// foldInstruction applies an operation to operands that are all pushed
+// constants, reporting false when they are not or the operation fails
+func foldInstruction(instr instruction, operands []instruction) (float64, bool) {
+	if len(operands) != instr.arity() {
+		return 0, false
+	}
+	args := make([]float64, len(operands))
+	for i, operand := range operands {
+		if operand.kind != opPush {
+			return 0, false
+		}
+		args[i] = operand.value
+	}
+
+	var value float64
+	var err error
+	switch instr.kind {
+	case opUnary:
+		value = instr.unary(args[0])
+	case opBinary:
+		value = instr.binary(args[0], args[1])
+	case opCheckedUnary:
+		value, err = instr.checkedUnary(args[0])
+	case opCheckedBinary:
+		value, err = instr.checkedBinary(args[0], args[1])
+	case opTernary:
+		value = instr.ternary(args[0], args[1], args[2])
+	case opCall:
+		value, err = instr.call.Apply(args)
+	}
+	return value, err == nil && isFoldable(value)
+}
+
+// isPush reports whether code is a single push of the given value
+func isPush(code []instruction, value float64) bool {
+	return len(code) == 1 && code[0].kind == opPush && code[0].value == value
+}
+
+// stackDepth returns the deepest the stack gets while code runs
+func stackDepth(code []instruction) int {
+	depth, deepest := 0, 0
+	for _, instr := range code {
+		if instr.arity() == 0 {
+			depth++
+		} else {
+			depth -= instr.arity() - 1
+		}
+		deepest = max(deepest, depth)
+	}
+	return deepest
+}
@@ -0,0 +1,115 @@
+package number
+
+import (
+	"strings"
+	"testing"
+)
+
+// optimizeTests are RPN expressions of x with the tokens left once their
+// constants are folded and identities dropped
+var optimizeTests = []struct {
+	rpn  string
+	want string
+}{
+	{"2 3 + x *", "5 x *"},
+	{"x 0 +", "x"},
+	{"0 x +", "x"},
+	{"x 0 -", "x"},
+	{"x 1 *", "x"},
+	{"1 x *", "x"},
+	{"x 1 /", "x"},
+	{"x 1 ^", "x"},
+	{"x neg neg", "x"},
+	{"x 2 2 - +", "x"},
+	{"x 4 sqrt 1 - *", "x"},
+	{"0 x -", "0 x -"},
+	{"x 0 *", "x 0 *"},
+	{"1 x /", "1 x /"},
+	{"x 2 3 * 4 + *", "x 10 *"},
+	{"x 2 + 3 +", "x 2 + 3 +"},
+	{"1 2 max x min", "2 x min"},
+	{"1 0 /", "1 0 /"},
+	{"-1 sqrt x +", "-1 sqrt x +"},
+	{"x 1 0 / *", "x 1 0 / *"},
+}
+
+func TestOptimize(t *testing.T) {
+	for _, test := range optimizeTests {
+		t.Run(test.rpn, func(t *testing.T) {
+			prog, err := Compile(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			optimized := prog.Optimize()
+			if got := strings.Join(optimized.Tokens(), " "); got != test.want {
+				t.Errorf("Optimize() = %q, want %q", got, test.want)
+			}
+			// Optimizing changes what runs, never the result
+			for _, x := range []float64{-2, 0, 0.5, 3} {
+				vars := map[string]float64{"x": x}
+				want, wantErr := prog.EvalWith(vars)
+				got, err := optimized.EvalWith(vars)
+				if (err == nil) != (wantErr == nil) || (err == nil && !sameFloat(got, want)) {
+					t.Errorf("x=%g: optimized = %g, %v, want %g, %v", x, got, err, want, wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	for _, test := range optimizeTests {
+		t.Run(test.rpn, func(t *testing.T) {
+			expr, err := ParseRPN(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(Simplify(expr).ToRPN(), " "); got != test.want {
+				t.Errorf("Simplify() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOptimizeFailingConstantsStillFail(t *testing.T) {
+	tests := []struct {
+		rpn  string
+		want string
+	}{
+		{"x -1 ! +", "x -1 ! +"},
+		{"5 2 nCr x *", "10 x *"},
+		{"x 5 2 nCr 5 2.5 nCr + *", "x 10 5 2.5 nCr + *"},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			prog, err := Compile(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			optimized := prog.Optimize()
+			if got := strings.Join(optimized.Tokens(), " "); got != test.want {
+				t.Errorf("Optimize() = %q, want %q", got, test.want)
+			}
+			vars := map[string]float64{"x": 1}
+			_, wantErr := prog.EvalWith(vars)
+			if _, err := optimized.EvalWith(vars); (err == nil) != (wantErr == nil) || (err != nil && err.Error() != wantErr.Error()) {
+				t.Errorf("optimized error = %v, want %v", err, wantErr)
+			}
+		})
+	}
+}
+
+func TestOptimizeKeepsLabels(t *testing.T) {
+	prog, err := Compile("2 3 + as subtotal x *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if optimized := prog.Optimize(); optimized != prog {
+		t.Errorf("Optimize() of a labeled program = %q, want it unchanged", optimized.Tokens())
+	}
+}
+
+// sameFloat reports whether a and b are equal or both NaN
+func sameFloat(a, b float64) bool {
+	return a == b || (a != a && b != b)
+}
@@ -0,0 +1,46 @@
+package number
+
+import "math/rand"
+
+// Option configures a calculator created by NewRPNCalculator, as an
+// alternative to calling its setters one by one:
+//
+//	calc := NewRPNCalculator(WithRoundingMode(RoundHalfEven), WithMaxStackDepth(64))
+type Option func(calc *RPNCalculator)
+
+// WithPercentMode sets how the % operator treats its base operand
+func WithPercentMode(mode PercentMode) Option {
+	return func(calc *RPNCalculator) { calc.SetPercentMode(mode) }
+}
+
+// WithRoundingMode sets how round and roundN resolve ties
+func WithRoundingMode(mode RoundingMode) Option {
+	return func(calc *RPNCalculator) { calc.SetRoundingMode(mode) }
+}
+
+// WithIntegerPolicy sets how gcd and lcm treat fractional operands
+func WithIntegerPolicy(policy IntegerPolicy) Option {
+	return func(calc *RPNCalculator) { calc.SetIntegerPolicy(policy) }
+}
+
+// WithRandSource sets the source used by rand and randint
+func WithRandSource(source rand.Source) Option {
+	return func(calc *RPNCalculator) { calc.SetRandSource(source) }
+}
+
+// WithLimits sets the resource limits enforced while evaluating
+func WithLimits(limits Limits) Option {
+	return func(calc *RPNCalculator) { calc.SetLimits(limits) }
+}
+
+// WithMaxStackDepth limits how many values the stack may hold at once,
+// leaving the other limits unchanged
+func WithMaxStackDepth(depth int) Option {
+	return func(calc *RPNCalculator) { calc.limits.MaxStackDepth = depth }
+}
+
+// WithMaxTokens limits how many tokens an expression may have, leaving the
+// other limits unchanged
+func WithMaxTokens(count int) Option {
+	return func(calc *RPNCalculator) { calc.limits.MaxTokens = count }
+}
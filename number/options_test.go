@@ -0,0 +1,72 @@
+package number
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		expression string
+		want       float64
+		wantErr    string
+	}{
+		{"defaults", nil, "200 10 %", 20, ""},
+		{"percent mode", []Option{WithPercentMode(PercentKeepBase)}, "200 10 % +", 220, ""},
+		{"default rounding", nil, "2.5 round", 3, ""},
+		{"rounding mode", []Option{WithRoundingMode(RoundHalfEven)}, "2.5 round", 2, ""},
+		{"default integer policy", nil, "4.5 6 gcd", 0, "gcd requires integer operands, got 4.5 (column 7)"},
+		{"integer policy", []Option{WithIntegerPolicy(IntegerRound)}, "4.5 6 gcd", 1, ""},
+		{"angle mode", []Option{WithAngleMode(Degrees)}, "90 sin", 1, ""},
+		{"rand source", []Option{WithRandSource(rand.NewSource(1))}, "rand", rand.New(rand.NewSource(1)).Float64(), ""},
+		{"max tokens", []Option{WithMaxTokens(3)}, "1 2 + 3 +", 0, "token count limit of 3 exceeded: 5"},
+		{"max stack depth", []Option{WithMaxStackDepth(2)}, "1 2 3 + +", 0, "stack depth limit of 2 exceeded: 3 (column 5)"},
+		{"limits", []Option{WithLimits(Limits{MaxTokens: 3})}, "1 2 + 3 +", 0, "token count limit of 3 exceeded: 5"},
+		{
+			"single limits keep the others",
+			[]Option{WithLimits(Limits{MaxTokens: 9}), WithMaxStackDepth(2)},
+			"1 2 3 + +", 0, "stack depth limit of 2 exceeded: 3 (column 5)",
+		},
+		{"later options win", []Option{WithMaxStackDepth(2), WithLimits(Limits{})}, "1 2 3 + +", 6, ""},
+		{
+			"several options",
+			[]Option{WithRoundingMode(RoundHalfEven), WithAngleMode(Degrees), WithMaxTokens(4)},
+			"30 sin round", 0, "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewRPNCalculator(test.opts...).EvaluateExpression(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("EvaluateExpression(%q) = %g, %v, want %g", test.expression, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestOptionsMatchSetters(t *testing.T) {
+	withOptions := NewRPNCalculator(
+		WithPercentMode(PercentKeepBase),
+		WithRoundingMode(RoundHalfUp),
+		WithIntegerPolicy(IntegerRound),
+		WithLimits(Limits{MaxTokens: 10, MaxStackDepth: 4}),
+	)
+	withSetters := NewRPNCalculator()
+	withSetters.SetPercentMode(PercentKeepBase)
+	withSetters.SetRoundingMode(RoundHalfUp)
+	withSetters.SetIntegerPolicy(IntegerRound)
+	withSetters.SetLimits(Limits{MaxTokens: 10, MaxStackDepth: 4})
+
+	if got, want := withOptions.Snapshot(), withSetters.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() with options = %+v, with setters %+v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package number
+
+import (
+	"math"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// pairOperator is an operator leaving two results, like the operators of
+// handheld RPN calculators splitting a value into parts
+type pairOperator struct {
+	arity int
+	// apply returns the results for the operands, deepest first, in the
+	// order they are pushed
+	apply func(operands []float64) (float64, float64)
+	// angleIn and angleOut mark an angle as the top operand and the top
+	// result, in radians for apply and in the angle mode on the stack
+	angleIn, angleOut bool
+}
+
+// pairOperators maps the operators leaving two results to their
+// implementations. Their names list the results bottom first
+var pairOperators = map[string]pairOperator{
+	"divmod": {arity: 2, apply: func(operands []float64) (float64, float64) {
+		return math.Trunc(operands[0] / operands[1]), math.Mod(operands[0], operands[1])
+	}},
+	"fracint": {arity: 1, apply: func(operands []float64) (float64, float64) {
+		integer, fraction := math.Modf(operands[0])
+		return fraction, integer
+	}},
+	"rect→polar":  {arity: 2, apply: rectToPolar, angleOut: true},
+	"rect->polar": {arity: 2, apply: rectToPolar, angleOut: true},
+	"polar→rect":  {arity: 2, apply: polarToRect, angleIn: true},
+	"polar->rect": {arity: 2, apply: polarToRect, angleIn: true},
+}
+
+// rectToPolar converts x and y to the radius and angle
+func rectToPolar(operands []float64) (float64, float64) {
+	return math.Hypot(operands[0], operands[1]), math.Atan2(operands[1], operands[0])
+}
+
+// polarToRect converts a radius and angle to x and y
+func polarToRect(operands []float64) (float64, float64) {
+	sin, cos := math.Sincos(operands[1])
+	return operands[0] * cos, operands[0] * sin
+}
+
+// performPair replaces the operands of a two result operator with its
+// results, checking the stack depth limit before changing the stack
+func (calc *RPNCalculator) performPair(token string, operator pairOperator) error {
+	if calc.Size() < operator.arity {
+		return engine.Underflow(token)
+	}
+	if err := calc.limits.checkStackDepth(calc.Size() - operator.arity + 2); err != nil {
+		return err
+	}
+	operands := make([]float64, operator.arity)
+	for i := operator.arity - 1; i >= 0; i-- {
+		operands[i], _ = calc.Pop()
+	}
+	degrees := calc.angleMode == Degrees
+	if degrees && operator.angleIn {
+		operands[operator.arity-1] *= math.Pi / 180
+	}
+	first, second := operator.apply(operands)
+	if degrees && operator.angleOut {
+		second *= 180 / math.Pi
+	}
+	calc.Push(first)
+	calc.Push(second)
+	return nil
+}
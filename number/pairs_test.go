@@ -0,0 +1,103 @@
+package number
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPairOperators(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []float64
+		wantErr    string
+	}{
+		{"17 5 divmod", []float64{3, 2}, ""},
+		{"3.75 fracint", []float64{0.75, 3}, ""},
+		{"-3.75 fracint", []float64{-0.75, -3}, ""},
+		{"3 4 rect→polar", []float64{5, math.Atan2(4, 3)}, ""},
+		{"3 4 rect->polar", []float64{5, math.Atan2(4, 3)}, ""},
+		{"0 0 rect->polar", []float64{0, 0}, ""},
+		{"2 0 polar→rect", []float64{2, 0}, ""},
+		{"2 1 polar->rect", []float64{2 * math.Cos(1), 2 * math.Sin(1)}, ""},
+		{"3 4 rect->polar polar->rect", []float64{3, 4}, ""},
+		{"1 2 3 divmod", []float64{1, 0, 2}, ""},
+		{"1 fracint 2", []float64{0, 1, 2}, ""},
+		{"3.75 fracint +", []float64{3.75}, ""},
+		{"fracint", nil, "insufficient operands for fracint operation (column 1)"},
+		{"1 rect->polar", nil, "insufficient operands for rect->polar operation (column 3)"},
+		{"1 polar→rect", nil, "insufficient operands for polar→rect operation (column 3)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewRPNCalculator().EvaluateAll(test.expression)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvaluateAll() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || len(got) != len(test.want) {
+				t.Fatalf("EvaluateAll() = %v, %v, want %v", got, err, test.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-test.want[i]) > 1e-12 {
+					t.Errorf("EvaluateAll() = %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPairOperatorStackDepth(t *testing.T) {
+	tests := []struct {
+		expression string
+		depth      int
+		wantErr    string
+	}{
+		{"1 2 fracint", 2, "stack depth limit of 2 exceeded: 3 (column 5)"},
+		{"1 2 fracint", 3, ""},
+		// divmod replaces two values with two
+		{"1 2 divmod", 2, ""},
+	}
+	for _, test := range tests {
+		calc := NewRPNCalculator(WithMaxStackDepth(test.depth))
+		_, err := calc.EvaluateAll(test.expression)
+		if test.wantErr == "" && err != nil || test.wantErr != "" && (err == nil || err.Error() != test.wantErr) {
+			t.Errorf("EvaluateAll(%q) with depth %d error = %v, want %q", test.expression, test.depth, err, test.wantErr)
+		}
+		if err != nil && calc.Size() != 2 {
+			// The operands are left in place
+			t.Errorf("EvaluateAll(%q) left %v", test.expression, calc.Values())
+		}
+	}
+}
+
+func TestPairOperatorsInDegrees(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []float64
+	}{
+		{"1 1 rect->polar", []float64{math.Sqrt2, 45}},
+		{"0 -2 rect→polar", []float64{2, -90}},
+		{"2 90 polar->rect", []float64{0, 2}},
+		{"2 60 polar→rect", []float64{1, math.Sqrt(3)}},
+		{"3 4 rect->polar polar->rect", []float64{3, 4}},
+		// Operators without angles are unaffected
+		{"17 5 divmod", []float64{3, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewRPNCalculator(WithAngleMode(Degrees)).EvaluateAll(test.expression)
+			if err != nil || len(got) != len(test.want) {
+				t.Fatalf("EvaluateAll() = %v, %v, want %v", got, err, test.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-test.want[i]) > 1e-12 {
+					t.Errorf("EvaluateAll() = %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package number
+
+import "github.com/StefanTrusnov/go-rpn/engine"
+
+// PercentMode selects how the % operator treats its base operand
+type PercentMode int
+
+const (
+	// PercentOfBase replaces base and rate with rate percent of base, so
+	// "200 10 %" leaves 20
+	PercentOfBase PercentMode = iota
+	// PercentKeepBase leaves the base on the stack below the percentage like
+	// handheld calculators, so "200 10 % +" leaves 220
+	PercentKeepBase
+)
+
+// percentOf returns rate percent of base
+func percentOf(base, rate float64) float64 {
+	return base * rate / 100
+}
+
+// SetPercentMode sets how the % operator treats its base operand
+func (calc *RPNCalculator) SetPercentMode(mode PercentMode) {
+	calc.percentMode = mode
+}
+
+// performPercent applies % keeping the base on the stack
+func (calc *RPNCalculator) performPercent() error {
+	if calc.Size() < 2 {
+		return engine.Underflow("%")
+	}
+
+	b, _ := calc.Pop()
+	a, _ := calc.Peek()
+	calc.Push(percentOf(a, b))
+	return nil
+}
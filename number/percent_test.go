@@ -0,0 +1,47 @@
+package number
+
+import (
+	"testing"
+)
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		mode       PercentMode
+		expression string
+		want       float64
+	}{
+		{PercentOfBase, "200 10 %", 20},
+		{PercentOfBase, "50 200 %", 100},
+		{PercentKeepBase, "200 10 % +", 220},
+		{PercentKeepBase, "200 10 % -", 180},
+		{PercentOfBase, "17 5 mod", 2},
+		{PercentKeepBase, "17 5 mod", 2},
+		{PercentOfBase, "-7 3 mod", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			calc := NewRPNCalculator(WithPercentMode(test.mode))
+			got, err := calc.EvaluateExpression(test.expression)
+			if err != nil || got != test.want {
+				t.Errorf("EvaluateExpression(%q) = %g, %v, want %g", test.expression, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestPercentKeepBaseLeavesBase(t *testing.T) {
+	calc := NewRPNCalculator(WithPercentMode(PercentKeepBase))
+	for _, token := range []string{"200", "10", "%"} {
+		if err := calc.Evaluate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if values := calc.Values(); len(values) != 2 || values[0] != 200 || values[1] != 20 {
+		t.Errorf("stack after 200 10 %% = %v, want [200 20]", values)
+	}
+
+	calc = NewRPNCalculator(WithPercentMode(PercentKeepBase))
+	if _, err := calc.EvaluateExpression("10 %"); err == nil || err.Error() != "insufficient operands for % operation (column 4)" {
+		t.Errorf("EvaluateExpression(10 %%) error = %v, want an underflow", err)
+	}
+}
@@ -0,0 +1,70 @@
+package number
+
+import "maps"
+
+// Associativity is the side from which operators of equal precedence group
+type Associativity int
+
+const (
+	// LeftAssociative operators group from the left: a - b - c is (a - b) - c
+	LeftAssociative Associativity = iota
+	// RightAssociative operators group from the right: a ^ b ^ c is a ^ (b ^ c)
+	RightAssociative
+)
+
+// OperatorPrecedence is how tightly an infix operator binds and how it groups
+type OperatorPrecedence struct {
+	Level         int
+	Associativity Associativity
+}
+
+// PrecedenceTable maps the binary operators and the prefix neg to their
+// precedence, higher levels binding tighter
+type PrecedenceTable map[string]OperatorPrecedence
+
+// defaultPrecedence is the table of conventional arithmetic
+var defaultPrecedence = PrecedenceTable{
+	"<":   {Level: 1},
+	">":   {Level: 1},
+	"<=":  {Level: 1},
+	">=":  {Level: 1},
+	"==":  {Level: 1},
+	"!=":  {Level: 1},
+	"+":   {Level: 2},
+	"-":   {Level: 2},
+	"*":   {Level: 3},
+	"/":   {Level: 3},
+	"%":   {Level: 3},
+	"mod": {Level: 3},
+	"neg": {Level: 4},
+	"^":   {Level: 5, Associativity: RightAssociative},
+	"**":  {Level: 5, Associativity: RightAssociative},
+}
+
+// DefaultPrecedence returns a copy of the built-in precedence table, to be
+// adjusted and passed to ParseInfixWith
+func DefaultPrecedence() PrecedenceTable {
+	return maps.Clone(defaultPrecedence)
+}
+
+// level returns the precedence level of an operator
+func (table PrecedenceTable) level(op string) int {
+	return table[op].Level
+}
+
+// rightAssociative reports whether an operator groups from the right
+func (table PrecedenceTable) rightAssociative(op string) bool {
+	return table[op].Associativity == RightAssociative
+}
+
+// shouldPop reports whether the operator on top of the stack must be output
+// before pushing token, honoring right associativity
+func (table PrecedenceTable) shouldPop(top, token string) bool {
+	if top == "(" {
+		return false
+	}
+	if table.rightAssociative(token) {
+		return table.level(top) > table.level(token)
+	}
+	return table.level(top) >= table.level(token)
+}
@@ -0,0 +1,120 @@
+package number
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// PrettyOptions controls how PrettyRPN lays out a program
+type PrettyOptions struct {
+	// Numbered prefixes every line with the step number of its token
+	Numbered bool
+	// Align pads the columns so tokens, annotations and stacks line up
+	Align bool
+	// Stack appends the stack of placeholders left after each step
+	Stack bool
+	// Indent is prepended to every line
+	Indent string
+}
+
+// PrettyRPN renders an RPN expression one token per line, annotated with
+// what the token computes. The value pushed by step n is written $n, so
+// 2 3 + annotates + as $3 = $1 + $2 and every operand can be traced back
+// to the step that produced it. The two values of an operator like divmod
+// are written $n.1 and $n.2. A value labeled with as is written by its
+// label from then on
+func PrettyRPN(expression string, opts PrettyOptions) (string, error) {
+	tokens := Tokenize(expression)
+	values := make([]string, len(tokens))
+	for i, token := range tokens {
+		values[i] = token.Value
+	}
+
+	rows := make([][]string, 0, len(tokens))
+	stack := []string{}
+	for i, token := range values {
+		if i > 0 && values[i-1] == labelToken {
+			// The name is shown on the row of as
+			continue
+		}
+		pops, pushes, err := stackEffect(values, i, len(stack))
+		if err != nil {
+			return "", positionError(expression, tokens[i], err)
+		}
+		if len(stack) < pops {
+			return "", positionError(expression, tokens[i], engine.Underflow(token))
+		}
+		operands := stack[len(stack)-pops:]
+		stack = stack[:len(stack)-pops]
+
+		annotation := "(display)"
+		if token == labelToken {
+			token = labelToken + " " + values[i+1]
+			annotation = values[i+1] + " = " + operands[0]
+			stack = append(stack, values[i+1])
+		} else if pushes > 0 {
+			results := make([]string, pushes)
+			for r := range results {
+				results[r] = fmt.Sprintf("$%d", i+1)
+				if pushes > 1 {
+					results[r] += fmt.Sprintf(".%d", r+1)
+				}
+			}
+			annotation = strings.Join(results, ", ") + " = " + describeStep(token, operands)
+			stack = append(stack, results...)
+		}
+
+		row := []string{}
+		if opts.Numbered {
+			row = append(row, fmt.Sprintf("%d", i+1))
+		}
+		row = append(row, token, annotation)
+		if opts.Stack {
+			row = append(row, "["+strings.Join(stack, " ")+"]")
+		}
+		rows = append(rows, row)
+	}
+
+	widths := []int{}
+	if opts.Align {
+		for _, row := range rows {
+			for column, cell := range row {
+				if column == len(widths) {
+					widths = append(widths, 0)
+				}
+				widths[column] = max(widths[column], len(cell))
+			}
+		}
+	}
+
+	var out strings.Builder
+	for _, row := range rows {
+		out.WriteString(opts.Indent)
+		for column, cell := range row {
+			if column > 0 {
+				out.WriteString("  ")
+			}
+			out.WriteString(cell)
+			if opts.Align && column < len(row)-1 {
+				out.WriteString(strings.Repeat(" ", widths[column]-len(cell)))
+			}
+		}
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+// describeStep renders what a token computes from its operand placeholders,
+// symbolic binary operators infix and everything else as a function call
+func describeStep(token string, operands []string) string {
+	if len(operands) == 0 {
+		return token
+	}
+	if len(operands) == 2 && !unicode.IsLetter(rune(token[0])) {
+		return operands[0] + " " + token + " " + operands[1]
+	}
+	return token + "(" + strings.Join(operands, ", ") + ")"
+}
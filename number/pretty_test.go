@@ -0,0 +1,107 @@
+package number
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrettyRPN(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		opts       PrettyOptions
+		want       []string
+	}{
+		{"plain", "2 3 +", PrettyOptions{}, []string{
+			"2  $1 = 2",
+			"3  $2 = 3",
+			"+  $3 = $1 + $2",
+		}},
+		{"numbered aligned stack", "x 2 ^ 1 + sqrt", PrettyOptions{Numbered: true, Align: true, Stack: true}, []string{
+			"1  x     $1 = x         [$1]",
+			"2  2     $2 = 2         [$1 $2]",
+			"3  ^     $3 = $1 ^ $2   [$3]",
+			"4  1     $4 = 1         [$3 $4]",
+			"5  +     $5 = $3 + $4   [$5]",
+			"6  sqrt  $6 = sqrt($5)  [$6]",
+		}},
+		{"labels", "subtotal tax * as taxAmount subtotal +", PrettyOptions{Align: true, Stack: true, Indent: "    "}, []string{
+			"    subtotal      $1 = subtotal        [$1]",
+			"    tax           $2 = tax             [$1 $2]",
+			"    *             $3 = $1 * $2         [$3]",
+			"    as taxAmount  taxAmount = $3       [taxAmount]",
+			"    subtotal      $6 = subtotal        [taxAmount $6]",
+			"    +             $7 = taxAmount + $6  [$7]",
+		}},
+		{"functions and display modes", "1 1 atan2 hex", PrettyOptions{Stack: true}, []string{
+			"1  $1 = 1  [$1]",
+			"1  $2 = 1  [$1 $2]",
+			"atan2  $3 = atan2($1, $2)  [$3]",
+			"hex  (display)  [$3]",
+		}},
+		{"variadic", "1 2 3 sum", PrettyOptions{Stack: true}, []string{
+			"1  $1 = 1  [$1]",
+			"2  $2 = 2  [$1 $2]",
+			"3  $3 = 3  [$1 $2 $3]",
+			"sum  $4 = sum($1, $2, $3)  [$4]",
+		}},
+		{"two results", "17 5 divmod +", PrettyOptions{Stack: true}, []string{
+			"17  $1 = 17  [$1]",
+			"5  $2 = 5  [$1 $2]",
+			"divmod  $3.1, $3.2 = divmod($1, $2)  [$3.1 $3.2]",
+			"+  $4 = $3.1 + $3.2  [$4]",
+		}},
+		{"empty", "", PrettyOptions{}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := PrettyRPN(test.expression, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ""
+			for _, line := range test.want {
+				want += line + "\n"
+			}
+			if got != want {
+				t.Errorf("PrettyRPN(%q) =\n%s\nwant:\n%s", test.expression, got, want)
+			}
+		})
+	}
+}
+
+func TestPrettyRPNErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"1 +", "insufficient operands for + operation (column 3)"},
+		{"1 as", "as requires a name (column 3)"},
+		{"1 as sqrt", "invalid label: sqrt (column 3)"},
+		{"1 divmod", "insufficient operands for divmod operation (column 3)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := PrettyRPN(test.expression, PrettyOptions{})
+			if err == nil || err.Error() != test.want {
+				t.Errorf("PrettyRPN(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+// TestPrettyRPNLinesPerToken checks that every token but label names gets
+// its own line
+func TestPrettyRPNLinesPerToken(t *testing.T) {
+	for _, expression := range []string{"1 2 + 3 *", "x as y y *", "5 neg abs"} {
+		got, err := PrettyRPN(expression, PrettyOptions{Numbered: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens := strings.Fields(expression)
+		want := len(tokens) - strings.Count(expression, " as ")
+		if lines := strings.Count(got, "\n"); lines != want {
+			t.Errorf("PrettyRPN(%q) has %d lines, want %d", expression, lines, want)
+		}
+	}
+}
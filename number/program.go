@@ -0,0 +1,197 @@
+package number
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"unicode"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// maxInlineStack is the stack depth Eval can serve without allocating
+const maxInlineStack = 32
+
+// contextCheckInterval is how many tokens or instructions run between
+// checks for cancellation of the evaluation context
+const contextCheckInterval = 64
+
+// opKind identifies what a compiled instruction does
+type opKind int
+
+const (
+	opPush opKind = iota
+	opVar
+	opUnary
+	opBinary
+	opCheckedUnary
+	opCheckedBinary
+	opTernary
+	opCall
+)
+
+// instruction is a single pre-resolved step of a compiled program, the
+// form the optimizer works on before the program is lowered to bytecode
+type instruction struct {
+	kind   opKind
+	op     string
+	value  float64
+	name   string
+	unary  func(float64) float64
+	binary func(float64, float64) float64
+
+	checkedUnary  func(float64) (float64, error)
+	checkedBinary func(float64, float64) (float64, error)
+	ternary       func(float64, float64, float64) float64
+	call          engine.Operator[float64]
+}
+
+// Program is an immutable, pre-tokenized RPN expression that can be
+// evaluated many times without parsing the source again
+type Program struct {
+	source   string
+	code     []instruction
+	vm       bytecode
+	maxDepth int
+
+	complexity Complexity
+
+	// labels names intermediate results with as, in program order
+	labels []programLabel
+}
+
+// Compile tokenizes and validates an RPN expression into a reusable program
+func Compile(expression string) (*Program, error) {
+	tokens := Tokenize(expression)
+	values := make([]string, len(tokens))
+	for i, token := range tokens {
+		values[i] = token.Value
+	}
+	prog := &Program{
+		source: expression,
+		code:   make([]instruction, 0, len(tokens)),
+	}
+
+	analysis, at, err := analyzeStack(values)
+	if err != nil {
+		return nil, positionError(expression, tokens[at], err)
+	}
+	if !analysis.Balanced() {
+		return nil, engine.Incomplete(analysis.FinalDepth)
+	}
+	prog.maxDepth = analysis.MaxDepth
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		token := tok.Value
+		if token == labelToken {
+			// analyzeStack validated the name following as
+			i++
+			prog.labels = append(prog.labels, programLabel{name: tokens[i].Value, at: len(prog.code)})
+			continue
+		}
+		if operation, ok := ternaryOperators[token]; ok {
+			prog.code = append(prog.code, instruction{kind: opTernary, op: token, ternary: operation})
+		} else if operation, ok := binaryOperators[token]; ok {
+			prog.code = append(prog.code, instruction{kind: opBinary, op: token, binary: operation})
+		} else if operation, ok := checkedBinaryOperators[token]; ok {
+			prog.code = append(prog.code, instruction{kind: opCheckedBinary, op: token, checkedBinary: operation})
+		} else if operation, ok := unaryOperators[token]; ok {
+			prog.code = append(prog.code, instruction{kind: opUnary, op: token, unary: operation})
+		} else if operation, ok := checkedUnaryOperators[token]; ok {
+			prog.code = append(prog.code, instruction{kind: opCheckedUnary, op: token, checkedUnary: operation})
+		} else if operator, ok := functions[token]; ok && operator.Arity != VariadicArity {
+			prog.code = append(prog.code, instruction{kind: opCall, op: token, call: operator})
+		} else if value, err := parseNumber(token); err == nil {
+			prog.code = append(prog.code, instruction{kind: opPush, value: value})
+		} else if isCalculatorOnlyToken(token) {
+			return nil, positionError(expression, tok, fmt.Errorf("unsupported token in compiled program: %s", token))
+		} else if isIdentifier(token) {
+			prog.code = append(prog.code, instruction{kind: opVar, name: token})
+		} else {
+			return nil, positionError(expression, tok, fmt.Errorf("unsupported token in compiled program: %s", token))
+		}
+	}
+
+	prog.vm = assemble(prog.code, prog.labels)
+	prog.complexity = measureComplexity(prog.code)
+	return prog, nil
+}
+
+// MaxDepth returns the deepest the stack gets while the program runs
+func (prog *Program) MaxDepth() int {
+	return prog.maxDepth
+}
+
+// String returns the source expression of the program
+func (prog *Program) String() string {
+	return prog.source
+}
+
+// Variables returns the names of the variables the program reads, in order
+// of first use
+func (prog *Program) Variables() []string {
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, instr := range prog.code {
+		if instr.kind == opVar && !seen[instr.name] {
+			seen[instr.name] = true
+			names = append(names, instr.name)
+		}
+	}
+	return names
+}
+
+// Eval runs the program and returns its result
+func (prog *Program) Eval() (float64, error) {
+	return prog.EvalWith(nil)
+}
+
+// EvalWith runs the program with variables bound to the given values
+func (prog *Program) EvalWith(vars map[string]float64) (float64, error) {
+	return prog.EvalContext(context.Background(), vars)
+}
+
+// EvalContext runs the program with variables bound to the given values,
+// giving up with the context's error once ctx is done. The context is
+// checked every contextCheckInterval instructions. It allocates nothing
+// unless it fails or the stack is deeper than maxInlineStack
+func (prog *Program) EvalContext(ctx context.Context, vars map[string]float64) (float64, error) {
+	if prog.maxDepth > maxInlineStack {
+		return prog.vm.run(ctx, vars, make([]float64, prog.maxDepth), nil)
+	}
+	// Functions receive their operands as a slice of the stack, so it
+	// escapes and is pooled rather than kept on the goroutine stack
+	stack := stackPool.Get().(*[maxInlineStack]float64)
+	defer stackPool.Put(stack)
+	return prog.vm.run(ctx, vars, stack[:], nil)
+}
+
+// stackPool holds the evaluation stacks of programs no deeper than
+// maxInlineStack
+var stackPool = sync.Pool{
+	New: func() any { return new([maxInlineStack]float64) },
+}
+
+// isCalculatorOnlyToken reports whether token only has meaning on an
+// interactive calculator stack, such as reducers, variadic functions and
+// display modes
+func isCalculatorOnlyToken(token string) bool {
+	_, _, isReducer := lookupReducer(token)
+	_, isDisplayBase := displayBases[token]
+	_, isPair := pairOperators[token]
+	function, isFunction := functions[token]
+	isVariadic := isFunction && function.Arity == VariadicArity
+	return isReducer || isDisplayBase || randomTokens[token] || isPair || isVariadic
+}
+
+// isIdentifier reports whether token is a valid variable name
+func isIdentifier(token string) bool {
+	for i, char := range token {
+		if char == '_' || unicode.IsLetter(char) || (i > 0 && unicode.IsDigit(char)) {
+			continue
+		}
+		return false
+	}
+	return token != ""
+}
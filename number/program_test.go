@@ -0,0 +1,135 @@
+package number
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+		maxDepth   int
+	}{
+		{"3 4 +", 7, 2},
+		{"5 1 2 + 4 * + 3 -", 14, 3},
+		{"2 3 ^ 1 -", 7, 2},
+		{"9 sqrt neg", -3, 1},
+		{"1 2 3 if", 2, 3},
+		{"5 !", 120, 1},
+		{"5 2 nCr", 10, 2},
+		{"10 as x 2 *", 20, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", test.expression, err)
+			}
+			if prog.String() != test.expression {
+				t.Errorf("String() = %q, want %q", prog.String(), test.expression)
+			}
+			if prog.MaxDepth() != test.maxDepth {
+				t.Errorf("MaxDepth() = %d, want %d", prog.MaxDepth(), test.maxDepth)
+			}
+			// A program evaluates the same any number of times
+			for range 2 {
+				if got, err := prog.Eval(); err != nil || got != test.want {
+					t.Errorf("Eval() = %g, %v, want %g", got, err, test.want)
+				}
+			}
+			if interpreted, err := Eval(test.expression); err != nil || interpreted != test.want {
+				t.Errorf("calculator Eval(%q) = %g, %v, want %g", test.expression, interpreted, err, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"3 +", "insufficient operands for + operation (column 3)"},
+		{"1 2", "expected 1 result, got 2"},
+		{"1 2 3 sum", "unsupported token in compiled program: sum"},
+		{"1 $", "(column 3)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := Compile(test.expression)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Compile(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestProgramEvalDoesNotAllocate(t *testing.T) {
+	prog, err := Compile("5 1 2 + 4 * + 3 - sqrt 2 max")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := prog.Eval(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Eval() allocates %g times per call, want 0", allocs)
+	}
+}
+
+func TestProgramEvalWith(t *testing.T) {
+	prog, err := Compile("price qty * discount -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(prog.Variables(), " "); got != "price qty discount" {
+		t.Errorf("Variables() = %q, want %q", got, "price qty discount")
+	}
+
+	tests := []struct {
+		name    string
+		vars    map[string]float64
+		want    float64
+		wantErr string
+	}{
+		{"bound", map[string]float64{"price": 2.5, "qty": 4, "discount": 1}, 9, ""},
+		{"rebound", map[string]float64{"price": 10, "qty": 3, "discount": 0}, 30, ""},
+		{"extra bindings", map[string]float64{"price": 1, "qty": 1, "discount": 1, "tax": 5}, 0, ""},
+		{"missing", map[string]float64{"price": 1, "discount": 1}, 0, "undefined variable: qty"},
+		{"none", nil, 0, "undefined variable: price"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := prog.EvalWith(test.vars)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("EvalWith() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("EvalWith() = %g, %v, want %g", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestProgramEvalWithDoesNotAllocate(t *testing.T) {
+	prog, err := Compile("price qty * 2 max")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]float64{"price": 3, "qty": 2}
+	allocs := testing.AllocsPerRun(100, func() {
+		vars["qty"]++
+		if _, err := prog.EvalWith(vars); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("EvalWith() allocates %g times per call, want 0", allocs)
+	}
+}
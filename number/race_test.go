@@ -0,0 +1,7 @@
+//go:build race
+
+package number
+
+// raceEnabled reports that the tests run with the race detector, whose
+// instrumentation allocates, so allocation counts do not hold
+const raceEnabled = true
@@ -0,0 +1,70 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// randomTokens lists the tokens drawing from the calculator's random source
+var randomTokens = map[string]bool{
+	"rand":    true,
+	"randint": true,
+}
+
+// lockedSource serializes access to a random source shared by the
+// goroutines evaluating through one calculator with Eval
+type lockedSource struct {
+	mu     sync.Mutex
+	source rand.Source
+}
+
+// Int63 implements rand.Source
+func (locked *lockedSource) Int63() int64 {
+	locked.mu.Lock()
+	defer locked.mu.Unlock()
+	return locked.source.Int63()
+}
+
+// Seed implements rand.Source
+func (locked *lockedSource) Seed(seed int64) {
+	locked.mu.Lock()
+	defer locked.mu.Unlock()
+	locked.source.Seed(seed)
+}
+
+// SetRandSource replaces the source used by rand and randint, so tests and
+// simulations can use a fixed seed for reproducible results
+func (calc *RPNCalculator) SetRandSource(source rand.Source) {
+	calc.random = rand.New(&lockedSource{source: source})
+}
+
+// defaultRandom is the generator calculators start with, seeded from the
+// clock. It is shared, so new and recycled calculators do not seed a
+// source each, and locked, so any goroutines may draw from it
+var defaultRandom = rand.New(&lockedSource{source: rand.NewSource(time.Now().UnixNano())})
+
+// performRandom pushes a uniform value in [0, 1) for rand, or pops lo and hi
+// and pushes a uniform integer in [lo, hi] for randint
+func (calc *RPNCalculator) performRandom(token string) error {
+	random := calc.random
+	if token == "rand" {
+		calc.Push(random.Float64())
+		return nil
+	}
+
+	return calc.performCheckedBinaryOperation(token, func(lo, hi float64) (float64, error) {
+		if lo != math.Trunc(lo) || hi != math.Trunc(hi) {
+			return 0, fmt.Errorf("randint requires integer bounds, got %g and %g", lo, hi)
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("randint requires lo <= hi, got %g and %g", lo, hi)
+		}
+		if hi-lo >= math.MaxInt64 {
+			return 0, fmt.Errorf("randint range too large")
+		}
+		return lo + float64(random.Int63n(int64(hi-lo)+1)), nil
+	})
+}
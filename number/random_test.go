@@ -0,0 +1,84 @@
+package number
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestRandomIsReproducibleWithASeed(t *testing.T) {
+	draw := func() []float64 {
+		calc := NewRPNCalculator()
+		calc.SetRandSource(rand.NewSource(42))
+		values := []float64{}
+		for _, expression := range []string{"rand", "1 6 randint", "-10 10 randint"} {
+			value, err := calc.EvaluateExpression(expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, value)
+		}
+		return values
+	}
+
+	first, second := draw(), draw()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("seeded draws differ: %v and %v", first, second)
+		}
+	}
+	if first[0] < 0 || first[0] >= 1 || first[1] < 1 || first[1] > 6 || first[2] < -10 || first[2] > 10 {
+		t.Errorf("draws out of range: %v", first)
+	}
+}
+
+func TestRandintErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"1.5 6 randint", "randint requires integer bounds, got 1.5 and 6 (column 7)"},
+		{"6 1 randint", "randint requires lo <= hi, got 6 and 1 (column 5)"},
+		{"1 randint", "insufficient operands for randint operation (column 3)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+// TestConcurrentRandomDraws draws through one shared calculator and through
+// the pooled calculators of Eval at once, which the race detector checks
+func TestConcurrentRandomDraws(t *testing.T) {
+	shared := NewRPNCalculator()
+	// A draw before sharing the calculator used to install an unlocked
+	// default generator the concurrent evaluations then shared
+	if _, err := shared.EvaluateExpression("rand"); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for _, eval := range []func(string) (float64, error){shared.Eval, Eval} {
+					value, err := eval("1 6 randint")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if value < 1 || value > 6 {
+						t.Errorf("randint = %g, want a value in [1, 6]", value)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
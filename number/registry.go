@@ -0,0 +1,216 @@
+package number
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VariadicArity marks operators that consume a variable number of operands
+const VariadicArity = -1
+
+// OperatorInfo describes an operator or function the calculator understands
+type OperatorInfo struct {
+	Symbol   string
+	Arity    int
+	Operands []string
+	Doc      string
+	Examples []string
+}
+
+// operatorRegistry documents every operator token, keyed by symbol
+var operatorRegistry = map[string]OperatorInfo{
+	"+": {Symbol: "+", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Adds the top two values", Examples: []string{"3 4 +"}},
+	"-": {Symbol: "-", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Subtracts the top value from the one below it", Examples: []string{"10 4 -"}},
+	"*": {Symbol: "*", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Multiplies the top two values", Examples: []string{"3 4 *"}},
+	"/": {Symbol: "/", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Divides the second value by the top value", Examples: []string{"12 4 /"}},
+	"^": {Symbol: "^", Arity: 2, Operands: []string{"number", "integer"},
+		Doc: "Raises the second value to the integer power on top", Examples: []string{"2 10 ^"}},
+	"**": {Symbol: "**", Arity: 2, Operands: []string{"number", "integer"},
+		Doc: "Alias of ^", Examples: []string{"2 10 **"}},
+	"%": {Symbol: "%", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Takes the top value as a percentage of the one below it, keeping the base in PercentKeepBase mode", Examples: []string{"200 10 %"}},
+	"mod": {Symbol: "mod", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Remainder of dividing the second value by the top value", Examples: []string{"17 5 mod"}},
+	"divmod": {Symbol: "divmod", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Replaces the top two values with the truncated quotient and the remainder of dividing the second by the top, leaving two results", Examples: []string{"17 5 divmod"}},
+	"fracint": {Symbol: "fracint", Arity: 1, Operands: []string{"number"},
+		Doc: "Replaces the top value with its fractional part and its integer part on top, leaving two results", Examples: []string{"3.75 fracint"}},
+	"rect→polar": {Symbol: "rect→polar", Arity: 2, Operands: []string{"x", "y"},
+		Doc: "Converts rectangular coordinates to the radius and the angle on top in the calculator angle mode, leaving two results", Examples: []string{"3 4 rect→polar"}},
+	"rect->polar": {Symbol: "rect->polar", Arity: 2, Operands: []string{"x", "y"},
+		Doc: "Alias of rect→polar", Examples: []string{"3 4 rect->polar"}},
+	"polar→rect": {Symbol: "polar→rect", Arity: 2, Operands: []string{"radius", "angle"},
+		Doc: "Converts a radius and an angle in the calculator angle mode to x and y on top, leaving two results", Examples: []string{"2 0.5 polar→rect"}},
+	"polar->rect": {Symbol: "polar->rect", Arity: 2, Operands: []string{"radius", "angle"},
+		Doc: "Alias of polar→rect", Examples: []string{"2 0.5 polar->rect"}},
+	"<": {Symbol: "<", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes 1 if the second value is less than the top value, else 0", Examples: []string{"2 3 <"}},
+	">": {Symbol: ">", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes 1 if the second value is greater than the top value, else 0", Examples: []string{"2 3 >"}},
+	"<=": {Symbol: "<=", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes 1 if the second value is less than or equal to the top value, else 0", Examples: []string{"3 3 <="}},
+	">=": {Symbol: ">=", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes 1 if the second value is greater than or equal to the top value, else 0", Examples: []string{"3 3 >="}},
+	"==": {Symbol: "==", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes 1 if the top two values are equal, else 0", Examples: []string{"3 3 =="}},
+	"!=": {Symbol: "!=", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes 1 if the top two values differ, else 0", Examples: []string{"3 4 !="}},
+	"if": {Symbol: "if", Arity: 3, Operands: []string{"condition", "number", "number"},
+		Doc: "Pops condition, then-value and else-value, pushing then-value when the condition is nonzero", Examples: []string{"1 10 20 if"}},
+	"select": {Symbol: "select", Arity: 3, Operands: []string{"condition", "number", "number"},
+		Doc: "Alias of if", Examples: []string{"5 3 > 1 0 select"}},
+	"min": {Symbol: "min", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes the smaller of the top two values", Examples: []string{"3 7 min"}},
+	"max": {Symbol: "max", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Pushes the larger of the top two values", Examples: []string{"3 7 max"}},
+	"minn": {Symbol: "minn", Arity: VariadicArity, Operands: []string{"number...", "count"},
+		Doc: "Pops a count N and replaces the top N values with the smallest", Examples: []string{"4 9 2 3 minn"}},
+	"maxn": {Symbol: "maxn", Arity: VariadicArity, Operands: []string{"number...", "count"},
+		Doc: "Pops a count N and replaces the top N values with the largest", Examples: []string{"4 9 2 3 maxn"}},
+	"neg": {Symbol: "neg", Arity: 1, Operands: []string{"number"},
+		Doc: "Negates the top value", Examples: []string{"5 neg"}},
+	"round": {Symbol: "round", Arity: 1, Operands: []string{"number"},
+		Doc: "Rounds to the nearest integer using the calculator rounding mode", Examples: []string{"2.5 round"}},
+	"floor": {Symbol: "floor", Arity: 1, Operands: []string{"number"},
+		Doc: "Rounds down to the nearest integer", Examples: []string{"2.7 floor"}},
+	"ceil": {Symbol: "ceil", Arity: 1, Operands: []string{"number"},
+		Doc: "Rounds up to the nearest integer", Examples: []string{"2.1 ceil"}},
+	"trunc": {Symbol: "trunc", Arity: 1, Operands: []string{"number"},
+		Doc: "Drops the fractional part", Examples: []string{"-2.7 trunc"}},
+	"sqrt": {Symbol: "sqrt", Arity: 1, Operands: []string{"number"},
+		Doc: "Takes the square root", Examples: []string{"16 sqrt"}},
+	"sin": {Symbol: "sin", Arity: 1, Operands: []string{"angle"},
+		Doc: "Sine of an angle in the calculator angle mode", Examples: []string{"0 sin"}},
+	"cos": {Symbol: "cos", Arity: 1, Operands: []string{"angle"},
+		Doc: "Cosine of an angle in the calculator angle mode", Examples: []string{"0 cos"}},
+	"tan": {Symbol: "tan", Arity: 1, Operands: []string{"angle"},
+		Doc: "Tangent of an angle in the calculator angle mode", Examples: []string{"0 tan"}},
+	"asin": {Symbol: "asin", Arity: 1, Operands: []string{"number"},
+		Doc: "Angle whose sine is the top value, in the calculator angle mode", Examples: []string{"1 asin"}},
+	"acos": {Symbol: "acos", Arity: 1, Operands: []string{"number"},
+		Doc: "Angle whose cosine is the top value, in the calculator angle mode", Examples: []string{"1 acos"}},
+	"atan": {Symbol: "atan", Arity: 1, Operands: []string{"number"},
+		Doc: "Angle whose tangent is the top value, in the calculator angle mode", Examples: []string{"1 atan"}},
+	"atan2": {Symbol: "atan2", Arity: 2, Operands: []string{"y", "x"},
+		Doc: "Angle of the point (x, y) from the positive x axis, in the calculator angle mode", Examples: []string{"1 1 atan2"}},
+	"hypot": {Symbol: "hypot", Arity: 2, Operands: []string{"number", "number"},
+		Doc: "Square root of the sum of the squares of the top two values", Examples: []string{"3 4 hypot"}},
+	"clamp": {Symbol: "clamp", Arity: 3, Operands: []string{"number", "lo", "hi"},
+		Doc: "Limits a value to the bounds lo and hi", Examples: []string{"15 0 10 clamp"}},
+	"roundN": {Symbol: "roundN", Arity: 2, Operands: []string{"number", "integer"},
+		Doc: "Rounds the second value to the number of decimal places on top", Examples: []string{"3.14159 2 roundN"}},
+	"gcd": {Symbol: "gcd", Arity: 2, Operands: []string{"integer", "integer"},
+		Doc: "Greatest common divisor, fractional operands error or round per the integer policy", Examples: []string{"12 18 gcd"}},
+	"lcm": {Symbol: "lcm", Arity: 2, Operands: []string{"integer", "integer"},
+		Doc: "Least common multiple, fractional operands error or round per the integer policy", Examples: []string{"4 6 lcm"}},
+	"!": {Symbol: "!", Arity: 1, Operands: []string{"integer"},
+		Doc: "Factorial of a non-negative integer", Examples: []string{"5 !"}},
+	"nCr": {Symbol: "nCr", Arity: 2, Operands: []string{"integer", "integer"},
+		Doc: "Number of combinations of r items chosen from n", Examples: []string{"52 5 nCr"}},
+	"nPr": {Symbol: "nPr", Arity: 2, Operands: []string{"integer", "integer"},
+		Doc: "Number of ordered selections of r items from n", Examples: []string{"10 3 nPr"}},
+	"sum": {Symbol: "sum", Arity: VariadicArity, Operands: []string{"number..."},
+		Doc: "Replaces the whole stack with its sum", Examples: []string{"1 2 3 sum"}},
+	"mean": {Symbol: "mean", Arity: VariadicArity, Operands: []string{"number..."},
+		Doc: "Replaces the whole stack with its arithmetic mean", Examples: []string{"1 2 3 mean"}},
+	"stddev": {Symbol: "stddev", Arity: VariadicArity, Operands: []string{"number..."},
+		Doc: "Replaces the whole stack with its sample standard deviation", Examples: []string{"2 4 4 4 5 5 7 9 stddev"}},
+	"median": {Symbol: "median", Arity: VariadicArity, Operands: []string{"number..."},
+		Doc: "Replaces the whole stack with its median", Examples: []string{"3 1 2 median"}},
+	"sumn": {Symbol: "sumn", Arity: VariadicArity, Operands: []string{"number...", "count"},
+		Doc: "Pops a count N and replaces the top N values with their sum", Examples: []string{"10 1 2 2 sumn +"}},
+	"meann": {Symbol: "meann", Arity: VariadicArity, Operands: []string{"number...", "count"},
+		Doc: "Pops a count N and replaces the top N values with their mean", Examples: []string{"10 1 2 2 meann +"}},
+	"stddevn": {Symbol: "stddevn", Arity: VariadicArity, Operands: []string{"number...", "count"},
+		Doc: "Pops a count N and replaces the top N values with their standard deviation", Examples: []string{"10 1 3 2 stddevn +"}},
+	"mediann": {Symbol: "mediann", Arity: VariadicArity, Operands: []string{"number...", "count"},
+		Doc: "Pops a count N and replaces the top N values with their median", Examples: []string{"10 3 1 2 3 mediann +"}},
+	"rand": {Symbol: "rand", Arity: 0,
+		Doc: "Pushes a uniform random value in [0, 1) from the calculator's random source", Examples: []string{"rand"}},
+	"randint": {Symbol: "randint", Arity: 2, Operands: []string{"integer", "integer"},
+		Doc: "Pops lo and hi and pushes a uniform random integer in [lo, hi]", Examples: []string{"1 6 randint"}},
+	"hex": {Symbol: "hex", Arity: 0,
+		Doc: "Displays results in hexadecimal", Examples: []string{"255 hex"}},
+	"bin": {Symbol: "bin", Arity: 0,
+		Doc: "Displays results in binary", Examples: []string{"10 bin"}},
+	"oct": {Symbol: "oct", Arity: 0,
+		Doc: "Displays results in octal", Examples: []string{"493 oct"}},
+	"dec": {Symbol: "dec", Arity: 0,
+		Doc: "Displays results in decimal", Examples: []string{"0xFF dec"}},
+	"eng": {Symbol: "eng", Arity: 0,
+		Doc: "Displays results in engineering notation with SI prefixes like 1.5k or 22u", Examples: []string{"1.5k 2 * eng"}},
+	"as": {Symbol: "as", Arity: 1, Operands: []string{"number"},
+		Doc: "Labels the top value with the name that follows, leaving it on the stack and retrievable after evaluation", Examples: []string{"100 0.2 * as tax 100 +"}},
+}
+
+// Operators returns the metadata of every registered operator sorted by symbol
+func Operators() []OperatorInfo {
+	operators := make([]OperatorInfo, 0, len(operatorRegistry))
+	for _, info := range operatorRegistry {
+		operators = append(operators, info)
+	}
+	sort.Slice(operators, func(i, j int) bool {
+		return operators[i].Symbol < operators[j].Symbol
+	})
+	return operators
+}
+
+// LookupOperator returns the metadata of an operator
+func LookupOperator(symbol string) (OperatorInfo, bool) {
+	info, ok := operatorRegistry[symbol]
+	return info, ok
+}
+
+// Completions returns the operator symbols starting with prefix
+func Completions(prefix string) []string {
+	symbols := []string{}
+	for _, info := range Operators() {
+		if strings.HasPrefix(info.Symbol, prefix) {
+			symbols = append(symbols, info.Symbol)
+		}
+	}
+	return symbols
+}
+
+// Help returns a one paragraph description of an operator
+func Help(symbol string) (string, error) {
+	info, ok := LookupOperator(symbol)
+	if !ok {
+		return "", fmt.Errorf("unknown operator: %s", symbol)
+	}
+
+	arity := fmt.Sprintf("%d", info.Arity)
+	if info.Arity == VariadicArity {
+		arity = "variadic"
+	}
+	help := fmt.Sprintf("%s (arity %s", info.Symbol, arity)
+	if len(info.Operands) > 0 {
+		help += ", operands: " + strings.Join(info.Operands, ", ")
+	}
+	help += ")\n  " + info.Doc
+	for _, example := range info.Examples {
+		help += "\n  Example: " + example
+	}
+	return help, nil
+}
+
+// ReferenceMarkdown renders the operator registry as a Markdown table
+func ReferenceMarkdown() string {
+	var builder strings.Builder
+	builder.WriteString("| Operator | Arity | Operands | Description | Example |\n")
+	builder.WriteString("|---|---|---|---|---|\n")
+	for _, info := range Operators() {
+		arity := fmt.Sprintf("%d", info.Arity)
+		if info.Arity == VariadicArity {
+			arity = "variadic"
+		}
+		fmt.Fprintf(&builder, "| `%s` | %s | %s | %s | `%s` |\n",
+			info.Symbol, arity, strings.Join(info.Operands, ", "), info.Doc, strings.Join(info.Examples, "`, `"))
+	}
+	return builder.String()
+}
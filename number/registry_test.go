@@ -0,0 +1,121 @@
+package number
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestRegistryCoversOperators checks that every operator token the
+// calculator understands is documented with its arity
+func TestRegistryCoversOperators(t *testing.T) {
+	arities := map[string]int{}
+	for token := range binaryOperators {
+		arities[token] = 2
+	}
+	for token := range checkedBinaryOperators {
+		arities[token] = 2
+	}
+	for token := range unaryOperators {
+		arities[token] = 1
+	}
+	for token := range checkedUnaryOperators {
+		arities[token] = 1
+	}
+	for token := range ternaryOperators {
+		arities[token] = 3
+	}
+	for token := range displayBases {
+		arities[token] = 0
+	}
+	for token, operator := range functions {
+		arities[token] = operator.Arity
+	}
+	for token, want := range arities {
+		info, ok := LookupOperator(token)
+		if !ok {
+			t.Errorf("operator %s is not registered", token)
+			continue
+		}
+		if info.Arity != want {
+			t.Errorf("operator %s registered with arity %d, want %d", token, info.Arity, want)
+		}
+		if info.Doc == "" {
+			t.Errorf("operator %s has no documentation", token)
+		}
+	}
+}
+
+// TestRegistryExamplesEvaluate runs the example of every registered operator
+func TestRegistryExamplesEvaluate(t *testing.T) {
+	for _, info := range Operators() {
+		for _, example := range info.Examples {
+			t.Run(example, func(t *testing.T) {
+				calc := NewRPNCalculator()
+				for _, token := range Tokenize(example) {
+					if err := calc.Evaluate(token.Value); err != nil {
+						t.Fatalf("example of %s fails at %s: %v", info.Symbol, token.Value, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestOperatorsSorted(t *testing.T) {
+	symbols := []string{}
+	for _, info := range Operators() {
+		symbols = append(symbols, info.Symbol)
+	}
+	if !slices.IsSorted(symbols) {
+		t.Errorf("Operators() not sorted by symbol: %v", symbols)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"sq", []string{"sqrt"}},
+		{"nC", []string{"nCr"}},
+		{"zzz", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.prefix, func(t *testing.T) {
+			if got := Completions(test.prefix); !slices.Equal(got, test.want) {
+				t.Errorf("Completions(%q) = %q, want %q", test.prefix, got, test.want)
+			}
+		})
+	}
+	for _, symbol := range Completions("s") {
+		if !strings.HasPrefix(symbol, "s") {
+			t.Errorf("Completions(s) includes %s", symbol)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	help, err := Help("+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "+ (arity 2, operands: number, number)\n  Adds the top two values\n  Example: 3 4 +"
+	if help != want {
+		t.Errorf("Help(+) = %q, want %q", help, want)
+	}
+	if _, err := Help("nope"); err == nil || err.Error() != "unknown operator: nope" {
+		t.Errorf("Help(nope) error = %v, want unknown operator", err)
+	}
+}
+
+func TestReferenceMarkdown(t *testing.T) {
+	reference := ReferenceMarkdown()
+	lines := strings.Split(strings.TrimSuffix(reference, "\n"), "\n")
+	if len(lines) != len(Operators())+2 {
+		t.Errorf("ReferenceMarkdown() has %d lines, want a header and one per operator", len(lines))
+	}
+	if !strings.Contains(reference, "| `+` | 2 | number, number | Adds the top two values | `3 4 +` |") {
+		t.Errorf("ReferenceMarkdown() lacks the + row:\n%s", reference)
+	}
+}
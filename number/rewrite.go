@@ -0,0 +1,230 @@
+package number
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRewritePasses bounds how often the rules are applied before giving up
+// on reaching a fixpoint
+const maxRewritePasses = 100
+
+// Inspect traverses an expression tree in depth-first order, calling visit
+// for each node and descending into its children while visit returns true
+func Inspect(expr Expr, visit func(Expr) bool) {
+	if !visit(expr) {
+		return
+	}
+
+	switch node := expr.(type) {
+	case *UnaryExpr:
+		Inspect(node.Operand, visit)
+	case *BinaryExpr:
+		Inspect(node.Left, visit)
+		Inspect(node.Right, visit)
+	case *CallExpr:
+		for _, arg := range node.Args {
+			Inspect(arg, visit)
+		}
+	}
+}
+
+// Transform rebuilds an expression tree bottom-up, replacing every node with
+// the result of calling fn on it after its children have been transformed
+func Transform(expr Expr, fn func(Expr) Expr) Expr {
+	switch node := expr.(type) {
+	case *UnaryExpr:
+		return fn(&UnaryExpr{Op: node.Op, Operand: Transform(node.Operand, fn)})
+	case *BinaryExpr:
+		return fn(&BinaryExpr{Op: node.Op, Left: Transform(node.Left, fn), Right: Transform(node.Right, fn)})
+	case *CallExpr:
+		args := make([]Expr, len(node.Args))
+		for i, arg := range node.Args {
+			args[i] = Transform(arg, fn)
+		}
+		return fn(&CallExpr{Func: node.Func, Args: args})
+	default:
+		return fn(expr)
+	}
+}
+
+// Equal reports whether two expression trees have the same structure
+func Equal(a, b Expr) bool {
+	switch left := a.(type) {
+	case *Literal:
+		right, ok := b.(*Literal)
+		return ok && left.Value == right.Value
+	case *Variable:
+		right, ok := b.(*Variable)
+		return ok && left.Name == right.Name
+	case *UnaryExpr:
+		right, ok := b.(*UnaryExpr)
+		return ok && left.Op == right.Op && Equal(left.Operand, right.Operand)
+	case *BinaryExpr:
+		right, ok := b.(*BinaryExpr)
+		return ok && left.Op == right.Op && Equal(left.Left, right.Left) && Equal(left.Right, right.Right)
+	case *CallExpr:
+		right, ok := b.(*CallExpr)
+		if !ok || left.Func != right.Func || len(left.Args) != len(right.Args) {
+			return false
+		}
+		for i := range left.Args {
+			if !Equal(left.Args[i], right.Args[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// RewriteRule replaces subtrees matching Pattern with Replacement. Variables
+// in the pattern match any subtree, and a variable used twice must match
+// equal subtrees both times
+type RewriteRule struct {
+	Pattern     Expr
+	Replacement Expr
+}
+
+// NewRewriteRule parses a rule from RPN pattern and replacement expressions,
+// such as "x x -" and "0"
+func NewRewriteRule(pattern, replacement string) (RewriteRule, error) {
+	patternExpr, err := ParseRPN(pattern)
+	if err != nil {
+		return RewriteRule{}, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	replacementExpr, err := ParseRPN(replacement)
+	if err != nil {
+		return RewriteRule{}, fmt.Errorf("invalid replacement %q: %w", replacement, err)
+	}
+
+	bound := variableNames(patternExpr)
+	for name := range variableNames(replacementExpr) {
+		if !bound[name] {
+			return RewriteRule{}, fmt.Errorf("replacement variable %s does not appear in pattern %q", name, pattern)
+		}
+	}
+
+	return RewriteRule{Pattern: patternExpr, Replacement: replacementExpr}, nil
+}
+
+// String returns the rule as "pattern => replacement" in RPN
+func (rule RewriteRule) String() string {
+	return strings.Join(rule.Pattern.ToRPN(), " ") + " => " + strings.Join(rule.Replacement.ToRPN(), " ")
+}
+
+// Apply rewrites expr if it matches the rule pattern at its root
+func (rule RewriteRule) Apply(expr Expr) (Expr, bool) {
+	bindings := make(map[string]Expr)
+	if !matchPattern(rule.Pattern, expr, bindings) {
+		return expr, false
+	}
+	return substitute(rule.Replacement, bindings), true
+}
+
+// Rewriter applies a set of user registered rules to expression trees
+type Rewriter struct {
+	rules []RewriteRule
+}
+
+// NewRewriter creates a rewriter without rules
+func NewRewriter() *Rewriter {
+	return &Rewriter{
+		rules: make([]RewriteRule, 0),
+	}
+}
+
+// Add registers a rule given as RPN pattern and replacement expressions
+func (rw *Rewriter) Add(pattern, replacement string) error {
+	rule, err := NewRewriteRule(pattern, replacement)
+	if err != nil {
+		return err
+	}
+	rw.AddRule(rule)
+	return nil
+}
+
+// AddRule registers a parsed rule
+func (rw *Rewriter) AddRule(rule RewriteRule) {
+	rw.rules = append(rw.rules, rule)
+}
+
+// Rules returns the registered rules in order
+func (rw *Rewriter) Rules() []RewriteRule {
+	return append([]RewriteRule(nil), rw.rules...)
+}
+
+// Rewrite applies the rules bottom-up until no rule matches anywhere
+func (rw *Rewriter) Rewrite(expr Expr) (Expr, error) {
+	for pass := 0; pass < maxRewritePasses; pass++ {
+		changed := false
+		expr = Transform(expr, func(node Expr) Expr {
+			for _, rule := range rw.rules {
+				if rewritten, ok := rule.Apply(node); ok {
+					changed = true
+					return rewritten
+				}
+			}
+			return node
+		})
+		if !changed {
+			return expr, nil
+		}
+	}
+	return nil, fmt.Errorf("rewrite did not reach a fixpoint after %d passes", maxRewritePasses)
+}
+
+// matchPattern matches expr against pattern, recording variable bindings
+func matchPattern(pattern, expr Expr, bindings map[string]Expr) bool {
+	switch node := pattern.(type) {
+	case *Variable:
+		if bound, ok := bindings[node.Name]; ok {
+			return Equal(bound, expr)
+		}
+		bindings[node.Name] = expr
+		return true
+	case *UnaryExpr:
+		target, ok := expr.(*UnaryExpr)
+		return ok && node.Op == target.Op && matchPattern(node.Operand, target.Operand, bindings)
+	case *BinaryExpr:
+		target, ok := expr.(*BinaryExpr)
+		return ok && node.Op == target.Op &&
+			matchPattern(node.Left, target.Left, bindings) &&
+			matchPattern(node.Right, target.Right, bindings)
+	case *CallExpr:
+		target, ok := expr.(*CallExpr)
+		if !ok || node.Func != target.Func || len(node.Args) != len(target.Args) {
+			return false
+		}
+		for i := range node.Args {
+			if !matchPattern(node.Args[i], target.Args[i], bindings) {
+				return false
+			}
+		}
+		return true
+	default:
+		return Equal(pattern, expr)
+	}
+}
+
+// substitute builds a copy of template with variables replaced by their bindings
+func substitute(template Expr, bindings map[string]Expr) Expr {
+	return Transform(template, func(node Expr) Expr {
+		if variable, ok := node.(*Variable); ok {
+			return bindings[variable.Name]
+		}
+		return node
+	})
+}
+
+// variableNames returns the set of variable names used in expr
+func variableNames(expr Expr) map[string]bool {
+	names := make(map[string]bool)
+	Inspect(expr, func(node Expr) bool {
+		if variable, ok := node.(*Variable); ok {
+			names[variable.Name] = true
+		}
+		return true
+	})
+	return names
+}
@@ -0,0 +1,56 @@
+package number
+
+import (
+	"fmt"
+	"math"
+)
+
+// RoundingMode selects how round and roundN resolve ties
+type RoundingMode int
+
+const (
+	// RoundHalfAwayFromZero rounds ties away from zero, 2.5 -> 3 and -2.5 -> -3
+	RoundHalfAwayFromZero RoundingMode = iota
+	// RoundHalfEven rounds ties to the nearest even digit, 2.5 -> 2 and 3.5 -> 4
+	RoundHalfEven
+	// RoundHalfUp rounds ties towards positive infinity, 2.5 -> 3 and -2.5 -> -2
+	RoundHalfUp
+)
+
+// roundingFunctions maps rounding modes to their implementations
+var roundingFunctions = map[RoundingMode]func(float64) float64{
+	RoundHalfAwayFromZero: math.Round,
+	RoundHalfEven:         math.RoundToEven,
+	RoundHalfUp:           func(x float64) float64 { return math.Floor(x + 0.5) },
+}
+
+// SetRoundingMode sets how the calculator's round and roundN resolve ties
+func (calc *RPNCalculator) SetRoundingMode(mode RoundingMode) {
+	calc.roundingMode = mode
+}
+
+// roundToPlaces rounds value to places decimal places using round
+func roundToPlaces(value, places float64, round func(float64) float64) (float64, error) {
+	if places != math.Trunc(places) || places < -15 || places > 15 {
+		return 0, fmt.Errorf("roundN requires an integer number of places between -15 and 15, got %g", places)
+	}
+
+	scale := math.Pow(10, places)
+	return round(value*scale) / scale, nil
+}
+
+// roundN rounds to N decimal places with ties away from zero
+func roundN(value, places float64) (float64, error) {
+	return roundToPlaces(value, places, math.Round)
+}
+
+// performRounding applies round or roundN using the calculator's rounding mode
+func (calc *RPNCalculator) performRounding(token string) error {
+	round := roundingFunctions[calc.roundingMode]
+	if token == "round" {
+		return calc.performUnaryOperation(token, round)
+	}
+	return calc.performCheckedBinaryOperation(token, func(value, places float64) (float64, error) {
+		return roundToPlaces(value, places, round)
+	})
+}
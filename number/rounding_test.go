@@ -0,0 +1,99 @@
+package number
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundN(t *testing.T) {
+	tests := []struct {
+		expression string
+		mode       RoundingMode
+		want       float64
+	}{
+		{"3.14159 2 roundN", RoundHalfAwayFromZero, 3.14},
+		{"1234.5 -2 roundN", RoundHalfAwayFromZero, 1200},
+		{"2.5 0 roundN", RoundHalfAwayFromZero, 3},
+		{"2.5 0 roundN", RoundHalfEven, 2},
+		{"-2.5 0 roundN", RoundHalfUp, -2},
+		{"2.5 round", RoundHalfEven, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			calc.SetRoundingMode(test.mode)
+			got, err := calc.EvaluateExpression(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("EvaluateExpression(%q) = %g, want %g", test.expression, got, test.want)
+			}
+		})
+	}
+
+	for _, expression := range []string{"1 1.5 roundN", "1 16 roundN"} {
+		if _, err := NewRPNCalculator().EvaluateExpression(expression); err == nil || !strings.Contains(err.Error(), "roundN requires an integer number of places") {
+			t.Errorf("EvaluateExpression(%q) error = %v, want a places error", expression, err)
+		}
+	}
+}
+
+func TestRoundNExpressionTree(t *testing.T) {
+	expr, err := ParseRPN("x 2 roundN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, ok := expr.(*CallExpr)
+	if !ok || call.Func != "roundN" || len(call.Args) != 2 {
+		t.Fatalf("ParseRPN(x 2 roundN) = %#v, want a roundN call", expr)
+	}
+	if got := formatInfix(expr); got != "roundN(x, 2)" {
+		t.Errorf("infix = %q, want %q", got, "roundN(x, 2)")
+	}
+	if got := ToLaTeX(expr); got != `\operatorname{roundN}\left(x, 2\right)` {
+		t.Errorf("ToLaTeX() = %q", got)
+	}
+
+	constant, err := ParseRPN("3.14159 2 roundN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := constant.Eval(); err != nil || value != 3.14 {
+		t.Errorf("Eval() = %g, %v, want 3.14", value, err)
+	}
+	invalid, err := ParseRPN("3.14159 0.5 roundN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := invalid.Eval(); err == nil {
+		t.Error("Eval() of a fractional number of places succeeded")
+	}
+}
+
+func TestGenerateGoRoundN(t *testing.T) {
+	expr, err := ParseRPN("price rate * 2 roundN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := GenerateGo(expr, GoOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeCheckGo(t, source)
+	if !strings.Contains(source, "rpnRoundN := func") || !strings.Contains(source, "return rpnRoundN(price*rate, 2)") {
+		t.Errorf("generated Go does not call rpnRoundN:\n%s", source)
+	}
+
+	folded, err := ParseRPN("3.14159 2 roundN x +")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err = GenerateGo(folded, GoOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(source, "rpnRoundN") {
+		t.Errorf("generated Go does not fold the constant roundN:\n%s", source)
+	}
+}
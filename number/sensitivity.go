@@ -0,0 +1,84 @@
+package number
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+)
+
+// VariableSensitivity is how a program's result responds to changing one
+// variable
+type VariableSensitivity struct {
+	Name string
+	// Input is the base value of the variable and Delta the change applied
+	// to it
+	Input float64
+	Delta float64
+	// Result is the program's result with the changed variable and Change
+	// its difference from the base result
+	Result float64
+	Change float64
+	// Elasticity is the relative change of the result over the relative
+	// change of the variable, NaN when the base result or input is zero
+	Elasticity float64
+}
+
+// SensitivityReport is the outcome of a sensitivity analysis
+type SensitivityReport struct {
+	// Base is the result with the base variables
+	Base float64
+	// Variables holds the changed variables, those moving the result the
+	// most first
+	Variables []VariableSensitivity
+}
+
+// Sensitivity evaluates a program with the base variables and again with
+// each variable in deltas changed by its delta, the others kept at their
+// base values, so analysts can see which inputs drive the result
+func Sensitivity(prog *Program, base map[string]float64, deltas map[string]float64) (SensitivityReport, error) {
+	result, err := prog.EvalWith(base)
+	if err != nil {
+		return SensitivityReport{}, err
+	}
+	report := SensitivityReport{Base: result, Variables: make([]VariableSensitivity, 0, len(deltas))}
+
+	variables := prog.Variables()
+	vars := maps.Clone(base)
+	for _, name := range slices.Sorted(maps.Keys(deltas)) {
+		delta := deltas[name]
+		if !slices.Contains(variables, name) {
+			return SensitivityReport{}, fmt.Errorf("program does not read variable %s", name)
+		}
+		if delta == 0 || math.IsNaN(delta) || math.IsInf(delta, 0) {
+			return SensitivityReport{}, fmt.Errorf("invalid delta for %s: %g", name, delta)
+		}
+
+		input := base[name]
+		vars[name] = input + delta
+		changed, err := prog.EvalWith(vars)
+		vars[name] = input
+		if err != nil {
+			return SensitivityReport{}, fmt.Errorf("%s changed by %g: %w", name, delta, err)
+		}
+
+		elasticity := math.NaN()
+		if result != 0 && input != 0 {
+			elasticity = ((changed - result) / result) / (delta / input)
+		}
+		report.Variables = append(report.Variables, VariableSensitivity{
+			Name:       name,
+			Input:      input,
+			Delta:      delta,
+			Result:     changed,
+			Change:     changed - result,
+			Elasticity: elasticity,
+		})
+	}
+
+	sort.SliceStable(report.Variables, func(i, j int) bool {
+		return math.Abs(report.Variables[i].Change) > math.Abs(report.Variables[j].Change)
+	})
+	return report, nil
+}
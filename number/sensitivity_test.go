@@ -0,0 +1,106 @@
+package number
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSensitivity(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		base       map[string]float64
+		deltas     map[string]float64
+		wantBase   float64
+		want       []VariableSensitivity
+		wantErr    string
+	}{
+		{
+			"ranked by change",
+			"p q * 50 -",
+			map[string]float64{"p": 10, "q": 100},
+			map[string]float64{"p": 1, "q": 20},
+			950,
+			[]VariableSensitivity{
+				{Name: "q", Input: 100, Delta: 20, Result: 1150, Change: 200, Elasticity: (200.0 / 950) / 0.2},
+				{Name: "p", Input: 10, Delta: 1, Result: 1050, Change: 100, Elasticity: (100.0 / 950) / 0.1},
+			},
+			"",
+		},
+		{
+			"ties keep name order",
+			"p q *",
+			map[string]float64{"p": 10, "q": 100},
+			map[string]float64{"q": 10, "p": 1},
+			1000,
+			[]VariableSensitivity{
+				{Name: "p", Input: 10, Delta: 1, Result: 1100, Change: 100, Elasticity: 1},
+				{Name: "q", Input: 100, Delta: 10, Result: 1100, Change: 100, Elasticity: 1},
+			},
+			"",
+		},
+		{
+			"unchanged variables",
+			"p q * r +",
+			map[string]float64{"p": 2, "q": 3, "r": 4},
+			map[string]float64{"r": -4},
+			10,
+			[]VariableSensitivity{{Name: "r", Input: 4, Delta: -4, Result: 6, Change: -4, Elasticity: 0.4}},
+			"",
+		},
+		{
+			"zero input",
+			"x 5 +",
+			map[string]float64{"x": 0},
+			map[string]float64{"x": 1},
+			5,
+			[]VariableSensitivity{{Name: "x", Input: 0, Delta: 1, Result: 6, Change: 1, Elasticity: math.NaN()}},
+			"",
+		},
+		{
+			"zero result",
+			"x 2 -",
+			map[string]float64{"x": 2},
+			map[string]float64{"x": 1},
+			0,
+			[]VariableSensitivity{{Name: "x", Input: 2, Delta: 1, Result: 1, Change: 1, Elasticity: math.NaN()}},
+			"",
+		},
+		{"no deltas", "x 2 *", map[string]float64{"x": 2}, nil, 4, []VariableSensitivity{}, ""},
+		{"unknown variable", "x 2 *", map[string]float64{"x": 2, "y": 1}, map[string]float64{"y": 1}, 0, nil, "program does not read variable y"},
+		{"zero delta", "x 2 *", map[string]float64{"x": 2}, map[string]float64{"x": 0}, 0, nil, "invalid delta for x: 0"},
+		{"nan delta", "x 2 *", map[string]float64{"x": 2}, map[string]float64{"x": math.NaN()}, 0, nil, "invalid delta for x: NaN"},
+		{"infinite delta", "x 2 *", map[string]float64{"x": 2}, map[string]float64{"x": math.Inf(1)}, 0, nil, "invalid delta for x: +Inf"},
+		{"unbound base", "x y *", map[string]float64{"x": 2}, map[string]float64{"x": 1}, 0, nil, "undefined variable: y"},
+		{"failing change", "n !", map[string]float64{"n": 3}, map[string]float64{"n": 0.5}, 0, nil, "n changed by 0.5: ! requires a non-negative integer, got 3.5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := Sensitivity(prog, test.base, test.deltas)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("Sensitivity() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Base != test.wantBase || len(report.Variables) != len(test.want) {
+				t.Fatalf("Sensitivity() = %+v, want base %g and %+v", report, test.wantBase, test.want)
+			}
+			for i, got := range report.Variables {
+				want := test.want[i]
+				elasticity := got.Elasticity
+				got.Elasticity, want.Elasticity = 0, 0
+				if got != want || !sameFloat(elasticity, test.want[i].Elasticity) {
+					t.Errorf("variable %d = %+v (elasticity %g), want %+v", i, got, elasticity, test.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package number
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// sessionVersion is the version of the JSON session format
+const sessionVersion = 1
+
+// session is the JSON form of a calculator. Its stack shadows the one of
+// the embedded state so values JSON numbers cannot hold, like the +Inf of
+// 1 0 /, survive the round trip
+type session struct {
+	Version int            `json:"version"`
+	Stack   []sessionValue `json:"stack"`
+	State
+}
+
+// sessionValue is a stack value encoded as a JSON number, or as one of the
+// strings "NaN", "+Inf" and "-Inf"
+type sessionValue float64
+
+// MarshalJSON encodes finite values as numbers and the others as strings
+func (value sessionValue) MarshalJSON() ([]byte, error) {
+	number := float64(value)
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return json.Marshal(strconv.FormatFloat(number, 'g', -1, 64))
+	}
+	return json.Marshal(number)
+}
+
+// UnmarshalJSON decodes a number or one of the non-finite strings
+func (value *sessionValue) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid stack value %q", text)
+		}
+		*value = sessionValue(number)
+		return nil
+	}
+
+	var number float64
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*value = sessionValue(number)
+	return nil
+}
+
+// MarshalJSON encodes the calculator's stack and settings so a front-end
+// can persist the session and resume it later
+func (calc *RPNCalculator) MarshalJSON() ([]byte, error) {
+	state := calc.Snapshot()
+	stored := session{Version: sessionVersion, Stack: make([]sessionValue, len(state.Stack)), State: state}
+	for i, value := range state.Stack {
+		stored.Stack[i] = sessionValue(value)
+	}
+	return json.Marshal(stored)
+}
+
+// UnmarshalJSON restores a session written by MarshalJSON
+func (calc *RPNCalculator) UnmarshalJSON(data []byte) error {
+	var stored session
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	if stored.Version != sessionVersion {
+		return fmt.Errorf("unsupported session version %d", stored.Version)
+	}
+
+	stored.State.Stack = make([]float64, len(stored.Stack))
+	for i, value := range stored.Stack {
+		stored.State.Stack[i] = float64(value)
+	}
+	calc.Restore(stored.State)
+	return nil
+}
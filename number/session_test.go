@@ -0,0 +1,66 @@
+package number
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSessionJSON(t *testing.T) {
+	calc := NewRPNCalculator(WithRoundingMode(RoundHalfEven), WithLocale(LocaleGerman))
+	for _, token := range []string{"1", "0", "/", "-1", "0", "/", "0", "0", "/", "2,5", "oct"} {
+		if err := calc.Evaluate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := json.Marshal(calc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":1,"stack":["+Inf","-Inf","NaN",2.5],"display_base":8,"percent_mode":0,"rounding_mode":1,"angle_mode":0,"locale":{"decimal":",","group":"."},"integer_policy":0,"limits":{}}`
+	if string(data) != want {
+		t.Fatalf("Marshal() = %s, want %s", data, want)
+	}
+
+	restored := NewRPNCalculator()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	got, saved := restored.Snapshot(), calc.Snapshot()
+	if !math.IsInf(got.Stack[0], 1) || !math.IsInf(got.Stack[1], -1) || !math.IsNaN(got.Stack[2]) || got.Stack[3] != 2.5 {
+		t.Errorf("restored stack = %v, want %v", got.Stack, saved.Stack)
+	}
+	got.Stack, saved.Stack = nil, nil
+	saved.Labels = nil
+	if !reflect.DeepEqual(got, saved) {
+		t.Errorf("restored state = %+v, want %+v", got, saved)
+	}
+}
+
+func TestSessionJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unsupported version", `{"version":2,"stack":[]}`, "unsupported session version 2"},
+		{"missing version", `{"stack":[1]}`, "unsupported session version 0"},
+		{"invalid value", `{"version":1,"stack":["many"]}`, `invalid stack value "many"`},
+		{"wrong type", `{"version":1,"stack":[true]}`, "json: cannot unmarshal bool into Go value of type float64"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			calc.Push(7)
+			err := json.Unmarshal([]byte(test.data), calc)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("Unmarshal() error = %v, want %q", err, test.want)
+			}
+			// A session that fails to load leaves the calculator as it was
+			if got := calc.Values(); !reflect.DeepEqual(got, []float64{7}) {
+				t.Errorf("stack after a failed load = %v, want [7]", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package number
+
+import "maps"
+
+// State is a copy of a calculator's stack and mode settings, taken with
+// Snapshot and applied with Restore to implement save points and "what-if"
+// evaluation. The random source and the formatter are not part of the
+// state, and the labels are not saved with a session
+type State struct {
+	Stack         []float64          `json:"stack"`
+	DisplayBase   int                `json:"display_base"`
+	PercentMode   PercentMode        `json:"percent_mode"`
+	RoundingMode  RoundingMode       `json:"rounding_mode"`
+	AngleMode     AngleMode          `json:"angle_mode"`
+	Locale        Locale             `json:"locale"`
+	IntegerPolicy IntegerPolicy      `json:"integer_policy"`
+	Limits        Limits             `json:"limits"`
+	Labels        map[string]float64 `json:"-"`
+}
+
+// Snapshot returns a copy of the calculator's stack and modes that later
+// evaluation does not affect
+func (calc *RPNCalculator) Snapshot() State {
+	return State{
+		Stack:         calc.Values(),
+		DisplayBase:   calc.displayBase,
+		PercentMode:   calc.percentMode,
+		RoundingMode:  calc.roundingMode,
+		AngleMode:     calc.angleMode,
+		Locale:        calc.locale,
+		IntegerPolicy: calc.integerPolicy,
+		Limits:        calc.limits,
+		Labels:        maps.Clone(calc.labels),
+	}
+}
+
+// Restore replaces the calculator's stack and modes with a snapshot. The
+// snapshot stays usable, so it can be restored again
+func (calc *RPNCalculator) Restore(state State) {
+	calc.SetValues(state.Stack)
+	calc.displayBase = state.DisplayBase
+	if calc.displayBase == 0 {
+		calc.displayBase = 10
+	}
+	calc.percentMode = state.PercentMode
+	calc.roundingMode = state.RoundingMode
+	calc.angleMode = state.AngleMode
+	calc.locale = state.Locale
+	calc.integerPolicy = state.IntegerPolicy
+	calc.limits = state.Limits
+	calc.labels = maps.Clone(state.Labels)
+	calc.labelNext = false
+}
@@ -0,0 +1,73 @@
+package number
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	calc := NewRPNCalculator(
+		WithPercentMode(PercentKeepBase),
+		WithRoundingMode(RoundHalfEven),
+		WithAngleMode(Degrees),
+		WithLocale(LocaleGerman),
+		WithIntegerPolicy(IntegerRound),
+		WithMaxStackDepth(8),
+	)
+	for _, token := range []string{"hex", "255", "as", "mask", "2"} {
+		if err := calc.Evaluate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := calc.Snapshot()
+	want := State{
+		Stack:         []float64{255, 2},
+		DisplayBase:   16,
+		PercentMode:   PercentKeepBase,
+		RoundingMode:  RoundHalfEven,
+		AngleMode:     Degrees,
+		Locale:        LocaleGerman,
+		IntegerPolicy: IntegerRound,
+		Limits:        Limits{MaxStackDepth: 8},
+		Labels:        map[string]float64{"mask": 255},
+	}
+	if !reflect.DeepEqual(saved, want) {
+		t.Fatalf("Snapshot() = %+v, want %+v", saved, want)
+	}
+
+	// Speculative evaluation changes neither the snapshot nor, once
+	// restored, the calculator
+	for round := 0; round < 2; round++ {
+		for _, token := range []string{"+", "dec", "as", "mask", "3"} {
+			if err := calc.Evaluate(token); err != nil {
+				t.Fatal(err)
+			}
+		}
+		calc.SetAngleMode(Radians)
+		calc.SetLimits(Limits{})
+		if !reflect.DeepEqual(saved, want) {
+			t.Fatalf("snapshot changed by evaluation: %+v", saved)
+		}
+
+		calc.Restore(saved)
+		if got := calc.Snapshot(); !reflect.DeepEqual(got, want) {
+			t.Errorf("round %d: Snapshot() after Restore = %+v, want %+v", round, got, want)
+		}
+		if got := calc.Format(255); got != "0xFF" {
+			t.Errorf("round %d: Format(255) = %q, want the restored hex display", round, got)
+		}
+	}
+}
+
+func TestRestoreZeroState(t *testing.T) {
+	calc := NewRPNCalculator(WithAngleMode(Degrees))
+	for _, token := range []string{"1", "2", "hex"} {
+		if err := calc.Evaluate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	calc.Restore(State{})
+	if got, want := calc.Snapshot(), NewRPNCalculator().Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() after restoring the zero State = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// reducers maps statistical tokens to functions collapsing values to a scalar.
+// On the calculator stack the plain token consumes the whole stack and the
+// "n" suffixed variant (sumn, meann, ...) pops a count and consumes that many.
+// Plain min and max are binary operators, so only minn and maxn reduce
+var reducers = map[string]func([]float64) float64{
+	"sum":    sumValues,
+	"mean":   meanValues,
+	"stddev": stddevValues,
+	"median": medianValues,
+	"min":    minValues,
+	"max":    maxValues,
+}
+
+// minReducerOperands is the number of values each reducer needs
+var minReducerOperands = map[string]int{
+	"sum":    1,
+	"mean":   1,
+	"stddev": 2,
+	"median": 1,
+	"min":    1,
+	"max":    1,
+}
+
+// lookupReducer resolves a reducer token, reporting whether it takes a count
+func lookupReducer(token string) (string, bool, bool) {
+	if _, ok := reducers[token]; ok {
+		return token, false, true
+	}
+	name := strings.TrimSuffix(token, "n")
+	if _, ok := reducers[name]; ok && name != token {
+		return name, true, true
+	}
+	return "", false, false
+}
+
+// performReduction replaces the whole stack, or the top N elements when
+// counted is set, with the result of a reducer
+func (calc *RPNCalculator) performReduction(name string, counted bool) error {
+	token, count, available := name, calc.Size(), calc.Size()
+	if counted {
+		// The count is only popped once the reduction is known to succeed,
+		// so a failing one leaves the stack as it was
+		token = name + "n"
+		n, err := calc.Peek()
+		if err != nil {
+			return engine.Underflow(token)
+		}
+		if n != math.Trunc(n) || n < 0 {
+			return fmt.Errorf("invalid count for %s: %g", token, n)
+		}
+		count, available = int(n), calc.Size()-1
+	}
+
+	if count < minReducerOperands[name] || count > available {
+		return engine.Underflow(token)
+	}
+
+	if counted {
+		calc.Pop()
+	}
+	start := calc.Size() - count
+	values := calc.Values()[start:]
+	calc.Truncate(start)
+	calc.Push(reducers[name](values))
+	return nil
+}
+
+// sumValues adds up all values
+func sumValues(values []float64) float64 {
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
+// meanValues returns the arithmetic mean of values
+func meanValues(values []float64) float64 {
+	return sumValues(values) / float64(len(values))
+}
+
+// stddevValues returns the sample standard deviation of values
+func stddevValues(values []float64) float64 {
+	mean := meanValues(values)
+	squares := 0.0
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(squares / float64(len(values)-1))
+}
+
+// minValues returns the smallest value
+func minValues(values []float64) float64 {
+	result := values[0]
+	for _, value := range values[1:] {
+		result = math.Min(result, value)
+	}
+	return result
+}
+
+// maxValues returns the largest value
+func maxValues(values []float64) float64 {
+	result := values[0]
+	for _, value := range values[1:] {
+		result = math.Max(result, value)
+	}
+	return result
+}
+
+// medianValues returns the middle value of values
+func medianValues(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,120 @@
+package number
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStackReducers(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []float64
+	}{
+		{"1 2 3 4 sum", []float64{10}},
+		{"1 2 3 4 mean", []float64{2.5}},
+		{"2 4 4 4 5 5 7 9 stddev", []float64{math.Sqrt(32.0 / 7)}},
+		{"3 1 2 median", []float64{2}},
+		{"4 1 3 2 median", []float64{2.5}},
+		{"10 1 2 2 sumn", []float64{10, 3}},
+		{"10 1 2 3 3 meann", []float64{10, 2}},
+		{"10 5 1 3 3 mediann", []float64{10, 3}},
+		{"9 1 5 2 maxn", []float64{9, 5}},
+		{"9 1 5 2 minn", []float64{9, 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			for _, token := range strings.Fields(test.expression) {
+				if err := calc.Evaluate(token); err != nil {
+					t.Fatalf("Evaluate(%q): %v", token, err)
+				}
+			}
+			if got := calc.Values(); !slices.Equal(got, test.want) {
+				t.Errorf("stack after %q = %v, want %v", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestStackReducerErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"sum", "insufficient operands for sum operation (column 1)"},
+		{"5 stddev", "insufficient operands for stddev operation (column 3)"},
+		{"1 2 3 stddevn", "insufficient operands for stddevn operation (column 7)"},
+		{"1 2 -1 meann", "invalid count for meann: -1 (column 8)"},
+		{"7 1 2 0 sumn", "insufficient operands for sumn operation (column 9)"},
+		{"1 2 0.5 sumn", "invalid count for sumn: 0.5 (column 9)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewRPNCalculator().EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"3 7 min", 3},
+		{"3 7 max", 7},
+		{"-2 -5 min", -5},
+		{"120 0 max 100 min", 100},
+		{"-3 0 max 100 min", 0},
+		{"4 8 1 9 4 minn", 1},
+		{"4 8 1 9 4 maxn", 9},
+		{"5 1 maxn", 5},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			if got, err := NewRPNCalculator().EvaluateExpression(test.expression); err != nil || got != test.want {
+				t.Errorf("EvaluateExpression(%q) = %g, %v, want %g", test.expression, got, err, test.want)
+			}
+		})
+	}
+}
+
+// TestBinaryMinMaxAcrossForms evaluates binary min and max compiled, as an
+// expression tree and parsed from infix calls
+func TestBinaryMinMaxAcrossForms(t *testing.T) {
+	tests := []struct {
+		rpn   string
+		infix string
+		want  float64
+	}{
+		{"3 7 min", "min(3, 7)", 3},
+		{"3 7 max 2 *", "max(3, 7) * 2", 14},
+		{"120 0 max 100 min", "min(max(120, 0), 100)", 100},
+	}
+	for _, test := range tests {
+		t.Run(test.rpn, func(t *testing.T) {
+			prog, err := Compile(test.rpn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := prog.Eval(); err != nil || got != test.want {
+				t.Errorf("Program.Eval() = %g, %v, want %g", got, err, test.want)
+			}
+
+			expr, err := ParseInfix(test.infix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(expr.ToRPN(), " "); got != test.rpn {
+				t.Errorf("ParseInfix(%q) = %q, want %q", test.infix, got, test.rpn)
+			}
+			if got, err := expr.Eval(); err != nil || got != test.want {
+				t.Errorf("Expr.Eval() = %g, %v, want %g", got, err, test.want)
+			}
+		})
+	}
+}
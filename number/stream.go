@@ -0,0 +1,71 @@
+package number
+
+import (
+	"bufio"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StackState is the calculator stack after one token of a stream was evaluated
+type StackState struct {
+	Token Token
+	Stack []float64
+}
+
+// EvaluateReader evaluates whitespace separated tokens as they arrive from
+// r, such as a pipe or network stream, without reading the whole expression
+// first. emit, if not nil, receives the stack after every token. The stack
+// is not cleared beforehand and is left as the stream ends, so callers can
+// Peek the result or keep evaluating. Token positions and error columns
+// count from the start of the stream
+func (calc *RPNCalculator) EvaluateReader(r io.Reader, emit func(StackState)) error {
+	scanner := bufio.NewScanner(r)
+	offset, column := 0, 0
+	start, startColumn := 0, 0
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		// Skip leading whitespace, then take everything up to the next space
+		skipped := 0
+		for skipped < len(data) {
+			char, size := utf8.DecodeRune(data[skipped:])
+			if !unicode.IsSpace(char) {
+				break
+			}
+			skipped += size
+			column++
+		}
+		for end := skipped; end < len(data); {
+			char, size := utf8.DecodeRune(data[end:])
+			if unicode.IsSpace(char) {
+				start, startColumn = offset+skipped, column
+				offset += end
+				column += utf8.RuneCount(data[skipped:end])
+				return end, data[skipped:end], nil
+			}
+			end += size
+		}
+		if atEOF && skipped < len(data) {
+			start, startColumn = offset+skipped, column
+			offset += len(data)
+			column += utf8.RuneCount(data[skipped:])
+			return len(data), data[skipped:], nil
+		}
+		// Keep the skipped whitespace counted but request more data
+		offset += skipped
+		return skipped, nil, nil
+	})
+
+	for count := 1; scanner.Scan(); count++ {
+		token := newToken(scanner.Text(), start)
+		if err := calc.limits.checkTokens(count); err != nil {
+			return err
+		}
+		if err := calc.Evaluate(token.Value); err != nil {
+			return &PositionError{Token: token, Column: startColumn + 1, Err: err}
+		}
+		if emit != nil {
+			emit(StackState{Token: token, Stack: calc.Values()})
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,108 @@
+package number
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEvaluateReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		tokens []Token
+		stacks [][]float64
+	}{
+		{
+			name:   "single line",
+			input:  "3 4 +",
+			tokens: []Token{{"3", TokenNumber, 0}, {"4", TokenNumber, 2}, {"+", TokenOperator, 4}},
+			stacks: [][]float64{{3}, {3, 4}, {7}},
+		},
+		{
+			name:   "across lines and extra whitespace",
+			input:  "  2\n\t5 *\n\n1 -\n",
+			tokens: []Token{{"2", TokenNumber, 2}, {"5", TokenNumber, 5}, {"*", TokenOperator, 7}, {"1", TokenNumber, 10}, {"-", TokenOperator, 12}},
+			stacks: [][]float64{{2}, {2, 5}, {10}, {10, 1}, {9}},
+		},
+		{
+			name:   "multibyte separators",
+			input:  "1\u00a02 +",
+			tokens: []Token{{"1", TokenNumber, 0}, {"2", TokenNumber, 3}, {"+", TokenOperator, 5}},
+			stacks: [][]float64{{1}, {1, 2}, {3}},
+		},
+		{
+			name:  "empty",
+			input: " \n ",
+		},
+	}
+	for _, test := range tests {
+		for _, oneByte := range []bool{false, true} {
+			name := test.name
+			if oneByte {
+				name += " one byte at a time"
+			}
+			t.Run(name, func(t *testing.T) {
+				var reader io.Reader = strings.NewReader(test.input)
+				if oneByte {
+					// Tokens then arrive split across reads
+					reader = iotest.OneByteReader(reader)
+				}
+				var tokens []Token
+				var stacks [][]float64
+				err := NewRPNCalculator().EvaluateReader(reader, func(state StackState) {
+					tokens = append(tokens, state.Token)
+					stacks = append(stacks, state.Stack)
+				})
+				if err != nil {
+					t.Fatalf("EvaluateReader() error = %v", err)
+				}
+				if !reflect.DeepEqual(tokens, test.tokens) {
+					t.Errorf("tokens = %v, want %v", tokens, test.tokens)
+				}
+				if !reflect.DeepEqual(stacks, test.stacks) {
+					t.Errorf("stacks = %v, want %v", stacks, test.stacks)
+				}
+			})
+		}
+	}
+}
+
+func TestEvaluateReaderKeepsStack(t *testing.T) {
+	calc := NewRPNCalculator()
+	if err := calc.EvaluateReader(strings.NewReader("2 3"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := calc.EvaluateReader(strings.NewReader("*"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := calc.Peek(); err != nil || got != 6 {
+		t.Errorf("Peek() = %g, %v, want 6", got, err)
+	}
+}
+
+func TestEvaluateReaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		limits Limits
+		want   string
+	}{
+		{"unknown token", "1 2\n  bogus +", Limits{}, "unknown token: bogus (column 7)"},
+		{"column counts characters", "1\u00a0bogus", Limits{}, "unknown token: bogus (column 3)"},
+		{"underflow", "1\n+", Limits{}, "insufficient operands for + operation (column 3)"},
+		{"token limit", "1 2 + 3 +", Limits{MaxTokens: 4}, "token count limit of 4 exceeded: 5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewRPNCalculator()
+			calc.SetLimits(test.limits)
+			err := calc.EvaluateReader(iotest.OneByteReader(strings.NewReader(test.input)), nil)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateReader() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package number
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// TokenKind classifies a token of an expression
+type TokenKind int
+
+const (
+	// TokenNumber is a numeric literal
+	TokenNumber TokenKind = iota
+	// TokenOperator is an operator or function known to the calculator
+	TokenOperator
+	// TokenIdentifier is a variable name
+	TokenIdentifier
+	// TokenParen is an opening or closing parenthesis of an infix expression
+	TokenParen
+	// TokenSeparator is the comma between the arguments of a function call
+	// in an infix expression
+	TokenSeparator
+	// TokenUnknown is anything else
+	TokenUnknown
+)
+
+// String returns the name of the token kind
+func (kind TokenKind) String() string {
+	switch kind {
+	case TokenNumber:
+		return "number"
+	case TokenOperator:
+		return "operator"
+	case TokenIdentifier:
+		return "identifier"
+	case TokenParen:
+		return "paren"
+	case TokenSeparator:
+		return "separator"
+	}
+	return "unknown"
+}
+
+// Token is a single token of an expression with the byte offset it starts
+// at in the source. Pos is -1 for tokens that did not come from source text
+type Token struct {
+	Value string
+	Kind  TokenKind
+	Pos   int
+}
+
+// PositionError reports an error caused by a token of an expression along
+// with where that token is, so editors and REPLs can underline it
+type PositionError struct {
+	Token Token
+	// Column is the 1-based character column of the token in the source
+	Column int
+	Err    error
+}
+
+// Error returns the underlying error with the token's column
+func (err *PositionError) Error() string {
+	return fmt.Sprintf("%v (column %d)", err.Err, err.Column)
+}
+
+// Unwrap returns the underlying error
+func (err *PositionError) Unwrap() error {
+	return err.Err
+}
+
+// Tokenize splits an RPN expression at whitespace, recording the offset and
+// kind of every token
+func Tokenize(expression string) []Token {
+	return appendTokens([]Token{}, expression)
+}
+
+// appendTokens appends the tokens of an RPN expression to tokens, so a
+// buffer can be reused across expressions
+func appendTokens(tokens []Token, expression string) []Token {
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, newToken(expression[start:end], start))
+			start = -1
+		}
+	}
+
+	for pos, char := range expression {
+		switch {
+		case unicode.IsSpace(char):
+			flush(pos)
+		case start < 0:
+			start = pos
+		}
+	}
+	flush(len(expression))
+	return tokens
+}
+
+// newToken classifies a token value found at pos
+func newToken(value string, pos int) Token {
+	kind := TokenUnknown
+	if _, ok := operatorRegistry[value]; ok {
+		kind = TokenOperator
+	} else if _, err := parseNumber(value); err == nil {
+		kind = TokenNumber
+	} else if isIdentifier(value) {
+		kind = TokenIdentifier
+	}
+	return Token{Value: value, Kind: kind, Pos: pos}
+}
+
+// tokenValues converts plain token values into tokens without positions
+func tokenValues(values []string) []Token {
+	tokens := make([]Token, len(values))
+	for i, value := range values {
+		tokens[i] = newToken(value, -1)
+	}
+	return tokens
+}
+
+// positionError attaches the position of token in source to err, leaving
+// errors of tokens without a position unchanged. An ErrUnknownToken raised
+// without a position gets the one of token
+func positionError(source string, token Token, err error) error {
+	if token.Pos < 0 || token.Pos > len(source) {
+		return err
+	}
+	var unknown *engine.ErrUnknownToken
+	if errors.As(err, &unknown) && unknown.Pos < 0 {
+		unknown.Pos = token.Pos
+	}
+	return &PositionError{Token: token, Column: utf8.RuneCountInString(source[:token.Pos]) + 1, Err: err}
+}
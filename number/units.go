@@ -0,0 +1,335 @@
+package number
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// dimension holds the exponents of the SI base quantities in the order of
+// baseUnits
+type dimension [7]int
+
+// baseUnits names the SI base unit of each dimension exponent
+var baseUnits = [7]string{"kg", "m", "s", "A", "K", "mol", "cd"}
+
+var (
+	mass        = dimension{1, 0, 0, 0, 0, 0, 0}
+	length      = dimension{0, 1, 0, 0, 0, 0, 0}
+	duration    = dimension{0, 0, 1, 0, 0, 0, 0}
+	current     = dimension{0, 0, 0, 1, 0, 0, 0}
+	temperature = dimension{0, 0, 0, 0, 1, 0, 0}
+	substance   = dimension{0, 0, 0, 0, 0, 1, 0}
+	luminosity  = dimension{0, 0, 0, 0, 0, 0, 1}
+)
+
+// unitDef is a named unit as a multiple of its SI base units
+type unitDef struct {
+	factor float64
+	dims   dimension
+}
+
+// units lists the unit symbols that may be combined in unit expressions. A
+// bare min token is the minimum operator, so minutes are written in
+// literals like 5min or compound units like km/min
+var units = map[string]unitDef{
+	"m":   {1, length},
+	"km":  {1000, length},
+	"cm":  {0.01, length},
+	"mm":  {0.001, length},
+	"in":  {0.0254, length},
+	"ft":  {0.3048, length},
+	"mi":  {1609.344, length},
+	"g":   {0.001, mass},
+	"kg":  {1, mass},
+	"t":   {1000, mass},
+	"lb":  {0.45359237, mass},
+	"s":   {1, duration},
+	"ms":  {0.001, duration},
+	"min": {60, duration},
+	"h":   {3600, duration},
+	"d":   {86400, duration},
+	"A":   {1, current},
+	"K":   {1, temperature},
+	"mol": {1, substance},
+	"cd":  {1, luminosity},
+	"L":   {0.001, dimension{0, 3, 0, 0, 0, 0, 0}},
+	"Hz":  {1, dimension{0, 0, -1, 0, 0, 0, 0}},
+	"N":   {1, dimension{1, 1, -2, 0, 0, 0, 0}},
+	"Pa":  {1, dimension{1, -1, -2, 0, 0, 0, 0}},
+	"J":   {1, dimension{1, 2, -2, 0, 0, 0, 0}},
+	"W":   {1, dimension{1, 2, -3, 0, 0, 0, 0}},
+	"V":   {1, dimension{1, 2, -3, -1, 0, 0, 0}},
+}
+
+// Quantity is a value with a physical dimension. Value is kept in SI base
+// units, Unit is the unit expression the value is displayed in, empty for
+// base units
+type Quantity struct {
+	Value float64
+	Dims  dimension
+	Unit  string
+}
+
+// Dimensionless reports whether the quantity is a plain number
+func (q Quantity) Dimensionless() bool {
+	return q.Dims == dimension{}
+}
+
+// String formats the quantity in its display unit
+func (q Quantity) String() string {
+	if q.Dimensionless() {
+		return strconv.FormatFloat(q.Value, 'g', -1, 64)
+	}
+	if q.Unit == durationUnit {
+		return formatDuration(q.Value)
+	}
+	if q.Unit != "" {
+		// The display unit parsed when the quantity was created
+		def, _ := parseUnit(q.Unit)
+		return strconv.FormatFloat(q.Value/def.factor, 'g', -1, 64) + " " + q.Unit
+	}
+	return strconv.FormatFloat(q.Value, 'g', -1, 64) + " " + formatDimension(q.Dims)
+}
+
+// UnitCalculator is an RPN calculator whose operands carry units. Adding or
+// comparing quantities requires matching dimensions, multiplying and
+// dividing composes them and the to operator converts between units.
+// Durations can also be written like time.Duration, as 1h30m, and the dur
+// token displays the duration on top that way
+type UnitCalculator struct {
+	*engine.Engine[Quantity]
+}
+
+// NewUnitCalculator creates a new unit-aware calculator instance
+func NewUnitCalculator() *UnitCalculator {
+	return &UnitCalculator{
+		Engine: engine.New(unitOperators, parseQuantity),
+	}
+}
+
+// unitOperators applies the binary operators and to with applyUnitBinary
+// and the unary ones with applyUnitUnary
+var unitOperators = scalarOperators(applyUnitBinary, applyUnitUnary, "to")
+
+// applyUnitUnary applies a unary operator to a quantity, which must be
+// dimensionless for every operator but neg
+func applyUnitUnary(token string, a Quantity) (Quantity, error) {
+	if token != "neg" && !a.Dimensionless() {
+		return Quantity{}, fmt.Errorf("%s requires a dimensionless operand, got %s", token, formatDimension(a.Dims))
+	}
+	return Quantity{Value: unaryOperators[token](a.Value), Dims: a.Dims, Unit: a.Unit}, nil
+}
+
+// Evaluate processes a single token. A quantity literal like 5m or 100km/h
+// is pushed as is, a bare unit like km/h tags a plain number on top of the
+// stack or otherwise pushes one of that unit, e.g. as the target of to.
+// Operators take precedence over units of the same name, like min
+func (calc *UnitCalculator) Evaluate(token string) error {
+	if _, ok := unitOperators[token]; ok {
+		return calc.Engine.Evaluate(token)
+	}
+
+	if token == durationUnit {
+		return calc.formatAsDuration()
+	}
+
+	if def, err := parseUnit(token); err == nil {
+		if top, err := calc.Peek(); err == nil && top.Dimensionless() && top.Unit == "" {
+			calc.Pop()
+			calc.Push(Quantity{Value: top.Value * def.factor, Dims: def.dims, Unit: token})
+			return nil
+		}
+		calc.Push(Quantity{Value: def.factor, Dims: def.dims, Unit: token})
+		return nil
+	}
+
+	return calc.Engine.Evaluate(token)
+}
+
+// EvaluateExpression processes an entire RPN expression and returns the resulting quantity
+func (calc *UnitCalculator) EvaluateExpression(expression string) (Quantity, error) {
+	calc.Clear()
+
+	for _, token := range Tokenize(expression) {
+		if err := calc.Evaluate(token.Value); err != nil {
+			return Quantity{}, positionError(expression, token, err)
+		}
+	}
+
+	if calc.Size() != 1 {
+		return Quantity{}, engine.Incomplete(calc.Size())
+	}
+
+	return calc.Peek()
+}
+
+// applyUnitBinary applies a binary operator or to, checking dimensions
+func applyUnitBinary(token string, a, b Quantity) (Quantity, error) {
+	switch token {
+	case "to":
+		if a.Dims != b.Dims {
+			return Quantity{}, fmt.Errorf("cannot convert %s to %s", formatDimension(a.Dims), formatDimension(b.Dims))
+		}
+		return Quantity{Value: a.Value, Dims: a.Dims, Unit: b.Unit}, nil
+	case "*":
+		return Quantity{Value: a.Value * b.Value, Dims: addDimensions(a.Dims, b.Dims, 1), Unit: scaledUnit(a, b)}, nil
+	case "/":
+		unit := ""
+		if b.Dimensionless() {
+			unit = a.Unit
+		}
+		return Quantity{Value: a.Value / b.Value, Dims: addDimensions(a.Dims, b.Dims, -1), Unit: unit}, nil
+	case "^", "**":
+		if !b.Dimensionless() {
+			return Quantity{}, fmt.Errorf("exponent must be dimensionless, got %s", formatDimension(b.Dims))
+		}
+		if a.Dimensionless() {
+			return Quantity{Value: power(a.Value, b.Value)}, nil
+		}
+		if b.Value != math.Trunc(b.Value) {
+			return Quantity{}, fmt.Errorf("cannot raise %s to non-integer power %g", formatDimension(a.Dims), b.Value)
+		}
+		var dims dimension
+		for i := range dims {
+			dims[i] = a.Dims[i] * int(b.Value)
+		}
+		return Quantity{Value: power(a.Value, b.Value), Dims: dims}, nil
+	case "+", "-", "mod", "min", "max":
+		if a.Dims != b.Dims {
+			return Quantity{}, fmt.Errorf("incompatible units for %s: %s and %s", token, formatDimension(a.Dims), formatDimension(b.Dims))
+		}
+		return Quantity{Value: binaryOperators[token](a.Value, b.Value), Dims: a.Dims, Unit: a.Unit}, nil
+	case "<", ">", "<=", ">=", "==", "!=":
+		if a.Dims != b.Dims {
+			return Quantity{}, fmt.Errorf("incompatible units for %s: %s and %s", token, formatDimension(a.Dims), formatDimension(b.Dims))
+		}
+		return Quantity{Value: binaryOperators[token](a.Value, b.Value)}, nil
+	}
+
+	if !a.Dimensionless() || !b.Dimensionless() {
+		return Quantity{}, fmt.Errorf("%s requires dimensionless operands", token)
+	}
+	return Quantity{Value: binaryOperators[token](a.Value, b.Value)}, nil
+}
+
+// scaledUnit keeps the display unit of a product when one factor is a plain number
+func scaledUnit(a, b Quantity) string {
+	switch {
+	case b.Dimensionless():
+		return a.Unit
+	case a.Dimensionless():
+		return b.Unit
+	}
+	return ""
+}
+
+// addDimensions returns a + sign*b exponent by exponent
+func addDimensions(a, b dimension, sign int) dimension {
+	for i := range a {
+		a[i] += sign * b[i]
+	}
+	return a
+}
+
+// parseQuantity parses a number with an optional unit suffix like 5m or 9.81m/s^2
+func parseQuantity(token string) (Quantity, error) {
+	// Split at the first position where the prefix is a number and the
+	// suffix a unit, so exponents like 1e3m stay part of the number. Units
+	// are tried before SI prefixes, so 5m is five meters rather than 0.005
+	for i := 1; i < len(token); i++ {
+		value, err := parseNumber(token[:i])
+		if err != nil {
+			continue
+		}
+		def, err := parseUnit(token[i:])
+		if err != nil {
+			continue
+		}
+		return Quantity{Value: value * def.factor, Dims: def.dims, Unit: token[i:]}, nil
+	}
+
+	if value, err := parseNumber(token); err == nil {
+		return Quantity{Value: value}, nil
+	}
+	if value, ok := parseDuration(token); ok {
+		return value, nil
+	}
+	return Quantity{}, engine.UnknownToken(token)
+}
+
+// parseUnit parses a unit expression of unit symbols with optional integer
+// exponents joined by *, with at most one / before the denominator, e.g. kg*m/s^2
+func parseUnit(expression string) (unitDef, error) {
+	numerator, denominator, divided := strings.Cut(expression, "/")
+	if strings.Contains(denominator, "/") {
+		return unitDef{}, fmt.Errorf("invalid unit: %s", expression)
+	}
+
+	result := unitDef{factor: 1}
+	if err := accumulateUnits(&result, numerator, 1); err != nil {
+		return unitDef{}, fmt.Errorf("invalid unit: %s", expression)
+	}
+	if divided {
+		if err := accumulateUnits(&result, denominator, -1); err != nil {
+			return unitDef{}, fmt.Errorf("invalid unit: %s", expression)
+		}
+	}
+	return result, nil
+}
+
+// accumulateUnits multiplies the *-separated unit terms into result, raised to sign
+func accumulateUnits(result *unitDef, terms string, sign int) error {
+	for _, term := range strings.Split(terms, "*") {
+		symbol, exponentText, hasExponent := strings.Cut(term, "^")
+		def, ok := units[symbol]
+		if !ok {
+			return fmt.Errorf("unknown unit: %s", symbol)
+		}
+
+		exponent := 1
+		if hasExponent {
+			var err error
+			if exponent, err = strconv.Atoi(exponentText); err != nil {
+				return err
+			}
+		}
+
+		result.factor *= math.Pow(def.factor, float64(sign*exponent))
+		result.dims = addDimensions(result.dims, def.dims, sign*exponent)
+	}
+	return nil
+}
+
+// formatDimension renders dimension exponents in SI base units, e.g. kg*m/s^2
+func formatDimension(dims dimension) string {
+	if dims == (dimension{}) {
+		return "dimensionless"
+	}
+
+	numerator, denominator := []string{}, []string{}
+	for i, exponent := range dims {
+		term := baseUnits[i]
+		if exponent > 1 || exponent < -1 {
+			term += "^" + strconv.Itoa(max(exponent, -exponent))
+		}
+		switch {
+		case exponent > 0:
+			numerator = append(numerator, term)
+		case exponent < 0:
+			denominator = append(denominator, term)
+		}
+	}
+
+	text := strings.Join(numerator, "*")
+	if text == "" {
+		text = "1"
+	}
+	if len(denominator) > 0 {
+		text += "/" + strings.Join(denominator, "*")
+	}
+	return text
+}
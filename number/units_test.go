@@ -0,0 +1,75 @@
+package number
+
+import (
+	"testing"
+)
+
+func TestUnitCalculator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"5m 3m +", "8 m"},
+		{"1km m to", "1000 m"},
+		{"100km/h m/s to", "27.77777777777778 m/s"},
+		{"2m 3s *", "6 m*s"},
+		{"10m 2s /", "5 m/s"},
+		{"2kg 9.81m/s^2 * N to", "19.62 N"},
+		{"3m 2 ^", "9 m^2"},
+		{"5m 2 *", "10 m"},
+		{"1.5 km", "1.5 km"},
+		{"3m 2m >", "1"},
+		{"2 3 +", "5"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewUnitCalculator().EvaluateExpression(test.expression)
+			if err != nil {
+				t.Fatalf("EvaluateExpression(%q): %v", test.expression, err)
+			}
+			if got.String() != test.want {
+				t.Errorf("EvaluateExpression(%q) = %q, want %q", test.expression, got.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestUnitCalculatorErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"5m 3s +", "incompatible units for +: m and s (column 7)"},
+		{"60s 1m ==", "incompatible units for ==: s and m (column 8)"},
+		{"5m s to", "cannot convert m to s (column 6)"},
+		{"2 3m ^", "exponent must be dimensionless, got m (column 6)"},
+		{"2m 0.5 ^", "cannot raise m to non-integer power 0.5 (column 8)"},
+		{"5m sqrt", "sqrt requires a dimensionless operand, got m (column 4)"},
+		{"5parsec", "unknown token: 5parsec (column 1)"},
+		{"5m to", "insufficient operands for to operation (column 4)"},
+		{"sqrt", "insufficient operands for sqrt operation (column 1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewUnitCalculator().EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
+
+func TestUnitCalculatorFailingOperationKeepsStack(t *testing.T) {
+	calc := NewUnitCalculator()
+	for _, token := range []string{"5m", "3s"} {
+		if err := calc.Evaluate(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := calc.Evaluate("+"); err == nil {
+		t.Fatal("adding meters and seconds succeeded")
+	}
+	if values := calc.Values(); len(values) != 2 || values[0].String() != "5 m" || values[1].String() != "3 s" {
+		t.Errorf("stack after a failing + = %v, want [5 m, 3 s]", values)
+	}
+}
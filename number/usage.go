@@ -0,0 +1,95 @@
+package number
+
+import (
+	"sync"
+	"time"
+)
+
+// FormulaUsage is how often a formula was evaluated through Formulas.Eval,
+// including the evaluations of a state passed to Formulas.Restore, so dead
+// formulas and problematic ones stand out. A formula never evaluated has no evaluations and a zero LastUsed
+type FormulaUsage struct {
+	Name        string    `json:"name"`
+	Evaluations int       `json:"evaluations"`
+	Errors      int       `json:"errors"`
+	LastUsed    time.Time `json:"last_used"`
+	// Latency is the total time of the evaluations, see AverageLatency
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// AverageLatency returns the mean time of an evaluation, zero when there
+// was none
+func (usage FormulaUsage) AverageLatency() time.Duration {
+	if usage.Evaluations == 0 {
+		return 0
+	}
+	return usage.Latency / time.Duration(usage.Evaluations)
+}
+
+// ErrorRate returns the fraction of the evaluations that failed, zero when
+// there was none
+func (usage FormulaUsage) ErrorRate() float64 {
+	if usage.Evaluations == 0 {
+		return 0
+	}
+	return float64(usage.Errors) / float64(usage.Evaluations)
+}
+
+// formulaUsage counts the evaluations of every formula. It has its own
+// lock so evaluations do not contend with reloads
+type formulaUsage struct {
+	mu     sync.Mutex
+	byName map[string]FormulaUsage
+}
+
+// record counts an evaluation of name that started at start
+func (usage *formulaUsage) record(name string, start time.Time, err error) {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	if usage.byName == nil {
+		usage.byName = make(map[string]FormulaUsage)
+	}
+	entry := usage.byName[name]
+	entry.Evaluations++
+	if err != nil {
+		entry.Errors++
+	}
+	entry.LastUsed = start
+	entry.Latency += time.Since(start)
+	usage.byName[name] = entry
+}
+
+// snapshot returns a copy of the usage of every evaluated name, as
+// Formulas.State returns it
+func (usage *formulaUsage) snapshot() map[string]FormulaUsage {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	byName := make(map[string]FormulaUsage, len(usage.byName))
+	for name, entry := range usage.byName {
+		entry.Name = name
+		byName[name] = entry
+	}
+	return byName
+}
+
+// restore replaces the usage with byName, as Formulas.Restore received it
+func (usage *formulaUsage) restore(byName map[string]FormulaUsage) {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	usage.byName = byName
+}
+
+// Usage returns the usage of every formula, sorted by name. Usage is kept
+// by name, so it carries over reloads changing a formula and removing and
+// adding it again
+func (formulas *Formulas) Usage() []FormulaUsage {
+	names := formulas.Names()
+	formulas.usage.mu.Lock()
+	defer formulas.usage.mu.Unlock()
+	usages := make([]FormulaUsage, len(names))
+	for i, name := range names {
+		usages[i] = formulas.usage.byName[name]
+		usages[i].Name = name
+	}
+	return usages
+}
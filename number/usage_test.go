@@ -0,0 +1,99 @@
+package number
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFormulasUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "formulas.json")
+	writeFormulas(t, path, `{"area": "w h *", "idle": "1"}`)
+	formulas := NewFormulas(path)
+	if err := formulas.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every step evaluates one formula and checks the evaluations and
+	// errors counted for it so far
+	steps := []struct {
+		name        string
+		formula     string
+		vars        map[string]float64
+		wantErr     bool
+		evaluations int
+		errors      int
+	}{
+		{"bound", "area", map[string]float64{"w": 2, "h": 3}, false, 1, 0},
+		{"unbound", "area", map[string]float64{"w": 2}, true, 2, 1},
+		{"bound again", "area", map[string]float64{"w": 1, "h": 1}, false, 3, 1},
+		{"unknown is not counted", "volume", nil, true, 0, 0},
+	}
+	for _, step := range steps {
+		before := time.Now()
+		if _, err := formulas.Eval(step.formula, step.vars); (err != nil) != step.wantErr {
+			t.Errorf("%s: Eval() error = %v", step.name, err)
+		}
+		var usage FormulaUsage
+		for _, candidate := range formulas.Usage() {
+			if candidate.Name == step.formula {
+				usage = candidate
+			}
+		}
+		if usage.Evaluations != step.evaluations || usage.Errors != step.errors {
+			t.Errorf("%s: usage = %+v, want %d evaluations and %d errors", step.name, usage, step.evaluations, step.errors)
+		}
+		if step.evaluations > 0 && usage.LastUsed.Before(before) {
+			t.Errorf("%s: LastUsed = %v, before the evaluation", step.name, usage.LastUsed)
+		}
+	}
+
+	usages := formulas.Usage()
+	if len(usages) != 2 || usages[0].Name != "area" || usages[1].Name != "idle" {
+		t.Fatalf("Usage() = %+v, want area and idle", usages)
+	}
+	if idle := usages[1]; idle.Evaluations != 0 || !idle.LastUsed.IsZero() || idle.Latency != 0 {
+		t.Errorf("unevaluated formula usage = %+v", idle)
+	}
+
+	// Usage is kept by name across a reload removing the formula and one
+	// adding it back
+	writeFormulas(t, path, `{"idle": "1"}`)
+	if err := formulas.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(formulas.Usage()); got != 1 {
+		t.Errorf("Usage() after removing area has %d formulas, want 1", got)
+	}
+	writeFormulas(t, path, `{"area": "w h * 2 /", "idle": "1"}`)
+	if err := formulas.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if usage := formulas.Usage()[0]; usage.Name != "area" || usage.Evaluations != 3 || usage.Errors != 1 {
+		t.Errorf("usage of area after adding it back = %+v", usage)
+	}
+}
+
+func TestFormulaUsageRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   FormulaUsage
+		latency time.Duration
+		rate    float64
+	}{
+		{"never evaluated", FormulaUsage{}, 0, 0},
+		{"one evaluation", FormulaUsage{Evaluations: 1, Latency: 3 * time.Millisecond}, 3 * time.Millisecond, 0},
+		{"some errors", FormulaUsage{Evaluations: 4, Errors: 1, Latency: 8 * time.Millisecond}, 2 * time.Millisecond, 0.25},
+		{"all errors", FormulaUsage{Evaluations: 2, Errors: 2, Latency: time.Millisecond}, 500 * time.Microsecond, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.usage.AverageLatency(); got != test.latency {
+				t.Errorf("AverageLatency() = %v, want %v", got, test.latency)
+			}
+			if got := test.usage.ErrorRate(); got != test.rate {
+				t.Errorf("ErrorRate() = %g, want %g", got, test.rate)
+			}
+		})
+	}
+}
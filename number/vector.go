@@ -0,0 +1,111 @@
+package number
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// VectorCalculator is an RPN calculator whose operands are vectors. Scalars
+// are vectors of length one and are broadcast against longer vectors
+type VectorCalculator struct {
+	*engine.Engine[[]float64]
+}
+
+// NewVectorCalculator creates a new vector calculator instance
+func NewVectorCalculator() *VectorCalculator {
+	return &VectorCalculator{
+		Engine: engine.New(vectorOperators, parseVector),
+	}
+}
+
+// vectorOperators applies the binary operators element-wise, broadcasting
+// scalars, the unary ones to every element and the reducers to a whole
+// vector. The binary min and max take precedence over the reducers
+var vectorOperators = func() engine.Operators[[]float64] {
+	operators := scalarOperators(func(token string, a, b []float64) ([]float64, error) {
+		return broadcast(a, b, binaryOperators[token])
+	}, func(token string, a []float64) ([]float64, error) {
+		result := make([]float64, len(a))
+		for i, value := range a {
+			result[i] = unaryOperators[token](value)
+		}
+		return result, nil
+	})
+	for token, reducer := range reducers {
+		if _, ok := operators[token]; ok {
+			continue
+		}
+		operators[token] = engine.Operator[[]float64]{Arity: 1, Apply: func(operands [][]float64) ([]float64, error) {
+			if len(operands[0]) < minReducerOperands[token] {
+				return nil, fmt.Errorf("cannot apply %s to a vector of length %d", token, len(operands[0]))
+			}
+			return []float64{reducer(operands[0])}, nil
+		}}
+	}
+	return operators
+}()
+
+// EvaluateExpression processes an entire RPN expression and returns the resulting vector
+func (calc *VectorCalculator) EvaluateExpression(expression string) ([]float64, error) {
+	calc.Clear()
+
+	for _, token := range Tokenize(expression) {
+		if err := calc.Evaluate(token.Value); err != nil {
+			return nil, positionError(expression, token, err)
+		}
+	}
+
+	if calc.Size() != 1 {
+		return nil, engine.Incomplete(calc.Size())
+	}
+
+	return calc.Peek()
+}
+
+// parseVector parses a scalar or a bracketed, comma separated vector literal like [1,2,3]
+func parseVector(token string) ([]float64, error) {
+	if !strings.HasPrefix(token, "[") || !strings.HasSuffix(token, "]") {
+		value, err := parseNumber(token)
+		if err != nil {
+			return nil, engine.UnknownToken(token)
+		}
+		return []float64{value}, nil
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(token, "["), "]")
+	if body == "" {
+		return []float64{}, nil
+	}
+
+	parts := strings.Split(body, ",")
+	vector := make([]float64, len(parts))
+	for i, part := range parts {
+		value, err := parseNumber(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector element %q in %s", part, token)
+		}
+		vector[i] = value
+	}
+	return vector, nil
+}
+
+// broadcast applies operation element-wise, stretching length one operands
+func broadcast(a, b []float64, operation func(float64, float64) float64) ([]float64, error) {
+	length := len(a)
+	switch {
+	case len(a) == len(b):
+	case len(a) == 1:
+		length = len(b)
+	case len(b) == 1:
+	default:
+		return nil, fmt.Errorf("vector length mismatch: %d and %d", len(a), len(b))
+	}
+
+	result := make([]float64, length)
+	for i := range result {
+		result[i] = operation(a[min(i, len(a)-1)], b[min(i, len(b)-1)])
+	}
+	return result, nil
+}
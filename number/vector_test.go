@@ -0,0 +1,57 @@
+package number
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVectorCalculator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []float64
+	}{
+		{"[1,2,3] 2 *", []float64{2, 4, 6}},
+		{"10 [1,2,3] -", []float64{9, 8, 7}},
+		{"[1,2] [3,4] +", []float64{4, 6}},
+		{"[4,9] sqrt", []float64{2, 3}},
+		{"[1,2,3] sum", []float64{6}},
+		{"[1,2,3] mean", []float64{2}},
+		{"[1,2,3] [1,1,1] + sum", []float64{9}},
+		{"2 3 *", []float64{6}},
+		{"[]", []float64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := NewVectorCalculator().EvaluateExpression(test.expression)
+			if err != nil {
+				t.Fatalf("EvaluateExpression(%q): %v", test.expression, err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("EvaluateExpression(%q) = %v, want %v", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestVectorCalculatorErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"[1,2] [1,2,3] +", "vector length mismatch: 2 and 3 (column 15)"},
+		{"[1,x] 1 +", `invalid vector element "x" in [1,x] (column 1)`},
+		{"[] sum", "cannot apply sum to a vector of length 0 (column 4)"},
+		{"[1,2] +", "insufficient operands for + operation (column 7)"},
+		{"neg", "insufficient operands for neg operation (column 1)"},
+		{"sum", "insufficient operands for sum operation (column 1)"},
+		{"[1,2] [3,4]", "invalid expression: expected 1 result, got 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := NewVectorCalculator().EvaluateExpression(test.expression)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("EvaluateExpression(%q) error = %v, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package number
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/StefanTrusnov/go-rpn/engine"
+)
+
+// opcode is the operation of a bytecode word
+type opcode uint8
+
+const (
+	// bcPush pushes consts[arg]
+	bcPush opcode = iota
+	// bcVar pushes the value bound to names[arg]
+	bcVar
+	// bcAdd, bcSub, bcMul, bcDiv and bcNeg run the arithmetic operators
+	// inline, the common case not paying for a function call
+	bcAdd
+	bcSub
+	bcMul
+	bcDiv
+	bcNeg
+	// bcUnary and the other call opcodes run the function at index arg of
+	// their table
+	bcUnary
+	bcBinary
+	bcCheckedUnary
+	bcCheckedBinary
+	bcTernary
+	bcCall
+	// bcLabel copies the top of the stack to labels[arg] when the run
+	// records labels
+	bcLabel
+)
+
+// word is a single bytecode instruction: an opcode and its immediate
+// operand, an index into one of the tables of the bytecode
+type word struct {
+	op  opcode
+	arg uint32
+}
+
+// inlineOpcodes maps the operators run without a function call to their
+// opcode
+var inlineOpcodes = map[string]opcode{
+	"+":   bcAdd,
+	"-":   bcSub,
+	"*":   bcMul,
+	"/":   bcDiv,
+	"neg": bcNeg,
+}
+
+// maxInlineVars is the number of distinct variables run can bind without
+// allocating
+const maxInlineVars = 16
+
+// bytecode is a program lowered for the VM. The words are small and hold
+// no pointers, so long programs stay compact in the cache, and everything
+// else an instruction needs is in the tables its immediate indexes
+type bytecode struct {
+	words  []word
+	consts []float64
+	// names holds every variable once, so each is looked up once per run
+	names []string
+
+	unary         []func(float64) float64
+	binary        []func(float64, float64) float64
+	checkedUnary  []func(float64) (float64, error)
+	checkedBinary []func(float64, float64) (float64, error)
+	ternary       []func(float64, float64, float64) float64
+	calls         []engine.Operator[float64]
+}
+
+// assemble lowers instructions to bytecode, with a bcLabel word for each
+// label after the instructions it follows
+func assemble(code []instruction, labels []programLabel) bytecode {
+	bc := bytecode{words: make([]word, 0, len(code)+len(labels))}
+	slots := make(map[string]uint32)
+	next := 0
+	emitLabels := func(at int) {
+		for ; next < len(labels) && labels[next].at == at; next++ {
+			bc.words = append(bc.words, word{op: bcLabel, arg: uint32(next)})
+		}
+	}
+	for i, instr := range code {
+		emitLabels(i)
+		if op, ok := inlineOpcodes[instr.op]; ok && (instr.kind == opUnary || instr.kind == opBinary) {
+			bc.words = append(bc.words, word{op: op})
+			continue
+		}
+		switch instr.kind {
+		case opPush:
+			bc.words = append(bc.words, word{op: bcPush, arg: uint32(len(bc.consts))})
+			bc.consts = append(bc.consts, instr.value)
+		case opVar:
+			slot, ok := slots[instr.name]
+			if !ok {
+				slot = uint32(len(bc.names))
+				slots[instr.name] = slot
+				bc.names = append(bc.names, instr.name)
+			}
+			bc.words = append(bc.words, word{op: bcVar, arg: slot})
+		case opUnary:
+			bc.words = append(bc.words, word{op: bcUnary, arg: uint32(len(bc.unary))})
+			bc.unary = append(bc.unary, instr.unary)
+		case opBinary:
+			bc.words = append(bc.words, word{op: bcBinary, arg: uint32(len(bc.binary))})
+			bc.binary = append(bc.binary, instr.binary)
+		case opCheckedUnary:
+			bc.words = append(bc.words, word{op: bcCheckedUnary, arg: uint32(len(bc.checkedUnary))})
+			bc.checkedUnary = append(bc.checkedUnary, instr.checkedUnary)
+		case opCheckedBinary:
+			bc.words = append(bc.words, word{op: bcCheckedBinary, arg: uint32(len(bc.checkedBinary))})
+			bc.checkedBinary = append(bc.checkedBinary, instr.checkedBinary)
+		case opTernary:
+			bc.words = append(bc.words, word{op: bcTernary, arg: uint32(len(bc.ternary))})
+			bc.ternary = append(bc.ternary, instr.ternary)
+		case opCall:
+			bc.words = append(bc.words, word{op: bcCall, arg: uint32(len(bc.calls))})
+			bc.calls = append(bc.calls, instr.call)
+		}
+	}
+	emitLabels(len(code))
+	return bc
+}
+
+// run executes the bytecode on stack, which must have room for the
+// deepest the program gets, checking ctx every contextCheckInterval words.
+// Labeled values are recorded in labels unless it is nil
+func (bc *bytecode) run(ctx context.Context, vars map[string]float64, stack []float64, labels []float64) (float64, error) {
+	var inlineValues [maxInlineVars]float64
+	var inlineBound [maxInlineVars]bool
+	values, bound := inlineValues[:], inlineBound[:]
+	if len(bc.names) > maxInlineVars {
+		values, bound = make([]float64, len(bc.names)), make([]bool, len(bc.names))
+	}
+	for slot, name := range bc.names {
+		values[slot], bound[slot] = vars[name]
+	}
+
+	done := ctx.Done()
+	top := -1
+	for i, w := range bc.words {
+		if i%contextCheckInterval == 0 && done != nil {
+			select {
+			case <-done:
+				return 0, ctx.Err()
+			default:
+			}
+		}
+
+		switch w.op {
+		case bcPush:
+			top++
+			stack[top] = bc.consts[w.arg]
+		case bcVar:
+			// A missing variable fails where it is read, so errors of
+			// earlier words are reported first
+			if !bound[w.arg] {
+				return 0, fmt.Errorf("undefined variable: %s", bc.names[w.arg])
+			}
+			top++
+			stack[top] = values[w.arg]
+		case bcAdd:
+			top--
+			stack[top] += stack[top+1]
+		case bcSub:
+			top--
+			stack[top] -= stack[top+1]
+		case bcMul:
+			top--
+			stack[top] *= stack[top+1]
+		case bcDiv:
+			top--
+			stack[top] /= stack[top+1]
+		case bcNeg:
+			stack[top] = -stack[top]
+		case bcUnary:
+			stack[top] = bc.unary[w.arg](stack[top])
+		case bcBinary:
+			top--
+			stack[top] = bc.binary[w.arg](stack[top], stack[top+1])
+		case bcCheckedUnary:
+			result, err := bc.checkedUnary[w.arg](stack[top])
+			if err != nil {
+				return 0, err
+			}
+			stack[top] = result
+		case bcCheckedBinary:
+			result, err := bc.checkedBinary[w.arg](stack[top-1], stack[top])
+			if err != nil {
+				return 0, err
+			}
+			top--
+			stack[top] = result
+		case bcTernary:
+			top -= 2
+			stack[top] = bc.ternary[w.arg](stack[top], stack[top+1], stack[top+2])
+		case bcCall:
+			call := bc.calls[w.arg]
+			start := top - call.Arity + 1
+			result, err := call.Apply(stack[start : top+1])
+			if err != nil {
+				return 0, err
+			}
+			top = start
+			stack[top] = result
+		case bcLabel:
+			if labels != nil {
+				labels[w.arg] = stack[top]
+			}
+		}
+	}
+	return stack[0], nil
+}
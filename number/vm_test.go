@@ -0,0 +1,146 @@
+package number
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// deepExpression pushes n ones and adds them up, reaching a stack depth of n
+func deepExpression(n int) string {
+	return strings.Repeat("1 ", n) + strings.Repeat("+ ", n-1)
+}
+
+func TestVMMatchesInterpreter(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		vars       map[string]float64
+	}{
+		{"push", "42", nil},
+		{"inline arithmetic", "5 1 2 + 4 * + 3 - 2 / neg", nil},
+		{"division by zero", "1 0 /", nil},
+		{"not a number", "0 0 /", nil},
+		{"unary", "2 sqrt floor 9 sqrt +", nil},
+		{"binary", "2 10 ^ 7 mod 3 max 1 min", nil},
+		{"comparisons", "1 2 < 2 2 <= + 3 2 != +", nil},
+		{"percent", "200 10 %", nil},
+		{"checked unary", "5 !", nil},
+		{"checked unary error", "-1 !", nil},
+		{"checked binary", "5 2 nCr 12 18 gcd +", nil},
+		{"checked binary error", "2.5 4 gcd", nil},
+		{"ternary", "0 1 2 if 1 3 4 select +", nil},
+		{"call", "3 4 hypot 1 1 atan2 +", nil},
+		{"call error", "5 3 1 clamp", nil},
+		{"ternary call", "15 0 10 clamp", nil},
+		{"labels", "2 as a 3 as b * as c 1 + as c", nil},
+		{"variables", "price qty * price -", map[string]float64{"price": 2.5, "qty": 4}},
+		{"long", longExpression(1000), nil},
+		{"deep", deepExpression(maxInlineStack + 8), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The interpreter has no variables, so it reads their values
+			interpreted := test.expression
+			for name, value := range test.vars {
+				interpreted = strings.ReplaceAll(interpreted, name, fmt.Sprint(value))
+			}
+			calc := NewRPNCalculator()
+			want, wantErr := calc.EvaluateExpression(interpreted)
+			// Programs do not know the columns of their tokens
+			var positioned *PositionError
+			if errors.As(wantErr, &positioned) {
+				wantErr = positioned.Unwrap()
+			}
+
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runs := map[string]func() (float64, error){
+				"EvalWith": func() (float64, error) { return prog.EvalWith(test.vars) },
+				"Optimize": func() (float64, error) { return prog.Optimize().EvalWith(test.vars) },
+				"EvalLabeled": func() (float64, error) {
+					got, labels, err := prog.EvalLabeled(test.vars)
+					if err == nil && fmt.Sprint(labels) != fmt.Sprint(calc.Labels()) {
+						t.Errorf("EvalLabeled() labels = %v, want %v", labels, calc.Labels())
+					}
+					return got, err
+				},
+			}
+			for name, run := range runs {
+				got, err := run()
+				if !sameFloat(got, want) || fmt.Sprint(err) != fmt.Sprint(wantErr) {
+					t.Errorf("%s() = %g, %v, want %g, %v", name, got, err, want, wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []opcode
+	}{
+		{"1 2 + 3 - 4 * 5 / neg", []opcode{bcPush, bcPush, bcAdd, bcPush, bcSub, bcPush, bcMul, bcPush, bcDiv, bcNeg}},
+		{"x y x + +", []opcode{bcVar, bcVar, bcVar, bcAdd, bcAdd}},
+		{"2 sqrt 3 max", []opcode{bcPush, bcUnary, bcPush, bcBinary}},
+		{"5 ! 2 nCr", []opcode{bcPush, bcCheckedUnary, bcPush, bcCheckedBinary}},
+		{"1 2 3 if", []opcode{bcPush, bcPush, bcPush, bcTernary}},
+		{"1 1 atan2 0 1 clamp", []opcode{bcPush, bcPush, bcCall, bcPush, bcPush, bcCall}},
+		{"1 as a as b 2 + as c", []opcode{bcPush, bcLabel, bcLabel, bcPush, bcAdd, bcLabel}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			prog, err := Compile(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ops := []opcode{}
+			for _, w := range prog.vm.words {
+				ops = append(ops, w.op)
+			}
+			if !slices.Equal(ops, test.want) {
+				t.Errorf("opcodes = %v, want %v", ops, test.want)
+			}
+		})
+	}
+
+	// Every table holds what its words index, variables once each
+	prog, err := Compile("y x max sqrt 2 3 clamp x +")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := prog.vm
+	if !slices.Equal(vm.names, []string{"y", "x"}) || !slices.Equal(vm.consts, []float64{2, 3}) {
+		t.Errorf("names = %v, consts = %v", vm.names, vm.consts)
+	}
+	if len(vm.unary) != 1 || len(vm.binary) != 1 || len(vm.calls) != 1 {
+		t.Errorf("tables hold %d unary, %d binary, %d calls", len(vm.unary), len(vm.binary), len(vm.calls))
+	}
+}
+
+func TestVMManyVariables(t *testing.T) {
+	// More variables than run binds without allocating
+	names := make([]string, maxInlineVars+4)
+	vars := make(map[string]float64)
+	for i := range names {
+		names[i] = fmt.Sprintf("v%d", i)
+		vars[names[i]] = float64(i)
+	}
+	prog, err := Compile(strings.Join(names, " ") + strings.Repeat(" +", len(names)-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := float64(len(names) * (len(names) - 1) / 2)
+	if got, err := prog.EvalWith(vars); err != nil || got != want {
+		t.Errorf("EvalWith() = %g, %v, want %g", got, err, want)
+	}
+	delete(vars, "v18")
+	if _, err := prog.EvalWith(vars); err == nil || err.Error() != "undefined variable: v18" {
+		t.Errorf("EvalWith() without v18 error = %v", err)
+	}
+}